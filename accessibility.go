@@ -0,0 +1,120 @@
+package gvcode
+
+import (
+	"fmt"
+	"strings"
+
+	"gioui.org/io/semantic"
+	"gioui.org/layout"
+)
+
+// maxAnnouncedEdit is the length in runes of the longest inserted text read
+// out by the screen readers; longer insertions are announced by length.
+const maxAnnouncedEdit = 40
+
+// accessibilityState describes the editor to the screen readers through the
+// semantic ops of Gio: its label is the visible text, and its description
+// announces the caret position, the selection, the last edit and the current
+// line.
+type accessibilityState struct {
+	// name is the name of the editor set by WithAccessibleName.
+	name string
+	// version is the text version of the last announced edit, once synced
+	// with the text in the first layout.
+	synced  bool
+	version int
+	edit    string
+	// label caches the visible text of the text version and the visible
+	// paragraphs.
+	label        string
+	labelVersion int
+	labelLines   [2]int
+}
+
+// AccessibleDescription returns the description of the editor read out by
+// the screen readers, e.g., "main.go, line 3, column 5, 4 characters
+// selected, inserted "abc": fmt.Println(x)". It is updated in each layout.
+func (e *Editor) AccessibleDescription() string {
+	e.initBuffer()
+	a := &e.a11y
+	var b strings.Builder
+	if a.name != "" {
+		b.WriteString(a.name)
+		b.WriteString(", ")
+	}
+	line, col := e.text.CaretPos()
+	fmt.Fprintf(&b, "line %d, column %d", line+1, col+1)
+	if n := e.text.SelectionLen(); n > 0 {
+		fmt.Fprintf(&b, ", %d characters selected", n)
+	}
+	if a.edit != "" {
+		b.WriteString(", ")
+		b.WriteString(a.edit)
+	}
+	if e.mode == ModeReadOnly {
+		b.WriteString(", read only")
+	}
+
+	_, para := e.text.FindParagraph(e.text.ConvertPos(line, 0))
+	text := strings.TrimRight(e.ReadRange(para.RuneOff, para.RuneOff+para.Runes), "\r\n")
+	b.WriteString(": ")
+	b.WriteString(strings.TrimSpace(text))
+	return b.String()
+}
+
+// updateAnnouncedEdit describes the edits made since the last layout.
+func (e *Editor) updateAnnouncedEdit() {
+	a := &e.a11y
+	version := e.TextVersion()
+	if !a.synced {
+		a.synced, a.version = true, version
+	}
+	if version == a.version {
+		return
+	}
+	changes, ok := e.ChangesSince(a.version)
+	a.version = version
+	if !ok || len(changes) != 1 {
+		a.edit = "text changed"
+		return
+	}
+
+	c := changes[0]
+	switch inserted, deleted := c.NewEnd-c.Start, c.OldEnd-c.Start; {
+	case inserted > 0 && inserted <= maxAnnouncedEdit:
+		a.edit = fmt.Sprintf("inserted %q", e.ReadRange(c.Start, c.NewEnd))
+	case inserted > 0:
+		a.edit = fmt.Sprintf("inserted %d characters", inserted)
+	case deleted == 1:
+		a.edit = "deleted 1 character"
+	default:
+		a.edit = fmt.Sprintf("deleted %d characters", deleted)
+	}
+}
+
+// visibleText returns the text of the paragraphs overlapping the viewport.
+func (e *Editor) visibleText() string {
+	a := &e.a11y
+	first, last := e.visibleParagraphs()
+	version := e.TextVersion()
+	if a.labelVersion == version && a.labelLines == [2]int{first, last} && a.label != "" {
+		return a.label
+	}
+	a.labelVersion, a.labelLines = version, [2]int{first, last}
+	a.label = ""
+	if first < last {
+		paragraphs := e.text.TextLayout().Paragraphs
+		end := paragraphs[last-1].RuneOff + paragraphs[last-1].Runes
+		a.label = e.ReadRange(paragraphs[first].RuneOff, end)
+	}
+	return a.label
+}
+
+// addSemantics describes the editor in the semantic node of its area.
+func (e *Editor) addSemantics(gtx layout.Context) {
+	e.updateAnnouncedEdit()
+	semantic.Editor.Add(gtx.Ops)
+	semantic.LabelOp(e.visibleText()).Add(gtx.Ops)
+	semantic.DescriptionOp(e.AccessibleDescription()).Add(gtx.Ops)
+	semantic.EnabledOp(gtx.Enabled()).Add(gtx.Ops)
+}
@@ -0,0 +1,60 @@
+package gvcode
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/semantic"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestAccessibleSemantics(t *testing.T) {
+	e := newTestEditor(t, "package main\n\nfunc main() {}\n", WithAccessibleName("main.go"))
+
+	var router input.Router
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	node := func() input.SemanticDesc {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 200)), Source: router.Source()}
+		e.Layout(gtx, shaper)
+		router.Frame(gtx.Ops)
+		for _, n := range router.AppendSemantics(nil) {
+			if n.Desc.Class == semantic.Editor {
+				return n.Desc
+			}
+		}
+		t.Fatal("got no editor in the semantic tree")
+		return input.SemanticDesc{}
+	}
+
+	desc := node()
+	if desc.Label != e.Text() {
+		t.Errorf("got label %q, want the visible text", desc.Label)
+	}
+	if want := "main.go, line 1, column 1: package main"; desc.Description != want {
+		t.Errorf("got description %q, want %q", desc.Description, want)
+	}
+
+	// the caret position, the selection and the edits are announced.
+	e.SetCaret(23, 19)
+	if got := node().Description; got != "main.go, line 3, column 10, 4 characters selected: func main() {}" {
+		t.Errorf("got description %q with a selection", got)
+	}
+	e.SetCaret(23, 23)
+	e.Insert("Foo")
+	desc = node()
+	if want := `main.go, line 3, column 13, inserted "Foo": func mainFoo() {}`; desc.Description != want {
+		t.Errorf("got description %q, want %q", desc.Description, want)
+	}
+	if !strings.Contains(desc.Label, "mainFoo") {
+		t.Errorf("got label %q, want the edited text", desc.Label)
+	}
+	e.Delete(-2)
+	if got := node().Description; !strings.Contains(got, "deleted 2 characters") {
+		t.Errorf("got description %q after a deletion", got)
+	}
+}
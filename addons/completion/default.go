@@ -12,6 +12,7 @@ import (
 )
 
 var _ gvcode.Completion = (*DefaultCompletion)(nil)
+var _ gvcode.ChoiceCompletion = (*DefaultCompletion)(nil)
 
 // DefaultCompletion is a built-in implementation of the gvcode.Completion API.
 type DefaultCompletion struct {
@@ -114,6 +115,22 @@ func (dc *DefaultCompletion) OnText(ctx gvcode.CompletionContext) {
 	}
 }
 
+// OnChoices starts a session presenting the options of a snippet choice
+// tabstop, using the popup of the first registered completor.
+func (dc *DefaultCompletion) OnChoices(ctx gvcode.CompletionContext, candidates []gvcode.CompletionCandidate) {
+	dc.Cancel()
+	if len(dc.completors) == 0 || len(candidates) == 0 {
+		return
+	}
+
+	cmp := &delegatedCompletor{
+		Completor: &choiceCompletor{candidates: candidates},
+		popup:     dc.completors[0].popup,
+	}
+	dc.session = newSession(cmp, keyTrigger)
+	dc.updateCandidates(dc.session.Update(ctx))
+}
+
 func (dc *DefaultCompletion) updateCandidates(candidates []gvcode.CompletionCandidate) {
 	dc.candidates = dc.candidates[:0]
 	dc.candidates = append(dc.candidates, candidates...)
@@ -142,6 +159,16 @@ func (dc *DefaultCompletion) Layout(gtx layout.Context) layout.Dimensions {
 	return completor.popup.Layout(gtx, dc.candidates)
 }
 
+// Resolver returns the resolver of the candidates of the current session, or
+// nil if its Completor does not implement gvcode.CompletionResolver.
+func (dc *DefaultCompletion) Resolver() gvcode.CompletionResolver {
+	if dc.session == nil {
+		return nil
+	}
+	resolver, _ := dc.session.Completor().Completor.(gvcode.CompletionResolver)
+	return resolver
+}
+
 func (dc *DefaultCompletion) Cancel() {
 	if dc.session != nil && dc.session.IsValid() {
 		dc.session.makeInvalid()
@@ -232,3 +259,34 @@ func canTrigger(tr gvcode.Trigger, input string) bool {
 	// Always allow symbol characters to trigger completion.
 	return isSymbolChar([]rune(input)[0])
 }
+
+// choiceCompletor suggests the fixed options of a snippet choice tabstop.
+type choiceCompletor struct {
+	candidates []gvcode.CompletionCandidate
+}
+
+func (c *choiceCompletor) Trigger() gvcode.Trigger {
+	return gvcode.Trigger{}
+}
+
+func (c *choiceCompletor) Suggest(ctx gvcode.CompletionContext) []gvcode.CompletionCandidate {
+	return c.candidates
+}
+
+func (c *choiceCompletor) FilterAndRank(pattern string, candidates []gvcode.CompletionCandidate) []gvcode.CompletionCandidate {
+	if pattern == "" {
+		return candidates
+	}
+
+	// The typed text replaced the selected placeholder, so the content of the
+	// tabstop is the pattern now.
+	typed := len([]rune(pattern))
+	filtered := FuzzyFilterAndRank(pattern, candidates)
+	for i := range filtered {
+		rng := &filtered[i].TextEdit.EditRange
+		rng.End = rng.Start
+		rng.End.Column += typed
+		rng.End.Runes += typed
+	}
+	return filtered
+}
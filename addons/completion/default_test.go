@@ -0,0 +1,41 @@
+package completion
+
+import (
+	"testing"
+
+	"github.com/oligo/gvcode"
+)
+
+func TestChoiceCompletorFilter(t *testing.T) {
+	// the options of a choice tabstop at 4-7.
+	rng := gvcode.EditRange{
+		Start: gvcode.Position{Column: 4, Runes: 4},
+		End:   gvcode.Position{Column: 7, Runes: 7},
+	}
+	var candidates []gvcode.CompletionCandidate
+	for _, label := range []string{"one", "two", "Three"} {
+		candidates = append(candidates, gvcode.CompletionCandidate{
+			Label:    label,
+			TextEdit: gvcode.TextEdit{NewText: label, EditRange: rng},
+		})
+	}
+	c := &choiceCompletor{candidates: candidates}
+
+	if got := c.FilterAndRank("", c.Suggest(gvcode.CompletionContext{})); len(got) != 3 {
+		t.Fatalf("got %d options without a pattern, want all of them", len(got))
+	}
+
+	// the typed text replaced the placeholder, and is replaced by the option.
+	got := c.FilterAndRank("t", candidates)
+	if len(got) != 2 || got[0].Label != "two" || got[1].Label != "Three" {
+		t.Fatalf("got options %+v, want two and Three", got)
+	}
+	for _, c := range got {
+		if r := c.TextEdit.EditRange; r.Start.Runes != 4 || r.End.Runes != 5 || r.End.Column != 5 {
+			t.Errorf("got range %+v for %q, want 4-5", r, c.Label)
+		}
+	}
+	if candidates[1].TextEdit.EditRange != rng {
+		t.Error("the range of the suggested options is changed")
+	}
+}
@@ -0,0 +1,172 @@
+package completion
+
+import (
+	"strings"
+	"unicode"
+)
+
+// docBlockKind is the kind of a block of the documentation of a candidate.
+type docBlockKind uint8
+
+const (
+	docParagraph docBlockKind = iota
+	docHeading
+	docListItem
+	docCode
+)
+
+// docSpan is a run of inline text sharing the same style.
+type docSpan struct {
+	text   string
+	code   bool
+	bold   bool
+	italic bool
+}
+
+// docBlock is a block of the documentation. Code blocks keep their text
+// verbatim in a single span, the other blocks are parsed into inline spans.
+type docBlock struct {
+	kind docBlockKind
+	// marker is the bullet or the number of a list item.
+	marker string
+	spans  []docSpan
+}
+
+// parseDoc parses the documentation of a candidate, written in a subset of
+// Markdown: ATX headings, fenced and indented code blocks, list items and
+// paragraphs, with inline code, bold, italic and links. It also reads fine
+// for plain text, as the lines of a paragraph are joined like in Markdown.
+func parseDoc(doc string) []docBlock {
+	var blocks []docBlock
+	var para []string
+	var code []string
+	fenced := false
+
+	flushPara := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, docBlock{kind: docParagraph, spans: parseInline(strings.Join(para, " "))})
+			para = para[:0]
+		}
+	}
+	flushCode := func() {
+		if len(code) > 0 {
+			text := strings.TrimRight(strings.Join(code, "\n"), "\n")
+			blocks = append(blocks, docBlock{kind: docCode, spans: []docSpan{{text: text, code: true}}})
+			code = code[:0]
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(doc, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			if fenced {
+				flushCode()
+			} else {
+				flushPara()
+			}
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			code = append(code, line)
+			continue
+		}
+
+		// indented code, which does not interrupt a paragraph.
+		if len(para) == 0 && (strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "    ")) && trimmed != "" {
+			code = append(code, strings.TrimPrefix(strings.TrimPrefix(line, "\t"), "    "))
+			continue
+		}
+		flushCode()
+
+		switch {
+		case trimmed == "":
+			flushPara()
+		case strings.HasPrefix(trimmed, "#"):
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			if level <= 6 && (len(trimmed) == level || trimmed[level] == ' ') {
+				flushPara()
+				blocks = append(blocks, docBlock{kind: docHeading, spans: parseInline(strings.TrimSpace(trimmed[level:]))})
+				continue
+			}
+			para = append(para, trimmed)
+		default:
+			if marker, rest, ok := listMarker(trimmed); ok {
+				flushPara()
+				blocks = append(blocks, docBlock{kind: docListItem, marker: marker, spans: parseInline(rest)})
+				continue
+			}
+			para = append(para, trimmed)
+		}
+	}
+	flushPara()
+	flushCode()
+	return blocks
+}
+
+// listMarker splits a list item into its marker and its text. Bullets are
+// replaced by a dot.
+func listMarker(line string) (marker, rest string, ok bool) {
+	if len(line) >= 2 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		return "•", strings.TrimSpace(line[2:]), true
+	}
+	digits := strings.IndexFunc(line, func(r rune) bool { return !unicode.IsDigit(r) })
+	if digits > 0 && digits+1 < len(line) && (line[digits] == '.' || line[digits] == ')') && line[digits+1] == ' ' {
+		return line[:digits+1], strings.TrimSpace(line[digits+2:]), true
+	}
+	return "", "", false
+}
+
+// parseInline splits text into spans of inline code, bold and italic text.
+// Links are replaced by their text, and backslashes escape the next rune.
+func parseInline(text string) []docSpan {
+	var spans []docSpan
+	var cur strings.Builder
+	bold, italic := false, false
+
+	flush := func() {
+		if cur.Len() > 0 {
+			spans = append(spans, docSpan{text: cur.String(), bold: bold, italic: italic})
+			cur.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && unicode.IsPunct(rune(text[i+1])):
+			i++
+			cur.WriteByte(text[i])
+		case c == '`':
+			end := strings.IndexByte(text[i+1:], '`')
+			if end < 0 {
+				cur.WriteByte(c)
+				continue
+			}
+			flush()
+			spans = append(spans, docSpan{text: text[i+1 : i+1+end], code: true})
+			i += end + 1
+		case (c == '*' || c == '_') && i+1 < len(text) && text[i+1] == c:
+			flush()
+			bold = !bold
+			i++
+		case c == '*':
+			flush()
+			italic = !italic
+		case c == '[':
+			// [text](url) is shown as text.
+			closing := strings.Index(text[i:], "](")
+			end := strings.IndexByte(text[i:], ')')
+			if closing < 0 || end < closing {
+				cur.WriteByte(c)
+				continue
+			}
+			cur.WriteString(text[i+1 : i+closing])
+			i += end
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	flush()
+	return spans
+}
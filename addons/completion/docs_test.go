@@ -0,0 +1,46 @@
+package completion
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseDoc(t *testing.T) {
+	doc := "# Title\n\nSome *text* spanning\ntwo lines.\n\n- first `item`\n2. second\n\n```go\nfunc f() {}\n```\n\tindented"
+	blocks := parseDoc(doc)
+
+	kinds := []docBlockKind{}
+	for _, b := range blocks {
+		kinds = append(kinds, b.kind)
+	}
+	expected := []docBlockKind{docHeading, docParagraph, docListItem, docListItem, docCode, docCode}
+	if !slices.Equal(kinds, expected) {
+		t.Fatalf("expected kinds %v, got %v", expected, kinds)
+	}
+
+	para := blocks[1].spans
+	if len(para) != 3 || para[1].text != "text" || !para[1].italic || para[2].text != " spanning two lines." {
+		t.Errorf("unexpected paragraph spans: %+v", para)
+	}
+	if blocks[2].marker != "•" || blocks[3].marker != "2." {
+		t.Errorf("unexpected list markers: %q, %q", blocks[2].marker, blocks[3].marker)
+	}
+	if code := blocks[4].spans[0]; code.text != "func f() {}" || !code.code {
+		t.Errorf("unexpected code block: %+v", code)
+	}
+	if code := blocks[5].spans[0]; code.text != "indented" {
+		t.Errorf("unexpected indented code block: %+v", code)
+	}
+}
+
+func TestParseInline(t *testing.T) {
+	spans := parseInline("call **`Foo`** or see [the docs](https://example.com) \\*not italic\\*")
+	expected := []docSpan{
+		{text: "call "},
+		{text: "Foo", code: true},
+		{text: " or see the docs *not italic*"},
+	}
+	if !slices.Equal(spans, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, spans)
+	}
+}
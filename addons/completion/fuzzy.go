@@ -0,0 +1,156 @@
+package completion
+
+import (
+	"slices"
+	"unicode"
+
+	"github.com/oligo/gvcode"
+)
+
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+
+	// bonusBoundary is given to a match right after a separator like '_' or '.'.
+	bonusBoundary = scoreMatch / 2
+	// bonusCamel is given to a match at a camelCase or letter/digit transition.
+	bonusCamel = bonusBoundary - 1
+	// bonusConsecutive is given to each match that follows another match.
+	bonusConsecutive = 4
+	// bonusFirstCharMultiplier emphasizes the bonus of the first pattern char.
+	bonusFirstCharMultiplier = 2
+	// bonusCase is given when the case of the matched char is the same as typed.
+	bonusCase = 1
+)
+
+// FuzzyMatch matches pattern against text as a case-insensitive subsequence, in the
+// style of fzf. It returns the score of the match and the rune indices of text
+// that matched, or false if text does not contain pattern as a subsequence.
+//
+// Matches at the start of text, after separators such as '_' and '.', and at
+// camelCase humps score higher, as do consecutive matches. Gaps between matches
+// are penalized.
+func FuzzyMatch(pattern, text string) (score int, positions []int, ok bool) {
+	pRunes := []rune(pattern)
+	tRunes := []rune(text)
+	if len(pRunes) == 0 {
+		return 0, nil, true
+	}
+	if len(pRunes) > len(tRunes) {
+		return 0, nil, false
+	}
+
+	// Find the end of the first occurrence going forward.
+	pIdx, end := 0, -1
+	for i, r := range tRunes {
+		if equalFold(r, pRunes[pIdx]) {
+			pIdx++
+			if pIdx == len(pRunes) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	// Then go backward from the end to find a shorter span.
+	positions = make([]int, len(pRunes))
+	pIdx = len(pRunes) - 1
+	for i := end; i >= 0 && pIdx >= 0; i-- {
+		if equalFold(tRunes[i], pRunes[pIdx]) {
+			positions[pIdx] = i
+			pIdx--
+		}
+	}
+
+	return scorePositions(pRunes, tRunes, positions), positions, true
+}
+
+func scorePositions(pattern, text []rune, positions []int) int {
+	score := 0
+	prev := -1
+	for i, pos := range positions {
+		score += scoreMatch
+		if text[pos] == pattern[i] {
+			score += bonusCase
+		}
+
+		bonus := charBonus(text, pos)
+		if i == 0 {
+			bonus *= bonusFirstCharMultiplier
+		}
+		score += bonus
+
+		if prev >= 0 {
+			if gap := pos - prev - 1; gap > 0 {
+				score += scoreGapStart + scoreGapExtension*(gap-1)
+			} else {
+				score += bonusConsecutive
+			}
+		} else if pos > 0 {
+			// penalize the unmatched leading chars lightly.
+			score += scoreGapExtension * min(pos, 3)
+		}
+		prev = pos
+	}
+	return score
+}
+
+// charBonus returns the positional bonus of the char at pos.
+func charBonus(text []rune, pos int) int {
+	if pos == 0 {
+		return bonusBoundary
+	}
+
+	prev, cur := text[pos-1], text[pos]
+	switch {
+	case !unicode.IsLetter(prev) && !unicode.IsDigit(prev):
+		return bonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return bonusCamel
+	case unicode.IsLetter(prev) && unicode.IsDigit(cur):
+		return bonusCamel
+	}
+	return 0
+}
+
+func equalFold(a, b rune) bool {
+	return a == b || unicode.ToLower(a) == unicode.ToLower(b)
+}
+
+// FuzzyFilterAndRank filters the candidates whose label fuzzy matches the pattern,
+// and sorts them by score, preferring shorter labels on ties. The MatchedRunes of
+// the returned candidates are set for highlighting. It can be used to implement
+// Completor.FilterAndRank.
+func FuzzyFilterAndRank(pattern string, candidates []gvcode.CompletionCandidate) []gvcode.CompletionCandidate {
+	type scored struct {
+		candidate gvcode.CompletionCandidate
+		score     int
+	}
+
+	matched := make([]scored, 0, len(candidates))
+	for _, c := range candidates {
+		score, positions, ok := FuzzyMatch(pattern, c.Label)
+		if !ok {
+			continue
+		}
+		c.MatchedRunes = positions
+		matched = append(matched, scored{candidate: c, score: score})
+	}
+
+	slices.SortStableFunc(matched, func(a, b scored) int {
+		if a.score != b.score {
+			return b.score - a.score
+		}
+		return len(a.candidate.Label) - len(b.candidate.Label)
+	})
+
+	result := make([]gvcode.CompletionCandidate, 0, len(matched))
+	for _, m := range matched {
+		result = append(result, m.candidate)
+	}
+	return result
+}
@@ -0,0 +1,59 @@
+package completion
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/oligo/gvcode"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	cases := []struct {
+		pattern   string
+		text      string
+		ok        bool
+		positions []int
+	}{
+		{pattern: "", text: "foo", ok: true},
+		{pattern: "fb", text: "fooBar", ok: true, positions: []int{0, 3}},
+		{pattern: "gcc", text: "getCompletionCandidate", ok: true, positions: []int{0, 3, 13}},
+		{pattern: "ab", text: "xx_a_b", ok: true, positions: []int{3, 5}},
+		{pattern: "bf", text: "fooBar", ok: false},
+		{pattern: "toolong", text: "tool", ok: false},
+	}
+
+	for _, tc := range cases {
+		_, positions, ok := FuzzyMatch(tc.pattern, tc.text)
+		if ok != tc.ok || !slices.Equal(positions, tc.positions) {
+			t.Logf("pattern: %q, text: %q, want: %v %v, actual: %v %v", tc.pattern, tc.text, tc.ok, tc.positions, ok, positions)
+			t.Fail()
+		}
+	}
+}
+
+func TestFuzzyFilterAndRank(t *testing.T) {
+	candidates := []gvcode.CompletionCandidate{
+		{Label: "xfxoxo"},
+		{Label: "fooBar"},
+		{Label: "afoo"},
+		{Label: "foo"},
+		{Label: "bar"},
+	}
+
+	result := FuzzyFilterAndRank("foo", candidates)
+	labels := make([]string, 0, len(result))
+	for _, c := range result {
+		labels = append(labels, c.Label)
+	}
+
+	expected := []string{"foo", "fooBar", "afoo", "xfxoxo"}
+	if !slices.Equal(labels, expected) {
+		t.Logf("want: %v, actual: %v", expected, labels)
+		t.Fail()
+	}
+
+	if !slices.Equal(result[0].MatchedRunes, []int{0, 1, 2}) {
+		t.Logf("wrong matched runes: %v", result[0].MatchedRunes)
+		t.Fail()
+	}
+}
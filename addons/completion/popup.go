@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"slices"
 	"strings"
 
 	"gioui.org/font"
@@ -17,6 +18,7 @@ import (
 	"gioui.org/unit"
 	"gioui.org/widget"
 	"gioui.org/widget/material"
+	"gioui.org/x/styledtext"
 	"github.com/oligo/gvcode"
 )
 
@@ -116,16 +118,25 @@ type CompletionPopup struct {
 	itemsCount int
 	focused    int
 	labels     []*itemLabel
+	// docList scrolls the documentation panel.
+	docList widget.List
+	// resolving tracks the lazy resolution of the focused candidate.
+	resolving resolveState
 
 	// Size configures the max popup dimensions. If no value
 	// is provided, a reasonable value is set.
 	Size image.Point
+	// DocSize configures the max dimensions of the documentation panel shown
+	// next to the list. If no value is provided, a reasonable value is set.
+	DocSize image.Point
 	// TextSize configures the size the text displayed in the popup. If no value
 	// is provided, a reasonable value is set.
 	TextSize unit.Sp
 	// Color used to highlight the selected item.
 	HighlightColor color.NRGBA
-	Theme          *material.Theme
+	// Color used to highlight the runes matched by the typed text.
+	MatchColor color.NRGBA
+	Theme      *material.Theme
 }
 
 func NewCompletionPopup(editor *gvcode.Editor, cmp gvcode.Completion) *CompletionPopup {
@@ -179,14 +190,40 @@ func (pop *CompletionPopup) Layout(gtx layout.Context, items []gvcode.Completion
 			}
 		})
 	contentCall := macro.Stop()
+	pop.paintBox(gtx, contentCall, contentDims.Size)
 
-	// Draw background with rounded corners
+	// Show the documentation of the selected item next to the list.
+	if pop.focused < 0 || pop.focused >= len(items) {
+		return contentDims
+	}
+	item, loading := pop.resolve(gtx, items[pop.focused])
+	docGtx := gtx
+	docGtx.Constraints.Max = image.Point{X: pop.DocSize.X, Y: max(pop.DocSize.Y, contentDims.Size.Y)}
+	docGtx.Constraints.Min = image.Point{X: 0, Y: contentDims.Size.Y}
+	macro = op.Record(gtx.Ops)
+	docDims := pop.layoutDocumentation(docGtx, pop.Theme, item, loading)
+	docCall := macro.Stop()
+	if docDims.Size == (image.Point{}) {
+		return contentDims
+	}
+
+	offset := op.Offset(image.Point{X: contentDims.Size.X}).Push(gtx.Ops)
+	pop.paintBox(gtx, docCall, docDims.Size)
+	offset.Pop()
+
+	return layout.Dimensions{Size: image.Point{
+		X: contentDims.Size.X + docDims.Size.X,
+		Y: max(contentDims.Size.Y, docDims.Size.Y),
+	}}
+}
+
+// paintBox draws the content with a rounded background and a subtle border.
+func (pop *CompletionPopup) paintBox(gtx layout.Context, content op.CallOp, size image.Point) {
 	cornerRadius := gtx.Dp(unit.Dp(6))
-	bgRect := image.Rectangle{Max: contentDims.Size}
+	bgRect := image.Rectangle{Max: size}
 	defer clip.UniformRRect(bgRect, cornerRadius).Push(gtx.Ops).Pop()
 	paint.Fill(gtx.Ops, pop.Theme.Bg)
 
-	// Draw subtle border
 	borderColor := adjustAlpha(pop.Theme.Fg, 0x30)
 	paint.FillShape(gtx.Ops, borderColor,
 		clip.Stroke{
@@ -194,10 +231,102 @@ func (pop *CompletionPopup) Layout(gtx layout.Context, items []gvcode.Completion
 			Width: float32(gtx.Dp(unit.Dp(1))),
 		}.Op())
 
-	// Draw the content
-	contentCall.Add(gtx.Ops)
+	content.Add(gtx.Ops)
+}
+
+// layoutDocumentation lays out the detail and documentation of the candidate,
+// returning empty dimensions if there is nothing to show. The documentation
+// is rendered as Markdown, and replaced by a placeholder while it is loading.
+func (pop *CompletionPopup) layoutDocumentation(gtx layout.Context, th *material.Theme, item gvcode.CompletionCandidate, loading bool) layout.Dimensions {
+	if item.Detail == "" && item.Documentation == "" && !loading {
+		return layout.Dimensions{}
+	}
+
+	var children []layout.Widget
+	if item.Detail != "" {
+		children = append(children, func(gtx layout.Context) layout.Dimensions {
+			lb := material.Label(th, pop.TextSize, item.Detail)
+			lb.Font = font.Font{Typeface: "monospace", Weight: font.Medium}
+			return lb.Layout(gtx)
+		})
+	}
+	if loading {
+		children = append(children, func(gtx layout.Context) layout.Dimensions {
+			lb := material.Label(th, pop.TextSize, "Loading…")
+			lb.Color = adjustAlpha(th.Fg, 0x80)
+			lb.Font.Style = font.Italic
+			return lb.Layout(gtx)
+		})
+	}
+	for _, block := range parseDoc(item.Documentation) {
+		children = append(children, func(gtx layout.Context) layout.Dimensions {
+			return pop.layoutDocBlock(gtx, th, block)
+		})
+	}
 
-	return contentDims
+	pop.docList.Axis = layout.Vertical
+	return layout.UniformInset(unit.Dp(8)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		li := material.List(th, &pop.docList)
+		li.AnchorStrategy = material.Overlay
+		li.ScrollbarStyle.Indicator.MinorWidth = unit.Dp(4)
+		return li.Layout(gtx, len(children), func(gtx layout.Context, i int) layout.Dimensions {
+			if i == 0 {
+				return children[i](gtx)
+			}
+			return layout.Inset{Top: unit.Dp(6)}.Layout(gtx, children[i])
+		})
+	})
+}
+
+// layoutDocBlock lays out a block of the documentation.
+func (pop *CompletionPopup) layoutDocBlock(gtx layout.Context, th *material.Theme, block docBlock) layout.Dimensions {
+	fg := adjustAlpha(th.Fg, 0xCC)
+	size := pop.TextSize
+	styles := make([]styledtext.SpanStyle, 0, len(block.spans))
+	for _, span := range block.spans {
+		style := styledtext.SpanStyle{Content: span.text, Size: size, Color: fg, Font: font.Font{Typeface: th.Face}}
+		if span.code {
+			style.Font.Typeface = "monospace"
+		}
+		if span.bold || block.kind == docHeading {
+			style.Font.Weight = font.Bold
+			style.Color = th.Fg
+		}
+		if span.italic {
+			style.Font.Style = font.Italic
+		}
+		if block.kind == docHeading {
+			style.Size = size * 1.15
+		}
+		styles = append(styles, style)
+	}
+	text := func(gtx layout.Context) layout.Dimensions {
+		return styledtext.Text(th.Shaper, styles...).Layout(gtx, nil)
+	}
+
+	switch block.kind {
+	case docCode:
+		return layout.Background{}.Layout(gtx,
+			func(gtx layout.Context) layout.Dimensions {
+				rect := clip.UniformRRect(image.Rectangle{Max: gtx.Constraints.Min}, gtx.Dp(unit.Dp(3)))
+				paint.FillShape(gtx.Ops, adjustAlpha(th.Fg, 0x10), rect.Op(gtx.Ops))
+				return layout.Dimensions{Size: gtx.Constraints.Min}
+			},
+			func(gtx layout.Context) layout.Dimensions {
+				return layout.UniformInset(unit.Dp(4)).Layout(gtx, text)
+			})
+	case docListItem:
+		return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Baseline}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				lb := material.Label(th, size, block.marker)
+				lb.Color = fg
+				return layout.Inset{Left: unit.Dp(4), Right: unit.Dp(6)}.Layout(gtx, lb.Layout)
+			}),
+			layout.Flexed(1, text),
+		)
+	default:
+		return text(gtx)
+	}
 }
 
 func (pop *CompletionPopup) updateSelection(direction int) {
@@ -237,6 +366,7 @@ func (pop *CompletionPopup) updateSelection(direction int) {
 }
 
 func (pop *CompletionPopup) reset() {
+	pop.resolving.reset()
 	pop.focused = 0
 	pop.labels = pop.labels[:0]
 	pop.list.ScrollTo(0)
@@ -253,6 +383,12 @@ func (pop *CompletionPopup) update(gtx layout.Context) {
 			Y: gtx.Dp(unit.Dp(200)),
 		}
 	}
+	if pop.DocSize == (image.Point{}) {
+		pop.DocSize = image.Point{
+			X: gtx.Dp(unit.Dp(300)),
+			Y: gtx.Dp(unit.Dp(200)),
+		}
+	}
 
 	pop.editor.RegisterCommand(pop, key.Filter{Name: key.NameUpArrow, Optional: key.ModShift},
 		func(gtx layout.Context, evt key.Event) gvcode.EditorEvent {
@@ -406,9 +542,7 @@ func (pop *CompletionPopup) layoutList(gtx layout.Context, th *material.Theme, i
 					layout.Rigid(layout.Spacer{Width: unit.Dp(6)}.Layout),
 					// Label (main text)
 					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-						lb := material.Label(th, pop.TextSize, c.Label)
-						lb.Font.Weight = font.Medium
-						return lb.Layout(gtx)
+						return pop.layoutLabel(gtx, th, c)
 					}),
 					// Flexible spacer
 					layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
@@ -430,6 +564,50 @@ func (pop *CompletionPopup) layoutList(gtx layout.Context, th *material.Theme, i
 	})
 }
 
+// layoutLabel lays out the label of the candidate, highlighting the runes
+// matched by the typed pattern.
+func (pop *CompletionPopup) layoutLabel(gtx layout.Context, th *material.Theme, c gvcode.CompletionCandidate) layout.Dimensions {
+	if len(c.MatchedRunes) == 0 {
+		lb := material.Label(th, pop.TextSize, c.Label)
+		lb.Font.Weight = font.Medium
+		return lb.Layout(gtx)
+	}
+
+	matchColor := pop.MatchColor
+	if matchColor == (color.NRGBA{}) {
+		matchColor = th.ContrastBg
+	}
+
+	// split the label into runs of matched and unmatched runes.
+	type run struct {
+		text    string
+		matched bool
+	}
+	var runs []run
+	for i, r := range []rune(c.Label) {
+		matched := slices.Contains(c.MatchedRunes, i)
+		if len(runs) > 0 && runs[len(runs)-1].matched == matched {
+			runs[len(runs)-1].text += string(r)
+			continue
+		}
+		runs = append(runs, run{text: string(r), matched: matched})
+	}
+
+	children := make([]layout.FlexChild, 0, len(runs))
+	for _, r := range runs {
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			lb := material.Label(th, pop.TextSize, r.text)
+			lb.Font.Weight = font.Medium
+			if r.matched {
+				lb.Color = matchColor
+				lb.Font.Weight = font.Bold
+			}
+			return lb.Layout(gtx)
+		}))
+	}
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Baseline}.Layout(gtx, children...)
+}
+
 type itemLabel struct {
 	state     widget.Clickable
 	hovering  bool
@@ -0,0 +1,53 @@
+package completion
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/widget/material"
+	"github.com/oligo/gvcode"
+)
+
+// activeCompletion is a Completion with a session always active.
+type activeCompletion struct {
+	gvcode.Completion
+}
+
+func (activeCompletion) IsActive() bool { return true }
+
+func TestPopupDocumentation(t *testing.T) {
+	pop := NewCompletionPopup(&gvcode.Editor{}, activeCompletion{})
+	pop.Theme = material.NewTheme()
+	items := []gvcode.CompletionCandidate{
+		{Label: "Println", Kind: "function"},
+		{Label: "Printf", Kind: "function"},
+	}
+	layoutPopup := func() layout.Dimensions {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+		return pop.Layout(gtx, items)
+	}
+
+	list := layoutPopup()
+	if list.Size.X != pop.Size.X {
+		t.Fatalf("got popup width %d without documentation, want %d", list.Size.X, pop.Size.X)
+	}
+
+	items[0].Detail = "func(a ...any) (n int, err error)"
+	items[0].Documentation = "Println formats using the default formats."
+	dims := layoutPopup()
+	if dims.Size.X <= list.Size.X || dims.Size.X > list.Size.X+pop.DocSize.X {
+		t.Fatalf("got popup width %d with the documentation panel, want in (%d, %d]",
+			dims.Size.X, list.Size.X, list.Size.X+pop.DocSize.X)
+	}
+	if dims.Size.Y < list.Size.Y {
+		t.Errorf("got popup height %d, want at least the list height %d", dims.Size.Y, list.Size.Y)
+	}
+
+	// the panel follows the selected candidate.
+	pop.updateSelection(1)
+	if got := layoutPopup(); got.Size.X != list.Size.X {
+		t.Errorf("got popup width %d for a candidate without documentation, want %d", got.Size.X, list.Size.X)
+	}
+}
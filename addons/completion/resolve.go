@@ -0,0 +1,142 @@
+package completion
+
+import (
+	"context"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"github.com/oligo/gvcode"
+)
+
+// resolvePollInterval is how often the popup checks for the result of a
+// pending resolution.
+const resolvePollInterval = 50 * time.Millisecond
+
+// resolveState tracks the lazy resolution of the focused candidate by a
+// gvcode.CompletionResolver. At most one resolution is pending, and the
+// resolved candidates are cached until the popup is reset.
+type resolveState struct {
+	// pending is the key of the candidate being resolved.
+	pending string
+	cancel  context.CancelFunc
+	results chan resolveResult
+	cache   map[string]gvcode.CompletionCandidate
+}
+
+type resolveResult struct {
+	key  string
+	item gvcode.CompletionCandidate
+	err  error
+}
+
+// candidateKey identifies a candidate across the filtering of the list.
+func candidateKey(item gvcode.CompletionCandidate) string {
+	return item.Kind + "\x00" + item.Label + "\x00" + item.TextEdit.NewText
+}
+
+// reset cancels the pending resolution and clears the cache.
+func (s *resolveState) reset() {
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+	s.pending = ""
+	clear(s.cache)
+}
+
+// collect stores the results of the finished resolutions.
+func (s *resolveState) collect() {
+	for {
+		select {
+		case r := <-s.results:
+			if r.err != nil {
+				logger.Debug("resolving completion candidate failed", "label", r.item.Label, "error", r.err)
+			}
+			s.cache[r.key] = r.item
+			if r.key == s.pending {
+				s.pending = ""
+				s.cancel = nil
+			}
+		default:
+			return
+		}
+	}
+}
+
+// resolver returns the resolver of the candidates shown by the popup, if any.
+func (pop *CompletionPopup) resolver() gvcode.CompletionResolver {
+	switch cmp := pop.cmp.(type) {
+	case gvcode.CompletionResolver:
+		return cmp
+	case interface {
+		Resolver() gvcode.CompletionResolver
+	}:
+		return cmp.Resolver()
+	}
+	return nil
+}
+
+// resolve returns the candidate with its detail and documentation resolved
+// lazily by the resolver of the completor, and reports whether the
+// resolution is still pending. Only the candidates without documentation are
+// resolved.
+func (pop *CompletionPopup) resolve(gtx layout.Context, item gvcode.CompletionCandidate) (gvcode.CompletionCandidate, bool) {
+	resolver := pop.resolver()
+	if resolver == nil || item.Documentation != "" {
+		return item, false
+	}
+
+	s := &pop.resolving
+	if s.cache == nil {
+		s.cache = make(map[string]gvcode.CompletionCandidate)
+		s.results = make(chan resolveResult, 1)
+	}
+	s.collect()
+
+	key := candidateKey(item)
+	if resolved, ok := s.cache[key]; ok {
+		// the resolver fills in the documentation, the rest of the candidate
+		// is kept as filtered.
+		item.Detail = firstNonEmpty(resolved.Detail, item.Detail)
+		item.Documentation = resolved.Documentation
+		return item, false
+	}
+
+	if s.pending != key {
+		// the previously focused candidate is no longer needed.
+		if s.cancel != nil {
+			s.cancel()
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		s.pending, s.cancel = key, cancel
+		results := s.results
+		go func() {
+			resolved, err := resolver.ResolveItem(ctx, item)
+			if ctx.Err() != nil {
+				// canceled results are not cached, the candidate is
+				// resolved again when it is focused.
+				return
+			}
+			if err != nil {
+				resolved = item
+			}
+			select {
+			case results <- resolveResult{key: key, item: resolved, err: err}:
+			case <-ctx.Done():
+			}
+		}()
+	}
+	gtx.Execute(op.InvalidateCmd{At: gtx.Now.Add(resolvePollInterval)})
+	return item, true
+}
+
+// firstNonEmpty returns the first non-empty string.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
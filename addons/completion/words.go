@@ -0,0 +1,217 @@
+package completion
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/oligo/gvcode"
+)
+
+var _ gvcode.Completor = (*WordCompletor)(nil)
+
+// WordCompletor is a Completor suggesting the words found in the editor
+// text, which is useful when no language server is available. The words
+// are indexed line by line, and only the lines changed since the last
+// suggestion are indexed again.
+type WordCompletor struct {
+	Editor *gvcode.Editor
+	// MinLength is the minimum length in runes of the indexed words.
+	// Defaults to 3.
+	MinLength int
+	// IsWordChar reports whether a rune is part of a word. Defaults to
+	// letters, digits and the underscore.
+	IsWordChar func(r rune) bool
+
+	index wordIndex
+}
+
+// NewWordCompletor creates a WordCompletor for the editor.
+func NewWordCompletor(editor *gvcode.Editor) *WordCompletor {
+	return &WordCompletor{Editor: editor}
+}
+
+func (c *WordCompletor) Trigger() gvcode.Trigger {
+	return gvcode.Trigger{}
+}
+
+func (c *WordCompletor) Suggest(ctx gvcode.CompletionContext) []gvcode.CompletionCandidate {
+	c.index.isWordChar = c.isWordChar
+	c.index.minLength = c.MinLength
+	if c.index.minLength <= 0 {
+		c.index.minLength = 3
+	}
+	c.index.sync(c.Editor)
+
+	// The word being typed is indexed too. Skip it if it is the only occurrence.
+	isSeparator := func(r rune) bool { return !c.isWordChar(r) }
+	current := c.Editor.ReadUntil(-1, isSeparator) + c.Editor.ReadUntil(1, isSeparator)
+
+	words := make([]string, 0, len(c.index.counts))
+	for word, count := range c.index.counts {
+		if word == current && count <= 1 {
+			continue
+		}
+		words = append(words, word)
+	}
+	slices.Sort(words)
+
+	candidates := make([]gvcode.CompletionCandidate, 0, len(words))
+	for _, word := range words {
+		candidates = append(candidates, gvcode.CompletionCandidate{
+			Label:    word,
+			TextEdit: gvcode.TextEdit{NewText: word},
+			Kind:     "text",
+		})
+	}
+	return candidates
+}
+
+func (c *WordCompletor) FilterAndRank(pattern string, candidates []gvcode.CompletionCandidate) []gvcode.CompletionCandidate {
+	return FuzzyFilterAndRank(pattern, candidates)
+}
+
+func (c *WordCompletor) isWordChar(r rune) bool {
+	if c.IsWordChar != nil {
+		return c.IsWordChar(r)
+	}
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// wordIndex counts the words of the editor text by line.
+type wordIndex struct {
+	minLength  int
+	isWordChar func(r rune) bool
+
+	built   bool
+	version int
+	// length of the indexed text in runes.
+	length int
+	// lineStarts are the rune offsets of the lines in the indexed text.
+	lineStarts []int
+	// lines holds the words of each line.
+	lines  [][]string
+	counts map[string]int
+}
+
+// sync updates the index to the latest text of the editor.
+func (idx *wordIndex) sync(editor *gvcode.Editor) {
+	if !idx.built {
+		idx.rebuild(editor)
+		return
+	}
+
+	changes, ok := editor.ChangesSince(idx.version)
+	if !ok {
+		idx.rebuild(editor)
+		return
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	// Merge the changes into a single dirty region, which spans from start
+	// to oldEnd in the indexed text, and from start to newEnd in the latest text.
+	start, oldEnd, newEnd := changes[0].Start, changes[0].OldEnd, changes[0].NewEnd
+	for _, c := range changes[1:] {
+		start = min(start, c.Start)
+		if c.OldEnd > newEnd {
+			oldEnd += c.OldEnd - newEnd
+			newEnd = c.OldEnd
+		}
+		newEnd += c.NewEnd - c.OldEnd
+	}
+
+	// Re-index the whole lines covering the dirty region.
+	first, last := idx.lineOf(start), idx.lineOf(oldEnd)
+	regionStart := idx.lineStarts[first]
+	regionEnd := idx.length
+	if last+1 < len(idx.lineStarts) {
+		regionEnd = idx.lineStarts[last+1]
+	}
+	delta := newEnd - oldEnd
+
+	lines := strings.SplitAfter(editor.ReadRange(regionStart, regionEnd+delta), "\n")
+	if regionEnd < idx.length {
+		// The region ends with a line break, drop the empty string after it.
+		lines = lines[:len(lines)-1]
+	}
+
+	for _, words := range idx.lines[first : last+1] {
+		idx.remove(words)
+	}
+
+	lineStarts := make([]int, 0, len(lines))
+	lineWords := make([][]string, 0, len(lines))
+	offset := regionStart
+	for _, line := range lines {
+		lineStarts = append(lineStarts, offset)
+		offset += utf8.RuneCountInString(line)
+		words := idx.split(line)
+		idx.add(words)
+		lineWords = append(lineWords, words)
+	}
+
+	for i := last + 1; i < len(idx.lineStarts); i++ {
+		idx.lineStarts[i] += delta
+	}
+	idx.lineStarts = slices.Replace(idx.lineStarts, first, last+1, lineStarts...)
+	idx.lines = slices.Replace(idx.lines, first, last+1, lineWords...)
+	idx.length += delta
+	idx.version = editor.TextVersion()
+}
+
+func (idx *wordIndex) rebuild(editor *gvcode.Editor) {
+	idx.version = editor.TextVersion()
+	idx.built = true
+	idx.length = 0
+	idx.lineStarts = idx.lineStarts[:0]
+	idx.lines = idx.lines[:0]
+	idx.counts = make(map[string]int)
+
+	for _, line := range strings.SplitAfter(editor.Text(), "\n") {
+		idx.lineStarts = append(idx.lineStarts, idx.length)
+		idx.length += utf8.RuneCountInString(line)
+		words := idx.split(line)
+		idx.add(words)
+		idx.lines = append(idx.lines, words)
+	}
+}
+
+// lineOf returns the index of the line containing the rune offset.
+func (idx *wordIndex) lineOf(offset int) int {
+	i := sort.Search(len(idx.lineStarts), func(i int) bool { return idx.lineStarts[i] > offset })
+	return max(i-1, 0)
+}
+
+// split returns the words of the line. Words starting with a digit, like
+// numbers, are skipped.
+func (idx *wordIndex) split(line string) []string {
+	var words []string
+	for _, w := range strings.FieldsFunc(line, func(r rune) bool { return !idx.isWordChar(r) }) {
+		first, _ := utf8.DecodeRuneInString(w)
+		if unicode.IsDigit(first) || utf8.RuneCountInString(w) < idx.minLength {
+			continue
+		}
+		words = append(words, w)
+	}
+	return words
+}
+
+func (idx *wordIndex) add(words []string) {
+	for _, w := range words {
+		idx.counts[w]++
+	}
+}
+
+func (idx *wordIndex) remove(words []string) {
+	for _, w := range words {
+		if idx.counts[w] <= 1 {
+			delete(idx.counts, w)
+		} else {
+			idx.counts[w]--
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package completion
+
+import (
+	"maps"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/oligo/gvcode"
+)
+
+func TestWordCompletorSuggest(t *testing.T) {
+	editor := &gvcode.Editor{}
+	editor.SetText("func foo() {\n\tfooBar := 1\n\treturn fooBar + 42\n}\nfunc")
+	// the caret is at the first "func".
+	editor.SetCaret(0, 0)
+
+	c := NewWordCompletor(editor)
+	labels := []string{}
+	for _, cand := range c.Suggest(gvcode.CompletionContext{}) {
+		labels = append(labels, cand.Label)
+	}
+
+	expected := []string{"foo", "fooBar", "func", "return"}
+	if !slices.Equal(labels, expected) {
+		t.Fatalf("expected %v, got %v", expected, labels)
+	}
+
+	// The word being typed is not suggested if it is the only occurrence.
+	editor.SetText("unique foo foo")
+	editor.SetCaret(0, 0)
+	candidates := c.Suggest(gvcode.CompletionContext{})
+	if len(candidates) != 1 || candidates[0].Label != "foo" {
+		t.Fatalf("unexpected candidates: %v", candidates)
+	}
+}
+
+func TestWordIndexIncremental(t *testing.T) {
+	editor := &gvcode.Editor{}
+	editor.SetText("alpha beta\ngamma delta\n\nepsilon")
+
+	c := NewWordCompletor(editor)
+	c.Suggest(gvcode.CompletionContext{})
+
+	pieces := []string{"", "zeta", " ", "\n", "eta theta", "iota\nkappa", "x"}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		start := rnd.Intn(editor.Len() + 1)
+		end := min(start+rnd.Intn(6), editor.Len())
+		editor.SetCaret(start, end)
+		editor.Insert(pieces[rnd.Intn(len(pieces))])
+
+		if i%7 != 0 {
+			continue
+		}
+		c.Suggest(gvcode.CompletionContext{})
+
+		expected := wordIndex{minLength: c.index.minLength, isWordChar: c.isWordChar}
+		expected.rebuild(editor)
+		if !maps.Equal(c.index.counts, expected.counts) {
+			t.Fatalf("step %d: expected %v, got %v", i, expected.counts, c.index.counts)
+		}
+		if !slices.Equal(c.index.lineStarts, expected.lineStarts) || c.index.length != expected.length {
+			t.Fatalf("step %d: expected lines %v, got %v", i, expected.lineStarts, c.index.lineStarts)
+		}
+	}
+}
@@ -0,0 +1,501 @@
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gioui.org/f32"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/gutter"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// BlameProviderID is the unique identifier for the blame provider.
+	BlameProviderID = "blame"
+
+	// uncommittedHash is the commit hash git blame gives to the lines which
+	// are not committed yet.
+	uncommittedHash = "0000000000000000000000000000000000000000"
+
+	// blamePadding is the space around the annotations.
+	blamePadding = unit.Dp(6)
+)
+
+// BlameLine is the commit which last changed a line, as reported by git
+// blame.
+type BlameLine struct {
+	// Commit is the full hash of the commit.
+	Commit string
+	// Author is the name of the author of the commit.
+	Author string
+	// AuthorMail is the email of the author, without angle brackets.
+	AuthorMail string
+	// Time is the author time of the commit, in the author time zone.
+	Time time.Time
+	// Summary is the first line of the commit message.
+	Summary string
+}
+
+// Uncommitted reports whether the line is changed in the working tree, or
+// in the content blamed by Blame.
+func (l BlameLine) Uncommitted() bool {
+	return l.Commit == uncommittedHash
+}
+
+// BlameEvent is emitted when the annotation of a line is clicked.
+type BlameEvent struct {
+	// Line is the 0-based line number of the annotation.
+	Line int
+	// Commit is the full hash of the commit of the line.
+	Commit string
+}
+
+// BlameProvider shows the author and date of the commit which last changed
+// each line in a column of the gutter, as reported by `git blame
+// --porcelain`. The annotation is dimmed, and only shown on the first line
+// of the lines changed by the same commit. Clicking an annotation emits a
+// BlameEvent with the hash of the commit, for the host app to show it.
+//
+// The lines are blamed by Blame and cached. Follow shifts them along with the
+// edits of the editor, the edited lines becoming uncommitted until Blame is
+// called again with the new content, e.g., once the edits pause, or the file
+// is saved.
+type BlameProvider struct {
+	dir      string
+	filename string
+
+	lines []BlameLine
+	// texts are the lines of the blamed content, and lens their lengths in
+	// runes, line breaks included. They map the edits to the lines.
+	texts []string
+	lens  []int
+	// longest is the longest annotation, which sets the width of the column.
+	longest string
+	format  func(BlameLine) string
+	pending []BlameEvent
+}
+
+// NewBlameProvider creates a blame provider of the file at filePath. It
+// returns nil if git is not available, or the file is not in a git
+// repository.
+func NewBlameProvider(filePath string) *BlameProvider {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil
+	}
+
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil
+	}
+	dir := filepath.Dir(absPath)
+
+	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil || strings.TrimSpace(string(output)) != "true" {
+		return nil
+	}
+
+	return &BlameProvider{
+		dir:      dir,
+		filename: filepath.Base(absPath),
+		format:   defaultBlameFormat,
+	}
+}
+
+// defaultBlameFormat formats the annotation of a line as the date and the
+// author of its commit.
+func defaultBlameFormat(l BlameLine) string {
+	return l.Time.Format(time.DateOnly) + " " + l.Author
+}
+
+// SetFormat sets the function formatting the annotations. The uncommitted
+// lines have no annotation.
+func (p *BlameProvider) SetFormat(format func(BlameLine) string) {
+	if format == nil {
+		format = defaultBlameFormat
+	}
+	p.format = format
+	p.setLines(p.lines)
+}
+
+// Blame runs git blame on content, the lines changed from the HEAD being
+// uncommitted, or on the file in the working tree if content is nil. The
+// lines are cached until the next call.
+func (p *BlameProvider) Blame(content []byte) error {
+	args := []string{"blame", "--porcelain"}
+	if content != nil {
+		args = append(args, "--contents", "-")
+	}
+	cmd := exec.Command("git", append(args, "--", p.filename)...)
+	cmd.Dir = p.dir
+	if content != nil {
+		cmd.Stdin = bytes.NewReader(content)
+	} else {
+		var err error
+		if content, err = os.ReadFile(filepath.Join(p.dir, p.filename)); err != nil {
+			return err
+		}
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("git blame: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	lines, err := parseBlame(bytes.NewReader(output))
+	if err != nil {
+		return err
+	}
+	p.setContent(content)
+	p.setLines(lines)
+	return nil
+}
+
+// Follow shifts the cached lines along with the edits of editor, which shows
+// the blamed content, until the returned function is called. The lines
+// changed by the edits become uncommitted.
+func (p *BlameProvider) Follow(editor *gvcode.Editor) (stop func()) {
+	return editor.OnEdit(p.applyEdit)
+}
+
+// setContent splits the blamed content into lines.
+func (p *BlameProvider) setContent(content []byte) {
+	p.texts = strings.Split(string(content), "\n")
+	p.lens = make([]int, len(p.texts))
+	for i, line := range p.texts {
+		p.lens[i] = utf8.RuneCountInString(line) + 1
+	}
+	// the last line has no line break.
+	p.lens[len(p.lens)-1]--
+}
+
+// lineAt returns the line and the column of the rune offset off of the
+// content.
+func (p *BlameProvider) lineAt(off int) (line, col int, ok bool) {
+	for i, n := range p.lens {
+		if off < n || (i == len(p.lens)-1 && off == n) {
+			return i, off, true
+		}
+		off -= n
+	}
+	return 0, 0, false
+}
+
+// applyEdit replaces the blames of the lines changed by delta with
+// uncommitted lines, keeping the blames of the first and the last line if
+// their text is unchanged. The cache is cleared if the edit is out of the
+// content, as it no longer matches the text of the editor.
+func (p *BlameProvider) applyEdit(delta gvcode.EditDelta) {
+	if p.texts == nil {
+		return
+	}
+	startLine, startCol, ok1 := p.lineAt(delta.Start)
+	endLine, endCol, ok2 := p.lineAt(delta.OldEnd)
+	if !ok1 || !ok2 || endLine < startLine {
+		p.texts, p.lens = nil, nil
+		p.setLines(nil)
+		return
+	}
+
+	oldTexts := p.texts[startLine : endLine+1]
+	merged := string([]rune(oldTexts[0])[:startCol]) + delta.Text + string([]rune(oldTexts[len(oldTexts)-1])[endCol:])
+	texts := strings.Split(merged, "\n")
+	lens := make([]int, len(texts))
+	for i, line := range texts {
+		lens[i] = utf8.RuneCountInString(line) + 1
+	}
+	if endLine == len(p.lens)-1 {
+		lens[len(lens)-1]--
+	}
+
+	// the uncommitted lines past the blamed ones keep the lines aligned.
+	lines := p.lines
+	for len(lines) < len(p.texts) {
+		lines = append(lines, BlameLine{Commit: uncommittedHash})
+	}
+	blames := make([]BlameLine, len(texts))
+	for i := range blames {
+		blames[i] = BlameLine{Commit: uncommittedHash}
+	}
+	keepFirst := texts[0] == oldTexts[0]
+	if keepFirst {
+		blames[0] = lines[startLine]
+	}
+	if last := len(texts) - 1; (last > 0 || !keepFirst) && texts[last] == oldTexts[len(oldTexts)-1] {
+		blames[last] = lines[endLine]
+	}
+
+	p.texts = slices.Replace(p.texts, startLine, endLine+1, texts...)
+	p.lens = slices.Replace(p.lens, startLine, endLine+1, lens...)
+	p.setLines(slices.Replace(lines, startLine, endLine+1, blames...))
+}
+
+// Lines returns the cached lines, indexed by their 0-based line number.
+func (p *BlameProvider) Lines() []BlameLine {
+	return p.lines
+}
+
+// Line returns the cached blame of the 0-based line.
+func (p *BlameProvider) Line(line int) (BlameLine, bool) {
+	if line < 0 || line >= len(p.lines) {
+		return BlameLine{}, false
+	}
+	return p.lines[line], true
+}
+
+// GetPendingEvents returns the pending blame events and clears the pending
+// list.
+func (p *BlameProvider) GetPendingEvents() []BlameEvent {
+	events := p.pending
+	p.pending = nil
+	return events
+}
+
+func (p *BlameProvider) setLines(lines []BlameLine) {
+	p.lines = lines
+	p.longest = ""
+	for i := range lines {
+		if s := p.annotation(i); len(s) > len(p.longest) {
+			p.longest = s
+		}
+	}
+}
+
+// annotation returns the annotation of the 0-based line, empty if the line
+// continues the lines of the same commit.
+func (p *BlameProvider) annotation(line int) string {
+	l := p.lines[line]
+	if l.Uncommitted() || (line > 0 && p.lines[line-1].Commit == l.Commit) {
+		return ""
+	}
+	return p.format(l)
+}
+
+// ID returns the unique identifier for this provider.
+func (p *BlameProvider) ID() string {
+	return BlameProviderID
+}
+
+// Priority returns the rendering priority. The annotations are rendered
+// leftmost, after the heatmap.
+func (p *BlameProvider) Priority() int {
+	return 300
+}
+
+// Width returns the width of the longest annotation.
+func (p *BlameProvider) Width(gtx layout.Context, shaper *text.Shaper, params text.Parameters, lineCount int) unit.Dp {
+	if p.longest == "" {
+		return 0
+	}
+	params.MinWidth = 0
+	shaper.LayoutString(params, p.longest)
+	var width fixed.Int26_6
+	for {
+		g, ok := shaper.NextGlyph()
+		if !ok {
+			break
+		}
+		width += g.Advance
+	}
+	return unit.Dp(float32(width.Ceil())/gtx.Metric.PxPerDp) + 2*blamePadding
+}
+
+// Layout renders the dimmed annotations of the visible lines.
+func (p *BlameProvider) Layout(gtx layout.Context, ctx gutter.GutterContext) layout.Dimensions {
+	dims := layout.Dimensions{Size: image.Point{X: gtx.Constraints.Max.X, Y: gtx.Constraints.Max.Y}}
+	if len(p.lines) == 0 || ctx.Colors == nil {
+		return dims
+	}
+
+	params := ctx.TextParams
+	params.Alignment = text.Start
+	params.MinWidth = 0
+	params.MaxWidth = max(gtx.Constraints.Max.X-gtx.Dp(blamePadding), 0)
+	params.MaxLines = 1
+
+	c := ctx.Colors.Text.MulAlpha(0x90)
+	material := op.Record(gtx.Ops)
+	paint.ColorOp{Color: c.NRGBA()}.Add(gtx.Ops)
+	textMaterial := material.Stop()
+
+	padding := gtx.Dp(blamePadding)
+	var glyphs []text.Glyph
+	first := true
+	for _, para := range ctx.Paragraphs {
+		if para.EndY < ctx.Viewport.Min.Y {
+			continue
+		}
+		if para.StartY > ctx.Viewport.Max.Y {
+			break
+		}
+		if para.Index >= len(p.lines) {
+			break
+		}
+
+		s := p.annotation(para.Index)
+		// repeat the annotation on the first visible line of a commit.
+		if s == "" && first && !p.lines[para.Index].Uncommitted() {
+			s = p.format(p.lines[para.Index])
+		}
+		first = false
+		if s == "" {
+			continue
+		}
+
+		ctx.Shaper.LayoutString(params, s)
+		glyphs = glyphs[:0]
+		for {
+			g, ok := ctx.Shaper.NextGlyph()
+			if !ok {
+				break
+			}
+			glyphs = append(glyphs, g)
+		}
+		if len(glyphs) == 0 {
+			continue
+		}
+
+		yPos := float32(para.StartY - ctx.Viewport.Min.Y)
+		trans := op.Affine(f32.Affine2D{}.Offset(f32.Point{X: float32(padding), Y: yPos})).Push(gtx.Ops)
+		outline := clip.Outline{Path: ctx.Shaper.Shape(glyphs)}.Op().Push(gtx.Ops)
+		textMaterial.Add(gtx.Ops)
+		paint.PaintOp{}.Add(gtx.Ops)
+		outline.Pop()
+		trans.Pop()
+	}
+
+	return dims
+}
+
+// HandleClick implements the gutter.InteractiveGutter interface. It emits a
+// BlameEvent if the line is committed.
+func (p *BlameProvider) HandleClick(line int, source pointer.Source, numClicks int, modifiers key.Modifiers) bool {
+	l, ok := p.Line(line)
+	if !ok || l.Uncommitted() {
+		return false
+	}
+	p.pending = append(p.pending, BlameEvent{Line: line, Commit: l.Commit})
+	return true
+}
+
+// HandleHover implements the gutter.InteractiveGutter interface. It shows
+// the commit of the line.
+func (p *BlameProvider) HandleHover(line int) *gutter.HoverInfo {
+	l, ok := p.Line(line)
+	if !ok || l.Uncommitted() {
+		return nil
+	}
+	return &gutter.HoverInfo{
+		Text: fmt.Sprintf("%.8s %s <%s>, %s\n%s", l.Commit, l.Author, l.AuthorMail, l.Time.Format(time.DateTime), l.Summary),
+	}
+}
+
+// parseBlame parses the output of git blame --porcelain. The details of a
+// commit are only given with its first group of lines.
+func parseBlame(r io.Reader) ([]BlameLine, error) {
+	commits := make(map[string]*BlameLine)
+	var lines []BlameLine
+	var current *BlameLine
+	var line int
+	var authorTime int64
+	var authorTZ string
+
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1024*1024)
+	for s.Scan() {
+		text := s.Text()
+		if strings.HasPrefix(text, "\t") {
+			// the content of the line ends its entry.
+			if current == nil {
+				return nil, errors.New("git blame: line content without header")
+			}
+			if authorTime != 0 {
+				current.Time = time.Unix(authorTime, 0).In(parseTZ(authorTZ))
+				authorTime, authorTZ = 0, ""
+			}
+			for len(lines) <= line {
+				lines = append(lines, BlameLine{})
+			}
+			lines[line] = *current
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			// the header: <hash> <original line> <final line> [<group size>]
+			fields := strings.Fields(text)
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("git blame: invalid header %q", text)
+			}
+			final, err := strconv.Atoi(fields[2])
+			if err != nil || final < 1 {
+				return nil, fmt.Errorf("git blame: invalid header %q", text)
+			}
+			line = final - 1
+			current = commits[fields[0]]
+			if current == nil {
+				current = &BlameLine{Commit: fields[0]}
+				commits[fields[0]] = current
+			}
+			continue
+		}
+
+		key, value, _ := strings.Cut(text, " ")
+		switch key {
+		case "author":
+			current.Author = value
+		case "author-mail":
+			current.AuthorMail = strings.TrimSuffix(strings.TrimPrefix(value, "<"), ">")
+		case "author-time":
+			authorTime, _ = strconv.ParseInt(value, 10, 64)
+		case "author-tz":
+			authorTZ = value
+		case "summary":
+			current.Summary = value
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// parseTZ returns the location of a git time zone, e.g., "+0200".
+func parseTZ(tz string) *time.Location {
+	if len(tz) != 5 || (tz[0] != '+' && tz[0] != '-') {
+		return time.UTC
+	}
+	hours, err1 := strconv.Atoi(tz[1:3])
+	minutes, err2 := strconv.Atoi(tz[3:5])
+	if err1 != nil || err2 != nil {
+		return time.UTC
+	}
+	offset := hours*3600 + minutes*60
+	if tz[0] == '-' {
+		offset = -offset
+	}
+	return time.FixedZone(tz, offset)
+}
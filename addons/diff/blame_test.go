@@ -0,0 +1,204 @@
+package diff
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gioui.org/io/pointer"
+	"github.com/oligo/gvcode/internal/editortest"
+)
+
+const blameOutput = `1111111111111111111111111111111111111111 1 1 2
+author Alice
+author-mail <alice@example.com>
+author-time 1700000000
+author-tz +0200
+committer Alice
+committer-mail <alice@example.com>
+committer-time 1700000000
+committer-tz +0200
+summary Add the file
+boundary
+filename main.go
+	package main
+1111111111111111111111111111111111111111 2 2
+	import "fmt"
+0000000000000000000000000000000000000000 3 3 1
+author Not Committed Yet
+author-mail <not.committed.yet>
+author-time 1700001000
+author-tz +0000
+committer Not Committed Yet
+committer-mail <not.committed.yet>
+committer-time 1700001000
+committer-tz +0000
+summary Version of main.go from -
+previous 1111111111111111111111111111111111111111 main.go
+filename main.go
+	func main() {}
+1111111111111111111111111111111111111111 3 4 1
+	// end
+`
+
+func TestParseBlame(t *testing.T) {
+	lines, err := parseBlame(strings.NewReader(blameOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4", len(lines))
+	}
+
+	first := lines[0]
+	if first.Author != "Alice" || first.AuthorMail != "alice@example.com" || first.Summary != "Add the file" {
+		t.Errorf("got %+v, want the details of the commit", first)
+	}
+	if got := first.Time.Format("2006-01-02 15:04 -0700"); got != "2023-11-15 00:13 +0200" {
+		t.Errorf("got time %s, want the author time in its zone", got)
+	}
+	if lines[1] != first || lines[3] != first {
+		t.Errorf("got %+v and %+v, want the details of the first group", lines[1], lines[3])
+	}
+	if !lines[2].Uncommitted() || lines[0].Uncommitted() {
+		t.Errorf("got line 2 %+v, want it uncommitted", lines[2])
+	}
+
+	if _, err := parseBlame(strings.NewReader("invalid\n\tline\n")); err == nil {
+		t.Error("parsed an invalid header")
+	}
+}
+
+func TestBlameProviderAnnotations(t *testing.T) {
+	lines, err := parseBlame(strings.NewReader(blameOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &BlameProvider{format: defaultBlameFormat}
+	p.setLines(lines)
+
+	want := []string{"2023-11-15 Alice", "", "", "2023-11-15 Alice"}
+	for i, w := range want {
+		if got := p.annotation(i); got != w {
+			t.Errorf("line %d: got annotation %q, want %q", i, got, w)
+		}
+	}
+	if p.longest != want[0] {
+		t.Errorf("got longest annotation %q, want %q", p.longest, want[0])
+	}
+
+	if p.HandleClick(2, pointer.Mouse, 1, 0) {
+		t.Error("handled the click of an uncommitted line")
+	}
+	if !p.HandleClick(1, pointer.Mouse, 1, 0) {
+		t.Fatal("the click of a committed line is not handled")
+	}
+	events := p.GetPendingEvents()
+	if len(events) != 1 || events[0].Line != 1 || events[0].Commit != lines[0].Commit {
+		t.Errorf("got events %+v, want the commit of line 1", events)
+	}
+	if len(p.GetPendingEvents()) != 0 {
+		t.Error("the events are not cleared")
+	}
+}
+
+func TestBlameProviderFollow(t *testing.T) {
+	lines, err := parseBlame(strings.NewReader(blameOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "package main\nimport \"fmt\"\nfunc main() {}\n// end\n"
+	p := &BlameProvider{format: defaultBlameFormat}
+	p.setContent([]byte(content))
+	p.setLines(lines)
+
+	editor := editortest.NewEditor(t, content)
+	stop := p.Follow(editor)
+	commits := func() string {
+		var b strings.Builder
+		for _, l := range p.Lines() {
+			if l.Uncommitted() {
+				b.WriteByte('u')
+			} else {
+				b.WriteByte('c')
+			}
+		}
+		return b.String()
+	}
+
+	// an inserted line shifts the lines below it.
+	editor.SetCaret(13, 13)
+	editor.Insert("x\n")
+	if got := commits(); got != "cucucu" {
+		t.Fatalf("got lines %q after inserting a line, want the lines below shifted", got)
+	}
+	if l, _ := p.Line(2); l != lines[1] {
+		t.Errorf("got line 2 %+v, want the blame of the import line", l)
+	}
+
+	// a line break at the end of a line keeps its blame.
+	editor.SetCaret(12, 12)
+	editor.Insert("\n")
+	if got := commits(); got != "cuucucu" {
+		t.Fatalf("got lines %q after breaking a line, want the new line uncommitted", got)
+	}
+
+	// an edited line becomes uncommitted, and a deleted one is gone.
+	editor.SetCaret(0, 0)
+	editor.Insert("// ")
+	editor.SetCaret(15, 16)
+	editor.Delete(1)
+	if got := commits(); got != "uucucu" {
+		t.Fatalf("got lines %q after the edits, want the edited line uncommitted", got)
+	}
+
+	stop()
+	editor.SetCaret(0, 0)
+	editor.Insert("\n")
+	if got := commits(); got != "uucucu" {
+		t.Errorf("got lines %q after stopping, want them kept", got)
+	}
+}
+
+func TestBlameProviderGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Bob", "-c", "user.email=bob@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("a\nb\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "a.txt")
+	git("commit", "-q", "-m", "first")
+
+	p := NewBlameProvider(path)
+	if p == nil {
+		t.Fatal("no blame provider in a git repository")
+	}
+	if err := p.Blame([]byte("a\nx\nb\n")); err != nil {
+		t.Fatal(err)
+	}
+	lines := p.Lines()
+	if len(lines) != 3 || lines[0].Author != "Bob" || lines[0].Summary != "first" || !lines[1].Uncommitted() {
+		t.Fatalf("got lines %+v, want the inserted line uncommitted", lines)
+	}
+
+	if err := p.Blame(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Lines()) != 2 {
+		t.Errorf("got %d lines of the file, want 2", len(p.Lines()))
+	}
+}
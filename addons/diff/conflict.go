@@ -0,0 +1,386 @@
+package diff
+
+import (
+	"image"
+	"strings"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"github.com/oligo/gvcode"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/gutter"
+)
+
+// conflictLinesProviderID is the ID of the gutter provider painting the
+// blocks of the conflicts.
+const conflictLinesProviderID = "conflict-lines"
+
+// Conflict is a merge conflict region of a document, delimited by the
+// markers written by git. The line numbers are 0-based.
+type Conflict struct {
+	// Start is the line of the <<<<<<< marker.
+	Start int
+	// Base is the line of the ||||||| marker of the diff3 conflict style,
+	// or -1 if there is none.
+	Base int
+	// Separator is the line of the ======= marker.
+	Separator int
+	// End is the line of the >>>>>>> marker.
+	End int
+	// OursLabel and TheirsLabel are the texts following the start and end
+	// markers, e.g., HEAD and the name of the merged branch.
+	OursLabel, TheirsLabel string
+}
+
+// Ours returns the first line and the number of lines of our side.
+func (c Conflict) Ours() (start, count int) {
+	end := c.Separator
+	if c.Base >= 0 {
+		end = c.Base
+	}
+	return c.Start + 1, end - c.Start - 1
+}
+
+// Theirs returns the first line and the number of lines of their side.
+func (c Conflict) Theirs() (start, count int) {
+	return c.Separator + 1, c.End - c.Separator - 1
+}
+
+// FindConflicts returns the conflict regions of the lines, in order. The
+// markers start a line, and are made of 7 characters followed by a space or
+// the end of the line. Unterminated regions are ignored, as are the regions
+// starting inside another one.
+func FindConflicts(lines []string) []Conflict {
+	var conflicts []Conflict
+	var current *Conflict
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case isConflictMarker(line, '<'):
+			current = &Conflict{Start: i, Base: -1, Separator: -1, OursLabel: markerLabel(line)}
+		case current == nil:
+		case isConflictMarker(line, '|') && current.Base < 0 && current.Separator < 0:
+			current.Base = i
+		case line == "=======" && current.Separator < 0:
+			current.Separator = i
+		case isConflictMarker(line, '>') && current.Separator >= 0:
+			current.End = i
+			current.TheirsLabel = markerLabel(line)
+			conflicts = append(conflicts, *current)
+			current = nil
+		}
+	}
+	return conflicts
+}
+
+// isConflictMarker reports whether the line starts with a conflict marker
+// made of c.
+func isConflictMarker(line string, c byte) bool {
+	if len(line) < 7 || strings.Count(line[:7], string(c)) != 7 {
+		return false
+	}
+	return len(line) == 7 || line[7] == ' '
+}
+
+// markerLabel returns the text following a conflict marker.
+func markerLabel(line string) string {
+	return strings.TrimSpace(line[7:])
+}
+
+// ConflictResolution is the way a conflict is resolved.
+type ConflictResolution int
+
+const (
+	// AcceptOurs keeps our side of the conflict.
+	AcceptOurs ConflictResolution = iota
+	// AcceptTheirs keeps their side of the conflict.
+	AcceptTheirs
+	// AcceptBoth keeps our side followed by their side.
+	AcceptBoth
+)
+
+func (r ConflictResolution) String() string {
+	switch r {
+	case AcceptOurs:
+		return "Accept Ours"
+	case AcceptTheirs:
+		return "Accept Theirs"
+	case AcceptBoth:
+		return "Accept Both"
+	default:
+		return "Unknown"
+	}
+}
+
+// ConflictEvent is emitted when a conflict is resolved by its actions.
+type ConflictEvent struct {
+	Resolution ConflictResolution
+	// Conflict is the resolved conflict, which is no longer in the
+	// document.
+	Conflict Conflict
+}
+
+// ConflictColors defines the color scheme of the ConflictResolver.
+type ConflictColors struct {
+	// Ours and Theirs are the backgrounds of the lines of each side.
+	Ours, Theirs gvcolor.Color
+	// OursMarker and TheirsMarker are the backgrounds of the start and end
+	// markers.
+	OursMarker, TheirsMarker gvcolor.Color
+	// Base is the background of the base lines of the diff3 conflict style,
+	// and their marker.
+	Base gvcolor.Color
+	// Action is the color of the labels of the actions.
+	Action gvcolor.Color
+}
+
+// DefaultConflictColors returns the default color scheme of the
+// ConflictResolver.
+func DefaultConflictColors() ConflictColors {
+	ours, _ := gvcolor.Hex2Color("#40C8AE33")
+	oursMarker, _ := gvcolor.Hex2Color("#40C8AE80")
+	theirs, _ := gvcolor.Hex2Color("#40A6FF33")
+	theirsMarker, _ := gvcolor.Hex2Color("#40A6FF80")
+	base, _ := gvcolor.Hex2Color("#8080802A")
+	action, _ := gvcolor.Hex2Color("#AAAAAA")
+
+	return ConflictColors{
+		Ours:         ours,
+		Theirs:       theirs,
+		OursMarker:   oursMarker,
+		TheirsMarker: theirsMarker,
+		Base:         base,
+		Action:       action,
+	}
+}
+
+// ConflictResolver highlights the merge conflict regions of the text of an
+// editor: our side and their side have distinct backgrounds, and a line
+// above each region holds the Accept Ours, Accept Theirs and Accept Both
+// actions, like the code lenses of other editors. An action replaces the
+// region, markers included, with the kept lines as a single undo step, and
+// emits a ConflictEvent.
+//
+// The regions are detected again whenever the text changes. The action lines
+// are phantom blocks, shown along with the ones set by other addons.
+type ConflictResolver struct {
+	// Editor shows the document with the conflicts.
+	Editor *gvcode.Editor
+
+	// Colors defines the color scheme.
+	Colors ConflictColors
+
+	// TextSize is the size of the labels of the actions.
+	TextSize unit.Sp
+
+	// text is the text the conflicts are detected in, at version.
+	text      string
+	version   int
+	scanned   bool
+	conflicts []Conflict
+	// blocks are the phantom blocks of the action lines.
+	blocks  []gvcode.PhantomBlock
+	actions []conflictActions
+	lines   *changedLines
+	pending []ConflictEvent
+}
+
+// conflictActions are the actions of a conflict.
+type conflictActions [3]widget.Clickable
+
+// NewConflictResolver creates a ConflictResolver of the conflicts of the
+// text of editor. A gutter provider painting the conflict lines is
+// registered in the editor.
+func NewConflictResolver(editor *gvcode.Editor) *ConflictResolver {
+	r := &ConflictResolver{
+		Editor:   editor,
+		Colors:   DefaultConflictColors(),
+		TextSize: unit.Sp(12),
+		lines:    &changedLines{id: conflictLinesProviderID},
+	}
+	editor.WithOptions(gvcode.WithGutter(r.lines))
+	r.refresh()
+	return r
+}
+
+// Conflicts returns the conflict regions of the text.
+func (r *ConflictResolver) Conflicts() []Conflict {
+	r.refresh()
+	return r.conflicts
+}
+
+// Resolve replaces the conflict region of index idx with the lines kept by
+// resolution, as a single undo step. It reports whether the conflict exists
+// and the editor is editable.
+func (r *ConflictResolver) Resolve(idx int, resolution ConflictResolution) bool {
+	r.refresh()
+	if idx < 0 || idx >= len(r.conflicts) {
+		return false
+	}
+	c := r.conflicts[idx]
+	lines := strings.Split(r.text, "\n")
+	oursStart, oursCount := c.Ours()
+	theirsStart, theirsCount := c.Theirs()
+
+	var kept []string
+	switch resolution {
+	case AcceptOurs:
+		kept = lines[oursStart : oursStart+oursCount]
+	case AcceptTheirs:
+		kept = lines[theirsStart : theirsStart+theirsCount]
+	case AcceptBoth:
+		kept = append(append(kept, lines[oursStart:oursStart+oursCount]...), lines[theirsStart:theirsStart+theirsCount]...)
+	default:
+		return false
+	}
+
+	if !replaceLines(r.Editor, c.Start, c.End-c.Start+1, kept) {
+		return false
+	}
+	r.refresh()
+	return true
+}
+
+// Close removes the highlights, the action lines and the gutter provider
+// added to the editor. The phantom blocks of other addons are kept.
+func (r *ConflictResolver) Close() {
+	r.setBlocks(nil)
+	if m := r.Editor.GetGutterManager(); m != nil {
+		m.Unregister(conflictLinesProviderID)
+	}
+	r.conflicts, r.actions, r.scanned = nil, nil, false
+}
+
+// Update detects the conflicts again if the text changed, applies the
+// clicked actions, and returns the events of the actions.
+func (r *ConflictResolver) Update(gtx layout.Context) (ConflictEvent, bool) {
+	r.refresh()
+outer:
+	for i := range r.actions {
+		for j := range r.actions[i] {
+			if !r.actions[i][j].Clicked(gtx) {
+				continue
+			}
+			c, resolution := r.conflicts[i], ConflictResolution(j)
+			if r.Resolve(i, resolution) {
+				r.pending = append(r.pending, ConflictEvent{Resolution: resolution, Conflict: c})
+			}
+			break outer
+		}
+	}
+
+	if len(r.pending) > 0 {
+		evt := r.pending[0]
+		r.pending = r.pending[1:]
+		return evt, true
+	}
+	return ConflictEvent{}, false
+}
+
+// Layout lays out the editor, with the actions of the conflicts over it.
+func (r *ConflictResolver) Layout(gtx layout.Context, shaper *text.Shaper) layout.Dimensions {
+	for {
+		if _, ok := r.Update(gtx); !ok {
+			break
+		}
+	}
+
+	dims := r.Editor.Layout(gtx, shaper)
+	r.layoutActions(gtx, shaper)
+	if r.refresh() {
+		gtx.Execute(op.InvalidateCmd{})
+	}
+	return dims
+}
+
+// refresh detects the conflicts again if the text changed since the last
+// scan. It reports whether the conflicts are detected.
+func (r *ConflictResolver) refresh() bool {
+	version := r.Editor.TextVersion()
+	if r.scanned && version == r.version {
+		return false
+	}
+	r.scanned, r.version, r.text = true, version, r.Editor.Text()
+	r.conflicts = FindConflicts(strings.Split(r.text, "\n"))
+	r.actions = make([]conflictActions, len(r.conflicts))
+
+	r.lines.lines = r.lines.lines[:0]
+	highlight := func(start, count int, c gvcolor.Color) {
+		for i := range count {
+			r.lines.lines = append(r.lines.lines, gutter.LineHighlight{Line: start + i, Color: c})
+		}
+	}
+	blocks := make([]gvcode.PhantomBlock, 0, len(r.conflicts))
+	for _, c := range r.conflicts {
+		oursStart, oursCount := c.Ours()
+		theirsStart, theirsCount := c.Theirs()
+		highlight(c.Start, 1, r.Colors.OursMarker)
+		highlight(oursStart, oursCount, r.Colors.Ours)
+		if c.Base >= 0 {
+			highlight(c.Base, c.Separator-c.Base, r.Colors.Base)
+		}
+		highlight(theirsStart, theirsCount, r.Colors.Theirs)
+		highlight(c.End, 1, r.Colors.TheirsMarker)
+		blocks = append(blocks, gvcode.PhantomBlock{Line: c.Start, Lines: []string{""}})
+	}
+	r.setBlocks(blocks)
+	return true
+}
+
+// setBlocks replaces the phantom blocks of the action lines with blocks,
+// keeping the blocks set by others.
+func (r *ConflictResolver) setBlocks(blocks []gvcode.PhantomBlock) {
+	r.blocks = replacePhantomBlocks(r.Editor, r.blocks, blocks)
+}
+
+// layoutActions lays out the actions of the visible conflicts in the line
+// above them.
+func (r *ConflictResolver) layoutActions(gtx layout.Context, shaper *text.Shaper) {
+	defer clip.Rect(image.Rectangle{Max: gtx.Constraints.Max}).Push(gtx.Ops).Pop()
+	scrollY := r.Editor.ScrollOffset().Y
+	for i, c := range r.conflicts {
+		top, bottom, ok := r.Editor.PhantomBlockBounds(c.Start)
+		if !ok || top-scrollY > gtx.Constraints.Max.Y || bottom-scrollY < 0 {
+			continue
+		}
+
+		stack := op.Offset(image.Pt(r.Editor.GutterWidth(), top-scrollY)).Push(gtx.Ops)
+		gtx := gtx
+		gtx.Constraints.Min = image.Point{}
+		gtx.Constraints.Max.Y = bottom - top
+		layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return r.layoutAction(gtx, shaper, &r.actions[i][AcceptOurs], AcceptOurs.String())
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return r.layoutAction(gtx, shaper, &r.actions[i][AcceptTheirs], AcceptTheirs.String())
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return r.layoutAction(gtx, shaper, &r.actions[i][AcceptBoth], AcceptBoth.String())
+			}),
+		)
+		stack.Pop()
+	}
+}
+
+// layoutAction lays out a clickable label.
+func (r *ConflictResolver) layoutAction(gtx layout.Context, shaper *text.Shaper, btn *widget.Clickable, label string) layout.Dimensions {
+	gtx.Constraints.Min = image.Point{}
+	return btn.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Inset{Left: unit.Dp(4), Right: unit.Dp(4)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			c := r.Colors.Action
+			if !btn.Hovered() {
+				c = c.MulAlpha(0xA0)
+			}
+			textMaterial := op.Record(gtx.Ops)
+			paint.ColorOp{Color: c.NRGBA()}.Add(gtx.Ops)
+			return widget.Label{MaxLines: 1}.Layout(gtx, shaper, font.Font{}, r.TextSize, label, textMaterial.Stop())
+		})
+	})
+}
@@ -0,0 +1,177 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/internal/editortest"
+)
+
+const conflictText = `a
+<<<<<<< HEAD
+ours
+=======
+theirs 1
+theirs 2
+>>>>>>> feature
+b
+<<<<<<< HEAD
+x
+||||||| base
+y
+=======
+z
+>>>>>>> feature
+`
+
+func TestFindConflicts(t *testing.T) {
+	conflicts := FindConflicts(strings.Split(conflictText, "\n"))
+	want := []Conflict{
+		{Start: 1, Base: -1, Separator: 3, End: 6, OursLabel: "HEAD", TheirsLabel: "feature"},
+		{Start: 8, Base: 10, Separator: 12, End: 14, OursLabel: "HEAD", TheirsLabel: "feature"},
+	}
+	if len(conflicts) != len(want) {
+		t.Fatalf("got conflicts %+v, want %+v", conflicts, want)
+	}
+	for i := range want {
+		if conflicts[i] != want[i] {
+			t.Errorf("got conflict %+v, want %+v", conflicts[i], want[i])
+		}
+	}
+
+	if start, count := conflicts[0].Theirs(); start != 4 || count != 2 {
+		t.Errorf("got their side %d+%d, want 4+2", start, count)
+	}
+	if start, count := conflicts[1].Ours(); start != 9 || count != 1 {
+		t.Errorf("got our side %d+%d, want 9+1", start, count)
+	}
+
+	for _, text := range []string{
+		"<<<<<<< HEAD\na\n=======\nb\n",             // unterminated
+		"<<<<<<<< HEAD\na\n=======\nb\n>>>>>>> x\n", // not a marker
+		"<<<<<<< HEAD\na\n>>>>>>> x\n",              // no separator
+	} {
+		if got := FindConflicts(strings.Split(text, "\n")); len(got) != 0 {
+			t.Errorf("got conflicts %+v in %q, want none", got, text)
+		}
+	}
+
+	// a region starting inside another one replaces it.
+	got := FindConflicts(strings.Split("<<<<<<< a\n<<<<<<< b\nx\n=======\r\ny\n>>>>>>> c\r\n", "\n"))
+	if len(got) != 1 || got[0].Start != 1 || got[0].OursLabel != "b" || got[0].TheirsLabel != "c" {
+		t.Errorf("got conflicts %+v, want the inner region", got)
+	}
+}
+
+func newConflictResolver(t *testing.T, content string) *ConflictResolver {
+	t.Helper()
+	r := NewConflictResolver(editortest.NewEditor(t, content))
+	r.Layout(editortest.NewContext(), editortest.NewShaper())
+	return r
+}
+
+func TestConflictResolverHighlights(t *testing.T) {
+	r := newConflictResolver(t, conflictText)
+
+	blocks := r.Editor.PhantomBlocks()
+	if len(blocks) != 2 || blocks[0].Line != 1 || blocks[1].Line != 8 {
+		t.Fatalf("got phantom blocks %+v, want the action lines above lines 1 and 8", blocks)
+	}
+
+	colors := make(map[int]string)
+	for _, hl := range r.lines.HighlightedLines() {
+		switch hl.Color {
+		case r.Colors.OursMarker:
+			colors[hl.Line] = "ours marker"
+		case r.Colors.Ours:
+			colors[hl.Line] = "ours"
+		case r.Colors.Theirs:
+			colors[hl.Line] = "theirs"
+		case r.Colors.TheirsMarker:
+			colors[hl.Line] = "theirs marker"
+		case r.Colors.Base:
+			colors[hl.Line] = "base"
+		}
+	}
+	want := map[int]string{
+		1: "ours marker", 2: "ours", 4: "theirs", 5: "theirs", 6: "theirs marker",
+		8: "ours marker", 9: "ours", 10: "base", 11: "base", 13: "theirs", 14: "theirs marker",
+	}
+	for line, w := range want {
+		if colors[line] != w {
+			t.Errorf("line %d: got %q background, want %q", line, colors[line], w)
+		}
+	}
+	if len(colors) != len(want) {
+		t.Errorf("got backgrounds %v, want %v", colors, want)
+	}
+}
+
+func TestConflictResolverResolve(t *testing.T) {
+	cases := []struct {
+		resolution ConflictResolution
+		want       string
+	}{
+		{AcceptOurs, "a\nours\nb\n"},
+		{AcceptTheirs, "a\ntheirs 1\ntheirs 2\nb\n"},
+		{AcceptBoth, "a\nours\ntheirs 1\ntheirs 2\nb\n"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.resolution.String(), func(t *testing.T) {
+			r := newConflictResolver(t, conflictText)
+			if !r.Resolve(0, tc.resolution) {
+				t.Fatal("resolve failed")
+			}
+			got := r.Editor.Text()
+			if !strings.HasPrefix(got, tc.want) {
+				t.Fatalf("got %q after the resolution, want it to start with %q", got, tc.want)
+			}
+
+			conflicts := r.Conflicts()
+			if len(conflicts) != 1 || conflicts[0].Start != strings.Count(tc.want, "\n") {
+				t.Errorf("got conflicts %+v, want the second one moved", conflicts)
+			}
+			if blocks := r.Editor.PhantomBlocks(); len(blocks) != 1 {
+				t.Errorf("got %d phantom blocks, want 1", len(blocks))
+			}
+		})
+	}
+
+	r := newConflictResolver(t, conflictText)
+	if r.Resolve(2, AcceptOurs) {
+		t.Error("resolved a missing conflict")
+	}
+	if !r.Resolve(1, AcceptTheirs) || !r.Resolve(0, AcceptOurs) {
+		t.Fatal("resolve failed")
+	}
+	if got, want := r.Editor.Text(), "a\nours\nb\nz\n"; got != want {
+		t.Errorf("got %q after resolving all conflicts, want %q", got, want)
+	}
+}
+
+func TestConflictResolverClose(t *testing.T) {
+	r := newConflictResolver(t, conflictText)
+	other := gvcode.PhantomBlock{Line: 3, Lines: []string{"deleted"}}
+	r.Editor.SetPhantomBlocks(append(r.Editor.PhantomBlocks(), other)...)
+
+	// the conflicts are detected again only after an edit.
+	if r.refresh() {
+		t.Error("got the conflicts detected again without an edit")
+	}
+	r.Editor.SetCaret(0, 0)
+	r.Editor.Insert("c\n")
+	if !r.refresh() {
+		t.Fatal("got the conflicts not detected again after an edit")
+	}
+	if blocks := r.Editor.PhantomBlocks(); len(blocks) != 3 {
+		t.Fatalf("got phantom blocks %+v, want the moved action lines and the other block", blocks)
+	}
+
+	r.Close()
+	blocks := r.Editor.PhantomBlocks()
+	if len(blocks) != 1 || blocks[0].Line != other.Line || blocks[0].Lines[0] != "deleted" {
+		t.Errorf("got phantom blocks %+v after closing, want only the other block kept", blocks)
+	}
+}
@@ -0,0 +1,445 @@
+package diff
+
+import (
+	"image"
+	"log"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"github.com/oligo/gvcode"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/gutter"
+	"github.com/oligo/gvcode/gutter/providers"
+	"github.com/oligo/gvcode/textstyle/decoration"
+)
+
+const (
+	// diffEditorSource is the source of the decorations added by the
+	// DiffEditor.
+	diffEditorSource = "diff-editor"
+	// changedLinesProviderID is the ID of the gutter provider painting the
+	// changed lines.
+	changedLinesProviderID = "diff-changed-lines"
+)
+
+// DiffEditorColors defines the color scheme of the DiffEditor.
+type DiffEditorColors struct {
+	// RemovedLine is the background of the lines of the left side changed
+	// by the hunks.
+	RemovedLine gvcolor.Color
+	// RemovedText is the background of the changed words of the left side.
+	RemovedText gvcolor.Color
+	// AddedLine is the background of the lines of the right side changed by
+	// the hunks.
+	AddedLine gvcolor.Color
+	// AddedText is the background of the changed words of the right side.
+	AddedText gvcolor.Color
+	// Filler is the background of the blank lines aligning the hunks.
+	Filler gvcolor.Color
+	// Action is the color of the take left/right arrows.
+	Action gvcolor.Color
+}
+
+// DefaultDiffEditorColors returns the default color scheme of the DiffEditor.
+func DefaultDiffEditorColors() DiffEditorColors {
+	removedLine, _ := gvcolor.Hex2Color("#F8514926")
+	removedText, _ := gvcolor.Hex2Color("#F8514966")
+	addedLine, _ := gvcolor.Hex2Color("#2EA04326")
+	addedText, _ := gvcolor.Hex2Color("#2EA04366")
+	filler, _ := gvcolor.Hex2Color("#8080801A")
+	action, _ := gvcolor.Hex2Color("#AAAAAA")
+
+	return DiffEditorColors{
+		RemovedLine: removedLine,
+		RemovedText: removedText,
+		AddedLine:   addedLine,
+		AddedText:   addedText,
+		Filler:      filler,
+		Action:      action,
+	}
+}
+
+// DiffEditor shows two versions of a document side by side, the old one in
+// the Left editor and the new one in the Right editor. The changed lines are
+// highlighted, with the changed words of the modified lines, and blank lines
+// are shown on the shorter side of a hunk, so that the two sides stay
+// aligned. The editors are scrolled together.
+//
+// Both editors stay editable: the hunks are computed again when a text
+// changes. The column between the editors holds the take left/right arrows
+// of the hunks, which copy the lines of one side to the other through the
+// editing API of the editor, so that they can be undone.
+type DiffEditor struct {
+	// Left shows the old version of the document.
+	Left *gvcode.Editor
+	// Right shows the new version of the document.
+	Right *gvcode.Editor
+
+	// Colors defines the color scheme. It is applied when the hunks are
+	// computed.
+	Colors DiffEditorColors
+
+	// ActionWidth is the width of the column between the editors.
+	ActionWidth unit.Dp
+
+	hunks   []*providers.DiffHunk
+	actions []hunkActions
+	// versions are the versions of the texts the hunks are computed from.
+	versions [2]int
+	diffed   bool
+	// blocks are the phantom blocks of the blank lines of the editors.
+	blocks [2][]gvcode.PhantomBlock
+	// scrolls are the scroll offsets of the editors after the last sync.
+	scrolls [2]image.Point
+	lines   [2]*changedLines
+}
+
+// hunkActions are the arrows of a hunk in the column between the editors.
+type hunkActions struct {
+	takeLeft  widget.Clickable
+	takeRight widget.Clickable
+}
+
+// NewDiffEditor creates a DiffEditor comparing the texts of left and right.
+// A gutter provider painting the changed lines is registered in both editors.
+func NewDiffEditor(left, right *gvcode.Editor) *DiffEditor {
+	d := &DiffEditor{
+		Left:        left,
+		Right:       right,
+		Colors:      DefaultDiffEditorColors(),
+		ActionWidth: unit.Dp(32),
+		lines:       [2]*changedLines{{}, {}},
+	}
+	left.WithOptions(gvcode.WithGutter(d.lines[0]))
+	right.WithOptions(gvcode.WithGutter(d.lines[1]))
+	return d
+}
+
+// Hunks returns the hunks changing the text of Left into the text of Right.
+// The line numbers of the new lines refer to Right.
+func (d *DiffEditor) Hunks() []*providers.DiffHunk {
+	d.refresh()
+	return d.hunks
+}
+
+// TakeLeft replaces the lines of the hunk of index idx in Right with the
+// lines of Left, as a single undo step of Right. It reports whether the hunk
+// exists and Right is editable.
+func (d *DiffEditor) TakeLeft(idx int) bool {
+	d.refresh()
+	if idx < 0 || idx >= len(d.hunks) {
+		return false
+	}
+	h := d.hunks[idx]
+	start, count := newRange(h)
+	if !replaceLines(d.Right, start, count, h.OldLines) {
+		return false
+	}
+	d.refresh()
+	return true
+}
+
+// TakeRight replaces the lines of the hunk of index idx in Left with the
+// lines of Right, as a single undo step of Left. It reports whether the hunk
+// exists and Left is editable.
+func (d *DiffEditor) TakeRight(idx int) bool {
+	d.refresh()
+	if idx < 0 || idx >= len(d.hunks) {
+		return false
+	}
+	h := d.hunks[idx]
+	start, count := oldRange(h)
+	if !replaceLines(d.Left, start, count, h.NewLines) {
+		return false
+	}
+	d.refresh()
+	return true
+}
+
+// Close removes the highlights, the blank lines and the gutter providers
+// added to the editors. The phantom blocks of other addons are kept.
+func (d *DiffEditor) Close() {
+	for i, e := range []*gvcode.Editor{d.Left, d.Right} {
+		e.ClearDecorations(diffEditorSource)
+		d.blocks[i] = replacePhantomBlocks(e, d.blocks[i], nil)
+		if m := e.GetGutterManager(); m != nil {
+			m.Unregister(changedLinesProviderID)
+		}
+	}
+	d.hunks, d.actions, d.diffed = nil, nil, false
+}
+
+// Update computes the hunks again if a text changed, and applies the clicked
+// take left/right actions.
+func (d *DiffEditor) Update(gtx layout.Context) {
+	d.refresh()
+	for i := range d.actions {
+		if d.actions[i].takeLeft.Clicked(gtx) {
+			d.TakeLeft(i)
+			return
+		}
+		if d.actions[i].takeRight.Clicked(gtx) {
+			d.TakeRight(i)
+			return
+		}
+	}
+}
+
+// Layout lays out the editors side by side, with the column of the actions
+// between them.
+func (d *DiffEditor) Layout(gtx layout.Context, shaper *text.Shaper) layout.Dimensions {
+	d.Update(gtx)
+	d.syncScroll()
+
+	dims := layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+		layout.Flexed(0.5, func(gtx layout.Context) layout.Dimensions {
+			return d.Left.Layout(gtx, shaper)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return d.layoutActions(gtx)
+		}),
+		layout.Flexed(0.5, func(gtx layout.Context) layout.Dimensions {
+			return d.Right.Layout(gtx, shaper)
+		}),
+	)
+
+	// the editors are edited or scrolled by the events of this frame.
+	if d.refresh() || d.syncScroll() {
+		gtx.Execute(op.InvalidateCmd{})
+	}
+	return dims
+}
+
+// refresh computes the hunks again if a text changed since the last diff.
+// It reports whether the hunks are computed.
+func (d *DiffEditor) refresh() bool {
+	versions := [2]int{d.Left.TextVersion(), d.Right.TextVersion()}
+	if d.diffed && versions == d.versions {
+		return false
+	}
+	d.diffed, d.versions = true, versions
+	d.hunks = DiffTexts([]byte(d.Left.Text()), []byte(d.Right.Text()))
+	d.actions = make([]hunkActions, len(d.hunks))
+	d.decorate()
+	return true
+}
+
+// decorate highlights the hunks in the editors, and aligns them with blank
+// lines.
+func (d *DiffEditor) decorate() {
+	var removed, added []gutter.LineHighlight
+	var leftBlocks, rightBlocks []gvcode.PhantomBlock
+	var leftDecos, rightDecos []decoration.Decoration
+
+	for _, h := range d.hunks {
+		oldStart, oldCount := oldRange(h)
+		newStart, newCount := newRange(h)
+		for i := range oldCount {
+			removed = append(removed, gutter.LineHighlight{Line: oldStart + i, Color: d.Colors.RemovedLine})
+		}
+		for i := range newCount {
+			added = append(added, gutter.LineHighlight{Line: newStart + i, Color: d.Colors.AddedLine})
+		}
+
+		// the blank lines follow the lines of the shorter side.
+		switch {
+		case oldCount > newCount:
+			rightBlocks = append(rightBlocks, gvcode.PhantomBlock{
+				Line: newStart + newCount, Lines: make([]string, oldCount-newCount), Background: d.Colors.Filler,
+			})
+		case newCount > oldCount:
+			leftBlocks = append(leftBlocks, gvcode.PhantomBlock{
+				Line: oldStart + oldCount, Lines: make([]string, newCount-oldCount), Background: d.Colors.Filler,
+			})
+		}
+
+		// the lines of a modification are compared in pairs.
+		for i := range min(oldCount, newCount) {
+			os, oe, ns, ne := lineChange(h.OldLines[i], h.NewLines[i])
+			if os < oe {
+				leftDecos = append(leftDecos, changeDecoration(d.Left, diffEditorSource, oldStart+i, os, oe, d.Colors.RemovedText))
+			}
+			if ns < ne {
+				rightDecos = append(rightDecos, changeDecoration(d.Right, diffEditorSource, newStart+i, ns, ne, d.Colors.AddedText))
+			}
+		}
+	}
+
+	d.lines[0].lines, d.lines[1].lines = removed, added
+	d.blocks[0] = replacePhantomBlocks(d.Left, d.blocks[0], leftBlocks)
+	d.blocks[1] = replacePhantomBlocks(d.Right, d.blocks[1], rightBlocks)
+	for _, side := range []struct {
+		editor *gvcode.Editor
+		decos  []decoration.Decoration
+	}{{d.Left, leftDecos}, {d.Right, rightDecos}} {
+		side.editor.ClearDecorations(diffEditorSource)
+		if err := side.editor.AddDecorations(side.decos...); err != nil {
+			log.Printf("failed to highlight the changed words: %v", err)
+		}
+	}
+}
+
+// changeDecoration highlights the runes from start to end of the line.
+func changeDecoration(e *gvcode.Editor, source string, line, start, end int, c gvcolor.Color) decoration.Decoration {
+	lineStart, _ := e.ConvertPos(line, 0)
+	return decoration.Decoration{
+		Source:     source,
+		Start:      lineStart + start,
+		End:        lineStart + end,
+		Background: &decoration.Background{Color: c},
+	}
+}
+
+// replaceLines replaces count lines of e from the 0-based line start with
+// lines, as a single undo step. It reports whether e is editable.
+func replaceLines(e *gvcode.Editor, start, count int, lines []string) bool {
+	if e.ReadOnly() {
+		return false
+	}
+
+	startOff, _ := e.ConvertPos(start, 0)
+	endOff, _ := e.ConvertPos(start+count, 0)
+	region := e.ReadRange(startOff, endOff)
+	text := strings.Join(lines, "\n")
+	switch {
+	case endOff < e.Len() || strings.HasSuffix(region, "\n") || (region == "" && (startOff == 0 || e.ReadRange(startOff-1, startOff) == "\n")):
+		// the lines are followed by a line break.
+		if len(lines) > 0 {
+			text += "\n"
+		}
+	case region == "":
+		// appending to the last line, which has no line break.
+		if len(lines) > 0 {
+			text = "\n" + text
+		}
+	case len(lines) == 0 && startOff > 0:
+		// removing the last lines removes the line break before them.
+		startOff--
+	}
+
+	if startOff == endOff && text == "" {
+		return true
+	}
+	e.Transaction(func(tx *gvcode.EditTx) {
+		tx.Replace(startOff, endOff, text)
+	})
+	return true
+}
+
+// syncScroll scrolls an editor to the offset of the other one if it was
+// scrolled since the last sync. It reports whether an editor is scrolled.
+func (d *DiffEditor) syncScroll() bool {
+	left, right := d.Left.ScrollOffset(), d.Right.ScrollOffset()
+	switch {
+	case left != d.scrolls[0]:
+		d.Right.SetScrollOffset(left)
+	case right != d.scrolls[1]:
+		d.Left.SetScrollOffset(right)
+	default:
+		return false
+	}
+	d.scrolls = [2]image.Point{d.Left.ScrollOffset(), d.Right.ScrollOffset()}
+	return true
+}
+
+// layoutActions lays out the arrows of the hunks, next to their first line.
+// The arrows of a read-only target are not shown.
+func (d *DiffEditor) layoutActions(gtx layout.Context) layout.Dimensions {
+	size := image.Pt(gtx.Dp(d.ActionWidth), gtx.Constraints.Max.Y)
+	defer clip.Rect(image.Rectangle{Max: size}).Push(gtx.Ops).Pop()
+
+	arrow := size.X / 2
+	scrollY := d.Left.ScrollOffset().Y
+	for i, h := range d.hunks {
+		top, bottom, ok := d.hunkLine(h)
+		if !ok {
+			continue
+		}
+		y := (top+bottom-arrow)/2 - scrollY
+		if y+arrow < 0 || y > size.Y {
+			continue
+		}
+		if !d.Right.ReadOnly() {
+			d.layoutArrow(gtx, &d.actions[i].takeLeft, image.Pt(0, y), arrow, true)
+		}
+		if !d.Left.ReadOnly() {
+			d.layoutArrow(gtx, &d.actions[i].takeRight, image.Pt(arrow, y), arrow, false)
+		}
+	}
+	return layout.Dimensions{Size: size}
+}
+
+// hunkLine returns the vertical extent of the first line of the hunk, on the
+// side having lines, as both sides are aligned.
+func (d *DiffEditor) hunkLine(h *providers.DiffHunk) (top, bottom int, ok bool) {
+	if start, count := oldRange(h); count > 0 {
+		return d.Left.LineBounds(start)
+	}
+	start, _ := newRange(h)
+	return d.Right.LineBounds(start)
+}
+
+// layoutArrow lays out a clickable triangle at pos, pointing to the right if
+// toRight is true.
+func (d *DiffEditor) layoutArrow(gtx layout.Context, btn *widget.Clickable, pos image.Point, size int, toRight bool) {
+	defer op.Offset(pos).Push(gtx.Ops).Pop()
+	gtx.Constraints = layout.Exact(image.Pt(size, size))
+	btn.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		c := d.Colors.Action
+		if !btn.Hovered() {
+			c = c.MulAlpha(0xA0)
+		}
+		s, inset := float32(size), float32(size)/4
+		tip, base := s-inset, inset
+		if !toRight {
+			tip, base = base, tip
+		}
+		var p clip.Path
+		p.Begin(gtx.Ops)
+		p.MoveTo(f32.Pt(base, inset))
+		p.LineTo(f32.Pt(tip, s/2))
+		p.LineTo(f32.Pt(base, s-inset))
+		p.Close()
+		paint.FillShape(gtx.Ops, c.NRGBA(), clip.Outline{Path: p.End()}.Op())
+		return layout.Dimensions{Size: gtx.Constraints.Min}
+	})
+}
+
+// changedLines is a gutter provider taking no space, which paints the
+// backgrounds of the changed lines across the editor.
+type changedLines struct {
+	// id is the ID of the provider, changedLinesProviderID if empty.
+	id    string
+	lines []gutter.LineHighlight
+}
+
+func (p *changedLines) ID() string {
+	if p.id != "" {
+		return p.id
+	}
+	return changedLinesProviderID
+}
+
+func (p *changedLines) Priority() int {
+	return 0
+}
+
+func (p *changedLines) Width(gtx layout.Context, shaper *text.Shaper, params text.Parameters, lineCount int) unit.Dp {
+	return 0
+}
+
+func (p *changedLines) Layout(gtx layout.Context, ctx gutter.GutterContext) layout.Dimensions {
+	return layout.Dimensions{}
+}
+
+// HighlightedLines implements gutter.LineHighlighter.
+func (p *changedLines) HighlightedLines() []gutter.LineHighlight {
+	return p.lines
+}
@@ -0,0 +1,163 @@
+package diff
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/text"
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/internal/editortest"
+)
+
+func newDiffEditor(t *testing.T, left, right string) (*DiffEditor, layout.Context, *text.Shaper) {
+	t.Helper()
+
+	d := NewDiffEditor(editortest.NewEditor(t, left), editortest.NewEditor(t, right))
+	gtx, shaper := editortest.NewContext(), editortest.NewShaper()
+	d.Layout(gtx, shaper)
+	return d, gtx, shaper
+}
+
+func TestLineChange(t *testing.T) {
+	tests := []struct {
+		old, new string
+		want     [4]int
+	}{
+		{"foo(x)", "foo(y)", [4]int{4, 5, 4, 5}},
+		{"count := 1", "counter := 1", [4]int{0, 5, 0, 7}},
+		{"foo", "foo bar", [4]int{3, 3, 3, 7}},
+		{"same", "same", [4]int{4, 4, 4, 4}},
+	}
+	for _, tc := range tests {
+		os, oe, ns, ne := lineChange(tc.old, tc.new)
+		if got := [4]int{os, oe, ns, ne}; got != tc.want {
+			t.Errorf("lineChange(%q, %q) = %v, want %v", tc.old, tc.new, got, tc.want)
+		}
+	}
+}
+
+func TestDiffEditorAlignment(t *testing.T) {
+	d, _, _ := newDiffEditor(t, "a\nb\nc\nd\n", "a\nB\nc\nx\ny\nd\n")
+
+	hunks := d.Hunks()
+	if len(hunks) != 2 {
+		t.Fatalf("got %d hunks, want 2", len(hunks))
+	}
+
+	// the added lines are aligned by blank lines on the left.
+	blocks := d.Left.PhantomBlocks()
+	if len(blocks) != 1 || blocks[0].Line != 3 || len(blocks[0].Lines) != 2 {
+		t.Fatalf("got left blocks %+v, want 2 lines above line 3", blocks)
+	}
+	if blocks := d.Right.PhantomBlocks(); len(blocks) != 0 {
+		t.Fatalf("got right blocks %+v, want none", blocks)
+	}
+	leftTop, _, _ := d.Left.LineBounds(3)
+	rightTop, _, _ := d.Right.LineBounds(5)
+	if leftTop != rightTop {
+		t.Errorf("line d is at %d on the left and at %d on the right", leftTop, rightTop)
+	}
+
+	if got := d.lines[0].HighlightedLines(); len(got) != 1 || got[0].Line != 1 {
+		t.Errorf("got removed lines %+v, want line 1", got)
+	}
+	if got := d.lines[1].HighlightedLines(); len(got) != 3 {
+		t.Errorf("got %d added lines, want 3", len(got))
+	}
+
+	// the hunks are computed again only after an edit.
+	if d.refresh() {
+		t.Error("got the hunks computed again without an edit")
+	}
+	d.Right.SetCaret(0, 0)
+	d.Right.Insert("z\n")
+	if !d.refresh() || len(d.hunks) != 3 {
+		t.Errorf("got hunks %+v after an edit, want 3", d.hunks)
+	}
+}
+
+func TestDiffEditorClose(t *testing.T) {
+	d, _, _ := newDiffEditor(t, "a\nb\n", "a\nb\nc\n")
+	other := gvcode.PhantomBlock{Line: 0, Lines: []string{"other"}}
+	d.Left.SetPhantomBlocks(append(d.Left.PhantomBlocks(), other)...)
+
+	// the blank lines are replaced after an edit, keeping the other block.
+	d.Right.SetCaret(0, 0)
+	d.Right.Insert("z\n")
+	d.refresh()
+	blocks := d.Left.PhantomBlocks()
+	if len(blocks) != 3 || blocks[0].Lines[0] != "other" || blocks[1].Line != 0 || blocks[2].Line != 2 {
+		t.Fatalf("got left blocks %+v, want the other block and the blank lines of z and c", blocks)
+	}
+
+	d.Close()
+	if blocks := d.Left.PhantomBlocks(); len(blocks) != 1 || blocks[0].Lines[0] != "other" {
+		t.Errorf("got left blocks %+v after closing, want only the other block kept", blocks)
+	}
+}
+
+func TestDiffEditorTake(t *testing.T) {
+	d, _, _ := newDiffEditor(t, "a\nb\nc\nd\n", "a\nB\nc\nd\nx\n")
+
+	if !d.TakeLeft(0) {
+		t.Fatal("take left failed")
+	}
+	if got, want := d.Right.Text(), "a\nb\nc\nd\nx\n"; got != want {
+		t.Fatalf("got right %q after take left, want %q", got, want)
+	}
+	if len(d.Hunks()) != 1 {
+		t.Fatalf("got %d hunks after take left, want 1", len(d.Hunks()))
+	}
+
+	// the appended line is copied to the left.
+	if !d.TakeRight(0) {
+		t.Fatal("take right failed")
+	}
+	if got, want := d.Left.Text(), "a\nb\nc\nd\nx\n"; got != want {
+		t.Fatalf("got left %q after take right, want %q", got, want)
+	}
+	if len(d.Hunks()) != 0 {
+		t.Fatalf("got %d hunks, want none", len(d.Hunks()))
+	}
+}
+
+func TestReplaceLines(t *testing.T) {
+	tests := []struct {
+		text         string
+		start, count int
+		lines        []string
+		want         string
+	}{
+		{"a\nb\nc", 1, 1, []string{"x", "y"}, "a\nx\ny\nc"},
+		{"a\nb\nc", 2, 1, nil, "a\nb"},
+		{"a\nb", 2, 0, []string{"c"}, "a\nb\nc"},
+		{"a\nb\n", 2, 0, []string{"c"}, "a\nb\nc\n"},
+		{"a\nb\n", 0, 1, nil, "b\n"},
+	}
+	for _, tc := range tests {
+		e := editortest.NewEditor(t, tc.text)
+		replaceLines(e, tc.start, tc.count, tc.lines)
+		if got := e.Text(); got != tc.want {
+			t.Errorf("replacing %d lines at %d of %q: got %q, want %q", tc.count, tc.start, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestDiffEditorScroll(t *testing.T) {
+	content := strings.Repeat("line\n", 200)
+	d, gtx, shaper := newDiffEditor(t, content, "first\n"+content)
+
+	d.Left.SetScrollOffset(image.Pt(0, 300))
+	d.Layout(gtx, shaper)
+	if got := d.Right.ScrollOffset(); got.Y != 300 {
+		t.Errorf("right scrolled to %v, want y 300", got)
+	}
+
+	d.Right.SetScrollOffset(image.Pt(0, 100))
+	d.Layout(gtx, shaper)
+	if got := d.Left.ScrollOffset(); got.Y != 100 {
+		t.Errorf("left scrolled to %v, want y 100", got)
+	}
+}
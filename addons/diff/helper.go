@@ -1,22 +1,21 @@
 package diff
 
 import (
-	"bufio"
 	"bytes"
 	"log"
-	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
-	"strconv"
+	"reflect"
+	"slices"
 	"strings"
 
+	"github.com/oligo/gvcode"
 	"github.com/oligo/gvcode/gutter/providers"
 )
 
-// GitDiff is a helper that can be used to parse git diff output.
-// Use the NewGitDiff function to build a new instance to make sure
-// we are dealing with a real git repository.
+// GitDiff is a helper diffing the content of a buffer against the version of
+// the file in the git HEAD. Use the NewGitDiff function to build a new
+// instance to make sure we are dealing with a real git repository.
 type GitDiff struct {
 	dir      string
 	filename string
@@ -76,7 +75,7 @@ func (d *GitDiff) ParseDiff(content []byte) []*providers.DiffHunk {
 }
 
 // parseBufferDiff returns the diff between HEAD and the given buffer content,
-// using pipes to avoid writing temp files on every keystroke.
+// computed in memory to avoid writing temp files on every keystroke.
 func (d *GitDiff) parseBufferDiff(content []byte) []*providers.DiffHunk {
 	// Get the HEAD version of the file.
 	cmd := exec.Command("git", "show", "HEAD:./"+d.filename)
@@ -87,41 +86,9 @@ func (d *GitDiff) parseBufferDiff(content []byte) []*providers.DiffHunk {
 		original = nil
 	}
 
-	// Pass HEAD version via fd 3 and buffer content via stdin.
-	pr, pw, err := os.Pipe()
-	if err != nil {
-		return nil
-	}
-
-	go func() {
-		pw.Write(original)
-		pw.Close()
-	}()
-
-	cmd = exec.Command("git", "diff", "--no-color", "-U0", "--no-index", "--", "/dev/fd/3", "-")
-	cmd.Dir = d.dir
-	cmd.Stdin = bytes.NewReader(content)
-	cmd.ExtraFiles = []*os.File{pr} // fd 3
-	output, err := cmd.Output()
-	pr.Close()
-
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			if len(exitErr.Stderr) > 0 {
-				log.Printf("git diff stderr: %s", exitErr.Stderr)
-			}
-		}
-	}
-
-	if len(output) == 0 {
-		return nil
-	}
-	return parseDiffOutput(output)
+	return DiffTexts(original, content)
 }
 
-// Regex to match hunk headers like @@ -10,3 +10,5 @@
-var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
-
 // finalizeHunkType determines the hunk type based on the actual content
 func finalizeHunkType(hunk *providers.DiffHunk) {
 	if hunk == nil {
@@ -142,70 +109,17 @@ func finalizeHunkType(hunk *providers.DiffHunk) {
 	}
 }
 
-// parseDiffOutput parses unified diff output into DiffHunks.
-func parseDiffOutput(output []byte) []*providers.DiffHunk {
-	var hunks []*providers.DiffHunk
-
-	scanner := bufio.NewScanner(bytes.NewReader(output))
-	var currentHunk *providers.DiffHunk
-	var inHunk bool
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Check for hunk header
-		if matches := hunkHeaderRe.FindStringSubmatch(line); matches != nil {
-			// Save previous hunk if exists
-			if currentHunk != nil {
-				finalizeHunkType(currentHunk)
-				hunks = append(hunks, currentHunk)
-			}
-
-			newStart, _ := strconv.Atoi(matches[3])
-			newCount := 1
-			if matches[4] != "" {
-				newCount, _ = strconv.Atoi(matches[4])
-			}
-
-			// Convert to 0-based line numbers
-			newStart--
-
-			// Create hunk with temporary type - will be determined after parsing content
-			currentHunk = &providers.DiffHunk{
-				Type:      providers.DiffModified, // Temporary, will be updated
-				StartLine: newStart,
-				EndLine:   newStart + max(newCount-1, 0),
-				OldLines:  make([]string, 0),
-				NewLines:  make([]string, 0),
-			}
-
-			inHunk = true
-			continue
+// replacePhantomBlocks replaces the phantom blocks own of the editor with
+// blocks, keeping the blocks set by others, e.g., by the other addons of the
+// editor. It returns blocks, to be passed as own by the next call.
+func replacePhantomBlocks(editor *gvcode.Editor, own, blocks []gvcode.PhantomBlock) []gvcode.PhantomBlock {
+	all := editor.PhantomBlocks()
+	for _, block := range own {
+		idx := slices.IndexFunc(all, func(b gvcode.PhantomBlock) bool { return reflect.DeepEqual(b, block) })
+		if idx >= 0 {
+			all = slices.Delete(all, idx, idx+1)
 		}
-
-		// Skip diff headers
-		if strings.HasPrefix(line, "diff ") ||
-			strings.HasPrefix(line, "index ") ||
-			strings.HasPrefix(line, "--- ") ||
-			strings.HasPrefix(line, "+++ ") {
-			continue
-		}
-
-		// Process hunk content
-		if inHunk && currentHunk != nil {
-			if after, ok := strings.CutPrefix(line, "-"); ok {
-				currentHunk.OldLines = append(currentHunk.OldLines, after)
-			} else if after, ok := strings.CutPrefix(line, "+"); ok {
-				currentHunk.NewLines = append(currentHunk.NewLines, after)
-			}
-		}
-	}
-
-	// Don't forget the last hunk
-	if currentHunk != nil {
-		finalizeHunkType(currentHunk)
-		hunks = append(hunks, currentHunk)
 	}
-
-	return hunks
+	editor.SetPhantomBlocks(append(all, blocks...)...)
+	return blocks
 }
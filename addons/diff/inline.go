@@ -0,0 +1,304 @@
+package diff
+
+import (
+	"image"
+	"log"
+	"slices"
+
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/gutter"
+	"github.com/oligo/gvcode/gutter/providers"
+	"github.com/oligo/gvcode/textstyle/decoration"
+)
+
+// inlineDiffSource is the source of the decorations added by the InlineDiff.
+const inlineDiffSource = "inline-diff"
+
+// InlineDiffEvent is emitted when an action of a hunk of the InlineDiff is
+// clicked.
+type InlineDiffEvent struct {
+	// Action is ActionRevert or ActionStage.
+	Action PopupAction
+	// Hunk is the hunk of the action. A reverted hunk is no longer in the
+	// document.
+	Hunk *providers.DiffHunk
+}
+
+// InlineDiff shows the changes of the text of an editor inline, in unified
+// diff style: the deleted lines are shown as red phantom blocks above the
+// lines replacing them, and the changed lines are highlighted, with their
+// changed words. Each hunk has revert and stage actions at its top right
+// corner.
+//
+// The revert action restores the old lines of the hunk in a single undo
+// step, and emits an InlineDiffEvent. The stage action only emits an
+// InlineDiffEvent, for the host app to stage the hunk in its version control
+// system. No git binary is needed by the actions.
+type InlineDiff struct {
+	// Editor shows the new version of the document.
+	Editor *gvcode.Editor
+
+	// Colors defines the color scheme. The Filler color is not used.
+	Colors DiffEditorColors
+
+	// TextSize is the size of the labels of the actions.
+	TextSize unit.Sp
+
+	// ShowStage controls whether the stage action is shown.
+	ShowStage bool
+
+	// base is the old text set by SetBase.
+	base    string
+	hasBase bool
+	// version is the version of the text the hunks are computed from.
+	version int
+	diffed  bool
+	hunks   []*providers.DiffHunk
+	// blocks are the phantom blocks of the deleted lines.
+	blocks  []gvcode.PhantomBlock
+	actions []inlineActions
+	lines   *changedLines
+	pending []InlineDiffEvent
+}
+
+// inlineActions are the actions of a hunk.
+type inlineActions struct {
+	revert widget.Clickable
+	stage  widget.Clickable
+}
+
+// NewInlineDiff creates an InlineDiff showing the changes of the text of
+// editor. Set the old text with SetBase, or the hunks with SetHunks. A gutter
+// provider painting the changed lines is registered in the editor.
+func NewInlineDiff(editor *gvcode.Editor) *InlineDiff {
+	d := &InlineDiff{
+		Editor:    editor,
+		Colors:    DefaultDiffEditorColors(),
+		TextSize:  unit.Sp(12),
+		ShowStage: true,
+		lines:     &changedLines{},
+	}
+	editor.WithOptions(gvcode.WithGutter(d.lines))
+	return d
+}
+
+// SetBase sets the old version of the text. The hunks changing it into the
+// text of the editor are computed again whenever the text changes.
+func (d *InlineDiff) SetBase(base string) {
+	d.base, d.hasBase, d.diffed = base, true, false
+	d.refresh()
+}
+
+// SetHunks shows the hunks computed elsewhere, e.g., by GitDiff.ParseDiff.
+// They replace the base set by SetBase, and are not updated when the text
+// changes, except by the revert action.
+func (d *InlineDiff) SetHunks(hunks []*providers.DiffHunk) {
+	d.base, d.hasBase = "", false
+	d.setHunks(hunks)
+}
+
+// Hunks returns the hunks shown.
+func (d *InlineDiff) Hunks() []*providers.DiffHunk {
+	d.refresh()
+	return d.hunks
+}
+
+// Revert restores the old lines of the hunk of index idx, as a single undo
+// step. It reports whether the hunk exists and the editor is editable.
+func (d *InlineDiff) Revert(idx int) bool {
+	d.refresh()
+	if idx < 0 || idx >= len(d.hunks) {
+		return false
+	}
+	h := d.hunks[idx]
+	start, count := newRange(h)
+	if !replaceLines(d.Editor, start, count, h.OldLines) {
+		return false
+	}
+
+	if d.hasBase {
+		d.refresh()
+		return true
+	}
+	// the lines of the following hunks are moved by the revert.
+	hunks := slices.Delete(slices.Clone(d.hunks), idx, idx+1)
+	delta := len(h.OldLines) - len(h.NewLines)
+	for i, next := range hunks[idx:] {
+		moved := *next
+		moved.StartLine += delta
+		moved.EndLine += delta
+		hunks[idx+i] = &moved
+	}
+	d.setHunks(hunks)
+	return true
+}
+
+// Close removes the highlights, the phantom blocks and the gutter provider
+// added to the editor. The phantom blocks of other addons are kept.
+func (d *InlineDiff) Close() {
+	d.Editor.ClearDecorations(inlineDiffSource)
+	d.blocks = replacePhantomBlocks(d.Editor, d.blocks, nil)
+	if m := d.Editor.GetGutterManager(); m != nil {
+		m.Unregister(changedLinesProviderID)
+	}
+	d.hunks, d.actions, d.hasBase, d.diffed = nil, nil, false, false
+}
+
+// Update computes the hunks again if the text changed, applies the clicked
+// actions, and returns the events of the actions.
+func (d *InlineDiff) Update(gtx layout.Context) (InlineDiffEvent, bool) {
+	d.refresh()
+	for i := range d.actions {
+		if d.actions[i].revert.Clicked(gtx) {
+			h := d.hunks[i]
+			if d.Revert(i) {
+				d.pending = append(d.pending, InlineDiffEvent{Action: ActionRevert, Hunk: h})
+			}
+			break
+		}
+		if d.actions[i].stage.Clicked(gtx) {
+			d.pending = append(d.pending, InlineDiffEvent{Action: ActionStage, Hunk: d.hunks[i]})
+		}
+	}
+
+	if len(d.pending) > 0 {
+		evt := d.pending[0]
+		d.pending = d.pending[1:]
+		return evt, true
+	}
+	return InlineDiffEvent{}, false
+}
+
+// Layout lays out the editor, with the actions of the hunks over it.
+func (d *InlineDiff) Layout(gtx layout.Context, shaper *text.Shaper) layout.Dimensions {
+	for {
+		if _, ok := d.Update(gtx); !ok {
+			break
+		}
+	}
+
+	dims := d.Editor.Layout(gtx, shaper)
+	d.layoutActions(gtx, shaper)
+	if d.refresh() {
+		gtx.Execute(op.InvalidateCmd{})
+	}
+	return dims
+}
+
+// refresh computes the hunks again if the text changed since the last diff
+// against the base. It reports whether the hunks are computed.
+func (d *InlineDiff) refresh() bool {
+	if !d.hasBase {
+		return false
+	}
+	version := d.Editor.TextVersion()
+	if d.diffed && version == d.version {
+		return false
+	}
+	d.diffed, d.version = true, version
+	d.setHunks(DiffTexts([]byte(d.base), []byte(d.Editor.Text())))
+	return true
+}
+
+// setHunks shows the hunks in the editor.
+func (d *InlineDiff) setHunks(hunks []*providers.DiffHunk) {
+	d.hunks = hunks
+	d.actions = make([]inlineActions, len(hunks))
+	d.lines.lines = d.lines.lines[:0]
+
+	var blocks []gvcode.PhantomBlock
+	var decos []decoration.Decoration
+	for _, h := range hunks {
+		newStart, newCount := newRange(h)
+		for i := range newCount {
+			d.lines.lines = append(d.lines.lines, gutter.LineHighlight{Line: newStart + i, Color: d.Colors.AddedLine})
+		}
+		if len(h.OldLines) == 0 {
+			continue
+		}
+
+		block := gvcode.PhantomBlock{Line: newStart, Lines: h.OldLines, Background: d.Colors.RemovedLine}
+		for i := range min(len(h.OldLines), newCount) {
+			os, oe, ns, ne := lineChange(h.OldLines[i], h.NewLines[i])
+			if os < oe {
+				block.Highlights = append(block.Highlights, gvcode.PhantomHighlight{
+					Line: i, Start: os, End: oe, Background: d.Colors.RemovedText,
+				})
+			}
+			if ns < ne {
+				decos = append(decos, changeDecoration(d.Editor, inlineDiffSource, newStart+i, ns, ne, d.Colors.AddedText))
+			}
+		}
+		blocks = append(blocks, block)
+	}
+
+	d.blocks = replacePhantomBlocks(d.Editor, d.blocks, blocks)
+	d.Editor.ClearDecorations(inlineDiffSource)
+	if err := d.Editor.AddDecorations(decos...); err != nil {
+		log.Printf("failed to highlight the changed words: %v", err)
+	}
+}
+
+// layoutActions lays out the actions of the visible hunks at the top right
+// corner of the hunks.
+func (d *InlineDiff) layoutActions(gtx layout.Context, shaper *text.Shaper) {
+	defer clip.Rect(image.Rectangle{Max: gtx.Constraints.Max}).Push(gtx.Ops).Pop()
+	scrollY := d.Editor.ScrollOffset().Y
+	for i, h := range d.hunks {
+		start, _ := newRange(h)
+		top, _, ok := d.Editor.PhantomBlockBounds(start)
+		if !ok {
+			// a deletion at the end of the document.
+			_, top, ok = d.Editor.LineBounds(start - 1)
+		}
+		if !ok || top-scrollY > gtx.Constraints.Max.Y {
+			continue
+		}
+
+		macro := op.Record(gtx.Ops)
+		dims := layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return d.layoutAction(gtx, shaper, &d.actions[i].revert, "Revert")
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if !d.ShowStage {
+					return layout.Dimensions{}
+				}
+				return d.layoutAction(gtx, shaper, &d.actions[i].stage, "Stage")
+			}),
+		)
+		call := macro.Stop()
+
+		y := top - scrollY
+		if y+dims.Size.Y < 0 {
+			continue
+		}
+		stack := op.Offset(image.Pt(gtx.Constraints.Max.X-dims.Size.X, y)).Push(gtx.Ops)
+		call.Add(gtx.Ops)
+		stack.Pop()
+	}
+}
+
+// layoutAction lays out a clickable label.
+func (d *InlineDiff) layoutAction(gtx layout.Context, shaper *text.Shaper, btn *widget.Clickable, label string) layout.Dimensions {
+	gtx.Constraints.Min = image.Point{}
+	return btn.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Inset{Left: unit.Dp(6), Right: unit.Dp(6)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			c := d.Colors.Action
+			if !btn.Hovered() {
+				c = c.MulAlpha(0xA0)
+			}
+			textMaterial := op.Record(gtx.Ops)
+			paint.ColorOp{Color: c.NRGBA()}.Add(gtx.Ops)
+			return widget.Label{MaxLines: 1}.Layout(gtx, shaper, font.Font{}, d.TextSize, label, textMaterial.Stop())
+		})
+	})
+}
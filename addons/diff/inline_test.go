@@ -0,0 +1,125 @@
+package diff
+
+import (
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/text"
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/gutter/providers"
+	"github.com/oligo/gvcode/internal/editortest"
+)
+
+func newInlineDiff(t *testing.T, content string) (*InlineDiff, layout.Context, *text.Shaper) {
+	t.Helper()
+	d := NewInlineDiff(editortest.NewEditor(t, content))
+	gtx, shaper := editortest.NewContext(), editortest.NewShaper()
+	d.Layout(gtx, shaper)
+	return d, gtx, shaper
+}
+
+func TestInlineDiffHunks(t *testing.T) {
+	d, _, _ := newInlineDiff(t, "a\ncount := 2\nc\nd\n")
+	d.SetHunks([]*providers.DiffHunk{
+		{Type: providers.DiffModified, StartLine: 1, EndLine: 1, OldStartLine: 1, OldLines: []string{"n := 1"}, NewLines: []string{"count := 2"}},
+		{Type: providers.DiffDeleted, StartLine: 2, EndLine: 2, OldStartLine: 3, OldLines: []string{"x", "y"}},
+	})
+
+	blocks := d.Editor.PhantomBlocks()
+	if len(blocks) != 2 {
+		t.Fatalf("got %d phantom blocks, want 2", len(blocks))
+	}
+	if blocks[0].Line != 1 || blocks[0].Lines[0] != "n := 1" {
+		t.Errorf("got block %+v, want the old line above line 1", blocks[0])
+	}
+	if hl := blocks[0].Highlights; len(hl) != 1 || hl[0].Start != 0 || hl[0].End != 6 {
+		t.Errorf("got highlights %+v of the deleted line, want 0-6", hl)
+	}
+	if blocks[1].Line != 3 || len(blocks[1].Lines) != 2 {
+		t.Errorf("got block %+v, want the deleted lines above line 3", blocks[1])
+	}
+	if got := d.lines.HighlightedLines(); len(got) != 1 || got[0].Line != 1 {
+		t.Errorf("got added lines %+v, want line 1", got)
+	}
+}
+
+func TestInlineDiffRevert(t *testing.T) {
+	d, _, _ := newInlineDiff(t, "a\nB\nc\nd\n")
+	d.SetHunks([]*providers.DiffHunk{
+		{Type: providers.DiffModified, StartLine: 1, EndLine: 1, OldStartLine: 1, OldLines: []string{"b1", "b2"}, NewLines: []string{"B"}},
+		{Type: providers.DiffAdded, StartLine: 3, EndLine: 3, OldStartLine: 4, NewLines: []string{"d"}},
+	})
+
+	if !d.Revert(0) {
+		t.Fatal("revert failed")
+	}
+	if got, want := d.Editor.Text(), "a\nb1\nb2\nc\nd\n"; got != want {
+		t.Fatalf("got %q after the revert, want %q", got, want)
+	}
+	// the following hunk is moved by the revert.
+	hunks := d.Hunks()
+	if len(hunks) != 1 || hunks[0].StartLine != 4 {
+		t.Fatalf("got hunks %+v, want the addition at line 4", hunks)
+	}
+
+	if !d.Revert(0) {
+		t.Fatal("revert failed")
+	}
+	if got, want := d.Editor.Text(), "a\nb1\nb2\nc\n"; got != want {
+		t.Fatalf("got %q after the revert, want %q", got, want)
+	}
+	if len(d.Editor.PhantomBlocks()) != 0 {
+		t.Errorf("got phantom blocks %+v after reverting all hunks", d.Editor.PhantomBlocks())
+	}
+}
+
+func TestInlineDiffBase(t *testing.T) {
+	d, gtx, shaper := newInlineDiff(t, "a\nb\nc\n")
+	d.SetBase("a\nc\n")
+	if hunks := d.Hunks(); len(hunks) != 1 || hunks[0].Type != providers.DiffAdded {
+		t.Fatalf("got hunks %+v, want an addition", hunks)
+	}
+
+	// the hunks follow the edits.
+	d.Editor.SetCaret(0, 0)
+	d.Editor.Insert("z\n")
+	d.Layout(gtx, shaper)
+	if hunks := d.Hunks(); len(hunks) != 2 {
+		t.Fatalf("got %d hunks after the edit, want 2", len(hunks))
+	}
+
+	if !d.Revert(1) {
+		t.Fatal("revert failed")
+	}
+	if got, want := d.Editor.Text(), "z\na\nc\n"; got != want {
+		t.Fatalf("got %q after the revert, want %q", got, want)
+	}
+}
+
+func TestInlineDiffClose(t *testing.T) {
+	d, _, _ := newInlineDiff(t, "a\nb\nc\n")
+	other := gvcode.PhantomBlock{Line: 0, Lines: []string{"other"}}
+	d.Editor.SetPhantomBlocks(other)
+	d.SetBase("a\nx\nb\nc\n")
+	if blocks := d.Editor.PhantomBlocks(); len(blocks) != 2 {
+		t.Fatalf("got phantom blocks %+v, want the deleted line and the other block", blocks)
+	}
+
+	// the hunks are computed again only after an edit.
+	if d.refresh() {
+		t.Error("got the hunks computed again without an edit")
+	}
+	d.Editor.SetCaret(0, 0)
+	d.Editor.Insert("y\n")
+	if !d.refresh() {
+		t.Fatal("got the hunks not computed again after an edit")
+	}
+	if blocks := d.Editor.PhantomBlocks(); len(blocks) != 2 {
+		t.Fatalf("got phantom blocks %+v, want the moved deleted line and the other block", blocks)
+	}
+
+	d.Close()
+	if blocks := d.Editor.PhantomBlocks(); len(blocks) != 1 || blocks[0].Lines[0] != "other" {
+		t.Errorf("got phantom blocks %+v after closing, want only the other block kept", blocks)
+	}
+}
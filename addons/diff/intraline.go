@@ -0,0 +1,61 @@
+package diff
+
+import (
+	"unicode"
+
+	"github.com/oligo/gvcode/gutter/providers"
+)
+
+// lineChange returns the rune ranges of oldLine and newLine that differ: the
+// common prefix and suffix are excluded, and the ranges are extended to
+// whole words, so that a changed identifier is highlighted entirely.
+func lineChange(oldLine, newLine string) (oldStart, oldEnd, newStart, newEnd int) {
+	a, b := []rune(oldLine), []rune(newLine)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	if prefix == len(a) && prefix == len(b) {
+		return prefix, prefix, prefix, prefix
+	}
+
+	// extend the change starting or ending in the middle of a word.
+	if (prefix < len(a)-suffix && isWordRune(a[prefix])) || (prefix < len(b)-suffix && isWordRune(b[prefix])) {
+		for prefix > 0 && isWordRune(a[prefix-1]) {
+			prefix--
+		}
+	}
+	if (len(a)-suffix > prefix && isWordRune(a[len(a)-suffix-1])) || (len(b)-suffix > prefix && isWordRune(b[len(b)-suffix-1])) {
+		for suffix > 0 && isWordRune(a[len(a)-suffix]) {
+			suffix--
+		}
+	}
+	return prefix, len(a) - suffix, prefix, len(b) - suffix
+}
+
+// isWordRune reports whether r belongs to an identifier.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// oldRange returns the first line and the number of lines of the hunk in the
+// old text. The first line of an addition is the line following the added
+// lines.
+func oldRange(h *providers.DiffHunk) (start, count int) {
+	return h.OldStartLine, len(h.OldLines)
+}
+
+// newRange returns the first line and the number of lines of the hunk in the
+// new text. The first line of a deletion is the line following the deleted
+// lines.
+func newRange(h *providers.DiffHunk) (start, count int) {
+	if len(h.NewLines) == 0 {
+		return h.StartLine + 1, 0
+	}
+	return h.StartLine, len(h.NewLines)
+}
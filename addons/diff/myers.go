@@ -0,0 +1,247 @@
+package diff
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/oligo/gvcode/gutter/providers"
+)
+
+// DiffTexts returns the hunks changing oldText into newText, the line numbers
+// of the new lines referring to newText. The hunks have no context lines, as
+// the ones of git diff -U0. A last line without a line break differs from the
+// same line followed by one.
+func DiffTexts(oldText, newText []byte) []*providers.DiffHunk {
+	return DiffLines(SplitLines(string(oldText)), SplitLines(string(newText)))
+}
+
+// DiffReaders is like DiffTexts, reading the texts from old and new, e.g.,
+// the readers of the text sources returned by Editor.GetReader.
+func DiffReaders(old, new io.Reader) ([]*providers.DiffHunk, error) {
+	oldLines, err := readLines(old)
+	if err != nil {
+		return nil, err
+	}
+	newLines, err := readLines(new)
+	if err != nil {
+		return nil, err
+	}
+	return DiffLines(oldLines, newLines), nil
+}
+
+// DiffLines returns the hunks changing the lines of oldLines into newLines,
+// computed with the Myers diff algorithm. The lines are the ones returned by
+// SplitLines: all but the last one end with a line break, which is removed
+// from the lines of the hunks.
+func DiffLines(oldLines, newLines []string) []*providers.DiffHunk {
+	ids := make(map[string]int)
+	intern := func(lines []string) []int {
+		seq := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			seq[i] = id
+		}
+		return seq
+	}
+
+	d := newDiffer(intern(oldLines), intern(newLines))
+	d.compare(0, len(d.a), 0, len(d.b))
+	return d.hunks(oldLines, newLines)
+}
+
+// SplitLines splits text after its line breaks. The last line has no line
+// break, and is omitted if it is empty.
+func SplitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// readLines reads the lines of r like SplitLines.
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			lines = append(lines, line)
+		}
+		if errors.Is(err, io.EOF) {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// differ finds the lines changed between the sequences of line IDs a and b.
+type differ struct {
+	a, b []int
+	// changed marks the lines of a and b which are not in their longest
+	// common subsequence.
+	aChanged, bChanged []bool
+	// forward and backward are the furthest reaching paths of the diagonals,
+	// shared by the searches of the middle snakes.
+	forward, backward []int
+}
+
+func newDiffer(a, b []int) *differ {
+	size := 2*((len(a)+len(b)+1)/2) + 3
+	return &differ{
+		a:        a,
+		b:        b,
+		aChanged: make([]bool, len(a)),
+		bChanged: make([]bool, len(b)),
+		forward:  make([]int, size),
+		backward: make([]int, size),
+	}
+}
+
+// compare marks the changed lines of a[aLo:aHi] and b[bLo:bHi], splitting
+// the ranges at a middle snake of their shortest edit script until one of
+// them is empty.
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		aLo++
+		bLo++
+	}
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	switch {
+	case aLo == aHi:
+		for i := bLo; i < bHi; i++ {
+			d.bChanged[i] = true
+		}
+	case bLo == bHi:
+		for i := aLo; i < aHi; i++ {
+			d.aChanged[i] = true
+		}
+	default:
+		// the ranges differ at both ends, so that the script has at least two
+		// edits, and both halves are shorter than the ranges.
+		x, y := d.middleSnake(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aHi, y, bHi)
+	}
+}
+
+// middleSnake returns a point of the shortest edit script of a[aLo:aHi] and
+// b[bLo:bHi] splitting its edits in halves, found by searching the paths
+// from both ends until they overlap, as described by E. Myers in "An O(ND)
+// Difference Algorithm and Its Variations".
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+	// the diagonal k is x-y forward, and u-v backward, u and v being the
+	// distances from the ends.
+	off := len(d.forward) / 2
+	vf, vb := d.forward, d.backward
+	vf[off+1], vb[off+1] = 0, 0
+
+	for D := 0; D <= maxD; D++ {
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && vf[off+k-1] < vf[off+k+1]) {
+				x = vf[off+k+1]
+			} else {
+				x = vf[off+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			vf[off+k] = x
+			if kb := delta - k; odd && kb >= -(D-1) && kb <= D-1 && x+vb[off+kb] >= n {
+				return aLo + x0, bLo + y0
+			}
+		}
+
+		for k := -D; k <= D; k += 2 {
+			var u int
+			if k == -D || (k != D && vb[off+k-1] < vb[off+k+1]) {
+				u = vb[off+k+1]
+			} else {
+				u = vb[off+k-1] + 1
+			}
+			v := u - k
+			u0, v0 := u, v
+			for u < n && v < m && d.a[aHi-1-u] == d.b[bHi-1-v] {
+				u++
+				v++
+			}
+			vb[off+k] = u
+			if kf := delta - k; !odd && kf >= -D && kf <= D && u+vf[off+kf] >= n {
+				return aHi - u0, bHi - v0
+			}
+		}
+	}
+	// unreachable: the paths overlap after (n+m+1)/2 edits at most.
+	return aLo, bLo
+}
+
+// hunks groups the changed lines into hunks.
+func (d *differ) hunks(oldLines, newLines []string) []*providers.DiffHunk {
+	var hunks []*providers.DiffHunk
+	i, j := 0, 0
+	for i < len(d.a) || j < len(d.b) {
+		if i < len(d.a) && j < len(d.b) && !d.aChanged[i] && !d.bChanged[j] {
+			i++
+			j++
+			continue
+		}
+
+		oldStart, newStart := i, j
+		for i < len(d.a) && d.aChanged[i] {
+			i++
+		}
+		for j < len(d.b) && d.bChanged[j] {
+			j++
+		}
+		hunks = append(hunks, newHunk(oldLines[oldStart:i], newLines[newStart:j], oldStart, newStart))
+	}
+	return hunks
+}
+
+// newHunk returns the hunk replacing the old lines from the 0-based line
+// oldStart with the new lines from newStart, with the line numbers of git
+// diff: an empty new range starts at the line before it.
+func newHunk(oldLines, newLines []string, oldStart, newStart int) *providers.DiffHunk {
+	hunk := &providers.DiffHunk{
+		StartLine:    newStart,
+		EndLine:      newStart + max(len(newLines)-1, 0),
+		OldStartLine: oldStart,
+		OldLines:     trimLineBreaks(oldLines),
+		NewLines:     trimLineBreaks(newLines),
+	}
+	if len(newLines) == 0 {
+		hunk.StartLine--
+		hunk.EndLine--
+	}
+	finalizeHunkType(hunk)
+	return hunk
+}
+
+// trimLineBreaks returns the lines without their line break.
+func trimLineBreaks(lines []string) []string {
+	trimmed := make([]string, len(lines))
+	for i, line := range lines {
+		trimmed[i] = strings.TrimSuffix(line, "\n")
+	}
+	return trimmed
+}
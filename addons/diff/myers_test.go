@@ -0,0 +1,159 @@
+package diff
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/oligo/gvcode/gutter/providers"
+)
+
+func TestDiffTexts(t *testing.T) {
+	cases := []struct {
+		name     string
+		old, new string
+		want     []providers.DiffHunk
+	}{
+		{
+			name: "identical",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+		},
+		{
+			name: "modified",
+			old:  "a\nb\nc\n",
+			new:  "a\nB\nc\n",
+			want: []providers.DiffHunk{
+				{Type: providers.DiffModified, StartLine: 1, EndLine: 1, OldStartLine: 1, OldLines: []string{"b"}, NewLines: []string{"B"}},
+			},
+		},
+		{
+			name: "added",
+			old:  "a\nc\n",
+			new:  "a\nb1\nb2\nc\n",
+			want: []providers.DiffHunk{
+				{Type: providers.DiffAdded, StartLine: 1, EndLine: 2, OldStartLine: 1, NewLines: []string{"b1", "b2"}},
+			},
+		},
+		{
+			name: "deleted",
+			old:  "a\nb\nc\n",
+			new:  "a\nc\n",
+			want: []providers.DiffHunk{
+				{Type: providers.DiffDeleted, StartLine: 0, EndLine: 0, OldStartLine: 1, OldLines: []string{"b"}},
+			},
+		},
+		{
+			name: "deleted at the start",
+			old:  "a\nb\n",
+			new:  "b\n",
+			want: []providers.DiffHunk{
+				{Type: providers.DiffDeleted, StartLine: -1, EndLine: -1, OldStartLine: 0, OldLines: []string{"a"}},
+			},
+		},
+		{
+			name: "missing line break",
+			old:  "a\nb\n",
+			new:  "a\nb",
+			want: []providers.DiffHunk{
+				{Type: providers.DiffModified, StartLine: 1, EndLine: 1, OldStartLine: 1, OldLines: []string{"b"}, NewLines: []string{"b"}},
+			},
+		},
+		{
+			name: "several hunks",
+			old:  "a\nb\nc\nd\ne\n",
+			new:  "x\na\nc\nd\nE\n",
+			want: []providers.DiffHunk{
+				{Type: providers.DiffAdded, StartLine: 0, EndLine: 0, OldStartLine: 0, NewLines: []string{"x"}},
+				{Type: providers.DiffDeleted, StartLine: 1, EndLine: 1, OldStartLine: 1, OldLines: []string{"b"}},
+				{Type: providers.DiffModified, StartLine: 4, EndLine: 4, OldStartLine: 4, OldLines: []string{"e"}, NewLines: []string{"E"}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hunks := DiffTexts([]byte(tc.old), []byte(tc.new))
+			if len(hunks) != len(tc.want) {
+				t.Fatalf("got %d hunks %+v, want %d", len(hunks), hunks, len(tc.want))
+			}
+			for i, h := range hunks {
+				want := tc.want[i]
+				if h.Type != want.Type || h.StartLine != want.StartLine || h.EndLine != want.EndLine ||
+					h.OldStartLine != want.OldStartLine || !slices.Equal(h.OldLines, want.OldLines) ||
+					!slices.Equal(h.NewLines, want.NewLines) {
+					t.Errorf("got hunk %d %+v, want %+v", i, *h, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffReaders(t *testing.T) {
+	hunks, err := DiffReaders(strings.NewReader("a\nb\nc"), strings.NewReader("a\nc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hunks) != 1 || hunks[0].Type != providers.DiffDeleted || hunks[0].OldStartLine != 1 {
+		t.Fatalf("got hunks %+v, want the deletion of line 1", hunks)
+	}
+}
+
+// TestDiffLinesRandom checks that the hunks change the old lines into the new
+// ones, with the fewest changed lines.
+func TestDiffLinesRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, r.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+
+	for range 2000 {
+		oldLines, newLines := randomLines(), randomLines()
+		hunks := DiffLines(oldLines, newLines)
+
+		var got []string
+		changed, next := 0, 0
+		for _, h := range hunks {
+			start, count := newRange(h)
+			if h.OldStartLine < next || start-len(got)+next != h.OldStartLine {
+				t.Fatalf("hunks %+v of %q -> %q are not in order", hunks, oldLines, newLines)
+			}
+			got = append(got, oldLines[next:h.OldStartLine]...)
+			got = append(got, h.NewLines...)
+			if len(h.NewLines) != count {
+				t.Fatalf("hunk %+v has %d new lines, want %d", h, len(h.NewLines), count)
+			}
+			next = h.OldStartLine + len(h.OldLines)
+			changed += len(h.OldLines) + len(h.NewLines)
+		}
+		got = append(got, oldLines[next:]...)
+
+		if !slices.Equal(got, newLines) {
+			t.Fatalf("hunks %+v change %q into %q, want %q", hunks, oldLines, got, newLines)
+		}
+		if want := len(oldLines) + len(newLines) - 2*lcsLen(oldLines, newLines); changed != want {
+			t.Fatalf("hunks %+v of %q -> %q change %d lines, want %d", hunks, oldLines, newLines, changed, want)
+		}
+	}
+}
+
+// lcsLen returns the length of the longest common subsequence of a and b.
+func lcsLen(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
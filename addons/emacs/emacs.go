@@ -0,0 +1,260 @@
+// Package emacs implements an addon binding Emacs style keys to the editor:
+// the kill ring, the mark and the basic motions.
+package emacs
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"gioui.org/layout"
+	"github.com/oligo/gvcode"
+)
+
+// Emacs binds the Emacs keys in the keymap of an editor. Killed text is
+// pushed to the clipboard ring of the editor and written to the host
+// clipboard, and yanking reads the host clipboard first, so that text copied
+// in other applications can be yanked too.
+//
+// The keymap of the editor is modified, so it should not be shared with
+// editors not using Emacs.
+type Emacs struct {
+	editor *gvcode.Editor
+	// mark is the other end of the region when markActive is true.
+	mark       int
+	markActive bool
+	// killEnd is the caret position after the last kill, and killText is
+	// the ring entry it produced. A kill at killEnd appends to the entry.
+	killEnd  int
+	killText string
+	// yankStart and yankEnd are the range of the last yanked text.
+	yankStart int
+	yankEnd   int
+	yankText  string
+	// saved are the bindings replaced by Enable.
+	saved   []gvcode.Binding
+	enabled bool
+}
+
+// New creates Emacs for the editor. Call Enable to bind the keys.
+func New(editor *gvcode.Editor) *Emacs {
+	return &Emacs{editor: editor, killEnd: -1, yankEnd: -1}
+}
+
+// Bindings returns the key bindings of Emacs.
+func (em *Emacs) Bindings() []gvcode.Binding {
+	return []gvcode.Binding{
+		{Keys: "Ctrl+A", Command: em.motion("emacsLineStart", gvcode.MoveLineStart)},
+		{Keys: "Ctrl+E", Command: em.motion("emacsLineEnd", gvcode.MoveLineEnd)},
+		{Keys: "Alt+F", Command: em.motion("emacsForwardWord", gvcode.MoveWordRight)},
+		{Keys: "Alt+B", Command: em.motion("emacsBackwardWord", gvcode.MoveWordLeft)},
+		{Keys: "Ctrl+Space", Command: gvcode.Command{Name: "emacsSetMark", Run: em.setMark}},
+		{Keys: "Ctrl+G", Command: gvcode.Command{Name: "emacsQuit", Run: em.quit}},
+		{Keys: "Ctrl+K", Command: gvcode.Command{Name: "emacsKillLine", Run: em.killLine}},
+		{Keys: "Ctrl+W", Command: gvcode.Command{Name: "emacsKillRegion", Run: em.killRegion}},
+		{Keys: "Alt+W", Command: gvcode.Command{Name: "emacsCopyRegion", Run: em.copyRegion}},
+		{Keys: "Ctrl+Y", Command: gvcode.Command{Name: "emacsYank", Run: em.yank}},
+		{Keys: "Alt+Y", Command: gvcode.Command{Name: "emacsYankPop", Run: em.yankPop}},
+	}
+}
+
+// Enable binds the keys in the keymap of the editor. The replaced bindings
+// are restored by Disable.
+func (em *Emacs) Enable() error {
+	if em.enabled {
+		return nil
+	}
+
+	km := em.editor.Keymap()
+	em.saved = em.saved[:0]
+	var bound []string
+	for _, b := range em.Bindings() {
+		if cmd, ok := km.Lookup(b.Keys); ok {
+			em.saved = append(em.saved, gvcode.Binding{Keys: b.Keys, Command: cmd})
+		}
+		if err := km.Bind(b.Keys, b.Command); err != nil {
+			em.rollback(bound)
+			return err
+		}
+		bound = append(bound, b.Keys)
+	}
+	em.enabled = true
+	return nil
+}
+
+// rollback unbinds the keys bound by a failed Enable, and restores the
+// bindings they replaced.
+func (em *Emacs) rollback(bound []string) {
+	km := em.editor.Keymap()
+	for _, keys := range bound {
+		_ = km.Unbind(keys)
+	}
+	for _, b := range em.saved {
+		_ = km.Bind(b.Keys, b.Command)
+	}
+	em.saved = em.saved[:0]
+}
+
+// Disable unbinds the keys, restoring the bindings replaced by Enable.
+func (em *Emacs) Disable() {
+	if !em.enabled {
+		return
+	}
+
+	km := em.editor.Keymap()
+	for _, b := range em.Bindings() {
+		_ = km.Unbind(b.Keys)
+	}
+	for _, b := range em.saved {
+		_ = km.Bind(b.Keys, b.Command)
+	}
+	em.saved = em.saved[:0]
+	em.markActive = false
+	em.enabled = false
+}
+
+// Mark returns the position of the mark, and whether it is active.
+func (em *Emacs) Mark() (int, bool) {
+	return em.mark, em.markActive
+}
+
+func (em *Emacs) caret() int {
+	caret, _ := em.editor.Selection()
+	return caret
+}
+
+// motion wraps the motion cmd so that it extends the region if the mark is
+// active.
+func (em *Emacs) motion(name string, cmd gvcode.Command) gvcode.Command {
+	return gvcode.Command{Name: name, Run: func(gtx layout.Context, e *gvcode.Editor) gvcode.EditorEvent {
+		caret := em.caret()
+		// collapse the selection first, or the motion would start from an
+		// end of it.
+		e.SetCaret(caret, caret)
+		evt := cmd.Run(gtx, e)
+		if em.markActive {
+			e.SetCaret(em.caret(), em.mark)
+		}
+		return evt
+	}}
+}
+
+func (em *Emacs) setMark(gtx layout.Context, e *gvcode.Editor) gvcode.EditorEvent {
+	em.mark = em.caret()
+	em.markActive = true
+	e.SetCaret(em.mark, em.mark)
+	return nil
+}
+
+func (em *Emacs) quit(gtx layout.Context, e *gvcode.Editor) gvcode.EditorEvent {
+	em.markActive = false
+	caret := em.caret()
+	e.SetCaret(caret, caret)
+	return nil
+}
+
+// region returns the selected text range.
+func (em *Emacs) region() (start, end int) {
+	start, end = em.editor.Selection()
+	return min(start, end), max(start, end)
+}
+
+// kill deletes the text in [start, end), and saves it to the clipboard ring.
+// Consecutive kills are accumulated into one entry.
+func (em *Emacs) kill(gtx layout.Context, start, end int) {
+	text := em.editor.ReadRange(start, end)
+	if text == "" {
+		return
+	}
+
+	ring := em.editor.ClipboardRing()
+	if current, ok := ring.Current(); ok && start == em.killEnd && current == em.killText {
+		ring.Append(text)
+	} else {
+		ring.Push(text)
+	}
+	em.killText, _ = ring.Current()
+	em.editor.WriteClipboard(gtx, em.killText)
+
+	em.editor.Transaction(func(tx *gvcode.EditTx) {
+		tx.Delete(start, end)
+		tx.SetCaret(start, start)
+	})
+	em.killEnd = start
+	em.markActive = false
+}
+
+// killLine kills the text from the caret to the end of the line, or the line
+// break if the caret is at the end of the line.
+func (em *Emacs) killLine(gtx layout.Context, e *gvcode.Editor) gvcode.EditorEvent {
+	caret := em.caret()
+	end := lineEnd(e, caret)
+	if end == caret {
+		end = min(caret+1, e.Len())
+	}
+	em.kill(gtx, caret, end)
+	return nil
+}
+
+func (em *Emacs) killRegion(gtx layout.Context, e *gvcode.Editor) gvcode.EditorEvent {
+	start, end := em.region()
+	em.kill(gtx, start, end)
+	return nil
+}
+
+func (em *Emacs) copyRegion(gtx layout.Context, e *gvcode.Editor) gvcode.EditorEvent {
+	start, end := em.region()
+	e.WriteClipboard(gtx, e.ReadRange(start, end))
+	em.killEnd = -1
+	em.markActive = false
+	caret := em.caret()
+	e.SetCaret(caret, caret)
+	return nil
+}
+
+// yank inserts the host clipboard text at the caret, and sets the mark at
+// the start of it.
+func (em *Emacs) yank(gtx layout.Context, e *gvcode.Editor) gvcode.EditorEvent {
+	e.ReadClipboard(gtx, func(text string) {
+		caret := em.caret()
+		em.insertYank(caret, caret, text)
+	})
+	return nil
+}
+
+// yankPop replaces the text inserted by the last yank with the previous
+// entry of the clipboard ring.
+func (em *Emacs) yankPop(gtx layout.Context, e *gvcode.Editor) gvcode.EditorEvent {
+	if em.yankEnd < 0 || em.caret() != em.yankEnd || e.ReadRange(em.yankStart, em.yankEnd) != em.yankText {
+		// the last command is not a yank.
+		return nil
+	}
+	if text, ok := e.ClipboardRing().Rotate(1); ok {
+		em.insertYank(em.yankStart, em.yankEnd, text)
+	}
+	return nil
+}
+
+func (em *Emacs) insertYank(start, end int, text string) {
+	em.editor.Transaction(func(tx *gvcode.EditTx) {
+		n := tx.Replace(start, end, text)
+		tx.SetCaret(start+n, start+n)
+		em.yankStart, em.yankEnd, em.yankText = start, start+n, text
+	})
+	em.mark = start
+	em.markActive = false
+	em.killEnd = -1
+}
+
+// lineEnd returns the offset of the line break after off, or the end of the
+// text.
+func lineEnd(e *gvcode.Editor, off int) int {
+	const chunk = 256
+	for off < e.Len() {
+		text := e.ReadRange(off, min(off+chunk, e.Len()))
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			return off + utf8.RuneCountInString(text[:i])
+		}
+		off += utf8.RuneCountInString(text)
+	}
+	return off
+}
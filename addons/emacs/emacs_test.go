@@ -0,0 +1,131 @@
+package emacs
+
+import (
+	"testing"
+
+	"gioui.org/layout"
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/internal/editortest"
+)
+
+func newEmacs(t *testing.T, content string) (*Emacs, *gvcode.Editor, layout.Context) {
+	t.Helper()
+	editor := editortest.NewEditor(t, content)
+	gtx := editortest.NewContext()
+	editor.SetCaret(0, 0)
+
+	em := New(editor)
+	if err := em.Enable(); err != nil {
+		t.Fatal(err)
+	}
+	return em, editor, gtx
+}
+
+func TestKillAndYank(t *testing.T) {
+	em, editor, gtx := newEmacs(t, "foo bar\nbaz\nqux")
+
+	// consecutive kills are accumulated into one entry.
+	em.killLine(gtx, editor)
+	em.killLine(gtx, editor)
+	if got, want := editor.Text(), "baz\nqux"; got != want {
+		t.Fatalf("kill line: got %q, want %q", got, want)
+	}
+	ring := editor.ClipboardRing()
+	if got, _ := ring.Current(); got != "foo bar\n" {
+		t.Fatalf("kill ring: got %q", got)
+	}
+
+	// a kill after a motion starts a new entry.
+	em.motion("", gvcode.MoveLineEnd).Run(gtx, editor)
+	em.killLine(gtx, editor)
+	if got := ring.Entries(); len(got) != 2 || got[0] != "\n" {
+		t.Fatalf("kill ring entries: got %q", got)
+	}
+
+	// yank, then cycle to the previous kill.
+	em.insertYank(3, 3, "\n")
+	if got, want := editor.Text(), "baz\nqux"; got != want {
+		t.Fatalf("yank: got %q, want %q", got, want)
+	}
+	em.yankPop(gtx, editor)
+	if got, want := editor.Text(), "bazfoo bar\nqux"; got != want {
+		t.Fatalf("yank pop: got %q, want %q", got, want)
+	}
+	if caret, _ := editor.Selection(); caret != 11 {
+		t.Fatalf("yank pop: got caret %d, want 11", caret)
+	}
+
+	// yank pop after another command is a no-op.
+	em.motion("", gvcode.MoveLeft).Run(gtx, editor)
+	em.yankPop(gtx, editor)
+	if got, want := editor.Text(), "bazfoo bar\nqux"; got != want {
+		t.Fatalf("yank pop after motion: got %q, want %q", got, want)
+	}
+}
+
+func TestMarkRegion(t *testing.T) {
+	em, editor, gtx := newEmacs(t, "foo bar baz")
+
+	em.setMark(gtx, editor)
+	em.motion("", gvcode.MoveWordRight).Run(gtx, editor)
+	em.motion("", gvcode.MoveWordRight).Run(gtx, editor)
+	start, end := editor.Selection()
+	if start <= 0 || end != 0 {
+		t.Fatalf("region: got selection (%d, %d)", start, end)
+	}
+
+	em.killRegion(gtx, editor)
+	if got, _ := editor.ClipboardRing().Current(); got+editor.Text() != "foo bar baz" {
+		t.Fatalf("kill region: got %q and %q", got, editor.Text())
+	}
+	if _, active := em.Mark(); active {
+		t.Fatal("expected the mark to be inactive after a kill")
+	}
+}
+
+func TestEnableDisable(t *testing.T) {
+	em, editor, _ := newEmacs(t, "")
+	km := editor.Keymap()
+	if cmd, _ := km.Lookup("Ctrl+K"); cmd.Name != "emacsKillLine" {
+		t.Fatalf("got command %q bound to Ctrl+K", cmd.Name)
+	}
+
+	em.Disable()
+	if _, ok := km.Lookup("Ctrl+K"); ok {
+		t.Fatal("expected Ctrl+K to be unbound")
+	}
+	if cmd, _ := km.Lookup("Ctrl+A"); cmd.Name != gvcode.SelectAll.Name {
+		t.Fatalf("got command %q bound to Ctrl+A, want the restored %q", cmd.Name, gvcode.SelectAll.Name)
+	}
+}
+
+func TestEnableConflict(t *testing.T) {
+	editor := &gvcode.Editor{}
+	km := editor.Keymap()
+	// Ctrl+K is the prefix of a chord, and Ctrl+Y is bound after it.
+	custom := gvcode.Command{Name: "custom", Run: func(layout.Context, *gvcode.Editor) gvcode.EditorEvent { return nil }}
+	if err := km.Bind("Ctrl+K Ctrl+C", custom); err != nil {
+		t.Fatal(err)
+	}
+	if err := km.Bind("Ctrl+Y", custom); err != nil {
+		t.Fatal(err)
+	}
+
+	em := New(editor)
+	if err := em.Enable(); err == nil {
+		t.Fatal("expected Enable to fail on the conflicting chord")
+	}
+	// only the keys bound by Enable are rolled back.
+	for keys, want := range map[string]string{
+		"Ctrl+A":        gvcode.SelectAll.Name,
+		"Ctrl+K Ctrl+C": "custom",
+		"Ctrl+Y":        "custom",
+	} {
+		if cmd, _ := km.Lookup(keys); cmd.Name != want {
+			t.Errorf("got command %q bound to %s, want %q", cmd.Name, keys, want)
+		}
+	}
+	if _, ok := km.Lookup("Ctrl+E"); ok {
+		t.Error("expected Ctrl+E to be unbound")
+	}
+}
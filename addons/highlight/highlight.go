@@ -0,0 +1,216 @@
+// Package highlight implements an addon highlighting the text of an editor
+// with the lexers of chroma. The text is lexed in a background goroutine
+// after the edits settle down, and only the lines around the edits are lexed
+// again, their tokens being updated with SetSyntaxTokensInRange.
+package highlight
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+const (
+	// defaultDelay is the default delay before lexing the edited text.
+	defaultDelay = 150 * time.Millisecond
+	// checkDelay is the delay after the last edit before the whole text is
+	// lexed again, to fix the tokens of an incremental lexing which restarted
+	// the lexer in another state than it had at that point, e.g., in a
+	// nested language.
+	checkDelay = time.Second
+	// pollInterval is how often the highlighter checks for the result of
+	// the lexing in progress.
+	pollInterval = 30 * time.Millisecond
+)
+
+// ErrNoLexer is returned when chroma has no lexer for a language.
+var ErrNoLexer = errors.New("no lexer for the language")
+
+// Highlighter sets the syntax tokens of an editor from a chroma lexer. Its
+// Update method must be called in each frame of the editor, before its
+// layout.
+type Highlighter struct {
+	// Delay is the delay after an edit before the text is lexed again, so
+	// that typing doesn't lex each keystroke. It defaults to 150ms.
+	Delay time.Duration
+	// Scope maps the token types to the scopes of the color scheme. It
+	// defaults to ScopeOf.
+	Scope func(chroma.TokenType) syntax.StyleScope
+
+	editor *gvcode.Editor
+	lexer  chroma.Lexer
+
+	// text and tokens are the text whose tokens were last set to the
+	// editor, and its tokens. version is the text version of the editor.
+	text    string
+	tokens  []lexToken
+	version int
+	// exact is true if the tokens were produced by lexing the whole text.
+	exact bool
+	// editedVersion and editedAt track the last edit, to debounce lexing.
+	editedVersion int
+	editedAt      time.Time
+	// lexing is the text version being lexed, or -1.
+	lexing  int
+	results chan lexResult
+}
+
+// New returns a highlighter of the editor for a language, which is looked up
+// by name, alias or file extension among the lexers of chroma, e.g., "go" or
+// "python". An empty language uses the ID of the language set to the editor.
+func New(editor *gvcode.Editor, language string) (*Highlighter, error) {
+	h := &Highlighter{
+		editor:  editor,
+		version: -1,
+		lexing:  -1,
+		results: make(chan lexResult, 1),
+	}
+	if err := h.SetLanguage(language); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// SetLanguage switches the lexer of the highlighter, and highlights the text
+// again. See New for how the language is looked up.
+func (h *Highlighter) SetLanguage(language string) error {
+	// the tokens of the previous lexer are replaced.
+	h.collect(true)
+	if language == "" {
+		language = h.editor.Language().ID
+	}
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		return ErrNoLexer
+	}
+	h.lexer = chroma.Coalesce(lexer)
+	h.text, h.tokens, h.version, h.exact = "", nil, -1, false
+	return nil
+}
+
+// Update sets the tokens of the text lexed in the background to the editor,
+// and starts lexing the text if it has been changed for longer than Delay.
+func (h *Highlighter) Update(gtx layout.Context) {
+	h.collect(false)
+
+	if h.lexing >= 0 {
+		gtx.Execute(op.InvalidateCmd{At: gtx.Now.Add(pollInterval)})
+		return
+	}
+	version := h.editor.TextVersion()
+	if version == h.version {
+		if h.exact {
+			return
+		}
+		if due := h.editedAt.Add(checkDelay); gtx.Now.Before(due) {
+			gtx.Execute(op.InvalidateCmd{At: due})
+			return
+		}
+		h.start(version, true)
+		gtx.Execute(op.InvalidateCmd{At: gtx.Now.Add(pollInterval)})
+		return
+	}
+	if version != h.editedVersion {
+		h.editedVersion, h.editedAt = version, gtx.Now
+	}
+	// the text shown first is lexed without waiting.
+	if due := h.editedAt.Add(h.delay()); h.tokens != nil && gtx.Now.Before(due) {
+		gtx.Execute(op.InvalidateCmd{At: due})
+		return
+	}
+
+	h.start(version, false)
+	gtx.Execute(op.InvalidateCmd{At: gtx.Now.Add(pollInterval)})
+}
+
+func (h *Highlighter) delay() time.Duration {
+	if h.Delay <= 0 {
+		return defaultDelay
+	}
+	return h.Delay
+}
+
+// start lexes the text of the editor in the background, incrementally unless
+// full is true.
+func (h *Highlighter) start(version int, full bool) {
+	h.lexing = version
+	lexer, prevText, prev, text := h.lexer, h.text, h.tokens, h.editor.Text()
+	if full {
+		prevText, prev = "", nil
+	}
+	results := h.results
+	go func() {
+		results <- relex(lexer, prevText, prev, text)
+	}()
+}
+
+// collect sets the tokens of the finished lexing to the editor. It waits for
+// the lexing in progress if wait is true.
+func (h *Highlighter) collect(wait bool) {
+	if h.lexing < 0 {
+		return
+	}
+	var r lexResult
+	if wait {
+		r = <-h.results
+	} else {
+		select {
+		case r = <-h.results:
+		default:
+			return
+		}
+	}
+
+	version := h.lexing
+	h.lexing = -1
+	if r.err != nil {
+		slog.Error("lexing failed", "error", r.err)
+		h.version = version
+		return
+	}
+	if version != h.editor.TextVersion() {
+		// the text was edited while it was lexed, the tokens would not
+		// match it.
+		return
+	}
+
+	if r.exact && h.tokens != nil && r.text == h.text {
+		// only the tokens fixed by lexing the whole text are updated.
+		r.start, r.end = changedRange(h.tokens, r.tokens)
+	}
+	if r.start == 0 && r.end >= h.editor.Len() {
+		h.editor.SetSyntaxTokens(h.syntaxTokens(r.tokens, 0, r.end)...)
+	} else if r.start < r.end {
+		h.editor.SetSyntaxTokensInRange(r.start, r.end, h.syntaxTokens(r.tokens, r.start, r.end)...)
+	}
+	h.text, h.tokens, h.version, h.exact = r.text, r.tokens, version, r.exact
+}
+
+// syntaxTokens converts the tokens overlapping [start, end) to the tokens of
+// the editor, dropping the ones without a scope.
+func (h *Highlighter) syntaxTokens(tokens []lexToken, start, end int) []syntax.Token {
+	scope := h.Scope
+	if scope == nil {
+		scope = ScopeOf
+	}
+	var result []syntax.Token
+	for _, tk := range tokens {
+		if tk.end <= start {
+			continue
+		}
+		if tk.start >= end {
+			break
+		}
+		if s := scope(tk.typ); s != "" {
+			result = append(result, syntax.Token{Start: tk.start, End: tk.end, Scope: s})
+		}
+	}
+	return result
+}
@@ -0,0 +1,141 @@
+package highlight
+
+import (
+	"image"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+const goSource = `package main
+
+import "fmt"
+
+/* a block
+comment */
+func main() {
+	s := "hello\n"
+	fmt.Println(s, 42)
+}
+`
+
+func TestRelexMatchesFullLexing(t *testing.T) {
+	lexer := chroma.Coalesce(lexers.Get("go"))
+	full := relex(lexer, "", nil, goSource)
+	if full.err != nil {
+		t.Fatal(full.err)
+	}
+
+	edits := []struct {
+		name     string
+		old, new string
+	}{
+		{"rename", "s :=", "str :="},
+		{"open a comment", "func main", "/* func main"},
+		{"close a string", `"hello\n"`, `"hel"lo\n"`},
+		{"unicode", "42", "4€2"},
+		{"delete a line", "\tfmt.Println(s, 42)\n", ""},
+		{"append", "}\n", "}\n// end"},
+	}
+	for _, edit := range edits {
+		t.Run(edit.name, func(t *testing.T) {
+			text := strings.Replace(goSource, edit.old, edit.new, 1)
+			got := relex(lexer, goSource, full.tokens, text)
+			want := relex(lexer, "", nil, text)
+			if got.err != nil || want.err != nil {
+				t.Fatal(got.err, want.err)
+			}
+			if !slices.Equal(got.tokens, want.tokens) {
+				t.Fatalf("got tokens %v, want %v", got.tokens, want.tokens)
+			}
+
+			// the tokens outside of the updated range are unchanged.
+			edited := strings.Index(goSource, edit.old)
+			if editedRunes := len([]rune(goSource[:edited])); got.start > editedRunes {
+				t.Fatalf("updated range starts at %d, after the edit at %d", got.start, editedRunes)
+			}
+		})
+	}
+}
+
+func TestRelexStopsAfterEdit(t *testing.T) {
+	lexer := chroma.Coalesce(lexers.Get("go"))
+	full := relex(lexer, "", nil, goSource)
+	text := strings.Replace(goSource, "s :=", "str :=", 1)
+	got := relex(lexer, goSource, full.tokens, text)
+	// the lines after the edited line are not lexed again.
+	if end := strings.Index(text, "fmt.Println"); got.end > len([]rune(text[:end])) {
+		t.Fatalf("updated range %d-%d extends past the edited line", got.start, got.end)
+	}
+}
+
+func TestHighlighter(t *testing.T) {
+	editor := &gvcode.Editor{}
+	scheme := syntax.ColorScheme{}
+	scheme.AddStyle("keyword", syntax.Bold, color.Color{}, color.Color{})
+	editor.WithOptions(gvcode.WithColorScheme(scheme))
+	editor.SetText(goSource)
+	h, err := New(editor, "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600)), Now: time.Now()}
+	h.Update(gtx)
+	h.collect(true)
+	if got := editor.ScopeAt(0); got.Root() != "keyword" {
+		t.Fatalf("ScopeAt(0) = %q, want a keyword", got)
+	}
+
+	// edits are lexed after the delay.
+	editor.SetCaret(0, 0)
+	editor.Insert("// ")
+	h.Update(gtx)
+	if h.lexing >= 0 {
+		t.Fatal("expected lexing to be delayed")
+	}
+	gtx.Now = gtx.Now.Add(h.delay())
+	h.Update(gtx)
+	h.collect(true)
+	if got := editor.ScopeAt(5); got != "comment.line" {
+		t.Fatalf("ScopeAt(5) = %q, want comment.line", got)
+	}
+
+	// the incremental tokens are checked by lexing the whole text later.
+	if h.exact {
+		t.Fatal("expected the edit to be lexed incrementally")
+	}
+	gtx.Now = gtx.Now.Add(checkDelay)
+	h.Update(gtx)
+	h.collect(true)
+	if !h.exact {
+		t.Fatal("expected the whole text to be lexed")
+	}
+}
+
+func TestChangedRange(t *testing.T) {
+	prev := []lexToken{{0, 2, chroma.Keyword}, {2, 3, chroma.Text}, {3, 8, chroma.String}, {8, 9, chroma.Text}}
+	tokens := []lexToken{{0, 2, chroma.Keyword}, {2, 3, chroma.Text}, {3, 5, chroma.Name}, {5, 8, chroma.Text}, {8, 9, chroma.Text}}
+	if start, end := changedRange(prev, tokens); start != 3 || end != 8 {
+		t.Fatalf("got %d-%d, want 3-8", start, end)
+	}
+	if start, end := changedRange(prev, prev); start != end {
+		t.Fatalf("got %d-%d for equal tokens", start, end)
+	}
+}
+
+func TestNoLexer(t *testing.T) {
+	editor := &gvcode.Editor{}
+	if _, err := New(editor, "no such language"); err != ErrNoLexer {
+		t.Fatalf("got %v, want ErrNoLexer", err)
+	}
+}
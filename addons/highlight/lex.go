@@ -0,0 +1,269 @@
+package highlight
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// maxRestartLines bounds the lines searched backwards for a point to restart
+// the lexer from, before lexing the whole text again.
+const maxRestartLines = 500
+
+// lexToken is a token of the lexer, in rune offsets.
+type lexToken struct {
+	start, end int
+	typ        chroma.TokenType
+}
+
+// lexResult is the outcome of lexing text: the tokens of the whole text, and
+// the rune range [start, end) whose tokens differ from the previous ones.
+type lexResult struct {
+	text       string
+	tokens     []lexToken
+	start, end int
+	// exact is true if the whole text was lexed.
+	exact bool
+	err   error
+}
+
+// relex lexes text, reusing the tokens of prevText. The lexer is restarted
+// from a line before the edited text, where the previous tokens show it was
+// not inside of a string or a comment, and stops after the edited text as
+// soon as it produces the previous tokens again at the start of a line. The
+// previous tokens are nil to lex the whole text.
+func relex(lexer chroma.Lexer, prevText string, prev []lexToken, text string) lexResult {
+	textLen := utf8.RuneCountInString(text)
+	if prev == nil {
+		tokens, _, err := lex(lexer, text, 0, nil)
+		return lexResult{text: text, tokens: clipTokens(tokens, textLen), start: 0, end: textLen, exact: true, err: err}
+	}
+	if prevText == text {
+		return lexResult{text: text, tokens: prev}
+	}
+
+	prefix, suffix := commonAffixes(prevText, text)
+	prefixRunes := utf8.RuneCountInString(text[:prefix])
+	suffixRunes := utf8.RuneCountInString(text[len(text)-suffix:])
+	delta := textLen - utf8.RuneCountInString(prevText)
+
+	restart, restartRunes, first := restartPoint(text, prefix, prefixRunes, prev)
+	conv := &convergence{
+		prev:      prev,
+		from:      first,
+		changeEnd: textLen - suffixRunes,
+		delta:     delta,
+	}
+	tokens, converged, err := lex(lexer, text[restart:], restartRunes, conv.match)
+	if err != nil {
+		return lexResult{err: err}
+	}
+
+	result := make([]lexToken, 0, len(prev)+len(tokens))
+	result = append(result, prev[:first]...)
+	start, end := restartRunes, textLen
+	result, start = appendCoalesced(result, start, tokens...)
+	if converged {
+		end = conv.offset
+		for i, tk := range prev[conv.index:] {
+			tk = lexToken{start: tk.start + delta, end: tk.end + delta, typ: tk.typ}
+			if i == 0 {
+				// a merged token is updated as a whole.
+				var merged int
+				if result, merged = appendCoalesced(result, -1, tk); merged >= 0 {
+					end = tk.end
+				}
+				continue
+			}
+			result = append(result, tk)
+		}
+	}
+	return lexResult{text: text, tokens: clipTokens(result, textLen), start: start, end: min(end, textLen)}
+}
+
+// appendCoalesced appends tokens to result, merging the first one into the
+// last token of result if they have the same type, like chroma.Coalesce does.
+// It returns the start of the merged token, or start if there was no merge.
+func appendCoalesced(result []lexToken, start int, tokens ...lexToken) ([]lexToken, int) {
+	if n := len(result); n > 0 && len(tokens) > 0 && result[n-1].typ == tokens[0].typ && result[n-1].end == tokens[0].start {
+		result[n-1].end = tokens[0].end
+		start = result[n-1].start
+		tokens = tokens[1:]
+	}
+	return append(result, tokens...), start
+}
+
+// lex lexes text, whose first rune is at the rune offset base. If stop is not
+// nil, lexing stops before the first token it matches, which is told whether
+// it is the first token of a line. It reports whether lexing was stopped.
+func lex(lexer chroma.Lexer, text string, base int, stop func(off int, tk chroma.Token, lineStart bool) bool) ([]lexToken, bool, error) {
+	it, err := lexer.Tokenise(nil, text)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var tokens []lexToken
+	off := base
+	lineStart := true
+	for tk := it(); tk != chroma.EOF; tk = it() {
+		if tk.Value == "" {
+			continue
+		}
+		if stop != nil && stop(off, tk, lineStart) {
+			return tokens, true, nil
+		}
+		n := utf8.RuneCountInString(tk.Value)
+		tokens = append(tokens, lexToken{start: off, end: off + n, typ: tk.Type})
+		off += n
+		// the first token of a line may follow its indentation.
+		lineStart = strings.Contains(tk.Value, "\n")
+	}
+	return tokens, false, nil
+}
+
+// convergence finds where the new tokens produced after the edited text are
+// the previous tokens shifted by the edit.
+type convergence struct {
+	prev []lexToken
+	// from is the index of the first previous token to search.
+	from int
+	// changeEnd is the end of the edited text, in the new text.
+	changeEnd int
+	delta     int
+
+	// offset and index are the rune offset in the new text, and the index
+	// of the matching previous token, when converged.
+	offset, index int
+}
+
+func (c *convergence) match(off int, tk chroma.Token, lineStart bool) bool {
+	if !lineStart || off < c.changeEnd {
+		return false
+	}
+	prevOff := off - c.delta
+	i := c.from + sort.Search(len(c.prev)-c.from, func(i int) bool {
+		return c.prev[c.from+i].start >= prevOff
+	})
+	if i >= len(c.prev) {
+		return false
+	}
+	old := c.prev[i]
+	if old.start != prevOff || old.typ != tk.Type || old.end-old.start != utf8.RuneCountInString(tk.Value) {
+		return false
+	}
+	// like for restarting, tokens in strings and comments don't show the
+	// lexer is back to the same state.
+	if !restartable(c.prev, i) {
+		return false
+	}
+	c.offset, c.index = off, i
+	return true
+}
+
+// restartPoint returns the byte and rune offsets of the point to restart the
+// lexer from, before the byte offset prefix of the first edit, and the index
+// of the first previous token at or after it.
+func restartPoint(text string, prefix, prefixRunes int, prev []lexToken) (offset, runes, index int) {
+	lineStart, lineRunes := prefix, prefixRunes
+	for range maxRestartLines {
+		// move to the start of the line.
+		i := strings.LastIndexByte(text[:lineStart], '\n') + 1
+		lineRunes -= utf8.RuneCountInString(text[i:lineStart])
+		lineStart = i
+		if lineStart == 0 {
+			break
+		}
+
+		// the token covering the line start.
+		k := sort.Search(len(prev), func(k int) bool { return prev[k].end > lineRunes })
+		if k < len(prev) && prev[k].start <= lineRunes && restartable(prev, k) {
+			offset = lineStart
+			for n := lineRunes - prev[k].start; n > 0; n-- {
+				_, size := utf8.DecodeLastRuneInString(text[:offset])
+				offset -= size
+			}
+			return offset, prev[k].start, k
+		}
+
+		// try the previous line.
+		lineStart--
+		lineRunes--
+	}
+	return 0, 0, 0
+}
+
+// restartable reports whether the lexer can be restarted at the start of the
+// previous token k, which is the case if neither the token nor the one before
+// it is a part of a string or a comment, which can span lines, or an error,
+// like an unterminated string.
+func restartable(prev []lexToken, k int) bool {
+	inBlock := func(tk lexToken) bool {
+		return tk.typ == chroma.Error || tk.typ.InCategory(chroma.Comment) || tk.typ.InSubCategory(chroma.LiteralString)
+	}
+	return !inBlock(prev[k]) && (k == 0 || !inBlock(prev[k-1]))
+}
+
+// commonAffixes returns the length in bytes of the common prefix and suffix
+// of a and b, which don't overlap and end at rune boundaries.
+func commonAffixes(a, b string) (prefix, suffix int) {
+	n := min(len(a), len(b))
+	for prefix < n && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for prefix > 0 && prefix < len(b) && !utf8.RuneStart(b[prefix]) {
+		prefix--
+	}
+	for prefix > 0 && prefix < len(a) && !utf8.RuneStart(a[prefix]) {
+		prefix--
+	}
+
+	n -= prefix
+	for suffix < n && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	for suffix > 0 && !utf8.RuneStart(b[len(b)-suffix]) {
+		suffix--
+	}
+	return prefix, suffix
+}
+
+// changedRange returns the rune range covering the tokens of a text that
+// differ between prev and tokens. It returns an empty range if they are equal.
+func changedRange(prev, tokens []lexToken) (start, end int) {
+	n := min(len(prev), len(tokens))
+	first := 0
+	for first < n && prev[first] == tokens[first] {
+		first++
+	}
+	if first == len(prev) && first == len(tokens) {
+		return 0, 0
+	}
+	last := 0
+	for last < n-first && prev[len(prev)-1-last] == tokens[len(tokens)-1-last] {
+		last++
+	}
+
+	start, end = math.MaxInt, 0
+	for _, tks := range [][]lexToken{prev[first : len(prev)-last], tokens[first : len(tokens)-last]} {
+		if len(tks) > 0 {
+			start = min(start, tks[0].start)
+			end = max(end, tks[len(tks)-1].end)
+		}
+	}
+	return start, end
+}
+
+// clipTokens drops the parts of the tokens after the end of the text, e.g.,
+// the line break added by the lexers ensuring the text ends with one.
+func clipTokens(tokens []lexToken, textLen int) []lexToken {
+	for len(tokens) > 0 && tokens[len(tokens)-1].start >= textLen {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if n := len(tokens); n > 0 && tokens[n-1].end > textLen {
+		tokens[n-1].end = textLen
+	}
+	return tokens
+}
@@ -0,0 +1,67 @@
+package highlight
+
+import (
+	"github.com/alecthomas/chroma/v2"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+// scopes maps the chroma token types to the scopes of the color scheme. The
+// sub-types missing in the map use the scope of their parent type, then of
+// their category.
+var scopes = map[chroma.TokenType]syntax.StyleScope{
+	chroma.Error: "invalid",
+
+	chroma.Keyword:            "keyword",
+	chroma.KeywordConstant:    "constant.language",
+	chroma.KeywordDeclaration: "storage.type",
+	chroma.KeywordNamespace:   "keyword.control.import",
+	chroma.KeywordType:        "storage.type",
+
+	chroma.NameAttribute:     "entity.other.attribute-name",
+	chroma.NameBuiltin:       "support.function.builtin",
+	chroma.NameClass:         "entity.name.type",
+	chroma.NameConstant:      "constant.other",
+	chroma.NameDecorator:     "entity.name.function.decorator",
+	chroma.NameFunction:      "entity.name.function",
+	chroma.NameFunctionMagic: "support.function",
+	chroma.NameLabel:         "entity.name.label",
+	chroma.NameNamespace:     "entity.name.namespace",
+	chroma.NameTag:           "entity.name.tag",
+	chroma.NameVariable:      "variable",
+
+	chroma.LiteralString:         "string",
+	chroma.LiteralStringBacktick: "string.quoted.raw",
+	chroma.LiteralStringChar:     "string.quoted.single",
+	chroma.LiteralStringDouble:   "string.quoted.double",
+	chroma.LiteralStringEscape:   "constant.character.escape",
+	chroma.LiteralStringInterpol: "meta.interpolation",
+	chroma.LiteralStringRegex:    "string.regexp",
+	chroma.LiteralStringSingle:   "string.quoted.single",
+	chroma.LiteralNumber:         "constant.numeric",
+
+	chroma.Operator:    "keyword.operator",
+	chroma.Punctuation: "punctuation",
+
+	chroma.Comment:          "comment",
+	chroma.CommentMultiline: "comment.block",
+	chroma.CommentPreproc:   "meta.preprocessor",
+	chroma.CommentSingle:    "comment.line",
+
+	chroma.GenericDeleted:  "markup.deleted",
+	chroma.GenericEmph:     "markup.italic",
+	chroma.GenericHeading:  "markup.heading",
+	chroma.GenericInserted: "markup.inserted",
+	chroma.GenericStrong:   "markup.bold",
+}
+
+// ScopeOf returns the scope of the color scheme for a chroma token type,
+// e.g., "string.quoted.double" for LiteralStringDouble. It returns an empty
+// scope for the types that are not highlighted, like names and whitespace.
+func ScopeOf(t chroma.TokenType) syntax.StyleScope {
+	for _, typ := range []chroma.TokenType{t, t.SubCategory(), t.Category()} {
+		if scope, ok := scopes[typ]; ok {
+			return scope
+		}
+	}
+	return ""
+}
@@ -0,0 +1,284 @@
+// Package markdown implements an addon with editing helpers for Markdown
+// documents: toggling the checkboxes of task lists, and renumbering ordered
+// lists after their items are added, removed or moved.
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gioui.org/layout"
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/textstyle/decoration"
+)
+
+// CheckboxSource is the source of the decorations added by
+// DecorateCheckboxes.
+const CheckboxSource = "markdown-checkbox"
+
+var (
+	// checkboxPattern matches a list item starting with a checkbox. The
+	// submatch is the mark of the checkbox.
+	checkboxPattern = regexp.MustCompile(`^[ \t]*(?:[-*+]|\d{1,9}[.)])[ \t]+\[([ xX])\]`)
+	// itemPattern matches a list item. The submatches are the indentation,
+	// the number of an ordered item, and its delimiter.
+	itemPattern = regexp.MustCompile(`^([ \t]*)(?:[-*+]|(\d{1,9})([.)]))(?:[ \t]|$)`)
+	// fencePattern matches the fences of the code blocks.
+	fencePattern = regexp.MustCompile("^[ \t]*(```|~~~)")
+)
+
+// The commands of the addon, which can be bound in the keymap of an editor.
+var (
+	// ToggleCheckbox toggles the checkboxes of the lines of the selection.
+	ToggleCheckbox = gvcode.Command{Name: "markdownToggleCheckbox", Run: func(gtx layout.Context, e *gvcode.Editor) gvcode.EditorEvent {
+		ToggleCheckboxes(e)
+		return nil
+	}}
+	// RenumberLists renumbers the ordered lists of the document.
+	RenumberLists = gvcode.Command{Name: "markdownRenumberLists", Run: func(gtx layout.Context, e *gvcode.Editor) gvcode.EditorEvent {
+		Renumber(e)
+		return nil
+	}}
+)
+
+// Checkbox finds the checkbox of a list item in line. start and end are the
+// rune columns of the checkbox, brackets included.
+func Checkbox(line string) (start, end int, checked, ok bool) {
+	loc := checkboxPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return 0, 0, false, false
+	}
+	// the mark is between the brackets.
+	start = utf8.RuneCountInString(line[:loc[2]]) - 1
+	return start, start + 3, line[loc[2]:loc[3]] != " ", true
+}
+
+// line is a line of the document.
+type line struct {
+	text string
+	// offset is the rune offset of the line start.
+	offset int
+}
+
+func lines(editor *gvcode.Editor) []line {
+	texts := strings.Split(editor.Text(), "\n")
+	lines := make([]line, len(texts))
+	offset := 0
+	for i, text := range texts {
+		lines[i] = line{text: text, offset: offset}
+		offset += utf8.RuneCountInString(text) + 1
+	}
+	return lines
+}
+
+// ToggleCheckboxes toggles the checkboxes of the lines of the selection, or
+// of the caret line. The checkboxes are checked if any of them is unchecked,
+// and unchecked otherwise. Only the marks of the checkboxes are replaced, so
+// that the caret and the decorations around them stay in place. It reports
+// whether the text is changed.
+func ToggleCheckboxes(editor *gvcode.Editor) bool {
+	if editor.ReadOnly() {
+		return false
+	}
+
+	start, end := editor.Selection()
+	start, end = min(start, end), max(start, end)
+	type checkbox struct {
+		offset  int
+		checked bool
+	}
+	var boxes []checkbox
+	check := false
+	for _, l := range lines(editor) {
+		lineEnd := l.offset + utf8.RuneCountInString(l.text)
+		if lineEnd < start || l.offset > end {
+			continue
+		}
+		col, _, checked, ok := Checkbox(l.text)
+		if !ok {
+			continue
+		}
+		boxes = append(boxes, checkbox{offset: l.offset + col, checked: checked})
+		check = check || !checked
+	}
+
+	changed := false
+	editor.Transaction(func(tx *gvcode.EditTx) {
+		for _, box := range boxes {
+			if box.checked != check {
+				setMark(tx, box.offset, check)
+				changed = true
+			}
+		}
+	})
+	return changed
+}
+
+// ToggleCheckboxAt toggles the checkbox at the rune offset, which is between
+// the brackets of the checkbox or at one of them. It reports whether the text
+// is changed.
+func ToggleCheckboxAt(editor *gvcode.Editor, offset int) bool {
+	if editor.ReadOnly() {
+		return false
+	}
+
+	for _, l := range lines(editor) {
+		lineEnd := l.offset + utf8.RuneCountInString(l.text)
+		if offset < l.offset || offset > lineEnd {
+			continue
+		}
+		start, end, checked, ok := Checkbox(l.text)
+		if !ok || offset < l.offset+start || offset > l.offset+end {
+			return false
+		}
+		editor.Transaction(func(tx *gvcode.EditTx) {
+			setMark(tx, l.offset+start, !checked)
+		})
+		return true
+	}
+	return false
+}
+
+// setMark replaces the mark of the checkbox at offset.
+func setMark(tx *gvcode.EditTx, offset int, checked bool) {
+	mark := " "
+	if checked {
+		mark = "x"
+	}
+	tx.Replace(offset+1, offset+2, mark)
+}
+
+// DecorateCheckboxes decorates the checkboxes of the document with style,
+// replacing the decorations added before, and makes them clickable: clicking
+// a checkbox toggles it. Call it again after the text is changed to decorate
+// the new checkboxes.
+func DecorateCheckboxes(editor *gvcode.Editor, style decoration.Decoration) error {
+	editor.ClearDecorations(CheckboxSource)
+
+	var decos []decoration.Decoration
+	for _, l := range lines(editor) {
+		start, end, _, ok := Checkbox(l.text)
+		if !ok {
+			continue
+		}
+		deco := style
+		deco.Source = CheckboxSource
+		deco.Start, deco.End = l.offset+start, l.offset+end
+		deco.Click = &decoration.ClickHandler{OnClick: func(start, end int) {
+			ToggleCheckboxAt(editor, start)
+		}}
+		decos = append(decos, deco)
+	}
+	if len(decos) == 0 {
+		return nil
+	}
+	return editor.AddDecorations(decos...)
+}
+
+// list is an ordered list being renumbered.
+type list struct {
+	indent string
+	delim  string
+	// next is the number expected for the next item.
+	next int
+}
+
+// Renumber renumbers the items of the ordered lists of the document, so that
+// they count up from the number of the first item of each list. Nested lists
+// are numbered on their own, and the code blocks are skipped. Only the
+// numbers which are wrong are replaced, in a single undo step. It reports
+// whether the text is changed.
+func Renumber(editor *gvcode.Editor) bool {
+	if editor.ReadOnly() {
+		return false
+	}
+
+	type edit struct {
+		start, end int
+		number     string
+	}
+	var edits []edit
+	var stack []list
+	var fence string
+	for _, l := range lines(editor) {
+		if m := fencePattern.FindStringSubmatch(l.text); m != nil {
+			if fence == "" {
+				fence = m[1]
+				// the code block ends the lists it is not nested in.
+				stack = popLists(stack, l.text[:leadingSpace(l.text)])
+			} else if fence == m[1] {
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+		if strings.TrimSpace(l.text) == "" {
+			// the items of loose lists are separated by blank lines.
+			continue
+		}
+
+		m := itemPattern.FindStringSubmatchIndex(l.text)
+		indent := l.text[:leadingSpace(l.text)]
+		if m == nil || m[4] < 0 {
+			// a paragraph, or a bullet item, ends the lists at its
+			// indentation and deeper.
+			stack = popLists(stack, indent)
+			continue
+		}
+		// the item ends the lists nested deeper.
+		for len(stack) > 0 && len(stack[len(stack)-1].indent) > len(indent) {
+			stack = stack[:len(stack)-1]
+		}
+		top := len(stack) - 1
+
+		number, _ := strconv.Atoi(l.text[m[4]:m[5]])
+		delim := l.text[m[6]:m[7]]
+		if top < 0 || stack[top].indent != indent || stack[top].delim != delim {
+			if top >= 0 && stack[top].indent == indent {
+				// a list with another delimiter starts a new list.
+				stack = stack[:top]
+			}
+			stack = append(stack, list{indent: indent, delim: delim, next: number + 1})
+			continue
+		}
+
+		if number != stack[top].next {
+			start := l.offset + utf8.RuneCountInString(l.text[:m[4]])
+			edits = append(edits, edit{
+				start:  start,
+				end:    start + m[5] - m[4],
+				number: strconv.Itoa(stack[top].next),
+			})
+		}
+		stack[top].next++
+	}
+
+	if len(edits) == 0 {
+		return false
+	}
+	editor.Transaction(func(tx *gvcode.EditTx) {
+		// replace from the end, so that the offsets of the edits before stay
+		// valid.
+		for i := len(edits) - 1; i >= 0; i-- {
+			tx.Replace(edits[i].start, edits[i].end, edits[i].number)
+		}
+	})
+	return true
+}
+
+// popLists removes the lists of the stack indented by indent or deeper.
+func popLists(stack []list, indent string) []list {
+	for len(stack) > 0 && len(stack[len(stack)-1].indent) >= len(indent) {
+		stack = stack[:len(stack)-1]
+	}
+	return stack
+}
+
+// leadingSpace returns the length of the indentation of text.
+func leadingSpace(text string) int {
+	return len(text) - len(strings.TrimLeft(text, " \t"))
+}
@@ -0,0 +1,213 @@
+package markdown
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/internal/editortest"
+	"github.com/oligo/gvcode/textstyle/decoration"
+)
+
+func newEditor(t *testing.T, content string) *gvcode.Editor {
+	t.Helper()
+	editor := editortest.NewEditor(t, content)
+	editor.SetCaret(0, 0)
+	return editor
+}
+
+func TestCheckbox(t *testing.T) {
+	for _, tc := range []struct {
+		line       string
+		start, end int
+		checked    bool
+		ok         bool
+	}{
+		{line: "- [ ] todo", start: 2, end: 5, ok: true},
+		{line: "  * [x] done", start: 4, end: 7, checked: true, ok: true},
+		{line: "12. [X] done", start: 4, end: 7, checked: true, ok: true},
+		{line: "- é [ ] not a checkbox"},
+		{line: "[ ] not an item"},
+		{line: "-[ ] no space"},
+	} {
+		start, end, checked, ok := Checkbox(tc.line)
+		if start != tc.start || end != tc.end || checked != tc.checked || ok != tc.ok {
+			t.Errorf("%q: got (%d, %d, %v, %v)", tc.line, start, end, checked, ok)
+		}
+	}
+}
+
+func TestToggleCheckboxes(t *testing.T) {
+	editor := newEditor(t, "# Tasks\n- [ ] one\n- [x] two\nnot a task\n- [ ] three\n")
+
+	// the selection has an unchecked box, so both are checked.
+	editor.SetCaret(10, 20)
+	if !ToggleCheckboxes(editor) {
+		t.Fatal("the text is not changed")
+	}
+	if got, want := editor.Text(), "# Tasks\n- [x] one\n- [x] two\nnot a task\n- [ ] three\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if start, end := editor.Selection(); start != 10 || end != 20 {
+		t.Errorf("the selection moved to (%d, %d)", start, end)
+	}
+
+	ToggleCheckboxes(editor)
+	if got, want := editor.Text(), "# Tasks\n- [ ] one\n- [ ] two\nnot a task\n- [ ] three\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// the caret line has no checkbox.
+	editor.SetCaret(30, 30)
+	if ToggleCheckboxes(editor) {
+		t.Error("toggled a line without checkbox")
+	}
+}
+
+func TestToggleCheckboxAt(t *testing.T) {
+	editor := newEditor(t, "- [ ] one\n- [x] two")
+	if !ToggleCheckboxAt(editor, 5) {
+		t.Fatal("the checkbox at its closing bracket is not toggled")
+	}
+	if !ToggleCheckboxAt(editor, 13) {
+		t.Fatal("the checkbox at its mark is not toggled")
+	}
+	if got, want := editor.Text(), "- [x] one\n- [ ] two"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if ToggleCheckboxAt(editor, 7) {
+		t.Error("toggled a checkbox away from it")
+	}
+}
+
+func TestDecorateCheckboxes(t *testing.T) {
+	editor := newEditor(t, "intro\n- [ ] one\n- [x] two\n")
+	if err := DecorateCheckboxes(editor, decoration.Decoration{Bold: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	var router input.Router
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	now := time.Now()
+	frame := func() {
+		// the clicks are apart enough not to be counted as double clicks.
+		now = now.Add(time.Second)
+		gtx := layout.Context{
+			Ops:         new(op.Ops),
+			Constraints: layout.Exact(image.Pt(800, 600)),
+			Source:      router.Source(),
+			Now:         now,
+		}
+		for {
+			if _, ok := editor.Update(gtx); !ok {
+				break
+			}
+		}
+		editor.Layout(gtx, shaper)
+		router.Frame(gtx.Ops)
+	}
+	click := func(line, col int) {
+		_, pos := editor.ConvertPos(line, col)
+		pos = pos.Add(f32.Pt(0, -4))
+		router.Queue(
+			pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: pos, Time: time.Duration(now.UnixNano())},
+			pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: pos, Time: time.Duration(now.UnixNano())},
+		)
+		frame()
+		frame()
+	}
+	frame()
+
+	// clicking the mark of the first checkbox checks it, and clicking the
+	// text of the second item doesn't toggle it.
+	click(1, 3)
+	click(2, 8)
+	if got, want := editor.Text(), "intro\n- [x] one\n- [x] two\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// the decorations follow the edits.
+	editor.SetCaret(0, 0)
+	editor.Insert("# ")
+	click(2, 3)
+	if got, want := editor.Text(), "# intro\n- [x] one\n- [ ] two\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestRenumber(t *testing.T) {
+	editor := newEditor(t, `1. one
+3. two
+
+7. three
+   continued
+   1. nested
+   1. nested
+8) other delimiter
+1) other
+- bullet
+2. new list
+2. second
+`+"```"+`
+1. code
+1. code
+`+"```"+`
+5. after
+`)
+
+	if !Renumber(editor) {
+		t.Fatal("the text is not changed")
+	}
+	want := `1. one
+2. two
+
+3. three
+   continued
+   1. nested
+   2. nested
+8) other delimiter
+9) other
+- bullet
+2. new list
+3. second
+` + "```" + `
+1. code
+1. code
+` + "```" + `
+5. after
+`
+	if got := editor.Text(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if Renumber(editor) {
+		t.Error("renumbered the lists twice")
+	}
+}
+
+func TestRenumberUndo(t *testing.T) {
+	editor := newEditor(t, "9. a\n9. b\n9. c\n")
+	var router input.Router
+	gtx := layout.Context{Ops: new(op.Ops), Source: router.Source()}
+	RenumberLists.Run(gtx, editor)
+	if got, want := editor.Text(), "9. a\n10. b\n11. c\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// the renumbering is undone in a single step.
+	evt, ok := editor.Update(gtx)
+	if _, isChange := evt.(gvcode.ChangeEvent); !ok || !isChange {
+		t.Errorf("got event %#v, want a ChangeEvent", evt)
+	}
+	gvcode.Undo.Run(gtx, editor)
+	if got, want := editor.Text(), "9. a\n9. b\n9. c\n"; got != want {
+		t.Fatalf("undo: got %q, want %q", got, want)
+	}
+}
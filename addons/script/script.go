@@ -0,0 +1,370 @@
+// Package script implements a bridge exposing the editor to embedded
+// scripting languages, e.g., Lua or Starlark, so that the users of an
+// application can define editor commands without recompiling it.
+//
+// The bridge doesn't embed an interpreter. The editor is exposed to the
+// scripts by the API interface, whose methods only take and return strings,
+// integers, booleans and errors, so that they map directly to the values of
+// the scripting languages. The application binds the methods of API to its
+// interpreter, and defines the commands implemented by the scripts with
+// Bridge.Define, wrapping the calls of the interpreter in a Func.
+package script
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"unicode/utf8"
+
+	"gioui.org/layout"
+	"github.com/oligo/gvcode"
+)
+
+var (
+	// ErrReadOnly is returned by the edits of a read-only editor.
+	ErrReadOnly = errors.New("script: the editor is read-only")
+	// ErrRange is returned for offsets, lines or columns out of the text.
+	ErrRange = errors.New("script: position out of range")
+)
+
+// API is the editor API exposed to the scripts. Offsets and columns are in
+// runes, and lines are counted from zero.
+type API interface {
+	// Text returns the text of the editor.
+	Text() string
+	// Len returns the length of the text.
+	Len() int
+	// Slice returns the text between the offsets start and end.
+	Slice(start, end int) (string, error)
+	// LineCount returns the number of lines of the text. Text ending with a
+	// line break has an empty last line.
+	LineCount() int
+	// Line returns the text of a line, without its line break.
+	Line(line int) (string, error)
+	// Offset returns the offset of the column of a line.
+	Offset(line, col int) (int, error)
+	// Position returns the line and the column of an offset.
+	Position(offset int) (line, col int, err error)
+
+	// Selection returns the selection. start is the caret, which is after
+	// end if the text is selected backward.
+	Selection() (start, end int)
+	// Select moves the caret to start, and selects the text up to end.
+	Select(start, end int) error
+	// SelectedText returns the selected text.
+	SelectedText() string
+
+	// Insert replaces the selection with text, and moves the caret after
+	// it.
+	Insert(text string) error
+	// Replace replaces the text between the offsets start and end.
+	Replace(start, end int, text string) error
+
+	// Commands returns the names of the commands which can be run.
+	Commands() []string
+	// Run runs the command of name: a command defined by the bridge, a
+	// command bound in the keymap, or a built-in command.
+	Run(name string) error
+
+	// Language returns the language ID of the editor.
+	Language() string
+	// ReadOnly reports whether the editor is read-only.
+	ReadOnly() bool
+}
+
+// Func is the body of a command implemented by a script. The API is only
+// valid during the call.
+type Func func(api API) error
+
+// Bridge defines the commands implemented by scripts for an editor.
+type Bridge struct {
+	editor  *gvcode.Editor
+	scripts map[string]Func
+	// builtins are the built-in commands by name.
+	builtins map[string]gvcode.Command
+	// OnError is called with the error of a command run by a key binding.
+	// The errors are dropped if it is nil.
+	OnError func(name string, err error)
+}
+
+// New creates a bridge for the editor.
+func New(editor *gvcode.Editor) *Bridge {
+	b := &Bridge{
+		editor:   editor,
+		scripts:  make(map[string]Func),
+		builtins: make(map[string]gvcode.Command),
+	}
+	for _, binding := range gvcode.DefaultKeymap().Bindings() {
+		b.builtins[binding.Command.Name] = binding.Command
+	}
+	return b
+}
+
+// Define defines the command of name implemented by fn, replacing the one
+// defined with the same name. The edits made by fn are grouped into a single
+// undo step. The returned command can be bound in the keymap of the editor.
+func (b *Bridge) Define(name string, fn Func) gvcode.Command {
+	b.scripts[name] = fn
+	return b.command(name)
+}
+
+// Undefine removes the command of name.
+func (b *Bridge) Undefine(name string) {
+	delete(b.scripts, name)
+}
+
+// command returns the command running the script of name. The script is
+// looked up when the command is run, so that it can be redefined without
+// binding it again.
+func (b *Bridge) command(name string) gvcode.Command {
+	return gvcode.Command{Name: name, Run: func(gtx layout.Context, e *gvcode.Editor) gvcode.EditorEvent {
+		evt, err := b.Run(gtx, name)
+		if err != nil && b.OnError != nil {
+			b.OnError(name, err)
+		}
+		return evt
+	}}
+}
+
+// Bind binds the keys to the command of name in the keymap of the editor.
+func (b *Bridge) Bind(keys string, name string) error {
+	cmd, ok := b.lookup(name)
+	if !ok {
+		return fmt.Errorf("script: unknown command %q", name)
+	}
+	return b.editor.Keymap().Bind(keys, cmd)
+}
+
+// Run runs the command of name, and returns the error of the script, if any.
+func (b *Bridge) Run(gtx layout.Context, name string) (gvcode.EditorEvent, error) {
+	if _, ok := b.scripts[name]; ok {
+		return b.run(gtx, func(api API) error { return api.Run(name) })
+	}
+	cmd, ok := b.lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("script: unknown command %q", name)
+	}
+	return cmd.Run(gtx, b.editor), nil
+}
+
+// lookup finds the command of name in the commands defined by the bridge,
+// the keymap of the editor, and the built-in commands.
+func (b *Bridge) lookup(name string) (gvcode.Command, bool) {
+	if _, ok := b.scripts[name]; ok {
+		return b.command(name), true
+	}
+	for _, binding := range b.editor.Keymap().Bindings() {
+		if binding.Command.Name == name {
+			return binding.Command, true
+		}
+	}
+	cmd, ok := b.builtins[name]
+	return cmd, ok
+}
+
+// commandNames returns the names of the commands which can be looked up.
+func (b *Bridge) commandNames() []string {
+	var names []string
+	for name := range b.scripts {
+		names = append(names, name)
+	}
+	for _, binding := range b.editor.Keymap().Bindings() {
+		names = append(names, binding.Command.Name)
+	}
+	for name := range b.builtins {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// run runs fn with the edits grouped into a transaction. A ChangeEvent is
+// generated if the text is changed.
+func (b *Bridge) run(gtx layout.Context, fn Func) (gvcode.EditorEvent, error) {
+	api := &editorAPI{bridge: b, gtx: gtx, running: make(map[string]bool)}
+	version := b.editor.TextVersion()
+
+	var err error
+	if b.editor.ReadOnly() {
+		err = fn(api)
+	} else {
+		b.editor.Transaction(func(tx *gvcode.EditTx) {
+			api.tx = tx
+			err = fn(api)
+		})
+	}
+
+	// edits made through the transaction are reported by it.
+	if !api.edited && b.editor.TextVersion() != version {
+		return gvcode.ChangeEvent{}, err
+	}
+	return api.event, err
+}
+
+// editorAPI implements API for the editor of a bridge.
+type editorAPI struct {
+	bridge *Bridge
+	gtx    layout.Context
+	tx     *gvcode.EditTx
+	// edited is true if the text is edited through the transaction.
+	edited bool
+	// event is the last event other than ChangeEvent of the commands run.
+	event gvcode.EditorEvent
+	// running are the names of the scripts being run, to detect recursion.
+	running map[string]bool
+}
+
+func (a *editorAPI) editor() *gvcode.Editor {
+	return a.bridge.editor
+}
+
+func (a *editorAPI) Text() string {
+	return a.editor().Text()
+}
+
+func (a *editorAPI) Len() int {
+	return a.editor().Len()
+}
+
+func (a *editorAPI) Slice(start, end int) (string, error) {
+	if err := a.checkRange(start, end); err != nil {
+		return "", err
+	}
+	return a.editor().ReadRange(start, end), nil
+}
+
+func (a *editorAPI) LineCount() int {
+	return a.editor().Lines()
+}
+
+// lineRange returns the rune offsets of the 0-based line, its line break
+// excluded.
+func (a *editorAPI) lineRange(line int) (start, end int, err error) {
+	e := a.editor()
+	if line < 0 || line >= e.Lines() {
+		return 0, 0, ErrRange
+	}
+	start, _ = e.ConvertPos(line, 0)
+	end = e.Len()
+	if line < e.Lines()-1 {
+		end, _ = e.ConvertPos(line+1, 0)
+		end--
+	}
+	return start, end, nil
+}
+
+func (a *editorAPI) Line(line int) (string, error) {
+	start, end, err := a.lineRange(line)
+	if err != nil {
+		return "", err
+	}
+	return a.editor().ReadRange(start, end), nil
+}
+
+func (a *editorAPI) Offset(line, col int) (int, error) {
+	start, end, err := a.lineRange(line)
+	if err != nil {
+		return 0, err
+	}
+	if col < 0 || col > end-start {
+		return 0, ErrRange
+	}
+	return start + col, nil
+}
+
+func (a *editorAPI) Position(offset int) (line, col int, err error) {
+	e := a.editor()
+	if offset < 0 || offset > e.Len() {
+		return 0, 0, ErrRange
+	}
+	// the last line starting at or before offset.
+	line = sort.Search(e.Lines(), func(i int) bool {
+		start, _ := e.ConvertPos(i, 0)
+		return start > offset
+	}) - 1
+	start, _ := e.ConvertPos(line, 0)
+	return line, offset - start, nil
+}
+
+func (a *editorAPI) Selection() (start, end int) {
+	return a.editor().Selection()
+}
+
+func (a *editorAPI) Select(start, end int) error {
+	if err := a.checkRange(min(start, end), max(start, end)); err != nil {
+		return err
+	}
+	a.editor().SetCaret(start, end)
+	return nil
+}
+
+func (a *editorAPI) SelectedText() string {
+	return a.editor().SelectedText()
+}
+
+func (a *editorAPI) Insert(text string) error {
+	start, end := a.Selection()
+	start, end = min(start, end), max(start, end)
+	if err := a.Replace(start, end, text); err != nil {
+		return err
+	}
+	caret := start + utf8.RuneCountInString(text)
+	a.editor().SetCaret(caret, caret)
+	return nil
+}
+
+func (a *editorAPI) Replace(start, end int, text string) error {
+	if a.tx == nil {
+		return ErrReadOnly
+	}
+	if err := a.checkRange(start, end); err != nil {
+		return err
+	}
+	a.tx.Replace(start, end, text)
+	a.edited = a.edited || start != end || text != ""
+	return nil
+}
+
+func (a *editorAPI) Commands() []string {
+	return a.bridge.commandNames()
+}
+
+func (a *editorAPI) Run(name string) error {
+	if a.running[name] {
+		return fmt.Errorf("script: command %q runs itself", name)
+	}
+	if fn, ok := a.bridge.scripts[name]; ok {
+		// scripts run the other scripts with the same API, so that their
+		// edits are in the same transaction and their errors are returned.
+		a.running[name] = true
+		defer delete(a.running, name)
+		return fn(a)
+	}
+
+	cmd, ok := a.bridge.lookup(name)
+	if !ok {
+		return fmt.Errorf("script: unknown command %q", name)
+	}
+	switch evt := cmd.Run(a.gtx, a.editor()).(type) {
+	case nil, gvcode.ChangeEvent:
+	default:
+		a.event = evt
+	}
+	return nil
+}
+
+func (a *editorAPI) Language() string {
+	return a.editor().Language().ID
+}
+
+func (a *editorAPI) ReadOnly() bool {
+	return a.editor().ReadOnly()
+}
+
+func (a *editorAPI) checkRange(start, end int) error {
+	if start < 0 || start > end || end > a.Len() {
+		return ErrRange
+	}
+	return nil
+}
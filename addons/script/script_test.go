@@ -0,0 +1,168 @@
+package script
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"gioui.org/layout"
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/internal/editortest"
+)
+
+func newBridge(t *testing.T, content string) (*Bridge, *gvcode.Editor, layout.Context) {
+	t.Helper()
+	editor := editortest.NewEditor(t, content)
+	gtx := editortest.NewContext()
+	editor.SetCaret(0, 0)
+	return New(editor), editor, gtx
+}
+
+// upperLine converts the line of the caret to upper case.
+func upperLine(api API) error {
+	caret, _ := api.Selection()
+	line, _, err := api.Position(caret)
+	if err != nil {
+		return err
+	}
+	text, err := api.Line(line)
+	if err != nil {
+		return err
+	}
+	start, _ := api.Offset(line, 0)
+	return api.Replace(start, start+len([]rune(text)), strings.ToUpper(text))
+}
+
+func TestBridgeDefine(t *testing.T) {
+	b, editor, gtx := newBridge(t, "foo\nbar\nbaz")
+	b.Define("upperLine", upperLine)
+	editor.SetCaret(5, 5)
+
+	evt, err := b.Run(gtx, "upperLine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := editor.Text(), "foo\nBAR\nbaz"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	// the change is reported by the transaction.
+	if evt != nil {
+		t.Errorf("got event %#v", evt)
+	}
+	if evt, ok := editor.Update(gtx); !ok || evt != (gvcode.ChangeEvent{}) {
+		t.Errorf("got event %#v, want a ChangeEvent", evt)
+	}
+}
+
+func TestBridgeRunCommands(t *testing.T) {
+	b, editor, gtx := newBridge(t, "foo\nbar")
+	b.Define("duplicateAndUpper", func(api API) error {
+		if err := api.Run("duplicateLine"); err != nil {
+			return err
+		}
+		if err := api.Select(0, 0); err != nil {
+			return err
+		}
+		return api.Run("upperLine")
+	})
+	b.Define("upperLine", upperLine)
+
+	if _, err := b.Run(gtx, "duplicateAndUpper"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := editor.Text(), "FOO\nfoo\nbar"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// the edits of the command are a single undo step.
+	if _, err := b.Run(gtx, "undo"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := editor.Text(), "foo\nbar"; got != want {
+		t.Fatalf("undo: got %q, want %q", got, want)
+	}
+
+	if names := (&editorAPI{bridge: b}).Commands(); !slices.Contains(names, "upperLine") || !slices.Contains(names, "undo") {
+		t.Errorf("got commands %v", names)
+	}
+}
+
+func TestBridgeErrors(t *testing.T) {
+	b, editor, gtx := newBridge(t, "foo")
+	b.Define("outOfRange", func(api API) error {
+		return api.Replace(2, 10, "x")
+	})
+	b.Define("recursive", func(api API) error {
+		return api.Run("recursive")
+	})
+
+	if _, err := b.Run(gtx, "outOfRange"); !errors.Is(err, ErrRange) {
+		t.Errorf("got %v, want ErrRange", err)
+	}
+	if _, err := b.Run(gtx, "recursive"); err == nil {
+		t.Error("recursion is not detected")
+	}
+	if _, err := b.Run(gtx, "missing"); err == nil {
+		t.Error("unknown command is run")
+	}
+
+	// errors of the commands run by key bindings are reported by OnError.
+	var reported string
+	b.OnError = func(name string, err error) { reported = name }
+	if err := b.Bind("Ctrl+U", "outOfRange"); err != nil {
+		t.Fatal(err)
+	}
+	cmd, _ := editor.Keymap().Lookup("Ctrl+U")
+	cmd.Run(gtx, editor)
+	if reported != "outOfRange" {
+		t.Errorf("got error reported for %q", reported)
+	}
+}
+
+func TestBridgeReadOnly(t *testing.T) {
+	b, editor, gtx := newBridge(t, "foo")
+	editor.WithOptions(gvcode.ReadOnlyMode(true))
+
+	var text string
+	b.Define("read", func(api API) error {
+		text = api.Text()
+		return api.Insert("x")
+	})
+	if _, err := b.Run(gtx, "read"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("got %v, want ErrReadOnly", err)
+	}
+	if text != "foo" || editor.Text() != "foo" {
+		t.Errorf("got %q, %q", text, editor.Text())
+	}
+}
+
+func TestAPIPositions(t *testing.T) {
+	b, _, gtx := newBridge(t, "héllo\nwörld\n")
+	api := &editorAPI{bridge: b, gtx: gtx}
+
+	if n := api.LineCount(); n != 3 {
+		t.Errorf("got %d lines, want 3", n)
+	}
+	if off, err := api.Offset(1, 2); err != nil || off != 8 {
+		t.Errorf("offset: got %d, %v, want 8", off, err)
+	}
+	if line, col, err := api.Position(8); err != nil || line != 1 || col != 2 {
+		t.Errorf("position: got %d:%d, %v, want 1:2", line, col, err)
+	}
+	if line, col, _ := api.Position(12); line != 2 || col != 0 {
+		t.Errorf("position of the end: got %d:%d, want 2:0", line, col)
+	}
+	if _, err := api.Offset(1, 6); !errors.Is(err, ErrRange) {
+		t.Errorf("got %v, want ErrRange", err)
+	}
+	if text, err := api.Line(1); err != nil || text != "wörld" {
+		t.Errorf("line: got %q, %v, want %q", text, err, "wörld")
+	}
+	if text, err := api.Line(2); err != nil || text != "" {
+		t.Errorf("last line: got %q, %v, want an empty line", text, err)
+	}
+	if _, err := api.Line(3); !errors.Is(err, ErrRange) {
+		t.Errorf("got %v, want ErrRange", err)
+	}
+}
@@ -0,0 +1,502 @@
+package vim
+
+import (
+	"strings"
+	"unicode"
+
+	"gioui.org/layout"
+	"github.com/oligo/gvcode"
+)
+
+// operators are applied to the text selected by a motion, e.g., "dw", or to
+// whole lines when doubled, e.g., "dd".
+const operators = "dcy<>"
+
+// aliases are the commands that are shorthands of an operator and a motion.
+var aliases = map[rune]string{
+	'x': "dl",
+	'X': "dh",
+	'D': "d$",
+	'C': "c$",
+	's': "cl",
+	'S': "cc",
+	'Y': "yy",
+}
+
+type parseStatus int
+
+const (
+	parseOK parseStatus = iota
+	parseIncomplete
+	parseInvalid
+)
+
+// parseCount parses the count starting at keys[i]. It returns 0 if there is
+// none, and the index of the key after the count.
+func parseCount(keys []rune, i int) (int, int) {
+	start, count := i, 0
+	for ; i < len(keys); i++ {
+		c := keys[i]
+		if c < '0' || c > '9' || (c == '0' && i == start) {
+			// a leading "0" is the motion to the line start.
+			break
+		}
+		count = count*10 + int(c-'0')
+	}
+	return count, i
+}
+
+// parseMotion parses the motion at the start of keys.
+func parseMotion(keys []rune) (name string, m motion, arg rune, status parseStatus) {
+	if len(keys) == 0 {
+		return "", motion{}, 0, parseIncomplete
+	}
+	name = string(keys[0])
+	if keys[0] == 'g' {
+		if len(keys) < 2 {
+			return "", motion{}, 0, parseIncomplete
+		}
+		name = string(keys[:2])
+	}
+	m, ok := motions[name]
+	if !ok {
+		return "", motion{}, 0, parseInvalid
+	}
+	if m.needsArg {
+		n := len([]rune(name))
+		if len(keys) <= n {
+			return "", motion{}, 0, parseIncomplete
+		}
+		arg = keys[n]
+	}
+	return name, m, arg, parseOK
+}
+
+// exec executes the pending keys with the grammar
+// ["x][count](operator[count]motion | operator operator | command | motion).
+// It returns true if the command is executed or invalid, and false if more
+// keys are expected.
+func (v *Vim) exec(gtx layout.Context) bool {
+	keys := v.keys
+	i := 0
+	reg := '"'
+	if keys[0] == '"' {
+		if len(keys) < 3 {
+			return false
+		}
+		reg, i = keys[1], 2
+	}
+	count, i := parseCount(keys, i)
+	if i >= len(keys) {
+		return false
+	}
+
+	if v.mode == Visual || v.mode == VisualLine {
+		return v.execVisual(gtx, reg, count, keys[i:])
+	}
+
+	// the caret may have been moved by the mouse or the arrow keys.
+	start, _ := v.editor.Selection()
+	v.cursor = min(start, v.reader.lastChar(start))
+
+	c := keys[i]
+	if alias, ok := aliases[c]; ok {
+		return v.execOperator(gtx, keys, reg, count, []rune(alias))
+	}
+	if strings.ContainsRune(operators, c) {
+		return v.execOperator(gtx, keys, reg, count, keys[i:])
+	}
+
+	r := &v.reader
+	off := v.cursor
+	switch c {
+	case 'i', 'a', 'I', 'A':
+		at := off
+		switch c {
+		case 'a':
+			at = min(off+1, r.lineEnd(off))
+		case 'I':
+			at = r.firstNonBlank(off)
+		case 'A':
+			at = r.lineEnd(off)
+		}
+		v.startChange(keys)
+		v.setMode(Insert)
+		v.setCursor(at)
+	case 'o', 'O':
+		ls := r.lineStart(off)
+		indent := v.editor.ReadRange(ls, r.firstNonBlank(ls))
+		at, text := ls, indent+"\n"
+		if c == 'o' {
+			at, text = r.lineEnd(off), "\n"+indent
+		}
+		v.startChange(keys)
+		v.edit(func(tx *gvcode.EditTx) { tx.Insert(at, text) })
+		if c == 'o' {
+			at++
+		}
+		at += len([]rune(indent))
+		v.setMode(Insert)
+		v.setCursor(at)
+	case 'p', 'P':
+		v.startChange(keys)
+		v.put(reg, count, c == 'P')
+		v.endChange()
+	case 'r':
+		if i+1 >= len(keys) {
+			return false
+		}
+		v.replaceChars(keys, keys[i+1], max(count, 1))
+	case 'u':
+		for range max(count, 1) {
+			gvcode.Undo.Run(gtx, v.editor)
+		}
+		v.reader.reset()
+		start, _ := v.editor.Selection()
+		v.setCursor(start)
+	case '.':
+		v.repeat(gtx)
+	case 'v', 'V':
+		v.anchor = off
+		if c == 'v' {
+			v.setMode(Visual)
+		} else {
+			v.setMode(VisualLine)
+		}
+		v.setCursor(off)
+	default:
+		_, m, arg, status := parseMotion(keys[i:])
+		if status == parseIncomplete {
+			return false
+		}
+		if status == parseInvalid {
+			return true
+		}
+		if target, ok := m.move(r, off, count, arg); ok {
+			v.setCursor(target)
+		}
+	}
+	return true
+}
+
+// execOperator executes an operator command. keys are all the keys of the
+// command, and opKeys are the keys starting with the operator.
+func (v *Vim) execOperator(gtx layout.Context, keys []rune, reg rune, count int, opKeys []rune) bool {
+	op := opKeys[0]
+	count2, j := parseCount(opKeys, 1)
+	if j >= len(opKeys) {
+		return false
+	}
+	if count > 0 || count2 > 0 {
+		count = max(count, 1) * max(count2, 1)
+	}
+
+	r := &v.reader
+	off := v.cursor
+	if opKeys[j] == op {
+		// the operator applies to count lines.
+		end := off
+		for range max(count, 1) - 1 {
+			next := r.nextLine(end)
+			if next < 0 {
+				break
+			}
+			end = next
+		}
+		v.startChange(keys)
+		v.operate(op, reg, off, end, true)
+		v.endChange()
+		return true
+	}
+
+	name, m, arg, status := parseMotion(opKeys[j:])
+	if status == parseIncomplete {
+		return false
+	}
+	if status == parseInvalid {
+		return true
+	}
+
+	var target int
+	var ok bool
+	if op == 'c' && (name == "w" || name == "W") && !unicode.IsSpace(v.charAt(off)) {
+		// "cw" changes to the end of the word, like "ce", but stays in a
+		// word of one character.
+		target, ok = v.wordEnd(off, count, name == "W"), true
+		m.inclusive = true
+	} else {
+		target, ok = m.move(r, off, count, arg)
+	}
+	if !ok {
+		return true
+	}
+	if (name == "w" || name == "W") && r.lineStart(target) > r.lineStart(off) {
+		// "dw" on the last word of a line doesn't join the next line.
+		target = max(off, r.lineEnd(r.prevLine(target)))
+	}
+
+	start, end := min(off, target), max(off, target)
+	if m.inclusive && !m.linewise {
+		end = min(end+1, r.len())
+	}
+	if op != 'y' {
+		v.startChange(keys)
+	}
+	v.operate(op, reg, start, end, m.linewise)
+	v.endChange()
+	return true
+}
+
+// execVisual executes the keys in the visual modes.
+func (v *Vim) execVisual(gtx layout.Context, reg rune, count int, keys []rune) bool {
+	r := &v.reader
+	c := keys[0]
+	switch c {
+	case 'o':
+		v.anchor, v.cursor = v.cursor, v.anchor
+		v.setCursor(v.cursor)
+	case 'v', 'V':
+		mode := Visual
+		if c == 'V' {
+			mode = VisualLine
+		}
+		if v.mode == mode {
+			mode = Normal
+		}
+		v.setMode(mode)
+		v.setCursor(v.cursor)
+	case 'd', 'x', 'c', 's', 'y', '>', '<':
+		start, end := v.visualRange(v.cursor)
+		linewise := v.mode == VisualLine
+		op := c
+		switch c {
+		case 'x':
+			op = 'd'
+		case 's':
+			op = 'c'
+		}
+		v.setMode(Normal)
+		v.operate(op, reg, start, end, linewise)
+	default:
+		_, m, arg, status := parseMotion(keys)
+		if status == parseIncomplete {
+			return false
+		}
+		if status == parseOK {
+			if target, ok := m.move(r, v.cursor, count, arg); ok {
+				v.setCursor(target)
+			}
+		}
+	}
+	return true
+}
+
+// operate applies the operator to the text in [start, end), or to the lines
+// from start to end if linewise is true.
+func (v *Vim) operate(op, reg rune, start, end int, linewise bool) {
+	r := &v.reader
+	if linewise {
+		start, end = r.lineStart(start), r.lineEnd(end)
+	}
+	if op == '>' || op == '<' {
+		v.indentLines(start, end, op == '<')
+		return
+	}
+
+	text := v.editor.ReadRange(start, end)
+	if linewise {
+		text += "\n"
+	}
+	v.store(reg, text, linewise, op == 'y')
+
+	switch op {
+	case 'y':
+		if !linewise || start < r.lineStart(v.cursor) {
+			v.setCursor(start)
+		}
+	case 'd':
+		delStart, delEnd := start, end
+		if linewise {
+			// delete the line break after the lines, or before them for the
+			// last lines.
+			if end < r.len() {
+				delEnd++
+			} else if start > 0 {
+				delStart--
+			}
+		}
+		v.edit(func(tx *gvcode.EditTx) { tx.Delete(delStart, delEnd) })
+		if linewise {
+			v.setCursor(r.firstNonBlank(min(start, r.len())))
+		} else {
+			v.setCursor(start)
+		}
+	case 'c':
+		if linewise {
+			// keep the indentation of the first line.
+			start = r.firstNonBlank(start)
+		}
+		v.edit(func(tx *gvcode.EditTx) { tx.Delete(start, end) })
+		v.setMode(Insert)
+		v.setCursor(start)
+	}
+}
+
+// store saves the yanked or deleted text to the register reg and the unnamed
+// register. Uppercase registers append to the lowercase ones, and the black
+// hole register "_" discards the text.
+func (v *Vim) store(reg rune, text string, linewise, yank bool) {
+	if reg == '_' {
+		return
+	}
+	content := register{text: text, linewise: linewise}
+	if unicode.IsUpper(reg) {
+		reg = unicode.ToLower(reg)
+		if prev, ok := v.registers[reg]; ok {
+			content.text = prev.text + text
+			content.linewise = prev.linewise || linewise
+		}
+	}
+
+	v.registers[reg] = content
+	v.registers['"'] = content
+	if reg != '"' {
+		return
+	}
+	if yank {
+		v.registers['0'] = content
+		return
+	}
+	// shift the numbered registers of deleted text.
+	for i := '9'; i > '1'; i-- {
+		v.registers[i] = v.registers[i-1]
+	}
+	v.registers['1'] = content
+}
+
+// put pastes the register reg count times, after the cursor or before it.
+func (v *Vim) put(reg rune, count int, before bool) {
+	content, ok := v.registers[unicode.ToLower(reg)]
+	if !ok || content.text == "" {
+		return
+	}
+	text := strings.Repeat(content.text, max(count, 1))
+
+	r := &v.reader
+	off := v.cursor
+	if content.linewise {
+		at := r.lineStart(off)
+		if !before {
+			at = r.nextLine(off)
+		}
+		if at < 0 {
+			// after the last line.
+			at = r.len()
+			text = "\n" + strings.TrimSuffix(text, "\n")
+		}
+		v.edit(func(tx *gvcode.EditTx) { tx.Insert(at, text) })
+		if strings.HasPrefix(text, "\n") {
+			at++
+		}
+		v.setCursor(r.firstNonBlank(at))
+		return
+	}
+
+	at := off
+	if !before && off < r.lineEnd(off) {
+		at++
+	}
+	var n int
+	v.edit(func(tx *gvcode.EditTx) { n = tx.Insert(at, text) })
+	v.setCursor(at + n - 1)
+}
+
+// replaceChars replaces count characters under the cursor with c.
+func (v *Vim) replaceChars(keys []rune, c rune, count int) {
+	off := v.cursor
+	if c == '\n' || off+count > v.reader.lineEnd(off) {
+		return
+	}
+	v.startChange(keys)
+	v.edit(func(tx *gvcode.EditTx) { tx.Replace(off, off+count, strings.Repeat(string(c), count)) })
+	v.setCursor(off + count - 1)
+	v.endChange()
+}
+
+// indentLines indents or unindents the lines from start to end by one level.
+// Empty lines are not indented.
+func (v *Vim) indentLines(start, end int, unindent bool) {
+	r := &v.reader
+	style, width := v.editor.TabStyle()
+	indent := "\t"
+	if style == gvcode.Spaces {
+		indent = strings.Repeat(" ", width)
+	}
+
+	type lineEdit struct{ at, remove int }
+	var edits []lineEdit
+	for ls := r.lineStart(start); ls >= 0 && ls <= end; ls = r.nextLine(ls) {
+		if !unindent {
+			if r.lineEnd(ls) > ls {
+				edits = append(edits, lineEdit{at: ls})
+			}
+			continue
+		}
+		n := 0
+		for c, _ := r.at(ls); n < max(width, 1); c, _ = r.at(ls + n) {
+			if c == '\t' {
+				n++
+				break
+			}
+			if c != ' ' {
+				break
+			}
+			n++
+		}
+		if n > 0 {
+			edits = append(edits, lineEdit{at: ls, remove: n})
+		}
+	}
+
+	first := r.lineStart(start)
+	v.edit(func(tx *gvcode.EditTx) {
+		// edit from the bottom, so that the offsets of the lines above are
+		// not shifted.
+		for i := len(edits) - 1; i >= 0; i-- {
+			if unindent {
+				tx.Delete(edits[i].at, edits[i].at+edits[i].remove)
+			} else {
+				tx.Insert(edits[i].at, indent)
+			}
+		}
+	})
+	v.setCursor(r.firstNonBlank(first))
+}
+
+// edit runs fn in a transaction of the editor.
+func (v *Vim) edit(fn func(tx *gvcode.EditTx)) {
+	v.editor.Transaction(fn)
+	v.reader.reset()
+}
+
+func (v *Vim) charAt(off int) rune {
+	c, _ := v.reader.at(off)
+	return c
+}
+
+// wordEnd returns the end of the word under off, and of the following words
+// for counts greater than 1.
+func (v *Vim) wordEnd(off, count int, bigWord bool) int {
+	cls := charClass(v.charAt(off), bigWord)
+	for {
+		c, ok := v.reader.at(off + 1)
+		if !ok || c == '\n' || charClass(c, bigWord) != cls {
+			break
+		}
+		off++
+	}
+	for range max(count, 1) - 1 {
+		off = v.reader.wordEnd(off, bigWord)
+	}
+	return off
+}
@@ -0,0 +1,126 @@
+package vim
+
+// motion moves the cursor in the normal and visual modes, or selects the
+// text an operator applies to.
+type motion struct {
+	// move returns the target offset of the motion from off. count is zero if
+	// not given. It returns false if the motion fails.
+	move func(r *textReader, off, count int, arg rune) (int, bool)
+	// linewise motions make operators apply to whole lines.
+	linewise bool
+	// inclusive motions include the character at the target.
+	inclusive bool
+	// needsArg motions take the next key as their argument, e.g., "fx".
+	needsArg bool
+}
+
+var motions = map[string]motion{
+	"h": {move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		target := max(r.lineStart(off), off-max(count, 1))
+		return target, target != off
+	}},
+	"l": {move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		// the target may be the line end, so that "dl" deletes the last
+		// character.
+		target := min(r.lineEnd(off), off+max(count, 1))
+		return target, target != off
+	}},
+	"j": {linewise: true, move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		return moveLines(r, off, max(count, 1))
+	}},
+	"k": {linewise: true, move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		return moveLines(r, off, -max(count, 1))
+	}},
+	"w": {move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		return repeatMove(off, count, func(off int) int { return r.wordForward(off, false) })
+	}},
+	"W": {move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		return repeatMove(off, count, func(off int) int { return r.wordForward(off, true) })
+	}},
+	"b": {move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		return repeatMove(off, count, func(off int) int { return r.wordBackward(off, false) })
+	}},
+	"B": {move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		return repeatMove(off, count, func(off int) int { return r.wordBackward(off, true) })
+	}},
+	"e": {inclusive: true, move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		return repeatMove(off, count, func(off int) int { return r.wordEnd(off, false) })
+	}},
+	"E": {inclusive: true, move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		return repeatMove(off, count, func(off int) int { return r.wordEnd(off, true) })
+	}},
+	"0": {move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		return r.lineStart(off), true
+	}},
+	"^": {move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		return r.firstNonBlank(off), true
+	}},
+	"$": {inclusive: true, move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		if count > 1 {
+			off, _ = moveLines(r, off, count-1)
+		}
+		return r.lastChar(off), true
+	}},
+	"G": {linewise: true, move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		if count == 0 {
+			return r.firstNonBlank(r.lineStart(r.len())), true
+		}
+		return r.firstNonBlank(r.lineAt(count - 1)), true
+	}},
+	"gg": {linewise: true, move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		return r.firstNonBlank(r.lineAt(max(count, 1) - 1)), true
+	}},
+	"f": {inclusive: true, needsArg: true, move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		target := r.findInLine(off, arg, max(count, 1), false)
+		return target, target >= 0
+	}},
+	"t": {inclusive: true, needsArg: true, move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		target := r.findInLine(off+1, arg, max(count, 1), false)
+		return target - 1, target >= 0
+	}},
+	"F": {needsArg: true, move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		target := r.findInLine(off, arg, max(count, 1), true)
+		return target, target >= 0
+	}},
+	"T": {needsArg: true, move: func(r *textReader, off, count int, arg rune) (int, bool) {
+		target := r.findInLine(off-1, arg, max(count, 1), true)
+		return target + 1, target >= 0
+	}},
+}
+
+// moveLines moves off by lines, keeping the column. It moves as far as
+// possible, and fails if it can not move at all.
+func moveLines(r *textReader, off, lines int) (int, bool) {
+	col := off - r.lineStart(off)
+	start := r.lineStart(off)
+	moved := false
+	for ; lines != 0; lines -= sign(lines) {
+		var next int
+		if lines > 0 {
+			next = r.nextLine(start)
+		} else {
+			next = r.prevLine(start)
+		}
+		if next < 0 {
+			break
+		}
+		start = next
+		moved = true
+	}
+	return min(start+col, r.lastChar(start)), moved
+}
+
+func repeatMove(off, count int, move func(off int) int) (int, bool) {
+	target := off
+	for range max(count, 1) {
+		target = move(target)
+	}
+	return target, target != off
+}
+
+func sign(n int) int {
+	if n < 0 {
+		return -1
+	}
+	return 1
+}
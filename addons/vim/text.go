@@ -0,0 +1,210 @@
+package vim
+
+import (
+	"unicode"
+
+	"github.com/oligo/gvcode"
+)
+
+// readChunk is the number of runes read from the editor at a time.
+const readChunk = 1024
+
+// textReader reads the runes of the editor by chunks, so that motions don't
+// have to copy the whole document.
+type textReader struct {
+	editor *gvcode.Editor
+	start  int
+	buf    []rune
+}
+
+func (r *textReader) reset() {
+	r.buf = r.buf[:0]
+}
+
+// len returns the length of the text in runes.
+func (r *textReader) len() int {
+	return r.editor.Len()
+}
+
+// at returns the rune at off. It returns false if off is out of the text.
+func (r *textReader) at(off int) (rune, bool) {
+	if off < 0 || off >= r.len() {
+		return 0, false
+	}
+	if off < r.start || off >= r.start+len(r.buf) {
+		r.start = max(0, off-readChunk/2)
+		r.buf = []rune(r.editor.ReadRange(r.start, min(r.start+readChunk, r.len())))
+	}
+	return r.buf[off-r.start], true
+}
+
+// lineStart returns the offset of the start of the line containing off.
+func (r *textReader) lineStart(off int) int {
+	for off > 0 {
+		if c, _ := r.at(off - 1); c == '\n' {
+			break
+		}
+		off--
+	}
+	return off
+}
+
+// lineEnd returns the offset of the line break ending the line containing
+// off, or the end of the text.
+func (r *textReader) lineEnd(off int) int {
+	for {
+		c, ok := r.at(off)
+		if !ok || c == '\n' {
+			return off
+		}
+		off++
+	}
+}
+
+// nextLine returns the start of the line after the one containing off, or -1
+// if it is the last line.
+func (r *textReader) nextLine(off int) int {
+	end := r.lineEnd(off)
+	if end >= r.len() {
+		return -1
+	}
+	return end + 1
+}
+
+// prevLine returns the start of the line before the one containing off, or
+// -1 if it is the first line.
+func (r *textReader) prevLine(off int) int {
+	start := r.lineStart(off)
+	if start == 0 {
+		return -1
+	}
+	return r.lineStart(start - 1)
+}
+
+// lineAt returns the start of the line with the 0-based index line, or of the
+// last line if there are fewer lines.
+func (r *textReader) lineAt(line int) int {
+	off := 0
+	for ; line > 0; line-- {
+		next := r.nextLine(off)
+		if next < 0 {
+			break
+		}
+		off = next
+	}
+	return off
+}
+
+// firstNonBlank returns the offset of the first character of the line
+// containing off that is not a space or a tab.
+func (r *textReader) firstNonBlank(off int) int {
+	off = r.lineStart(off)
+	for {
+		c, ok := r.at(off)
+		if !ok || (c != ' ' && c != '\t') {
+			return off
+		}
+		off++
+	}
+}
+
+// lastChar returns the offset of the last character of the line containing
+// off, which is the line start for empty lines.
+func (r *textReader) lastChar(off int) int {
+	return max(r.lineStart(off), r.lineEnd(off)-1)
+}
+
+// charClass classifies the runes for word motions: 0 for spaces, 1 for word
+// characters and 2 for punctuations. If bigWord is true, all the non-space
+// runes are of the same class.
+func charClass(c rune, bigWord bool) int {
+	switch {
+	case unicode.IsSpace(c):
+		return 0
+	case bigWord || c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// wordForward returns the start of the next word after off.
+func (r *textReader) wordForward(off int, bigWord bool) int {
+	c, ok := r.at(off)
+	if !ok {
+		return off
+	}
+	if cls := charClass(c, bigWord); cls != 0 {
+		for ok && charClass(c, bigWord) == cls {
+			off++
+			c, ok = r.at(off)
+		}
+	}
+	for ok && charClass(c, bigWord) == 0 {
+		off++
+		c, ok = r.at(off)
+	}
+	return off
+}
+
+// wordEnd returns the end of the word after off, inclusive.
+func (r *textReader) wordEnd(off int, bigWord bool) int {
+	off++
+	c, ok := r.at(off)
+	for ok && charClass(c, bigWord) == 0 {
+		off++
+		c, ok = r.at(off)
+	}
+	if !ok {
+		return max(0, r.len()-1)
+	}
+	cls := charClass(c, bigWord)
+	for {
+		next, ok := r.at(off + 1)
+		if !ok || charClass(next, bigWord) != cls {
+			return off
+		}
+		off++
+	}
+}
+
+// wordBackward returns the start of the word before off.
+func (r *textReader) wordBackward(off int, bigWord bool) int {
+	off--
+	c, ok := r.at(off)
+	for ok && charClass(c, bigWord) == 0 {
+		off--
+		c, ok = r.at(off)
+	}
+	if !ok {
+		return 0
+	}
+	cls := charClass(c, bigWord)
+	for {
+		prev, ok := r.at(off - 1)
+		if !ok || charClass(prev, bigWord) != cls {
+			return off
+		}
+		off--
+	}
+}
+
+// findInLine returns the offset of the count-th occurrence of target in the
+// line of off, searching forward or backward from off. It returns -1 if not
+// found.
+func (r *textReader) findInLine(off int, target rune, count int, backward bool) int {
+	start, end := r.lineStart(off), r.lineEnd(off)
+	step := 1
+	if backward {
+		step = -1
+	}
+	for off += step; off >= start && off < end; off += step {
+		if c, _ := r.at(off); c == target {
+			count--
+			if count == 0 {
+				return off
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,388 @@
+// Package vim implements a modal editing addon emulating the core of Vim: the
+// normal, insert and visual modes, motions, operators with counts, registers
+// and dot-repeat.
+package vim
+
+import (
+	"slices"
+	"strings"
+
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"github.com/oligo/gvcode"
+)
+
+// Mode is the editing mode of Vim.
+type Mode int
+
+const (
+	// Normal mode interprets the typed keys as commands.
+	Normal Mode = iota
+	// Insert mode inserts the typed text.
+	Insert
+	// Visual mode selects characters.
+	Visual
+	// VisualLine mode selects whole lines.
+	VisualLine
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Insert:
+		return "INSERT"
+	case Visual:
+		return "VISUAL"
+	case VisualLine:
+		return "VISUAL LINE"
+	default:
+		return "NORMAL"
+	}
+}
+
+// escape is the key recorded for the escape key in dot-repeat.
+const escape = '\x1b'
+
+// register holds yanked or deleted text.
+type register struct {
+	text     string
+	linewise bool
+}
+
+// Vim adds Vim key bindings to an editor. Typed text is interpreted by Vim
+// except in the insert mode, and the built-in key bindings of the editor are
+// kept, e.g., the arrow keys.
+type Vim struct {
+	editor *gvcode.Editor
+	reader textReader
+	mode   Mode
+	// keys are the keys of the pending command.
+	keys      []rune
+	registers map[rune]register
+	// anchor is the start of the visual selection, and cursor is the
+	// position of the caret in the normal and visual modes.
+	anchor int
+	cursor int
+	// lastChange are the keys of the last change, repeated by ".".
+	lastChange []rune
+	// recording are the keys of the change in progress.
+	recording []rune
+	replaying bool
+	// caretStyle is the caret style of the editor before Enable, used in
+	// the insert mode.
+	caretStyle gvcode.CaretStyle
+
+	// OnModeChange is called when the mode changes.
+	OnModeChange func(mode Mode)
+}
+
+// New creates Vim for the editor. Call Enable to start the modal editing.
+func New(editor *gvcode.Editor) *Vim {
+	return &Vim{
+		editor:    editor,
+		reader:    textReader{editor: editor},
+		registers: make(map[rune]register),
+	}
+}
+
+// allModifiers are the modifiers of the key strokes intercepted by Vim.
+const allModifiers = key.ModCtrl | key.ModCommand | key.ModShift | key.ModAlt | key.ModSuper
+
+// viewCommands are the commands of the editor keymap that do not edit the
+// text, kept in the normal and visual modes.
+var viewCommands = map[string]bool{
+	gvcode.Copy.Name:                  true,
+	gvcode.MoveLeft.Name:              true,
+	gvcode.MoveRight.Name:             true,
+	gvcode.MoveWordLeft.Name:          true,
+	gvcode.MoveWordRight.Name:         true,
+	gvcode.MoveUp.Name:                true,
+	gvcode.MoveDown.Name:              true,
+	gvcode.MoveLineStart.Name:         true,
+	gvcode.MoveLineEnd.Name:           true,
+	gvcode.MoveTextStart.Name:         true,
+	gvcode.MoveTextEnd.Name:           true,
+	gvcode.MoveBlockUp.Name:           true,
+	gvcode.MoveBlockDown.Name:         true,
+	gvcode.MoveToMatchingBracket.Name: true,
+	gvcode.PageUp.Name:                true,
+	gvcode.PageDown.Name:              true,
+	gvcode.HalfPageUp.Name:            true,
+	gvcode.HalfPageDown.Name:          true,
+	gvcode.ToggleWrapLine.Name:        true,
+	gvcode.ToggleFold.Name:            true,
+	gvcode.ToggleCharInspector.Name:   true,
+	gvcode.FindNext.Name:              true,
+	gvcode.FindPrevious.Name:          true,
+	gvcode.GoToLine.Name:              true,
+}
+
+// Enable enables the modal editing in the normal mode. The keys of the
+// editor keymap bound to editing commands, e.g., Backspace, Tab or
+// Shortcut+V, are intercepted, so that they do not edit the text outside of
+// the insert mode. The keymap is read once, so the keys bound after Enable
+// are not intercepted.
+func (v *Vim) Enable() {
+	v.editor.SetTextInputHook(v.onInput)
+	for _, name := range v.interceptedKeys() {
+		v.editor.RegisterCommand(v, key.Filter{Name: name, Optional: allModifiers}, v.onKey)
+	}
+
+	v.keys = v.keys[:0]
+	v.caretStyle = v.editor.CaretStyle()
+	start, _ := v.editor.Selection()
+	v.setCursor(start)
+	v.setMode(Normal)
+	v.updateCaret()
+}
+
+// Disable disables the modal editing, restoring the default key handling.
+func (v *Vim) Disable() {
+	v.editor.SetTextInputHook(nil)
+	v.editor.RemoveCommands(v)
+	v.keys = v.keys[:0]
+	v.setMode(Insert)
+}
+
+// Mode returns the current mode.
+func (v *Vim) Mode() Mode {
+	return v.mode
+}
+
+// PendingKeys returns the keys typed so far of an incomplete command, e.g.,
+// "2d".
+func (v *Vim) PendingKeys() string {
+	return string(v.keys)
+}
+
+// Register returns the text of the named register. The unnamed register is
+// '"'.
+func (v *Vim) Register(name rune) string {
+	return v.registers[name].text
+}
+
+func (v *Vim) setMode(mode Mode) {
+	if v.mode == mode {
+		return
+	}
+	v.mode = mode
+	v.updateCaret()
+	if v.OnModeChange != nil {
+		v.OnModeChange(mode)
+	}
+}
+
+// updateCaret shows a block caret on the character under the cursor, except
+// in the insert mode.
+func (v *Vim) updateCaret() {
+	style := v.caretStyle
+	if v.mode != Insert {
+		style.Shape = gvcode.CaretBlock
+	}
+	v.editor.SetCaretStyle(style)
+}
+
+func (v *Vim) onInput(gtx layout.Context, text string) bool {
+	v.reader.reset()
+	if v.mode == Insert {
+		if v.recording != nil {
+			v.recording = append(v.recording, []rune(text)...)
+		}
+		return false
+	}
+
+	for _, r := range text {
+		v.feed(gtx, r)
+	}
+	return true
+}
+
+// interceptedKeys returns the names of the keys handled by onKey: the keys
+// of the editing commands of the keymap, Esc, Enter and Ctrl+R.
+func (v *Vim) interceptedKeys() []key.Name {
+	names := []key.Name{key.NameEscape, key.NameEnter, key.NameReturn, "R"}
+	for _, b := range v.editor.Keymap().Bindings() {
+		if viewCommands[b.Command.Name] {
+			continue
+		}
+		for _, keys := range strings.Fields(b.Keys) {
+			stroke, err := gvcode.ParseKeyStroke(keys)
+			if err == nil && !slices.Contains(names, stroke.Name) {
+				names = append(names, stroke.Name)
+			}
+		}
+	}
+	return names
+}
+
+// onKey handles the intercepted keys. In the insert mode, the keys run the
+// commands bound in the keymap. In the other modes, Backspace and Delete are
+// the "h" and "x" commands, and the other editing keys are ignored.
+func (v *Vim) onKey(gtx layout.Context, evt key.Event) gvcode.EditorEvent {
+	v.reader.reset()
+	mods := evt.Modifiers
+	switch {
+	case evt.Name == key.NameEscape && mods == 0:
+		v.feed(gtx, escape)
+		return nil
+	case (evt.Name == key.NameEnter || evt.Name == key.NameReturn) && mods&^key.ModShift == 0:
+		v.feed(gtx, '\n')
+		return nil
+	case evt.Name == "R" && mods == key.ModCtrl:
+		return v.onRedo(gtx)
+	}
+
+	if v.mode != Insert {
+		if mods == 0 {
+			switch evt.Name {
+			case key.NameDeleteBackward:
+				v.feed(gtx, 'h')
+			case key.NameDeleteForward:
+				v.feed(gtx, 'x')
+			}
+		}
+		return nil
+	}
+
+	stroke := gvcode.KeyStroke{Name: evt.Name, Modifiers: mods}
+	if cmd, ok := v.editor.Keymap().Lookup(stroke.String()); ok {
+		return cmd.Run(gtx, v.editor)
+	}
+	return nil
+}
+
+func (v *Vim) onRedo(gtx layout.Context) gvcode.EditorEvent {
+	ev := gvcode.Redo.Run(gtx, v.editor)
+	if v.mode != Insert {
+		start, _ := v.editor.Selection()
+		v.setCursor(start)
+		v.setMode(Normal)
+	}
+	return ev
+}
+
+// feed processes a key typed by the user or replayed by dot-repeat.
+func (v *Vim) feed(gtx layout.Context, r rune) {
+	if v.mode == Insert {
+		switch r {
+		case escape:
+			v.exitInsert()
+		case '\n':
+			gvcode.InsertLineBreak.Run(gtx, v.editor)
+			v.record(r)
+		default:
+			// only replayed text gets here, typed text is inserted by the
+			// editor.
+			v.editor.Insert(string(r))
+		}
+		return
+	}
+
+	if r == escape {
+		v.keys = v.keys[:0]
+		if v.mode != Normal {
+			v.setMode(Normal)
+			v.setCursor(v.cursor)
+		}
+		return
+	}
+
+	v.keys = append(v.keys, r)
+	if v.exec(gtx) {
+		v.keys = v.keys[:0]
+	}
+}
+
+func (v *Vim) record(r rune) {
+	if v.recording != nil {
+		v.recording = append(v.recording, r)
+	}
+}
+
+func (v *Vim) exitInsert() {
+	v.setMode(Normal)
+	start, _ := v.editor.Selection()
+	// the cursor moves back onto the last inserted character, as in Vim.
+	if start > v.reader.lineStart(start) {
+		start--
+	}
+	v.setCursor(start)
+
+	if v.recording != nil {
+		if !v.replaying {
+			v.lastChange = append(v.recording, escape)
+		}
+		v.recording = nil
+	}
+}
+
+// setCursor moves the caret to off. In the normal mode, the cursor stays on
+// the characters of the line, never after the last one.
+func (v *Vim) setCursor(off int) {
+	off = max(0, min(off, v.reader.len()))
+	switch v.mode {
+	case Normal:
+		off = min(off, v.reader.lastChar(off))
+		v.editor.SetCaret(off, off)
+	case Visual, VisualLine:
+		v.showSelection(off)
+	default:
+		v.editor.SetCaret(off, off)
+	}
+	v.cursor = off
+}
+
+// showSelection selects the text from the anchor to off.
+func (v *Vim) showSelection(off int) {
+	start, end := v.visualRange(off)
+	if off < v.anchor {
+		v.editor.SetCaret(start, end)
+	} else {
+		v.editor.SetCaret(end, start)
+	}
+}
+
+// visualRange returns the range of the visual selection with the cursor at
+// off. The selection includes the character under the cursor.
+func (v *Vim) visualRange(off int) (start, end int) {
+	start, end = min(v.anchor, off), max(v.anchor, off)
+	if v.mode == VisualLine {
+		return v.reader.lineStart(start), v.reader.lineEnd(end)
+	}
+	return start, min(end+1, v.reader.len())
+}
+
+// startChange begins recording the keys of a change for dot-repeat.
+func (v *Vim) startChange(keys []rune) {
+	if v.replaying {
+		v.recording = []rune{}
+		return
+	}
+	v.recording = append([]rune{}, keys...)
+}
+
+// endChange ends the recording of a change, unless it continues in the
+// insert mode.
+func (v *Vim) endChange() {
+	if v.mode == Insert {
+		return
+	}
+	if !v.replaying && v.recording != nil {
+		v.lastChange = v.recording
+	}
+	v.recording = nil
+}
+
+// repeat replays the last change.
+func (v *Vim) repeat(gtx layout.Context) {
+	if len(v.lastChange) == 0 || v.replaying {
+		return
+	}
+	v.replaying = true
+	defer func() { v.replaying = false }()
+
+	keys := append([]rune{}, v.lastChange...)
+	v.keys = v.keys[:0]
+	for _, r := range keys {
+		v.feed(gtx, r)
+	}
+}
@@ -0,0 +1,226 @@
+package vim
+
+import (
+	"image"
+	"slices"
+	"strings"
+	"testing"
+
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/internal/editortest"
+)
+
+// vimEditor is an editor with Vim, receiving the key events through an
+// input router like in a window.
+type vimEditor struct {
+	router input.Router
+	shaper *text.Shaper
+	*gvcode.Editor
+}
+
+func newVim(t *testing.T, content string) (*Vim, *vimEditor) {
+	t.Helper()
+	editor := &vimEditor{Editor: editortest.NewEditor(t, content), shaper: editortest.NewShaper()}
+	gtx := editor.frame()
+	gtx.Execute(key.FocusCmd{Tag: editor.Editor})
+	editor.frame()
+	editor.SetCaret(0, 0)
+	v := New(editor.Editor)
+	v.Enable()
+	editor.frame()
+	return v, editor
+}
+
+// frame processes the queued events and lays out the editor.
+func (e *vimEditor) frame() layout.Context {
+	gtx := layout.Context{Ops: new(op.Ops), Source: e.router.Source(), Constraints: layout.Exact(image.Pt(800, 600))}
+	for {
+		if _, ok := e.Update(gtx); !ok {
+			break
+		}
+	}
+	e.Layout(gtx, e.shaper)
+	e.router.Frame(gtx.Ops)
+	return gtx
+}
+
+// press sends the key pressed with the modifiers.
+func (e *vimEditor) press(name key.Name, mods key.Modifiers) {
+	e.router.Queue(key.Event{Name: name, Modifiers: mods, State: key.Press})
+	e.frame()
+}
+
+// typeKeys types the keys, where "<Esc>" is the escape key and "\n" is the
+// return key. The other keys are typed as text.
+func typeKeys(editor *vimEditor, keys string) {
+	for len(keys) > 0 {
+		if rest, ok := strings.CutPrefix(keys, "<Esc>"); ok {
+			editor.press(key.NameEscape, 0)
+			keys = rest
+			continue
+		}
+		r := []rune(keys)[0]
+		keys = keys[len(string(r)):]
+		if r == '\n' {
+			editor.press(key.NameReturn, 0)
+			continue
+		}
+		start, end := editor.Selection()
+		editor.router.Queue(key.EditEvent{Range: key.Range{Start: start, End: end}, Text: string(r)})
+		editor.frame()
+	}
+}
+
+func TestVimCommands(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		keys    string
+		want    string
+		caret   int
+	}{
+		{"delete word", "foo bar baz", "dw", "bar baz", 0},
+		{"delete words with count", "foo bar baz", "2dw", "baz", 0},
+		{"delete last word", "foo bar\nbaz", "wdw", "foo \nbaz", 3},
+		{"delete chars", "foo bar", "3x", " bar", 0},
+		{"delete to end", "foo bar", "wD", "foo ", 3},
+		{"delete line", "one\ntwo\nthree", "jdd", "one\nthree", 4},
+		{"delete last line", "one\ntwo", "jdd", "one", 0},
+		{"delete lines down", "one\ntwo\nthree\nfour", "dj", "three\nfour", 0},
+		{"delete to find", "foo(bar)", "dt)", ")", 0},
+		{"yank and put line", "one\ntwo", "yyp", "one\none\ntwo", 4},
+		{"put line before", "one\ntwo", "jyykP", "two\none\ntwo", 0},
+		{"put at end", "one\ntwo", "yyjp", "one\ntwo\none", 8},
+		{"put word", "foo bar", "yw$p", "foo barfoo ", 10},
+		{"change word", "foo bar", "cwbaz<Esc>", "baz bar", 2},
+		{"change line", "\tfoo\nbar", "ccbaz<Esc>", "\tbaz\nbar", 3},
+		{"append", "foo", "A bar<Esc>", "foo bar", 6},
+		{"open line", "\tfoo", "obar<Esc>", "\tfoo\n\tbar", 8},
+		{"replace", "foo", "2rx", "xxo", 1},
+		{"visual delete", "foo bar", "vlld", " bar", 0},
+		{"visual line yank", "one\ntwo", "Vyjp", "one\ntwo\none", 8},
+		{"indent", "foo\nbar", ">j", "\tfoo\n\tbar", 1},
+		{"named register", "foo bar", "\"ayw\"_dw\"aP", "foo bar", 3},
+		{"dot repeat", "a b c d", "dw..", "d", 0},
+		{"dot repeat insert", "foo\nbar", "Ax<Esc>j.", "foox\nbarx", 8},
+		{"undo", "foo bar", "dwdwu", "bar", 2},
+		{"go to last line", "one\ntwo\nthree", "Gdd", "one\ntwo", 4},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, editor := newVim(t, tc.content)
+			typeKeys(editor, tc.keys)
+			if got := editor.Text(); got != tc.want {
+				t.Fatalf("keys %q: got %q, want %q", tc.keys, got, tc.want)
+			}
+			if v.Mode() != Normal {
+				t.Fatalf("keys %q: got mode %v, want normal", tc.keys, v.Mode())
+			}
+			if caret, _ := editor.Selection(); caret != tc.caret {
+				t.Fatalf("keys %q: got caret %d, want %d", tc.keys, caret, tc.caret)
+			}
+		})
+	}
+}
+
+func TestVimModes(t *testing.T) {
+	v, editor := newVim(t, "foo")
+	var modes []Mode
+	v.OnModeChange = func(mode Mode) { modes = append(modes, mode) }
+
+	typeKeys(editor, "iab")
+	if v.Mode() != Insert || editor.Text() != "abfoo" {
+		t.Fatalf("insert: got mode %v and text %q", v.Mode(), editor.Text())
+	}
+	typeKeys(editor, "<Esc>vl")
+	if v.Mode() != Visual || editor.SelectedText() != "bf" {
+		t.Fatalf("visual: got mode %v and selection %q", v.Mode(), editor.SelectedText())
+	}
+	typeKeys(editor, "<Esc>")
+	if want := []Mode{Insert, Normal, Visual, Normal}; !slices.Equal(modes, want) {
+		t.Fatalf("got modes %v, want %v", modes, want)
+	}
+
+	v.Disable()
+	typeKeys(editor, "x")
+	if got := editor.Text(); got != "abxfoo" {
+		t.Fatalf("got %q after disabling, want the text inserted", got)
+	}
+}
+
+func TestVimEditingKeys(t *testing.T) {
+	v, editor := newVim(t, "foo\nbar")
+	for _, k := range []struct {
+		name key.Name
+		mods key.Modifiers
+	}{
+		{key.NameTab, 0},
+		{key.NameTab, key.ModShift},
+		{key.NameReturn, key.ModShift},
+		{"V", key.ModShortcut},
+		{"X", key.ModShortcut},
+		{"D", key.ModShortcut},
+		{"D", key.ModShortcut | key.ModShift},
+		{"/", key.ModShortcut},
+		{key.NameDeleteBackward, key.ModShortcutAlt},
+	} {
+		editor.press(k.name, k.mods)
+		if got := editor.Text(); got != "foo\nbar" {
+			t.Fatalf("%v: got %q in the normal mode, want the text unchanged", gvcode.KeyStroke{Name: k.name, Modifiers: k.mods}, got)
+		}
+	}
+
+	// Backspace and Delete are "h" and "x".
+	typeKeys(editor, "$")
+	editor.press(key.NameDeleteBackward, 0)
+	editor.press(key.NameDeleteForward, 0)
+	if got, caret := editor.Text(), v.cursor; got != "fo\nbar" || caret != 1 {
+		t.Fatalf("got %q with the cursor at %d, want %q at 1", got, caret, "fo\nbar")
+	}
+
+	// the keys edit the text in the insert mode.
+	typeKeys(editor, "A")
+	editor.press(key.NameDeleteBackward, 0)
+	editor.press(key.NameTab, 0)
+	if got, want := editor.Text(), "f\t\nbar"; got != want {
+		t.Fatalf("got %q in the insert mode, want %q", got, want)
+	}
+}
+
+func TestVimRegisters(t *testing.T) {
+	v, editor := newVim(t, "one\ntwo")
+	typeKeys(editor, "\"Ayy")
+	typeKeys(editor, "j\"Ayy")
+	if got := v.Register('a'); got != "one\ntwo\n" {
+		t.Fatalf("appended register: got %q", got)
+	}
+	typeKeys(editor, "yydd")
+	if got := v.Register('1'); got != "two\n" {
+		t.Fatalf("delete register: got %q", got)
+	}
+	if got := v.Register('0'); got != "two\n" {
+		t.Fatalf("yank register: got %q", got)
+	}
+}
+
+func TestVimCaretShape(t *testing.T) {
+	v, editor := newVim(t, "foo")
+	if got := editor.CaretStyle().Shape; got != gvcode.CaretBlock {
+		t.Fatalf("normal: got caret shape %v, want block", got)
+	}
+	typeKeys(editor, "i")
+	if got := editor.CaretStyle().Shape; got != gvcode.CaretBar {
+		t.Fatalf("insert: got caret shape %v, want bar", got)
+	}
+	typeKeys(editor, "<Esc>")
+	v.Disable()
+	if got := editor.CaretStyle().Shape; got != gvcode.CaretBar {
+		t.Fatalf("disabled: got caret shape %v, want bar", got)
+	}
+}
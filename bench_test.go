@@ -0,0 +1,130 @@
+package gvcode
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+// benchmarkLines is the number of lines of the synthetic documents.
+const benchmarkLines = 100_000
+
+// syntheticDocument generates Go code of about lines lines, with functions of
+// various lengths, comments and blank lines.
+func syntheticDocument(lines int) string {
+	var sb strings.Builder
+	sb.WriteString("package bench\n\n")
+	for n, i := 2, 0; n < lines; i++ {
+		fmt.Fprintf(&sb, "// fn%d computes a value from the arguments.\n", i)
+		fmt.Fprintf(&sb, "func fn%d(a, b int) int {\n", i)
+		n += 2
+		for j := range i%7 + 1 {
+			fmt.Fprintf(&sb, "\tif a > %d {\n\t\tb += a * %d // a comment at the end of the line\n\t}\n", j, i)
+			n += 3
+		}
+		sb.WriteString("\treturn a + b\n}\n\n")
+		n += 3
+	}
+	return sb.String()
+}
+
+// newBenchEditor creates an editor showing a synthetic document, laid out
+// once.
+func newBenchEditor(b *testing.B, virtual bool) (*Editor, layout.Context, *text.Shaper) {
+	b.Helper()
+	e := &Editor{}
+	e.WithOptions(
+		WithColorScheme(syntax.ColorScheme{}),
+		WithTextSize(14),
+		WithDefaultGutters(),
+		WithVirtualLayout(virtual),
+	)
+	e.SetText(syntheticDocument(benchmarkLines))
+	shaper := text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(1200, 800))}
+	e.Layout(gtx, shaper)
+	return e, gtx, shaper
+}
+
+// runLayouts runs the sub-benchmarks of fn with the full and the virtualized
+// layouts.
+func runLayouts(b *testing.B, fn func(b *testing.B, virtual bool)) {
+	for _, virtual := range []bool{false, true} {
+		name := "full"
+		if virtual {
+			name = "virtual"
+		}
+		b.Run(name, func(b *testing.B) { fn(b, virtual) })
+	}
+}
+
+// reportStats reports the shaping activity per operation.
+func reportStats(b *testing.B, e *Editor) {
+	b.ReportMetric(float64(e.Stats().GlyphCache.Misses)/float64(b.N), "shaped/op")
+}
+
+func BenchmarkOpen(b *testing.B) {
+	doc := syntheticDocument(benchmarkLines)
+	runLayouts(b, func(b *testing.B, virtual bool) {
+		e, gtx, shaper := newBenchEditor(b, virtual)
+		b.ResetTimer()
+		for range b.N {
+			e.SetText(doc)
+			gtx.Ops.Reset()
+			e.Layout(gtx, shaper)
+		}
+	})
+}
+
+func BenchmarkTyping(b *testing.B) {
+	runLayouts(b, func(b *testing.B, virtual bool) {
+		e, gtx, shaper := newBenchEditor(b, virtual)
+		// type in the middle of the document.
+		off, _ := e.ConvertPos(benchmarkLines/2, 1)
+		e.SetCaret(off, off)
+		e.ResetStats()
+
+		b.ResetTimer()
+		for i := range b.N {
+			if i%40 == 39 {
+				e.Insert("\n")
+			} else {
+				e.Insert("x")
+			}
+			gtx.Ops.Reset()
+			e.Layout(gtx, shaper)
+		}
+		b.StopTimer()
+		reportStats(b, e)
+		b.ReportMetric(float64(e.Stats().Buffer.Pieces), "pieces")
+	})
+}
+
+func BenchmarkScrolling(b *testing.B) {
+	runLayouts(b, func(b *testing.B, virtual bool) {
+		e, gtx, shaper := newBenchEditor(b, virtual)
+		e.ResetStats()
+
+		b.ResetTimer()
+		for range b.N {
+			// scroll down by a page, and back to the top at the end.
+			_, _, minY, maxY := e.ScrollRatio()
+			if maxY >= 1 {
+				e.Scroll(gtx, 0, -1)
+			} else {
+				e.Scroll(gtx, 0, maxY-minY)
+			}
+			gtx.Ops.Reset()
+			e.Layout(gtx, shaper)
+		}
+		b.StopTimer()
+		reportStats(b, e)
+	})
+}
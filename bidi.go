@@ -0,0 +1,15 @@
+package gvcode
+
+// CaretMovement controls how the Left and Right arrow keys move the caret in
+// text mixing LTR and RTL runs, e.g., a comment in Hebrew or Arabic.
+type CaretMovement uint8
+
+const (
+	// CaretLogical moves the caret to the next or the previous character in
+	// the text order, so the caret moves towards the opposite direction of the
+	// arrow key in runs of the other direction than the locale.
+	CaretLogical CaretMovement = iota
+	// CaretVisual moves the caret to the adjacent position on the screen,
+	// in the direction of the arrow key.
+	CaretVisual
+)
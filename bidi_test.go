@@ -0,0 +1,54 @@
+package gvcode
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/io/input"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestCaretMovement(t *testing.T) {
+	// "ab " is followed by an RTL run of 4 runes and " cd".
+	const src = "ab שלום cd"
+	for _, tc := range []struct {
+		mode CaretMovement
+		want []int
+	}{
+		{CaretLogical, []int{3, 4, 5, 6, 7, 8}},
+		{CaretVisual, []int{3, 7, 6, 5, 4, 8}},
+	} {
+		e := newTestEditor(t, src, WithCaretMovement(tc.mode))
+		shaper := text.NewShaper()
+		var router input.Router
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 200)), Source: router.Source()}
+		e.Layout(gtx, shaper)
+		e.SetCaret(2, 2)
+
+		for i, want := range tc.want {
+			MoveRight.Run(gtx, e)
+			if got, _ := e.Selection(); got != want {
+				t.Errorf("mode %d, move %d: caret at %d, want %d", tc.mode, i, got, want)
+			}
+		}
+
+		// the selection extends to the left, keeping its anchor.
+		SelectLeft.Run(gtx, e)
+		start, end := e.Selection()
+		if start != tc.want[len(tc.want)-2] || end != 8 {
+			t.Errorf("mode %d: selection is [%d, %d), want [%d, 8)", tc.mode, start, end, tc.want[len(tc.want)-2])
+		}
+
+		// Home and End move in the text order in both modes.
+		MoveLineStart.Run(gtx, e)
+		if got, _ := e.Selection(); got != 0 {
+			t.Errorf("mode %d: Home moved to %d, want 0", tc.mode, got)
+		}
+		MoveLineEnd.Run(gtx, e)
+		if got, _ := e.Selection(); got != 10 {
+			t.Errorf("mode %d: End moved to %d, want 10", tc.mode, got)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package gvcode
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// binarySniffLen is the number of bytes inspected to detect binary content.
+const binarySniffLen = 8000
+
+// minBinarySample is the minimum number of bytes inspected before the ratios
+// of control and invalid UTF-8 bytes are trusted, so that a short text with a
+// stray byte is not binary.
+const minBinarySample = 32
+
+// maxControlRatio is the ratio of control bytes above which content is
+// considered binary.
+const maxControlRatio = 0.1
+
+// maxInvalidUTF8Ratio is the ratio of invalid UTF-8 bytes above which content
+// is considered binary.
+const maxInvalidUTF8Ratio = 0.1
+
+// ErrBinaryContent is returned when opening binary content as text.
+var ErrBinaryContent = errors.New("binary content")
+
+// BinaryContentEvent is generated when the text set by SetText looks like
+// binary content, instead of laying out garbage text. The text is not set,
+// and the application can show the content with OpenHexView instead.
+type BinaryContentEvent struct {
+	Data []byte
+}
+
+func (BinaryContentEvent) isEditorEvent() {}
+
+// IsBinary reports whether data looks like binary content: its first bytes
+// contain a NUL byte, too many control bytes other than the whitespace,
+// backspace and escape ones, or too many invalid UTF-8 bytes. A few invalid
+// bytes are not binary, and are handled by the InvalidUTF8Policy.
+func IsBinary(data []byte) bool {
+	sample := data[:min(len(data), binarySniffLen)]
+	n := len(sample)
+	control, invalid := 0, 0
+	for len(sample) > 0 {
+		r, size := utf8.DecodeRune(sample)
+		switch {
+		case r == 0:
+			return true
+		case r == utf8.RuneError && size == 1:
+			if len(sample) < utf8.UTFMax && len(data) > binarySniffLen {
+				// a sequence cut by the end of the sample.
+				n -= len(sample)
+				sample = nil
+				continue
+			}
+			invalid++
+		case r < 0x20 && !strings.ContainsRune("\t\n\v\f\r\b\x1b", r), r == 0x7f:
+			control++
+		}
+		sample = sample[size:]
+	}
+
+	if n < minBinarySample {
+		return false
+	}
+	return float64(control) > maxControlRatio*float64(n) ||
+		float64(invalid) > maxInvalidUTF8Ratio*float64(n)
+}
+
+// isBinaryText reports whether the text set by SetText should be rejected as
+// binary content.
+func (e *Editor) isBinaryText(s string) bool {
+	if e.allowBinary || e.invalidUTF8 == InvalidUTF8PassThrough {
+		return false
+	}
+	return IsBinary([]byte(s))
+}
+
+// OpenHexView shows data as a read-only hex dump, with the offsets, the hex
+// bytes and their printable characters on each line. The hex view is closed
+// by CloseHexView, or by setting another text with SetText, which restore
+// the mode of the editor before the hex view was opened.
+func (e *Editor) OpenHexView(data []byte) {
+	mode := e.mode
+	if e.hexView {
+		mode = e.hexViewMode
+	}
+	e.SetText(hex.Dump(data))
+	e.setMode(ModeReadOnly)
+	e.hexView, e.hexViewMode = true, mode
+}
+
+// CloseHexView clears the hex dump shown by OpenHexView, and restores the
+// mode of the editor. It does nothing if the hex view is not open.
+func (e *Editor) CloseHexView() {
+	if e.hexView {
+		e.SetText("")
+	}
+}
+
+// closeHexView restores the mode of the editor before the hex view was
+// opened, when its text is replaced.
+func (e *Editor) closeHexView() {
+	if !e.hexView {
+		return
+	}
+	e.hexView = false
+	e.setMode(e.hexViewMode)
+}
@@ -0,0 +1,75 @@
+package gvcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+func TestIsBinary(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"text", []byte("package main\n\nfunc main() {}\n"), false},
+		{"unicode", []byte("héllo wörld ✓"), false},
+		{"nul byte", []byte("ELF\x00\x01\x02"), true},
+		{"few invalid bytes", []byte(strings.Repeat("text ", 10) + "\xff"), false},
+		{"many invalid bytes", bytes.Repeat([]byte{0xff, 'a'}, 100), true},
+		{"rune cut by the sample end", []byte("a" + strings.Repeat("é", binarySniffLen)), false},
+		{"short invalid text", []byte("a\xffb\xe2\x82c"), false},
+		{"ansi escapes", []byte(strings.Repeat("\x1b[31mred\x1b[0m\r\n", 10)), false},
+		{"many control bytes", bytes.Repeat([]byte{0x01, 0x02, 'a', 'b'}, 20), true},
+		{"short text with a control byte", []byte("a\x01b"), false},
+	}
+
+	for _, tc := range cases {
+		if got := IsBinary(tc.data); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestBinaryContentEvent(t *testing.T) {
+	e := newTestEditor(t, "text")
+	data := "\x7fELF\x00\x00"
+	e.SetText(data)
+	if got := e.Text(); got != "text" {
+		t.Fatalf("got text %q, want it unchanged", got)
+	}
+
+	evt, ok := e.Update(layout.Context{Ops: new(op.Ops)})
+	binary, isBinary := evt.(BinaryContentEvent)
+	if !ok || !isBinary || string(binary.Data) != data {
+		t.Fatalf("got event %#v", evt)
+	}
+
+	e.OpenHexView(binary.Data)
+	if !e.ReadOnly() || !strings.HasPrefix(e.Text(), "00000000  7f 45 4c 46 00 00") {
+		t.Fatalf("hex view: got read-only %v and text %q", e.ReadOnly(), e.Text())
+	}
+
+	// closing the hex view restores the mode.
+	e.CloseHexView()
+	if e.ReadOnly() || e.Text() != "" {
+		t.Fatalf("closed hex view: got read-only %v and text %q", e.ReadOnly(), e.Text())
+	}
+	e.WithOptions(ReadOnlyMode(true))
+	e.OpenHexView(binary.Data)
+	e.OpenHexView(binary.Data)
+	e.SetText("text")
+	if !e.ReadOnly() || e.Text() != "text" {
+		t.Fatalf("replaced hex view: got read-only %v and text %q, want the read-only mode kept", e.ReadOnly(), e.Text())
+	}
+	e.WithOptions(ReadOnlyMode(false))
+
+	e.WithOptions(WithBinaryDetection(false))
+	e.SetText(data)
+	if got := e.Text(); got != data {
+		t.Fatalf("detection disabled: got text %q, want %q", got, data)
+	}
+}
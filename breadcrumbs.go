@@ -0,0 +1,149 @@
+package gvcode
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// BreadcrumbsEvent is generated when the scopes enclosing the caret change,
+// if enabled by WithBreadcrumbs.
+type BreadcrumbsEvent struct {
+	Path []Symbol
+}
+
+func (BreadcrumbsEvent) isEditorEvent() {}
+
+var (
+	packagePattern  = regexp.MustCompile(`^package\s+(\w+)`)
+	receiverPattern = regexp.MustCompile(`^func\s*\(\s*(?:\w+\s+)?\*?\s*(\w+)`)
+)
+
+// breadcrumbState tracks the path of the scopes reported by the last
+// BreadcrumbsEvent.
+type breadcrumbState struct {
+	enabled bool
+	synced  bool
+	version int
+	caret   int
+	path    []Symbol
+}
+
+// Breadcrumbs returns the chain of the scopes enclosing the caret, from the
+// outermost one: the package of the file, the type of the receiver of a
+// method, then the symbols of the Outline containing the caret, e.g.,
+// main → Point → String. Hosts can render it as a breadcrumb bar, and call
+// JumpToSymbol when a scope is clicked. The receiver type has a Line of -1
+// if it is not declared in the document.
+func (e *Editor) Breadcrumbs() []Symbol {
+	e.initBuffer()
+	caret, _ := e.text.Selection()
+	return e.breadcrumbsAt(caret)
+}
+
+// breadcrumbsAt returns the chain of the scopes enclosing the rune offset.
+func (e *Editor) breadcrumbsAt(offset int) []Symbol {
+	s := e.analyzeStructure()
+	symbols := s.symbols()
+
+	var path []Symbol
+	if pkg, ok := s.packageSymbol(); ok {
+		path = append(path, pkg)
+	}
+
+	var scopes []Symbol
+	for children := symbols; ; {
+		idx := slices.IndexFunc(children, func(sym Symbol) bool {
+			return offset >= sym.Range.Start && offset <= sym.Range.End
+		})
+		if idx < 0 {
+			break
+		}
+		sym := children[idx]
+		children = sym.Children
+		sym.Children = nil
+		scopes = append(scopes, sym)
+	}
+
+	for _, scope := range scopes {
+		if scope.Kind == SymbolFunction {
+			if recv, ok := s.receiverSymbol(scope, symbols); ok {
+				path = append(path, recv)
+			}
+		}
+		path = append(path, scope)
+	}
+	return path
+}
+
+// packageSymbol returns the package clause of a Go file, the comments and
+// the blank lines before it skipped.
+func (s structure) packageSymbol() (Symbol, bool) {
+	for i, line := range s.lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		m := packagePattern.FindStringSubmatchIndex(trimmed)
+		if m == nil {
+			return Symbol{}, false
+		}
+
+		indent := utf8.RuneCountInString(line[:strings.Index(line, trimmed)])
+		start := s.lineStarts[i] + indent + utf8.RuneCountInString(trimmed[:m[2]])
+		last := len(s.lines) - 1
+		return Symbol{
+			Name:      trimmed[m[2]:m[3]],
+			Kind:      SymbolPackage,
+			Line:      i,
+			Range:     TextRange{Start: 0, End: s.lineStarts[last] + utf8.RuneCountInString(s.lines[last])},
+			NameRange: TextRange{Start: start, End: start + utf8.RuneCountInString(trimmed[m[2]:m[3]])},
+		}, true
+	}
+	return Symbol{}, false
+}
+
+// receiverSymbol returns the receiver type of the method fn, which is the
+// type declared among symbols if any.
+func (s structure) receiverSymbol(fn Symbol, symbols []Symbol) (Symbol, bool) {
+	m := receiverPattern.FindStringSubmatch(strings.TrimSpace(s.lines[fn.Line]))
+	if m == nil {
+		return Symbol{}, false
+	}
+
+	idx := slices.IndexFunc(symbols, func(sym Symbol) bool {
+		return sym.Kind == SymbolType && sym.Name == m[1]
+	})
+	if idx < 0 {
+		return Symbol{Name: m[1], Kind: SymbolType, Line: -1}, true
+	}
+	recv := symbols[idx]
+	recv.Children = nil
+	return recv, true
+}
+
+// syncBreadcrumbs generates a BreadcrumbsEvent if the scopes enclosing the
+// caret changed since the last event. The scopes are computed again only
+// when the caret moves or the text changes.
+func (e *Editor) syncBreadcrumbs() {
+	s := &e.breadcrumbs
+	if !s.enabled {
+		return
+	}
+	caret, _ := e.text.Selection()
+	version := e.buffer.Version()
+	if s.synced && caret == s.caret && version == s.version {
+		return
+	}
+	s.synced, s.caret, s.version = true, caret, version
+
+	path := e.breadcrumbsAt(caret)
+	if slices.EqualFunc(path, s.path, func(a, b Symbol) bool {
+		return a.Name == b.Name && a.Kind == b.Kind && a.Line == b.Line
+	}) {
+		return
+	}
+	s.path = path
+	e.pending = append(e.pending, BreadcrumbsEvent{Path: path})
+}
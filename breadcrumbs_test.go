@@ -0,0 +1,81 @@
+package gvcode
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestBreadcrumbs(t *testing.T) {
+	e := newGoEditor(t, "// Package main is a test.\n"+outlineSource+"\nfunc (b *Buffer) Len() int {\n\treturn 0\n}\n")
+	e.WithOptions(WithBreadcrumbs(true))
+
+	names := func(path []Symbol) []string {
+		var out []string
+		for _, sym := range path {
+			out = append(out, sym.Kind.String()+" "+sym.Name)
+		}
+		return out
+	}
+	check := func(line, col int, want ...string) {
+		t.Helper()
+		off := e.text.ConvertPos(line, col)
+		e.SetCaret(off, off)
+		got := names(e.Breadcrumbs())
+		if len(got) != len(want) {
+			t.Fatalf("at %d:%d got %q, want %q", line, col, got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("at %d:%d got %q, want %q", line, col, got, want)
+			}
+		}
+	}
+
+	check(3, 0, "package main")
+	check(12, 3, "package main", "type Point", "function String")
+	check(16, 1, "package main", "function main")
+	check(20, 2, "package main", "type Buffer", "function Len")
+
+	path := e.Breadcrumbs()
+	if path[1].Line != -1 {
+		t.Errorf("got line %d for the undeclared receiver type, want -1", path[1].Line)
+	}
+	if got := e.ReadRange(path[0].NameRange.Start, path[0].NameRange.End); got != "main" {
+		t.Errorf("package name range reads %q", got)
+	}
+
+	// a BreadcrumbsEvent is generated when the caret moves to another scope.
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	events := func() []BreadcrumbsEvent {
+		var out []BreadcrumbsEvent
+		for {
+			evt, ok := e.Update(gtx)
+			if !ok {
+				break
+			}
+			if be, ok := evt.(BreadcrumbsEvent); ok {
+				out = append(out, be)
+			}
+		}
+		e.Layout(gtx, shaper)
+		return out
+	}
+	events()
+
+	off := e.text.ConvertPos(12, 1)
+	e.SetCaret(off, off)
+	if got := events(); len(got) != 1 || len(got[0].Path) != 3 || got[0].Path[2].Name != "String" {
+		t.Fatalf("got events %+v, want one event with the path to String", got)
+	}
+	// moving in the same scope does not generate an event.
+	e.SetCaret(off+1, off+1)
+	if got := events(); len(got) != 0 {
+		t.Errorf("got events %+v in the same scope", got)
+	}
+}
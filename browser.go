@@ -0,0 +1,137 @@
+package gvcode
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"gioui.org/io/key"
+)
+
+// In web builds, the browser sees the key events before Gio does. The js/wasm
+// integration in browser_js.go relies on the helpers below to decide which of
+// the DOM key events are editor commands, whose browser default action, e.g.,
+// saving the page for Ctrl+S, must be prevented, and which ones belong to an
+// input method composing text and must not reach the editor at all.
+
+// domKeyNames maps the DOM KeyboardEvent.key values of the named keys to the
+// key names.
+var domKeyNames = map[string]key.Name{
+	"ArrowLeft":  key.NameLeftArrow,
+	"ArrowRight": key.NameRightArrow,
+	"ArrowUp":    key.NameUpArrow,
+	"ArrowDown":  key.NameDownArrow,
+	"Enter":      key.NameReturn,
+	"Escape":     key.NameEscape,
+	"Home":       key.NameHome,
+	"End":        key.NameEnd,
+	"Backspace":  key.NameDeleteBackward,
+	"Delete":     key.NameDeleteForward,
+	"PageUp":     key.NamePageUp,
+	"PageDown":   key.NamePageDown,
+	"Tab":        key.NameTab,
+	" ":          key.NameSpace,
+	"F1":         key.NameF1,
+	"F2":         key.NameF2,
+	"F3":         key.NameF3,
+	"F4":         key.NameF4,
+	"F5":         key.NameF5,
+	"F6":         key.NameF6,
+	"F7":         key.NameF7,
+	"F8":         key.NameF8,
+	"F9":         key.NameF9,
+	"F10":        key.NameF10,
+	"F11":        key.NameF11,
+	"F12":        key.NameF12,
+}
+
+// domKeyEvent is the part of a DOM KeyboardEvent relevant to the editor.
+type domKeyEvent struct {
+	Key                    string
+	KeyCode                int
+	IsComposing            bool
+	Ctrl, Shift, Alt, Meta bool
+}
+
+// imeProcessKeyCode is the legacy key code of the key events consumed by an
+// input method.
+const imeProcessKeyCode = 229
+
+// keyStroke converts the DOM key event to a key stroke. It reports false for
+// the keys which are not translated to key events, e.g., modifiers or dead
+// keys.
+func (ev domKeyEvent) keyStroke() (KeyStroke, bool) {
+	var stroke KeyStroke
+	if name, ok := domKeyNames[ev.Key]; ok {
+		stroke.Name = name
+	} else if utf8.RuneCountInString(ev.Key) == 1 {
+		stroke.Name = key.Name(strings.ToUpper(ev.Key))
+	} else {
+		return KeyStroke{}, false
+	}
+
+	if ev.Ctrl {
+		stroke.Modifiers |= key.ModCtrl
+	}
+	if ev.Shift {
+		stroke.Modifiers |= key.ModShift
+	}
+	if ev.Alt {
+		stroke.Modifiers |= key.ModAlt
+	}
+	if ev.Meta {
+		stroke.Modifiers |= key.ModSuper
+	}
+	return stroke, true
+}
+
+// composing reports whether the key event is part of an input method
+// composition. Browsers disagree on how these events are marked: Chrome sets
+// isComposing, while Safari sends the key committing the composition, e.g.,
+// Enter, after compositionend with only the legacy key code set.
+func (ev domKeyEvent) composing() bool {
+	return ev.IsComposing || ev.KeyCode == imeProcessKeyCode
+}
+
+// hidesFromEditor reports whether the key event must be kept from the editor
+// because an input method is consuming it. Such keys, like Enter committing a
+// candidate or the arrows moving in the candidate list, would otherwise also
+// run the editor command bound to them.
+func (ev domKeyEvent) hidesFromEditor() bool {
+	if !ev.composing() {
+		return false
+	}
+	_, named := domKeyNames[ev.Key]
+	return named
+}
+
+// preventsDefault reports whether the browser default action of the key event
+// must be prevented, because the key stroke is bound to an editor command
+// given the pending key strokes of a chord. Text input, the keys an input
+// method is composing with, and the paste command are left to the browser:
+// the paste event it generates carries the clipboard text without requiring
+// the permission of the asynchronous clipboard API.
+func (km *Keymap) preventsDefault(ev domKeyEvent, pending []KeyStroke) bool {
+	if ev.composing() {
+		return false
+	}
+	stroke, ok := ev.keyStroke()
+	if !ok {
+		return false
+	}
+	if stroke.Modifiers&^key.ModShift == 0 && utf8.RuneCountInString(ev.Key) == 1 {
+		// typing a character.
+		return false
+	}
+
+	chord := append(pending[:len(pending):len(pending)], stroke)
+	for _, strokes := range [][]KeyStroke{chord, {stroke}} {
+		cmd, exact, prefix := km.match(strokes)
+		if prefix || exact && cmd.Name != Paste.Name {
+			return true
+		}
+		if len(pending) == 0 {
+			break
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+//go:build js && wasm
+
+package gvcode
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"syscall/js"
+	"time"
+
+	"gioui.org/io/clipboard"
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// browser is the state shared by the editors of a web build with the DOM
+// listeners installed by installBrowserListeners. The listeners run on the
+// event loop of the browser, while the editors are laid out by the window
+// goroutine.
+var browser struct {
+	install sync.Once
+
+	mu sync.Mutex
+	// focused is the editor focused in the last frame, whose keymap decides
+	// which browser shortcuts are prevented.
+	focused     *Editor
+	keymap      *Keymap
+	pendingKeys []KeyStroke
+	// pasted is the text of a paste event not yet consumed by the focused
+	// editor.
+	pasted *string
+	// pastedBy and pastedAt record the last consumption of a paste event, so
+	// that the paste command run by the same key stroke doesn't read the
+	// clipboard again.
+	pastedBy *Editor
+	pastedAt time.Time
+	// reads are the pending asynchronous clipboard reads of the editors.
+	reads map[*Editor]*clipboardRead
+	// composing is set during a composition of an input method, and
+	// composition is the text it composes.
+	composing   bool
+	composition string
+}
+
+// clipboardRead is an asynchronous read of the clipboard.
+type clipboardRead struct {
+	done   bool
+	failed bool
+	text   string
+}
+
+// installBrowserListeners installs the document listeners complementing the
+// key and clipboard handling of Gio. They listen in the capture phase to see
+// the events before the text area of the Gio window does:
+//
+//   - Key events consumed by an input method are stopped, so that e.g. Enter
+//     committing a composition doesn't also insert a line break.
+//   - The browser default actions of the key strokes bound in the keymap of
+//     the focused editor are prevented, e.g., Ctrl+S saving the page.
+//   - Paste events deliver the clipboard text to the focused editor, which
+//     works in browsers and contexts without the asynchronous clipboard API,
+//     or where reading it needs a permission.
+//   - Composition events track the text composed by an input method, which
+//     the focused editor underlines.
+func installBrowserListeners() {
+	doc := js.Global().Get("document")
+	capture := true
+
+	for _, name := range []string{"compositionstart", "compositionupdate", "compositionend"} {
+		doc.Call("addEventListener", name, js.FuncOf(func(this js.Value, args []js.Value) any {
+			browser.mu.Lock()
+			defer browser.mu.Unlock()
+			browser.composing = name != "compositionend"
+			browser.composition = ""
+			if data := args[0].Get("data"); browser.composing && data.Type() == js.TypeString {
+				browser.composition = data.String()
+			}
+			return nil
+		}), capture)
+	}
+
+	doc.Call("addEventListener", "keydown", js.FuncOf(func(this js.Value, args []js.Value) any {
+		ev := domKeyEventOf(args[0])
+		if ev.hidesFromEditor() {
+			args[0].Call("stopPropagation")
+			return nil
+		}
+
+		browser.mu.Lock()
+		defer browser.mu.Unlock()
+		if browser.focused != nil && browser.keymap.preventsDefault(ev, browser.pendingKeys) {
+			args[0].Call("preventDefault")
+		}
+		return nil
+	}), capture)
+
+	doc.Call("addEventListener", "paste", js.FuncOf(func(this js.Value, args []js.Value) any {
+		browser.mu.Lock()
+		defer browser.mu.Unlock()
+		if browser.focused == nil {
+			return nil
+		}
+		// the text is pasted by the editor, not by the text area.
+		args[0].Call("preventDefault")
+		data := args[0].Get("clipboardData")
+		if data.IsUndefined() || data.IsNull() {
+			return nil
+		}
+		text := data.Call("getData", "text/plain").String()
+		browser.pasted = &text
+		return nil
+	}), capture)
+}
+
+// domKeyEventOf reads a DOM KeyboardEvent.
+func domKeyEventOf(v js.Value) domKeyEvent {
+	return domKeyEvent{
+		Key:         v.Get("key").String(),
+		KeyCode:     v.Get("keyCode").Int(),
+		IsComposing: v.Get("isComposing").Truthy(),
+		Ctrl:        v.Get("ctrlKey").Truthy(),
+		Shift:       v.Get("shiftKey").Truthy(),
+		Alt:         v.Get("altKey").Truthy(),
+		Meta:        v.Get("metaKey").Truthy(),
+	}
+}
+
+// processBrowserEvents publishes the focus and the keymap of the editor to
+// the DOM listeners, and pastes the clipboard text received by the paste
+// events or by the asynchronous clipboard reads.
+func (e *Editor) processBrowserEvents(gtx layout.Context) EditorEvent {
+	browser.install.Do(installBrowserListeners)
+
+	browser.mu.Lock()
+	if gtx.Focused(e) {
+		browser.focused = e
+		browser.keymap = e.Keymap()
+		browser.pendingKeys = slices.Clone(e.pendingKeys)
+	} else if browser.focused == e {
+		browser.focused = nil
+	}
+
+	composing, composition := browser.focused == e && browser.composing, browser.composition
+	var pasted *string
+	if browser.focused == e && browser.pasted != nil {
+		pasted, browser.pasted = browser.pasted, nil
+		browser.pastedBy, browser.pastedAt = e, gtx.Now
+	}
+	read := browser.reads[e]
+	if read != nil && read.done {
+		delete(browser.reads, e)
+	}
+	browser.mu.Unlock()
+
+	e.syncComposition(composing, composition)
+	switch {
+	case pasted != nil:
+		return e.onPasteText(*pasted)
+	case read == nil:
+		return nil
+	case !read.done:
+		// the promise resolves outside of a frame, so poll for it.
+		gtx.Execute(op.InvalidateCmd{})
+		return nil
+	case read.failed:
+		e.clipboardRead = nil
+		return nil
+	default:
+		return e.onPasteText(read.text)
+	}
+}
+
+// readHostClipboard reads the text of the clipboard with the asynchronous
+// clipboard API of the browser. The text is pasted in a later frame, unless a
+// paste event of the same key stroke has already delivered it.
+func (e *Editor) readHostClipboard(gtx layout.Context) {
+	browser.mu.Lock()
+	defer browser.mu.Unlock()
+	if browser.pastedBy == e && browser.pastedAt.Equal(gtx.Now) {
+		return
+	}
+
+	api := js.Global().Get("navigator").Get("clipboard")
+	if api.IsUndefined() || api.Get("readText").IsUndefined() {
+		// only the paste events can read the clipboard.
+		e.clipboardRead = nil
+		return
+	}
+
+	read := &clipboardRead{}
+	if browser.reads == nil {
+		browser.reads = make(map[*Editor]*clipboardRead)
+	}
+	browser.reads[e] = read
+
+	var resolve, reject js.Func
+	settle := func(text string, failed bool) {
+		browser.mu.Lock()
+		read.text, read.failed, read.done = text, failed, true
+		browser.mu.Unlock()
+		resolve.Release()
+		reject.Release()
+	}
+	resolve = js.FuncOf(func(this js.Value, args []js.Value) any {
+		settle(args[0].String(), false)
+		return nil
+	})
+	reject = js.FuncOf(func(this js.Value, args []js.Value) any {
+		settle("", true)
+		return nil
+	})
+	api.Call("readText").Call("then", resolve, reject)
+	gtx.Execute(op.InvalidateCmd{})
+}
+
+// writeHostClipboard writes text to the clipboard. Gio writes it with the
+// asynchronous clipboard API, which is missing in insecure contexts, e.g.,
+// pages served over plain HTTP. The text is then copied by a copy command
+// of the document, which is allowed shortly after the key stroke.
+func writeHostClipboard(gtx layout.Context, text string) {
+	gtx.Execute(clipboard.WriteCmd{Type: "application/text", Data: io.NopCloser(strings.NewReader(text))})
+
+	if !js.Global().Get("navigator").Get("clipboard").IsUndefined() {
+		return
+	}
+	doc := js.Global().Get("document")
+	onCopy := js.FuncOf(func(this js.Value, args []js.Value) any {
+		args[0].Call("preventDefault")
+		args[0].Get("clipboardData").Call("setData", "text/plain", text)
+		return nil
+	})
+	defer onCopy.Release()
+	doc.Call("addEventListener", "copy", onCopy, true)
+	defer doc.Call("removeEventListener", "copy", onCopy, true)
+	doc.Call("execCommand", "copy")
+}
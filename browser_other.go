@@ -0,0 +1,28 @@
+//go:build !(js && wasm)
+
+package gvcode
+
+import (
+	"io"
+	"strings"
+
+	"gioui.org/io/clipboard"
+	"gioui.org/layout"
+)
+
+// processBrowserEvents handles the input received from the browser outside of
+// Gio. It is only needed in web builds.
+func (e *Editor) processBrowserEvents(gtx layout.Context) EditorEvent {
+	return nil
+}
+
+// readHostClipboard requests the text of the host clipboard, which is
+// delivered to the editor as a transfer.DataEvent.
+func (e *Editor) readHostClipboard(gtx layout.Context) {
+	gtx.Execute(clipboard.ReadCmd{Tag: e})
+}
+
+// writeHostClipboard writes text to the host clipboard.
+func writeHostClipboard(gtx layout.Context, text string) {
+	gtx.Execute(clipboard.WriteCmd{Type: "application/text", Data: io.NopCloser(strings.NewReader(text))})
+}
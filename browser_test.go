@@ -0,0 +1,90 @@
+package gvcode
+
+import (
+	"testing"
+
+	"gioui.org/io/key"
+)
+
+func TestDOMKeyStroke(t *testing.T) {
+	cases := []struct {
+		ev   domKeyEvent
+		want KeyStroke
+		ok   bool
+	}{
+		{domKeyEvent{Key: "s", Ctrl: true}, KeyStroke{Name: "S", Modifiers: key.ModCtrl}, true},
+		{domKeyEvent{Key: "ArrowLeft", Alt: true, Shift: true}, KeyStroke{Name: key.NameLeftArrow, Modifiers: key.ModAlt | key.ModShift}, true},
+		{domKeyEvent{Key: " ", Ctrl: true}, KeyStroke{Name: key.NameSpace, Modifiers: key.ModCtrl}, true},
+		{domKeyEvent{Key: "F5"}, KeyStroke{Name: key.NameF5}, true},
+		{domKeyEvent{Key: "Control", Ctrl: true}, KeyStroke{}, false},
+		{domKeyEvent{Key: "Dead"}, KeyStroke{}, false},
+	}
+
+	for _, c := range cases {
+		got, ok := c.ev.keyStroke()
+		if got != c.want || ok != c.ok {
+			t.Errorf("%+v: got %v, %v, want %v, %v", c.ev, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestDOMKeyHidesFromEditor(t *testing.T) {
+	cases := []struct {
+		ev   domKeyEvent
+		want bool
+	}{
+		{domKeyEvent{Key: "Enter"}, false},
+		// Chrome, committing a composition.
+		{domKeyEvent{Key: "Enter", KeyCode: imeProcessKeyCode, IsComposing: true}, true},
+		// Safari, committing a composition after compositionend.
+		{domKeyEvent{Key: "Enter", KeyCode: imeProcessKeyCode}, true},
+		{domKeyEvent{Key: "ArrowDown", IsComposing: true}, true},
+		// the text of the composition is delivered by input events.
+		{domKeyEvent{Key: "a", IsComposing: true}, false},
+	}
+
+	for _, c := range cases {
+		if got := c.ev.hidesFromEditor(); got != c.want {
+			t.Errorf("%+v: got %v, want %v", c.ev, got, c.want)
+		}
+	}
+}
+
+func TestKeymapPreventsDefault(t *testing.T) {
+	km := NewKeymap()
+	for keys, cmd := range map[string]Command{
+		"Ctrl+D":        DuplicateLine,
+		"Ctrl+V":        Paste,
+		"Ctrl+K Ctrl+C": ToggleLineComment,
+		"Tab":           Indent,
+		"Shift+Tab":     Unindent,
+	} {
+		if err := km.Bind(keys, cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chord := []KeyStroke{{Name: "K", Modifiers: key.ModCtrl}}
+
+	cases := []struct {
+		name    string
+		ev      domKeyEvent
+		pending []KeyStroke
+		want    bool
+	}{
+		{"bound", domKeyEvent{Key: "d", Ctrl: true}, nil, true},
+		{"unbound", domKeyEvent{Key: "s", Ctrl: true}, nil, false},
+		{"paste", domKeyEvent{Key: "v", Ctrl: true}, nil, false},
+		{"chord prefix", domKeyEvent{Key: "k", Ctrl: true}, nil, true},
+		{"chord end", domKeyEvent{Key: "c", Ctrl: true}, chord, true},
+		{"chord end without prefix", domKeyEvent{Key: "c", Ctrl: true}, nil, false},
+		{"named key", domKeyEvent{Key: "Tab", Shift: true}, nil, true},
+		{"typing", domKeyEvent{Key: "D", Shift: true}, nil, false},
+		{"composing", domKeyEvent{Key: "Tab", IsComposing: true}, nil, false},
+	}
+
+	for _, c := range cases {
+		if got := km.preventsDefault(c.ev, c.pending); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package gvcode
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"gioui.org/io/key"
+	"github.com/oligo/gvcode/internal/buffer"
+	"github.com/oligo/gvcode/textview"
+)
+
+// indentationSampleSize is the size of the head of a file read to guess its
+// indentation.
+const indentationSampleSize = 64 << 10
+
+// Buffer is a named document managed by a BufferSet. Besides the text, it
+// keeps the view states of the document, like the caret, the scroll offset,
+// the folds, syntax tokens and decorations, as well as its language and pair
+// profile, while other buffers are shown in the editor.
+type Buffer struct {
+	name string
+	// language is the config of the language of the buffer.
+	language LanguageConfig
+
+	state    textview.ViewState
+	softTab  bool
+	tabWidth int
+	// visualTabWidth is the width tab characters are rendered with.
+	visualTabWidth int
+	diagnostics    []diagnosticItem
+	// pairs are the pairs auto-completed in the buffer.
+	pairs *textview.BracketsQuotes
+}
+
+// Name returns the name of the buffer.
+func (b *Buffer) Name() string {
+	return b.name
+}
+
+// Language returns the ID of the language of the buffer, or an empty string
+// if no language is set.
+func (b *Buffer) Language() string {
+	return b.language.ID
+}
+
+// BufferSet manages multiple named documents shown in a single editor, one
+// at a time. Switching to a buffer swaps the text source of the editor and
+// restores the view states of the buffer.
+type BufferSet struct {
+	editor  *Editor
+	buffers []*Buffer
+	active  *Buffer
+}
+
+// NewBufferSet creates a BufferSet for editor. The document currently shown in
+// the editor is adopted as the active buffer with the given name.
+func NewBufferSet(editor *Editor, name string) *BufferSet {
+	editor.initBuffer()
+	buf := &Buffer{name: name, language: editor.language}
+	return &BufferSet{
+		editor:  editor,
+		buffers: []*Buffer{buf},
+		active:  buf,
+	}
+}
+
+// Open creates a new buffer with the content, without switching to it. It
+// is an error to open a buffer with a name that is already in use. The
+// buffer has no language until one is set by SetLanguage.
+func (bs *BufferSet) Open(name string, content string) (*Buffer, error) {
+	if bs.Get(name) != nil {
+		return nil, fmt.Errorf("buffer %q already exists", name)
+	}
+
+	if bs.editor.isBinaryText(content) {
+		return nil, ErrBinaryContent
+	}
+	content, rejected := bs.editor.checkUTF8("setText", content)
+	if rejected != nil {
+		return nil, fmt.Errorf("invalid UTF-8 at byte %d", rejected.Offset)
+	}
+
+	src := buffer.NewTextSource()
+	src.SetText([]byte(content))
+	indent, _, size := GuessIndentation(content)
+
+	buf := &Buffer{
+		name:     name,
+		state:    bs.editor.text.NewViewState(src),
+		softTab:  indent == Spaces,
+		tabWidth: size,
+		pairs:    bs.editor.text.BracketsQuotes.Clone(),
+	}
+	bs.buffers = append(bs.buffers, buf)
+	return buf, nil
+}
+
+// OpenFile creates a new buffer with the content of the file r of size bytes,
+// without switching to it. Unlike Open, the file is read on demand, so that
+// opening a large file, e.g., a log file of hundreds of megabytes, does not
+// load it in memory. The file must not be changed while the buffer is open.
+// The binary content detection and the invalid UTF-8 policy do not apply:
+// invalid bytes are read as U+FFFD.
+func (bs *BufferSet) OpenFile(name string, r io.ReaderAt, size int64) (*Buffer, error) {
+	if bs.Get(name) != nil {
+		return nil, fmt.Errorf("buffer %q already exists", name)
+	}
+
+	src, err := buffer.NewFileTextSource(r, size)
+	if err != nil {
+		return nil, err
+	}
+	// guess the indentation from the head of the file.
+	head := make([]byte, min(size, indentationSampleSize))
+	n, _ := r.ReadAt(head, 0)
+	indent, _, tabSize := GuessIndentation(string(head[:n]))
+
+	buf := &Buffer{
+		name:     name,
+		state:    bs.editor.text.NewViewState(src),
+		softTab:  indent == Spaces,
+		tabWidth: tabSize,
+		pairs:    bs.editor.text.BracketsQuotes.Clone(),
+	}
+	bs.buffers = append(bs.buffers, buf)
+	return buf, nil
+}
+
+// Get returns the buffer with the name, or nil if it does not exist.
+func (bs *BufferSet) Get(name string) *Buffer {
+	idx := slices.IndexFunc(bs.buffers, func(b *Buffer) bool { return b.name == name })
+	if idx < 0 {
+		return nil
+	}
+	return bs.buffers[idx]
+}
+
+// Active returns the buffer shown in the editor.
+func (bs *BufferSet) Active() *Buffer {
+	return bs.active
+}
+
+// Names returns the names of the buffers in the order they were opened.
+func (bs *BufferSet) Names() []string {
+	names := make([]string, 0, len(bs.buffers))
+	for _, b := range bs.buffers {
+		names = append(names, b.name)
+	}
+	return names
+}
+
+// Switch shows the buffer with the name in the editor.
+func (bs *BufferSet) Switch(name string) error {
+	buf := bs.Get(name)
+	if buf == nil {
+		return fmt.Errorf("buffer %q not found", name)
+	}
+	if buf == bs.active {
+		return nil
+	}
+
+	bs.save(bs.active)
+	bs.restore(buf)
+	bs.active = buf
+	return nil
+}
+
+// SetLanguage applies the registered config of the language id to the buffer
+// with the name. The language is applied to the editor whenever the buffer is
+// shown, as by Editor.SetLanguage.
+func (bs *BufferSet) SetLanguage(name, id string) error {
+	buf := bs.Get(name)
+	if buf == nil {
+		return fmt.Errorf("buffer %q not found", name)
+	}
+	if buf == bs.active {
+		return bs.editor.SetLanguage(id)
+	}
+
+	config, ok := LookupLanguage(id)
+	if !ok {
+		return fmt.Errorf("language %q not registered", id)
+	}
+	buf.language = config
+	bs.setPairs(buf, config.Pairs)
+	if config.Indent.TabWidth > 0 {
+		buf.tabWidth = config.Indent.TabWidth
+		buf.softTab = config.Indent.SoftTab
+	}
+	buf.visualTabWidth = config.Indent.VisualTabWidth
+	return nil
+}
+
+// SetPairProfile configures the pairs auto-completed in the buffer with the
+// name, which are applied to the editor whenever the buffer is shown.
+func (bs *BufferSet) SetPairProfile(name string, profile PairProfile) error {
+	buf := bs.Get(name)
+	if buf == nil {
+		return fmt.Errorf("buffer %q not found", name)
+	}
+
+	if buf == bs.active {
+		bs.editor.SetPairProfile(profile)
+		return nil
+	}
+	bs.setPairs(buf, profile)
+	return nil
+}
+
+// setPairs configures the pairs of a buffer which is not shown.
+func (bs *BufferSet) setPairs(buf *Buffer, profile PairProfile) {
+	if buf.pairs == nil {
+		buf.pairs = bs.editor.text.BracketsQuotes.Clone()
+	}
+	buf.pairs.SetBrackets(profile.Brackets)
+	buf.pairs.SetQuotes(profile.Quotes)
+	buf.pairs.SetPairs(profile.Pairs)
+}
+
+// Rename changes the name of a buffer.
+func (bs *BufferSet) Rename(name, newName string) error {
+	buf := bs.Get(name)
+	if buf == nil {
+		return fmt.Errorf("buffer %q not found", name)
+	}
+	if name == newName {
+		return nil
+	}
+	if bs.Get(newName) != nil {
+		return fmt.Errorf("buffer %q already exists", newName)
+	}
+
+	buf.name = newName
+	return nil
+}
+
+// Close removes the buffer with the name. If it is the active buffer, the
+// editor switches to the buffer opened next to it. The last buffer can not
+// be closed.
+func (bs *BufferSet) Close(name string) error {
+	idx := slices.IndexFunc(bs.buffers, func(b *Buffer) bool { return b.name == name })
+	if idx < 0 {
+		return fmt.Errorf("buffer %q not found", name)
+	}
+	if len(bs.buffers) == 1 {
+		return errors.New("can not close the last buffer")
+	}
+
+	buf := bs.buffers[idx]
+	bs.buffers = slices.Delete(bs.buffers, idx, idx+1)
+	if buf == bs.active {
+		next := bs.buffers[min(idx, len(bs.buffers)-1)]
+		bs.restore(next)
+		bs.active = next
+	}
+	return nil
+}
+
+// save stores the states of the editor into buf.
+func (bs *BufferSet) save(buf *Buffer) {
+	e := bs.editor
+	buf.state = e.text.ViewState()
+	buf.language = e.language
+	buf.softTab = e.text.SoftTab
+	buf.tabWidth = e.text.TabWidth
+	buf.visualTabWidth = e.text.VisualTabWidth
+	buf.diagnostics = slices.Clone(e.diagnostics.items)
+	buf.pairs = e.text.BracketsQuotes
+}
+
+// restore shows buf in the editor.
+func (bs *BufferSet) restore(buf *Buffer) {
+	e := bs.editor
+	e.resetTransientStates()
+	e.unicodeWarnings.reset(e.buffer)
+
+	// Tab widths are restored first, as the document is laid out by
+	// SetViewState. The folds restored by it are kept by the language if
+	// the fold markers are unchanged.
+	e.text.SoftTab = buf.softTab
+	if buf.tabWidth > 0 {
+		e.text.TabWidth = buf.tabWidth
+	}
+	e.text.VisualTabWidth = buf.visualTabWidth
+	e.text.SetViewState(buf.state)
+	e.buffer = e.text.Source()
+	e.edits.bind(e.buffer)
+	if buf.pairs != nil {
+		e.text.BracketsQuotes = buf.pairs
+	}
+	e.useLanguage(buf.language)
+	e.diagnostics.items = slices.Clone(buf.diagnostics)
+	e.diagnostics.lensAreas = e.diagnostics.lensAreas[:0]
+	e.swatches.reset()
+	e.scrollCaret = false
+}
+
+// resetTransientStates quits the states bound to the current document
+// before switching to another one.
+func (e *Editor) resetTransientStates() {
+	if e.mode == ModeSnippet || e.mode == ModeColumnEdit {
+		e.setMode(ModeNormal)
+	}
+	e.cancelCompletor()
+	e.ClearSearch()
+	e.clearAutoInsertions()
+	e.ime.start = 0
+	e.ime.end = 0
+	e.ime.compose = key.Range{}
+	e.ime.composing = false
+	e.lastInput = nil
+	e.phantoms = nil
+}
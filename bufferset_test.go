@@ -0,0 +1,200 @@
+package gvcode
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestBufferSetSwitchLayout(t *testing.T) {
+	e := newGoEditor(t, "package main\n")
+	bs := NewBufferSet(e, "short")
+	if _, err := bs.Open("long", strings.Repeat("line\n", 200)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bs.Switch("long"); err != nil {
+		t.Fatal(err)
+	}
+	// The new document is laid out by the switch, so scrolling before the
+	// next frame is bounded by its size rather than the old one.
+	e.text.ScrollRel(0, 1000)
+	if got := e.text.ScrollOff().Y; got != 1000 {
+		t.Fatalf("scroll offset after switch: got %d, want 1000", got)
+	}
+
+	if err := bs.Switch("short"); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.text.ScrollOff().Y; got != 0 {
+		t.Fatalf("scroll offset of the short buffer: got %d, want 0", got)
+	}
+
+	// Switching back reuses the saved layout and restores the offset.
+	if err := bs.Switch("long"); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.text.ScrollOff().Y; got != 1000 {
+		t.Fatalf("restored scroll offset: got %d, want 1000", got)
+	}
+	if got := e.text.Paragraphs(); got != 201 {
+		t.Fatalf("paragraphs: got %d, want 201", got)
+	}
+}
+
+func TestBufferSetOpenFile(t *testing.T) {
+	e := newGoEditor(t, "package main\n")
+	bs := NewBufferSet(e, "main.go")
+	content := strings.Repeat("    log line\n", 100)
+	if _, err := bs.OpenFile("app.log", strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bs.OpenFile("app.log", strings.NewReader(content), int64(len(content))); err == nil {
+		t.Fatal("expected an error opening a buffer with a duplicate name")
+	}
+
+	if err := bs.Switch("app.log"); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Text(); got != content {
+		t.Fatalf("got %d bytes of text, want the %d bytes of the file", len(got), len(content))
+	}
+	if !e.text.SoftTab || e.text.TabWidth != 4 {
+		t.Errorf("got soft tab %v with width %d, want the indentation of the file", e.text.SoftTab, e.text.TabWidth)
+	}
+
+	e.SetCaret(0, 0)
+	e.Insert("// ")
+	if got := e.Text(); got != "// "+content {
+		t.Errorf("got %q after editing the file buffer", got[:20])
+	}
+}
+
+func TestBufferSetFolds(t *testing.T) {
+	e := newTestEditor(t, foldAnimationText, WithCodeFolding())
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	frame := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 300))}
+		e.Layout(gtx, shaper)
+	}
+	// the folds are detected by the gutter in the first frame.
+	frame()
+	frame()
+
+	bs := NewBufferSet(e, "a.go")
+	if _, err := bs.Open("b.go", foldAnimationText); err != nil {
+		t.Fatal(err)
+	}
+	if !e.ToggleFold() {
+		t.Fatal("no fold at the caret")
+	}
+	frame()
+	folded := lineY(e, 5)
+
+	fm := e.text.FoldManager()
+	if err := bs.Switch("b.go"); err != nil {
+		t.Fatal(err)
+	}
+	frame()
+	frame()
+	if fm.HasCollapsed() {
+		t.Error("the fold of a.go is collapsed in b.go")
+	}
+	if len(fm.GetFoldRanges()) == 0 {
+		t.Error("got no folds detected in b.go")
+	}
+
+	if err := bs.Switch("a.go"); err != nil {
+		t.Fatal(err)
+	}
+	if fold := fm.GetFoldAtLine(0); fold == nil || !fold.Collapsed {
+		t.Fatalf("got fold %+v at line 0 of a.go, want it collapsed", fold)
+	}
+	// the restored folds are laid out by the switch, and kept by the next
+	// frame.
+	if got := lineY(e, 5); got != folded {
+		t.Errorf("got line 5 at %d after the switch, want it at %d", got, folded)
+	}
+	frame()
+	if fold := fm.GetFoldAtLine(0); fold == nil || !fold.Collapsed || lineY(e, 5) != folded {
+		t.Errorf("got fold %+v with line 5 at %d, want the fold collapsed", fold, lineY(e, 5))
+	}
+}
+
+func TestBufferSetLanguage(t *testing.T) {
+	RegisterLanguage(LanguageConfig{
+		ID:       "bufferset-test",
+		Comments: CommentTokens{Line: "#"},
+		Indent:   IndentRules{TabWidth: 2, SoftTab: true},
+	})
+
+	e := newGoEditor(t, "package main\n")
+	bs := NewBufferSet(e, "main.go")
+	if _, err := bs.Open("script", "echo\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.SetLanguage("script", "bufferset-test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.SetLanguage("script", "missing"); err == nil {
+		t.Error("expected an error setting a language which is not registered")
+	}
+	if got := e.Language().ID; got != "go" {
+		t.Fatalf("got language %q in the active buffer, want go", got)
+	}
+
+	if err := bs.Switch("script"); err != nil {
+		t.Fatal(err)
+	}
+	if got := bs.Active().Language(); got != "bufferset-test" || e.Language().ID != got || e.Metadata().Language != got {
+		t.Errorf("got language %q of the buffer, %q in the editor, want bufferset-test", got, e.Language().ID)
+	}
+	if !e.text.SoftTab || e.text.TabWidth != 2 {
+		t.Errorf("got soft tab %v with width %d, want the indentation of the language", e.text.SoftTab, e.text.TabWidth)
+	}
+	e.ToggleLineComment()
+	if got := e.Text(); got != "# echo\n" {
+		t.Errorf("got %q after commenting, want the comment token of the language", got)
+	}
+
+	if err := bs.Switch("main.go"); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Language().ID; got != "go" || e.text.SoftTab {
+		t.Errorf("got language %q with soft tab %v, want go restored", got, e.text.SoftTab)
+	}
+	e.ToggleLineComment()
+	if got := e.Text(); got != "// package main\n" {
+		t.Errorf("got %q after commenting, want the comment token of go", got)
+	}
+}
+
+func TestBufferSetEditedWhileHidden(t *testing.T) {
+	e := newGoEditor(t, "package main\n")
+	bs := NewBufferSet(e, "main.go")
+	if _, err := bs.Open("notes", "a\nb\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.Switch("notes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.Switch("main.go"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the layout saved with the buffer is stale once its source is edited
+	// while it is not shown.
+	src := bs.Get("notes").state.Source()
+	src.Replace(0, 0, "x\ny\nz\n")
+	if err := bs.Switch("notes"); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.text.Paragraphs(); got != 6 {
+		t.Errorf("got %d paragraphs after the switch, want the 6 lines of the edited text", got)
+	}
+}
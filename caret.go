@@ -0,0 +1,174 @@
+package gvcode
+
+import (
+	"image"
+	"image/color"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/painter"
+)
+
+// CaretShape is the shape of the caret.
+type CaretShape uint8
+
+const (
+	// CaretBar is a vertical bar between the characters. It is the default.
+	CaretBar CaretShape = iota
+	// CaretBlock covers the character after the caret, which is drawn with
+	// the background color over it.
+	CaretBlock
+	// CaretUnderline is a line under the character after the caret.
+	CaretUnderline
+)
+
+const (
+	defaultCaretWidth       = unit.Dp(2)
+	defaultCaretBlinkPeriod = time.Second
+)
+
+// CaretStyle configures the shape and the blinking of the caret. The zero
+// value is a blinking bar.
+type CaretStyle struct {
+	Shape CaretShape
+	// Width is the width of the bar, or the thickness of the underline. It
+	// defaults to 2dp, and is not used by the block caret.
+	Width unit.Dp
+	// BlinkPeriod is the duration of a blink, the caret being shown half of
+	// the time. It defaults to one second.
+	BlinkPeriod time.Duration
+	// NoBlink keeps the caret shown.
+	NoBlink bool
+}
+
+func (s CaretStyle) width() unit.Dp {
+	if s.Width <= 0 {
+		return defaultCaretWidth
+	}
+	return s.Width
+}
+
+func (s CaretStyle) blinkPeriod() time.Duration {
+	if s.BlinkPeriod <= 0 {
+		return defaultCaretBlinkPeriod
+	}
+	return s.BlinkPeriod
+}
+
+// SetCaretStyle changes the shape and the blinking of the caret, e.g., to show
+// a block caret in the normal mode of a modal editor.
+func (e *Editor) SetCaretStyle(style CaretStyle) {
+	e.caretStyle = style
+}
+
+// CaretStyle returns the style of the caret.
+func (e *Editor) CaretStyle() CaretStyle {
+	return e.caretStyle
+}
+
+// caretVisible reports whether the caret is shown in the blink cycle at now,
+// and when it changes next. The caret stops blinking maxBlinkDuration after
+// the last edit or move.
+func (e *Editor) caretVisible(now time.Time) (visible bool, next time.Time) {
+	dt := now.Sub(e.blinkStart)
+	if e.caretStyle.NoBlink || dt >= maxBlinkDuration {
+		return true, time.Time{}
+	}
+	period := e.caretStyle.blinkPeriod()
+	return dt%period < period/2, now.Add(period/2 - dt%(period/2))
+}
+
+// caretCell returns the bounds of the grapheme cluster after the caret, in
+// the coordinates of the text area, and its text. At the end of a line, the
+// cell has the width of a space.
+func (e *Editor) caretCell(gtx layout.Context, shaper *text.Shaper) (image.Rectangle, string) {
+	pos, ascent, descent := e.text.CaretInfo()
+	caret, _ := e.text.Selection()
+	width := 0
+	cluster := ""
+	if info, ok := e.InspectCharacterAt(caret); ok && info.Text != "\n" && info.Text != "\r\n" {
+		cluster = info.Text
+		next := e.text.RuneCoords(info.Offset + len([]rune(info.Text)))
+		if int(next.Y) == pos.Y {
+			width = abs(int(next.X) - pos.X)
+			if int(next.X) < pos.X {
+				// the cell of a right-to-left character is on the left.
+				pos.X = int(next.X)
+			}
+		}
+	}
+	if width == 0 {
+		width = e.spaceAdvance(shaper)
+	}
+	if width == 0 {
+		width = gtx.Dp(defaultCaretWidth)
+	}
+	return image.Rect(pos.X, pos.Y-ascent, pos.X+width, pos.Y+descent), cluster
+}
+
+// spaceAdvance returns the advance of a space in the font of the editor.
+func (e *Editor) spaceAdvance(shaper *text.Shaper) int {
+	if shaper == nil {
+		return 0
+	}
+	params := e.text.Params()
+	params.MinWidth = 0
+	params.MaxWidth = 1 << 24
+	params.MaxLines = 1
+	shaper.LayoutString(params, " ")
+	advance := 0
+	for {
+		g, ok := shaper.NextGlyph()
+		if !ok {
+			break
+		}
+		advance += g.Advance.Ceil()
+	}
+	return advance
+}
+
+// paintShapedCaret paints the block and underline carets over the character
+// after the caret. The character under a block caret is drawn again with the
+// background color, so it stays readable.
+func (e *Editor) paintShapedCaret(gtx layout.Context, shaper *text.Shaper, material gvcolor.Color) {
+	cell, cluster := e.caretCell(gtx, shaper)
+	if e.caretStyle.Shape == CaretUnderline {
+		cell.Min.Y = cell.Max.Y - max(gtx.Dp(e.caretStyle.width()), 1)
+	}
+	view := image.Rectangle{Max: gtx.Constraints.Max}
+	if cell = view.Intersect(cell); cell.Empty() {
+		return
+	}
+	paint.FillShape(gtx.Ops, material.NRGBA(), clip.Rect(cell).Op())
+	if e.caretStyle.Shape != CaretBlock || cluster == "" || cluster == "\t" || shaper == nil {
+		return
+	}
+
+	bg := color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	if e.colorPalette != nil && e.colorPalette.Background.IsSet() {
+		bg = e.colorPalette.Background.NRGBA()
+		bg.A = 0xFF
+	}
+	params := e.text.Params()
+	params.MinWidth = 0
+	params.MaxWidth = 1 << 24
+	params.MaxLines = 1
+	pos, _, _ := e.text.CaretInfo()
+	defer clip.Rect(cell).Push(gtx.Ops).Pop()
+	glyphs, _ := shapeLine(shaper, params, cluster)
+	if len(glyphs) == 0 {
+		return
+	}
+	defer op.Affine(f32.Affine2D{}.Offset(f32.Point{
+		X: float32(cell.Min.X + glyphs[0].X.Floor()), Y: float32(pos.Y),
+	})).Push(gtx.Ops).Pop()
+	glyphColor := gvcolor.MakeColor(bg)
+	painter.DrawGlyphs(gtx, shaper, glyphs, glyphColor.Op(gtx.Ops))
+}
@@ -0,0 +1,60 @@
+package gvcode
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestCaretBlink(t *testing.T) {
+	e := &Editor{}
+	start := time.Now()
+	e.blinkStart = start
+
+	visible, next := e.caretVisible(start.Add(100 * time.Millisecond))
+	if !visible || !next.Equal(start.Add(500*time.Millisecond)) {
+		t.Fatalf("got %v, next blink at %v, want visible until 500ms", visible, next.Sub(start))
+	}
+	if visible, _ := e.caretVisible(start.Add(600 * time.Millisecond)); visible {
+		t.Fatal("expected the caret to be hidden in the second half of the period")
+	}
+
+	e.SetCaretStyle(CaretStyle{BlinkPeriod: 200 * time.Millisecond})
+	if visible, _ := e.caretVisible(start.Add(250 * time.Millisecond)); !visible {
+		t.Fatal("expected the caret to be shown at the start of the third half period")
+	}
+
+	e.SetCaretStyle(CaretStyle{NoBlink: true})
+	if visible, next := e.caretVisible(start.Add(600 * time.Millisecond)); !visible || !next.IsZero() {
+		t.Fatal("expected the caret to be shown without blinking")
+	}
+	if visible, _ := (&Editor{blinkStart: start}).caretVisible(start.Add(maxBlinkDuration)); !visible {
+		t.Fatal("expected the caret to stop blinking")
+	}
+}
+
+func TestCaretCell(t *testing.T) {
+	e := newTestEditor(t, "ab\tc\n")
+	e.SetCaret(1, 1)
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	cell, cluster := e.caretCell(gtx, shaper)
+	if cluster != "b" || cell.Dx() <= 0 {
+		t.Fatalf("got cell %v of %q, want the cell of b", cell, cluster)
+	}
+	e.SetCaret(2, 2)
+	tab, _ := e.caretCell(gtx, shaper)
+	if tab.Dx() <= cell.Dx() {
+		t.Fatalf("got tab cell %v, want wider than %v", tab, cell)
+	}
+	// the end of the line has the width of a space.
+	e.SetCaret(4, 4)
+	if end, cluster := e.caretCell(gtx, shaper); cluster != "" || end.Dx() != e.spaceAdvance(shaper) {
+		t.Fatalf("got end cell %v of %q, want the width of a space", end, cluster)
+	}
+}
@@ -0,0 +1,252 @@
+package gvcode
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/oligo/gvcode/internal/buffer"
+)
+
+// TextChange describes an edit of the editor text, in rune offsets. The text
+// between Start and OldEnd before the edit is replaced by the text between
+// Start and NewEnd.
+type TextChange struct {
+	Start  int
+	OldEnd int
+	NewEnd int
+}
+
+// TextVersion returns the version of the editor text. The version is
+// increased by every edit, including the ones made by undo and redo.
+func (e *Editor) TextVersion() int {
+	e.initBuffer()
+	return e.buffer.Version()
+}
+
+// ChangesSince returns the edits made to the text after version, in the order
+// they were made. It can be used to update states derived from the text
+// incrementally. It returns false if the changes are too old to be tracked,
+// and the states should be rebuilt from the full text.
+func (e *Editor) ChangesSince(version int) ([]TextChange, bool) {
+	e.initBuffer()
+	edits, ok := e.buffer.EditsSince(version)
+	if !ok {
+		return nil, false
+	}
+
+	changes := make([]TextChange, 0, len(edits))
+	for _, edit := range edits {
+		changes = append(changes, TextChange{Start: edit.Start, OldEnd: edit.OldEnd, NewEnd: edit.NewEnd})
+	}
+	return changes, true
+}
+
+// EditDelta describes an edit reported to the listeners registered by
+// OnEdit, with both the rune and byte offsets of the edit, and the inserted
+// text.
+type EditDelta struct {
+	TextChange
+	// Version is the text version after the edit.
+	Version int
+	// StartByte and OldEndByte are the byte offsets of the replaced text
+	// before the edit, and NewEndByte is the end byte offset of the inserted
+	// text after the edit.
+	StartByte  int
+	OldEndByte int
+	NewEndByte int
+	// Text is the inserted text. It is empty for deletions.
+	Text string
+}
+
+// OnEdit registers fn to be called after each edit of the text, including
+// the ones made by undo, redo and SetText, so that syntax highlighters, LSP
+// clients or collaborative layers can update their states incrementally. A
+// replacement may be reported as a deletion followed by an insertion. The
+// listener follows the document shown by the editor when it is switched by a
+// BufferSet. OnEdit returns a function to unregister fn.
+func (e *Editor) OnEdit(fn func(EditDelta)) (remove func()) {
+	e.initBuffer()
+	l := &editListener{fn: fn}
+	e.edits.listeners = append(e.edits.listeners, l)
+	e.edits.bind(e.buffer)
+	return func() {
+		e.edits.listeners = slices.DeleteFunc(e.edits.listeners, func(other *editListener) bool { return other == l })
+	}
+}
+
+type editListener struct {
+	fn func(EditDelta)
+}
+
+// editListeners forwards the edit deltas of the text source shown by the
+// editor to the listeners.
+type editListeners struct {
+	listeners []*editListener
+	source    buffer.TextSource
+	remove    func()
+}
+
+// bind forwards the edits of src, instead of the previous source.
+func (l *editListeners) bind(src buffer.TextSource) {
+	if len(l.listeners) == 0 || l.source == src {
+		return
+	}
+	if l.remove != nil {
+		l.remove()
+	}
+	l.source = src
+	l.remove = src.OnEdit(l.dispatch)
+}
+
+func (l *editListeners) dispatch(delta buffer.EditDelta) {
+	evt := EditDelta{
+		TextChange: TextChange{Start: delta.Start, OldEnd: delta.OldEnd, NewEnd: delta.NewEnd},
+		Version:    delta.Version,
+		StartByte:  delta.StartByte,
+		OldEndByte: delta.OldEndByte,
+		NewEndByte: delta.NewEndByte,
+		Text:       delta.Text,
+	}
+	for _, listener := range slices.Clone(l.listeners) {
+		listener.fn(evt)
+	}
+}
+
+// Edit is an edit about to be made to the text, replacing the rune range
+// [Start, End) with Text.
+type Edit struct {
+	Start, End int
+	Text       string
+}
+
+type beforeChangeHook struct {
+	fn func(change Edit) (Edit, bool)
+}
+
+// OnBeforeChange registers fn to be called before each edit made by the user
+// or through the editor API reaches the text buffer, e.g., for input masks,
+// fields of a maximum length, or protected regions. fn can return the edit
+// unchanged, rewrite it, or return false to veto it. The hooks are called in
+// the order they were registered, each with the edit returned by the
+// previous one. An edit replacing several ranges, e.g., typing with
+// multiple carets or commenting the selected lines, is filtered once as the
+// replacement of the range spanning all of them. If the edit is rewritten,
+// the caret is moved after the rewritten text and the extra carets are
+// removed. SetText, undo and redo are not filtered. OnBeforeChange returns a
+// function to unregister fn.
+func (e *Editor) OnBeforeChange(fn func(change Edit) (Edit, bool)) (remove func()) {
+	h := &beforeChangeHook{fn: fn}
+	e.beforeChange = append(e.beforeChange, h)
+	return func() {
+		e.beforeChange = slices.DeleteFunc(e.beforeChange, func(other *beforeChangeHook) bool { return other == h })
+	}
+}
+
+// filterChange runs the hooks registered by OnBeforeChange on the edit. It
+// reports false if a hook vetoes it.
+func (e *Editor) filterChange(change Edit) (Edit, bool) {
+	for _, h := range slices.Clone(e.beforeChange) {
+		var ok bool
+		if change, ok = h.fn(change); !ok {
+			return change, false
+		}
+	}
+	return change, true
+}
+
+// editResult is the outcome of an edit filtered by the OnBeforeChange hooks.
+type editResult int
+
+const (
+	// editApplied means the edit is made as requested.
+	editApplied editResult = iota
+	// editRewritten means a hook rewrote the edit. The rewritten edit is made
+	// instead, and the caret is moved after its text.
+	editRewritten
+	// editVetoed means a hook vetoed the edit, and the text is unchanged.
+	editVetoed
+)
+
+// replace replaces the text between start and end with s. Indices are in
+// runes. It returns the number of runes inserted. The edit is filtered by
+// the hooks registered by OnBeforeChange, see replaceEdits.
+func (e *Editor) replace(start, end int, s string) (int, editResult) {
+	return e.replaceEdits([]Edit{{Start: start, End: end, Text: s}})
+}
+
+// replaceEdits makes the replacements of a single logical edit, given in the
+// offsets of the text before the edit. The replacements must not overlap;
+// the ones at the same offset are inserted in the order given. The edit is
+// filtered once by the OnBeforeChange hooks, as the replacement of the range
+// spanning all the replacements. If it is rewritten, the caret is moved after
+// the rewritten text and the extra carets are removed, so the callers must
+// not move the carets themselves. It returns the number of runes inserted.
+func (e *Editor) replaceEdits(edits []Edit) (int, editResult) {
+	length := e.text.Len()
+	edits = slices.Clone(edits)
+	for i := range edits {
+		start, end := edits[i].Start, edits[i].End
+		if start > end {
+			start, end = end, start
+		}
+		edits[i].Start, edits[i].End = min(max(start, 0), length), min(max(end, 0), length)
+	}
+	edits = slices.DeleteFunc(edits, func(edit Edit) bool { return edit.Start == edit.End && edit.Text == "" })
+	slices.SortStableFunc(edits, func(a, b Edit) int { return cmp.Compare(a.Start, b.Start) })
+	if len(edits) == 0 {
+		return 0, editApplied
+	}
+
+	if len(e.beforeChange) > 0 {
+		whole := e.spanEdit(edits)
+		change, ok := e.filterChange(whole)
+		if !ok {
+			return 0, editVetoed
+		}
+		if change != whole {
+			start := min(max(min(change.Start, change.End), 0), length)
+			inserted := e.applyReplace(change.Start, change.End, change.Text)
+			e.clearExtraSelections()
+			e.columnEdit.selections = nil
+			e.SetCaret(start+inserted, start+inserted)
+			return inserted, editRewritten
+		}
+	}
+
+	e.buffer.GroupOp()
+	defer e.buffer.UnGroupOp()
+	inserted := 0
+	// edit from the end, so that the offsets of the replacements before are
+	// kept.
+	for i := len(edits) - 1; i >= 0; i-- {
+		inserted += e.applyReplace(edits[i].Start, edits[i].End, edits[i].Text)
+	}
+	return inserted, editApplied
+}
+
+// spanEdit returns the edits, sorted and not overlapping, as the replacement
+// of the range spanning all of them.
+func (e *Editor) spanEdit(edits []Edit) Edit {
+	if len(edits) == 1 {
+		return edits[0]
+	}
+	var b strings.Builder
+	span := Edit{Start: edits[0].Start, End: edits[0].Start}
+	for _, edit := range edits {
+		if edit.Start > span.End {
+			b.WriteString(e.ReadRange(span.End, edit.Start))
+		}
+		b.WriteString(edit.Text)
+		span.End = max(span.End, edit.End)
+	}
+	span.Text = b.String()
+	return span
+}
+
+// replaceTextViewEdit makes the edits of the text view, e.g., indenting the
+// selected lines with Tab, through replace.
+func (e *Editor) replaceTextViewEdit(start, end int, s string) (int, bool) {
+	n, result := e.replace(start, end, s)
+	return n, result == editApplied
+}
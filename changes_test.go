@@ -0,0 +1,151 @@
+package gvcode
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"gioui.org/io/key"
+)
+
+func TestOnBeforeChange(t *testing.T) {
+	e := newTestEditor(t, "abc")
+
+	// a field of 5 runes at most, accepting digits only.
+	const maxLen = 5
+	remove := e.OnBeforeChange(func(change Edit) (Edit, bool) {
+		if strings.ContainsFunc(change.Text, func(r rune) bool { return r < '0' || r > '9' }) {
+			return change, false
+		}
+		room := maxLen - (utf8.RuneCountInString(e.Text()) - (change.End - change.Start))
+		if n := utf8.RuneCountInString(change.Text); n > room {
+			change.Text = string([]rune(change.Text)[:max(room, 0)])
+		}
+		return change, true
+	})
+
+	e.SetCaret(3, 3)
+	e.onTextInput(key.EditEvent{Range: key.Range{Start: 3, End: 3}, Text: "x"})
+	if got := e.Text(); got != "abc" {
+		t.Fatalf("got %q, want the letter to be vetoed", got)
+	}
+	if n := e.Insert("1234"); n != 2 {
+		t.Fatalf("inserted %d runes, want 2", n)
+	}
+	if got := e.Text(); got != "abc12" {
+		t.Fatalf("got %q, want the text truncated", got)
+	}
+	if start, end := e.Selection(); start != 5 || end != 5 {
+		t.Fatalf("got caret %d-%d, want 5", start, end)
+	}
+
+	// deletions pass the filter.
+	e.SetCaret(0, 3)
+	e.Delete(1)
+	if got := e.Text(); got != "12" {
+		t.Fatalf("got %q, want %q", got, "12")
+	}
+
+	remove()
+	e.Insert("xyz")
+	if got := e.Text(); got != "xyz12" {
+		t.Fatalf("got %q after removing the hook", got)
+	}
+}
+
+func TestOnBeforeChangeRewrite(t *testing.T) {
+	e := newTestEditor(t, "ab")
+	// the hooks are chained.
+	e.OnBeforeChange(func(change Edit) (Edit, bool) {
+		change.Text = strings.ToUpper(change.Text)
+		return change, true
+	})
+	e.OnBeforeChange(func(change Edit) (Edit, bool) {
+		change.Text = strings.ReplaceAll(change.Text, "X", "Y")
+		return change, true
+	})
+
+	e.SetCaret(1, 1)
+	e.Insert("x")
+	if got := e.Text(); got != "aYb" {
+		t.Fatalf("got %q, want %q", got, "aYb")
+	}
+}
+
+func TestOnBeforeChangeWholeEdit(t *testing.T) {
+	// recordEdits records the edits filtered by the hooks of e.
+	recordEdits := func(e *Editor) *[]Edit {
+		var edits []Edit
+		e.OnBeforeChange(func(change Edit) (Edit, bool) {
+			edits = append(edits, change)
+			return change, true
+		})
+		return &edits
+	}
+
+	e := newTestEditor(t, "/* x */")
+	edits := recordEdits(e)
+	e.SetCaret(0, 7)
+	e.ToggleBlockComment()
+	if got := e.Text(); got != "x" {
+		t.Fatalf("got %q after uncommenting, want %q", got, "x")
+	}
+	if want := (Edit{Start: 0, End: 7, Text: "x"}); len(*edits) != 1 || (*edits)[0] != want {
+		t.Errorf("got edits %+v, want the block uncomment filtered once as %+v", *edits, want)
+	}
+
+	e = newTestEditor(t, "a\nb\n")
+	edits = recordEdits(e)
+	e.SetCaret(0, 3)
+	e.ToggleLineComment()
+	if want := (Edit{Start: 0, End: 2, Text: "// a\n// "}); len(*edits) != 1 || (*edits)[0] != want {
+		t.Errorf("got edits %+v, want the lines commented in a single edit %+v", *edits, want)
+	}
+	e.SetCaret(0, len([]rune(e.Text())))
+	*edits = nil
+	e.onTab(false)
+	if got, want := e.Text(), "\t// a\n\t// b\n"; got != want || len(*edits) != 1 {
+		t.Errorf("got %q with edits %+v after Tab, want %q filtered once", got, *edits, want)
+	}
+
+	e = newColumnEditor(t, "foo\nfoo", 2, 0)
+	edits = recordEdits(e)
+	for i := range e.columnEdit.selections {
+		cursor := &e.columnEdit.selections[i]
+		_, end := e.ConvertPos(cursor.line, 3)
+		cursor.endX, cursor.col = int(end.X), 3
+	}
+	typeColumns(e, "(")
+	if got, want := e.Text(), "(foo)\n(foo)"; got != want {
+		t.Fatalf("got %q after surrounding, want %q", got, want)
+	}
+	if want := (Edit{Start: 0, End: 7, Text: "(foo)\n(foo)"}); len(*edits) != 1 || (*edits)[0] != want {
+		t.Errorf("got edits %+v, want the column surround filtered once as %+v", *edits, want)
+	}
+}
+
+func TestOnBeforeChangeRewriteCaret(t *testing.T) {
+	e := newTestEditor(t, "a\nb\nc")
+	// the hook drops the comment token of the last line.
+	e.OnBeforeChange(func(change Edit) (Edit, bool) {
+		change.Text = strings.TrimSuffix(change.Text, "\n// ") + "\n"
+		return change, true
+	})
+
+	e.SetCaret(0, 5)
+	e.ToggleLineComment()
+	if got, want := e.Text(), "// a\n// b\nc"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	// the caret is placed after the rewritten text.
+	if start, end := e.Selection(); start != 10 || end != 10 {
+		t.Errorf("got caret %d-%d, want 10", start, end)
+	}
+
+	// a vetoed Tab leaves the text as is.
+	e.OnBeforeChange(func(change Edit) (Edit, bool) { return change, false })
+	e.SetCaret(0, 5)
+	if e.onTab(false) != nil || e.Text() != "// a\n// b\nc" {
+		t.Errorf("got %q after a vetoed Tab", e.Text())
+	}
+}
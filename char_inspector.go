@@ -0,0 +1,213 @@
+package gvcode
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"github.com/go-text/typesetting/segmenter"
+	gvcolor "github.com/oligo/gvcode/color"
+	"golang.org/x/text/unicode/runenames"
+)
+
+// inspectWindow is the number of runes read around the inspected offset to
+// find the grapheme cluster containing it.
+const inspectWindow = 32
+
+// CodePoint describes a code point of an inspected character.
+type CodePoint struct {
+	Rune rune
+	// Name is the name of the code point in the Unicode Character Database,
+	// e.g., "ZERO WIDTH SPACE".
+	Name string
+	// UTF8 is the UTF-8 encoding of the code point.
+	UTF8 []byte
+	// Invisible reports whether the code point has no visible glyph: a
+	// format or control character other than tabs and line breaks, or a
+	// separator other than the space.
+	Invisible bool
+}
+
+// String formats the code point like "U+200B ZERO WIDTH SPACE (E2 80 8B)".
+func (c CodePoint) String() string {
+	return fmt.Sprintf("U+%04X %s (% X)", c.Rune, c.Name, c.UTF8)
+}
+
+// CharacterInfo describes the user-perceived character, i.e. the grapheme
+// cluster, at an offset of the document.
+type CharacterInfo struct {
+	// Offset is the rune offset of the start of the grapheme cluster.
+	Offset int
+	// Text is the text of the grapheme cluster.
+	Text string
+	// CodePoints are the code points composing the grapheme cluster.
+	CodePoints []CodePoint
+}
+
+// Invisible reports whether any code point of the character is invisible.
+func (c CharacterInfo) Invisible() bool {
+	for _, cp := range c.CodePoints {
+		if cp.Invisible {
+			return true
+		}
+	}
+	return false
+}
+
+// InspectCharacterAt returns the code points, names and UTF-8 encoding of the
+// grapheme cluster containing the rune at offset. It reports false if offset
+// is out of the document.
+func (e *Editor) InspectCharacterAt(offset int) (CharacterInfo, bool) {
+	e.initBuffer()
+	if offset < 0 || offset >= e.text.Len() {
+		return CharacterInfo{}, false
+	}
+
+	start := max(offset-inspectWindow, 0)
+	runes := []rune(e.ReadRange(start, min(offset+inspectWindow, e.text.Len())))
+	if offset-start >= len(runes) {
+		return CharacterInfo{}, false
+	}
+
+	cluster, clusterOff := runes[offset-start:offset-start+1], offset
+	var seg segmenter.Segmenter
+	seg.Init(runes)
+	iter := seg.GraphemeIterator()
+	for iter.Next() {
+		g := iter.Grapheme()
+		if g.Offset+len(g.Text) > offset-start {
+			cluster, clusterOff = g.Text, start+g.Offset
+			break
+		}
+	}
+
+	info := CharacterInfo{Offset: clusterOff, Text: string(cluster)}
+	for _, r := range cluster {
+		info.CodePoints = append(info.CodePoints, CodePoint{
+			Rune:      r,
+			Name:      runeName(r),
+			UTF8:      utf8.AppendRune(nil, r),
+			Invisible: isInvisibleRune(r),
+		})
+	}
+	return info, true
+}
+
+// runeName returns the Unicode name of r, or a placeholder for the code
+// points without a name.
+func runeName(r rune) string {
+	if name := runenames.Name(r); name != "" {
+		return name
+	}
+	if unicode.Is(unicode.Co, r) {
+		return "<private use>"
+	}
+	return "<unassigned>"
+}
+
+// isInvisibleRune reports whether r has no visible glyph.
+func isInvisibleRune(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\r':
+		return false
+	}
+	return unicode.In(r, unicode.Cc, unicode.Cf, unicode.Z)
+}
+
+// SetCharInspectorVisible shows or hides the character inspector, a popup
+// describing the character after the caret.
+func (e *Editor) SetCharInspectorVisible(visible bool) {
+	e.charInspector = visible
+}
+
+// CharInspectorVisible reports whether the character inspector is shown.
+func (e *Editor) CharInspectorVisible() bool {
+	return e.charInspector
+}
+
+// paintCharInspector shows the character after the caret and its code points
+// in a popup below the caret, if the inspector is visible.
+func (e *Editor) paintCharInspector(gtx layout.Context, shaper *text.Shaper, textColor gvcolor.Color) {
+	if !e.charInspector || shaper == nil {
+		return
+	}
+	caret, _ := e.text.Selection()
+	info, ok := e.InspectCharacterAt(caret)
+	if !ok {
+		return
+	}
+
+	// the popup is positioned in the document, like the completion popup.
+	offset := e.text.CaretCoords().Round().Add(e.text.ScrollOff())
+	e.text.PaintOverlay(gtx, offset, func(gtx layout.Context) layout.Dimensions {
+		return e.layoutCharInspector(gtx, shaper, textColor, info)
+	})
+}
+
+// layoutCharInspector renders the quoted character followed by one row per
+// code point. The invisible code points are highlighted.
+func (e *Editor) layoutCharInspector(gtx layout.Context, shaper *text.Shaper, textColor gvcolor.Color, info CharacterInfo) layout.Dimensions {
+	lineHeight := e.text.GetLineHeight().Ceil()
+	padding := gtx.Dp(unit.Dp(6))
+	maxWidth := gtx.Constraints.Max.X - 2*padding
+
+	params := e.text.Params()
+	params.MinWidth = 0
+	params.MaxWidth = 1 << 20
+	params.MaxLines = 1
+
+	rows := []string{strconv.Quote(info.Text)}
+	for _, cp := range info.CodePoints {
+		rows = append(rows, cp.String())
+	}
+	warnColor := gvcolor.MakeColor(color.NRGBA{R: 0xD0, G: 0x40, B: 0x20, A: 0xFF})
+
+	// Record the content first to know the size of the box.
+	macro := op.Record(gtx.Ops)
+	width := 0
+	for i, row := range rows {
+		c := textColor
+		if i == 0 {
+			c = textColor.MulAlpha(0xA0)
+		} else if info.CodePoints[i-1].Invisible {
+			c = warnColor
+		}
+		shaper.LayoutString(params, strings.ReplaceAll(row, "\t", " "))
+		width = max(width, paintTextGlyphs(gtx, shaper, image.Pt(padding, padding+i*lineHeight), maxWidth, c))
+	}
+	content := macro.Stop()
+
+	size := image.Pt(min(width, maxWidth)+2*padding, len(rows)*lineHeight+2*padding)
+	rect := image.Rectangle{Max: size}
+	radius := gtx.Dp(unit.Dp(4))
+
+	bgColor := color.NRGBA{R: 0xF8, G: 0xF8, B: 0xF8, A: 0xFF}
+	if e.colorPalette != nil && e.colorPalette.Background.IsSet() {
+		bgColor = e.colorPalette.Background.NRGBA()
+		bgColor.A = 0xFF
+	}
+	paint.FillShape(gtx.Ops, bgColor, clip.UniformRRect(rect, radius).Op(gtx.Ops))
+
+	borderColor := textColor.NRGBA()
+	borderColor.A = 0x40
+	paint.FillShape(gtx.Ops, borderColor, clip.Stroke{
+		Path:  clip.UniformRRect(rect, radius).Path(gtx.Ops),
+		Width: float32(gtx.Dp(unit.Dp(1))),
+	}.Op())
+
+	stack := clip.Rect(rect.Inset(padding / 2)).Push(gtx.Ops)
+	content.Add(gtx.Ops)
+	stack.Pop()
+
+	return layout.Dimensions{Size: size}
+}
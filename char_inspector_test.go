@@ -0,0 +1,37 @@
+package gvcode
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestInspectCharacterAt(t *testing.T) {
+	e := newTestEditor(t, "e\u0301x\u200b")
+
+	// the combining accent belongs to the cluster of the letter.
+	for _, offset := range []int{0, 1} {
+		info, ok := e.InspectCharacterAt(offset)
+		if !ok || info.Offset != 0 || info.Text != "e\u0301" || len(info.CodePoints) != 2 {
+			t.Fatalf("offset %d: got %+v, want the cluster e\\u0301", offset, info)
+		}
+		if info.CodePoints[1].Name != "COMBINING ACUTE ACCENT" || info.Invisible() {
+			t.Errorf("offset %d: got code points %v", offset, info.CodePoints)
+		}
+	}
+
+	info, ok := e.InspectCharacterAt(3)
+	if !ok || info.Offset != 3 || len(info.CodePoints) != 1 {
+		t.Fatalf("got %+v, want the zero width space", info)
+	}
+	cp := info.CodePoints[0]
+	if cp.Name != "ZERO WIDTH SPACE" || !cp.Invisible || !bytes.Equal(cp.UTF8, []byte{0xE2, 0x80, 0x8B}) {
+		t.Errorf("got %+v, want an invisible ZERO WIDTH SPACE", cp)
+	}
+	if got, want := cp.String(), "U+200B ZERO WIDTH SPACE (E2 80 8B)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, ok := e.InspectCharacterAt(4); ok {
+		t.Error("expected no character at the end of the document")
+	}
+}
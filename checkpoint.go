@@ -0,0 +1,39 @@
+package gvcode
+
+// Checkpoint marks the current state of the text with name, replacing the
+// previous checkpoint of the name. It can be used to track the saved state of
+// a file: the text is dirty since the checkpoint until undo or redo restores
+// it.
+func (e *Editor) Checkpoint(name string) {
+	e.initBuffer()
+	e.buffer.Checkpoint(name)
+}
+
+// RemoveCheckpoint removes the checkpoint of name.
+func (e *Editor) RemoveCheckpoint(name string) {
+	e.initBuffer()
+	e.buffer.RemoveCheckpoint(name)
+}
+
+// IsDirtySince reports whether the text has changed since the checkpoint of
+// name. It reports true if the checkpoint does not exist, including after
+// SetText, which clears the checkpoints.
+func (e *Editor) IsDirtySince(name string) bool {
+	e.initBuffer()
+	return e.buffer.IsDirtySince(name)
+}
+
+// RevertTo restores the text to the checkpoint of name by undoing or redoing
+// the edits made since it, so that the restoration can be undone too. It
+// returns false, leaving the text untouched, if the checkpoint does not exist,
+// or is no longer reachable in the undo history because of later edits.
+func (e *Editor) RevertTo(name string) bool {
+	e.initBuffer()
+	positions, ok := e.text.RevertTo(name)
+	if len(positions) > 0 {
+		last := positions[len(positions)-1]
+		e.SetCaret(last.End, last.Start)
+		e.pending = append(e.pending, ChangeEvent{})
+	}
+	return ok
+}
@@ -0,0 +1,399 @@
+package gvcode
+
+import (
+	"image"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Multi-caret clipboard semantics follow VSCode: copying N column selections,
+// or N selections of SelectNextOccurrence, stores N entries joined by newlines, and pasting text of exactly N entries
+// with N carets distributes one entry per caret. When every selection is
+// empty, the whole lines of the carets are copied instead, and the clipboard
+// text ends with a newline to mark it as a line operation. Pasting such text
+// inserts each line above the line of its caret.
+
+// columnLineEnd returns the rune offset of the end of line, excluding the line
+// break.
+func (e *Editor) columnLineEnd(line int) int {
+	end := e.text.ConvertPos(line+1, 0)
+	if end > e.text.ConvertPos(line, 0) {
+		if r, err := e.text.ReadRuneAt(end - 1); err == nil && r == '\n' {
+			end--
+		}
+	}
+	return end
+}
+
+// columnCursorRange returns the rune range covered by the selection rectangle
+// of cursor on its line.
+func (e *Editor) columnCursorRange(cursor columnCursor) (start, end int) {
+	lineStart := e.text.ConvertPos(cursor.line, 0)
+	lineEnd := e.columnLineEnd(cursor.line)
+	// query the middle of the line, as its top is also the bottom of the
+	// line above.
+	lineHeight := e.text.GetLineHeight().Round()
+	y := cursor.line*lineHeight + lineHeight/2 - e.text.ScrollOff().Y
+
+	offsetAt := func(x int) int {
+		_, _, off := e.text.QueryPos(image.Point{X: x, Y: y})
+		if off >= 0 {
+			return min(max(off, lineStart), lineEnd)
+		}
+		// x is outside of the line boundary.
+		if _, pos := e.ConvertPos(cursor.line, 0); float32(x) <= pos.X {
+			return lineStart
+		}
+		return lineEnd
+	}
+
+	start, end = offsetAt(cursor.startX), offsetAt(cursor.endX)
+	if start > end {
+		start, end = end, start
+	}
+	return start, end
+}
+
+// collapseColumnCursor places the column cursor at the rune column col of
+// line, and shrinks its selection rectangle to the caret.
+func (e *Editor) collapseColumnCursor(cursor *columnCursor, line, col int) {
+	cursor.line = line
+	cursor.col = col
+	_, pos := e.ConvertPos(line, col)
+	cursor.startX = int(pos.X)
+	cursor.endX = cursor.startX
+}
+
+// columnClipboardText returns the clipboard text of the column selections. If
+// all of the selections are empty, the lines of the carets are returned and
+// lineOp is true.
+func (e *Editor) columnClipboardText() (text string, lineOp bool) {
+	entries := make([]string, 0, len(e.columnEdit.selections))
+	for _, cursor := range e.columnEdit.selections {
+		start, end := e.columnCursorRange(cursor)
+		entries = append(entries, e.ReadRange(start, end))
+	}
+
+	if strings.Join(entries, "") == "" {
+		lineOp = true
+		for i, cursor := range e.columnEdit.selections {
+			entries[i] = e.ReadRange(e.text.ConvertPos(cursor.line, 0), e.columnLineEnd(cursor.line))
+		}
+		return strings.Join(entries, "\n") + "\n", lineOp
+	}
+
+	return strings.Join(entries, "\n"), lineOp
+}
+
+// cutColumns deletes the column selections, or the lines of the carets in the
+// case of a line operation. It returns the number of runes deleted.
+func (e *Editor) cutColumns(lineOp bool) (deletedRunes int) {
+	selections := e.columnEdit.selections
+
+	edits := make([]Edit, 0, len(selections))
+	cols := make([]int, len(selections))
+	for i, cursor := range selections {
+		lineStart := e.text.ConvertPos(cursor.line, 0)
+		if lineOp {
+			edits = append(edits, Edit{Start: lineStart, End: e.text.ConvertPos(cursor.line+1, 0)})
+		} else {
+			start, end := e.columnCursorRange(cursor)
+			edits = append(edits, Edit{Start: start, End: end})
+			cols[i] = start - lineStart
+		}
+		deletedRunes += edits[i].End - edits[i].Start
+	}
+	if _, result := e.replaceEdits(edits); result != editApplied {
+		e.scrollCaret = true
+		if result == editVetoed {
+			return 0
+		}
+		return deletedRunes
+	}
+	if !lineOp {
+		for i := range selections {
+			e.collapseColumnCursor(&selections[i], selections[i].line, cols[i])
+		}
+	}
+
+	if lineOp && len(selections) > 0 {
+		// The carets are gone with their lines.
+		start := e.text.ConvertPos(selections[0].line, 0)
+		e.clearColumnEdit()
+		e.SetCaret(start, start)
+	} else {
+		e.syncColumnCaret()
+	}
+
+	e.scrollCaret = true
+	e.text.MoveCaret(0, 0)
+	return deletedRunes
+}
+
+// pasteColumns pastes text at each column cursor. If text has one entry per
+// caret, the entries are distributed to the carets in order, otherwise text
+// is pasted at every caret. It returns the number of runes inserted.
+func (e *Editor) pasteColumns(text string) (insertedRunes int) {
+	selections := e.columnEdit.selections
+	entries := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	lineOp := strings.HasSuffix(text, "\n") && len(entries) == len(selections)
+	if len(entries) != len(selections) {
+		entries = make([]string, len(selections))
+		for i := range entries {
+			entries[i] = text
+		}
+	}
+
+	edits := make([]Edit, 0, len(selections))
+	for i, cursor := range selections {
+		lineStart := e.text.ConvertPos(cursor.line, 0)
+		if lineOp {
+			edits = append(edits, Edit{Start: lineStart, End: lineStart, Text: entries[i] + "\n"})
+			continue
+		}
+		start, end := e.columnCursorRange(cursor)
+		edits = append(edits, Edit{Start: start, End: end, Text: entries[i]})
+		// remember the caret column for the second pass.
+		selections[i].col = start - lineStart
+	}
+	insertedRunes, result := e.replaceEdits(edits)
+	if result != editApplied {
+		e.scrollCaret = true
+		return insertedRunes
+	}
+
+	// Move the carets to the end of the pasted entries, accounting for the
+	// lines inserted above them.
+	shift := 0
+	for i := range selections {
+		cursor := &selections[i]
+		if lineOp {
+			shift++
+			e.collapseColumnCursor(cursor, cursor.line+shift, cursor.col)
+			continue
+		}
+
+		line, col := cursor.line+shift, cursor.col
+		if n := strings.Count(entries[i], "\n"); n > 0 {
+			shift += n
+			line += n
+			col = utf8.RuneCountInString(entries[i][strings.LastIndexByte(entries[i], '\n')+1:])
+		} else {
+			col += utf8.RuneCountInString(entries[i])
+		}
+		e.collapseColumnCursor(cursor, line, col)
+	}
+	e.syncColumnCaret()
+
+	e.scrollCaret = true
+	e.text.MoveCaret(0, 0)
+	return insertedRunes
+}
+
+// reindentPaste re-indents the lines of the multi-line text pasted at the
+// caret, so that the block keeps its relative indentation, but is indented
+// from the indentation of the caret line. The first line is inserted at the
+// caret as is, unless the caret is in the indentation and the line has its
+// own indentation, which is then made relative too.
+func (e *Editor) reindentPaste(text string) string {
+	if !strings.Contains(text, "\n") {
+		return text
+	}
+
+	width := max(e.text.TabWidth, 1)
+	start, end := e.text.Selection()
+	caret := min(start, end)
+	_, para := e.text.FindParagraph(caret)
+	before := e.ReadRange(para.RuneOff, caret)
+	target := indentColumns(leadingIndent(before), width)
+
+	lines := strings.Split(text, "\n")
+	first := 1
+	if strings.TrimLeft(before, " \t") == "" && leadingIndent(lines[0]) != "" {
+		first = 0
+	}
+	isBlank := func(line string) bool { return strings.Trim(line, " \t\r") == "" }
+
+	// the indentation of the block is the smallest of its lines.
+	base := -1
+	for _, line := range lines[first:] {
+		if isBlank(line) {
+			continue
+		}
+		if cols := indentColumns(leadingIndent(line), width); base < 0 || cols < base {
+			base = cols
+		}
+	}
+	if base < 0 {
+		return text
+	}
+
+	for i := first; i < len(lines); i++ {
+		indent := leadingIndent(lines[i])
+		if isBlank(lines[i]) {
+			lines[i] = lines[i][len(indent):]
+			continue
+		}
+		cols := indentColumns(indent, width) - base
+		if i > 0 {
+			cols += target
+		}
+		lines[i] = convertIndent(strings.Repeat(" ", cols), e.text.SoftTab, width) + lines[i][len(indent):]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// leadingIndent returns the spaces and tabs at the start of line.
+func leadingIndent(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// indentColumns returns the visual width of indent with tab stops every
+// width columns.
+func indentColumns(indent string, width int) int {
+	cols := 0
+	for _, r := range indent {
+		if r == '\t' {
+			cols += width - cols%width
+		} else {
+			cols++
+		}
+	}
+	return cols
+}
+
+// selectionsClipboardText returns the clipboard text of the selections added
+// by SelectNextOccurrence and SelectAllOccurrences, one entry per selection in
+// document order. If all of the selections are empty, the lines of the carets
+// are returned and lineOp is true.
+func (e *Editor) selectionsClipboardText() (text string, lineOp bool) {
+	selections := e.Selections()
+	entries := make([]string, 0, len(selections))
+	for _, sel := range selections {
+		entries = append(entries, e.ReadRange(min(sel.Start, sel.End), max(sel.Start, sel.End)))
+	}
+
+	if strings.Join(entries, "") == "" {
+		for i, sel := range selections {
+			line, para := e.text.FindParagraph(sel.Start)
+			entries[i] = e.ReadRange(para.RuneOff, e.columnLineEnd(line))
+		}
+		return strings.Join(entries, "\n") + "\n", true
+	}
+
+	return strings.Join(entries, "\n"), false
+}
+
+// cutSelections deletes the text of the selections, or the lines of the
+// carets in the case of a line operation, leaving a caret where each of them
+// was. It returns the number of runes deleted.
+func (e *Editor) cutSelections(lineOp bool) (deletedRunes int) {
+	if !lineOp {
+		e.editSelections(func(sel TextRange) (int, int, string) {
+			deletedRunes += sel.End - sel.Start
+			return sel.Start, sel.End, ""
+		})
+		return deletedRunes
+	}
+
+	// the lines of the carets, without duplicates.
+	selections, primary := e.selectionsWithPrimary()
+	type lineRange struct{ start, end int }
+	var lines []lineRange
+	primaryLine := 0
+	for i, sel := range selections {
+		line, para := e.text.FindParagraph(sel.Start)
+		if n := len(lines); n == 0 || lines[n-1].start != para.RuneOff {
+			lines = append(lines, lineRange{start: para.RuneOff, end: e.text.ConvertPos(line+1, 0)})
+		}
+		if i == primary {
+			primaryLine = len(lines) - 1
+		}
+	}
+
+	edits := make([]Edit, len(lines))
+	for i, l := range lines {
+		edits[i] = Edit{Start: l.start, End: l.end}
+	}
+	result := editVetoed
+	e.Transaction(func(tx *EditTx) {
+		_, result = tx.replaceEdits(edits)
+	})
+	if result == editVetoed {
+		return 0
+	}
+	// a caret is left at the start of the line moved up to each deleted line.
+	carets := make([]int, len(lines))
+	for i, l := range lines {
+		carets[i] = l.start - deletedRunes
+		deletedRunes += l.end - l.start
+	}
+	if result == editApplied {
+		e.setSelectionCarets(carets, primaryLine)
+	}
+	return deletedRunes
+}
+
+// pasteSelections pastes text at each selection. If text has one entry per
+// selection, the entries are distributed to the selections in document
+// order, otherwise text is pasted at every selection. Whole lines copied from
+// carets on distinct lines are inserted above the lines of the carets. It
+// returns the number of runes inserted.
+func (e *Editor) pasteSelections(text string) (insertedRunes int) {
+	selections, primary := e.selectionsWithPrimary()
+	entries := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if len(entries) != len(selections) {
+		return e.replaceSelections(text)
+	}
+
+	lineStarts := make([]int, len(selections))
+	lineOp := strings.HasSuffix(text, "\n")
+	for i, sel := range selections {
+		_, para := e.text.FindParagraph(sel.Start)
+		lineStarts[i] = para.RuneOff
+		if i > 0 && lineStarts[i] == lineStarts[i-1] {
+			lineOp = false
+		}
+	}
+
+	if !lineOp {
+		i := 0
+		e.editSelections(func(sel TextRange) (int, int, string) {
+			entry := entries[i]
+			i++
+			insertedRunes += utf8.RuneCountInString(entry)
+			return sel.Start, sel.End, entry
+		})
+		return insertedRunes
+	}
+
+	edits := make([]Edit, len(selections))
+	for i := range selections {
+		edits[i] = Edit{Start: lineStarts[i], End: lineStarts[i], Text: entries[i] + "\n"}
+	}
+	result := editVetoed
+	e.Transaction(func(tx *EditTx) {
+		insertedRunes, result = tx.replaceEdits(edits)
+	})
+	if result != editApplied {
+		return insertedRunes
+	}
+	// the carets keep their columns in the lines moved down.
+	carets := make([]int, len(selections))
+	shift := 0
+	for i, sel := range selections {
+		shift += utf8.RuneCountInString(entries[i]) + 1
+		carets[i] = sel.Start + shift
+	}
+	e.setSelectionCarets(carets, primary)
+	return insertedRunes
+}
+
+// selectionsWithPrimary returns the selections in document order, and the
+// index of the primary one.
+func (e *Editor) selectionsWithPrimary() ([]TextRange, int) {
+	start, end := e.text.Selection()
+	selections := e.Selections()
+	return selections, slices.Index(selections, TextRange{Start: start, End: end})
+}
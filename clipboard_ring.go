@@ -0,0 +1,109 @@
+package gvcode
+
+import (
+	"slices"
+
+	"gioui.org/layout"
+)
+
+// defaultClipboardRingSize is the number of entries kept by the clipboard
+// ring of an editor, if not configured by WithClipboardRing.
+const defaultClipboardRingSize = 32
+
+// ClipboardRing is a multi-entry clipboard keeping the most recently copied
+// or cut texts, like the kill ring of Emacs. The editor pushes the text it
+// copies to the host clipboard, and the text pasted from the host clipboard
+// if it was copied elsewhere, so that the latest entry is in sync with the
+// host clipboard.
+type ClipboardRing struct {
+	// entries are the texts, the newest first.
+	entries []string
+	size    int
+	// yank is the index of the current entry, rotated by Rotate.
+	yank int
+}
+
+// NewClipboardRing creates a clipboard ring keeping up to size entries.
+func NewClipboardRing(size int) *ClipboardRing {
+	return &ClipboardRing{size: max(size, 1)}
+}
+
+// Push adds text as the newest entry, dropping the oldest entries exceeding
+// the size of the ring. Pushing the text of the newest entry again is a no-op.
+// The current entry is reset to the newest one.
+func (r *ClipboardRing) Push(text string) {
+	r.yank = 0
+	if text == "" || (len(r.entries) > 0 && r.entries[0] == text) {
+		return
+	}
+	r.entries = slices.Insert(r.entries, 0, text)
+	if len(r.entries) > r.size {
+		r.entries = r.entries[:r.size]
+	}
+}
+
+// Append appends text to the newest entry, or pushes it if the ring is
+// empty. It is used to accumulate consecutive kills into one entry.
+func (r *ClipboardRing) Append(text string) {
+	if len(r.entries) == 0 {
+		r.Push(text)
+		return
+	}
+	r.yank = 0
+	r.entries[0] += text
+}
+
+// Len returns the number of entries.
+func (r *ClipboardRing) Len() int {
+	return len(r.entries)
+}
+
+// Entries returns the entries, the newest first.
+func (r *ClipboardRing) Entries() []string {
+	return slices.Clone(r.entries)
+}
+
+// Current returns the current entry, which is the newest one unless the ring
+// is rotated.
+func (r *ClipboardRing) Current() (string, bool) {
+	if len(r.entries) == 0 {
+		return "", false
+	}
+	return r.entries[r.yank], true
+}
+
+// Rotate moves the current entry by n entries to the older ones, wrapping
+// around, and returns it.
+func (r *ClipboardRing) Rotate(n int) (string, bool) {
+	if len(r.entries) == 0 {
+		return "", false
+	}
+	r.yank = ((r.yank+n)%len(r.entries) + len(r.entries)) % len(r.entries)
+	return r.entries[r.yank], true
+}
+
+// ClipboardRing returns the clipboard ring of the editor.
+func (e *Editor) ClipboardRing() *ClipboardRing {
+	if e.clipboardRing == nil {
+		e.clipboardRing = NewClipboardRing(defaultClipboardRingSize)
+	}
+	return e.clipboardRing
+}
+
+// WriteClipboard writes text to the host clipboard, and pushes it to the
+// clipboard ring.
+func (e *Editor) WriteClipboard(gtx layout.Context, text string) {
+	if text == "" {
+		return
+	}
+	writeHostClipboard(gtx, text)
+	e.ClipboardRing().Push(text)
+}
+
+// ReadClipboard requests the text of the host clipboard, and calls fn with it
+// instead of pasting it. The text is pushed to the clipboard ring before fn is
+// called, so that the current entry of the ring is the host clipboard text.
+func (e *Editor) ReadClipboard(gtx layout.Context, fn func(text string)) {
+	e.clipboardRead = fn
+	e.readHostClipboard(gtx)
+}
@@ -0,0 +1,36 @@
+package gvcode
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestClipboardRing(t *testing.T) {
+	r := NewClipboardRing(3)
+	if _, ok := r.Current(); ok {
+		t.Fatal("expected an empty ring")
+	}
+
+	for _, text := range []string{"a", "b", "b", "c", "d"} {
+		r.Push(text)
+	}
+	if got, want := r.Entries(), []string{"d", "c", "b"}; !slices.Equal(got, want) {
+		t.Fatalf("entries: got %q, want %q", got, want)
+	}
+
+	if got, _ := r.Rotate(1); got != "c" {
+		t.Fatalf("rotate: got %q, want %q", got, "c")
+	}
+	if got, _ := r.Rotate(2); got != "d" {
+		t.Fatalf("rotate with wrap: got %q, want %q", got, "d")
+	}
+	if got, _ := r.Rotate(-1); got != "b" {
+		t.Fatalf("rotate backward: got %q, want %q", got, "b")
+	}
+
+	// appending extends the newest entry, and makes it the current one.
+	r.Append("e")
+	if got, _ := r.Current(); got != "de" {
+		t.Fatalf("append: got %q, want %q", got, "de")
+	}
+}
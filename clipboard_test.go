@@ -0,0 +1,237 @@
+package gvcode
+
+import (
+	"image"
+	"slices"
+	"strings"
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// stubCompletion counts the completions started by OnText.
+type stubCompletion struct {
+	started, cancelled int
+}
+
+func (c *stubCompletion) AddCompletor(completor Completor, popup CompletionPopup) error { return nil }
+func (c *stubCompletion) OnText(ctx CompletionContext)                                  { c.started++ }
+func (c *stubCompletion) OnConfirm(idx int)                                             {}
+func (c *stubCompletion) Cancel()                                                       { c.cancelled++ }
+func (c *stubCompletion) IsActive() bool                                                { return false }
+func (c *stubCompletion) Offset() image.Point                                           { return image.Point{} }
+func (c *stubCompletion) Layout(gtx layout.Context) layout.Dimensions                   { return layout.Dimensions{} }
+
+func TestPasteReindent(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		caret   int
+		paste   string
+		want    string
+	}{
+		{
+			// the block copied from the middle of a line keeps the indentation
+			// of its lines relative to the closing line.
+			name:    "after text",
+			content: "func f() {\n\tx := \n}",
+			caret:   len("func f() {\n\tx := "),
+			paste:   "g(func() {\n        y()\n    })",
+			want:    "func f() {\n\tx := g(func() {\n\t\ty()\n\t})\n}",
+		},
+		{
+			// whole lines pasted in the indentation.
+			name:    "in indentation",
+			content: "func f() {\n\t\n}",
+			caret:   len("func f() {\n\t"),
+			paste:   "    if x {\n        y()\n\n    }\n",
+			want:    "func f() {\n\tif x {\n\t\ty()\n\n\t}\n\n}",
+		},
+		{
+			name:    "single line",
+			content: "\ta",
+			caret:   2,
+			paste:   "  b",
+			want:    "\ta  b",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestEditor(t, tc.content)
+			e.WithOptions(WithPasteReindent(true))
+			e.SetCaret(tc.caret, tc.caret)
+			if evt := e.onPasteText(tc.paste); evt != (ChangeEvent{}) {
+				t.Fatalf("got event %#v", evt)
+			}
+			if got := e.Text(); got != tc.want {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPasteUndoGroup(t *testing.T) {
+	e := newTestEditor(t, "a")
+	e.WithOptions(AddBeforePasteHook(func(text string) string {
+		// the hook edits the text too.
+		e.SetCaret(0, 0)
+		e.Insert("> ")
+		e.SetCaret(e.Len(), e.Len())
+		return strings.ToUpper(text)
+	}))
+	completion := &stubCompletion{}
+	e.WithOptions(WithAutoCompletion(completion))
+
+	e.SetCaret(1, 1)
+	e.onPasteText("bc")
+	if got := e.Text(); got != "> aBC" {
+		t.Fatalf("got %q", got)
+	}
+	e.OnTextEdit()
+	if completion.started != 0 {
+		t.Fatal("expected the paste not to start a completion")
+	}
+
+	if _, ok := e.undo(); !ok {
+		t.Fatal("expected the paste to be undone")
+	}
+	if got := e.Text(); got != "a" {
+		t.Fatalf("got %q after undo, want %q", got, "a")
+	}
+
+	// typing still starts a completion.
+	e.Insert("x")
+	e.OnTextEdit()
+	if completion.started != 1 {
+		t.Fatalf("got %d completions, want 1", completion.started)
+	}
+}
+
+func TestMultiSelectionClipboard(t *testing.T) {
+	e := newTestEditor(t, "x := a1\ny := a2\n")
+	gtx := layout.Context{Ops: new(op.Ops)}
+	// select "a1" and "a2".
+	e.SetCaret(7, 5)
+	e.multiSel.extras = []TextRange{{Start: 13, End: 15}}
+	e.recordMultiSelection()
+
+	e.onCopyCut(gtx, true)
+	if got, _ := e.ClipboardRing().Current(); got != "a1\na2" {
+		t.Fatalf("got clipboard %q, want one entry per selection", got)
+	}
+	if got, want := e.Text(), "x := \ny := \n"; got != want {
+		t.Fatalf("got %q after the cut, want %q", got, want)
+	}
+	want := []TextRange{{Start: 5, End: 5}, {Start: 11, End: 11}}
+	if got := e.Selections(); !slices.Equal(got, want) {
+		t.Fatalf("got carets %v after the cut, want %v", got, want)
+	}
+
+	// the entries are distributed to the carets in order.
+	e.onPasteText("b1\nb2")
+	if got, want := e.Text(), "x := b1\ny := b2\n"; got != want {
+		t.Fatalf("got %q after the paste, want %q", got, want)
+	}
+	// a number of entries not matching the carets is pasted at every caret.
+	e.onPasteText("c\nd\ne")
+	if got, want := e.Text(), "x := b1c\nd\ne\ny := b2c\nd\ne\n"; got != want {
+		t.Fatalf("got %q after the paste, want %q", got, want)
+	}
+}
+
+func TestMultiSelectionClipboardLines(t *testing.T) {
+	e := newTestEditor(t, "a\nbb\ncc\nd\n")
+	gtx := layout.Context{Ops: new(op.Ops)}
+	// empty selections on lines 1 and 2.
+	e.SetCaret(6, 6)
+	e.multiSel.extras = []TextRange{{Start: 3, End: 3}}
+	e.recordMultiSelection()
+
+	e.onCopyCut(gtx, false)
+	if got, _ := e.ClipboardRing().Current(); got != "bb\ncc\n" {
+		t.Fatalf("got clipboard %q, want the lines of the carets", got)
+	}
+
+	// the lines are pasted above the lines of the carets, which keep their
+	// columns.
+	e.onPasteText("x\ny\n")
+	if got, want := e.Text(), "a\nx\nbb\ny\ncc\nd\n"; got != want {
+		t.Fatalf("got %q after the paste, want %q", got, want)
+	}
+	want := []TextRange{{Start: 5, End: 5}, {Start: 10, End: 10}}
+	if got := e.Selections(); !slices.Equal(got, want) {
+		t.Fatalf("got carets %v after the paste, want %v", got, want)
+	}
+
+	e.onCopyCut(gtx, true)
+	if got, want := e.Text(), "a\nx\ny\nd\n"; got != want {
+		t.Fatalf("got %q after cutting the lines, want %q", got, want)
+	}
+	if start, end := e.Selection(); start != 6 || end != 6 {
+		t.Errorf("got primary caret %d-%d, want it at the start of the line moved up", start, end)
+	}
+	if _, ok := e.undo(); !ok || e.Text() != "a\nx\nbb\ny\ncc\nd\n" {
+		t.Errorf("got %q after undoing the cut, want it undone in one step", e.Text())
+	}
+}
+
+func TestColumnClipboard(t *testing.T) {
+	e := newColumnEditor(t, "abcd\nabcd\nab\n", 3, 1)
+	gtx := layout.Context{Ops: new(op.Ops)}
+	// select columns 1-3 of each line.
+	_, start := e.ConvertPos(0, 1)
+	_, end := e.ConvertPos(0, 3)
+	for i := range e.columnEdit.selections {
+		cursor := &e.columnEdit.selections[i]
+		cursor.startX, cursor.endX = int(start.X), int(end.X)
+	}
+
+	e.onCopyCut(gtx, true)
+	if got, _ := e.ClipboardRing().Current(); got != "bc\nbc\nb" {
+		t.Fatalf("got clipboard %q, want one entry per caret", got)
+	}
+	if got, want := e.Text(), "ad\nad\na\n"; got != want {
+		t.Fatalf("got %q after the cut, want %q", got, want)
+	}
+
+	// the entries are distributed to the carets in order.
+	e.onPasteText("X\nY\nZ")
+	if got, want := e.Text(), "aXd\naYd\naZ\n"; got != want {
+		t.Fatalf("got %q after the paste, want %q", got, want)
+	}
+	// a number of entries not matching the carets is pasted at every caret.
+	e.onPasteText("1\n2")
+	if got, want := e.Text(), "aX1\n2d\naY1\n2d\naZ1\n2\n"; got != want {
+		t.Fatalf("got %q after the paste, want %q", got, want)
+	}
+	if _, ok := e.undo(); !ok || e.Text() != "aXd\naYd\naZ\n" {
+		t.Errorf("got %q after undoing the paste, want it undone in one step", e.Text())
+	}
+}
+
+func TestColumnClipboardLines(t *testing.T) {
+	e := newColumnEditor(t, "ab\ncd\nef\n", 2, 1)
+	gtx := layout.Context{Ops: new(op.Ops)}
+
+	// without a selection the lines of the carets are copied.
+	e.onCopyCut(gtx, false)
+	if got, _ := e.ClipboardRing().Current(); got != "ab\ncd\n" {
+		t.Fatalf("got clipboard %q, want the lines of the carets", got)
+	}
+
+	// the lines are pasted above the lines of the carets.
+	e.onPasteText("x\ny\n")
+	if got, want := e.Text(), "x\nab\ny\ncd\nef\n"; got != want {
+		t.Fatalf("got %q after the paste, want %q", got, want)
+	}
+
+	e.onCopyCut(gtx, true)
+	if got, want := e.Text(), "x\ny\nef\n"; got != want {
+		t.Fatalf("got %q after cutting the lines, want %q", got, want)
+	}
+	if e.columnEdit.selections != nil {
+		t.Errorf("got carets %+v after cutting their lines", e.columnEdit.selections)
+	}
+}
@@ -55,6 +55,29 @@ func (c Color) MulAlpha(alpha uint8) Color {
 	return c
 }
 
+// Mix blends the color with to by the ratio t in [0, 1], returning to when t
+// is 1. When one of the colors is unset, the other one is faded in or out.
+func (c Color) Mix(to Color, t float32) Color {
+	if t <= 0 {
+		return c
+	}
+	if t >= 1 {
+		return to
+	}
+
+	a, b := c.NRGBA(), to.NRGBA()
+	if !c.IsSet() {
+		a, a.A = b, 0
+	}
+	if !to.IsSet() {
+		b, b.A = a, 0
+	}
+	lerp := func(x, y uint8) uint8 {
+		return uint8(float32(x) + (float32(y)-float32(x))*t + 0.5)
+	}
+	return MakeColor(color.NRGBA{R: lerp(a.R, b.R), G: lerp(a.G, b.G), B: lerp(a.B, b.B), A: lerp(a.A, b.A)})
+}
+
 func (c Color) IsSet() bool {
 	return c.val != 0
 }
@@ -118,6 +141,11 @@ type ColorPalette struct {
 	LineColor Color
 	// Color used to paint the line number
 	LineNumberColor Color
+	// Color used to paint the line number of the current line. It defaults
+	// to LineNumberColor.
+	LineNumberActiveColor Color
+	// Color used to paint the background of the gutter.
+	GutterBackground Color
 	// Other colors.
 	colors []Color
 }
@@ -146,3 +174,30 @@ func (p *ColorPalette) AddColor(cl Color) int {
 func (p *ColorPalette) Clear() {
 	p.colors = p.colors[:0]
 }
+
+// Clone returns a copy of the palette which doesn't share its colors.
+func (p *ColorPalette) Clone() ColorPalette {
+	clone := *p
+	clone.colors = slices.Clone(p.colors)
+	return clone
+}
+
+// Mix returns the palette blending the colors of p with those of to by the
+// ratio t in [0, 1]. The colors added to the palettes are blended by their
+// IDs.
+func (p *ColorPalette) Mix(to *ColorPalette, t float32) ColorPalette {
+	mixed := ColorPalette{
+		Foreground:            p.Foreground.Mix(to.Foreground, t),
+		Background:            p.Background.Mix(to.Background, t),
+		SelectColor:           p.SelectColor.Mix(to.SelectColor, t),
+		LineColor:             p.LineColor.Mix(to.LineColor, t),
+		LineNumberColor:       p.LineNumberColor.Mix(to.LineNumberColor, t),
+		LineNumberActiveColor: p.LineNumberActiveColor.Mix(to.LineNumberActiveColor, t),
+		GutterBackground:      p.GutterBackground.Mix(to.GutterBackground, t),
+		colors:                make([]Color, max(len(p.colors), len(to.colors))),
+	}
+	for i := range mixed.colors {
+		mixed.colors[i] = p.GetColor(i).Mix(to.GetColor(i), t)
+	}
+	return mixed
+}
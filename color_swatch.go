@@ -0,0 +1,264 @@
+package gvcode
+
+import (
+	"image"
+	"image/color"
+	"maps"
+	"slices"
+	"sort"
+	"unicode/utf8"
+
+	"gioui.org/gesture"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/colorpicker"
+	lt "github.com/oligo/gvcode/internal/layout"
+	"github.com/oligo/gvcode/textview"
+)
+
+// swatchWidth is the space reserved before a color literal for its swatch.
+const swatchWidth = unit.Dp(16)
+
+// ColorPickEvent is generated when the user clicks the swatch painted before
+// a color literal. The host app can show a color picker, and replace the
+// literal with the picked color.
+type ColorPickEvent struct {
+	// Start and End are the rune offsets of the literal.
+	Start, End int
+	// Literal is the text of the literal, e.g., "#ff8800" or
+	// "rgb(255, 136, 0)".
+	Literal string
+	// Color is the color of the literal.
+	Color color.NRGBA
+}
+
+func (ColorPickEvent) isEditorEvent() {}
+
+// colorSwatches detects the color literals of the visible lines, and paints
+// a swatch of their color before them.
+type colorSwatches struct {
+	enabled  bool
+	detector *colorpicker.ColorDetector
+	// valid is true if the literals were detected in the text version and
+	// the paragraphs below.
+	valid    bool
+	version  int
+	visible  [2]int
+	literals []colorLiteral
+	// offsets reserve the space of the swatches in the layout. They map the
+	// screen lines to the rune offsets in the line and the width of the
+	// space inserted before them.
+	offsets map[int]map[int]int
+	// applied are the offsets set to the layout, including those of the
+	// color indicator provider.
+	applied map[int]map[int]int
+	// reapply forces setting the offsets to the layout of another document.
+	reapply bool
+	// areas are the clickable swatches of the last frame.
+	areas   []swatchArea
+	clicker gesture.Click
+}
+
+type colorLiteral struct {
+	start, end int
+	text       string
+	color      color.NRGBA
+	// line and col are the screen line of the literal, and its rune offset
+	// in the line.
+	line, col int
+}
+
+type swatchArea struct {
+	bounds  image.Rectangle
+	literal colorLiteral
+}
+
+// reset forgets the literals and the offsets of the previous document.
+func (s *colorSwatches) reset() {
+	s.valid, s.reapply = false, true
+	s.literals, s.offsets, s.areas = s.literals[:0], nil, s.areas[:0]
+}
+
+// detectColorLiterals finds the hex and rgb() color literals of the visible
+// paragraphs, unless the text and the viewport are unchanged.
+func (e *Editor) detectColorLiterals() {
+	s := &e.swatches
+	first, last := e.visibleParagraphs()
+	version := e.TextVersion()
+	if s.valid && s.version == version && s.visible == [2]int{first, last} {
+		return
+	}
+	s.valid, s.version, s.visible = true, version, [2]int{first, last}
+	s.literals = s.literals[:0]
+	if first >= last {
+		return
+	}
+
+	if s.detector == nil {
+		s.detector = colorpicker.NewColorDetector()
+	}
+	paragraphs := e.text.TextLayout().Paragraphs
+	from := paragraphs[first].RuneOff
+	text := e.ReadRange(from, paragraphs[last-1].RuneOff+paragraphs[last-1].Runes)
+	for _, info := range s.detector.DetectColors(text) {
+		switch info.Format {
+		case colorpicker.ColorFormatHex3, colorpicker.ColorFormatHex4, colorpicker.ColorFormatHex6,
+			colorpicker.ColorFormatHex8, colorpicker.ColorFormatRGB, colorpicker.ColorFormatRGBA:
+		default:
+			continue
+		}
+		start := from + utf8.RuneCountInString(text[:info.Range.Start])
+		s.literals = append(s.literals, colorLiteral{
+			start: start,
+			end:   start + utf8.RuneCountInString(info.Original),
+			text:  info.Original,
+			color: info.Color,
+		})
+	}
+
+	// the detector returns the literals by format.
+	slices.SortFunc(s.literals, func(a, b colorLiteral) int { return a.start - b.start })
+	s.literals = slices.CompactFunc(s.literals, func(a, b colorLiteral) bool { return a.start < b.end && b.start < a.end })
+}
+
+// swatchOffsets returns the offsets reserving the space of the swatches of
+// the detected literals.
+func (e *Editor) swatchOffsets(gtx layout.Context) map[int]map[int]int {
+	s := &e.swatches
+	if len(s.literals) == 0 {
+		return nil
+	}
+
+	width := gtx.Dp(swatchWidth)
+	lines := e.text.TextLayout().Lines
+	offsets := make(map[int]map[int]int)
+	for i, lit := range s.literals {
+		idx := sort.Search(len(lines), func(i int) bool { return lines[i].RuneOff+lines[i].Runes > lit.start })
+		if idx >= len(lines) {
+			s.literals[i].line = -1
+			continue
+		}
+		if offsets[idx] == nil {
+			offsets[idx] = make(map[int]int)
+		}
+		s.literals[i].line, s.literals[i].col = idx, lit.start-lines[idx].RuneOff
+		offsets[idx][s.literals[i].col] = width
+	}
+	return offsets
+}
+
+// mergeColorOffsets adds the offsets of the swatches to the offsets of the
+// color indicators, and sets them to the layout if they changed.
+func (e *Editor) mergeColorOffsets(offsets map[int]map[int]int) {
+	s := &e.swatches
+	for line, lineOffsets := range s.offsets {
+		if offsets == nil {
+			offsets = make(map[int]map[int]int)
+		}
+		if offsets[line] == nil {
+			offsets[line] = make(map[int]int)
+		}
+		for col, width := range lineOffsets {
+			offsets[line][col] += width
+		}
+	}
+
+	// setting the offsets lays out the text again.
+	if !s.reapply && maps.EqualFunc(offsets, s.applied, maps.Equal) {
+		return
+	}
+	s.applied, s.reapply = offsets, false
+	e.text.SetColorOffsets(offsets)
+}
+
+// paintColorSwatches paints the swatches of the color literals of the
+// visible lines, in the space reserved before them, and generates a
+// ColorPickEvent when one is clicked.
+func (e *Editor) paintColorSwatches(gtx layout.Context, border gvcolor.Color) {
+	s := &e.swatches
+	s.areas = s.areas[:0]
+
+	var offsets map[int]map[int]int
+	if s.enabled {
+		e.detectColorLiterals()
+		offsets = e.swatchOffsets(gtx)
+	}
+	if !maps.EqualFunc(offsets, s.offsets, maps.Equal) {
+		// the space of the swatches is reserved in the next layout.
+		s.offsets = offsets
+		gtx.Execute(op.InvalidateCmd{})
+		return
+	}
+	if !s.enabled {
+		return
+	}
+
+	width := gtx.Dp(swatchWidth)
+	lines := e.text.TextLayout().Lines
+	var regions []textview.Region
+	for _, lit := range s.literals {
+		if lit.line < 0 || lit.line >= len(lines) {
+			continue
+		}
+		glyph := lineGlyphAt(lines[lit.line], lit.col)
+		regions = e.text.Regions(lit.start, lit.start+1, regions[:0])
+		if glyph == nil || len(regions) == 0 {
+			continue
+		}
+		// the space of the swatch is before the first glyph of the literal,
+		// which ends the region of its first rune.
+		r := regions[0].Bounds
+		side := min(width-gtx.Dp(4), r.Dy()*7/10)
+		box := image.Rectangle{Min: image.Point{
+			X: r.Max.X - glyph.Advance.Round() - width + (width-side)/2,
+			Y: r.Min.Y + (r.Dy()-side)/2,
+		}}
+		box.Max = box.Min.Add(image.Pt(side, side))
+
+		paint.FillShape(gtx.Ops, border.NRGBA(), clip.Rect(box).Op())
+		paint.FillShape(gtx.Ops, lit.color, clip.Rect(box.Inset(1)).Op())
+		area := clip.Rect(box).Push(gtx.Ops)
+		pointer.CursorPointer.Add(gtx.Ops)
+		s.clicker.Add(gtx.Ops)
+		area.Pop()
+		s.areas = append(s.areas, swatchArea{bounds: box, literal: lit})
+	}
+
+	for {
+		evt, ok := s.clicker.Update(gtx.Source)
+		if !ok {
+			break
+		}
+		if evt.Kind != gesture.KindClick {
+			continue
+		}
+
+		pos := evt.Position
+		for _, area := range s.areas {
+			if pos.In(area.bounds) {
+				lit := area.literal
+				e.pending = append(e.pending, ColorPickEvent{Start: lit.start, End: lit.end, Literal: lit.text, Color: lit.color})
+				break
+			}
+		}
+	}
+}
+
+// lineGlyphAt returns the glyph of the rune at the offset col of a screen
+// line.
+func lineGlyphAt(line lt.Line, col int) *text.Glyph {
+	runes := 0
+	for _, g := range line.Glyphs {
+		if runes >= col {
+			return g
+		}
+		runes += int(g.Runes)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package gvcode
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+func TestColorSwatches(t *testing.T) {
+	e := &Editor{}
+	e.WithOptions(WithColorScheme(syntax.ColorScheme{}), WithTextSize(14), WithColorSwatches(true))
+	e.SetText("a { color: #ff8800; }\nb { background: rgb(0, 0, 255); }\n// issue 12\n")
+
+	var router input.Router
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	frame := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 200)), Source: router.Source()}
+		e.Layout(gtx, shaper)
+		router.Frame(gtx.Ops)
+	}
+	literalX := func(start int) int {
+		regions := e.text.Regions(start, start+1, nil)
+		if len(regions) == 0 {
+			t.Fatalf("no region at %d", start)
+		}
+		return regions[0].Bounds.Max.X
+	}
+
+	frame()
+	hexStart, rgbStart := 11, 38
+	before := literalX(hexStart)
+	frame()
+
+	if len(e.swatches.literals) != 2 {
+		t.Fatalf("got %d literals, want 2: %+v", len(e.swatches.literals), e.swatches.literals)
+	}
+	hex, rgb := e.swatches.literals[0], e.swatches.literals[1]
+	if hex.start != hexStart || hex.end != hexStart+7 || hex.color != (color.NRGBA{R: 0xff, G: 0x88, A: 0xff}) {
+		t.Errorf("got hex literal %+v", hex)
+	}
+	if rgb.start != rgbStart || rgb.text != "rgb(0, 0, 255)" {
+		t.Errorf("got rgb literal %+v", rgb)
+	}
+	// the space of the swatch is reserved before the literal.
+	if after := literalX(hexStart); after <= before {
+		t.Errorf("literal at x=%d, want it moved after x=%d", after, before)
+	}
+	if len(e.swatches.areas) != 2 {
+		t.Fatalf("got %d swatches painted, want 2", len(e.swatches.areas))
+	}
+
+	// clicking a swatch generates a ColorPickEvent.
+	center := e.swatches.areas[1].bounds.Min.Add(e.swatches.areas[1].bounds.Size().Div(2))
+	pos := f32.Pt(float32(center.X), float32(center.Y))
+	router.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: pos},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: pos},
+	)
+	frame()
+	var got *ColorPickEvent
+	for {
+		evt, ok := e.Update(layout.Context{Ops: new(op.Ops), Source: router.Source()})
+		if !ok {
+			break
+		}
+		if pick, ok := evt.(ColorPickEvent); ok {
+			got = &pick
+		}
+	}
+	if got == nil {
+		t.Fatal("expected a ColorPickEvent")
+	}
+	if got.Start != rgbStart || got.Literal != "rgb(0, 0, 255)" || got.Color != (color.NRGBA{B: 0xff, A: 0xff}) {
+		t.Errorf("got %+v", *got)
+	}
+
+	// disabling the swatches removes their space.
+	e.WithOptions(WithColorSwatches(false))
+	frame()
+	frame()
+	if x := literalX(hexStart); x != before {
+		t.Errorf("literal at x=%d, want x=%d", x, before)
+	}
+}
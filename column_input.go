@@ -0,0 +1,222 @@
+package gvcode
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"gioui.org/io/key"
+	"github.com/oligo/gvcode/internal/buffer"
+)
+
+// columnComposition tracks the region of the last text input applied at the
+// primary column caret. Input methods compose text by repeatedly replacing
+// the region, e.g. "n" -> "ni" -> "你", so a later edit inside of the region
+// is applied to the mirrored region of each secondary caret instead of being
+// inserted at the secondary caret again.
+type columnComposition struct {
+	// start and end are the rune offsets of the region at the primary caret.
+	start, end int
+	// caret and version are the caret position and the buffer version left
+	// by the last input. The composition is broken if either of them has
+	// changed since then.
+	caret   int
+	version int
+	active  bool
+}
+
+// syncColumnCaret moves the editor caret to the first column cursor, which is
+// the primary caret that input methods are told about.
+func (e *Editor) syncColumnCaret() {
+	if len(e.columnEdit.selections) == 0 {
+		return
+	}
+
+	cursor := e.columnEdit.selections[0]
+	off := e.text.ConvertPos(cursor.line, cursor.col)
+	e.text.SetCaret(off, off)
+}
+
+// onColumnTextInput applies the edit event at the primary caret, and mirrors
+// it to the secondary column carets. Edits that continue an ongoing
+// composition replace the mirrored composition region of each secondary
+// caret, keeping all the carets in sync until the input method commits.
+func (e *Editor) onColumnTextInput(ke key.EditEvent) {
+	selections := e.columnEdit.selections
+	if strings.Contains(ke.Text, "\n") {
+		// A line break breaks the column layout, so only the primary caret
+		// takes the input.
+		e.clearColumnEdit()
+		e.replace(ke.Range.Start, ke.Range.End, ke.Text)
+		return
+	}
+
+	caret, _ := e.text.Selection()
+	comp := &e.columnEdit.compose
+	update := comp.active && comp.caret == caret && comp.version == e.buffer.Version() &&
+		ke.Range.Start >= comp.start && ke.Range.End <= comp.end
+
+	// The secondary carets are on the lines below the primary one.
+	inserted := utf8.RuneCountInString(ke.Text)
+	edits := []Edit{{Start: ke.Range.Start, End: ke.Range.End, Text: ke.Text}}
+	for i := 1; i < len(selections); i++ {
+		cursor := &selections[i]
+		lineStart := e.text.ConvertPos(cursor.line, 0)
+		lineLen := e.columnLineEnd(cursor.line) - lineStart
+
+		var from, to int
+		if update {
+			from = cursor.composeCol + ke.Range.Start - comp.start
+			to = cursor.composeCol + ke.Range.End - comp.start
+		} else {
+			from = cursor.col + ke.Range.Start - caret
+			to = cursor.col + ke.Range.End - caret
+		}
+		from = min(max(from, 0), lineLen)
+		to = min(max(to, from), lineLen)
+		if !update {
+			cursor.composeCol = from
+		}
+
+		edits = append(edits, Edit{Start: lineStart + from, End: lineStart + to, Text: ke.Text})
+		cursor.col = from + inserted
+	}
+	if _, result := e.replaceEdits(edits); result != editApplied {
+		comp.active = false
+		return
+	}
+
+	newCaret := ke.Range.Start + inserted
+	if update {
+		comp.end += inserted - (ke.Range.End - ke.Range.Start)
+	} else {
+		comp.start = ke.Range.Start
+		comp.end = newCaret
+		comp.active = true
+	}
+
+	primary := &selections[0]
+	primary.col = newCaret - e.text.ConvertPos(primary.line, 0)
+	for i := range selections {
+		e.collapseColumnCursor(&selections[i], selections[i].line, selections[i].col)
+	}
+
+	e.text.SetCaret(newCaret, newCaret)
+	comp.caret = newCaret
+	comp.version = e.buffer.Version()
+}
+
+// columnPairAction is how a bracket or a quote typed with multiple column
+// carets is applied at one of them.
+type columnPairAction int
+
+const (
+	// columnInsert inserts the typed rune at the caret.
+	columnInsert columnPairAction = iota
+	// columnOvertype moves the caret past the closing part auto-inserted at it.
+	columnOvertype
+	// columnAutoClose inserts the pair, with the caret between its parts.
+	columnAutoClose
+	// columnSurround surrounds the selection of the caret with the pair.
+	columnSurround
+)
+
+// columnPairEdit tracks the edit of a column caret with markers, as the
+// edits at the other carets shift its offsets.
+type columnPairEdit struct {
+	action columnPairAction
+	// start and end are the selection of the caret, and caret is the caret
+	// offset relative to start.
+	start, end *buffer.Marker
+	caret      int
+}
+
+// onColumnPairInput applies the bracket or the quote r typed with multiple
+// column carets, deciding at each caret independently like for a single
+// caret: the selection is surrounded with the pair, the auto-inserted closing
+// part at the caret is overtyped, or the pair is auto-closed. It reports false
+// if the input is left to onColumnTextInput, e.g., when an input method
+// replaces the text it is composing.
+func (e *Editor) onColumnPairInput(ke key.EditEvent, r, counterpart rune, isOpening bool) bool {
+	if caret, _ := e.text.Selection(); ke.Text != string(r) || ke.Range.Start != caret || ke.Range.End != caret {
+		return false
+	}
+
+	selections := e.columnEdit.selections
+	edits := make([]columnPairEdit, len(selections))
+	defer func() {
+		for _, edit := range edits {
+			e.buffer.RemoveMarker(edit.start)
+			e.buffer.RemoveMarker(edit.end)
+		}
+	}()
+
+	for i, cursor := range selections {
+		caret := e.text.ConvertPos(cursor.line, 0) + cursor.col
+		start, end := e.columnCursorRange(cursor)
+		if start == end {
+			start, end = caret, caret
+		}
+
+		edit := &edits[i]
+		switch {
+		case start == end && (!isOpening || counterpart == r) && e.takeAutoInsertion(caret, r):
+			edit.action = columnOvertype
+		case start != end && isOpening:
+			edit.action = columnSurround
+		case isOpening && e.shouldAutoClose(caret, r, counterpart):
+			edit.action = columnAutoClose
+		}
+		// the pair is inserted between the markers.
+		var err error
+		if edit.start, err = e.buffer.CreateMarker(start, buffer.BiasForward); err != nil {
+			return false
+		}
+		if edit.end, err = e.buffer.CreateMarker(end, buffer.BiasBackward); err != nil {
+			return false
+		}
+		edit.caret = min(max(caret, start), end) - start
+	}
+
+	var replacements []Edit
+	for _, edit := range edits {
+		switch edit.action {
+		case columnInsert:
+			replacements = append(replacements, Edit{Start: edit.end.Offset(), End: edit.end.Offset(), Text: string(r)})
+		case columnAutoClose:
+			replacements = append(replacements, Edit{Start: edit.end.Offset(), End: edit.end.Offset(), Text: string(r) + string(counterpart)})
+		case columnSurround:
+			replacements = append(replacements,
+				Edit{Start: edit.start.Offset(), End: edit.start.Offset(), Text: string(r)},
+				Edit{Start: edit.end.Offset(), End: edit.end.Offset(), Text: string(counterpart)})
+		}
+	}
+	if _, result := e.replaceEdits(replacements); result != editApplied {
+		return true
+	}
+
+	for i, edit := range edits {
+		cursor := &selections[i]
+		start, end := edit.start.Offset(), edit.end.Offset()
+		switch edit.action {
+		case columnAutoClose:
+			e.trackAutoInsertion(end+1, counterpart)
+			fallthrough
+		case columnInsert, columnOvertype:
+			// the caret is after the typed rune.
+			caret := end + 1
+			line, p := e.text.FindParagraph(caret)
+			e.collapseColumnCursor(cursor, line, caret-p.RuneOff)
+		case columnSurround:
+			// the selection is kept on the surrounded text.
+			line, p := e.text.FindParagraph(start)
+			e.collapseColumnCursor(cursor, line, end-p.RuneOff)
+			_, pos := e.ConvertPos(line, start-p.RuneOff)
+			cursor.startX = int(pos.X)
+			cursor.col = start + edit.caret - p.RuneOff
+		}
+	}
+
+	e.columnEdit.compose.active = false
+	e.syncColumnCaret()
+	return true
+}
@@ -0,0 +1,187 @@
+package gvcode
+
+import (
+	"testing"
+
+	"gioui.org/io/key"
+)
+
+func newColumnEditor(t *testing.T, content string, lines, col int) *Editor {
+	t.Helper()
+	e := newTestEditor(t, content)
+
+	e.SetColumnEditMode(true)
+	for line := range lines {
+		e.columnEdit.selections = append(e.columnEdit.selections, columnCursor{line: line, col: col})
+	}
+	e.syncColumnCaret()
+	return e
+}
+
+// typeIME sends edit events the way an input method does, each replacing
+// the range relative to the composition start at the primary caret.
+func typeIME(e *Editor, events ...key.EditEvent) {
+	for _, ke := range events {
+		e.onTextInput(ke)
+	}
+}
+
+func TestColumnInputChinese(t *testing.T) {
+	e := newColumnEditor(t, "abc\nabc\nabc", 3, 3)
+
+	typeIME(e,
+		// pinyin "ni", committed as 你.
+		key.EditEvent{Range: key.Range{Start: 3, End: 3}, Text: "n"},
+		key.EditEvent{Range: key.Range{Start: 3, End: 4}, Text: "ni"},
+		key.EditEvent{Range: key.Range{Start: 3, End: 5}, Text: "你"},
+		// pinyin "hao", committed as 好.
+		key.EditEvent{Range: key.Range{Start: 4, End: 4}, Text: "h"},
+		key.EditEvent{Range: key.Range{Start: 4, End: 5}, Text: "ha"},
+		key.EditEvent{Range: key.Range{Start: 4, End: 6}, Text: "hao"},
+		key.EditEvent{Range: key.Range{Start: 4, End: 7}, Text: "好"},
+	)
+
+	if got, want := e.Text(), "abc你好\nabc你好\nabc你好"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if start, end := e.Selection(); start != 5 || end != 5 {
+		t.Errorf("primary caret at (%d, %d), want 5", start, end)
+	}
+	for i, cursor := range e.columnEdit.selections {
+		if cursor.line != i || cursor.col != 5 {
+			t.Errorf("caret %d at (%d, %d), want (%d, 5)", i, cursor.line, cursor.col, i)
+		}
+	}
+}
+
+func TestColumnInputKorean(t *testing.T) {
+	e := newColumnEditor(t, "ab\nab", 2, 1)
+
+	typeIME(e,
+		// jamo are combined into the syllable in place.
+		key.EditEvent{Range: key.Range{Start: 1, End: 1}, Text: "ㅎ"},
+		key.EditEvent{Range: key.Range{Start: 1, End: 2}, Text: "하"},
+		key.EditEvent{Range: key.Range{Start: 1, End: 2}, Text: "한"},
+		// the next consonant commits 한 and starts a new syllable.
+		key.EditEvent{Range: key.Range{Start: 2, End: 2}, Text: "ㄱ"},
+		key.EditEvent{Range: key.Range{Start: 2, End: 3}, Text: "그"},
+		key.EditEvent{Range: key.Range{Start: 2, End: 3}, Text: "글"},
+	)
+
+	if got, want := e.Text(), "a한글b\na한글b"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestColumnInputBrokenComposition(t *testing.T) {
+	e := newColumnEditor(t, "abc\nabc", 2, 3)
+
+	typeIME(e, key.EditEvent{Range: key.Range{Start: 3, End: 3}, Text: "x"})
+	// moving the carets breaks the composition, so the following edit is
+	// applied relative to each caret.
+	e.moveColumnCarets(-1)
+	typeIME(e, key.EditEvent{Range: key.Range{Start: 2, End: 3}, Text: "y"})
+
+	if got, want := e.Text(), "abyx\nabyx"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+// typeColumns types text at the primary column caret, one rune at a time.
+func typeColumns(e *Editor, text string) {
+	for _, r := range text {
+		caret, _ := e.Selection()
+		e.onTextInput(key.EditEvent{Range: key.Range{Start: caret, End: caret}, Text: string(r)})
+	}
+}
+
+// assertColumnCarets checks that the column carets are at the column col of
+// the consecutive lines from the first one.
+func assertColumnCarets(t *testing.T, e *Editor, col int) {
+	t.Helper()
+	for i, cursor := range e.columnEdit.selections {
+		if cursor.line != i || cursor.col != col {
+			t.Errorf("caret %d at (%d, %d), want (%d, %d)", i, cursor.line, cursor.col, i, col)
+		}
+	}
+}
+
+func TestColumnAutoClosePairs(t *testing.T) {
+	e := newColumnEditor(t, "f\nf\nf", 3, 1)
+
+	typeColumns(e, "(")
+	if got, want := e.Text(), "f()\nf()\nf()"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	assertColumnCarets(t, e, 2)
+
+	// typing inside of the pairs keeps them tracked, and the closing parts
+	// are overtyped.
+	typeColumns(e, "x)")
+	if got, want := e.Text(), "f(x)\nf(x)\nf(x)"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	assertColumnCarets(t, e, 4)
+
+	// deleting an opening part deletes its auto-inserted closing part.
+	typeColumns(e, "[")
+	e.onColumnEditDelete(-1)
+	if got, want := e.Text(), "f(x)\nf(x)\nf(x)"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// the pairs typed at all the carets are undone in a single step.
+	e.undo()
+	if got, want := e.Text(), "f(x)[]\nf(x)[]\nf(x)[]"; got != want {
+		t.Fatalf("undo: got %q, want %q", got, want)
+	}
+}
+
+func TestColumnAutoCloseEachCaret(t *testing.T) {
+	// the pair is auto-closed at a caret unless it is followed by a word.
+	e := newColumnEditor(t, "ab\n\nab", 3, 0)
+
+	typeColumns(e, "(")
+	if got, want := e.Text(), "(ab\n()\n(ab"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	assertColumnCarets(t, e, 1)
+
+	// the closing part is only overtyped where it was auto-inserted.
+	typeColumns(e, ")")
+	if got, want := e.Text(), "()ab\n()\n()ab"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	assertColumnCarets(t, e, 2)
+}
+
+func TestColumnSurroundSelections(t *testing.T) {
+	e := newColumnEditor(t, "foo bar\nfoo baz\nfoo qux", 3, 0)
+	// select "foo" on every line.
+	for i := range e.columnEdit.selections {
+		cursor := &e.columnEdit.selections[i]
+		_, start := e.ConvertPos(cursor.line, 0)
+		_, end := e.ConvertPos(cursor.line, 3)
+		cursor.startX, cursor.endX = int(start.X), int(end.X)
+		cursor.col = 3
+	}
+
+	typeColumns(e, "[\"")
+	if got, want := e.Text(), "[\"foo\"] bar\n[\"foo\"] baz\n[\"foo\"] qux"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	// the selections are kept on the surrounded text, with the carets at
+	// their ends.
+	assertColumnCarets(t, e, 5)
+	for i, cursor := range e.columnEdit.selections {
+		start, end := e.columnCursorRange(cursor)
+		if got := e.ReadRange(start, end); got != "foo" {
+			t.Errorf("selection %d is %q, want \"foo\"", i, got)
+		}
+	}
+
+	e.undo()
+	if got, want := e.Text(), "[foo] bar\n[foo] baz\n[foo] qux"; got != want {
+		t.Fatalf("undo: got %q, want %q", got, want)
+	}
+}
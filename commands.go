@@ -3,7 +3,6 @@ package gvcode
 import (
 	"slices"
 
-	"gioui.org/io/clipboard"
 	"gioui.org/io/key"
 	"gioui.org/io/system"
 	"gioui.org/layout"
@@ -22,17 +21,14 @@ type keyCommand struct {
 
 // RegisterCommand register an extra command handler responding to key events.
 // If there is an existing handler, it appends to the existing ones. Only the
-// last key filter is checked during event handling. This method is expected to
-// be invoked dynamically during layout.
+// last key filter is checked during event handling, and the key bindings of the
+// keymap with the same key name are disabled until the handlers are removed.
+// This method is expected to be invoked dynamically during layout.
 func (e *Editor) RegisterCommand(srcTag any, filter key.Filter, handler CommandHandler) {
 	if e.commands == nil {
 		e.commands = make(map[key.Name][]keyCommand)
 	}
 
-	if len(e.commands) == 0 {
-		e.buildBuiltinCommands()
-	}
-
 	if srcTag == nil || handler == nil {
 		return
 	}
@@ -65,346 +61,459 @@ func (e *Editor) RemoveCommands(tag any) {
 	}
 }
 
-func (e *Editor) buildBuiltinCommands() {
-	if e.commands == nil {
-		e.commands = make(map[key.Name][]keyCommand)
-	}
-	clear(e.commands)
-
-	registerCommand := func(filter key.Filter, handler CommandHandler) {
-		filter.Focus = e
-		e.commands[filter.Name] = append(e.commands[filter.Name], keyCommand{filter: filter, handler: handler})
-	}
-
-	registerCommand(key.Filter{Focus: e, Name: key.NameEnter, Optional: key.ModShift},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			return e.onInsertLineBreak(evt)
-		},
-	)
-
-	registerCommand(key.Filter{Focus: e, Name: key.NameReturn, Optional: key.ModShift},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			return e.onInsertLineBreak(evt)
-		},
-	)
-
-	registerCommand(key.Filter{Focus: e, Name: "C", Required: key.ModShortcut},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			return e.onCopyCut(gtx, evt)
-		},
-	)
-
-	// Initiate a paste operation, by requesting the clipboard contents; other
-	// half is in Editor.processKey() under clipboard.Event.
-	registerCommand(key.Filter{Focus: e, Name: "V", Required: key.ModShortcut},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			if e.mode != ModeReadOnly {
-				gtx.Execute(clipboard.ReadCmd{Tag: e})
+func (e *Editor) processCommands(gtx layout.Context) EditorEvent {
+	for _, cmds := range e.commands {
+		cmd := cmds[len(cmds)-1]
+		for {
+			ke, ok := gtx.Event(cmd.filter)
+			if !ok {
+				break
 			}
-			return nil
-		})
 
-	registerCommand(key.Filter{Focus: e, Name: "X", Required: key.ModShortcut},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			return e.onCopyCut(gtx, evt)
-		})
-
-	registerCommand(key.Filter{Focus: e, Name: "Z", Required: key.ModShortcut, Optional: key.ModShift},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			if e.mode != ModeReadOnly {
-				if evt.Modifiers.Contain(key.ModShift) {
-					if ev, ok := e.redo(); ok {
-						return ev
-					}
-				} else {
-					if ev, ok := e.undo(); ok {
-						return ev
-					}
+			e.blinkStart = gtx.Now
+			if ke, ok := ke.(key.Event); ok {
+				if !gtx.Focused(e) || ke.State != key.Press {
+					break
+				}
+				e.scrollCaret = true
+				e.scroller.Stop()
+				// the key is not a stroke of the keymap chord.
+				e.pendingKeys = nil
+				if cmd.tag == nil || cmd.tag == e {
+					e.cancelCompletor()
 				}
-			}
-			return nil
-		})
-
-	registerCommand(key.Filter{Focus: e, Name: "A", Required: key.ModShortcut},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			e.text.SetCaret(0, e.text.Len())
-			return nil
-		})
 
-	registerCommand(key.Filter{Focus: e, Name: "D", Required: key.ModShortcut},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			if e.mode != ModeReadOnly {
-				if e.DuplicateLine() != 0 {
-					return ChangeEvent{}
+				if !ke.Modifiers.Contain(cmd.filter.Required) {
+					break
 				}
-			}
-			return nil
-		})
 
-	registerCommand(key.Filter{Focus: e, Name: key.NameHome, Optional: key.ModShortcut | key.ModShift},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			selAct := textview.SelectionClear
-			if evt.Modifiers.Contain(key.ModShift) {
-				selAct = textview.SelectionExtend
+				if evt := cmd.handler(gtx, ke); evt != nil {
+					return evt
+				}
 			}
+		}
+	}
 
-			if evt.Modifiers.Contain(key.ModShortcut) {
-				e.text.MoveTextStart(selAct)
-			} else {
-				e.text.MoveLineStart(selAct)
-			}
-			return nil
-		})
+	return e.processKeymap(gtx)
+}
 
-	registerCommand(key.Filter{Focus: e, Name: key.NameEnd, Optional: key.ModShortcut | key.ModShift},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			selAct := textview.SelectionClear
-			if evt.Modifiers.Contain(key.ModShift) {
-				selAct = textview.SelectionExtend
+// The built-in commands of the editor, bound by DefaultKeymap.
+var (
+	InsertLineBreak = Command{Name: "insertLineBreak", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		return e.onInsertLineBreak(key.Event{})
+	}}
+	Copy = Command{Name: "copy", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		return e.onCopyCut(gtx, false)
+	}}
+	Cut = Command{Name: "cut", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		return e.onCopyCut(gtx, true)
+	}}
+	// Paste initiates a paste operation, by requesting the clipboard contents;
+	// other half is in Editor.processKey() under clipboard.Event.
+	Paste = Command{Name: "paste", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		if e.mode != ModeReadOnly {
+			e.clipboardRead = nil
+			e.readHostClipboard(gtx)
+		}
+		return nil
+	}}
+	Undo = Command{Name: "undo", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		if e.mode != ModeReadOnly {
+			if ev, ok := e.undo(); ok {
+				return ev
 			}
-			if evt.Modifiers.Contain(key.ModShortcut) {
-				e.text.MoveTextEnd(selAct)
-			} else {
-				e.text.MoveLineEnd(selAct)
+		}
+		return nil
+	}}
+	Redo = Command{Name: "redo", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		if e.mode != ModeReadOnly {
+			if ev, ok := e.redo(); ok {
+				return ev
 			}
+		}
+		return nil
+	}}
+	SelectAll = Command{Name: "selectAll", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.text.SetCaret(0, e.text.Len())
+		return nil
+	}}
+	DuplicateLine = Command{Name: "duplicateLine", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		if e.mode != ModeReadOnly && e.DuplicateLine() != 0 {
+			return ChangeEvent{}
+		}
+		return nil
+	}}
+	ToggleLineComment = Command{Name: "toggleLineComment", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		return changeEventIf(e.ToggleLineComment())
+	}}
+	ToggleBlockComment = Command{Name: "toggleBlockComment", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		return changeEventIf(e.ToggleBlockComment())
+	}}
+	Indent = Command{Name: "indent", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		return e.onTab(false)
+	}}
+	Unindent = Command{Name: "unindent", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		return e.onTab(true)
+	}}
+	DeleteBackward = Command{Name: "deleteBackward", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		return changeEventIf(e.mode != ModeReadOnly && e.Delete(-1) != 0)
+	}}
+	DeleteForward = Command{Name: "deleteForward", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		return changeEventIf(e.mode != ModeReadOnly && e.Delete(1) != 0)
+	}}
+	DeleteWordBackward = Command{Name: "deleteWordBackward", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		return changeEventIf(e.mode != ModeReadOnly && e.deleteWord(-1) != 0)
+	}}
+	DeleteWordForward = Command{Name: "deleteWordForward", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		return changeEventIf(e.mode != ModeReadOnly && e.deleteWord(1) != 0)
+	}}
+
+	MoveLeft        = horizontalMoveCommand("moveLeft", -1, false, false)
+	MoveRight       = horizontalMoveCommand("moveRight", 1, false, false)
+	MoveWordLeft    = horizontalMoveCommand("moveWordLeft", -1, true, false)
+	MoveWordRight   = horizontalMoveCommand("moveWordRight", 1, true, false)
+	SelectLeft      = horizontalMoveCommand("selectLeft", -1, false, true)
+	SelectRight     = horizontalMoveCommand("selectRight", 1, false, true)
+	SelectWordLeft  = horizontalMoveCommand("selectWordLeft", -1, true, true)
+	SelectWordRight = horizontalMoveCommand("selectWordRight", 1, true, true)
+	MoveUp          = verticalMoveCommand("moveUp", -1, false)
+	MoveDown        = verticalMoveCommand("moveDown", 1, false)
+	SelectUp        = verticalMoveCommand("selectUp", -1, true)
+	SelectDown      = verticalMoveCommand("selectDown", 1, true)
+
+	MoveLineStart = Command{Name: "moveLineStart", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.text.MoveLineStart(textview.SelectionClear)
+		return nil
+	}}
+	MoveLineEnd = Command{Name: "moveLineEnd", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.text.MoveLineEnd(textview.SelectionClear)
+		return nil
+	}}
+	MoveTextStart = Command{Name: "moveTextStart", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.text.MoveTextStart(textview.SelectionClear)
+		return nil
+	}}
+	MoveTextEnd = Command{Name: "moveTextEnd", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.text.MoveTextEnd(textview.SelectionClear)
+		return nil
+	}}
+	// MoveBlockDown moves the caret to the blank line after the next block
+	// of lines.
+	MoveBlockDown = Command{Name: "moveBlockDown", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.text.MoveBlocks(1, textview.SelectionClear)
+		return nil
+	}}
+	// MoveBlockUp moves the caret to the blank line before the previous block
+	// of lines.
+	MoveBlockUp = Command{Name: "moveBlockUp", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.text.MoveBlocks(-1, textview.SelectionClear)
+		return nil
+	}}
+	// MoveToMatchingBracket moves the caret to the counterpart of the bracket
+	// next to it, or to the closing bracket enclosing it.
+	MoveToMatchingBracket = Command{Name: "moveToMatchingBracket", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.text.MoveToMatchingBracket(textview.SelectionClear)
+		return nil
+	}}
+	SelectBlockDown = Command{Name: "selectBlockDown", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.text.MoveBlocks(1, textview.SelectionExtend)
+		return nil
+	}}
+	SelectBlockUp = Command{Name: "selectBlockUp", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.text.MoveBlocks(-1, textview.SelectionExtend)
+		return nil
+	}}
+	SelectToMatchingBracket = Command{Name: "selectToMatchingBracket", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.text.MoveToMatchingBracket(textview.SelectionExtend)
+		return nil
+	}}
+	SelectLineStart = Command{Name: "selectLineStart", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.text.MoveLineStart(textview.SelectionExtend)
+		return nil
+	}}
+	SelectLineEnd = Command{Name: "selectLineEnd", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.text.MoveLineEnd(textview.SelectionExtend)
+		return nil
+	}}
+	SelectTextStart = Command{Name: "selectTextStart", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.text.MoveTextStart(textview.SelectionExtend)
+		return nil
+	}}
+	SelectTextEnd = Command{Name: "selectTextEnd", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.text.MoveTextEnd(textview.SelectionExtend)
+		return nil
+	}}
+
+	PageUp             = pageCommand("pageUp", -2, false)
+	PageDown           = pageCommand("pageDown", 2, false)
+	SelectPageUp       = pageCommand("selectPageUp", -2, true)
+	SelectPageDown     = pageCommand("selectPageDown", 2, true)
+	HalfPageUp         = pageCommand("halfPageUp", -1, false)
+	HalfPageDown       = pageCommand("halfPageDown", 1, false)
+	SelectHalfPageUp   = pageCommand("selectHalfPageUp", -1, true)
+	SelectHalfPageDown = pageCommand("selectHalfPageDown", 1, true)
+
+	// SelectNextOccurrence adds the next occurrence of the selection as a
+	// new selection, or selects the word at the caret.
+	SelectNextOccurrence = Command{Name: "selectNextOccurrence", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.SelectNextOccurrence()
+		return nil
+	}}
+	// SkipOccurrence moves the last added selection to the next occurrence.
+	SkipOccurrence = Command{Name: "skipOccurrence", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.SkipOccurrence()
+		return nil
+	}}
+	// SelectAllOccurrences selects all the occurrences of the selection.
+	SelectAllOccurrences = Command{Name: "selectAllOccurrences", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.SelectAllOccurrences()
+		return nil
+	}}
+	// ExpandSelection grows the selection to the enclosing syntactic unit.
+	ExpandSelection = Command{Name: "expandSelection", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.ExpandSelection()
+		return nil
+	}}
+	// ShrinkSelection reverts the last ExpandSelection.
+	ShrinkSelection = Command{Name: "shrinkSelection", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.ShrinkSelection()
+		return nil
+	}}
+
+	// ExitColumnEdit exits column editing mode, or drops the selections added
+	// besides the primary one, or ends linked editing.
+	ExitColumnEdit = Command{Name: "exitColumnEdit", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		if e.hasExtraSelections() {
+			e.clearExtraSelections()
 			return nil
-		})
-
-	registerCommand(key.Filter{Focus: e, Name: key.NameTab, Optional: key.ModShift},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			return e.onTab(evt)
-		})
-
-	registerCommand(key.Filter{Focus: e, Name: key.NameDeleteBackward, Optional: key.ModShortcutAlt | key.ModShift},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			if e.mode != ModeReadOnly {
-				moveByWord := evt.Modifiers.Contain(key.ModShortcutAlt)
-
-				if moveByWord {
-					if e.deleteWord(-1) != 0 {
-						return ChangeEvent{}
-					}
-				} else {
-					if e.Delete(-1) != 0 {
-						return ChangeEvent{}
-					}
-				}
-			}
+		}
+		if len(e.linked.regions) > 0 {
+			e.StopLinkedEdit()
 			return nil
-		})
-
-	registerCommand(key.Filter{Focus: e, Name: key.NameDeleteForward, Optional: key.ModShortcutAlt | key.ModShift},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			if e.mode != ModeReadOnly {
-				moveByWord := evt.Modifiers.Contain(key.ModShortcutAlt)
-				if moveByWord {
-					if e.deleteWord(1) != 0 {
-						return ChangeEvent{}
-					}
-				} else {
-					if e.Delete(1) != 0 {
-						return ChangeEvent{}
-					}
-				}
+		}
+		// Debug log for ESC key
+		println("[ColumnEdit] ESC key pressed, ColumnEditEnabled:", e.ColumnEditEnabled())
+		if e.ColumnEditEnabled() {
+			e.clearColumnEdit()
+			e.ClearSelection()
+			println("[ColumnEdit] Exited column editing mode")
+		}
+		return nil
+	}}
+	// ToggleColumnEdit toggles column editing mode.
+	ToggleColumnEdit = Command{Name: "toggleColumnEdit", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		// Debug log for Alt+C
+		println("[ColumnEdit] Alt+C pressed, current mode:", e.mode, "ReadOnly:", e.mode == ModeReadOnly)
+		if e.mode != ModeReadOnly {
+			wasEnabled := e.ColumnEditEnabled()
+			e.SetColumnEditMode(!wasEnabled)
+			isEnabled := e.ColumnEditEnabled()
+			println("[ColumnEdit] Toggled column editing mode - was:", wasEnabled, "now:", isEnabled)
+			if !isEnabled {
+				e.ClearSelection()
 			}
-			return nil
-		})
+		} else {
+			println("[ColumnEdit] Cannot enable column edit in ReadOnly mode")
+		}
+		return nil
+	}}
+
+	// ToggleWrapLine toggles the wrapping of the lines.
+	ToggleWrapLine = Command{Name: "toggleWrapLine", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.SetWrapLine(!e.WrapLineEnabled())
+		return nil
+	}}
+
+	// ToggleFold collapses or expands the fold at the caret line.
+	ToggleFold = Command{Name: "toggleFold", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.ToggleFold()
+		return nil
+	}}
+	// ToggleCharInspector shows or hides the character inspector.
+	ToggleCharInspector = Command{Name: "toggleCharInspector", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.SetCharInspectorVisible(!e.CharInspectorVisible())
+		return nil
+	}}
+	// GoToLine generates a GoToRequest for the host app to ask for the line
+	// to go to.
+	GoToLine = Command{Name: "goToLine", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		line, col := e.CaretPos()
+		return GoToRequest{Line: line, Col: col, Lines: e.Lines()}
+	}}
+	// FindNext selects the next match of the last search.
+	FindNext = Command{Name: "findNext", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.SelectNextMatch(false)
+		return nil
+	}}
+	// FindPrevious selects the previous match of the last search.
+	FindPrevious = Command{Name: "findPrevious", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.SelectNextMatch(true)
+		return nil
+	}}
+)
 
-	registerCommand(key.Filter{Focus: e, Name: key.NamePageDown, Optional: key.ModShift},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			selAct := textview.SelectionClear
-			if evt.Modifiers.Contain(key.ModShift) {
-				selAct = textview.SelectionExtend
+// DefaultKeymap returns a keymap with the default bindings of the built-in
+// commands.
+func DefaultKeymap() *Keymap {
+	km := NewKeymap()
+	for _, b := range []struct {
+		keys []string
+		cmd  Command
+	}{
+		{[]string{"Enter", "Shift+Enter", "Return", "Shift+Return"}, InsertLineBreak},
+		{[]string{"Shortcut+C"}, Copy},
+		{[]string{"Shortcut+X"}, Cut},
+		{[]string{"Shortcut+V"}, Paste},
+		{[]string{"Shortcut+Z"}, Undo},
+		{[]string{"Shortcut+Shift+Z"}, Redo},
+		{[]string{"Shortcut+A"}, SelectAll},
+		{[]string{"Shortcut+Shift+D"}, DuplicateLine},
+		{[]string{"Shortcut+D"}, SelectNextOccurrence},
+		{[]string{"Shortcut+Alt+D"}, SkipOccurrence},
+		{[]string{"Shortcut+Shift+L"}, SelectAllOccurrences},
+		{[]string{"Shortcut+W"}, ExpandSelection},
+		{[]string{"Shortcut+Shift+W"}, ShrinkSelection},
+		{[]string{"Shortcut+/"}, ToggleLineComment},
+		{[]string{"Shortcut+Shift+/"}, ToggleBlockComment},
+		{[]string{"Tab"}, Indent},
+		{[]string{"Shift+Tab"}, Unindent},
+		{[]string{"Backspace", "Shift+Backspace"}, DeleteBackward},
+		{[]string{"Delete", "Shift+Delete"}, DeleteForward},
+		{[]string{"ShortcutAlt+Backspace", "ShortcutAlt+Shift+Backspace"}, DeleteWordBackward},
+		{[]string{"ShortcutAlt+Delete", "ShortcutAlt+Shift+Delete"}, DeleteWordForward},
+		{[]string{"Left"}, MoveLeft},
+		{[]string{"Right"}, MoveRight},
+		{[]string{"ShortcutAlt+Left"}, MoveWordLeft},
+		{[]string{"ShortcutAlt+Right"}, MoveWordRight},
+		{[]string{"Shift+Left"}, SelectLeft},
+		{[]string{"Shift+Right"}, SelectRight},
+		{[]string{"ShortcutAlt+Shift+Left"}, SelectWordLeft},
+		{[]string{"ShortcutAlt+Shift+Right"}, SelectWordRight},
+		{[]string{"Up", "ShortcutAlt+Up"}, MoveUp},
+		{[]string{"Down", "ShortcutAlt+Down"}, MoveDown},
+		{[]string{"Shift+Up", "ShortcutAlt+Shift+Up"}, SelectUp},
+		{[]string{"Shift+Down", "ShortcutAlt+Shift+Down"}, SelectDown},
+		{[]string{"Home"}, MoveLineStart},
+		{[]string{"End"}, MoveLineEnd},
+		{[]string{"Shortcut+Home"}, MoveTextStart},
+		{[]string{"Shortcut+End"}, MoveTextEnd},
+		{[]string{"Shift+Home"}, SelectLineStart},
+		{[]string{"Shift+End"}, SelectLineEnd},
+		{[]string{"Shortcut+Shift+Home"}, SelectTextStart},
+		{[]string{"Shortcut+Shift+End"}, SelectTextEnd},
+		{[]string{"Alt+]"}, MoveBlockDown},
+		{[]string{"Alt+["}, MoveBlockUp},
+		{[]string{"Alt+Shift+]"}, SelectBlockDown},
+		{[]string{"Alt+Shift+["}, SelectBlockUp},
+		{[]string{"Shortcut+Shift+\\"}, MoveToMatchingBracket},
+		{[]string{"Shortcut+Alt+Shift+\\"}, SelectToMatchingBracket},
+		{[]string{"PageUp"}, PageUp},
+		{[]string{"PageDown"}, PageDown},
+		{[]string{"Shift+PageUp"}, SelectPageUp},
+		{[]string{"Shift+PageDown"}, SelectPageDown},
+		// Alt+PageUp/PageDown moves by half pages.
+		{[]string{"Alt+PageUp"}, HalfPageUp},
+		{[]string{"Alt+PageDown"}, HalfPageDown},
+		{[]string{"Alt+Shift+PageUp"}, SelectHalfPageUp},
+		{[]string{"Alt+Shift+PageDown"}, SelectHalfPageDown},
+		{[]string{"Esc"}, ExitColumnEdit},
+		{[]string{"Alt+C"}, ToggleColumnEdit},
+		{[]string{"Alt+Z"}, ToggleWrapLine},
+		{[]string{"Shortcut+Shift+["}, ToggleFold},
+		{[]string{"Shortcut+Shift+I"}, ToggleCharInspector},
+		{[]string{"F3"}, FindNext},
+		{[]string{"Shift+F3"}, FindPrevious},
+		{[]string{"Shortcut+G"}, GoToLine},
+	} {
+		for _, keys := range b.keys {
+			if err := km.Bind(keys, b.cmd); err != nil {
+				panic(err)
 			}
-			e.text.MovePages(+1, selAct)
+		}
+	}
+	return km
+}
+
+// changeEventIf returns a ChangeEvent if changed is true.
+func changeEventIf(changed bool) EditorEvent {
+	if changed {
+		return ChangeEvent{}
+	}
+	return nil
+}
+
+// horizontalMoveCommand creates a command moving the caret by one grapheme
+// cluster or word in direction, in the reading order of the locale. With
+// CaretVisual, the caret moves by grapheme cluster in the visual order.
+func horizontalMoveCommand(name string, direction int, byWord, extend bool) Command {
+	return Command{Name: name, Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		// Handle column editing mode: move all carets
+		if e.ColumnEditEnabled() && len(e.columnEdit.selections) > 0 {
+			e.moveColumnCarets(direction)
 			return nil
-		})
+		}
 
-	registerCommand(key.Filter{Focus: e, Name: key.NamePageUp, Optional: key.ModShift},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
+		if !byWord && e.caretMovement == CaretVisual {
 			selAct := textview.SelectionClear
-			if evt.Modifiers.Contain(key.ModShift) {
+			if extend {
 				selAct = textview.SelectionExtend
 			}
-			e.text.MovePages(-1, selAct)
+			e.text.MoveVisual(direction, selAct)
 			return nil
-		})
+		}
 
-	checkPos := func(gtx layout.Context) (bool, bool) {
 		caret, _ := e.text.Selection()
 		atBeginning := caret == 0
 		atEnd := caret == e.text.Len()
 		if gtx.Locale.Direction.Progression() != system.FromOrigin {
 			atEnd, atBeginning = atBeginning, atEnd
 		}
-		return atBeginning, atEnd
-	}
-
-	registerCommand(key.Filter{Focus: e, Name: key.NameLeftArrow, Optional: key.ModShortcutAlt | key.ModShift},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			// Handle column editing mode: move all carets left
-			if e.ColumnEditEnabled() && len(e.columnEdit.selections) > 0 {
-				e.moveColumnCarets(-1)
-				return nil
-			}
-
-			atBeginning, _ := checkPos(gtx)
-			if atBeginning {
-				return nil
-			}
-
-			moveByWord := evt.Modifiers.Contain(key.ModShortcutAlt)
-			direction := 1
-			if gtx.Locale.Direction.Progression() == system.TowardOrigin {
-				direction = -1
-			}
-			selAct := textview.SelectionClear
-			if evt.Modifiers.Contain(key.ModShift) {
-				selAct = textview.SelectionExtend
-			}
-
-			if moveByWord {
-				e.text.MoveWords(-1*direction, selAct)
-			} else {
-				if selAct == textview.SelectionClear {
-					e.text.ClearSelection()
-				}
-				e.text.MoveCaret(-1*direction, -1*direction*int(selAct))
-			}
-			return nil
-		})
-
-	registerCommand(key.Filter{Focus: e, Name: key.NameUpArrow, Optional: key.ModShortcutAlt | key.ModShift},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			atBeginning, _ := checkPos(gtx)
-			if atBeginning {
-				return nil
-			}
-
-			selAct := textview.SelectionClear
-			if evt.Modifiers.Contain(key.ModShift) {
-				selAct = textview.SelectionExtend
-			}
-			e.text.MoveLines(-1, selAct)
-			return nil
-		})
-
-	registerCommand(key.Filter{Focus: e, Name: key.NameRightArrow, Optional: key.ModShortcutAlt | key.ModShift},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			// Handle column editing mode: move all carets right
-			if e.ColumnEditEnabled() && len(e.columnEdit.selections) > 0 {
-				e.moveColumnCarets(1)
-				return nil
-			}
-
-			_, atEnd := checkPos(gtx)
-			if atEnd {
-				return nil
-			}
-
-			moveByWord := evt.Modifiers.Contain(key.ModShortcutAlt)
-			direction := 1
-			if gtx.Locale.Direction.Progression() == system.TowardOrigin {
-				direction = -1
-			}
-			selAct := textview.SelectionClear
-			if evt.Modifiers.Contain(key.ModShift) {
-				selAct = textview.SelectionExtend
-			}
-
-			if moveByWord {
-				e.text.MoveWords(1*direction, selAct)
-			} else {
-				if selAct == textview.SelectionClear {
-					e.text.ClearSelection()
-				}
-				e.text.MoveCaret(1*direction, int(selAct)*direction)
-			}
+		if (direction < 0 && atBeginning) || (direction > 0 && atEnd) {
 			return nil
-		})
-
-	registerCommand(key.Filter{Focus: e, Name: key.NameDownArrow, Optional: key.ModShortcutAlt | key.ModShift},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			_, atEnd := checkPos(gtx)
-			if atEnd {
-				return nil
-			}
-
-			selAct := textview.SelectionClear
-			if evt.Modifiers.Contain(key.ModShift) {
-				selAct = textview.SelectionExtend
-			}
-			e.text.MoveLines(+1, selAct)
-			return nil
-		})
+		}
 
-	// ESC key exits column editing mode
-	registerCommand(key.Filter{Focus: e, Name: key.NameEscape},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			// Debug log for ESC key
-			println("[ColumnEdit] ESC key pressed, ColumnEditEnabled:", e.ColumnEditEnabled())
-			if e.ColumnEditEnabled() {
-				e.clearColumnEdit()
-				e.ClearSelection()
-				println("[ColumnEdit] Exited column editing mode")
-			}
-			return nil
-		})
+		if gtx.Locale.Direction.Progression() == system.TowardOrigin {
+			direction = -direction
+		}
+		selAct := textview.SelectionClear
+		if extend {
+			selAct = textview.SelectionExtend
+		}
 
-	// Alt+C toggles column editing mode
-	registerCommand(key.Filter{Focus: e, Name: "C", Required: key.ModAlt},
-		func(gtx layout.Context, evt key.Event) EditorEvent {
-			// Debug log for Alt+C
-			println("[ColumnEdit] Alt+C pressed, current mode:", e.mode, "ReadOnly:", e.mode == ModeReadOnly)
-			if e.mode != ModeReadOnly {
-				wasEnabled := e.ColumnEditEnabled()
-				e.SetColumnEditMode(!wasEnabled)
-				isEnabled := e.ColumnEditEnabled()
-				println("[ColumnEdit] Toggled column editing mode - was:", wasEnabled, "now:", isEnabled)
-				if !isEnabled {
-					e.ClearSelection()
-				}
-			} else {
-				println("[ColumnEdit] Cannot enable column edit in ReadOnly mode")
+		if byWord {
+			e.text.MoveWords(direction, selAct)
+		} else {
+			if selAct == textview.SelectionClear {
+				e.text.ClearSelection()
 			}
-			return nil
-		})
+			e.text.MoveCaret(direction, direction*int(selAct))
+		}
+		return nil
+	}}
 }
 
-func (e *Editor) processCommands(gtx layout.Context) EditorEvent {
-	if len(e.commands) == 0 {
-		e.buildBuiltinCommands()
-	}
-
-	for _, cmds := range e.commands {
-		cmd := cmds[len(cmds)-1]
-		for {
-			ke, ok := gtx.Event(cmd.filter)
-			if !ok {
-				break
-			}
-
-			e.blinkStart = gtx.Now
-			if ke, ok := ke.(key.Event); ok {
-				if !gtx.Focused(e) || ke.State != key.Press {
-					break
-				}
-				e.scrollCaret = true
-				e.scroller.Stop()
-				if cmd.tag == nil || cmd.tag == e {
-					e.cancelCompletor()
-				}
-
-				if !ke.Modifiers.Contain(cmd.filter.Required) {
-					break
-				}
+// verticalMoveCommand creates a command moving the caret by one line in
+// direction.
+func verticalMoveCommand(name string, direction int, extend bool) Command {
+	return Command{Name: name, Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		caret, _ := e.text.Selection()
+		if (direction < 0 && caret == 0) || (direction > 0 && caret == e.text.Len()) {
+			return nil
+		}
 
-				if evt := cmd.handler(gtx, ke); evt != nil {
-					return evt
-				}
-			}
+		selAct := textview.SelectionClear
+		if extend {
+			selAct = textview.SelectionExtend
 		}
-	}
+		e.text.MoveLines(direction, selAct)
+		return nil
+	}}
+}
 
-	return nil
+// pageCommand creates a command moving by halfPages half pages.
+func pageCommand(name string, halfPages int, extend bool) Command {
+	return Command{Name: name, Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		e.MoveHalfPages(halfPages, extend)
+		return nil
+	}}
 }
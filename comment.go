@@ -0,0 +1,229 @@
+package gvcode
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ToggleLineComment comments or uncomments the lines of the selection with
+// the line comment token of the language set by SetLanguage. The lines are
+// uncommented if all the non-blank lines are commented. The comment tokens
+// are inserted at the smallest indentation of the lines, keeping the
+// indentation of the lines. If the language has no line comment, the
+// selection is block commented instead. Without a language, the "//" and
+// "/* */" tokens are used. It reports whether the text is changed.
+func (e *Editor) ToggleLineComment() bool {
+	e.initBuffer()
+	if e.mode == ModeReadOnly {
+		return false
+	}
+
+	comments := e.commentTokens()
+	token := comments.Line
+	if token == "" {
+		if comments.BlockStart != "" {
+			return e.ToggleBlockComment()
+		}
+		return false
+	}
+
+	if e.denyReadOnlyLines() {
+		return false
+	}
+
+	startLine, endLine := e.selectedLines()
+	lines := make([]string, 0, endLine-startLine+1)
+	for line := startLine; line <= endLine; line++ {
+		lines = append(lines, e.ReadRange(e.text.ConvertPos(line, 0), e.columnLineEnd(line)))
+	}
+
+	// Check whether all the non-blank lines are commented, and find the
+	// smallest indentation.
+	commented := true
+	indent := -1
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		col := utf8.RuneCountInString(line) - utf8.RuneCountInString(trimmed)
+		if !strings.HasPrefix(trimmed, token) || !e.commentAt(e.text.ConvertPos(startLine+i, col)) {
+			commented = false
+		}
+		if indent < 0 || col < indent {
+			indent = col
+		}
+	}
+	if indent < 0 {
+		// all of the lines are blank.
+		return false
+	}
+
+	// The lines are edited as a single edit.
+	var edits []Edit
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+
+		lineStart := e.text.ConvertPos(startLine+i, 0)
+		if commented {
+			col := utf8.RuneCountInString(line) - utf8.RuneCountInString(trimmed)
+			n := utf8.RuneCountInString(token)
+			if strings.HasPrefix(trimmed[len(token):], " ") {
+				n++
+			}
+			edits = append(edits, Edit{Start: lineStart + col, End: lineStart + col + n})
+		} else {
+			edits = append(edits, Edit{Start: lineStart + indent, End: lineStart + indent, Text: token + " "})
+		}
+	}
+	if _, result := e.replaceEdits(edits); result == editVetoed {
+		return false
+	}
+
+	e.scrollCaret = true
+	return true
+}
+
+// ToggleBlockComment wraps the selection with the block comment tokens of the
+// language set by SetLanguage, or unwraps it if it is already wrapped. Without
+// a selection, the block comment at the caret is unwrapped, or the text of the
+// caret line is wrapped if the caret is not in a block comment. If the
+// language has no block comment, the selected lines are line commented
+// instead. Without a language, the "/* */" tokens are used. It reports
+// whether the text is changed.
+func (e *Editor) ToggleBlockComment() bool {
+	e.initBuffer()
+	if e.mode == ModeReadOnly {
+		return false
+	}
+
+	comments := e.commentTokens()
+	opening, closing := comments.BlockStart, comments.BlockEnd
+	if opening == "" || closing == "" {
+		if comments.Line != "" {
+			return e.ToggleLineComment()
+		}
+		return false
+	}
+	if e.denyReadOnlyLines() {
+		return false
+	}
+
+	start, end := e.text.Selection()
+	if start > end {
+		start, end = end, start
+	}
+
+	if start == end {
+		if cStart, cEnd, ok := e.blockCommentAt(start, opening, closing); ok {
+			start, end = cStart, cEnd
+		} else {
+			// Use the caret line without the surrounding whitespaces.
+			line, _ := e.text.CaretPos()
+			start, end = e.text.ConvertPos(line, 0), e.columnLineEnd(line)
+			text := e.ReadRange(start, end)
+			start += utf8.RuneCountInString(text) - utf8.RuneCountInString(strings.TrimLeft(text, " \t"))
+			end -= utf8.RuneCountInString(text) - utf8.RuneCountInString(strings.TrimRight(text, " \t"))
+			if start >= end {
+				return false
+			}
+		}
+	}
+
+	var edits []Edit
+	text := e.ReadRange(start, end)
+	trimmed := strings.TrimSpace(text)
+	if len(trimmed) >= len(opening)+len(closing) && strings.HasPrefix(trimmed, opening) && strings.HasSuffix(trimmed, closing) {
+		// Unwrap the comment, including the padding spaces.
+		leading := utf8.RuneCountInString(text) - utf8.RuneCountInString(strings.TrimLeftFunc(text, unicode.IsSpace))
+		trailing := utf8.RuneCountInString(text) - utf8.RuneCountInString(strings.TrimRightFunc(text, unicode.IsSpace))
+		body := trimmed[len(opening) : len(trimmed)-len(closing)]
+
+		openStart := start + leading
+		openEnd := openStart + utf8.RuneCountInString(opening)
+		if strings.HasPrefix(body, " ") {
+			openEnd++
+		}
+		closeEnd := end - trailing
+		closeStart := closeEnd - utf8.RuneCountInString(closing)
+		if strings.HasSuffix(body, " ") && closeStart-1 >= openEnd {
+			closeStart--
+		}
+
+		edits = []Edit{{Start: openStart, End: openEnd}, {Start: closeStart, End: closeEnd}}
+	} else {
+		edits = []Edit{{Start: start, End: start, Text: opening + " "}, {Start: end, End: end, Text: " " + closing}}
+	}
+	if _, result := e.replaceEdits(edits); result == editVetoed {
+		return false
+	}
+
+	e.scrollCaret = true
+	return true
+}
+
+// defaultCommentTokens are the comment tokens used without a language.
+var defaultCommentTokens = CommentTokens{Line: "//", BlockStart: "/*", BlockEnd: "*/"}
+
+// commentTokens returns the comment tokens of the language set by
+// SetLanguage, or the default ones if no language is set.
+func (e *Editor) commentTokens() CommentTokens {
+	if e.language.ID == "" {
+		return defaultCommentTokens
+	}
+	return e.language.Comments
+}
+
+// selectedLines returns the first and the last line of the selection. A
+// selection ending at the start of a line does not include that line.
+func (e *Editor) selectedLines() (startLine, endLine int) {
+	start, end := e.text.Selection()
+	if start > end {
+		start, end = end, start
+	}
+
+	startLine, _ = e.text.FindParagraph(start)
+	endLine, p := e.text.FindParagraph(end)
+	if end == p.RuneOff && endLine > startLine {
+		endLine--
+	}
+	return startLine, endLine
+}
+
+// commentAt checks if the comment token at runeOff starts a comment, using
+// the syntax tokens: a comment token inside of a string, e.g., a line of a
+// multi-line string, is not a comment. Without syntax tokens at runeOff, the
+// comment token is taken as a comment.
+func (e *Editor) commentAt(runeOff int) bool {
+	tokens := e.text.TokensAt(runeOff)
+	if len(tokens) == 0 {
+		return true
+	}
+	for _, token := range tokens {
+		if token.Scope.Root() == "string" {
+			return false
+		}
+	}
+	return true
+}
+
+// blockCommentAt returns the range of the block comment covering the caret
+// at runeOff, using the syntax tokens.
+func (e *Editor) blockCommentAt(runeOff int, opening, closing string) (start, end int, ok bool) {
+	for _, off := range []int{runeOff, runeOff - 1} {
+		for _, token := range e.text.TokensAt(off) {
+			if token.Scope.Root() != "comment" {
+				continue
+			}
+			text := e.ReadRange(token.Start, token.End)
+			if strings.HasPrefix(text, opening) && strings.HasSuffix(text, closing) {
+				return token.Start, token.End, true
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,98 @@
+package gvcode
+
+import (
+	"testing"
+
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+func TestToggleLineComment(t *testing.T) {
+	e := newGoEditor(t, "func f() {\n\tif x {\n\t\ty()\n\n\t}\n}")
+	// select from the second line to the start of the last line.
+	e.SetCaret(11, 29)
+
+	if !e.ToggleLineComment() {
+		t.Fatal("expected the text to be changed")
+	}
+	want := "func f() {\n\t// if x {\n\t// \ty()\n\n\t// }\n}"
+	if got := e.Text(); got != want {
+		t.Fatalf("comment: got %q, want %q", got, want)
+	}
+
+	e.ToggleLineComment()
+	want = "func f() {\n\tif x {\n\t\ty()\n\n\t}\n}"
+	if got := e.Text(); got != want {
+		t.Fatalf("uncomment: got %q, want %q", got, want)
+	}
+
+	// the toggle is undone in one step.
+	e.ToggleLineComment()
+	e.undo()
+	if got := e.Text(); got != want {
+		t.Fatalf("undo: got %q, want %q", got, want)
+	}
+}
+
+func TestToggleLineCommentInString(t *testing.T) {
+	text := "s := `\n// a\n// b\n`"
+	e := newGoEditor(t, text)
+	scheme := syntax.ColorScheme{}
+	scheme.AddStyle("string", 0, gvcolor.Color{}, gvcolor.Color{})
+	e.WithOptions(WithColorScheme(scheme))
+	// the lines starting with the comment token are in a raw string.
+	e.SetSyntaxTokens(syntax.Token{Start: 5, End: len(text), Scope: "string.quoted.raw"})
+	e.SetCaret(7, 17)
+
+	if !e.ToggleLineComment() {
+		t.Fatal("expected the text to be changed")
+	}
+	want := "s := `\n// // a\n// // b\n`"
+	if got := e.Text(); got != want {
+		t.Fatalf("got %q, want the lines in the string commented", got)
+	}
+}
+
+func TestToggleBlockComment(t *testing.T) {
+	e := newGoEditor(t, "a := b + c")
+	e.SetCaret(5, 10)
+
+	e.ToggleBlockComment()
+	want := "a := /* b + c */"
+	if got := e.Text(); got != want {
+		t.Fatalf("comment: got %q, want %q", got, want)
+	}
+
+	e.SetCaret(5, 16)
+	e.ToggleBlockComment()
+	want = "a := b + c"
+	if got := e.Text(); got != want {
+		t.Fatalf("uncomment: got %q, want %q", got, want)
+	}
+}
+
+func TestToggleCommentWithoutLanguage(t *testing.T) {
+	e := newTestEditor(t, "a\nb")
+	e.SetCaret(0, 3)
+	if !e.ToggleLineComment() || e.Text() != "// a\n// b" {
+		t.Fatalf("got %q, want the lines commented with the default token", e.Text())
+	}
+	e.SetCaret(0, 0)
+	if !e.ToggleBlockComment() || e.Text() != "/* // a */\n// b" {
+		t.Fatalf("got %q, want the line wrapped with the default tokens", e.Text())
+	}
+
+	// a language without comments has nothing to toggle.
+	RegisterLanguage(LanguageConfig{ID: "plain-comment-test"})
+	if err := e.SetLanguage("plain-comment-test"); err != nil {
+		t.Fatal(err)
+	}
+	if e.ToggleLineComment() || e.ToggleBlockComment() {
+		t.Errorf("got %q, want the text unchanged without comment tokens", e.Text())
+	}
+
+	// the block comment keys are matched by the shifted symbol too.
+	if cmd, ok := e.Keymap().Lookup("Shortcut+?"); !ok || cmd.Name != ToggleBlockComment.Name {
+		t.Errorf("got %q bound to Shortcut+?, want %q", cmd.Name, ToggleBlockComment.Name)
+	}
+}
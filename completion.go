@@ -1,10 +1,12 @@
 package gvcode
 
 import (
+	"context"
 	"image"
 
 	"gioui.org/io/key"
 	"gioui.org/layout"
+	"github.com/oligo/gvcode/gutter"
 )
 
 // Completion is the main auto-completion interface for the editor. A Completion object
@@ -29,6 +31,14 @@ type Completion interface {
 	Layout(gtx layout.Context) layout.Dimensions
 }
 
+// ChoiceCompletion is an optional interface a Completion can implement to
+// present the options of a snippet choice tabstop, e.g., ${1|one,two|}, when
+// the tabstop becomes active. The TextEdit of each candidate replaces the
+// content of the tabstop.
+type ChoiceCompletion interface {
+	OnChoices(ctx CompletionContext, candidates []CompletionCandidate)
+}
+
 type CompletionPopup interface {
 	Layout(gtx layout.Context, items []CompletionCandidate) layout.Dimensions
 }
@@ -61,6 +71,9 @@ type CompletionContext struct {
 	Coords image.Point
 	// The position of the caret in line/column and selection range.
 	Position Position
+	// Metadata describes the document being edited. It is a copy of the
+	// metadata of the editor.
+	Metadata *gutter.Metadata
 }
 
 // CompletionCandidate are results returned from Completor, to be presented
@@ -74,13 +87,26 @@ type CompletionCandidate struct {
 	TextEdit TextEdit
 	// A short description of the candicate.
 	Description string
+	// Detail is additional information of the candidate, like a
+	// function signature or the type of a variable. It is shown
+	// at the top of the documentation panel.
+	Detail string
+	// Documentation of the candidate, shown in the documentation
+	// panel when the candidate is selected.
+	Documentation string
 	// Kind of the candicate, for example, function,
 	// class, keywords etc.
 	Kind string
+	// MatchedRunes are the rune indices of Label matched by the typed
+	// pattern. They are set when filtering and used to highlight the label.
+	MatchedRunes []int
 	// TextFormat defines whether the insert text in a completion item
 	// should be interpreted as plain text or a snippet. The possible values are
 	// PlainText or Snippet.
 	TextFormat string
+	// Data is kept for the Completor to resolve the candidate, like the data
+	// field of the completion items of a language server.
+	Data any
 }
 
 // TextEdit is the text with range info to be
@@ -120,6 +146,15 @@ type Completor interface {
 	FilterAndRank(pattern string, candidates []CompletionCandidate) []CompletionCandidate
 }
 
+// CompletionResolver is an optional interface a Completor can implement to
+// fill in the Detail and Documentation of a candidate lazily, when it is
+// selected, like the completionItem/resolve request of the Language Server
+// Protocol. ResolveItem is called in its own goroutine, and ctx is canceled
+// when another candidate is selected or the completion ends.
+type CompletionResolver interface {
+	ResolveItem(ctx context.Context, item CompletionCandidate) (CompletionCandidate, error)
+}
+
 // Trigger
 type Trigger struct {
 	// Characters that must be present before the caret to trigger the completion.
@@ -0,0 +1,76 @@
+package gvcode
+
+import (
+	"image"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	gvcolor "github.com/oligo/gvcode/color"
+	"golang.org/x/image/math/fixed"
+)
+
+// currentLineHighlight configures the background of the caret line.
+type currentLineHighlight struct {
+	enabled bool
+	// hideOnSelection hides the highlight while the selection is not empty.
+	hideOnSelection bool
+}
+
+// lineHighlightColor returns the color of the current line background: the
+// LineColor of the palette, or a dimmed foreground color.
+func (e *Editor) lineHighlightColor() gvcolor.Color {
+	if e.colorPalette == nil {
+		return gvcolor.Color{}
+	}
+	if e.colorPalette.LineColor.IsSet() {
+		return e.colorPalette.LineColor
+	}
+	if e.colorPalette.Foreground.IsSet() {
+		return e.colorPalette.Foreground.MulAlpha(0x30)
+	}
+	return gvcolor.Color{}
+}
+
+// paintCurrentLine fills the background of the paragraph of the caret, over
+// the whole width of the editor, if the current line highlight is enabled.
+func (e *Editor) paintCurrentLine(gtx layout.Context) {
+	if !e.currentLine.enabled {
+		return
+	}
+	c := e.lineHighlightColor()
+	if !c.IsSet() {
+		return
+	}
+	if start, end := e.text.Selection(); start != end && e.currentLine.hideOnSelection {
+		return
+	}
+
+	line, _ := e.text.CaretPos()
+	paragraphs := e.text.TextLayout().Paragraphs
+	if line < 0 || line >= len(paragraphs) {
+		return
+	}
+	para := paragraphs[line]
+	bounds := paragraphBounds(para.StartY, para.EndY, para.Ascent, para.Descent, e.text.GetLineHeight().Ceil())
+	bounds.Min.X, bounds.Max.X = 0, gtx.Constraints.Max.X
+	bounds = bounds.Sub(image.Pt(0, e.text.ScrollOff().Y))
+	if !bounds.Overlaps(image.Rectangle{Max: gtx.Constraints.Max}) {
+		return
+	}
+	paint.FillShape(gtx.Ops, c.NRGBA(), clip.Rect(bounds).Op())
+}
+
+// paragraphBounds returns the vertical extent of a paragraph whose first and
+// last screen lines have their baselines at startY and endY. The leading of
+// the line height is split above and below the glyphs, so that the bounds of
+// consecutive paragraphs touch.
+func paragraphBounds(startY, endY int, ascent, descent fixed.Int26_6, lineHeight int) image.Rectangle {
+	glyphHeight := ascent.Ceil() + descent.Ceil()
+	leading := max(lineHeight-glyphHeight, 0)
+	leadingTop := leading / 2
+	return image.Rectangle{
+		Min: image.Pt(0, startY-ascent.Ceil()-leadingTop),
+		Max: image.Pt(0, endY+descent.Ceil()+leading-leadingTop),
+	}
+}
@@ -0,0 +1,34 @@
+package gvcode
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/textstyle/syntax"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestParagraphBounds(t *testing.T) {
+	// 4px of leading are split above and below the glyphs.
+	got := paragraphBounds(20, 40, fixed.I(12), fixed.I(4), 20)
+	if want := image.Rect(0, 6, 0, 46); got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestCurrentLineHighlightReplacesGutterHighlight(t *testing.T) {
+	e := newTestEditor(t, "a\nb\n")
+	scheme := syntax.ColorScheme{}
+	scheme.LineColor = gvcolor.MakeColor(color.NRGBA{A: 0x20})
+	e.WithOptions(WithColorScheme(scheme))
+	if !e.gutterColors().LineHighlight.IsSet() {
+		t.Fatal("expected the gutter to highlight the current line")
+	}
+
+	e.WithOptions(WithCurrentLineHighlight(true, true))
+	if e.gutterColors().LineHighlight.IsSet() {
+		t.Fatal("expected the gutter highlight to be disabled")
+	}
+}
@@ -0,0 +1,267 @@
+package gvcode
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/gesture"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/internal/buffer"
+	"github.com/oligo/gvcode/textstyle/decoration"
+)
+
+const (
+	diagnosticDeco = "_diagnostic_deco"
+)
+
+// DiagnosticSeverity is the severity of a diagnostic, ordered from the
+// most severe to the least severe.
+type DiagnosticSeverity uint8
+
+const (
+	SeverityError DiagnosticSeverity = iota + 1
+	SeverityWarning
+	SeverityInfo
+	SeverityHint
+)
+
+// Diagnostic is a problem reported for a range of text, usually by a
+// compiler, a linter or a language server.
+type Diagnostic struct {
+	// Start and End are rune offsets of the problematic text.
+	Start, End int
+	Severity   DiagnosticSeverity
+	// Message is the human readable description of the problem. Only the
+	// first line is shown in the error lens.
+	Message string
+	// Source names the producer of the diagnostic, e.g., "go vet".
+	Source string
+}
+
+// DiagnosticEvent is generated when the user clicks the inline message
+// of a diagnostic rendered by the error lens.
+type DiagnosticEvent struct {
+	Diagnostic Diagnostic
+}
+
+func (DiagnosticEvent) isEditorEvent() {}
+
+// diagnosticState tracks the diagnostics set to the editor and the
+// state of the error lens.
+type diagnosticState struct {
+	items []diagnosticItem
+	// noSquiggles disables the squiggles under the diagnostic ranges.
+	noSquiggles bool
+	// lens renders the diagnostic message at the end of the line if enabled.
+	lens bool
+	// colors overrides the default color of each severity.
+	colors map[DiagnosticSeverity]gvcolor.Color
+	// lensAreas are the clickable areas of the messages in the last frame.
+	lensAreas []lensArea
+	clicker   gesture.Click
+}
+
+type diagnosticItem struct {
+	Diagnostic
+	// marker tracks the start of the diagnostic as the text changes.
+	marker *buffer.Marker
+}
+
+type lensArea struct {
+	bounds image.Rectangle
+	diag   Diagnostic
+}
+
+var defaultSeverityColors = map[DiagnosticSeverity]color.NRGBA{
+	SeverityError:   {R: 0xE5, G: 0x39, B: 0x35, A: 0xFF},
+	SeverityWarning: {R: 0xF9, G: 0xA8, B: 0x25, A: 0xFF},
+	SeverityInfo:    {R: 0x1E, G: 0x88, B: 0xE5, A: 0xFF},
+	SeverityHint:    {R: 0x75, G: 0x75, B: 0x75, A: 0xFF},
+}
+
+func (s *diagnosticState) color(severity DiagnosticSeverity) gvcolor.Color {
+	if c, ok := s.colors[severity]; ok && c.IsSet() {
+		return c
+	}
+	if c, ok := defaultSeverityColors[severity]; ok {
+		return gvcolor.MakeColor(c)
+	}
+	return gvcolor.MakeColor(defaultSeverityColors[SeverityHint])
+}
+
+// SetDiagnostics replaces the diagnostics of the editor. Diagnostics
+// are underlined with squiggles and/or shown inline by the error lens,
+// depending on the configuration.
+func (e *Editor) SetDiagnostics(diags ...Diagnostic) error {
+	e.initBuffer()
+	e.ClearDiagnostics()
+
+	decos := make([]decoration.Decoration, 0, len(diags))
+	for _, d := range diags {
+		if d.Start > d.End {
+			d.Start, d.End = d.End, d.Start
+		}
+		marker, err := e.buffer.CreateMarker(d.Start, buffer.BiasBackward)
+		if err != nil {
+			return err
+		}
+		e.diagnostics.items = append(e.diagnostics.items, diagnosticItem{Diagnostic: d, marker: marker})
+
+		if !e.diagnostics.noSquiggles && d.End > d.Start {
+			decos = append(decos, decoration.Decoration{
+				Source:   diagnosticDeco,
+				Start:    d.Start,
+				End:      d.End,
+				Squiggle: &decoration.Squiggle{Color: e.diagnostics.color(d.Severity)},
+			})
+		}
+	}
+
+	if len(decos) > 0 {
+		return e.AddDecorations(decos...)
+	}
+	return nil
+}
+
+// ClearDiagnostics removes all the diagnostics from the editor.
+func (e *Editor) ClearDiagnostics() {
+	e.initBuffer()
+	for _, item := range e.diagnostics.items {
+		e.buffer.RemoveMarker(item.marker)
+	}
+	e.diagnostics.items = e.diagnostics.items[:0]
+	e.diagnostics.lensAreas = e.diagnostics.lensAreas[:0]
+	e.ClearDecorations(diagnosticDeco)
+}
+
+// Diagnostics returns the diagnostics of the editor, with their start
+// offsets updated to follow the edits made since they were set.
+func (e *Editor) Diagnostics() []Diagnostic {
+	diags := make([]Diagnostic, 0, len(e.diagnostics.items))
+	for _, item := range e.diagnostics.items {
+		d := item.Diagnostic
+		if off := item.marker.Offset(); off >= 0 {
+			d.End += off - d.Start
+			d.Start = off
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+// paintErrorLens draws the message of the most severe diagnostic of each
+// visible line after the end of the line.
+func (e *Editor) paintErrorLens(gtx layout.Context, shaper *text.Shaper) {
+	e.diagnostics.lensAreas = e.diagnostics.lensAreas[:0]
+	if !e.diagnostics.lens || len(e.diagnostics.items) == 0 || shaper == nil {
+		return
+	}
+
+	// pick the most severe diagnostic of each line.
+	lineDiags := make(map[int]Diagnostic)
+	for _, d := range e.Diagnostics() {
+		line, _ := e.text.FindParagraph(d.Start)
+		if prev, ok := lineDiags[line]; !ok || d.Severity < prev.Severity {
+			lineDiags[line] = d
+		}
+	}
+
+	lines := make([]int, 0, len(lineDiags))
+	for line := range lineDiags {
+		lines = append(lines, line)
+	}
+	slices.Sort(lines)
+
+	viewport := e.text.Viewport()
+	scrollOff := e.text.ScrollOff()
+	paragraphs := e.text.TextLayout().Paragraphs
+	foldManager := e.text.FoldManager()
+	gap := e.text.GetLineHeight().Round()
+
+	params := e.text.Params()
+	params.MinWidth = 0
+	params.MaxWidth = 1 << 24
+	params.MaxLines = 1
+
+	for _, line := range lines {
+		if line < 0 || line >= len(paragraphs) {
+			continue
+		}
+		if foldManager != nil && !foldManager.IsLineVisible(line) {
+			continue
+		}
+		para := paragraphs[line]
+		if para.EndY < viewport.Min.Y || para.StartY > viewport.Max.Y {
+			continue
+		}
+
+		d := lineDiags[line]
+		msg, _, _ := strings.Cut(d.Message, "\n")
+		msg = strings.TrimSpace(msg)
+		if msg == "" {
+			continue
+		}
+
+		shaper.LayoutString(params, msg)
+		var glyphs []text.Glyph
+		var width int
+		for {
+			g, ok := shaper.NextGlyph()
+			if !ok {
+				break
+			}
+			glyphs = append(glyphs, g)
+			width = (g.X + g.Advance).Ceil()
+		}
+		if len(glyphs) == 0 {
+			continue
+		}
+
+		origin := image.Point{
+			X: para.EndX.Ceil() + gap - scrollOff.X,
+			Y: para.EndY - scrollOff.Y,
+		}
+		trans := op.Affine(f32.Affine2D{}.Offset(
+			f32.Point{X: float32(origin.X), Y: float32(origin.Y) - float32(glyphs[0].Y)},
+		)).Push(gtx.Ops)
+		outline := clip.Outline{Path: shaper.Shape(glyphs)}.Op().Push(gtx.Ops)
+		paint.ColorOp{Color: e.diagnostics.color(d.Severity).MulAlpha(0xB0).NRGBA()}.Add(gtx.Ops)
+		paint.PaintOp{}.Add(gtx.Ops)
+		outline.Pop()
+		trans.Pop()
+
+		bounds := image.Rect(origin.X, origin.Y-para.Ascent.Ceil(), origin.X+width, origin.Y+para.Descent.Ceil())
+		area := clip.Rect(bounds).Push(gtx.Ops)
+		pointer.CursorPointer.Add(gtx.Ops)
+		e.diagnostics.clicker.Add(gtx.Ops)
+		area.Pop()
+		e.diagnostics.lensAreas = append(e.diagnostics.lensAreas, lensArea{bounds: bounds, diag: d})
+	}
+
+	for {
+		evt, ok := e.diagnostics.clicker.Update(gtx.Source)
+		if !ok {
+			break
+		}
+		if evt.Kind != gesture.KindClick {
+			continue
+		}
+
+		pos := evt.Position
+		for _, area := range e.diagnostics.lensAreas {
+			if pos.In(area.bounds) {
+				e.pending = append(e.pending, DiagnosticEvent{Diagnostic: area.diag})
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package gvcode
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestDiagnosticsFollowEdits(t *testing.T) {
+	e := newTestEditor(t, "a := 1\nb := x\n")
+	if err := e.SetDiagnostics(Diagnostic{Start: 12, End: 13, Severity: SeverityError, Message: "undefined: x"}); err != nil {
+		t.Fatal(err)
+	}
+
+	e.SetCaret(0, 0)
+	e.Insert("// c\n")
+	diags := e.Diagnostics()
+	if len(diags) != 1 || diags[0].Start != 17 || diags[0].End != 18 {
+		t.Fatalf("got diagnostics %+v, want the range moved to 17-18", diags)
+	}
+	if decos := e.text.QueryDecorations(17, 18); len(decos) != 1 || decos[0].Squiggle == nil {
+		t.Errorf("got decorations %+v, want the squiggle moved with the text", decos)
+	}
+
+	e.ClearDiagnostics()
+	if len(e.Diagnostics()) != 0 || len(e.text.QueryDecorations(0, e.Len())) != 0 {
+		t.Error("the diagnostics are not cleared")
+	}
+
+	// squiggles can be disabled.
+	e.WithOptions(WithDiagnosticSquiggles(false))
+	e.SetDiagnostics(Diagnostic{Start: 0, End: 2, Severity: SeverityWarning, Message: "w"})
+	if decos := e.text.QueryDecorations(0, 2); len(decos) != 0 {
+		t.Errorf("got decorations %+v with the squiggles disabled", decos)
+	}
+}
+
+func TestErrorLens(t *testing.T) {
+	e := newTestEditor(t, "a := 1\nb := x\n", WithErrorLens(true))
+	e.SetDiagnostics(
+		Diagnostic{Start: 12, End: 13, Severity: SeverityWarning, Message: "unused"},
+		Diagnostic{Start: 12, End: 13, Severity: SeverityError, Message: "undefined: x\nmore details"},
+	)
+
+	var router input.Router
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	var events []EditorEvent
+	frame := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 200)), Source: router.Source()}
+		for {
+			evt, ok := e.Update(gtx)
+			if !ok {
+				break
+			}
+			events = append(events, evt)
+		}
+		e.Layout(gtx, shaper)
+		router.Frame(gtx.Ops)
+	}
+	frame()
+
+	// the most severe diagnostic of the line is shown after its end.
+	areas := e.diagnostics.lensAreas
+	if len(areas) != 1 || areas[0].diag.Severity != SeverityError {
+		t.Fatalf("got lens areas %+v, want the error of line 1", areas)
+	}
+	lineEnd := e.text.RuneCoords(13)
+	if areas[0].bounds.Min.X <= int(lineEnd.X) {
+		t.Errorf("got the message at %v, want it after the line end at %v", areas[0].bounds, lineEnd)
+	}
+
+	pos := layout.FPt(areas[0].bounds.Min.Add(areas[0].bounds.Max).Div(2))
+	router.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: pos},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: pos},
+	)
+	frame()
+	frame()
+	var clicked []Diagnostic
+	for _, evt := range events {
+		if de, ok := evt.(DiagnosticEvent); ok {
+			clicked = append(clicked, de.Diagnostic)
+		}
+	}
+	if len(clicked) != 1 || clicked[0].Message != "undefined: x\nmore details" {
+		t.Errorf("got clicked diagnostics %+v, want the error", clicked)
+	}
+}
@@ -0,0 +1,84 @@
+package gvcode
+
+import (
+	"image"
+	"image/color"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/gutter/providers"
+)
+
+// diffProvider returns the VCS diff provider registered in the gutter.
+func (e *Editor) diffProvider() *providers.VCSDiffProvider {
+	if e.gutterManager == nil {
+		return nil
+	}
+
+	for _, p := range e.gutterManager.Providers() {
+		if provider, ok := p.(*providers.VCSDiffProvider); ok {
+			return provider
+		}
+	}
+	return nil
+}
+
+// renderStickyDiffHeader pins the header of the diff hunk the topmost visible
+// line belongs to, once the start of the hunk is scrolled out of view, so that
+// reviewers know which hunk the visible lines belong to. The header is placed
+// below the sticky lines area of height top.
+func (e *Editor) renderStickyDiffHeader(gtx layout.Context, shaper *text.Shaper, textColor gvcolor.Color, top int) {
+	if shaper == nil {
+		return
+	}
+	hunk := e.stickyDiffHunk(top)
+	if hunk == nil {
+		return
+	}
+
+	lineHeight := e.text.GetLineHeight().Ceil()
+	rect := image.Rect(0, top, gtx.Constraints.Max.X, top+lineHeight)
+
+	bgColor := color.NRGBA{R: 0xF0, G: 0xF0, B: 0xF0, A: 0xFF}
+	if e.colorPalette != nil && e.colorPalette.Background.IsSet() {
+		bgColor = e.colorPalette.Background.NRGBA()
+		bgColor.A = 0xFF
+	}
+	paint.FillShape(gtx.Ops, bgColor, clip.Rect(rect).Op())
+
+	borderColor := textColor.NRGBA()
+	borderColor.A = 0x40
+	paint.FillShape(gtx.Ops, borderColor, clip.Rect(image.Rect(0, rect.Max.Y-1, rect.Max.X, rect.Max.Y)).Op())
+
+	params := e.text.Params()
+	params.MinWidth = 0
+	params.MaxWidth = 1 << 20
+	params.MaxLines = 1
+
+	padding := gtx.Dp(unit.Dp(8))
+	shaper.LayoutString(params, hunk.Header())
+	paintTextGlyphs(gtx, shaper, image.Pt(padding, top), rect.Dx()-2*padding, textColor.MulAlpha(0xC0))
+}
+
+// stickyDiffHunk returns the diff hunk whose header is pinned at top: the
+// hunk of the line at top, if its start is scrolled out of view. The line is
+// found by its vertical position only, so that it is found whatever the
+// horizontal scroll and the width of the line.
+func (e *Editor) stickyDiffHunk(top int) *providers.DiffHunk {
+	provider := e.diffProvider()
+	if provider == nil || !provider.StickyHeader() || e.text.Paragraphs() == 0 {
+		return nil
+	}
+
+	line, _ := e.text.FindParagraph(e.text.ClosestOffset(image.Point{Y: top}))
+	hunk := provider.GetHunk(line)
+	if hunk == nil || hunk.StartLine >= line {
+		// the start of the hunk is still visible.
+		return nil
+	}
+	return hunk
+}
@@ -0,0 +1,45 @@
+package gvcode
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"github.com/oligo/gvcode/gutter/providers"
+)
+
+func TestStickyDiffHunk(t *testing.T) {
+	provider := providers.NewVCSDiffProvider()
+	provider.SetStickyHeader(true)
+	// short lines below a line wider than the viewport.
+	e := newTestEditor(t, strings.Repeat("x", 400)+"\n"+strings.Repeat("a\n", 100), WithGutter(provider))
+	hunk := &providers.DiffHunk{Type: providers.DiffAdded, StartLine: 10, EndLine: 60, OldStartLine: 10}
+	provider.UpdateDiff([]*providers.DiffHunk{hunk})
+
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 300))}
+	e.Layout(gtx, shaper)
+	if got := e.stickyDiffHunk(0); got != nil {
+		t.Fatalf("got hunk %+v pinned while its start is visible", got)
+	}
+
+	e.ScrollToLine(30)
+	e.Layout(gtx, shaper)
+	if got := e.stickyDiffHunk(0); got != hunk {
+		t.Fatalf("got hunk %+v pinned at line 30, want %+v", got, hunk)
+	}
+
+	// the top line is found past the end of the short lines.
+	e.text.ScrollRel(200, 0)
+	e.Layout(gtx, shaper)
+	if e.text.ScrollOff().X == 0 {
+		t.Fatal("the editor is not scrolled horizontally")
+	}
+	if got := e.stickyDiffHunk(0); got != hunk {
+		t.Errorf("got hunk %+v pinned when scrolled horizontally, want %+v", got, hunk)
+	}
+}
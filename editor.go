@@ -2,17 +2,18 @@ package gvcode
 
 import (
 	"image"
-	"image/color"
 	"io"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"gioui.org/f32"
 	"gioui.org/gesture"
 	"gioui.org/io/event"
 	"gioui.org/io/key"
 	"gioui.org/io/pointer"
-	"gioui.org/io/semantic"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/op/clip"
@@ -24,6 +25,7 @@ import (
 	"github.com/oligo/gvcode/gutter"
 	"github.com/oligo/gvcode/internal/buffer"
 	gestureExt "github.com/oligo/gvcode/internal/gesture"
+	"github.com/oligo/gvcode/snippet"
 	"github.com/oligo/gvcode/textview"
 )
 
@@ -37,6 +39,11 @@ type Editor struct {
 	text       *textview.TextView
 	buffer     buffer.TextSource
 	snippetCtx *snippetContext
+	// snapshot is the layout snapshot published after the last layout. It
+	// is the only state of the editor read from other goroutines.
+	snapshot atomic.Pointer[textview.LayoutSnapshot]
+	// variableResolver resolves snippet variables provided by the host.
+	variableResolver snippet.VariableResolver
 	// colorPalette configures the color scheme used for syntax highlighting.
 	colorPalette *gvcolor.ColorPalette
 	// gutterGap specifies the right inset between the gutter and the
@@ -44,11 +51,33 @@ type Editor struct {
 	gutterGap unit.Dp
 	// gutterManager manages multiple gutter providers (line numbers, breakpoints, etc.)
 	gutterManager *gutter.Manager
+	// gutterEditsObserved is set once the edits are forwarded to the gutter
+	// providers.
+	gutterEditsObserved bool
 	// hooks
 	onPaste   BeforePasteHook
+	onInput   TextInputHook
 	completor Completion
+	// invalidUTF8 is the policy for text with invalid UTF-8.
+	invalidUTF8 InvalidUTF8Policy
+	// normalizeNFC normalizes the typed and pasted text to the NFC form.
+	normalizeNFC bool
+	// allowBinary disables the detection of binary content in SetText.
+	allowBinary bool
+	// hexView is true while the hex dump of OpenHexView is shown, and
+	// hexViewMode is the mode restored once it is closed.
+	hexView     bool
+	hexViewMode EditorMode
+	// wrapIndicators enables the arrows marking the wrapped lines.
+	wrapIndicators bool
 	// last input when the editor received an EditEvent.
 	lastInput *key.EditEvent
+	// pasteReindent re-indents the multi-line pastes from the caret line.
+	pasteReindent bool
+	// pasted is set with the text version after a paste, so that OnTextEdit
+	// does not start a completion for the pasted text.
+	pasted        bool
+	pastedVersion int
 
 	// scratch is a byte buffer that is reused to efficiently read portions of text
 	// from the textView.
@@ -71,8 +100,49 @@ type Editor struct {
 	pending     []EditorEvent
 	// commands is a registry of key commands.
 	commands map[key.Name][]keyCommand
+	// keymap binds key strokes to commands.
+	keymap *Keymap
+	// clipboardRing keeps the recently copied texts.
+	clipboardRing *ClipboardRing
+	// primary syncs the selection with the primary selection set by
+	// WithPrimarySelection.
+	primary primarySelectionState
+	// fileDrop tracks the pointer position of the file drops.
+	fileDrop fileDropState
+	// touch implements the long press selection and the selection handles.
+	touch touchState
+	// zoom scales the text size with the pinches and the Ctrl+wheels.
+	zoom zoomState
+	// a11y describes the editor to the screen readers.
+	a11y accessibilityState
+	// clipboardRead receives the clipboard text requested by ReadClipboard.
+	clipboardRead func(text string)
+	// edits are the edit listeners registered by OnEdit.
+	edits editListeners
+	// beforeChange are the hooks registered by OnBeforeChange.
+	beforeChange []*beforeChangeHook
+	// frames measures the time spent in the phases of the frames.
+	frames frameTimer
+	// pendingKeys are the key strokes of an incomplete chord.
+	pendingKeys []KeyStroke
+	// reveal is the state of RevealRange.
+	reveal revealState
+	// scrollAnim animates the scrolling to lines, the caret and ranges.
+	scrollAnim scrollAnimation
+	// swatches paints the swatches of the color literals.
+	swatches colorSwatches
+	// paletteFade crossfades the colors replaced by SetColorPalette.
+	paletteFade paletteTransition
+	// foldAnim slides the lines moved by collapsing and expanding folds.
+	foldAnim foldAnimation
+	// reducedMotion disables the animations, see WithReducedMotion.
+	reducedMotion bool
+	// expansion is the stack of the selections grown by ExpandSelection.
+	expansion selectionExpansion
+	// gotoFlash flashes the line that GoToLineColumn moves to.
+	gotoFlash bool
 	// autoInsertions tracks recently inserted closing brackets or quotes.
-	autoInsertions map[int]rune
+	autoInsertions []autoInsertion
 	// gutterWidth can be used to guide to set the horizontal offset when
 	// laying out a horizontal scrollbar.
 	gutterWidth int
@@ -84,6 +154,62 @@ type Editor struct {
 	columnEdit columnEditState
 	// sticky lines state
 	stickyLinesClicker gesture.Click
+	// stickyHeight is the height of the sticky lines area in the last
+	// layout.
+	stickyHeight int
+	// foldPreviewLines is the maximum number of hidden lines previewed when
+	// hovering over a collapsed fold. Negative values disable the preview.
+	foldPreviewLines int
+	// foldPlaceholders are the bounds of the fold placeholders painted in
+	// the last layout, to preview the folds hovered over them.
+	foldPlaceholders []foldPlaceholderBounds
+	// hoveredPlaceholder is the start line of the fold whose placeholder is
+	// hovered, if placeholderHovered is true.
+	hoveredPlaceholder int
+	placeholderHovered bool
+	// charInspector shows the code points of the character after the caret.
+	charInspector bool
+	// multiSel tracks the selections added to the primary one.
+	multiSel multiSelection
+	// linked is the linked editing session started by StartLinkedEdit.
+	linked linkedEdit
+	// caretStyle is the shape and the blinking of the caret.
+	caretStyle CaretStyle
+	// currentLine configures the background of the caret line.
+	currentLine currentLineHighlight
+	// lineEndMarkers enables the pilcrows drawn at the end of the lines.
+	lineEndMarkers bool
+	// lineEnding caches the line ending detected in the text.
+	lineEnding lineEndingState
+	// txDepth tracks the nesting of transactions, and txChanged records
+	// edits made in them.
+	txDepth   int
+	txChanged bool
+	// pagingMode controls how the caret moves when paging.
+	pagingMode PagingMode
+	// caretMovement controls how the arrow keys move the caret in bidi text.
+	caretMovement CaretMovement
+	// diagnostics and error lens state
+	diagnostics diagnosticState
+	// references are the highlighted references of a symbol.
+	references referenceState
+	// unicodeWarnings tracks the suspicious characters of the text.
+	unicodeWarnings unicodeWarningState
+	// breadcrumbs tracks the scopes enclosing the caret.
+	breadcrumbs breadcrumbState
+	// phantoms are the blocks of lines shown between the lines of the text.
+	phantoms []PhantomBlock
+	// readOnlyRegions are the ranges protected from the edits of the user.
+	readOnlyRegions []readOnlyRegion
+	// emptyArea is laid out in the area below the last line.
+	emptyArea     layout.Widget
+	emptyAreaRect image.Rectangle
+	// search state of the background search.
+	search searchState
+	// language is the config of the language set by SetLanguage.
+	language LanguageConfig
+	// metadata describes the document for providers and plugins.
+	metadata gutter.Metadata
 }
 
 // GetGutterManager returns the gutter manager instance
@@ -100,6 +226,8 @@ type columnEditState struct {
 	selections []columnCursor
 	// anchor stores the initial mouse position when starting a column selection
 	anchor image.Point
+	// compose tracks the input composed at the primary caret.
+	compose columnComposition
 }
 
 // columnCursor represents a cursor position for column editing
@@ -112,6 +240,18 @@ type columnCursor struct {
 	startX int
 	// endX is the pixel X coordinate of the selection end (if different from startX)
 	endX int
+	// composeCol is the start column of the region mirroring the composition
+	// of the primary caret.
+	composeCol int
+}
+
+// autoInsertion is a closing bracket or quote inserted automatically after
+// its opening part was typed.
+type autoInsertion struct {
+	// opening and marker track the opening part and the closing part.
+	opening *buffer.Marker
+	marker  *buffer.Marker
+	r       rune
 }
 
 type imeState struct {
@@ -121,6 +261,10 @@ type imeState struct {
 	}
 	snippet    key.Snippet
 	start, end int
+	// compose is the region composed by the input method, and composing
+	// is set while it follows a composition of the browser.
+	compose   key.Range
+	composing bool
 }
 
 type EditorEvent interface {
@@ -146,10 +290,8 @@ type HoverEvent struct {
 	IsCancel bool
 }
 
-const (
-	blinksPerSecond  = 1
-	maxBlinkDuration = 10 * time.Second
-)
+// maxBlinkDuration is the duration after which the caret stops blinking.
+const maxBlinkDuration = 10 * time.Second
 
 // initBuffer should be invoked first in every exported function that accesses
 // text state. It ensures that the underlying text widget is both ready to use
@@ -160,7 +302,11 @@ func (e *Editor) initBuffer() {
 		e.buffer = e.text.Source()
 	}
 
-	e.text.CaretWidth = unit.Dp(1)
+	if e.text.EditHook == nil {
+		e.text.EditHook = e.replaceTextViewEdit
+	}
+	// the bar is painted on both sides of the caret position.
+	e.text.CaretWidth = e.caretStyle.width() / 2
 	e.wordHighlighter.editor = e
 	e.selectionHighlighter.editor = e
 }
@@ -178,30 +324,23 @@ func (e *Editor) Invalidate() {
 // false.
 func (e *Editor) Update(gtx layout.Context) (EditorEvent, bool) {
 	e.initBuffer()
+	e.syncSearch()
+	e.syncUnicodeWarnings()
+	e.syncBreadcrumbs()
+	e.syncPeerEdits()
 	event, ok := e.processEvents(gtx)
+	e.syncPrimarySelection()
 	// Notify IME of selection if it changed.
-	newSel := e.ime.selection
-	start, end := e.text.Selection()
-	newSel.rng = key.Range{
-		Start: start,
-		End:   end,
-	}
-	caretPos, carAsc, carDesc := e.text.CaretInfo()
-	newSel.caret = key.Caret{
-		Pos:     layout.FPt(caretPos),
-		Ascent:  float32(carAsc),
-		Descent: float32(carDesc),
-	}
-	if newSel != e.ime.selection {
-		e.ime.selection = newSel
-		gtx.Execute(key.SelectionCmd{Tag: e, Range: newSel.rng, Caret: newSel.caret})
-	}
+	e.syncIMESelection(gtx)
 
 	e.updateSnippet(gtx, e.ime.start, e.ime.end)
 	return event, ok
 }
 
 func (e *Editor) Layout(gtx layout.Context, lt *text.Shaper) layout.Dimensions {
+	e.frames.begin()
+	defer e.finishFrame()
+
 	for {
 		_, ok := e.Update(gtx)
 		if !ok {
@@ -212,10 +351,17 @@ func (e *Editor) Layout(gtx layout.Context, lt *text.Shaper) layout.Dimensions {
 	// Adjust scrolling for new viewport and layout.
 	e.text.ScrollRel(0, 0)
 
+	e.scrollToReveal()
+	e.scrollToRequested()
 	if e.scrollCaret {
 		e.scrollCaret = false
+		// following the caret interrupts the animations.
+		e.scrollAnim.stop()
 		e.text.ScrollToCaret()
 	}
+	e.animateScroll(gtx)
+	e.animatePalette(gtx)
+	e.animateFolds(gtx)
 
 	defer clip.Rect(image.Rectangle{Max: gtx.Constraints.Max}).Push(gtx.Ops).Pop()
 	e.scroller.Add(gtx.Ops)
@@ -223,12 +369,16 @@ func (e *Editor) Layout(gtx layout.Context, lt *text.Shaper) layout.Dimensions {
 		e.colorPalette.Background.Op(nil).Add(gtx.Ops)
 		paint.PaintOp{}.Add(gtx.Ops)
 	}
+	e.paintCurrentLine(gtx)
 
-	return layout.Flex{
+	dims := layout.Flex{
 		Axis: layout.Horizontal,
 	}.Layout(gtx,
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 			if e.gutterManager != nil && e.gutterManager.HasProviders() {
+				start := time.Now()
+				defer func() { e.frames.current.Gutter += time.Since(start) }()
+
 				ctx := e.buildGutterContext(gtx, lt)
 
 				// Process gutter events
@@ -263,9 +413,19 @@ func (e *Editor) Layout(gtx layout.Context, lt *text.Shaper) layout.Dimensions {
 			return layout.Dimensions{}
 		}),
 		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			start := time.Now()
 			// Set color offsets before layout
 			e.setColorOffsets(gtx)
 			e.text.Layout(gtx, lt)
+			e.startFoldAnimation(gtx)
+			e.snapshot.Store(e.text.LayoutSnapshot())
+			e.restoreZoomAnchor()
+			// the caret is moved by the scrolling and the new layout.
+			e.syncIMESelection(gtx)
+			e.frames.current.Layout += time.Since(start)
+
+			start = time.Now()
+			defer func() { e.frames.current.Paint += time.Since(start) }()
 			dims := e.layout(gtx, lt)
 			if e.completor != nil {
 				e.text.PaintOverlay(gtx, e.completor.Offset(), e.completor.Layout)
@@ -275,6 +435,20 @@ func (e *Editor) Layout(gtx layout.Context, lt *text.Shaper) layout.Dimensions {
 			return dims
 		}),
 	)
+
+	// Tooltips of gutter providers float over both the gutter and the text.
+	start := time.Now()
+	e.paintGutterTooltip(gtx, lt)
+	e.frames.current.Paint += time.Since(start)
+	return dims
+}
+
+// finishFrame records the statistics of the frame, and queues a
+// SlowFrameEvent if it exceeds the frame budget.
+func (e *Editor) finishFrame() {
+	if frame, slow := e.frames.finish(); slow {
+		e.pending = append(e.pending, SlowFrameEvent{Frame: frame, Budget: e.frames.budget})
+	}
 }
 
 func (e *Editor) layout(gtx layout.Context, shaper *text.Shaper) layout.Dimensions {
@@ -298,27 +472,23 @@ func (e *Editor) layout(gtx layout.Context, shaper *text.Shaper) layout.Dimensio
 		event.Op(gtx.Ops, e)
 	}
 
-	// e.scroller.Add(gtx.Ops)
-
 	// Only add event handlers if color picker is not open
 	if !colorPickerOpen {
 		e.clicker.Add(gtx.Ops)
 		e.dragger.Add(gtx.Ops)
 		e.hover.Add(gtx.Ops)
+		e.addPrimaryPasteHandler(gtx)
+		e.addDropHandler(gtx)
 	}
 	e.showCaret = false
 	if gtx.Focused(e) {
-		now := gtx.Now
-		dt := now.Sub(e.blinkStart)
-		blinking := dt < maxBlinkDuration
-		const timePerBlink = time.Second / blinksPerSecond
-		nextBlink := now.Add(timePerBlink/2 - dt%(timePerBlink/2))
-		if blinking {
+		var nextBlink time.Time
+		e.showCaret, nextBlink = e.caretVisible(gtx.Now)
+		if !nextBlink.IsZero() {
 			gtx.Execute(op.InvalidateCmd{At: nextBlink})
 		}
-		e.showCaret = !blinking || dt%timePerBlink < timePerBlink/2
 	}
-	semantic.Editor.Add(gtx.Ops)
+	e.addSemantics(gtx)
 
 	// determine the various colors to use.
 	if e.colorPalette == nil {
@@ -335,8 +505,12 @@ func (e *Editor) layout(gtx layout.Context, shaper *text.Shaper) layout.Dimensio
 		selectColor = textColor.MulAlpha(0x60)
 	}
 
+	e.validateExtraSelections()
+	e.validateLinkedEdit()
 	if e.Len() > 0 {
+		e.paintReadOnlyRegions(gtx)
 		e.paintSelection(gtx, selectColor)
+		e.paintRevealFlash(gtx, selectColor)
 		e.text.HighlightMatchingBrackets(gtx, selectColor.Op(gtx.Ops))
 		if e.wordHighlighter.IsDirty() {
 			e.wordHighlighter.HighlightAtCaret(e.colorPalette.SelectColor)
@@ -346,9 +520,23 @@ func (e *Editor) layout(gtx layout.Context, shaper *text.Shaper) layout.Dimensio
 		}
 
 		e.paintText(gtx, textColor)
+		e.paintFoldPlaceholders(gtx, shaper, textColor)
+		e.paintComposition(gtx, textColor)
+		e.paintUnicodeWarnings(gtx)
+		if e.wrapIndicators {
+			indicatorColor := textColor.MulAlpha(0x80)
+			e.text.PaintWrapIndicators(gtx, indicatorColor.Op(gtx.Ops))
+		}
+		if e.lineEndMarkers {
+			e.paintLineEndMarkers(gtx, shaper, textColor.MulAlpha(0x80))
+		}
+		e.paintErrorLens(gtx, shaper)
+		e.paintColorSwatches(gtx, textColor.MulAlpha(0x80))
 
 		e.renderColorIndicatorsInText(gtx, shaper)
 	}
+	// an empty document can show the lines deleted from it.
+	e.paintPhantomBlocks(gtx, shaper, textColor)
 
 	// Paint column selection if active
 	if e.ColumnEditEnabled() && len(e.columnEdit.selections) > 0 {
@@ -358,15 +546,34 @@ func (e *Editor) layout(gtx layout.Context, shaper *text.Shaper) layout.Dimensio
 	}
 
 	if gtx.Enabled() {
-		e.paintCaret(gtx, textColor)
+		e.paintCaret(gtx, shaper, textColor)
+		e.paintExtraCarets(gtx, textColor)
+		e.layoutSelectionHandles(gtx, textColor, selectColor)
 	}
 
+	e.layoutEmptyArea(gtx)
+
 	// Render sticky lines if enabled
-	e.renderStickyLines(gtx, shaper, textColor)
+	stickyHeight := e.renderStickyLines(gtx, shaper, textColor)
+	// Pin the header of the diff hunk at the top below the sticky lines.
+	e.renderStickyDiffHeader(gtx, shaper, textColor, stickyHeight)
+	// Preview the content of the hovered collapsed fold.
+	e.paintFoldPreview(gtx, shaper, textColor)
+	// Describe the character after the caret.
+	e.paintCharInspector(gtx, shaper, textColor)
 
 	return layout.Dimensions{Size: gtx.Constraints.Max}
 }
 
+// LayoutSnapshot returns the immutable snapshot of the last layout of the
+// text, or nil if the snapshots are not enabled by WithLayoutSnapshots. It is
+// safe to call it and read the snapshot from any goroutine, e.g., to render a
+// minimap or compute the geometry of overlays in the background, while the
+// editor lays out the next frame.
+func (e *Editor) LayoutSnapshot() *textview.LayoutSnapshot {
+	return e.snapshot.Load()
+}
+
 // PaintOverlay draws a overlay widget over the main editor area.
 func (e *Editor) PaintOverlay(gtx layout.Context, position image.Point, w layout.Widget) {
 	offset := position.Add(e.text.ScrollOff()).Add(image.Point{X: e.gutterWidth})
@@ -387,13 +594,32 @@ func (e *Editor) paintText(gtx layout.Context, material gvcolor.Color) {
 	e.text.PaintText(gtx, material.Op(gtx.Ops))
 }
 
+// visibleParagraphs returns the range [first, last) of the paragraphs
+// overlapping the viewport in the last layout.
+func (e *Editor) visibleParagraphs() (first, last int) {
+	paragraphs := e.text.TextLayout().Paragraphs
+	viewport := e.text.Viewport()
+	first = sort.Search(len(paragraphs), func(i int) bool {
+		return paragraphs[i].EndY+paragraphs[i].Descent.Ceil() >= viewport.Min.Y
+	})
+	last = first + sort.Search(len(paragraphs)-first, func(i int) bool {
+		p := paragraphs[first+i]
+		return p.StartY-p.Ascent.Ceil() > viewport.Max.Y
+	})
+	return first, last
+}
+
 // paintCaret paints the text glyphs using the provided material to set the fill material
 // of the caret rectangle.
-func (e *Editor) paintCaret(gtx layout.Context, material gvcolor.Color) {
+func (e *Editor) paintCaret(gtx layout.Context, shaper *text.Shaper, material gvcolor.Color) {
 	e.initBuffer()
 	if !e.showCaret || e.mode == ModeReadOnly {
 		return
 	}
+	if e.caretStyle.Shape != CaretBar {
+		e.paintShapedCaret(gtx, shaper, material)
+		return
+	}
 	e.text.PaintCaret(gtx, material.Op(gtx.Ops))
 }
 
@@ -494,6 +720,19 @@ func (e *Editor) Text() string {
 	return string(e.scratch)
 }
 
+// ReadRange returns the text between the rune offsets start and end.
+func (e *Editor) ReadRange(start, end int) string {
+	e.initBuffer()
+	if start > end {
+		start, end = end, start
+	}
+	startOff := e.buffer.RuneOffset(start)
+	endOff := e.buffer.RuneOffset(end)
+	buf := make([]byte, endOff-startOff)
+	n, _ := e.buffer.ReadAt(buf, int64(startOff))
+	return string(buf[:n])
+}
+
 // GetReader returns a [io.ReadSeeker] to the caller to read the text buffer. This
 // is the preferred way to read from the editor, especially when reading from
 // multiple goroutines.
@@ -501,8 +740,23 @@ func (e *Editor) GetReader() io.ReadSeeker {
 	return buffer.NewReader(e.text.Source())
 }
 
+// SetText replaces the text of the editor, and moves the caret to the start.
+// Invalid UTF-8 in s is handled by the policy set with WithInvalidUTF8Policy.
+// If s looks like binary content, the text is not set and a
+// BinaryContentEvent is generated instead.
 func (e *Editor) SetText(s string) {
 	e.initBuffer()
+	if e.isBinaryText(s) {
+		e.pending = append(e.pending, BinaryContentEvent{Data: []byte(s)})
+		return
+	}
+	s, rejected := e.checkUTF8("setText", s)
+	if rejected != nil {
+		e.pending = append(e.pending, *rejected)
+		return
+	}
+
+	e.closeHexView()
 
 	indent, _, size := GuessIndentation(s)
 	e.text.SoftTab = indent == Spaces
@@ -537,6 +791,23 @@ func (e *Editor) ConvertPos(line, col int) (runeOff int, pos f32.Point) {
 	return
 }
 
+// LineBounds returns the vertical extent of the 0-based line in the document
+// coordinates, the phantom blocks above it excluded. It reports false if the
+// line does not exist or is hidden by a collapsed fold.
+func (e *Editor) LineBounds(line int) (top, bottom int, ok bool) {
+	e.initBuffer()
+	paragraphs := e.text.TextLayout().Paragraphs
+	if line < 0 || line >= len(paragraphs) {
+		return 0, 0, false
+	}
+	if fm := e.text.FoldManager(); fm != nil && !fm.IsLineVisible(line) {
+		return 0, 0, false
+	}
+	para := paragraphs[line]
+	bounds := paragraphBounds(para.StartY, para.EndY, para.Ascent, para.Descent, e.text.GetLineHeight().Ceil())
+	return bounds.Min.Y, bounds.Max.Y, true
+}
+
 // Lines returns the total number of rendered logical lines.
 func (e *Editor) Lines() int {
 	e.initBuffer()
@@ -562,9 +833,20 @@ func (e *Editor) Delete(graphemeClusters int) (deletedRunes int) {
 
 	// Handle column editing mode
 	if e.ColumnEditEnabled() && len(e.columnEdit.selections) > 0 {
+		if e.denyReadOnlyColumns() {
+			return 0
+		}
 		return e.onColumnEditDelete(graphemeClusters)
 	}
+	if e.hasExtraSelections() {
+		if e.denyReadOnlySelections() {
+			return 0
+		}
+		return e.deleteAtSelections(graphemeClusters)
+	}
 
+	defer e.groupSnippetEdit()()
+	selStart, selEnd := e.text.Selection()
 	if graphemeClusters < 0 {
 		// update selection based on some rules.
 		e.onDeleteBackward()
@@ -579,10 +861,31 @@ func (e *Editor) Delete(graphemeClusters int) (deletedRunes int) {
 	e.text.MoveCaret(0, graphemeClusters)
 	// Get the new rune offsets of the selection.
 	start, end = e.text.Selection()
-	e.replace(start, end, "")
+	if e.denyReadOnlyEdit(start, end) {
+		e.text.SetCaret(selStart, selEnd)
+		return 0
+	}
+	if changed, ok := e.linkedReplace(start, end, ""); ok {
+		if !changed {
+			e.text.SetCaret(selStart, selEnd)
+			return 0
+		}
+		return end - start
+	}
+	switch _, result := e.replace(start, end, ""); result {
+	case editVetoed:
+		e.text.SetCaret(selStart, selEnd)
+		return 0
+	case editRewritten:
+		return end - start
+	}
 	// Reset xoff.
 	e.text.MoveCaret(0, 0)
 	e.ClearSelection()
+	if e.mode == ModeSnippet {
+		caret, _ := e.Selection()
+		e.snippetCtx.OnInsertAt(caret, caret)
+	}
 	return end - start
 }
 
@@ -592,11 +895,16 @@ func (e *Editor) DeleteLine() (deletedRunes int) {
 	e.initBuffer()
 
 	start, end := e.text.SelectedLineRange()
-	if start == end {
+	if start == end || e.denyReadOnlyEdit(start, end) {
 		return 0
 	}
 
-	e.replace(start, end, "")
+	switch _, result := e.replace(start, end, ""); result {
+	case editVetoed:
+		return 0
+	case editRewritten:
+		return end - start
+	}
 	// Reset xoff.
 	e.text.MoveCaret(0, 0)
 	e.SetCaret(start, start)
@@ -612,8 +920,30 @@ func (e *Editor) Insert(s string) (insertedRunes int) {
 		return
 	}
 
+	if e.hasExtraSelections() {
+		if e.denyReadOnlySelections() {
+			return 0
+		}
+		return e.replaceSelections(s)
+	}
+
 	start, end := e.text.Selection()
-	moves := e.replace(start, end, s)
+	if e.denyReadOnlyEdit(start, end) {
+		return 0
+	}
+	defer e.groupSnippetEdit()()
+	if changed, ok := e.linkedReplace(start, end, s); ok {
+		if !changed {
+			return 0
+		}
+		e.scrollCaret = true
+		return utf8.RuneCountInString(s)
+	}
+	moves, result := e.replace(start, end, s)
+	if result != editApplied {
+		e.scrollCaret = true
+		return moves
+	}
 	if end < start {
 		start = end
 	}
@@ -621,6 +951,9 @@ func (e *Editor) Insert(s string) (insertedRunes int) {
 	e.text.MoveCaret(0, 0)
 	e.SetCaret(start+moves, start+moves)
 	e.scrollCaret = true
+	if e.mode == ModeSnippet && len(e.snippetCtx.markers) > 0 {
+		e.snippetCtx.OnInsertAt(start, start+moves)
+	}
 	return moves
 }
 
@@ -645,7 +978,13 @@ func (e *Editor) InsertLine(s string) (insertedRunes int) {
 		// If s is a paragraph of text, insert s between the current line
 		// and the previous line.
 		start, end := e.text.SelectedLineRange()
-		moves := e.replace(start, start, s)
+		if e.denyReadOnlyEdit(start, start) {
+			return 0
+		}
+		moves, result := e.replace(start, start, s)
+		if result != editApplied {
+			return moves
+		}
 		// Reset xoff.
 		e.text.MoveCaret(0, 0)
 		e.SetCaret(end, end)
@@ -665,7 +1004,7 @@ func (e *Editor) DuplicateLine() (duplicatedRunes int) {
 	}
 
 	start, end := e.text.SelectedLineRange()
-	if start == end {
+	if start == end || e.denyReadOnlyEdit(end, end) {
 		return 0
 	}
 
@@ -682,9 +1021,10 @@ func (e *Editor) DuplicateLine() (duplicatedRunes int) {
 
 	// Duplicate the line: insert the line content at the end of current line
 	// Group operations for undo
-	e.buffer.GroupOp()
-	moves := e.replace(end, end, string(lineContent))
-	e.buffer.UnGroupOp()
+	moves, result := e.replace(end, end, string(lineContent))
+	if result != editApplied {
+		return moves
+	}
 
 	// Move caret to the end of the duplicated line
 	newCaretPos := end + moves
@@ -733,16 +1073,18 @@ func (e *Editor) redo() (EditorEvent, bool) {
 	return ChangeEvent{}, true
 }
 
-// replace the text between start and end with s. Indices are in runes.
-// It returns the number of runes inserted.
-func (e *Editor) replace(start, end int, s string) int {
+// applyReplace replaces the text between start and end with s, without
+// filtering the edit. Indices are in runes. It returns the number of runes
+// inserted.
+func (e *Editor) applyReplace(start, end int, s string) int {
 	length := e.text.Len()
 	if start > end {
 		start, end = end, start
 	}
-	start = min(start, length)
-	end = min(end, length)
+	start = min(max(start, 0), length)
+	end = min(max(end, 0), length)
 
+	e.dropEditedAutoInsertions(start, end)
 	sc := e.text.Replace(start, end, s)
 	newEnd := start + sc
 	adjust := func(pos int) int {
@@ -757,6 +1099,8 @@ func (e *Editor) replace(start, end int, s string) int {
 	}
 	e.ime.start = adjust(e.ime.start)
 	e.ime.end = adjust(e.ime.end)
+	e.ime.compose.Start = adjust(e.ime.compose.Start)
+	e.ime.compose.End = adjust(e.ime.compose.End)
 	e.text.UpdateSyntaxTokensOffset(start, end, newEnd)
 	return sc
 }
@@ -768,17 +1112,19 @@ func (e *Editor) ReplaceAll(texts []TextRange, newStr string) int {
 		return 0
 	}
 
-	// Traverse in reverse order to prevent match offsets from changing after
-	// each replace.
-	e.buffer.GroupOp()
-	finalPos := 0
-	for idx := len(texts) - 1; idx >= 0; idx-- {
-		start, end := texts[idx].Start, texts[idx].End
-		e.replace(start, end, newStr)
-		finalPos = start
+	// The occurrences are replaced as a single edit.
+	edits := make([]Edit, len(texts))
+	for i, r := range texts {
+		edits[i] = Edit{Start: r.Start, End: r.End, Text: newStr}
+	}
+	switch _, result := e.replaceEdits(edits); result {
+	case editVetoed:
+		return 0
+	case editRewritten:
+		return len(texts)
 	}
-	e.buffer.UnGroupOp()
 
+	finalPos := texts[0].Start
 	e.SetCaret(finalPos, finalPos)
 	return len(texts)
 }
@@ -897,13 +1243,21 @@ func (e *Editor) ScrollRatio() (minX, maxX float32, minY, maxY float32) {
 	visibleDims := e.text.Dimensions()
 	scrollOff := e.text.ScrollOff()
 
-	minX = float32(scrollOff.X) / float32(textDims.Size.X)
-	maxX = float32(scrollOff.X+visibleDims.Size.X) / float32(textDims.Size.X)
-	minY = float32(scrollOff.Y) / float32(textDims.Size.Y)
-	maxY = float32(scrollOff.Y+visibleDims.Size.Y) / float32(textDims.Size.Y)
+	minX, maxX = scrollRatio(scrollOff.X, visibleDims.Size.X, textDims.Size.X)
+	minY, maxY = scrollRatio(scrollOff.Y, visibleDims.Size.Y, textDims.Size.Y)
 	return
 }
 
+// scrollRatio returns the start and end of the viewport of size visible
+// scrolled by off, relative to the document size. An empty document is
+// entirely visible.
+func scrollRatio(off, visible, size int) (start, end float32) {
+	if size <= 0 {
+		return 0, 1
+	}
+	return float32(off) / float32(size), float32(off+visible) / float32(size)
+}
+
 // Scroll scrolls the horizontal or vertical scrollbar, using ratio related to
 // the rendered document size.
 func (e *Editor) Scroll(gtx layout.Context, xRatio, yRatio float32) {
@@ -916,6 +1270,21 @@ func (e *Editor) Scroll(gtx layout.Context, xRatio, yRatio float32) {
 	e.text.ScrollRel(int(float32(textDims.X)*xRatio), int(float32(textDims.Y)*yRatio))
 }
 
+// ScrollOffset returns the scroll offset of the text viewport in pixels.
+func (e *Editor) ScrollOffset() image.Point {
+	e.initBuffer()
+	return e.text.ScrollOff()
+}
+
+// SetScrollOffset scrolls the text viewport to the offset in pixels, clamped
+// to the bounds of the document, e.g., to keep two editors scrolled together.
+func (e *Editor) SetScrollOffset(off image.Point) {
+	e.initBuffer()
+	cur := e.text.ScrollOff()
+	e.scrollAnim.stop()
+	e.text.ScrollRel(off.X-cur.X, off.Y-cur.Y)
+}
+
 // GutterWidth returns the width of the gutter in pixel, which can be used to
 // guide to set the horizontal offset when laying out a horizontal scrollbar.
 func (e *Editor) GutterWidth() int {
@@ -927,10 +1296,30 @@ func (e *Editor) ReadOnly() bool {
 	return e.mode == ModeReadOnly
 }
 
+// WrapLineEnabled reports whether the lines are wrapped at the width of the
+// editor.
+func (e *Editor) WrapLineEnabled() bool {
+	e.initBuffer()
+	return e.text.WrapLine
+}
+
+// SetWrapLine enables or disables the wrapping of the lines. The lines are
+// scrolled horizontally when they are not wrapped.
+func (e *Editor) SetWrapLine(enabled bool) {
+	e.initBuffer()
+	e.text.SetWrapLine(enabled)
+}
+
 func (e *Editor) Mode() EditorMode {
 	return e.mode
 }
 
+// SetTextInputHook sets the hook called before the text typed by the user is
+// inserted. A nil hook removes it.
+func (e *Editor) SetTextInputHook(hook TextInputHook) {
+	e.onInput = hook
+}
+
 func (e *Editor) TabStyle() (TabStyle, int) {
 	if e.text.SoftTab {
 		return Spaces, e.text.TabWidth
@@ -980,10 +1369,9 @@ func (e *Editor) onColumnEditDelete(graphemeClusters int) (deletedRunes int) {
 	// Approximate: average character width of 10 pixels
 	deletedWidth := abs(graphemeClusters) * 10
 
-	// Group operations for undo
-	e.buffer.GroupOp()
-
-	// Delete at each column cursor position
+	// Find the range to delete at each column cursor position, deleted as a
+	// single edit.
+	edits := make([]Edit, len(e.columnEdit.selections))
 	for i := range e.columnEdit.selections {
 		cursor := &e.columnEdit.selections[i]
 
@@ -997,55 +1385,51 @@ func (e *Editor) onColumnEditDelete(graphemeClusters int) (deletedRunes int) {
 
 		if graphemeClusters > 0 {
 			// Delete forward
-			end = runeOff + graphemeClusters
+			end = e.text.MoveGraphemes(runeOff, graphemeClusters)
 		} else {
 			// Delete backward
-			start = runeOff + graphemeClusters
+			start = e.text.MoveGraphemes(runeOff, graphemeClusters)
+			// an auto-inserted pair around the caret is deleted together.
+			if graphemeClusters == -1 && e.takeAutoPair(runeOff) {
+				end++
+			}
 		}
 
 		// Clamp to valid range
-		if start < 0 {
-			start = 0
-		}
-		if end > e.Len() {
-			end = e.Len()
+		edits[i] = Edit{Start: max(start, 0), End: min(end, e.Len())}
+		deletedRunes += edits[i].End - edits[i].Start
+	}
+	if _, result := e.replaceEdits(edits); result != editApplied {
+		e.scrollCaret = true
+		if result == editVetoed {
+			return 0
 		}
+		return deletedRunes
+	}
 
-		if start != end {
-			// Delete the text at this position
-			deleted := end - start
-			e.replace(start, end, "")
-			deletedRunes += deleted
-			println("[ColumnEdit] Deleted", deleted, "runes from line:", cursor.line)
-
-			// Adjust other cursor positions to account for deletion
-			for j := i + 1; j < len(e.columnEdit.selections); j++ {
-				if e.columnEdit.selections[j].line > cursor.line {
-					// Adjust cursor position for lines below
-					break
-				}
-			}
+	for i := range e.columnEdit.selections {
+		cursor := &e.columnEdit.selections[i]
+		if edits[i].Start == edits[i].End {
+			continue
+		}
+		println("[ColumnEdit] Deleted", edits[i].End-edits[i].Start, "runes from line:", cursor.line)
 
-			// Move the cursor back if we deleted backward
-			if graphemeClusters < 0 {
-				cursor.col += graphemeClusters
-				if cursor.col < 0 {
-					cursor.col = 0
-				}
+		// Move the cursor back if we deleted backward
+		if graphemeClusters < 0 {
+			cursor.col += graphemeClusters
+			if cursor.col < 0 {
+				cursor.col = 0
 			}
+		}
 
-			// Shrink the rectangle (reduce endX)
-			cursor.endX -= deletedWidth
-			// Ensure minimum width
-			if cursor.endX-cursor.startX < 2 {
-				cursor.endX = cursor.startX + 2
-			}
+		// Shrink the rectangle (reduce endX)
+		cursor.endX -= deletedWidth
+		// Ensure minimum width
+		if cursor.endX-cursor.startX < 2 {
+			cursor.endX = cursor.startX + 2
 		}
 	}
 
-	e.buffer.UnGroupOp()
-	println("[ColumnEdit] onColumnEditDelete completed, total deleted:", deletedRunes)
-
 	e.scrollCaret = true
 	e.scroller.Stop()
 	e.text.MoveCaret(0, 0)
@@ -1068,14 +1452,14 @@ func (e *Editor) moveColumnCarets(delta int) {
 	for i := range e.columnEdit.selections {
 		cursor := &e.columnEdit.selections[i]
 
-		// Calculate the new column position
-		newCol := cursor.col + delta
-
 		// Get line length to ensure we don't go beyond line boundaries
 		lineRuneOff := e.text.ConvertPos(cursor.line, 0)
 		nextLineRuneOff := e.text.ConvertPos(cursor.line+1, 0)
 		lineLength := nextLineRuneOff - lineRuneOff
 
+		// Calculate the new column position, moving by grapheme clusters
+		newCol := e.text.MoveGraphemes(lineRuneOff+cursor.col, delta) - lineRuneOff
+
 		// Clamp to line boundaries
 		if newCol < 0 {
 			newCol = 0
@@ -1123,6 +1507,7 @@ func (e *Editor) moveColumnCarets(delta int) {
 		cursor.col = newCol
 	}
 
+	e.syncColumnCaret()
 	e.scrollCaret = true
 }
 
@@ -1172,7 +1557,9 @@ func (e *Editor) updateColumnSelection(_ layout.Context, pos image.Point) {
 			continue
 		}
 
-		screenY := lineNum*lineHeight - scrollOff.Y
+		// the middle of the line, as its top is also the bottom of the line
+		// above.
+		screenY := lineNum*lineHeight + lineHeight/2 - scrollOff.Y
 		startPos := image.Point{X: startX, Y: screenY}
 
 		// Query the column position for this line
@@ -1188,6 +1575,7 @@ func (e *Editor) updateColumnSelection(_ layout.Context, pos image.Point) {
 				})
 		}
 	}
+	e.syncColumnCaret()
 }
 
 func (s ChangeEvent) isEditorEvent()        {}
@@ -1214,178 +1602,17 @@ type StickyLineEventWrapper struct {
 
 func (StickyLineEventWrapper) isEditorEvent() {}
 
-// renderStickyLines renders sticky lines at the top of the editor viewport.
-// Sticky lines show code structure (functions, types, etc.) that has been scrolled
-// out of view, helping users maintain context.
-func (e *Editor) renderStickyLines(gtx layout.Context, shaper *text.Shaper, textColor gvcolor.Color) {
-	if e.gutterManager == nil {
-		return
-	}
-
-	var stickyProvider interface {
-		GetStickyLinesInfo() ([]struct {
-			Line   int
-			Text   string
-			Indent int
-			Type   string
-		}, int)
-		HandleStickyLineClick(y int) bool
-	}
-
-	for _, p := range e.gutterManager.Providers() {
-		if p.ID() == "stickylines" {
-			if provider, ok := p.(interface {
-				GetStickyLinesInfo() ([]struct {
-					Line   int
-					Text   string
-					Indent int
-					Type   string
-				}, int)
-				HandleStickyLineClick(y int) bool
-			}); ok {
-				stickyProvider = provider
-				break
-			}
-		}
-	}
-
-	if stickyProvider == nil {
-		return
-	}
-
-	stickyLines, stickyHeight := stickyProvider.GetStickyLinesInfo()
-	if len(stickyLines) == 0 || stickyHeight == 0 {
-		return
-	}
-
-	lineHeight := e.text.GetLineHeight().Round()
-
-	// Set up colors
-	var bgColor, borderColor color.NRGBA
-	if e.colorPalette != nil && e.colorPalette.Background.IsSet() {
-		bgColor = e.colorPalette.Background.NRGBA()
-	} else {
-		bgColor = color.NRGBA{R: 0xF0, G: 0xF0, B: 0xF0, A: 0xD0}
-	}
-	bgColor.A = 0xD0 // Slight transparency
-
-	textColorNRGBA := textColor.NRGBA()
-	borderColor = textColorNRGBA
-	borderColor.A = 0x40
-
-	// Render each sticky line (MUST render before processing events!)
-	for i, sticky := range stickyLines {
-		stickyY := i * lineHeight
-
-		// Draw background
-		bgRect := image.Rect(0, stickyY, gtx.Constraints.Max.X, stickyY+lineHeight)
-		bgStack := clip.Rect(bgRect).Push(gtx.Ops)
-		paint.ColorOp{Color: bgColor}.Add(gtx.Ops)
-		paint.PaintOp{}.Add(gtx.Ops)
-		bgStack.Pop()
-
-		// Draw border at bottom
-		if i < len(stickyLines)-1 {
-			borderRect := image.Rect(0, stickyY+lineHeight-1, gtx.Constraints.Max.X, stickyY+lineHeight)
-			borderStack := clip.Rect(borderRect).Push(gtx.Ops)
-			paint.ColorOp{Color: borderColor}.Add(gtx.Ops)
-			paint.PaintOp{}.Add(gtx.Ops)
-			borderStack.Pop()
-		}
-
-		// Register click area
-		pointer.CursorPointer.Add(gtx.Ops)
-		clip.Rect(bgRect).Push(gtx.Ops).Pop()
-		e.stickyLinesClicker.Add(gtx.Ops)
-
-		// Draw text
-		params := e.text.Params()
-		params.MinWidth = 0
-		params.MaxLines = 1
-
-		// Trim whitespace for display
-		displayText := strings.TrimLeft(sticky.Text, "\t")
-		displayText = strings.TrimRight(displayText, " \t\r\n")
-
-		if displayText != "" && shaper != nil {
-			shaper.LayoutString(params, displayText)
-
-			glyphs := make([]text.Glyph, 0)
-			for {
-				g, ok := shaper.NextGlyph()
-				if !ok {
-					break
-				}
-				glyphs = append(glyphs, g)
-			}
-
-			if len(glyphs) > 0 {
-				// Transform to the correct position
-				yPos := float32(stickyY) + float32(lineHeight)/2
-				trans := op.Affine(f32.Affine2D{}.Offset(
-					f32.Point{X: float32(glyphs[0].X.Floor()) + 8, Y: yPos},
-				)).Push(gtx.Ops)
-
-				// Draw the glyphs
-				path := shaper.Shape(glyphs)
-				outline := clip.Outline{Path: path}.Op().Push(gtx.Ops)
-
-				paint.ColorOp{Color: textColorNRGBA}.Add(gtx.Ops)
-				paint.PaintOp{}.Add(gtx.Ops)
-
-				outline.Pop()
-				trans.Pop()
-			}
-		}
-	}
-
-	for {
-		evt, ok := e.stickyLinesClicker.Update(gtx.Source)
-		if !ok {
-			break
-		}
-
-		if evt.Kind == gesture.KindClick {
-			clickY := int(evt.Position.Y)
-			stickyLineIndex := clickY / lineHeight
-
-			if stickyLineIndex >= 0 && stickyLineIndex < len(stickyLines) {
-				targetLine := stickyLines[stickyLineIndex].Line
-				e.moveToLine(targetLine)
-
-				stickyProvider.HandleStickyLineClick(clickY)
-
-				e.pending = append(e.pending, StickyLineEventWrapper{
-					Event: gutter.StickyLineEvent{
-						Line: targetLine,
-						Text: stickyLines[stickyLineIndex].Text,
-					},
-				})
-			}
-		}
-	}
-}
-
-// moveToLine scrolls the editor to make the specified line visible at the top.
-func (e *Editor) moveToLine(lineNum int) {
-	textLayout := e.text.TextLayout()
-	paragraphs := textLayout.Paragraphs
-
-	if lineNum < 0 || lineNum >= len(paragraphs) {
-		return
-	}
-
-	para := paragraphs[lineNum]
-	scrollOff := e.text.ScrollOff()
-
-	// Scroll to make this line visible near the top
-	e.text.ScrollRel(0, para.StartY-scrollOff.Y)
+// setColorOffsets reserves the space of the color indicators and of the
+// color swatches in the text layout.
+func (e *Editor) setColorOffsets(gtx layout.Context) {
+	e.mergeColorOffsets(e.colorIndicatorOffsets(gtx))
 }
 
-// renderColorPickerOverlay renders the color picker overlay if needed.
-func (e *Editor) setColorOffsets(gtx layout.Context) {
+// colorIndicatorOffsets returns the offsets of the color indicators of the
+// color indicator provider.
+func (e *Editor) colorIndicatorOffsets(gtx layout.Context) map[int]map[int]int {
 	if e.gutterManager == nil {
-		return
+		return nil
 	}
 
 	// Find the color indicator provider
@@ -1402,13 +1629,13 @@ func (e *Editor) setColorOffsets(gtx layout.Context) {
 	}
 
 	if colorPickerProvider == nil {
-		return
+		return nil
 	}
 
 	// Get color offsets from the provider
 	colorOffsets := colorPickerProvider.GetColorOffsets()
 	if len(colorOffsets) == 0 {
-		return
+		return nil
 	}
 
 	// Convert color offsets to the format expected by text layout
@@ -1423,10 +1650,10 @@ func (e *Editor) setColorOffsets(gtx layout.Context) {
 		layoutOffsets[line] = lineOffsets
 	}
 
-	// Set the color offsets in the text layout
-	e.text.SetColorOffsets(layoutOffsets)
+	return layoutOffsets
 }
 
+// renderColorPickerOverlay renders the color picker overlay if needed.
 func (e *Editor) renderColorPickerOverlay(gtx layout.Context) {
 	if e.gutterManager == nil {
 		return
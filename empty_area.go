@@ -0,0 +1,37 @@
+package gvcode
+
+import (
+	"image"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+)
+
+// layoutEmptyArea lays out the host provided widget in the area below the
+// last line of text, if the area is visible.
+func (e *Editor) layoutEmptyArea(gtx layout.Context) {
+	e.emptyAreaRect = image.Rectangle{}
+	if e.emptyArea == nil {
+		return
+	}
+
+	textBottom := e.text.FullDimensions().Size.Y - e.text.ScrollOff().Y
+	if textBottom >= gtx.Constraints.Max.Y {
+		return
+	}
+	textBottom = max(0, textBottom)
+
+	e.emptyAreaRect = image.Rect(0, textBottom, gtx.Constraints.Max.X, gtx.Constraints.Max.Y)
+	defer op.Offset(e.emptyAreaRect.Min).Push(gtx.Ops).Pop()
+	defer clip.Rect(image.Rectangle{Max: e.emptyAreaRect.Size()}).Push(gtx.Ops).Pop()
+
+	gtx.Constraints = layout.Exact(e.emptyAreaRect.Size())
+	e.emptyArea(gtx)
+}
+
+// inEmptyArea reports whether pos falls in the area occupied by the empty
+// area widget. Pointer events landing there belong to the widget.
+func (e *Editor) inEmptyArea(pos image.Point) bool {
+	return e.emptyArea != nil && pos.In(e.emptyAreaRect)
+}
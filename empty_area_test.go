@@ -0,0 +1,68 @@
+package gvcode
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+func TestEmptyAreaWidget(t *testing.T) {
+	var size image.Point
+	calls := 0
+	e := &Editor{}
+	e.WithOptions(WithColorScheme(syntax.ColorScheme{}), WithTextSize(14),
+		WithEmptyAreaWidget(func(gtx layout.Context) layout.Dimensions {
+			calls++
+			size = gtx.Constraints.Max
+			return layout.Dimensions{Size: size}
+		}))
+	e.SetText("a\nb\nc")
+
+	var router input.Router
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	frame := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 200)), Source: router.Source()}
+		for {
+			if _, ok := e.Update(gtx); !ok {
+				break
+			}
+		}
+		e.Layout(gtx, shaper)
+		router.Frame(gtx.Ops)
+	}
+	frame()
+
+	// the widget fills the area below the last line.
+	textHeight := e.text.FullDimensions().Size.Y
+	if calls != 1 || size != image.Pt(400, 200-textHeight) {
+		t.Fatalf("got the widget laid out %d times with size %v, want once with %v", calls, size, image.Pt(400, 200-textHeight))
+	}
+
+	// clicks in the area do not move the caret.
+	e.SetCaret(1, 1)
+	pos := layout.FPt(image.Pt(10, 190))
+	router.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: pos},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: pos},
+	)
+	frame()
+	if start, end := e.Selection(); start != 1 || end != 1 {
+		t.Errorf("got selection %d-%d after clicking the empty area, want the caret kept at 1", start, end)
+	}
+
+	// the widget is not laid out if the text fills the viewport.
+	e.SetText(strings.Repeat("line\n", 100))
+	calls = 0
+	frame()
+	if calls != 0 {
+		t.Errorf("got the widget laid out %d times below a text filling the viewport", calls)
+	}
+}
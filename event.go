@@ -4,17 +4,18 @@ import (
 	"image"
 	"io"
 	"math"
+	"slices"
 	"strings"
 	"unicode"
 	"unicode/utf8"
 
 	"gioui.org/gesture"
-	"gioui.org/io/clipboard"
 	"gioui.org/io/event"
 	"gioui.org/io/key"
 	"gioui.org/io/pointer"
 	"gioui.org/io/transfer"
 	"gioui.org/layout"
+	"github.com/oligo/gvcode/internal/buffer"
 	gestureExt "github.com/oligo/gvcode/internal/gesture"
 	"github.com/oligo/gvcode/textview"
 )
@@ -29,6 +30,7 @@ func (e *Editor) processEvents(gtx layout.Context) (ev EditorEvent, ok bool) {
 	defer func() {
 		afterSelStart, afterSelEnd := e.Selection()
 		if selStart != afterSelStart || selEnd != afterSelEnd {
+			e.dropAutoInsertionsOffCaretLine()
 			// Selection changed - mark word highlighter active regardless
 			// of whether SelectEvent is returned now or queued for later
 			e.wordHighlighter.MarkActive(true)
@@ -58,6 +60,10 @@ func (e *Editor) processEvents(gtx layout.Context) (ev EditorEvent, ok bool) {
 }
 
 func (e *Editor) processPointer(gtx layout.Context) (EditorEvent, bool) {
+	// the selection handles grab their touches from the scroller and the
+	// editor below them.
+	e.processSelectionHandles(gtx)
+
 	var scrollX, scrollY pointer.ScrollRange
 	textDims := e.text.FullDimensions()
 	visibleDims := e.text.Dimensions()
@@ -71,18 +77,26 @@ func (e *Editor) processPointer(gtx layout.Context) (EditorEvent, bool) {
 	scrollY.Max = max(0, textDims.Size.Y-(scrollOffY+visibleDims.Size.Y))
 	sbounds := e.text.ScrollBounds()
 
-	var soff, smin, smax int
-	sdist := e.scroller.Update(gtx.Metric, gtx.Source, gtx.Now, scrollX, scrollY)
+	sdists := e.scroller.Update(gtx.Metric, gtx.Source, gtx.Now, scrollX, scrollY)
+	if sdists != (image.Point{}) {
+		// scrolling by hand interrupts the animations.
+		e.scrollAnim.stop()
+	}
+	e.text.ScrollRel(sdists.X, sdists.Y)
+	if ev, ok := e.zoomBy(e.scroller.Zoom()); ok {
+		return ev, ok
+	}
+	// a fling moves along a single axis.
+	sdist, soff, smin, smax := sdists.Y, e.text.ScrollOff().Y, sbounds.Min.Y, sbounds.Max.Y
 	if e.scroller.Direction() == gestureExt.Horizontal {
-		e.text.ScrollRel(sdist, 0)
-		soff = e.text.ScrollOff().X
-		smin, smax = sbounds.Min.X, sbounds.Max.X
-	} else {
-		e.text.ScrollRel(0, sdist)
-		soff = e.text.ScrollOff().Y
-		smin, smax = sbounds.Min.Y, sbounds.Max.Y
+		sdist, soff, smin, smax = sdists.X, e.text.ScrollOff().X, sbounds.Min.X, sbounds.Max.X
 	}
 
+	e.trackDropPosition(gtx)
+	e.checkLongPress(gtx)
+	if ev, ok := e.processPrimaryPaste(gtx); ok {
+		return ev, ok
+	}
 	for {
 		evt, ok := e.clicker.Update(gtx.Source)
 		if !ok {
@@ -111,6 +125,7 @@ func (e *Editor) processPointer(gtx layout.Context) (EditorEvent, bool) {
 	// detects hover event.
 	hoverEvent, ok := e.hover.Update(gtx)
 	if ok {
+		e.updateHoveredPlaceholder(hoverEvent)
 		switch hoverEvent.Kind {
 		case gestureExt.KindHovered:
 			line, col, runeOff := e.text.QueryPos(hoverEvent.Position)
@@ -128,10 +143,18 @@ func (e *Editor) processPointer(gtx layout.Context) (EditorEvent, bool) {
 func (e *Editor) processPointerEvent(gtx layout.Context, ev event.Event) (EditorEvent, bool) {
 	switch evt := ev.(type) {
 	case gesture.ClickEvent:
+		if e.inEmptyArea(evt.Position) || e.inStickyArea(evt.Position) {
+			// the empty area widget and the sticky lines take priority over
+			// caret placement.
+			break
+		}
 		switch {
 		case evt.Kind == gesture.KindPress && evt.Source == pointer.Mouse,
 			evt.Kind == gesture.KindClick && evt.Source != pointer.Mouse:
 			prevCaretPos, _ := e.text.Selection()
+			if evt.Source == pointer.Mouse {
+				e.hideSelectionHandles()
+			}
 			e.blinkStart = gtx.Now
 			e.text.MoveCoord(image.Point{
 				X: int(math.Round(float64(evt.Position.X))),
@@ -184,7 +207,19 @@ func (e *Editor) processPointerEvent(gtx layout.Context, ev event.Event) (Editor
 				e.setMode(ModeNormal)
 			}
 		}
+
+		// a plain click on a clickable decoration runs its handler, after
+		// placing the caret.
+		if evt.Kind == gesture.KindClick && evt.NumClicks == 1 && evt.Modifiers == 0 && e.text.SelectionLen() == 0 {
+			if _, _, runeOff := e.text.QueryPos(evt.Position); runeOff >= 0 {
+				e.clickDecoration(runeOff)
+			}
+		}
 	case pointer.Event:
+		if evt.Source == pointer.Touch {
+			e.processTouch(gtx, evt)
+			break
+		}
 		release := false
 		switch {
 		case evt.Kind == pointer.Release && evt.Source == pointer.Mouse:
@@ -237,9 +272,14 @@ func (e *Editor) processKey(gtx layout.Context) (EditorEvent, bool) {
 }
 
 func (e *Editor) processEditEvents(gtx layout.Context) EditorEvent {
+	if evt := e.processBrowserEvents(gtx); evt != nil {
+		return evt
+	}
+
 	filters := []event.Filter{
 		key.FocusFilter{Target: e},
 		transfer.TargetFilter{Target: e, Type: "application/text"},
+		transfer.TargetFilter{Target: e, Type: uriListMIME},
 	}
 
 	for {
@@ -254,12 +294,18 @@ func (e *Editor) processEditEvents(gtx layout.Context) EditorEvent {
 		case key.FocusEvent:
 			// Reset IME state.
 			e.ime.imeState = imeState{}
+			e.pendingKeys = nil
 			if ke.Focus && e.mode != ModeReadOnly {
 				gtx.Execute(key.SoftKeyboardCmd{Show: true})
 			}
 		case key.SnippetEvent:
 			e.updateSnippet(gtx, ke.Start, ke.End)
 		case key.EditEvent:
+			// typing text cancels the pending chord.
+			e.pendingKeys = nil
+			if e.onInput != nil && e.onInput(gtx, ke.Text) {
+				break
+			}
 			e.onTextInput(ke)
 		case key.SelectionEvent:
 			e.scrollCaret = true
@@ -268,6 +314,12 @@ func (e *Editor) processEditEvents(gtx layout.Context) EditorEvent {
 
 			// Complete a paste event, initiated by Shortcut-V in Editor.command().
 		case transfer.DataEvent:
+			if ke.Type == uriListMIME {
+				if evt := e.onFileDrop(ke); evt != nil {
+					return evt
+				}
+				break
+			}
 			if evt := e.onPasteEvent(ke); evt != nil {
 				return evt
 			}
@@ -324,7 +376,14 @@ func (e *Editor) updateSnippet(gtx layout.Context, start, end int) {
 	gtx.Execute(key.SnippetCmd{Tag: e, Snippet: newSnip})
 }
 
-func (e *Editor) onCopyCut(gtx layout.Context, k key.Event) EditorEvent {
+func (e *Editor) onCopyCut(gtx layout.Context, cut bool) EditorEvent {
+	if e.ColumnEditEnabled() && len(e.columnEdit.selections) > 0 {
+		return e.onColumnCopyCut(gtx, cut)
+	}
+	if e.hasExtraSelections() {
+		return e.onSelectionsCopyCut(gtx, cut)
+	}
+
 	lineOp := false
 	if e.text.SelectionLen() == 0 {
 		lineOp = true
@@ -337,8 +396,8 @@ func (e *Editor) onCopyCut(gtx layout.Context, k key.Event) EditorEvent {
 	}
 
 	if text := string(e.scratch); text != "" {
-		gtx.Execute(clipboard.WriteCmd{Type: "application/text", Data: io.NopCloser(strings.NewReader(text))})
-		if k.Name == "X" && e.mode != ModeReadOnly {
+		e.WriteClipboard(gtx, text)
+		if cut && e.mode != ModeReadOnly {
 			if !lineOp {
 				if e.Delete(1) != 0 {
 					return ChangeEvent{}
@@ -354,18 +413,52 @@ func (e *Editor) onCopyCut(gtx layout.Context, k key.Event) EditorEvent {
 	return nil
 }
 
+// onColumnCopyCut copies or cuts the column selections as one clipboard entry
+// per caret.
+func (e *Editor) onColumnCopyCut(gtx layout.Context, cut bool) EditorEvent {
+	text, lineOp := e.columnClipboardText()
+	if text == "" {
+		return nil
+	}
+
+	e.WriteClipboard(gtx, text)
+	if cut && e.mode != ModeReadOnly && !e.denyReadOnlyColumns() {
+		if e.cutColumns(lineOp) != 0 {
+			return ChangeEvent{}
+		}
+	}
+
+	return nil
+}
+
+// onSelectionsCopyCut copies or cuts the selections of SelectNextOccurrence
+// and SelectAllOccurrences as one clipboard entry per selection.
+func (e *Editor) onSelectionsCopyCut(gtx layout.Context, cut bool) EditorEvent {
+	text, lineOp := e.selectionsClipboardText()
+	if text == "" {
+		return nil
+	}
+
+	e.WriteClipboard(gtx, text)
+	if cut && e.mode != ModeReadOnly && !e.denyReadOnlySelections() {
+		if e.cutSelections(lineOp) != 0 {
+			return ChangeEvent{}
+		}
+	}
+
+	return nil
+}
+
 // onTab handles tab key event. If there is no selection of lines, intert a tab character
-// at position of the cursor, else indent or unindent the selected lines, depending on if
-// the event contains the shift modifier.
-func (e *Editor) onTab(k key.Event) EditorEvent {
+// at position of the cursor, else indent or unindent the selected lines, depending on
+// unindent.
+func (e *Editor) onTab(unindent bool) EditorEvent {
 	if e.mode == ModeReadOnly {
 		return nil
 	}
 
-	shiftPressed := k.Modifiers.Contain(key.ModShift)
-
 	if e.mode == ModeSnippet {
-		if shiftPressed {
+		if unindent {
 			e.snippetCtx.PrevTabStop()
 		} else {
 			e.snippetCtx.NextTabStop()
@@ -373,7 +466,15 @@ func (e *Editor) onTab(k key.Event) EditorEvent {
 		return nil
 	}
 
-	if e.text.IndentLines(shiftPressed) > 0 {
+	if start, end := e.text.Selection(); start == end && !unindent {
+		if e.denyReadOnlyEdit(start, end) {
+			return nil
+		}
+	} else if e.denyReadOnlyLines() {
+		return nil
+	}
+
+	if e.text.IndentLines(unindent) > 0 {
 		// Reset xoff.
 		e.text.MoveCaret(0, 0)
 		e.scrollCaret = true
@@ -388,60 +489,87 @@ func (e *Editor) onTextInput(ke key.EditEvent) {
 		return
 	}
 
-	if e.autoInsertions == nil {
-		e.autoInsertions = make(map[int]rune)
+	if e.ColumnEditEnabled() && len(e.columnEdit.selections) > 1 {
+		if e.denyReadOnlyColumns() {
+			return
+		}
+	} else if e.denyReadOnlyEdit(ke.Range.Start, ke.Range.End) {
+		return
+	}
+	defer e.groupSnippetEdit()()
+
+	if e.hasExtraSelections() {
+		if start, end := e.text.Selection(); min(start, end) == ke.Range.Start && max(start, end) == ke.Range.End {
+			if !e.denyReadOnlySelections() {
+				e.replaceSelections(e.normalizeText(ke.Text))
+				e.lastInput = nil
+			}
+			return
+		}
+		// an input method editing another range ends the multi-selection.
+		e.clearExtraSelections()
+	}
+
+	ke.Range.Start, ke.Range.End, ke.Text = e.normalizeInput(ke.Range.Start, ke.Range.End, ke.Text)
+
+	if changed, ok := e.linkedReplace(ke.Range.Start, ke.Range.End, ke.Text); ok {
+		if changed {
+			e.scrollCaret = true
+			e.scroller.Stop()
+			// completions would only replace the edited occurrence.
+			e.lastInput = nil
+		}
+		return
 	}
 
 	// check if the input character is a bracket or a quote.
 	r := []rune(ke.Text)[0]
 	counterpart, isOpening := e.text.BracketsQuotes.GetCounterpart(r)
 
-	if counterpart > 0 && isOpening {
-		// Assume we will auto-insert by default.
-		shouldAutoInsert := true
-
-		if counterpart != r {
-			// only check the next char.
-			if e.isNearWordChar(ke.Range.Start, false) {
-				shouldAutoInsert = false
-			}
-		} else {
-			// check both the previous and next char.
-			if e.isNearWordChar(ke.Range.Start, true) || e.isNearWordChar(ke.Range.Start, false) {
-				shouldAutoInsert = false
-			}
+	if e.ColumnEditEnabled() && len(e.columnEdit.selections) > 1 {
+		// Brackets and quotes are paired at each caret, unless the input
+		// continues a composition.
+		if counterpart == 0 || !e.onColumnPairInput(ke, r, counterpart, isOpening) {
+			e.onColumnTextInput(ke)
 		}
-
+	} else if counterpart > 0 && (!isOpening || counterpart == r) && ke.Range.Start == ke.Range.End &&
+		e.takeAutoInsertion(ke.Range.Start, r) {
+		// The input is a closing part that was just auto-inserted to the right
+		// of the caret, over-type it by moving the caret past it.
+		e.text.MoveCaret(1, 1)
+	} else if counterpart > 0 && isOpening && ke.Range.Start != ke.Range.End {
+		// Surround the selection with the pair, and keep the selection
+		// on the surrounded text.
+		selStart, selEnd := e.text.Selection()
+		selected := e.ReadRange(ke.Range.Start, ke.Range.End)
+		if _, result := e.replace(ke.Range.Start, ke.Range.End, ke.Text+selected+string(counterpart)); result == editApplied {
+			e.text.SetCaret(selStart+1, selEnd+1)
+		}
+		// the selection is kept, do not reset the caret.
+		e.scrollCaret = true
+		e.scroller.Stop()
+		e.lastInput = nil
+		e.snippetCtx.OnInsertAt(e.Selection())
+		return
+	} else if pair, ok := e.matchPairOpening(ke); ok {
+		// The input completes the opening half of a multi-rune pair.
+		if _, result := e.replace(ke.Range.Start, ke.Range.End, ke.Text+pair.Closing); result == editApplied {
+			caret := ke.Range.Start + utf8.RuneCountInString(ke.Text)
+			e.text.SetCaret(caret, caret)
+		}
+	} else if counterpart > 0 && isOpening {
+		shouldAutoInsert := e.shouldAutoClose(ke.Range.Start, r, counterpart)
 		replaced := ke.Text
 		if shouldAutoInsert {
 			replaced += string(counterpart)
 		}
 
-		e.replace(ke.Range.Start, ke.Range.End, replaced)
-		if shouldAutoInsert {
+		if _, result := e.replace(ke.Range.Start, ke.Range.End, replaced); result == editApplied && shouldAutoInsert {
 			e.text.MoveCaret(-1, -1)
 			start, _ := e.text.Selection() // start and end should be the same
-			e.autoInsertions[start] = counterpart
-		} else {
-			// If only the opening char was inserted, ensure it's not tracked
-			delete(e.autoInsertions, ke.Range.Start)
-		}
-
-	} else if counterpart > 0 {
-		// The input character is a bracket or a quote, but it is a closing part.
-		//
-		// check if we can just move the cursor to the next position
-		// if the input is a just inserted closing part.
-		nextRune, err := e.text.ReadRuneAt(ke.Range.Start)
-
-		if err == nil && nextRune == e.autoInsertions[ke.Range.Start] {
-			e.text.MoveCaret(1, 1)
-			delete(e.autoInsertions, ke.Range.Start)
-		} else {
-			e.replace(ke.Range.Start, ke.Range.End, ke.Text)
+			e.trackAutoInsertion(start, counterpart)
 		}
 	} else {
-		delete(e.autoInsertions, ke.Range.Start)
 		e.replace(ke.Range.Start, ke.Range.End, ke.Text)
 	}
 
@@ -458,6 +586,48 @@ func (e *Editor) onTextInput(ke key.EditEvent) {
 	e.snippetCtx.OnInsertAt(finalStart, finalEnd)
 }
 
+// shouldAutoClose reports whether the opening bracket or quote r typed at
+// runeOff is auto-closed with counterpart.
+func (e *Editor) shouldAutoClose(runeOff int, r, counterpart rune) bool {
+	if e.text.CaretInStringOrComment(runeOff) {
+		// no auto-closing inside of strings or comments.
+		return false
+	}
+	if counterpart != r {
+		// only check the next char.
+		return !e.isNearWordChar(runeOff, false)
+	}
+	// check both the previous and next char.
+	return !e.isNearWordChar(runeOff, true) && !e.isNearWordChar(runeOff, false)
+}
+
+// matchPairOpening checks if the input of ke completes the opening half of a
+// configured multi-rune pair, which is auto-closed outside of strings and
+// comments unless the closing half is already there.
+func (e *Editor) matchPairOpening(ke key.EditEvent) (textview.Pair, bool) {
+	maxLen := e.text.BracketsQuotes.MaxOpeningLen()
+	if maxLen == 0 || ke.Range.Start != ke.Range.End {
+		return textview.Pair{}, false
+	}
+
+	before := e.ReadRange(max(0, ke.Range.Start-maxLen+1), ke.Range.Start) + ke.Text
+	pair, ok := e.text.BracketsQuotes.MatchOpening(before)
+	if !ok {
+		return textview.Pair{}, false
+	}
+
+	openingStart := ke.Range.Start + utf8.RuneCountInString(ke.Text) - utf8.RuneCountInString(pair.Opening)
+	if openingStart < 0 || e.text.CaretInStringOrComment(openingStart) {
+		return textview.Pair{}, false
+	}
+	after := e.ReadRange(ke.Range.Start, min(e.Len(), ke.Range.Start+utf8.RuneCountInString(pair.Closing)))
+	if after == pair.Closing {
+		return textview.Pair{}, false
+	}
+
+	return pair, true
+}
+
 func (e *Editor) isNearWordChar(runeOff int, backward bool) bool {
 	pos := runeOff
 	if backward {
@@ -500,7 +670,10 @@ func (e *Editor) currentCompletionCtx() CompletionContext {
 // GetCompletionContext returns a context from the current caret position.
 // This is usually used in the condition of a key triggered completion.
 func (e *Editor) GetCompletionContext() CompletionContext {
-	return e.currentCompletionCtx()
+	ctx := e.currentCompletionCtx()
+	md := e.Metadata()
+	ctx.Metadata = &md
+	return ctx
 }
 
 // OnTextEdit should be called after normal keyboard input to update the
@@ -509,40 +682,90 @@ func (e *Editor) OnTextEdit() {
 	if e.completor == nil {
 		return
 	}
+	// pasting text does not trigger the completion.
+	if e.pasted {
+		e.pasted = false
+		if e.pastedVersion == e.TextVersion() {
+			e.lastInput = nil
+			return
+		}
+	}
 
 	ctx := e.currentCompletionCtx()
 	if ctx == (CompletionContext{}) {
 		return
 	}
 
+	md := e.Metadata()
+	ctx.Metadata = &md
 	e.completor.OnText(ctx)
 }
 
 func (e *Editor) onPasteEvent(ke transfer.DataEvent) EditorEvent {
-	if e.mode == ModeReadOnly {
+	if e.mode == ModeReadOnly && e.clipboardRead == nil {
 		return nil
 	}
 
-	e.scrollCaret = true
-	e.scroller.Stop()
 	content, err := io.ReadAll(ke.Open())
 	if err != nil {
 		return nil
 	}
+	return e.onPasteText(string(content))
+}
+
+// onPasteText pastes text read from the host clipboard, or passes it to the
+// callback of ReadClipboard.
+func (e *Editor) onPasteText(content string) EditorEvent {
+	if e.mode == ModeReadOnly && e.clipboardRead == nil {
+		return nil
+	}
+
+	e.scrollCaret = true
+	e.scroller.Stop()
+	text, rejected := e.checkUTF8("paste", content)
+	if rejected != nil {
+		return *rejected
+	}
+	// keep the clipboard ring in sync with text copied outside of the editor.
+	e.ClipboardRing().Push(text)
+	if read := e.clipboardRead; read != nil {
+		e.clipboardRead = nil
+		read(text)
+		return nil
+	}
 
-	text := string(content)
+	text = e.normalizeText(text)
+
+	// the edits of the hook and the paste are undone in one step.
+	e.buffer.GroupOp()
+	defer e.buffer.UnGroupOp()
 	if e.onPaste != nil {
 		text = e.onPaste(text)
 	}
 
 	runes := 0
-	if isSingleLine(text) {
+	if e.ColumnEditEnabled() && len(e.columnEdit.selections) > 0 {
+		if e.denyReadOnlyColumns() {
+			return nil
+		}
+		runes = e.pasteColumns(text)
+	} else if e.hasExtraSelections() {
+		if e.denyReadOnlySelections() {
+			return nil
+		}
+		runes = e.pasteSelections(text)
+	} else if isSingleLine(text) {
 		runes = e.InsertLine(text)
 	} else {
+		if e.pasteReindent {
+			text = e.reindentPaste(text)
+		}
 		runes = e.Insert(text)
 	}
 
 	if runes != 0 {
+		e.cancelCompletor()
+		e.pasted, e.pastedVersion = true, e.TextVersion()
 		return ChangeEvent{}
 	}
 
@@ -553,6 +776,9 @@ func (e *Editor) onInsertLineBreak(ke key.Event) EditorEvent {
 	if e.mode == ModeReadOnly {
 		return nil
 	}
+	if start, end := e.text.Selection(); e.denyReadOnlyEdit(start, end) {
+		return nil
+	}
 
 	e.text.IndentOnBreak("\n")
 	// Reset xoff.
@@ -602,17 +828,142 @@ func (e *Editor) onDeleteBackward() {
 			e.text.MoveCaret(0, -moves)
 		}
 
-	} else {
-		// when there is rencently auto-inserted brackets or quotes,
-		// delete the auto inserted character and the previous character.
-		if inserted, exists := e.autoInsertions[start]; exists {
-			defer delete(e.autoInsertions, start)
-			counterpart, isOpening := e.text.BracketsQuotes.GetCounterpart(inserted)
-			if !isOpening && counterpart > 0 || inserted == counterpart {
-				if prev == counterpart {
-					e.text.MoveCaret(-1, 1)
-				}
-			}
+	} else if e.takeAutoPair(start) {
+		// when the caret sits between an auto-inserted pair, delete the
+		// auto inserted character and the previous character.
+		e.text.MoveCaret(-1, 1)
+	}
+}
+
+// takeAutoPair reports whether the caret at runeOff sits between an opening
+// bracket or quote and its auto-inserted closing part, and stops tracking the
+// closing part if so.
+func (e *Editor) takeAutoPair(runeOff int) bool {
+	if runeOff <= 0 {
+		return false
+	}
+	prev, err := e.text.ReadRuneAt(runeOff - 1)
+	if err != nil {
+		return false
+	}
+	counterpart, isOpening := e.text.BracketsQuotes.GetCounterpart(prev)
+	return counterpart > 0 && isOpening && e.takeAutoInsertion(runeOff, counterpart)
+}
+
+// maxAutoInsertions caps the number of auto-inserted closing parts tracked,
+// unless there are more column carets. The oldest ones are dropped first.
+const maxAutoInsertions = 16
+
+// trackAutoInsertion records the closing bracket or quote r auto-inserted at
+// runeOff, right after its opening part. The positions of both parts are
+// tracked with markers so that they survive edits made before them, e.g.
+// typing between the pair.
+func (e *Editor) trackAutoInsertion(runeOff int, r rune) {
+	if runeOff <= 0 {
+		return
+	}
+	opening, err := e.buffer.CreateMarker(runeOff-1, buffer.BiasForward)
+	if err != nil {
+		return
+	}
+	marker, err := e.buffer.CreateMarker(runeOff, buffer.BiasForward)
+	if err != nil {
+		e.buffer.RemoveMarker(opening)
+		return
+	}
+	if len(e.autoInsertions) >= max(maxAutoInsertions, len(e.columnEdit.selections)) {
+		e.removeAutoInsertion(e.autoInsertions[0])
+		e.autoInsertions = slices.Delete(e.autoInsertions, 0, 1)
+	}
+	e.autoInsertions = append(e.autoInsertions, autoInsertion{opening: opening, marker: marker, r: r})
+}
+
+// takeAutoInsertion reports whether the rune r at runeOff is an auto-inserted
+// closing part, and stops tracking it if so. Insertions whose closing part has
+// been edited away are dropped along the way.
+func (e *Editor) takeAutoInsertion(runeOff int, r rune) bool {
+	found := false
+	e.autoInsertions = slices.DeleteFunc(e.autoInsertions, func(ai autoInsertion) bool {
+		off := ai.marker.Offset()
+		current, err := e.text.ReadRuneAt(off)
+		stale := err != nil || current != ai.r
+		matched := !found && !stale && off == runeOff && ai.r == r
+		if matched {
+			found = true
+		}
+		if stale || matched {
+			e.removeAutoInsertion(ai)
+			return true
+		}
+		return false
+	})
+
+	return found
+}
+
+// dropEditedAutoInsertions stops tracking the auto-inserted pairs whose
+// opening or closing part is in the range [start, end) replaced by an edit.
+// Text inserted between the parts keeps them tracked.
+func (e *Editor) dropEditedAutoInsertions(start, end int) {
+	if start == end {
+		return
+	}
+	e.autoInsertions = slices.DeleteFunc(e.autoInsertions, func(ai autoInsertion) bool {
+		opening, closing := ai.opening.Offset(), ai.marker.Offset()
+		if (start <= opening && opening < end) || (start <= closing && closing < end) {
+			e.removeAutoInsertion(ai)
+			return true
+		}
+		return false
+	})
+}
+
+// dropAutoInsertionsOffCaretLine stops tracking the auto-inserted closing
+// parts which are not on the line of a caret, as the caret left the line.
+func (e *Editor) dropAutoInsertionsOffCaretLine() {
+	if len(e.autoInsertions) == 0 {
+		return
+	}
+	caret, _ := e.text.Selection()
+	carets := []int{caret}
+	if e.ColumnEditEnabled() && len(e.columnEdit.selections) > 1 {
+		carets = carets[:0]
+		for _, cursor := range e.columnEdit.selections {
+			carets = append(carets, e.text.ConvertPos(cursor.line, 0)+cursor.col)
+		}
+	}
+
+	e.autoInsertions = slices.DeleteFunc(e.autoInsertions, func(ai autoInsertion) bool {
+		off := ai.marker.Offset()
+		if !slices.ContainsFunc(carets, func(caret int) bool { return e.sameLine(caret, off) }) {
+			e.removeAutoInsertion(ai)
+			return true
+		}
+		return false
+	})
+}
+
+// sameLine reports whether there is no line break between the rune offsets a
+// and b.
+func (e *Editor) sameLine(a, b int) bool {
+	for i := min(a, b); i < max(a, b); i++ {
+		r, err := e.text.ReadRuneAt(i)
+		if err != nil || r == '\n' {
+			return false
 		}
 	}
+	return true
+}
+
+func (e *Editor) removeAutoInsertion(ai autoInsertion) {
+	e.buffer.RemoveMarker(ai.opening)
+	e.buffer.RemoveMarker(ai.marker)
+}
+
+// clearAutoInsertions stops tracking all of the auto-inserted closing parts.
+func (e *Editor) clearAutoInsertions() {
+	for _, ai := range e.autoInsertions {
+		e.removeAutoInsertion(ai)
+	}
+	e.autoInsertions = e.autoInsertions[:0]
 }
@@ -0,0 +1,143 @@
+package gvcode
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+// autoPairEditor is an editor receiving the key events of a router.
+type autoPairEditor struct {
+	*Editor
+	router input.Router
+	shaper *text.Shaper
+}
+
+func newAutoPairEditor(t *testing.T, content string) *autoPairEditor {
+	t.Helper()
+	e := &autoPairEditor{Editor: newTestEditor(t, content), shaper: text.NewShaper(text.WithCollection(gofont.Collection()))}
+	e.frame()
+	e.frame()
+	return e
+}
+
+func (e *autoPairEditor) frame() {
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 200)), Source: e.router.Source()}
+	for {
+		if _, ok := e.Update(gtx); !ok {
+			break
+		}
+	}
+	e.Layout(gtx, e.shaper)
+	if !gtx.Focused(e.Editor) {
+		gtx.Execute(key.FocusCmd{Tag: e.Editor})
+	}
+	e.router.Frame(gtx.Ops)
+}
+
+func (e *autoPairEditor) press(name key.Name) {
+	e.router.Queue(key.Event{Name: name, State: key.Press})
+	e.frame()
+}
+
+func (e *autoPairEditor) typeText(s string) {
+	for _, r := range s {
+		start, end := e.Selection()
+		e.router.Queue(key.EditEvent{Range: key.Range{Start: start, End: end}, Text: string(r)})
+		e.frame()
+	}
+}
+
+func TestAutoInsertionOvertype(t *testing.T) {
+	e := newAutoPairEditor(t, "\nx")
+	e.typeText("(a)")
+	if got := e.Text(); got != "(a)\nx" {
+		t.Fatalf("got %q, want the auto-inserted closing part overtyped", got)
+	}
+
+	// the closing part is not overtyped once the caret left its line.
+	e.SetCaret(0, 0)
+	e.typeText("(")
+	e.press(key.NameDownArrow)
+	e.press(key.NameUpArrow)
+	e.SetCaret(1, 1)
+	e.typeText(")")
+	if got := e.Text(); got != "())(a)\nx" {
+		t.Errorf("got %q after the caret left the line, want the closing part inserted", got)
+	}
+}
+
+func TestAutoInsertionEdited(t *testing.T) {
+	e := newAutoPairEditor(t, "")
+	e.typeText("(")
+	// replacing the closing part drops it, even with the same rune.
+	e.SetCaret(1, 2)
+	e.Insert(")")
+	e.SetCaret(1, 1)
+	e.typeText(")")
+	if got := e.Text(); got != "())" {
+		t.Errorf("got %q after the closing part was edited, want the typed one inserted", got)
+	}
+
+	// text inserted between the parts keeps them paired.
+	e.SetText("")
+	e.typeText("[")
+	e.Insert("xy")
+	e.typeText("]")
+	if got := e.Text(); got != "[xy]" {
+		t.Errorf("got %q, want the closing part overtyped", got)
+	}
+}
+
+func TestAutoInsertionLimit(t *testing.T) {
+	e := newAutoPairEditor(t, "")
+	n := maxAutoInsertions + 1
+	e.typeText(strings.Repeat("(", n))
+	if got := len(e.autoInsertions); got != maxAutoInsertions {
+		t.Fatalf("got %d closing parts tracked, want %d", got, maxAutoInsertions)
+	}
+
+	// the oldest closing part is no longer overtyped.
+	e.typeText(strings.Repeat(")", n))
+	if got, want := e.Text(), strings.Repeat("(", n)+strings.Repeat(")", n+1); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSurroundSelection(t *testing.T) {
+	e := newAutoPairEditor(t, "foo bar")
+	e.SetCaret(4, 7)
+	e.typeText("(")
+	if got := e.Text(); got != "foo (bar)" {
+		t.Fatalf("got %q, want the selection surrounded", got)
+	}
+	if start, end := e.Selection(); start != 5 || end != 8 {
+		t.Fatalf("got selection %d-%d, want the surrounded text selected", start, end)
+	}
+	e.typeText("\"")
+	if got := e.Text(); got != "foo (\"bar\")" {
+		t.Fatalf("got %q, want the selection surrounded again", got)
+	}
+
+	// the direction of the selection is kept.
+	e.SetCaret(3, 0)
+	e.typeText("[")
+	if got := e.Text(); got != "[foo] (\"bar\")" {
+		t.Fatalf("got %q, want the selection surrounded", got)
+	}
+	if start, end := e.Selection(); start != 4 || end != 1 {
+		t.Errorf("got selection %d-%d, want 4-1", start, end)
+	}
+
+	e.undo()
+	if got := e.Text(); got != "foo (\"bar\")" {
+		t.Errorf("got %q after undo, want the surrounding undone in one step", got)
+	}
+}
@@ -0,0 +1,86 @@
+//go:build examples
+
+package main
+
+import (
+	"strings"
+
+	"gioui.org/io/key"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/addons/completion"
+)
+
+const completionText = `package main
+
+// Type a few letters of a snippet, e.g., iferr, or of a word of this
+// document to complete it, or press Ctrl+Space to list the snippets.
+func main() {
+	message := "hello"
+
+}
+`
+
+// newCompletionDemo completes snippets and the words of the document in the
+// built-in completion popup.
+func newCompletionDemo(th *material.Theme) *demo {
+	editor := newEditor(th, completionText)
+
+	cm := &completion.DefaultCompletion{Editor: editor}
+	popup := completion.NewCompletionPopup(editor, cm)
+	popup.Theme = th
+	popup.TextSize = unit.Sp(12)
+	// a single completor is triggered by typing, so the keywords and the
+	// words of the document are suggested by the same one.
+	cm.AddCompletor(&keywordCompletor{words: completion.NewWordCompletor(editor)}, popup)
+	editor.WithOptions(gvcode.WithAutoCompletion(cm))
+
+	return &demo{
+		name:   "Completion",
+		editor: editor,
+		handle: func(gtx C, evt gvcode.EditorEvent) {
+			if _, ok := evt.(gvcode.ChangeEvent); ok {
+				// update the completion as the user types ahead.
+				editor.OnTextEdit()
+			}
+		},
+	}
+}
+
+// keywordCompletor suggests snippets expanding to the common statements of
+// Go, and the words of the document.
+type keywordCompletor struct {
+	words *completion.WordCompletor
+}
+
+var keywordSnippets = []gvcode.CompletionCandidate{
+	{Label: "for", Detail: "for range loop", TextEdit: gvcode.TextEdit{NewText: "for ${1:i} := range ${2:n} {\n\t$0\n}"}},
+	{Label: "if", Detail: "if statement", TextEdit: gvcode.TextEdit{NewText: "if ${1:cond} {\n\t$0\n}"}},
+	{Label: "iferr", Detail: "error check", TextEdit: gvcode.TextEdit{NewText: "if err != nil {\n\treturn ${1:err}\n}"}},
+	{Label: "func", Detail: "function declaration", TextEdit: gvcode.TextEdit{NewText: "func ${1:name}($2) {\n\t$0\n}"}},
+	{Label: "return", Detail: "return statement", TextEdit: gvcode.TextEdit{NewText: "return $0"}},
+	{Label: "switch", Detail: "switch statement", TextEdit: gvcode.TextEdit{NewText: "switch ${1:x} {\ncase ${2:v}:\n\t$0\n}"}},
+}
+
+func (c *keywordCompletor) Trigger() gvcode.Trigger {
+	tr := gvcode.Trigger{}
+	tr.KeyBinding.Name = key.NameSpace
+	tr.KeyBinding.Modifiers = key.ModShortcut
+	return tr
+}
+
+func (c *keywordCompletor) Suggest(ctx gvcode.CompletionContext) []gvcode.CompletionCandidate {
+	candidates := make([]gvcode.CompletionCandidate, len(keywordSnippets))
+	for i, snippet := range keywordSnippets {
+		snippet.Kind = "snippet"
+		snippet.TextFormat = "Snippet"
+		snippet.Documentation = "Expands to:\n\n" + strings.ReplaceAll(snippet.TextEdit.NewText, "\t", "    ")
+		candidates[i] = snippet
+	}
+	return append(candidates, c.words.Suggest(ctx)...)
+}
+
+func (c *keywordCompletor) FilterAndRank(pattern string, candidates []gvcode.CompletionCandidate) []gvcode.CompletionCandidate {
+	return completion.FuzzyFilterAndRank(pattern, candidates)
+}
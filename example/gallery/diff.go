@@ -0,0 +1,120 @@
+//go:build examples
+
+package main
+
+import (
+	"strings"
+
+	"gioui.org/widget/material"
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/gutter/providers"
+)
+
+const diffBaseText = `package main
+
+import "fmt"
+
+func main() {
+	name := "world"
+	fmt.Println("hello", name)
+}
+`
+
+const diffText = `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	name := os.Args[1]
+	fmt.Println("hello", name)
+}
+`
+
+// newDiffDemo marks the lines changed from a base version of the text in the
+// gutter, as a version control system would. The diff is computed again as
+// the text is edited.
+func newDiffDemo(th *material.Theme) *demo {
+	editor := newEditor(th, diffText)
+	diffs := providers.NewVCSDiffProvider()
+	diffs.SetHighlightLines(true, 0x20)
+	editor.WithOptions(gvcode.WithGutter(diffs))
+	diffs.UpdateDiff(diffLines(diffBaseText, editor.Text()))
+
+	return &demo{
+		name:   "Diff gutter",
+		editor: editor,
+		handle: func(gtx C, evt gvcode.EditorEvent) {
+			if _, ok := evt.(gvcode.ChangeEvent); ok {
+				diffs.UpdateDiff(diffLines(diffBaseText, editor.Text()))
+			}
+		},
+	}
+}
+
+// diffLines returns the hunks changing the lines of base to the lines of text.
+// It finds the longest common subsequence of lines, which is fine for the
+// small texts of the demo. Applications usually parse the output of their
+// version control system instead, e.g., with the addons/diff package.
+func diffLines(base, text string) []*providers.DiffHunk {
+	a, b := strings.Split(base, "\n"), strings.Split(text, "\n")
+
+	// lcs[i][j] is the length of the common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var hunks []*providers.DiffHunk
+	var hunk *providers.DiffHunk
+	flush := func() {
+		if hunk == nil {
+			return
+		}
+		switch {
+		case len(hunk.NewLines) == 0:
+			hunk.Type = providers.DiffDeleted
+			hunk.EndLine = hunk.StartLine
+		case len(hunk.OldLines) == 0:
+			hunk.Type = providers.DiffAdded
+		default:
+			hunk.Type = providers.DiffModified
+		}
+		hunks = append(hunks, hunk)
+		hunk = nil
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if i < len(a) && j < len(b) && a[i] == b[j] {
+			flush()
+			i++
+			j++
+			continue
+		}
+		if hunk == nil {
+			hunk = &providers.DiffHunk{StartLine: j, EndLine: j - 1, OldStartLine: i}
+		}
+		if j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]) {
+			hunk.NewLines = append(hunk.NewLines, b[j])
+			hunk.EndLine = j
+			j++
+		} else {
+			hunk.OldLines = append(hunk.OldLines, a[i])
+			i++
+		}
+	}
+	flush()
+	return hunks
+}
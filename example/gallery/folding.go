@@ -0,0 +1,67 @@
+//go:build examples
+
+package main
+
+import (
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/oligo/gvcode"
+)
+
+const foldingText = `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Click the buttons in the gutter, or press Ctrl+Shift+[ to fold the code
+// around the caret. Hover over the button of a folded block to preview it.
+type greeter struct {
+	greeting string
+	names    []string
+}
+
+func (g *greeter) greet() {
+	for _, name := range g.names {
+		if name == "" {
+			continue
+		}
+		fmt.Println(g.greeting, strings.ToUpper(name))
+	}
+}
+
+func main() {
+	g := &greeter{
+		greeting: "hello",
+		names:    []string{"gopher", "gio"},
+	}
+	g.greet()
+}
+`
+
+// newFoldingDemo folds blocks of code from the gutter and the keyboard, and
+// keeps the headers of the blocks scrolled out at the top of the editor.
+func newFoldingDemo(th *material.Theme) *demo {
+	editor := newEditor(th, foldingText)
+	editor.WithOptions(
+		gvcode.WithCodeFolding(),
+		gvcode.WithFoldPreview(0),
+		gvcode.WithStickyLines(),
+	)
+
+	var toggle widget.Clickable
+	return &demo{
+		name:   "Folding",
+		editor: editor,
+		toolbar: func(gtx C, th *material.Theme) D {
+			if toggle.Clicked(gtx) {
+				editor.ToggleFold()
+			}
+			return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+				layout.Rigid(material.Button(th, &toggle, "Toggle fold at caret").Layout),
+			)
+		},
+	}
+}
@@ -0,0 +1,262 @@
+//go:build examples
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/widget/material"
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/gutter/providers"
+)
+
+// harness lays out a demo headless, and delivers input events to it.
+type harness struct {
+	t      *testing.T
+	th     *material.Theme
+	router input.Router
+	ops    op.Ops
+	demo   *demo
+}
+
+func newHarness(t *testing.T, newDemo func(th *material.Theme) *demo) *harness {
+	t.Helper()
+	th := material.NewTheme()
+	th.Shaper = text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection()))
+	h := &harness{t: t, th: th}
+	h.demo = newDemo(th)
+	h.frame()
+	h.router.Source().Execute(key.FocusCmd{Tag: h.demo.editor})
+	h.frame()
+	return h
+}
+
+// frame lays out the demo and handles its events.
+func (h *harness) frame() layout.Dimensions {
+	h.ops.Reset()
+	gtx := layout.Context{
+		Ops:         &h.ops,
+		Constraints: layout.Exact(image.Pt(800, 600)),
+		Source:      h.router.Source(),
+		Now:         time.Now(),
+	}
+	dims := h.demo.layout(gtx, h.th)
+	h.router.Frame(&h.ops)
+	return dims
+}
+
+// typeText types text at the caret of the editor.
+func (h *harness) typeText(text string) {
+	for _, r := range text {
+		start, end := h.demo.editor.Selection()
+		h.router.Queue(key.EditEvent{Range: key.Range{Start: start, End: end}, Text: string(r)})
+		h.frame()
+	}
+}
+
+// press presses and releases a key.
+func (h *harness) press(name key.Name) {
+	h.router.Queue(key.Event{Name: name, State: key.Press}, key.Event{Name: name, State: key.Release})
+	h.frame()
+}
+
+// until runs frames until cond is true, as the updates made in the
+// background are applied by the frames.
+func (h *harness) until(what string, cond func() bool) {
+	h.t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			h.t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+		h.frame()
+	}
+}
+
+func noInvalidate() {}
+
+func TestGalleryLayout(t *testing.T) {
+	for _, d := range newDemos(material.NewTheme(), noInvalidate) {
+		t.Run(d.name, func(t *testing.T) {
+			h := newHarness(t, func(*material.Theme) *demo { return d })
+			if dims := h.frame(); dims.Size != image.Pt(800, 600) {
+				t.Errorf("got size %v", dims.Size)
+			}
+		})
+	}
+}
+
+func TestCompletionDemo(t *testing.T) {
+	h := newHarness(t, newCompletionDemo)
+	editor := h.demo.editor
+	off, _ := editor.ConvertPos(7, 0)
+	editor.SetCaret(off, off)
+	h.frame()
+
+	h.typeText("\tife")
+	h.press(key.NameReturn)
+	if want := "\tif err != nil {\n\treturn err\n}"; !strings.Contains(editor.Text(), want) {
+		t.Errorf("the snippet is not expanded in %q", editor.Text())
+	}
+
+	// the words of the document are completed.
+	h.press(key.NameEscape)
+	editor.SetCaret(off, off)
+	h.typeText("mess")
+	h.press(key.NameReturn)
+	if !strings.Contains(editor.Text(), "message\tif err") {
+		t.Errorf("the word is not completed in %q", editor.Text())
+	}
+}
+
+func TestLSPDemo(t *testing.T) {
+	h := newHarness(t, func(th *material.Theme) *demo { return newLSPDemo(th, noInvalidate) })
+	editor := h.demo.editor
+	h.until("the diagnostics of the TODO comment", func() bool { return len(editor.Diagnostics()) == 1 })
+	if d := editor.Diagnostics()[0]; d.Severity != gvcode.SeverityInfo || d.Message != "TODO comment" {
+		t.Errorf("got diagnostic %+v", d)
+	}
+
+	// remove the closing brace of main.
+	text := editor.Text()
+	brace := len([]rune(text[:strings.LastIndex(text, "}")]))
+	editor.SetCaret(brace, brace+1)
+	editor.Delete(1)
+	h.until("the error of the unclosed brace", func() bool { return len(editor.Diagnostics()) == 2 })
+	d := editor.Diagnostics()[1]
+	if d.Severity != gvcode.SeverityError || editor.ReadRange(d.Start, d.End) != "{" {
+		t.Errorf("got diagnostic %+v", d)
+	}
+}
+
+func TestDocumentPositions(t *testing.T) {
+	doc := newDocument("a😀b\nc")
+	// 😀 is two UTF-16 code units.
+	if pos := doc.position(2); pos != (lspPosition{Line: 0, Character: 3}) {
+		t.Errorf("got %+v", pos)
+	}
+	if off := doc.offset(lspPosition{Line: 1, Character: 1}); off != 5 {
+		t.Errorf("got offset %d, want 5", off)
+	}
+
+	doc.apply(contentChange{Range: lspRange{Start: lspPosition{0, 1}, End: lspPosition{1, 0}}, Text: "x\ny"})
+	if got := doc.String(); got != "ax\nyc" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestDiffDemo(t *testing.T) {
+	h := newHarness(t, newDiffDemo)
+	editor := h.demo.editor
+	diffs := editor.GetGutterManager().GetProvider(providers.DiffProviderID).(*providers.VCSDiffProvider)
+
+	types := func() []providers.DiffType {
+		var types []providers.DiffType
+		for _, hunk := range diffs.GetAllHunks() {
+			types = append(types, hunk.Type)
+		}
+		return types
+	}
+	want := []providers.DiffType{providers.DiffModified, providers.DiffModified}
+	if got := types(); !slices.Equal(got, want) {
+		t.Fatalf("got hunks %v, want %v", got, want)
+	}
+
+	// restore the base text.
+	editor.SetCaret(0, editor.Len())
+	editor.Insert(diffBaseText)
+	h.frame()
+	if got := types(); len(got) != 0 {
+		t.Errorf("got hunks %v for the base text", got)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	hunks := diffLines("a\nb\nc\nd", "a\nx\nc\nd\ne")
+	if len(hunks) != 2 {
+		t.Fatalf("got %d hunks, want 2", len(hunks))
+	}
+	if h := hunks[0]; h.Type != providers.DiffModified || h.StartLine != 1 || h.EndLine != 1 || h.OldLines[0] != "b" {
+		t.Errorf("got %+v", h)
+	}
+	if h := hunks[1]; h.Type != providers.DiffAdded || h.StartLine != 4 || h.EndLine != 4 {
+		t.Errorf("got %+v", h)
+	}
+
+	hunks = diffLines("a\nb\nc", "a\nc")
+	if len(hunks) != 1 || hunks[0].Type != providers.DiffDeleted || hunks[0].StartLine != 1 || hunks[0].OldStartLine != 1 {
+		t.Errorf("got %+v", hunks)
+	}
+}
+
+func TestFoldingDemo(t *testing.T) {
+	h := newHarness(t, newFoldingDemo)
+	editor := h.demo.editor
+	lines := editor.Lines()
+
+	// fold the fields of greeter.
+	off, _ := editor.ConvertPos(10, 0)
+	editor.SetCaret(off, off)
+	if !editor.ToggleFold() {
+		t.Fatal("no fold at the fields of greeter")
+	}
+	h.frame()
+	if got := editor.Lines(); got != lines {
+		t.Errorf("folding changed the lines of the document to %d", got)
+	}
+	if line, _ := editor.CaretPos(); line != 9 {
+		t.Errorf("got the caret at line %d, want the start of the fold", line)
+	}
+}
+
+func TestThemeDemo(t *testing.T) {
+	h := newHarness(t, newThemeDemo)
+	editor := h.demo.editor
+	for _, th := range themes {
+		editor.WithOptions(gvcode.WithColorScheme(th.scheme()))
+		h.frame()
+		want := th.scheme().Background.NRGBA()
+		if got := editor.ColorPalette().Background.NRGBA(); got != want {
+			t.Errorf("%s: got background %v, want %v", th.name, got, want)
+		}
+	}
+}
+
+func TestSearchDemo(t *testing.T) {
+	var bar *searchBar
+	h := newHarness(t, func(th *material.Theme) *demo {
+		bar = newSearchBar(newEditor(th, foldingText), noInvalidate)
+		return bar.demo()
+	})
+	editor := h.demo.editor
+
+	bar.query.SetText("greet")
+	bar.search()
+	h.until("the matches", func() bool { return bar.status != "searching…" })
+	if want := fmt.Sprintf("%d matches", strings.Count(foldingText, "greet")); bar.status != want {
+		t.Fatalf("got status %q, want %q", bar.status, want)
+	}
+
+	editor.SetCaret(0, 0)
+	if !editor.SelectNextMatch(false) || editor.SelectedText() != "greet" {
+		t.Errorf("got selection %q", editor.SelectedText())
+	}
+
+	bar.query.SetText("(")
+	bar.regexp.Value = true
+	bar.search()
+	if !strings.Contains(bar.status, "missing closing )") {
+		t.Errorf("got status %q for an invalid expression", bar.status)
+	}
+}
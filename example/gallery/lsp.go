@@ -0,0 +1,279 @@
+//go:build examples
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"gioui.org/widget/material"
+	"github.com/oligo/gvcode"
+)
+
+const lspText = `package main
+
+import "fmt"
+
+// The language server of this demo reports the TODO comments, and the braces
+// which are not balanced. Remove a brace to see an error.
+func main() {
+	// TODO: greet the user by name.
+	fmt.Println("hello")
+}
+`
+
+// newLSPDemo synchronizes the editor with a language server, and shows the
+// diagnostics it publishes.
+func newLSPDemo(th *material.Theme, invalidate func()) *demo {
+	editor := newEditor(th, lspText)
+	editor.WithOptions(gvcode.WithErrorLens(true))
+	client := newLSPClient(editor, invalidate)
+
+	return &demo{
+		name:   "Language server",
+		editor: editor,
+		sync:   client.sync,
+		toolbar: func(gtx C, th *material.Theme) D {
+			return material.Body2(th, fmt.Sprintf("%d problems", len(editor.Diagnostics()))).Layout(gtx)
+		},
+	}
+}
+
+// The messages of the language server protocol used by the demo. Positions
+// are zero-based lines, and characters in UTF-16 code units.
+type (
+	lspPosition struct {
+		Line, Character int
+	}
+
+	lspRange struct {
+		Start, End lspPosition
+	}
+
+	// contentChange is an incremental change of a didChange notification.
+	contentChange struct {
+		Range lspRange
+		Text  string
+	}
+
+	lspDiagnostic struct {
+		Range lspRange
+		// Severity is 1 for errors, 2 for warnings, 3 for information and 4
+		// for hints.
+		Severity int
+		Message  string
+	}
+
+	// publishDiagnostics is the notification of the diagnostics of a
+	// version of the document.
+	publishDiagnostics struct {
+		Version     int
+		Diagnostics []lspDiagnostic
+	}
+)
+
+// lspClient adapts the editor to a language server. The edits of the editor
+// are sent as incremental changes, and the diagnostics published by the
+// server are applied if they are for the current version of the text.
+type lspClient struct {
+	editor *gvcode.Editor
+	server *languageServer
+	// doc is the text as sent to the server, which converts the rune offsets
+	// of the editor to the positions of the protocol.
+	doc       *document
+	published chan publishDiagnostics
+}
+
+func newLSPClient(editor *gvcode.Editor, invalidate func()) *lspClient {
+	c := &lspClient{
+		editor:    editor,
+		doc:       newDocument(editor.Text()),
+		published: make(chan publishDiagnostics, 1),
+	}
+
+	c.server = startLanguageServer(editor.Text(), editor.TextVersion(), func(p publishDiagnostics) {
+		// keep the latest notification only.
+		select {
+		case <-c.published:
+		default:
+		}
+		c.published <- p
+		invalidate()
+	})
+
+	editor.OnEdit(func(delta gvcode.EditDelta) {
+		change := contentChange{
+			Range: lspRange{Start: c.doc.position(delta.Start), End: c.doc.position(delta.OldEnd)},
+			Text:  delta.Text,
+		}
+		c.doc.apply(change)
+		c.server.didChange(delta.Version, change)
+	})
+	return c
+}
+
+// sync applies the diagnostics published by the server. It must be called
+// from the UI goroutine.
+func (c *lspClient) sync() {
+	var p publishDiagnostics
+	select {
+	case p = <-c.published:
+	default:
+		return
+	}
+	if p.Version != c.editor.TextVersion() {
+		// the diagnostics of the current version are on the way.
+		return
+	}
+
+	diags := make([]gvcode.Diagnostic, 0, len(p.Diagnostics))
+	for _, d := range p.Diagnostics {
+		diags = append(diags, gvcode.Diagnostic{
+			Start:    c.doc.offset(d.Range.Start),
+			End:      c.doc.offset(d.Range.End),
+			Severity: gvcode.DiagnosticSeverity(d.Severity),
+			Message:  d.Message,
+			Source:   "demo-ls",
+		})
+	}
+	c.editor.SetDiagnostics(diags...)
+}
+
+// didChangeParams is a didChange notification.
+type didChangeParams struct {
+	version int
+	changes []contentChange
+}
+
+// languageServer stands for a language server running in another process. A
+// real client would exchange the same messages with it over JSON-RPC.
+type languageServer struct {
+	changes chan didChangeParams
+}
+
+// startLanguageServer starts a server for the text of version, which calls
+// publish with the diagnostics of each version of the text it analyses.
+func startLanguageServer(text string, version int, publish func(publishDiagnostics)) *languageServer {
+	s := &languageServer{changes: make(chan didChangeParams, 64)}
+	go func() {
+		doc := newDocument(text)
+		publish(publishDiagnostics{Version: version, Diagnostics: analyze(doc)})
+		for params := range s.changes {
+			for _, change := range params.changes {
+				doc.apply(change)
+			}
+			version = params.version
+			// analyze the latest version only when typing fast.
+			if len(s.changes) > 0 {
+				continue
+			}
+			publish(publishDiagnostics{Version: version, Diagnostics: analyze(doc)})
+		}
+	}()
+	return s
+}
+
+func (s *languageServer) didChange(version int, changes ...contentChange) {
+	s.changes <- didChangeParams{version: version, changes: changes}
+}
+
+// analyze reports the TODO comments and the unbalanced braces of doc.
+func analyze(doc *document) []lspDiagnostic {
+	var diags []lspDiagnostic
+	var open []lspPosition
+	for i, line := range doc.lines {
+		if idx := strings.Index(line, "// TODO"); idx >= 0 {
+			start := lspPosition{Line: i, Character: utf16Len(line[:idx])}
+			end := lspPosition{Line: i, Character: utf16Len(line)}
+			diags = append(diags, lspDiagnostic{Range: lspRange{start, end}, Severity: 3, Message: "TODO comment"})
+			line = line[:idx]
+		}
+
+		for j, r := range line {
+			pos := lspPosition{Line: i, Character: utf16Len(line[:j])}
+			switch r {
+			case '{':
+				open = append(open, pos)
+			case '}':
+				if len(open) == 0 {
+					end := lspPosition{Line: i, Character: pos.Character + 1}
+					diags = append(diags, lspDiagnostic{Range: lspRange{pos, end}, Severity: 1, Message: "unexpected }"})
+					continue
+				}
+				open = open[:len(open)-1]
+			}
+		}
+	}
+
+	for _, pos := range open {
+		end := lspPosition{Line: pos.Line, Character: pos.Character + 1}
+		diags = append(diags, lspDiagnostic{Range: lspRange{pos, end}, Severity: 1, Message: "unclosed {"})
+	}
+	return diags
+}
+
+// document is a text addressed by the positions of the protocol.
+type document struct {
+	lines []string
+}
+
+func newDocument(text string) *document {
+	return &document{lines: strings.Split(text, "\n")}
+}
+
+func (d *document) String() string {
+	return strings.Join(d.lines, "\n")
+}
+
+// position converts a rune offset to a position.
+func (d *document) position(runeOff int) lspPosition {
+	for i, line := range d.lines {
+		n := utf8.RuneCountInString(line)
+		if runeOff <= n || i == len(d.lines)-1 {
+			runes := []rune(line)
+			return lspPosition{Line: i, Character: utf16Len(string(runes[:min(runeOff, n)]))}
+		}
+		runeOff -= n + 1
+	}
+	return lspPosition{}
+}
+
+// offset converts a position to a rune offset.
+func (d *document) offset(pos lspPosition) int {
+	off := 0
+	for i, line := range d.lines {
+		if i < pos.Line {
+			off += utf8.RuneCountInString(line) + 1
+			continue
+		}
+		units := 0
+		for _, r := range line {
+			if units >= pos.Character {
+				break
+			}
+			units += utf16.RuneLen(r)
+			off++
+		}
+		break
+	}
+	return off
+}
+
+// apply applies an incremental change.
+func (d *document) apply(c contentChange) {
+	text := d.String()
+	runes := []rune(text)
+	start, end := d.offset(c.Range.Start), d.offset(c.Range.End)
+	d.lines = strings.Split(string(runes[:start])+c.Text+string(runes[end:]), "\n")
+}
+
+// utf16Len returns the length of s in UTF-16 code units.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
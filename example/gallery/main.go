@@ -0,0 +1,209 @@
+//go:build examples
+
+// Command gallery is a gallery of small demos, each wiring the editor to one
+// of its subsystems through the public APIs only: the completion popup, a
+// language server adapter, the diff gutter, code folding, color schemes and
+// search. Every demo is self-contained in its own file, so that it can be
+// copied as the starting point of an application.
+//
+// The gallery is excluded from the regular builds. Run it with:
+//
+//	go run -tags examples ./example/gallery
+//
+// The demos are also laid out and driven headless by the tests of the
+// package, which run with:
+//
+//	go test -tags examples ./example/gallery
+package main
+
+import (
+	"log"
+	"os"
+	"regexp"
+
+	"gioui.org/app"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/oligo/gvcode"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/textstyle/syntax"
+	wg "github.com/oligo/gvcode/widget"
+)
+
+type (
+	C = layout.Context
+	D = layout.Dimensions
+)
+
+// demo is an editor wired to one of the subsystems.
+type demo struct {
+	name   string
+	editor *gvcode.Editor
+	// sync is called before the events of the editor are handled, to apply
+	// the updates made in the background, if not nil.
+	sync func()
+	// handle handles the events of the editor, if not nil.
+	handle func(gtx C, evt gvcode.EditorEvent)
+	// toolbar lays out the controls of the demo above the editor, if not nil.
+	toolbar func(gtx C, th *material.Theme) D
+}
+
+// update handles the pending events of the editor.
+func (d *demo) update(gtx C) {
+	if d.sync != nil {
+		d.sync()
+	}
+	for {
+		evt, ok := d.editor.Update(gtx)
+		if !ok {
+			break
+		}
+		if _, ok := evt.(gvcode.ChangeEvent); ok {
+			highlightKeywords(d.editor)
+		}
+		if d.handle != nil {
+			d.handle(gtx, evt)
+		}
+	}
+}
+
+func (d *demo) layout(gtx C, th *material.Theme) D {
+	d.update(gtx)
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			if d.toolbar == nil {
+				return D{}
+			}
+			return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx C) D {
+				return d.toolbar(gtx, th)
+			})
+		}),
+		layout.Flexed(1, func(gtx C) D {
+			return d.editor.Layout(gtx, th.Shaper)
+		}),
+	)
+}
+
+// newEditor creates an editor with the colors of th, showing content.
+func newEditor(th *material.Theme, content string) *gvcode.Editor {
+	editor := wg.NewEditor(th)
+	editor.WithOptions(
+		gvcode.WithColorScheme(colorScheme(th)),
+		gvcode.WithDefaultGutters(),
+		gvcode.WithGutterGap(unit.Dp(12)),
+	)
+	editor.SetText(content)
+	highlightKeywords(editor)
+	return editor
+}
+
+// colorScheme derives the colors of the editor from th.
+func colorScheme(th *material.Theme) syntax.ColorScheme {
+	scheme := syntax.ColorScheme{}
+	scheme.Foreground = gvcolor.MakeColor(th.Fg)
+	scheme.Background = gvcolor.MakeColor(th.Bg)
+	scheme.SelectColor = gvcolor.MakeColor(th.ContrastBg).MulAlpha(0x60)
+	scheme.LineColor = gvcolor.MakeColor(th.ContrastBg).MulAlpha(0x30)
+	scheme.LineNumberColor = gvcolor.MakeColor(th.Fg).MulAlpha(0xb6)
+	keyword, _ := gvcolor.Hex2Color("#AF00DB")
+	scheme.AddStyle("keyword", syntax.Bold, keyword, gvcolor.Color{})
+	return scheme
+}
+
+var keywordPattern = regexp.MustCompile(`\b(package|import|type|func|struct|return|for|range|var|const|if|else)\b`)
+
+// highlightKeywords sets the tokens of the Go keywords of the editor. A real
+// application would use a parser instead.
+func highlightKeywords(editor *gvcode.Editor) {
+	text := []byte(editor.Text())
+	var tokens []syntax.Token
+	for _, match := range keywordPattern.FindAllIndex(text, -1) {
+		// tokens are in rune offsets.
+		start := len([]rune(string(text[:match[0]])))
+		end := start + len([]rune(string(text[match[0]:match[1]])))
+		tokens = append(tokens, syntax.Token{Start: start, End: end, Scope: "keyword"})
+	}
+	editor.SetSyntaxTokens(tokens...)
+}
+
+// newDemos creates the demos of the gallery. invalidate is called when a demo
+// is updated in the background.
+func newDemos(th *material.Theme, invalidate func()) []*demo {
+	return []*demo{
+		newCompletionDemo(th),
+		newLSPDemo(th, invalidate),
+		newDiffDemo(th),
+		newFoldingDemo(th),
+		newThemeDemo(th),
+		newSearchDemo(th, invalidate),
+	}
+}
+
+// gallery shows the demos in tabs.
+type gallery struct {
+	th       *material.Theme
+	demos    []*demo
+	tabs     []widget.Clickable
+	selected int
+}
+
+func (g *gallery) layout(gtx C) D {
+	for i := range g.tabs {
+		if g.tabs[i].Clicked(gtx) {
+			g.selected = i
+		}
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			tabs := make([]layout.FlexChild, len(g.demos))
+			for i, d := range g.demos {
+				tabs[i] = layout.Rigid(func(gtx C) D {
+					btn := material.Button(g.th, &g.tabs[i], d.name)
+					if i != g.selected {
+						btn.Background = g.th.Bg
+						btn.Color = g.th.Fg
+					}
+					return layout.UniformInset(unit.Dp(2)).Layout(gtx, btn.Layout)
+				})
+			}
+			return layout.Flex{}.Layout(gtx, tabs...)
+		}),
+		layout.Flexed(1, func(gtx C) D {
+			return g.demos[g.selected].layout(gtx, g.th)
+		}),
+	)
+}
+
+func run(window *app.Window, g *gallery) error {
+	var ops op.Ops
+	for {
+		switch e := window.Event().(type) {
+		case app.DestroyEvent:
+			return e.Err
+		case app.FrameEvent:
+			gtx := app.NewContext(&ops, e)
+			g.layout(gtx)
+			e.Frame(gtx.Ops)
+		}
+	}
+}
+
+func main() {
+	window := &app.Window{}
+	window.Option(app.Title("gvcode gallery"))
+	th := material.NewTheme()
+	demos := newDemos(th, window.Invalidate)
+	g := &gallery{th: th, demos: demos, tabs: make([]widget.Clickable, len(demos))}
+
+	go func() {
+		if err := run(window, g); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}()
+	app.Main()
+}
@@ -0,0 +1,131 @@
+//go:build examples
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/oligo/gvcode"
+)
+
+// newSearchDemo searches the editor in the background as the query is typed,
+// and selects the matches one after another.
+func newSearchDemo(th *material.Theme, invalidate func()) *demo {
+	editor := newEditor(th, strings.Repeat(foldingText, 20))
+	return newSearchBar(editor, invalidate).demo()
+}
+
+// searchBar is the search UI of an editor.
+type searchBar struct {
+	editor     *gvcode.Editor
+	invalidate func()
+
+	query     widget.Editor
+	matchCase widget.Bool
+	regexp    widget.Bool
+	wholeWord widget.Bool
+	prev      widget.Clickable
+	next      widget.Clickable
+	// status describes the result of the last search.
+	status string
+}
+
+func newSearchBar(editor *gvcode.Editor, invalidate func()) *searchBar {
+	s := &searchBar{editor: editor, invalidate: invalidate}
+	s.query.SingleLine = true
+	s.query.Submit = true
+	return s
+}
+
+// demo shows the search bar above the editor.
+func (s *searchBar) demo() *demo {
+	return &demo{
+		name:    "Search",
+		editor:  s.editor,
+		handle:  s.handle,
+		toolbar: s.layout,
+	}
+}
+
+// search starts searching the current query, replacing the previous search.
+func (s *searchBar) search() {
+	query := gvcode.SearchQuery{
+		Pattern:   s.query.Text(),
+		Regexp:    s.regexp.Value,
+		MatchCase: s.matchCase.Value,
+		WholeWord: s.wholeWord.Value,
+	}
+	if query.Pattern == "" {
+		s.editor.ClearSearch()
+		s.status = ""
+		return
+	}
+	if err := s.editor.Search(query, s.invalidate); err != nil {
+		s.status = err.Error()
+		return
+	}
+	s.status = "searching…"
+}
+
+func (s *searchBar) handle(gtx C, evt gvcode.EditorEvent) {
+	if evt, ok := evt.(gvcode.SearchEvent); ok {
+		s.status = fmt.Sprintf("%d matches", evt.Matches)
+	}
+}
+
+func (s *searchBar) update(gtx C) {
+	changed := false
+	for {
+		evt, ok := s.query.Update(gtx)
+		if !ok {
+			break
+		}
+		switch evt.(type) {
+		case widget.ChangeEvent:
+			changed = true
+		case widget.SubmitEvent:
+			s.editor.SelectNextMatch(false)
+		}
+	}
+	for _, b := range []*widget.Bool{&s.matchCase, &s.regexp, &s.wholeWord} {
+		if b.Update(gtx) {
+			changed = true
+		}
+	}
+	if changed {
+		s.search()
+	}
+
+	if s.prev.Clicked(gtx) {
+		s.editor.SelectNextMatch(true)
+	}
+	if s.next.Clicked(gtx) {
+		s.editor.SelectNextMatch(false)
+	}
+}
+
+func (s *searchBar) layout(gtx C, th *material.Theme) D {
+	s.update(gtx)
+
+	return layout.Flex{Alignment: layout.Middle, Spacing: layout.SpaceEnd}.Layout(gtx,
+		layout.Flexed(1, func(gtx C) D {
+			return widget.Border{Color: th.Fg, Width: unit.Dp(1)}.Layout(gtx, func(gtx C) D {
+				return layout.UniformInset(unit.Dp(4)).Layout(gtx, material.Editor(th, &s.query, "Search").Layout)
+			})
+		}),
+		layout.Rigid(material.CheckBox(th, &s.matchCase, "Aa").Layout),
+		layout.Rigid(material.CheckBox(th, &s.wholeWord, "Word").Layout),
+		layout.Rigid(material.CheckBox(th, &s.regexp, ".*").Layout),
+		layout.Rigid(material.Button(th, &s.prev, "↑").Layout),
+		layout.Rigid(layout.Spacer{Width: unit.Dp(4)}.Layout),
+		layout.Rigid(material.Button(th, &s.next, "↓").Layout),
+		layout.Rigid(func(gtx C) D {
+			return layout.Inset{Left: unit.Dp(8)}.Layout(gtx, material.Body2(th, s.status).Layout)
+		}),
+	)
+}
@@ -0,0 +1,79 @@
+//go:build examples
+
+package main
+
+import (
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/oligo/gvcode"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+const themesText = `package main
+
+// Pick a theme above to restyle the editor. A color scheme sets the colors of
+// the editor, and the styles of the scopes of the syntax tokens.
+func main() {
+	for i := range 3 {
+		println(i)
+	}
+}
+`
+
+// theme is a color scheme given as hex colors.
+type theme struct {
+	name                                         string
+	fg, bg, selection, line, lineNumber, keyword string
+}
+
+var themes = []theme{
+	{name: "Light", fg: "#1f2328", bg: "#ffffff", selection: "#0969da40", line: "#eaeef2", lineNumber: "#8c959f", keyword: "#cf222e"},
+	{name: "Dark", fg: "#d4d4d4", bg: "#1e1e1e", selection: "#264f78", line: "#2a2d2e", lineNumber: "#858585", keyword: "#569cd6"},
+	{name: "Solarized", fg: "#657b83", bg: "#fdf6e3", selection: "#eee8d5", line: "#eee8d5", lineNumber: "#93a1a1", keyword: "#859900"},
+}
+
+// scheme builds the color scheme of the theme.
+func (t theme) scheme() syntax.ColorScheme {
+	hex := func(s string) gvcolor.Color {
+		c, _ := gvcolor.Hex2Color(s)
+		return c
+	}
+
+	scheme := syntax.ColorScheme{}
+	scheme.Name = t.name
+	scheme.Foreground = hex(t.fg)
+	scheme.Background = hex(t.bg)
+	scheme.SelectColor = hex(t.selection)
+	scheme.LineColor = hex(t.line)
+	scheme.LineNumberColor = hex(t.lineNumber)
+	scheme.AddStyle("keyword", syntax.Bold, hex(t.keyword), gvcolor.Color{})
+	return scheme
+}
+
+// newThemeDemo switches the color scheme of the editor.
+func newThemeDemo(th *material.Theme) *demo {
+	editor := newEditor(th, themesText)
+	var selected widget.Enum
+
+	return &demo{
+		name:   "Themes",
+		editor: editor,
+		toolbar: func(gtx C, th *material.Theme) D {
+			if selected.Update(gtx) {
+				for _, t := range themes {
+					if t.name == selected.Value {
+						editor.WithOptions(gvcode.WithColorScheme(t.scheme()))
+					}
+				}
+			}
+
+			buttons := make([]layout.FlexChild, len(themes))
+			for i, t := range themes {
+				buttons[i] = layout.Rigid(material.RadioButton(th, &selected, t.name, t.name).Layout)
+			}
+			return layout.Flex{Alignment: layout.Middle}.Layout(gtx, buttons...)
+		},
+	}
+}
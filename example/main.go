@@ -461,5 +461,5 @@ func (c *goCompletor) Suggest(ctx gvcode.CompletionContext) []gvcode.CompletionC
 }
 
 func (c *goCompletor) FilterAndRank(pattern string, candidates []gvcode.CompletionCandidate) []gvcode.CompletionCandidate {
-	return candidates
+	return completion.FuzzyFilterAndRank(pattern, candidates)
 }
@@ -0,0 +1,118 @@
+package gvcode
+
+import (
+	"image"
+	"io"
+	"math"
+	"strings"
+
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/io/transfer"
+	"gioui.org/layout"
+)
+
+// uriListMIME is the MIME type of the files dropped over the editor.
+const uriListMIME = "text/uri-list"
+
+// FileDropEvent is generated when files are dropped over the editor, for the
+// host app to insert their paths, open them, or embed their contents.
+type FileDropEvent struct {
+	// URIs are the dropped files, as URIs like "file:///tmp/main.go", or as
+	// paths if the host passed paths to DropFiles.
+	URIs []string
+	// PixelOff is the drop position, relative to the editor, and Pos is the
+	// text position under it. They are those of the caret if the pointer
+	// position is unknown.
+	PixelOff image.Point
+	Pos      Position
+}
+
+func (FileDropEvent) isEditorEvent() {}
+
+// fileDropState tracks the pointer over the editor, to locate the drops.
+type fileDropState struct {
+	pos    image.Point
+	hasPos bool
+}
+
+// DropFiles generates a FileDropEvent for the files dropped over the editor
+// at the last pointer position. Gio delivers the drops of other Gio widgets
+// as transfer events of the "text/uri-list" type, which the editor handles
+// itself; hosts receiving the drops of the system in another way, e.g., with
+// the file drop callback of their app package, call DropFiles instead.
+func (e *Editor) DropFiles(uris []string) {
+	e.initBuffer()
+	if len(uris) == 0 {
+		return
+	}
+	e.pending = append(e.pending, e.fileDropEvent(uris))
+}
+
+// fileDropEvent returns the FileDropEvent of uris at the last pointer
+// position.
+func (e *Editor) fileDropEvent(uris []string) FileDropEvent {
+	evt := FileDropEvent{URIs: uris}
+	if !e.fileDrop.hasPos {
+		caret, _ := e.text.Selection()
+		evt.Pos.Line, evt.Pos.Column = e.text.CaretPos()
+		evt.Pos.Runes = caret
+		evt.PixelOff = e.text.CaretCoords().Round()
+		return evt
+	}
+
+	runeOff := e.text.ClosestOffset(e.fileDrop.pos)
+	line, para := e.text.FindParagraph(runeOff)
+	evt.PixelOff = e.fileDrop.pos
+	evt.Pos = Position{Line: line, Column: runeOff - para.RuneOff, Runes: runeOff}
+	return evt
+}
+
+// onFileDrop handles the transfer of a "text/uri-list".
+func (e *Editor) onFileDrop(data transfer.DataEvent) EditorEvent {
+	r := data.Open()
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil
+	}
+	uris := parseURIList(string(content))
+	if len(uris) == 0 {
+		return nil
+	}
+	return e.fileDropEvent(uris)
+}
+
+// trackDropPosition records the position of the pointer over the editor.
+func (e *Editor) trackDropPosition(gtx layout.Context) {
+	for {
+		evt, ok := gtx.Event(pointer.Filter{Target: &e.fileDrop, Kinds: pointer.Enter | pointer.Move | pointer.Leave})
+		if !ok {
+			return
+		}
+		pe, ok := evt.(pointer.Event)
+		if !ok {
+			continue
+		}
+		e.fileDrop.pos = image.Point{X: int(math.Round(float64(pe.Position.X))), Y: int(math.Round(float64(pe.Position.Y)))}
+		e.fileDrop.hasPos = pe.Kind != pointer.Leave
+	}
+}
+
+// addDropHandler registers the handler tracking the pointer position.
+func (e *Editor) addDropHandler(gtx layout.Context) {
+	event.Op(gtx.Ops, &e.fileDrop)
+}
+
+// parseURIList returns the URIs of a "text/uri-list", skipping the comments.
+func parseURIList(list string) []string {
+	var uris []string
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		uris = append(uris, line)
+	}
+	return uris
+}
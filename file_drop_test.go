@@ -0,0 +1,70 @@
+package gvcode
+
+import (
+	"image"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/pointer"
+	"gioui.org/io/transfer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestFileDrop(t *testing.T) {
+	e := newTestEditor(t, "ab\ncd")
+
+	var router input.Router
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	frame := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 200)), Source: router.Source()}
+		e.Layout(gtx, shaper)
+		router.Frame(gtx.Ops)
+	}
+	frame()
+
+	// without a pointer position, the files are dropped at the caret.
+	e.SetCaret(1, 1)
+	data := transfer.DataEvent{Type: uriListMIME, Open: func() io.ReadCloser {
+		return io.NopCloser(strings.NewReader("# comment\r\nfile:///tmp/a.go\r\nfile:///tmp/b.go\r\n"))
+	}}
+	evt, ok := e.onFileDrop(data).(FileDropEvent)
+	if !ok {
+		t.Fatal("expected a FileDropEvent")
+	}
+	if !slices.Equal(evt.URIs, []string{"file:///tmp/a.go", "file:///tmp/b.go"}) {
+		t.Errorf("got URIs %q", evt.URIs)
+	}
+	if evt.Pos != (Position{Line: 0, Column: 1, Runes: 1}) {
+		t.Errorf("got position %+v, want the caret", evt.Pos)
+	}
+
+	// the drop is located at the pointer.
+	r := e.text.Regions(4, 5, nil)[0].Bounds
+	pos := f32.Pt(float32(r.Min.X+1), float32(r.Min.Y+r.Dy()/2))
+	router.Queue(pointer.Event{Kind: pointer.Move, Source: pointer.Mouse, Position: pos})
+	frame()
+	e.DropFiles([]string{"/tmp/c.go"})
+	var got *FileDropEvent
+	for {
+		evt, ok := e.Update(layout.Context{Ops: new(op.Ops), Source: router.Source()})
+		if !ok {
+			break
+		}
+		if drop, ok := evt.(FileDropEvent); ok {
+			got = &drop
+		}
+	}
+	if got == nil {
+		t.Fatal("expected a FileDropEvent")
+	}
+	if got.Pos != (Position{Line: 1, Column: 1, Runes: 4}) || got.URIs[0] != "/tmp/c.go" {
+		t.Errorf("got %+v", *got)
+	}
+}
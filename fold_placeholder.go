@@ -0,0 +1,185 @@
+package gvcode
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/internal/folding"
+	"github.com/oligo/gvcode/painter"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+// placeholderSegment is a piece of the summary of a collapsed fold.
+type placeholderSegment struct {
+	text string
+	// bracket is true for the closing part of the summary, painted with the
+	// color of the bracket opening the fold.
+	bracket bool
+	// info is true for the ellipsis and the line count.
+	info bool
+}
+
+// foldPlaceholderBounds are the bounds of the placeholder of a collapsed
+// fold, relative to the viewport.
+type foldPlaceholderBounds struct {
+	startLine int
+	bounds    image.Rectangle
+}
+
+// foldClosers are the closing brackets of the brackets opening a fold at the
+// end of its start line.
+var foldClosers = map[rune]string{'{': "}", '(': ")", '[': "]"}
+
+// foldPlaceholder returns the summary painted after the start line of the
+// collapsed fold, which keeps showing its source text line. E.g., the summary
+// of a function starting with "func Foo(a int) error {" is " … 42 lines }",
+// closed by the counterpart of the bracket ending the line, or by the end of
+// the block comment for comments.
+func foldPlaceholder(line string, fold folding.FoldRange, markers folding.Markers) []placeholderSegment {
+	lines := fold.EndLine - fold.StartLine
+	count := fmt.Sprintf(" … %d lines ", lines)
+	if lines == 1 {
+		count = " … 1 line "
+	}
+
+	closer := ""
+	if fold.Type == folding.FoldTypeComment {
+		closer = markers.BlockCommentEnd
+		if markers.BlockCommentStart == "" {
+			closer = "*/"
+		}
+	} else if last, _ := utf8.DecodeLastRuneInString(strings.TrimRightFunc(line, unicode.IsSpace)); last != utf8.RuneError {
+		closer = foldClosers[last]
+	}
+	if closer == "" {
+		return []placeholderSegment{{text: strings.TrimRight(count, " "), info: true}}
+	}
+	return []placeholderSegment{{text: count, info: true}, {text: closer, bracket: true}}
+}
+
+// paintFoldPlaceholders paints the summary of each visible collapsed fold
+// after its start line. The start line itself is painted with the rest of the
+// text, together with its caret and selection. The summary is styled with
+// syntax.FoldPlaceholderScope if the color scheme registers it. The bounds of
+// the summaries are recorded for the fold preview.
+func (e *Editor) paintFoldPlaceholders(gtx layout.Context, shaper *text.Shaper, textColor gvcolor.Color) {
+	e.foldPlaceholders = e.foldPlaceholders[:0]
+	foldManager := e.text.FoldManager()
+	if shaper == nil || foldManager == nil || !foldManager.HasCollapsed() {
+		return
+	}
+
+	infoColor := textColor.MulAlpha(0xA0)
+	var boxColor gvcolor.Color
+	if fg, bg, ok := e.text.ScopeColors(syntax.FoldPlaceholderScope); ok {
+		if fg.IsSet() {
+			infoColor = fg
+		}
+		boxColor = bg
+	}
+
+	viewport := e.text.Viewport()
+	paragraphs := e.text.TextLayout().Paragraphs
+	markers := foldManager.Markers()
+
+	params := e.text.Params()
+	params.MinWidth = 0
+	params.MaxWidth = 1 << 24
+	params.MaxLines = 1
+
+	for _, fold := range foldManager.GetFoldRanges() {
+		if !fold.Collapsed || !foldManager.IsLineVisible(fold.StartLine) {
+			continue
+		}
+		if fold.StartLine >= len(paragraphs) {
+			continue
+		}
+		para := paragraphs[fold.StartLine]
+		if para.EndY+para.Descent.Ceil() < viewport.Min.Y || para.StartY-para.Ascent.Ceil() > viewport.Max.Y {
+			continue
+		}
+
+		lineText := strings.TrimRight(e.ReadRange(para.RuneOff, para.RuneOff+para.Runes), "\r\n")
+		lineEnd := para.RuneOff + utf8.RuneCountInString(lineText)
+		// the closing bracket has the color of the opening one.
+		bracketColor := textColor
+		trimmed := strings.TrimRightFunc(lineText, unicode.IsSpace)
+		if off := para.RuneOff + utf8.RuneCountInString(trimmed) - 1; off >= para.RuneOff {
+			if colors := e.text.SyntaxColors(off, off+1); len(colors) > 0 {
+				bracketColor = colors[len(colors)-1].Color
+			}
+		}
+
+		pos := e.text.RuneCoords(lineEnd)
+		origin := image.Pt(int(pos.X), int(pos.Y))
+		top, bottom := origin.Y-para.Ascent.Ceil(), origin.Y+para.Descent.Ceil()
+
+		// record the glyphs first to know the width of the box.
+		macro := op.Record(gtx.Ops)
+		width := 0
+		for _, seg := range foldPlaceholder(lineText, fold, markers) {
+			c := infoColor
+			if seg.bracket {
+				c = bracketColor
+			}
+			glyphs, advance := shapeLine(shaper, params, seg.text)
+			if len(glyphs) > 0 {
+				trans := op.Affine(f32.Affine2D{}.Offset(f32.Point{
+					X: float32(origin.X + width + glyphs[0].X.Floor()), Y: float32(origin.Y),
+				})).Push(gtx.Ops)
+				painter.DrawGlyphs(gtx, shaper, glyphs, c.Op(gtx.Ops))
+				trans.Pop()
+			}
+			width += advance
+		}
+		call := macro.Stop()
+
+		box := image.Rect(origin.X, top, origin.X+width, bottom)
+		if boxColor.IsSet() {
+			radius := gtx.Dp(unit.Dp(3))
+			paint.FillShape(gtx.Ops, boxColor.NRGBA(), clip.UniformRRect(box, radius).Op(gtx.Ops))
+		}
+		call.Add(gtx.Ops)
+		e.foldPlaceholders = append(e.foldPlaceholders, foldPlaceholderBounds{startLine: fold.StartLine, bounds: box})
+	}
+}
+
+// foldPlaceholderAt returns the start line of the collapsed fold whose
+// placeholder, painted in the last layout, covers pos. It reports false if
+// there is none.
+func (e *Editor) foldPlaceholderAt(pos image.Point) (int, bool) {
+	for _, p := range e.foldPlaceholders {
+		if pos.In(p.bounds) {
+			return p.startLine, true
+		}
+	}
+	return 0, false
+}
+
+// shapeLine shapes s on a single line with params, returning its glyphs and
+// their advance.
+func shapeLine(shaper *text.Shaper, params text.Parameters, s string) ([]text.Glyph, int) {
+	shaper.LayoutString(params, s)
+	var glyphs []text.Glyph
+	advance := 0
+	for {
+		g, ok := shaper.NextGlyph()
+		if !ok {
+			break
+		}
+		advance += g.Advance.Ceil()
+		glyphs = append(glyphs, g)
+	}
+	return glyphs, advance
+}
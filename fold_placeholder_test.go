@@ -0,0 +1,69 @@
+package gvcode
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"github.com/oligo/gvcode/internal/folding"
+)
+
+func TestFoldPlaceholder(t *testing.T) {
+	cases := []struct {
+		line string
+		fold folding.FoldRange
+		want string
+	}{
+		{"func (r *R) Foo(a int) error {", folding.FoldRange{StartLine: 1, EndLine: 43, Type: folding.FoldTypeFunction, Name: "Foo"}, " … 42 lines }"},
+		{"type Bar struct { ", folding.FoldRange{StartLine: 0, EndLine: 1, Type: folding.FoldTypeType, Name: "Bar"}, " … 1 line }"},
+		{"import (", folding.FoldRange{StartLine: 0, EndLine: 5, Type: folding.FoldTypeImport}, " … 5 lines )"},
+		{"var x = []int{", folding.FoldRange{StartLine: 0, EndLine: 2, Type: folding.FoldTypeFunction}, " … 2 lines }"},
+		{"/* a comment", folding.FoldRange{StartLine: 0, EndLine: 3, Type: folding.FoldTypeComment}, " … 3 lines */"},
+		{"//region setup", folding.FoldRange{StartLine: 0, EndLine: 3, Type: folding.FoldTypeRegion, Name: "setup"}, " … 3 lines"},
+	}
+
+	for _, tc := range cases {
+		var sb strings.Builder
+		for _, seg := range foldPlaceholder(tc.line, tc.fold, folding.Markers{}) {
+			sb.WriteString(seg.text)
+		}
+		if got := sb.String(); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.line, got, tc.want)
+		}
+	}
+
+	// the comment is closed by the block comment end of the language.
+	markers := folding.Markers{BlockCommentStart: "{-", BlockCommentEnd: "-}"}
+	segments := foldPlaceholder("{- a", folding.FoldRange{EndLine: 2, Type: folding.FoldTypeComment}, markers)
+	if last := segments[len(segments)-1]; last.text != "-}" || !last.bracket {
+		t.Errorf("got the comment closed by %+v, want -}", last)
+	}
+}
+
+func TestPaintFoldPlaceholders(t *testing.T) {
+	content := "package main\n\nfunc f() {\n\ta()\n\tb()\n}"
+	e := newTestEditor(t, content)
+	fm := folding.NewManager()
+	fm.AnalyzeLines(strings.Split(content, "\n"))
+	if !fm.CollapseFold(2) {
+		t.Fatal("expected the function to be folded")
+	}
+	e.text.SetFoldManager(fm)
+
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+	e.Layout(gtx, text.NewShaper(text.WithCollection(gofont.Collection())))
+	paragraphs := e.text.TextLayout().Paragraphs
+	if len(paragraphs) != 6 {
+		t.Fatalf("got %d lines, want 6", len(paragraphs))
+	}
+	// the folded lines take no space.
+	for i := 3; i < len(paragraphs); i++ {
+		if paragraphs[i].StartY != paragraphs[2].EndY {
+			t.Errorf("line %d: got y %d, want %d", i, paragraphs[i].StartY, paragraphs[2].EndY)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package gvcode
+
+import (
+	"image"
+	"image/color"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/internal/folding"
+	gestureExt "github.com/oligo/gvcode/internal/gesture"
+)
+
+// defaultFoldPreviewLines is the number of hidden lines shown when hovering
+// over a collapsed fold, if not configured by WithFoldPreview.
+const defaultFoldPreviewLines = 10
+
+// hoveredFold returns the collapsed fold whose gutter button or placeholder
+// is hovered.
+func (e *Editor) hoveredFold() (folding.FoldRange, bool) {
+	if e.foldPreviewLines < 0 {
+		return folding.FoldRange{}, false
+	}
+
+	if e.gutterManager != nil {
+		for _, p := range e.gutterManager.Providers() {
+			if provider, ok := p.(interface {
+				HoveredFold() (folding.FoldRange, bool)
+			}); ok {
+				if fold, ok := provider.HoveredFold(); ok {
+					return fold, true
+				}
+				break
+			}
+		}
+	}
+
+	// the pointer may have left the editor since the hover started.
+	if fm := e.text.FoldManager(); fm != nil && e.placeholderHovered && e.hover.Hovering() {
+		if fold := fm.GetFoldAtLine(e.hoveredPlaceholder); fold != nil && fold.Collapsed {
+			return *fold, true
+		}
+	}
+	return folding.FoldRange{}, false
+}
+
+// updateHoveredPlaceholder tracks the fold placeholder under the hovering
+// pointer, or clears it if the hover is cancelled.
+func (e *Editor) updateHoveredPlaceholder(evt gestureExt.HoverEvent) {
+	e.placeholderHovered = false
+	if evt.Kind == gestureExt.KindHovered {
+		e.hoveredPlaceholder, e.placeholderHovered = e.foldPlaceholderAt(evt.Position)
+	}
+}
+
+// paintFoldPreview shows the first lines hidden by the hovered fold in a
+// read-only box below the fold start line, so that users can peek into the
+// fold without expanding it.
+func (e *Editor) paintFoldPreview(gtx layout.Context, shaper *text.Shaper, textColor gvcolor.Color) {
+	fold, ok := e.hoveredFold()
+	if !ok || shaper == nil || fold.EndLine <= fold.StartLine {
+		return
+	}
+
+	maxLines := e.foldPreviewLines
+	if maxLines == 0 {
+		maxLines = defaultFoldPreviewLines
+	}
+	last := min(fold.EndLine, fold.StartLine+maxLines, e.text.Paragraphs()-1)
+	if last <= fold.StartLine {
+		return
+	}
+
+	_, pos := e.ConvertPos(fold.StartLine, 0)
+	offset := image.Point{X: e.text.ScrollOff().X, Y: int(pos.Y)}
+	e.text.PaintOverlay(gtx, offset, func(gtx layout.Context) layout.Dimensions {
+		return e.layoutFoldPreview(gtx, shaper, textColor, fold.StartLine+1, last, last < fold.EndLine)
+	})
+}
+
+// layoutFoldPreview renders the lines from first to last (inclusive) with
+// syntax colors. If truncated is true, an ellipsis line is appended to hint
+// that there are more hidden lines.
+func (e *Editor) layoutFoldPreview(gtx layout.Context, shaper *text.Shaper, textColor gvcolor.Color, first, last int, truncated bool) layout.Dimensions {
+	lineHeight := e.text.GetLineHeight().Ceil()
+	padding := gtx.Dp(unit.Dp(6))
+	maxWidth := gtx.Constraints.Max.X - 2*padding
+
+	params := e.text.Params()
+	params.MinWidth = 0
+	params.MaxWidth = 1 << 20
+	params.MaxLines = 1
+
+	// Record the content first to know the size of the box.
+	macro := op.Record(gtx.Ops)
+	width := 0
+	rows := 0
+	for line := first; line <= last; line++ {
+		start := e.text.ConvertPos(line, 0)
+		end := e.columnLineEnd(line)
+		w := e.paintPreviewLine(gtx, shaper, params, start, end, image.Pt(padding, padding+rows*lineHeight), maxWidth, textColor)
+		width = max(width, w)
+		rows++
+	}
+	if truncated {
+		shaper.LayoutString(params, "…")
+		width = max(width, paintTextGlyphs(gtx, shaper, image.Pt(padding, padding+rows*lineHeight), maxWidth, textColor.MulAlpha(0xA0)))
+		rows++
+	}
+	content := macro.Stop()
+
+	size := image.Pt(min(width, maxWidth)+2*padding, rows*lineHeight+2*padding)
+	rect := image.Rectangle{Max: size}
+	radius := gtx.Dp(unit.Dp(4))
+
+	bgColor := color.NRGBA{R: 0xF8, G: 0xF8, B: 0xF8, A: 0xFF}
+	if e.colorPalette != nil && e.colorPalette.Background.IsSet() {
+		bgColor = e.colorPalette.Background.NRGBA()
+		bgColor.A = 0xFF
+	}
+	paint.FillShape(gtx.Ops, bgColor, clip.UniformRRect(rect, radius).Op(gtx.Ops))
+
+	borderColor := textColor.NRGBA()
+	borderColor.A = 0x40
+	paint.FillShape(gtx.Ops, borderColor, clip.Stroke{
+		Path:  clip.UniformRRect(rect, radius).Path(gtx.Ops),
+		Width: float32(gtx.Dp(unit.Dp(1))),
+	}.Op())
+
+	stack := clip.Rect(rect.Inset(padding / 2)).Push(gtx.Ops)
+	content.Add(gtx.Ops)
+	stack.Pop()
+
+	return layout.Dimensions{Size: size}
+}
+
+// paintPreviewLine paints the text of the rune range [start, end) at the
+// top-left position pos, coloring it with the syntax tokens. It returns the
+// width of the painted line.
+func (e *Editor) paintPreviewLine(gtx layout.Context, shaper *text.Shaper, params text.Parameters, start, end int, pos image.Point, maxWidth int, textColor gvcolor.Color) int {
+	lineText := []rune(e.ReadRange(start, end))
+	colors := e.text.SyntaxColors(start, end)
+	tabWidth := e.text.RenderTabWidth()
+	tab := strings.Repeat(" ", max(tabWidth, 1))
+
+	x := 0
+	paintSegment := func(from, to int, c gvcolor.Color) {
+		if from >= to || x >= maxWidth {
+			return
+		}
+		segment := strings.ReplaceAll(string(lineText[from-start:to-start]), "\t", tab)
+		shaper.LayoutString(params, segment)
+		x += paintTextGlyphs(gtx, shaper, pos.Add(image.Pt(x, 0)), maxWidth-x, c)
+	}
+
+	next := start
+	for _, r := range colors {
+		if r.Start < next {
+			// nested tokens are covered by the outer one.
+			continue
+		}
+		paintSegment(next, r.Start, textColor)
+		paintSegment(r.Start, r.End, r.Color)
+		next = r.End
+	}
+	paintSegment(next, end, textColor)
+
+	return x
+}
+
+// paintTextGlyphs paints the glyphs of the text laid out by shaper at the
+// top-left position pos, and returns their advance. Glyphs exceeding maxWidth
+// are skipped.
+func paintTextGlyphs(gtx layout.Context, shaper *text.Shaper, pos image.Point, maxWidth int, c gvcolor.Color) int {
+	var glyphs []text.Glyph
+	advance := 0
+	clipped := false
+	for {
+		g, ok := shaper.NextGlyph()
+		if !ok {
+			break
+		}
+		if clipped || advance+g.Advance.Ceil() > maxWidth {
+			clipped = true
+			continue
+		}
+		advance += g.Advance.Ceil()
+		glyphs = append(glyphs, g)
+	}
+	if len(glyphs) == 0 {
+		return advance
+	}
+
+	// the shape of the glyphs is relative to the dot of the first one, which
+	// is on the baseline below pos.
+	trans := op.Affine(f32.Affine2D{}.Offset(layout.FPt(pos.Add(image.Pt(glyphs[0].X.Floor(), int(glyphs[0].Y)))))).Push(gtx.Ops)
+	outline := clip.Outline{Path: shaper.Shape(glyphs)}.Op().Push(gtx.Ops)
+	c.Op(gtx.Ops).Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+	outline.Pop()
+	trans.Pop()
+
+	return advance
+}
@@ -0,0 +1,63 @@
+package gvcode
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestFoldPlaceholderPreview(t *testing.T) {
+	e := newTestEditor(t, foldAnimationText, WithCodeFolding())
+
+	var router input.Router
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	now := time.Now()
+	frame := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 300)), Source: router.Source(), Now: now}
+		e.Layout(gtx, shaper)
+		router.Frame(gtx.Ops)
+	}
+	// the folds are detected by the gutter in the first frame.
+	frame()
+	frame()
+	if !e.ToggleFold() {
+		t.Fatal("no fold at the caret")
+	}
+	frame()
+	if len(e.foldPlaceholders) != 1 || e.foldPlaceholders[0].startLine != 0 {
+		t.Fatalf("got placeholders %+v, want the one of line 0", e.foldPlaceholders)
+	}
+
+	hover := func(pos image.Point) {
+		router.Queue(pointer.Event{Kind: pointer.Move, Source: pointer.Mouse, Position: layout.FPt(pos)})
+		frame()
+		now = now.Add(time.Second)
+		frame()
+	}
+
+	// the text before the placeholder shows no preview.
+	bounds := e.foldPlaceholders[0].bounds
+	hover(image.Pt(bounds.Min.X-20, bounds.Min.Y+bounds.Dy()/2))
+	if fold, ok := e.hoveredFold(); ok {
+		t.Fatalf("got fold %+v previewed over the text", fold)
+	}
+
+	hover(bounds.Min.Add(bounds.Max).Div(2))
+	fold, ok := e.hoveredFold()
+	if !ok || fold.StartLine != 0 {
+		t.Fatalf("got fold %+v, %v previewed over the placeholder, want the fold of line 0", fold, ok)
+	}
+
+	// expanding the fold ends the preview.
+	e.ToggleFold()
+	if fold, ok := e.hoveredFold(); ok {
+		t.Errorf("got fold %+v previewed after expanding it", fold)
+	}
+}
@@ -0,0 +1,71 @@
+package gvcode
+
+import (
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+// ToggleFold collapses or expands the innermost fold containing the caret
+// line. If the fold is collapsed, the caret is moved to the start line of the
+// fold, as the other lines are hidden. It reports whether a fold is toggled.
+func (e *Editor) ToggleFold() bool {
+	e.initBuffer()
+	fm := e.text.FoldManager()
+	if fm == nil {
+		return false
+	}
+
+	line, _ := e.text.CaretPos()
+	fold := fm.GetFoldAtLine(line)
+	if fold == nil {
+		fold = fm.GetDeepestFoldAtLine(line)
+	}
+	if fold == nil {
+		return false
+	}
+
+	startLine := fold.StartLine
+	if fm.ToggleFold(startLine) && line != startLine {
+		off := e.text.ConvertPos(startLine, 0)
+		e.SetCaret(off, off)
+	}
+	e.text.Invalidate()
+	return true
+}
+
+// foldAnimation times the animation of the lines after folds are collapsed
+// or expanded. The lines are moved by the layout of the text view.
+type foldAnimation struct {
+	// duration of the animation. The lines jump if it is zero.
+	duration time.Duration
+	begin    time.Time
+}
+
+// startFoldAnimation starts timing the fold animation if it is started, or
+// restarted, by the last layout.
+func (e *Editor) startFoldAnimation(gtx layout.Context) {
+	if !e.text.FoldAnimating() {
+		return
+	}
+	if e.text.FoldAnimationProgress() == 0 {
+		e.foldAnim.begin = gtx.Now
+	}
+	gtx.Execute(op.InvalidateCmd{})
+}
+
+// animateFolds sets the progress of the fold animation in the current frame.
+func (e *Editor) animateFolds(gtx layout.Context) {
+	a := &e.foldAnim
+	if !e.text.FoldAnimating() || a.begin.IsZero() {
+		return
+	}
+
+	elapsed := gtx.Now.Sub(a.begin)
+	if elapsed >= a.duration || e.reducedMotion {
+		e.text.SetFoldAnimationProgress(1)
+		return
+	}
+	e.text.SetFoldAnimationProgress(easeOutCubic(float32(elapsed) / float32(a.duration)))
+}
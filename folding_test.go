@@ -0,0 +1,79 @@
+package gvcode
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+const foldAnimationText = "func a() {\n\tx := 1\n\ty := 2\n\tz := 3\n}\nfunc b() {}\n"
+
+// lineY returns the top of the line in the last layout.
+func lineY(e *Editor, line int) int {
+	_, p := e.text.FindParagraph(e.text.ConvertPos(line, 0))
+	return p.StartY
+}
+
+func TestFoldAnimation(t *testing.T) {
+	e := newTestEditor(t, foldAnimationText, WithCodeFolding(), WithFoldAnimation(120*time.Millisecond))
+
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	now := time.Now()
+	frame := func(elapsed time.Duration) {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 300)), Now: now.Add(elapsed)}
+		e.Layout(gtx, shaper)
+	}
+	// the folds are detected by the gutter in the first frame.
+	frame(0)
+	frame(0)
+	expanded := lineY(e, 5)
+
+	if !e.ToggleFold() {
+		t.Fatal("no fold at the caret")
+	}
+	frame(0)
+	if got := lineY(e, 5); got != expanded {
+		t.Fatalf("got line 5 at %d in the first frame, want it at %d before the fold", got, expanded)
+	}
+	frame(60 * time.Millisecond)
+	middle := lineY(e, 5)
+	frame(120 * time.Millisecond)
+	collapsed := lineY(e, 5)
+	if middle >= expanded || middle <= collapsed {
+		t.Errorf("got line 5 at %d in the middle of the animation, want it between %d and %d", middle, expanded, collapsed)
+	}
+	if e.text.FoldAnimating() {
+		t.Error("the animation did not end")
+	}
+
+	// with reduced motion, the lines jump to their new positions.
+	e.WithOptions(WithReducedMotion(true))
+	e.ToggleFold()
+	frame(200 * time.Millisecond)
+	if got := lineY(e, 5); got != expanded || e.text.FoldAnimating() {
+		t.Errorf("got line 5 at %d with reduced motion, want it at %d", got, expanded)
+	}
+}
+
+func TestFoldAnimationGutterToggle(t *testing.T) {
+	e := newTestEditor(t, foldAnimationText, WithCodeFolding(), WithFoldAnimation(120*time.Millisecond))
+
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 300)), Now: time.Now()}
+	e.Layout(gtx, shaper)
+	e.Layout(gtx, shaper)
+	expanded := lineY(e, 5)
+
+	// the folds toggled outside of the editor, e.g., by the fold buttons,
+	// are laid out and animated in the next frame.
+	e.text.FoldManager().ToggleFold(0)
+	e.Layout(gtx, shaper)
+	if !e.text.FoldAnimating() || lineY(e, 5) != expanded {
+		t.Errorf("got line 5 at %d, want the fold animation to start at %d", lineY(e, 5), expanded)
+	}
+}
@@ -0,0 +1,167 @@
+package gvcode
+
+import (
+	"time"
+)
+
+// FrameStats is the time spent by the editor in the phases of a frame.
+type FrameStats struct {
+	// Layout is the time spent shaping and laying out the text.
+	Layout time.Duration
+	// Tokenize is the time spent applying the syntax tokens set by
+	// SetSyntaxTokens since the previous frame.
+	Tokenize time.Duration
+	// Gutter is the time spent laying out the gutter providers.
+	Gutter time.Duration
+	// Paint is the time spent painting the text, the decorations and the
+	// overlays.
+	Paint time.Duration
+	// Total is the time spent in Layout, including the processing of the
+	// input events, plus the tokenization.
+	Total time.Duration
+}
+
+// Stats summarizes the frames laid out by the editor.
+type Stats struct {
+	// Frames is the number of frames laid out.
+	Frames int
+	// SlowFrames is the number of frames exceeding the frame budget.
+	SlowFrames int
+	// Last is the most recent frame.
+	Last FrameStats
+	// Average is a moving average of the recent frames, weighting the
+	// recent ones more.
+	Average FrameStats
+	// Max is the maximum of each phase over the frames.
+	Max FrameStats
+	// Buffer is the state of the text buffer when the stats are taken.
+	Buffer BufferStats
+	// GlyphCache is the activity of the cache of the shaped paragraphs.
+	GlyphCache GlyphCacheStats
+}
+
+// BufferStats describes the state of the text buffer, which grows with the
+// editing history.
+type BufferStats struct {
+	// Pieces is the number of pieces the text is split into by the edits.
+	Pieces int
+	// UndoDepth and RedoDepth are the number of steps which can be undone
+	// and redone.
+	UndoDepth int
+	RedoDepth int
+	// OriginalBytes is the size of the text set to the editor, and
+	// AddedBytes the size of the text inserted since then, including the
+	// text kept for the history.
+	OriginalBytes int
+	AddedBytes    int
+	// Markers is the number of markers tracking positions of the text, e.g.,
+	// for the search matches and the decorations.
+	Markers int
+}
+
+// GlyphCacheStats reports the activity of the cache of the shaped
+// paragraphs, which are reused wherever their text appears again.
+type GlyphCacheStats struct {
+	// Hits is the number of paragraphs laid out from the cache, and Misses
+	// the number of paragraphs shaped.
+	Hits, Misses int
+	// Evictions is the number of paragraphs dropped from the full cache.
+	Evictions int
+	// Entries is the number of paragraphs in the cache, up to Capacity.
+	Entries, Capacity int
+}
+
+// SlowFrameEvent is generated when the total time of a frame exceeds the
+// budget set by WithFrameBudget. Applications can use it to degrade
+// gracefully, e.g., by disabling expensive decorations. The event is
+// returned by Update in the next frame.
+type SlowFrameEvent struct {
+	// Frame is the slow frame.
+	Frame FrameStats
+	// Budget is the frame budget exceeded.
+	Budget time.Duration
+}
+
+func (SlowFrameEvent) isEditorEvent() {}
+
+// averageWeight is the weight of a new frame in the moving average.
+const averageWeight = 8
+
+// frameTimer measures the phases of the frames.
+type frameTimer struct {
+	stats   Stats
+	current FrameStats
+	// start is when the current frame started.
+	start  time.Time
+	budget time.Duration
+}
+
+// begin starts measuring a frame.
+func (f *frameTimer) begin() {
+	f.start = time.Now()
+}
+
+// finish records the current frame. It reports whether the frame exceeds the
+// budget.
+func (f *frameTimer) finish() (FrameStats, bool) {
+	frame := f.current
+	frame.Total = time.Since(f.start) + frame.Tokenize
+	f.current = FrameStats{}
+
+	s := &f.stats
+	s.Frames++
+	s.Last = frame
+	if s.Frames == 1 {
+		s.Average = frame
+	} else {
+		s.Average = frame.combine(s.Average, func(cur, avg time.Duration) time.Duration {
+			return avg + (cur-avg)/averageWeight
+		})
+	}
+	s.Max = frame.combine(s.Max, func(cur, prev time.Duration) time.Duration {
+		return max(cur, prev)
+	})
+
+	slow := f.budget > 0 && frame.Total > f.budget
+	if slow {
+		s.SlowFrames++
+	}
+	return frame, slow
+}
+
+// combine applies fn to each phase of fs and other.
+func (fs FrameStats) combine(other FrameStats, fn func(a, b time.Duration) time.Duration) FrameStats {
+	return FrameStats{
+		Layout:   fn(fs.Layout, other.Layout),
+		Tokenize: fn(fs.Tokenize, other.Tokenize),
+		Gutter:   fn(fs.Gutter, other.Gutter),
+		Paint:    fn(fs.Paint, other.Paint),
+		Total:    fn(fs.Total, other.Total),
+	}
+}
+
+// Stats returns the time spent by the editor in the recent frames, and the
+// state of its buffer and caches.
+func (e *Editor) Stats() Stats {
+	e.initBuffer()
+	stats := e.frames.stats
+	buf := e.buffer.Stats()
+	stats.Buffer = BufferStats{
+		Pieces:        buf.Pieces,
+		UndoDepth:     buf.UndoDepth,
+		RedoDepth:     buf.RedoDepth,
+		OriginalBytes: buf.OriginalBytes,
+		AddedBytes:    buf.AddedBytes,
+		Markers:       buf.Markers,
+	}
+	stats.GlyphCache = GlyphCacheStats(e.text.GlyphCacheStats())
+	return stats
+}
+
+// ResetStats clears the frame statistics and the counters of the glyph
+// cache.
+func (e *Editor) ResetStats() {
+	e.initBuffer()
+	e.frames.stats = Stats{}
+	e.text.ResetGlyphCacheStats()
+}
@@ -0,0 +1,77 @@
+package gvcode
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestFrameStats(t *testing.T) {
+	e := newTestEditor(t, "package main\n\nfunc main() {}\n")
+	e.ResetStats()
+	e.WithOptions(WithFrameBudget(time.Nanosecond))
+
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+	e.Layout(gtx, shaper)
+
+	stats := e.Stats()
+	if stats.Frames != 1 || stats.SlowFrames != 1 {
+		t.Fatalf("got %d frames and %d slow frames, want 1 and 1", stats.Frames, stats.SlowFrames)
+	}
+	last := stats.Last
+	if last.Total <= 0 || last.Layout+last.Paint > last.Total {
+		t.Errorf("inconsistent frame stats: %+v", last)
+	}
+	if stats.Average != last || stats.Max != last {
+		t.Errorf("got average %+v and max %+v, want the only frame %+v", stats.Average, stats.Max, last)
+	}
+
+	evt, ok := e.Update(gtx)
+	slow, isSlow := evt.(SlowFrameEvent)
+	if !ok || !isSlow {
+		t.Fatalf("got event %#v, want a SlowFrameEvent", evt)
+	}
+	if slow.Frame != last || slow.Budget != time.Nanosecond {
+		t.Errorf("got %+v, want the last frame with the budget", slow)
+	}
+
+	// no events without a budget.
+	e.WithOptions(WithFrameBudget(0))
+	e.Layout(gtx, shaper)
+	if evt, ok := e.Update(gtx); ok {
+		t.Errorf("got event %#v without a frame budget", evt)
+	}
+	if stats := e.Stats(); stats.Frames != 2 || stats.SlowFrames != 1 {
+		t.Errorf("got %d frames and %d slow frames, want 2 and 1", stats.Frames, stats.SlowFrames)
+	}
+}
+
+func TestBufferAndCacheStats(t *testing.T) {
+	e := newTestEditor(t, "package main\n\nfunc main() {\n}\n\nfunc f() {\n}\n")
+	stats := e.Stats()
+	// the lines of the closing braces are shaped once.
+	if c := stats.GlyphCache; c.Misses == 0 || c.Hits == 0 || c.Entries != c.Misses {
+		t.Errorf("got glyph cache stats %+v", c)
+	}
+
+	e.SetCaret(0, 0)
+	e.Insert("// doc\n")
+	e.SetCaret(e.Len(), e.Len())
+	e.Insert("x")
+	e.undo()
+	want := BufferStats{Pieces: 2, UndoDepth: 1, RedoDepth: 1, OriginalBytes: 44, AddedBytes: 8}
+	if got := e.Stats().Buffer; got != want {
+		t.Errorf("got buffer stats %+v, want %+v", got, want)
+	}
+
+	e.ResetStats()
+	if c := e.Stats().GlyphCache; c.Hits != 0 || c.Misses != 0 || c.Entries == 0 {
+		t.Errorf("got glyph cache stats %+v after a reset", c)
+	}
+}
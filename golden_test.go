@@ -0,0 +1,291 @@
+//go:build golden
+
+package gvcode
+
+// The golden image tests render editor states with the GPU headless and
+// compare them with the images in testdata/golden. They need an EGL driver,
+// e.g., the software rasterizer of Mesa:
+//
+//	EGL_PLATFORM=surfaceless go test -tags "golden novulkan" -run Golden .
+//
+// Run them with -update to write the golden images again, after checking
+// that a change of the rendering is intended.
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gioui.org/font"
+	"gioui.org/font/gofont"
+	"gioui.org/gpu/headless"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/gutter/providers"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+var updateGoldens = flag.Bool("update", false, "write the golden images")
+
+const (
+	// goldenChannelTolerance is the difference of a color channel under
+	// which pixels are considered equal, absorbing the antialiasing
+	// differences of the rasterizers.
+	goldenChannelTolerance = 48
+	// goldenPixelTolerance is the fraction of the pixels allowed to differ.
+	goldenPixelTolerance = 0.005
+)
+
+var goldenSize = image.Pt(480, 240)
+
+const goldenSource = `package main
+
+import "fmt"
+
+// greet prints a greeting.
+func greet(name string) {
+	if name == "" {
+		name = "world"
+	}
+	fmt.Println("hello,", name)
+}
+
+func main() {
+	greet("gvcode")
+}
+`
+
+// newGoldenEditor returns an editor with fixed fonts and colors, so that the
+// rendering does not depend on the system.
+func newGoldenEditor(t *testing.T, content string, options ...EditorOption) *Editor {
+	t.Helper()
+	scheme := syntax.ColorScheme{}
+	scheme.Foreground = gvcolor.MakeColor(color.NRGBA{R: 0x20, G: 0x20, B: 0x20, A: 0xFF})
+	scheme.Background = gvcolor.MakeColor(color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF})
+	scheme.SelectColor = gvcolor.MakeColor(color.NRGBA{R: 0x40, G: 0x80, B: 0xF0, A: 0x60})
+	scheme.LineColor = gvcolor.MakeColor(color.NRGBA{R: 0xF0, G: 0xF0, B: 0xF0, A: 0xFF})
+	scheme.LineNumberColor = gvcolor.MakeColor(color.NRGBA{R: 0x90, G: 0x90, B: 0x90, A: 0xFF})
+
+	e := &Editor{}
+	e.WithOptions(append([]EditorOption{
+		WithColorScheme(scheme),
+		WithFont(font.Font{Typeface: "Go Mono"}),
+		WithTextSize(14),
+		WithTabWidth(4),
+	}, options...)...)
+	e.SetText(content)
+	if err := e.SetLanguage("go"); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// goldenRenderer renders the frames of editors headless.
+type goldenRenderer struct {
+	window *headless.Window
+	shaper *text.Shaper
+}
+
+func newGoldenRenderer(t *testing.T) *goldenRenderer {
+	t.Helper()
+	w, err := headless.NewWindow(goldenSize.X, goldenSize.Y)
+	if err != nil {
+		t.Skipf("no headless GPU: %v", err)
+	}
+	t.Cleanup(w.Release)
+	return &goldenRenderer{
+		window: w,
+		shaper: text.NewShaper(text.NoSystemFonts(), text.WithCollection(gofont.Collection())),
+	}
+}
+
+// render lays out the editor twice, as some overlays like the sticky lines
+// follow the previous layout, and returns the image of the second frame.
+func (r *goldenRenderer) render(t *testing.T, e *Editor) *image.RGBA {
+	t.Helper()
+	ops := new(op.Ops)
+	for range 2 {
+		ops.Reset()
+		gtx := layout.Context{
+			Ops:         ops,
+			Metric:      unit.Metric{PxPerDp: 1, PxPerSp: 1},
+			Constraints: layout.Exact(goldenSize),
+		}
+		e.Layout(gtx, r.shaper)
+	}
+	if err := r.window.Frame(ops); err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rectangle{Max: goldenSize})
+	if err := r.window.Screenshot(img); err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+// compareGolden compares img with the golden image name, or writes it with
+// -update. The image is saved to the temporary directory if it differs.
+func compareGolden(t *testing.T, name string, img *image.RGBA) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".png")
+	if *updateGoldens {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writePNG(t, path, img)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("missing golden image, run the test with -update: %v", err)
+	}
+	defer f.Close()
+	want, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want.Bounds() != img.Bounds() {
+		t.Fatalf("golden image size is %v, want %v", want.Bounds(), img.Bounds())
+	}
+
+	diff := 0
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			if !similarColors(img.At(x, y), want.At(x, y)) {
+				diff++
+			}
+		}
+	}
+	if limit := int(goldenPixelTolerance * float64(img.Rect.Dx()*img.Rect.Dy())); diff > limit {
+		actual := filepath.Join(os.TempDir(), "gvcode-"+name+".png")
+		writePNG(t, actual, img)
+		t.Errorf("%d pixels differ from %s, more than %d; the rendering is saved to %s", diff, path, limit, actual)
+	}
+}
+
+func similarColors(a, b color.Color) bool {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	for _, d := range [...][2]uint32{{ar, br}, {ag, bg}, {ab, bb}, {aa, ba}} {
+		// the channels are 16 bits.
+		if max(d[0], d[1])-min(d[0], d[1]) > goldenChannelTolerance<<8 {
+			return false
+		}
+	}
+	return true
+}
+
+func writePNG(t *testing.T, path string, img image.Image) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGoldenImages(t *testing.T) {
+	r := newGoldenRenderer(t)
+
+	cases := []struct {
+		name    string
+		content string
+		options []EditorOption
+		setup   func(t *testing.T, e *Editor)
+	}{
+		{
+			name:    "selection",
+			content: goldenSource,
+			options: []EditorOption{WithDefaultGutters()},
+			setup: func(t *testing.T, e *Editor) {
+				// a selection over several lines of different widths.
+				start, _ := e.ConvertPos(5, 8)
+				end, _ := e.ConvertPos(9, 6)
+				e.SetCaret(start, end)
+			},
+		},
+		{
+			name:    "fold",
+			content: goldenSource,
+			options: []EditorOption{WithDefaultGutters(), WithCodeFolding()},
+			setup: func(t *testing.T, e *Editor) {
+				start, _ := e.ConvertPos(5, 0)
+				e.SetCaret(start, start)
+				if !e.ToggleFold() {
+					t.Fatal("expected to collapse the function")
+				}
+			},
+		},
+		{
+			name:    "diff",
+			content: goldenSource,
+			options: []EditorOption{WithDefaultGutters(), WithGutter(providers.NewVCSDiffProvider())},
+			setup: func(t *testing.T, e *Editor) {
+				e.diffProvider().UpdateDiff([]*providers.DiffHunk{
+					{Type: providers.DiffAdded, StartLine: 4, EndLine: 4, OldStartLine: 4},
+					{Type: providers.DiffModified, StartLine: 10, EndLine: 10, OldStartLine: 9, OldLines: []string{"\tfmt.Println(name)"}},
+					{Type: providers.DiffDeleted, StartLine: 13, EndLine: 13, OldStartLine: 12, OldLines: []string{"\tgreet(\"\")"}},
+				})
+			},
+		},
+		{
+			name:    "sticky_lines",
+			content: "func long() {\n" + strings.Repeat("\tstep()\n", 40) + "}\n",
+			options: []EditorOption{WithDefaultGutters(), WithStickyLines()},
+			setup: func(t *testing.T, e *Editor) {
+				e.ScrollToLine(20)
+			},
+		},
+		{
+			name:    "current_line",
+			content: goldenSource,
+			options: []EditorOption{WithDefaultGutters(), WithCurrentLineHighlight(true, false)},
+			setup: func(t *testing.T, e *Editor) {
+				start, _ := e.ConvertPos(6, 2)
+				end, _ := e.ConvertPos(7, 3)
+				e.SetCaret(end, start)
+			},
+		},
+		{
+			name:    "line_endings",
+			content: "package main\r\n\r\nfunc main() {\n\tprintln(\"mixed\")\r\n}\n",
+			options: []EditorOption{WithDefaultGutters(), WithLineEndMarkers(true)},
+			setup: func(t *testing.T, e *Editor) {
+				e.SetCaret(13, 13)
+			},
+		},
+		{
+			name: "rtl",
+			// the Go fonts have no Hebrew and Arabic glyphs, so the RTL
+			// runs are drawn with the missing glyph box, which still shows
+			// their order and the selection across the runs.
+			content: "// שלום עולם mixed with text\nx := \"مرحبا\" + y\n",
+			options: []EditorOption{WithDefaultGutters()},
+			setup: func(t *testing.T, e *Editor) {
+				e.SetCaret(5, 20)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newGoldenEditor(t, tc.content, tc.options...)
+			// the first layout positions the lines for the setup.
+			r.render(t, e)
+			tc.setup(t, e)
+			compareGolden(t, tc.name, r.render(t, e))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package gvcode
+
+// GoToRequest is generated by the GoToLine command, for the host app to show
+// a dialog asking for the line and column to go to, and to call
+// GoToLineColumn with them. The line and column are 0-based, like those of
+// CaretPos.
+type GoToRequest struct {
+	// Line and Col are the position of the caret.
+	Line, Col int
+	// Lines is the number of lines of the document.
+	Lines int
+}
+
+func (GoToRequest) isEditorEvent() {}
+
+// GoToLineColumn moves the caret to the rune col of the 0-based logical line,
+// and scrolls it to the center of the viewport in the next layout. The line
+// and the column are clamped to the document. The collapsed folds hiding the
+// line are expanded, and for a wrapped line, the screen line of the column
+// is revealed. The line is flashed if enabled by WithGoToFlash. It reports
+// whether the line is in the document.
+func (e *Editor) GoToLineColumn(line, col int) bool {
+	e.initBuffer()
+	lines := e.text.Paragraphs()
+	if lines == 0 {
+		return false
+	}
+	ok := line >= 0 && line < lines
+	line = max(0, min(line, lines-1))
+
+	e.expandFoldsHiding(line, line)
+	_, para := e.text.FindParagraph(e.text.ConvertPos(line, 0))
+	// the column is clamped before the line break.
+	runes := para.Runes
+	if runes > 0 && e.ReadRange(para.RuneOff+runes-1, para.RuneOff+runes) == "\n" {
+		runes--
+	}
+	off := e.text.ConvertPos(line, max(0, min(col, runes)))
+	e.SetCaret(off, off)
+	e.RevealRange(off, off, RevealCenter)
+	if e.gotoFlash {
+		e.flashRange(para.RuneOff, para.RuneOff+para.Runes)
+	}
+	return ok
+}
@@ -0,0 +1,70 @@
+package gvcode
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"github.com/oligo/gvcode/internal/folding"
+)
+
+func TestGoToLineColumn(t *testing.T) {
+	content := "package main\n\nfunc f() {\n\ta()\n\tb()\n}\n" + strings.Repeat("line\n", 200)
+	e := newTestEditor(t, content)
+	e.WithOptions(WithGoToFlash(true))
+	fm := folding.NewManager()
+	fm.AnalyzeLines(strings.Split(content, "\n"))
+	if !fm.CollapseFold(2) {
+		t.Fatal("expected the function to be folded")
+	}
+	e.text.SetFoldManager(fm)
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	relayout := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+		e.Layout(gtx, shaper)
+	}
+	relayout()
+
+	// going to a folded line expands the fold.
+	if !e.GoToLineColumn(4, 2) {
+		t.Fatal("expected line 4 to be in the document")
+	}
+	relayout()
+	if !fm.IsLineVisible(4) {
+		t.Fatal("expected the fold to be expanded")
+	}
+	if line, col := e.CaretPos(); line != 4 || col != 2 {
+		t.Fatalf("caret at %d:%d, want 4:2", line, col)
+	}
+	if !e.reveal.flashing || e.ReadRange(e.reveal.flashStart, e.reveal.flashEnd) != "\tb()\n" {
+		t.Fatal("expected the line to flash")
+	}
+
+	// the line is scrolled to the center of the viewport.
+	e.GoToLineColumn(150, 0)
+	relayout()
+	_, pos := e.ConvertPos(150, 0)
+	if d := int(pos.Y) - 300; d < -e.text.GetLineHeight().Ceil() || d > e.text.GetLineHeight().Ceil() {
+		t.Fatalf("line 150 at %v is not centered", pos.Y)
+	}
+
+	// positions out of the document are clamped.
+	if e.GoToLineColumn(1000, 0) {
+		t.Fatal("expected line 1000 to be out of the document")
+	}
+	if line, _ := e.CaretPos(); line != e.Lines()-1 {
+		t.Fatalf("caret at line %d, want the last line", line)
+	}
+	e.GoToLineColumn(2, 100)
+	if line, col := e.CaretPos(); line != 2 || col != len("func f() {") {
+		t.Fatalf("caret at %d:%d, want the end of line 2", line, col)
+	}
+
+	if evt := GoToLine.Run(layout.Context{}, e); evt != (GoToRequest{Line: 2, Col: 10, Lines: e.Lines()}) {
+		t.Fatalf("got %+v", evt)
+	}
+}
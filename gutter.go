@@ -7,13 +7,15 @@ import (
 	"strings"
 
 	"gioui.org/layout"
+	"gioui.org/op"
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
 	"gioui.org/text"
+	"gioui.org/unit"
 	gvcolor "github.com/oligo/gvcode/color"
 	"github.com/oligo/gvcode/gutter"
 	"github.com/oligo/gvcode/internal/buffer"
-	"github.com/oligo/gvcode/internal/painter"
+	"github.com/oligo/gvcode/painter"
 )
 
 // GutterManager returns the editor's gutter manager, if one is configured.
@@ -26,6 +28,15 @@ func (e *Editor) buildGutterContext(gtx layout.Context, shaper *text.Shaper) gut
 	viewport := e.text.Viewport()
 	textLayout := e.text.TextLayout()
 
+	foldManager := e.text.FoldManager()
+
+	// the providers read the lines of the snapshot, if published, which are
+	// not modified by the next layout.
+	layoutLines := textLayout.Lines
+	if snapshot := e.text.LayoutSnapshot(); snapshot != nil {
+		layoutLines = snapshot.Lines
+	}
+
 	// Convert internal Paragraphs to gutter.Paragraph slice
 	paragraphs := make([]gutter.Paragraph, 0, len(textLayout.Paragraphs))
 	for i, p := range textLayout.Paragraphs {
@@ -33,6 +44,10 @@ func (e *Editor) buildGutterContext(gtx layout.Context, shaper *text.Shaper) gut
 		if p.EndY < viewport.Min.Y {
 			continue
 		}
+		// Skip paragraphs hidden by collapsed folds
+		if foldManager != nil && !foldManager.IsLineVisible(i) {
+			continue
+		}
 		if p.StartY > viewport.Max.Y {
 			break
 		}
@@ -59,6 +74,9 @@ func (e *Editor) buildGutterContext(gtx layout.Context, shaper *text.Shaper) gut
 	e.feedLineContentsToStickyLinesProvider(paragraphs)
 	e.feedLineContentsToFoldButtonProvider(paragraphs)
 	e.feedLineContentsToColorIndicatorProvider(paragraphs)
+	e.feedReadOnlyRegionsToProviders()
+	// providers may be registered to the manager directly.
+	e.observeGutterEdits()
 
 	return gutter.GutterContext{
 		Shaper:      shaper,
@@ -68,7 +86,49 @@ func (e *Editor) buildGutterContext(gtx layout.Context, shaper *text.Shaper) gut
 		CurrentLine: currentLine,
 		LineHeight:  e.text.GetLineHeight(),
 		Colors:      e.gutterColors(),
-		LayoutLines: textLayout.Lines,
+		LayoutLines: layoutLines,
+		Metadata:    e.Metadata(),
+	}
+}
+
+// observeGutterEdits forwards the edits of the text to the gutter providers
+// implementing gutter.EditObserver, once one of them is registered.
+func (e *Editor) observeGutterEdits() {
+	if e.gutterManager == nil || e.gutterEditsObserved {
+		return
+	}
+	for _, p := range e.gutterManager.Providers() {
+		if _, ok := p.(gutter.EditObserver); ok {
+			e.gutterEditsObserved = true
+			e.OnEdit(e.notifyGutterEdit)
+			return
+		}
+	}
+}
+
+// notifyGutterEdit notifies the gutter providers implementing
+// gutter.EditObserver of an edit, including the ones made by undo and redo.
+func (e *Editor) notifyGutterEdit(delta EditDelta) {
+	if e.gutterManager == nil {
+		return
+	}
+	for _, p := range e.gutterManager.Providers() {
+		if observer, ok := p.(gutter.EditObserver); ok {
+			observer.TextReplaced(delta.Start, delta.OldEnd, delta.NewEnd)
+		}
+	}
+}
+
+// notifyGutterJump notifies the gutter providers implementing
+// gutter.JumpObserver of a jump of the caret to the rune offset.
+func (e *Editor) notifyGutterJump(offset int) {
+	if e.gutterManager == nil {
+		return
+	}
+	for _, p := range e.gutterManager.Providers() {
+		if observer, ok := p.(gutter.JumpObserver); ok {
+			observer.CaretJumped(offset)
+		}
 	}
 }
 
@@ -115,18 +175,7 @@ func (e *Editor) feedLineContentsToRunButtonProvider(paragraphs []gutter.Paragra
 
 // feedLineContentsToStickyLinesProvider reads all line contents and feeds them to the sticky lines provider.
 func (e *Editor) feedLineContentsToStickyLinesProvider(paragraphs []gutter.Paragraph) {
-	// Find the sticky lines provider
-	var stickyLinesProvider gutter.LineContentProvider
-
-	for _, p := range e.gutterManager.Providers() {
-		if p.ID() == "stickylines" {
-			if sl, ok := p.(gutter.LineContentProvider); ok {
-				stickyLinesProvider = sl
-				break
-			}
-		}
-	}
-
+	stickyLinesProvider := e.stickyLinesProvider()
 	if stickyLinesProvider == nil {
 		return
 	}
@@ -140,6 +189,9 @@ func (e *Editor) feedLineContentsToStickyLinesProvider(paragraphs []gutter.Parag
 		return
 	}
 
+	// The scopes are the folds of the editor if code folding is enabled.
+	stickyLinesProvider.SetFoldManager(e.text.FoldManager())
+
 	// Read all lines from the buffer using buffer.NewReader
 	srcReader := buffer.NewReader(e.buffer)
 
@@ -229,7 +281,10 @@ func (e *Editor) gutterColors() *gutter.GutterColors {
 
 	var text, highlight gvcolor.Color
 
-	if e.colorPalette.LineNumberColor.IsSet() {
+	if e.colorPalette.LineNumberColor.IsSet() && e.colorPalette.LineNumberActiveColor.IsSet() {
+		highlight = e.colorPalette.LineNumberActiveColor
+		text = e.colorPalette.LineNumberColor
+	} else if e.colorPalette.LineNumberColor.IsSet() {
 		highlight = e.colorPalette.LineNumberColor
 		// Use a slightly dimmed version for non-highlighted lines
 		text = e.colorPalette.LineNumberColor.MulAlpha(0x90)
@@ -239,17 +294,17 @@ func (e *Editor) gutterColors() *gutter.GutterColors {
 		highlight = gvcolor.MakeColor(color.NRGBA{A: 0xFF})
 	}
 
+	// The editor paints the current line itself when its highlight is
+	// enabled, so the line number provider does not paint it twice.
 	var lineHighlight gvcolor.Color
-	if e.colorPalette.LineColor.IsSet() {
-		lineHighlight = e.colorPalette.LineColor
-	} else if e.colorPalette.Foreground.IsSet() {
-		lineHighlight = e.colorPalette.Foreground.MulAlpha(0x30)
+	if !e.currentLine.enabled {
+		lineHighlight = e.lineHighlightColor()
 	}
 
 	return &gutter.GutterColors{
 		Text:          text,
 		TextHighlight: highlight,
-		Background:    gvcolor.Color{}, // Transparent by default
+		Background:    e.colorPalette.GutterBackground, // Transparent by default
 		LineHighlight: lineHighlight,
 		Custom:        nil,
 	}
@@ -291,24 +346,10 @@ func (e *Editor) paintProviderHighlights(gtx layout.Context, ctx gutter.GutterCo
 			continue
 		}
 
-		// Calculate bounds from baseline using ascent/descent
-		ascent := para.Ascent.Ceil()
-		descent := para.Descent.Ceil()
-		glyphHeight := ascent + descent
-
-		// Calculate leading (extra space beyond glyph bounds)
-		leading := 0
-		if lineHeight > glyphHeight {
-			leading = lineHeight - glyphHeight
-		}
-		leadingTop := leading / 2
-		leadingBottom := leading - leadingTop
-
 		// Build the full-width bounds
-		bounds := image.Rectangle{
-			Min: image.Point{X: 0, Y: para.StartY - ascent - leadingTop - scrollOffY},
-			Max: image.Point{X: gtx.Constraints.Max.X, Y: para.EndY + descent + leadingBottom - scrollOffY},
-		}
+		bounds := paragraphBounds(para.StartY, para.EndY, para.Ascent, para.Descent, lineHeight)
+		bounds.Min.X, bounds.Max.X = 0, gtx.Constraints.Max.X
+		bounds = bounds.Sub(image.Pt(0, scrollOffY))
 
 		// Check if this highlight can be added to the last group
 		// (same color and consecutive line)
@@ -334,13 +375,85 @@ func (e *Editor) paintProviderHighlights(gtx layout.Context, ctx gutter.GutterCo
 
 	for _, group := range groups {
 		polygonBuilder.Group(group.rects)
-		paths := polygonBuilder.Paths(gtx)
+		painter.FillPaths(gtx, polygonBuilder.Paths(gtx), group.color.Op(gtx.Ops))
+	}
+}
 
-		for _, path := range paths {
-			outline := clip.Outline{Path: path}.Op().Push(gtx.Ops)
-			paint.ColorOp{Color: group.color.NRGBA()}.Add(gtx.Ops)
-			paint.PaintOp{}.Add(gtx.Ops)
-			outline.Pop()
+// paintGutterTooltip draws the hover information of the gutter provider under
+// the pointer. The tooltip is placed below the pointer, and moved to stay
+// inside of the editor.
+func (e *Editor) paintGutterTooltip(gtx layout.Context, shaper *text.Shaper) {
+	if e.gutterManager == nil {
+		return
+	}
+	tooltip := e.gutterManager.Tooltip()
+	if tooltip == nil {
+		return
+	}
+	if _, ok := e.hoveredFold(); ok {
+		// The fold preview tells more than the tooltip.
+		return
+	}
+
+	fg := gvcolor.MakeColor(color.NRGBA{A: 0xFF})
+	if e.colorPalette != nil && e.colorPalette.Foreground.IsSet() {
+		fg = e.colorPalette.Foreground
+	}
+	bgColor := color.NRGBA{R: 0xF8, G: 0xF8, B: 0xF8, A: 0xFF}
+	if e.colorPalette != nil && e.colorPalette.Background.IsSet() {
+		bgColor = e.colorPalette.Background.NRGBA()
+		bgColor.A = 0xFF
+	}
+	borderColor := fg.NRGBA()
+	borderColor.A = 0x40
+
+	padding := gtx.Dp(unit.Dp(4))
+	w := tooltip.Info.Widget
+	if w == nil {
+		w = func(gtx layout.Context) layout.Dimensions {
+			if shaper == nil {
+				return layout.Dimensions{}
+			}
+			params := e.text.Params()
+			params.MinWidth = 0
+			params.MaxWidth = gtx.Constraints.Max.X
+			params.MaxLines = 1
+			shaper.LayoutString(params, tooltip.Info.Text)
+			width := paintTextGlyphs(gtx, shaper, image.Point{}, gtx.Constraints.Max.X, fg)
+			return layout.Dimensions{Size: image.Pt(width, e.text.GetLineHeight().Ceil())}
 		}
 	}
+
+	// Record the content to know its size.
+	cgtx := gtx
+	cgtx.Constraints.Min = image.Point{}
+	cgtx.Constraints.Max = gtx.Constraints.Max.Sub(image.Pt(2*padding, 2*padding))
+	macro := op.Record(gtx.Ops)
+	dims := w(cgtx)
+	call := macro.Stop()
+	if dims.Size == (image.Point{}) {
+		return
+	}
+	size := dims.Size.Add(image.Pt(2*padding, 2*padding))
+
+	// Place the tooltip below the pointer, or above it if there is not enough
+	// room, and keep it inside of the editor horizontally.
+	offset := tooltip.Position.Add(image.Pt(padding, e.text.GetLineHeight().Ceil()))
+	if offset.Y+size.Y > gtx.Constraints.Max.Y {
+		offset.Y = tooltip.Position.Y - size.Y - padding
+	}
+	offset.Y = max(offset.Y, 0)
+	offset.X = max(min(offset.X, gtx.Constraints.Max.X-size.X), 0)
+
+	defer op.Offset(offset).Push(gtx.Ops).Pop()
+	rect := image.Rectangle{Max: size}
+	radius := gtx.Dp(unit.Dp(4))
+	paint.FillShape(gtx.Ops, bgColor, clip.UniformRRect(rect, radius).Op(gtx.Ops))
+	paint.FillShape(gtx.Ops, borderColor, clip.Stroke{
+		Path:  clip.UniformRRect(rect, radius).Path(gtx.Ops),
+		Width: float32(gtx.Dp(unit.Dp(1))),
+	}.Op())
+
+	defer op.Offset(image.Pt(padding, padding)).Push(gtx.Ops).Pop()
+	call.Add(gtx.Ops)
 }
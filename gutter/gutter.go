@@ -46,6 +46,49 @@ type LineContentProvider interface {
 	SetLineContents(lines []string, startLine int)
 }
 
+// EditObserver is an optional interface that GutterProviders can implement
+// to be notified of the edits of the document, e.g., to keep the positions
+// they track in sync with the text.
+type EditObserver interface {
+	GutterProvider
+	// TextReplaced is called after the runes in [start, end) are replaced by
+	// the runes in [start, newEnd). The edits made by undo and redo are
+	// reported too.
+	TextReplaced(start, end, newEnd int)
+}
+
+// JumpObserver is an optional interface that GutterProviders can implement
+// to be notified of the jumps of the caret, e.g., to a line or to a symbol.
+type JumpObserver interface {
+	GutterProvider
+	// CaretJumped is called after the caret jumped to the rune offset.
+	CaretJumped(offset int)
+}
+
+// RuneRange is a range of runes of the document, from Start to End
+// exclusive.
+type RuneRange struct {
+	Start, End int
+}
+
+// ReadOnlyObserver is an optional interface that GutterProviders can
+// implement to be notified of the read-only regions of the document.
+type ReadOnlyObserver interface {
+	GutterProvider
+	// SetReadOnlyRegions sets the rune ranges of the read-only regions,
+	// sorted by their start.
+	SetReadOnlyRegions(regions []RuneRange)
+}
+
+// PaletteObserver is an optional interface that GutterProviders can
+// implement to be notified when the colors of the editor are replaced, e.g.,
+// to update the colors they derive from them.
+type PaletteObserver interface {
+	GutterProvider
+	// PaletteChanged is called with the gutter colors of the new palette.
+	PaletteChanged(colors *GutterColors)
+}
+
 // GutterContext provides the context needed for gutter providers to render
 // their content. It includes information about the visible area, line metadata,
 // and colors.
@@ -79,6 +122,9 @@ type GutterContext struct {
 	// LayoutLines contains the layout lines from the text layouter.
 	// This is used by color indicators to get accurate glyph positions.
 	LayoutLines []lt.Line
+
+	// Metadata describes the document, e.g., its language and file path.
+	Metadata Metadata
 }
 
 // Paragraph contains metadata about a paragraph (logical line) in the document.
@@ -6,12 +6,14 @@ import (
 
 	"gioui.org/gesture"
 	"gioui.org/io/pointer"
+	"gioui.org/io/semantic"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
 	"gioui.org/text"
 	"gioui.org/unit"
+	gestureExt "github.com/oligo/gvcode/internal/gesture"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -36,6 +38,12 @@ type Manager struct {
 	// clicker handles click events on the gutter area.
 	clicker gesture.Click
 
+	// hover detects the pointer resting over the gutter area.
+	hover gestureExt.Hover
+
+	// tooltip is the hover information of the provider under the pointer.
+	tooltip *Tooltip
+
 	// pending holds events that haven't been consumed yet.
 	pending []GutterEvent
 
@@ -49,6 +57,19 @@ type Manager struct {
 	viewport image.Rectangle
 }
 
+// Tooltip is the hover information returned by a provider, to be shown near
+// the pointer.
+type Tooltip struct {
+	// ProviderID is the ID of the provider being hovered.
+	ProviderID string
+	// Line is the 0-based line number being hovered.
+	Line int
+	// Position is the pointer position in the gutter coordinates.
+	Position image.Point
+	// Info is the information to display.
+	Info *HoverInfo
+}
+
 // NewManager creates a new gutter manager with default settings.
 func NewManager() *Manager {
 	return &Manager{
@@ -177,6 +198,8 @@ func (m *Manager) Update(gtx layout.Context) (GutterEvent, bool) {
 		}
 	}
 
+	m.updateHover(gtx)
+
 	// Return any newly generated events
 	if len(m.pending) > 0 {
 		evt := m.pending[0]
@@ -187,6 +210,55 @@ func (m *Manager) Update(gtx layout.Context) (GutterEvent, bool) {
 	return nil, false
 }
 
+// updateHover asks the interactive provider under the pointer for hover
+// information once the pointer rests, and dismisses the tooltip when the
+// pointer moves away or leaves the gutter.
+func (m *Manager) updateHover(gtx layout.Context) {
+	evt, ok := m.hover.Update(gtx)
+	if ok {
+		m.tooltip = nil
+		if evt.Kind == gestureExt.KindHovered {
+			m.handleHover(evt.Position)
+		}
+	}
+	if !m.hover.Hovering() {
+		m.tooltip = nil
+	}
+}
+
+// handleHover finds the hover information at pos.
+func (m *Manager) handleHover(pos image.Point) {
+	for _, p := range m.providers {
+		bounds, ok := m.providerBounds[p.ID()]
+		if !ok || !pos.In(bounds) {
+			continue
+		}
+
+		interactive, ok := p.(InteractiveGutter)
+		if !ok {
+			return
+		}
+		line := m.hitTestLine(pos.Y)
+		if line < 0 {
+			return
+		}
+		info := interactive.HandleHover(line)
+		if info == nil || (info.Text == "" && info.Widget == nil) {
+			return
+		}
+
+		m.tooltip = &Tooltip{ProviderID: p.ID(), Line: line, Position: pos, Info: info}
+		m.pending = append(m.pending, GutterHoverEvent{ProviderID: p.ID(), Line: line, Info: info})
+		return
+	}
+}
+
+// Tooltip returns the hover information to show, or nil if the pointer is
+// not resting over an interactive provider with something to tell.
+func (m *Manager) Tooltip() *Tooltip {
+	return m.tooltip
+}
+
 // handleClick processes a click event and generates appropriate gutter events.
 func (m *Manager) handleClick(gtx layout.Context, evt gesture.ClickEvent) {
 	pos := image.Point{X: int(evt.Position.X), Y: int(evt.Position.Y)}
@@ -325,6 +397,7 @@ func (m *Manager) Layout(gtx layout.Context, ctx GutterContext) layout.Dimension
 	// Set up the clip area and register for events
 	area := clip.Rect(image.Rectangle{Max: image.Point{X: totalWidth, Y: gtx.Constraints.Max.Y}})
 	stack := area.Push(gtx.Ops)
+	semantic.DescriptionOp("gutter").Add(gtx.Ops)
 
 	// Paint background if specified
 	if ctx.Colors != nil && ctx.Colors.Background.IsSet() {
@@ -332,9 +405,10 @@ func (m *Manager) Layout(gtx layout.Context, ctx GutterContext) layout.Dimension
 		paint.PaintOp{}.Add(gtx.Ops)
 	}
 
-	// Register click handler
+	// Register click and hover handlers
 	pointer.CursorDefault.Add(gtx.Ops)
 	m.clicker.Add(gtx.Ops)
+	m.hover.Add(gtx.Ops)
 
 	// Render each provider
 	xOffset := 0
@@ -0,0 +1,38 @@
+package gutter
+
+// Metadata describes the document shown in an editor. It is passed to the
+// gutter providers in GutterContext, so that they can behave per-file without
+// global state.
+type Metadata struct {
+	// Language is the ID of the language of the document, e.g., "go".
+	Language string
+
+	// FilePath is the path of the file the document is loaded from. It is
+	// empty for documents not backed by a file.
+	FilePath string
+
+	// ReadOnly reports whether the origin of the document is read-only, e.g.,
+	// a file without write permission or a revision of a VCS.
+	ReadOnly bool
+
+	// Values holds custom keys set by the application.
+	Values map[string]any
+}
+
+// Value returns the custom value of key.
+func (m Metadata) Value(key string) (any, bool) {
+	v, ok := m.Values[key]
+	return v, ok
+}
+
+// Clone returns a copy of m that does not share the custom values.
+func (m Metadata) Clone() Metadata {
+	if m.Values != nil {
+		values := make(map[string]any, len(m.Values))
+		for k, v := range m.Values {
+			values[k] = v
+		}
+		m.Values = values
+	}
+	return m
+}
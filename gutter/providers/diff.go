@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"fmt"
 	"image"
 
 	"gioui.org/f32"
@@ -46,6 +47,10 @@ type DiffHunk struct {
 	// For deleted hunks, StartLine == EndLine and represents where the deletion occurred.
 	EndLine int
 
+	// OldStartLine is the 0-based line index where this hunk starts in the
+	// original document.
+	OldStartLine int
+
 	// OldLines contains the original content (for modified and deleted hunks).
 	OldLines []string
 
@@ -61,6 +66,16 @@ func (h *DiffHunk) LineCount() int {
 	return h.EndLine - h.StartLine + 1
 }
 
+// Header returns the unified diff header of the hunk, e.g., "@@ -12,3 +12,4 @@".
+func (h *DiffHunk) Header() string {
+	oldStart, newStart := h.OldStartLine+1, h.StartLine+1
+	// An empty old range starts at the line before it in unified diffs.
+	if len(h.OldLines) == 0 {
+		oldStart--
+	}
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldStart, len(h.OldLines), newStart, h.LineCount())
+}
+
 // VCSDiffProvider renders vcs (like git) diff indicators in the gutter.
 // It shows colored bars for added/modified lines and triangles for deleted lines.
 type VCSDiffProvider struct {
@@ -86,6 +101,10 @@ type VCSDiffProvider struct {
 
 	// highlightAlpha is the alpha value for line highlighting (0-255).
 	highlightAlpha uint8
+
+	// stickyHeader controls whether the editor pins the header of the hunk
+	// scrolled past the top of the viewport.
+	stickyHeader bool
 }
 
 // NewGitDiffProvider creates a new git diff provider with default colors.
@@ -125,6 +144,17 @@ func (p *VCSDiffProvider) SetHighlightLines(enabled bool, alpha uint8) {
 	p.highlightAlpha = alpha
 }
 
+// SetStickyHeader controls whether the editor keeps the header of the hunk
+// the topmost visible line belongs to pinned at the top while scrolling.
+func (p *VCSDiffProvider) SetStickyHeader(enabled bool) {
+	p.stickyHeader = enabled
+}
+
+// StickyHeader reports whether the hunk header is pinned while scrolling.
+func (p *VCSDiffProvider) StickyHeader() bool {
+	return p.stickyHeader
+}
+
 // UpdateDiff updates the diff state with new hunks.
 // This clears any existing diff data.
 func (p *VCSDiffProvider) UpdateDiff(hunks []*DiffHunk) {
@@ -0,0 +1,31 @@
+package providers
+
+import "testing"
+
+func TestDiffHunkHeader(t *testing.T) {
+	cases := []struct {
+		hunk     DiffHunk
+		expected string
+	}{
+		{
+			hunk:     DiffHunk{Type: DiffModified, StartLine: 9, EndLine: 11, OldStartLine: 9, OldLines: []string{"a", "b"}},
+			expected: "@@ -10,2 +10,3 @@",
+		},
+		{
+			// lines added after line 4 of the original document.
+			hunk:     DiffHunk{Type: DiffAdded, StartLine: 4, EndLine: 4, OldStartLine: 4},
+			expected: "@@ -4,0 +5,1 @@",
+		},
+		{
+			// lines deleted after line 2 of the current document.
+			hunk:     DiffHunk{Type: DiffDeleted, StartLine: 1, EndLine: 1, OldStartLine: 2, OldLines: []string{"x"}},
+			expected: "@@ -3,1 +2,0 @@",
+		},
+	}
+
+	for _, c := range cases {
+		if header := c.hunk.Header(); header != c.expected {
+			t.Errorf("Header() = %q, want %q", header, c.expected)
+		}
+	}
+}
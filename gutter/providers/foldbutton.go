@@ -1,12 +1,14 @@
 package providers
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 
 	"gioui.org/gesture"
 	"gioui.org/io/key"
 	"gioui.org/io/pointer"
+	"gioui.org/io/semantic"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/op/clip"
@@ -16,6 +18,7 @@ import (
 	gvcolor "github.com/oligo/gvcode/color"
 	"github.com/oligo/gvcode/gutter"
 	"github.com/oligo/gvcode/internal/folding"
+	gestureExt "github.com/oligo/gvcode/internal/gesture"
 )
 
 const (
@@ -46,6 +49,13 @@ type FoldButtonProvider struct {
 	// clicker handles click events on buttons.
 	clicker gesture.Click
 
+	// hoverer detects the pointer hovering over the buttons.
+	hoverer gestureExt.Hover
+
+	// hoveredLine is the start line of the collapsed fold whose button is
+	// hovered, or -1 if there is none.
+	hoveredLine int
+
 	// pending holds fold events that haven't been consumed yet.
 	pending []FoldButtonEvent
 
@@ -79,6 +89,7 @@ func NewFoldButtonProvider(foldManager *folding.Manager) *FoldButtonProvider {
 		foldManager:  foldManager,
 		buttonStates: make(map[int]FoldButtonType),
 		pending:      make([]FoldButtonEvent, 0),
+		hoveredLine:  -1,
 		enabled:      true,
 	}
 }
@@ -152,6 +163,12 @@ func (p *FoldButtonProvider) Layout(gtx layout.Context, ctx gutter.GutterContext
 	buttonSizePx := gtx.Dp(unit.Dp(foldButtonSize))
 	padding := (ctx.LineHeight.Ceil() - buttonSizePx) / 2
 
+	// Register hover detection over the whole button column.
+	hoverStack := clip.Rect(image.Rect(0, 0, buttonSizePx+4, ctx.Viewport.Dy())).Push(gtx.Ops)
+	p.hoverer.Add(gtx.Ops)
+	hoverStack.Pop()
+	p.updateHover(gtx, ctx)
+
 	// Render buttons for each visible paragraph
 	for _, para := range ctx.Paragraphs {
 		// Skip paragraphs outside the viewport
@@ -183,7 +200,14 @@ func (p *FoldButtonProvider) Layout(gtx layout.Context, ctx gutter.GutterContext
 
 		// Register click handler
 		pointer.CursorPointer.Add(gtx.Ops)
-		clip.Rect(image.Rect(xPos, buttonY, xPos+buttonSizePx, buttonY+buttonSizePx)).Push(gtx.Ops).Pop()
+		btnArea := clip.Rect(image.Rect(xPos, buttonY, xPos+buttonSizePx, buttonY+buttonSizePx)).Push(gtx.Ops)
+		semantic.Button.Add(gtx.Ops)
+		if btnType == FoldButtonCollapsed {
+			semantic.LabelOp(fmt.Sprintf("Expand line %d", para.Index+1)).Add(gtx.Ops)
+		} else {
+			semantic.LabelOp(fmt.Sprintf("Collapse line %d", para.Index+1)).Add(gtx.Ops)
+		}
+		btnArea.Pop()
 		p.clicker.Add(gtx.Ops)
 
 		// Draw the button background/border (subtle rectangle)
@@ -235,6 +259,41 @@ func (p *FoldButtonProvider) Layout(gtx layout.Context, ctx gutter.GutterContext
 	return layout.Dimensions{Size: image.Pt(buttonWidth, 0)}
 }
 
+// updateHover tracks the collapsed fold whose button is hovered.
+func (p *FoldButtonProvider) updateHover(gtx layout.Context, ctx gutter.GutterContext) {
+	evt, ok := p.hoverer.Update(gtx)
+	if ok && evt.Kind == gestureExt.KindHovered {
+		p.hoveredLine = -1
+		line := p.hitTestLine(evt.Position.Y + ctx.Viewport.Min.Y)
+		if fold := p.foldManager.GetFoldAtLine(line); fold != nil && fold.Collapsed {
+			p.hoveredLine = line
+		}
+	}
+	if !p.hoverer.Hovering() {
+		p.hoveredLine = -1
+	}
+
+	// The fold may have been expanded since it was hovered.
+	if p.hoveredLine >= 0 {
+		if fold := p.foldManager.GetFoldAtLine(p.hoveredLine); fold == nil || !fold.Collapsed {
+			p.hoveredLine = -1
+		}
+	}
+}
+
+// HoveredFold returns the collapsed fold whose button is hovered, if any.
+// Editors use it to show a preview of the hidden lines.
+func (p *FoldButtonProvider) HoveredFold() (folding.FoldRange, bool) {
+	if !p.enabled || p.hoveredLine < 0 {
+		return folding.FoldRange{}, false
+	}
+	fold := p.foldManager.GetFoldAtLine(p.hoveredLine)
+	if fold == nil || !fold.Collapsed {
+		return folding.FoldRange{}, false
+	}
+	return *fold, true
+}
+
 // drawPlus draws a plus sign at the given position.
 func drawPlus(ops *op.Ops, centerX, centerY, size float32) {
 	// Horizontal line
@@ -0,0 +1,289 @@
+package providers
+
+import (
+	"image"
+	"math"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/gutter"
+)
+
+const (
+	// HeatmapProviderID is the unique identifier for the heatmap provider.
+	HeatmapProviderID = "heatmap"
+
+	// defaultHeatHalfLife is the time for the heat of a visit to halve.
+	defaultHeatHalfLife = 2 * time.Minute
+	// maxHeatEntries is the number of visits kept by the heatmap.
+	maxHeatEntries = 256
+	// heatLifetime is the number of half lives after which a visit is
+	// dropped, as its heat is negligible.
+	heatLifetime = 8
+)
+
+// HeatSpot is the heat of a position of the document.
+type HeatSpot struct {
+	// Offset is the rune offset of the position.
+	Offset int
+	// Heat is in [0, 1], 1 for a position visited just now.
+	Heat float32
+	// Edit is true if the text was edited at the position, otherwise the
+	// caret was placed there.
+	Edit bool
+}
+
+// heatEntry is a visit of a line by the caret or an edit.
+type heatEntry struct {
+	offset int
+	at     time.Time
+	edit   bool
+}
+
+// HeatmapProvider shows where the user has recently placed the caret or
+// edited the text, as bars in the gutter fading out over time. It records the
+// caret line from the gutter context, the edits, including undo and redo, it
+// is notified of as a gutter.EditObserver, and the jumps of the caret as a
+// gutter.JumpObserver, so it only needs to be added to the editor. The heat is
+// timed by the frames, so the visits recorded between two frames are stamped
+// with the time of the later one.
+//
+// The recorded positions are also available from Spots, to annotate a
+// scrollbar laid out by the application.
+type HeatmapProvider struct {
+	// entries are the visits, the oldest first.
+	entries  []heatEntry
+	halfLife time.Duration
+	// lastLine is the caret line recorded last.
+	lastLine int
+
+	caretColor gvcolor.Color
+	editColor  gvcolor.Color
+	width      unit.Dp
+
+	// now is the time of the last frame.
+	now time.Time
+}
+
+// NewHeatmapProvider creates a heatmap provider with default colors.
+func NewHeatmapProvider() *HeatmapProvider {
+	caretColor, _ := gvcolor.Hex2Color("#4d8fe6") // Blue
+	editColor, _ := gvcolor.Hex2Color("#e5894b")  // Orange
+
+	return &HeatmapProvider{
+		halfLife:   defaultHeatHalfLife,
+		lastLine:   -1,
+		caretColor: caretColor,
+		editColor:  editColor,
+		width:      unit.Dp(3),
+	}
+}
+
+// SetColors sets the colors of the caret and edit heat. They are painted
+// with an alpha scaled by the heat.
+func (p *HeatmapProvider) SetColors(caret, edit gvcolor.Color) {
+	p.caretColor = caret
+	p.editColor = edit
+}
+
+// SetHalfLife sets the time for the heat of a visit to halve.
+func (p *HeatmapProvider) SetHalfLife(halfLife time.Duration) {
+	if halfLife > 0 {
+		p.halfLife = halfLife
+	}
+}
+
+// SetWidth sets the width of the heat bars.
+func (p *HeatmapProvider) SetWidth(width unit.Dp) {
+	p.width = width
+}
+
+// Clear forgets the recorded visits.
+func (p *HeatmapProvider) Clear() {
+	p.entries = p.entries[:0]
+	p.lastLine = -1
+}
+
+// RecordCaret records a visit of the caret at offset.
+func (p *HeatmapProvider) RecordCaret(offset int) {
+	p.record(offset, false)
+}
+
+// RecordEdit records an edit at offset.
+func (p *HeatmapProvider) RecordEdit(offset int) {
+	p.record(offset, true)
+}
+
+func (p *HeatmapProvider) record(offset int, edit bool) {
+	if len(p.entries) >= maxHeatEntries {
+		p.entries = append(p.entries[:0], p.entries[1:]...)
+	}
+	// stamped by the next frame.
+	p.entries = append(p.entries, heatEntry{offset: offset, edit: edit})
+}
+
+// stamp sets the time of the frame, and stamps the visits recorded since the
+// last one.
+func (p *HeatmapProvider) stamp(now time.Time) {
+	p.now = now
+	for i := len(p.entries) - 1; i >= 0 && p.entries[i].at.IsZero(); i-- {
+		p.entries[i].at = now
+	}
+}
+
+// CaretJumped implements gutter.JumpObserver. It records the target of the
+// jump.
+func (p *HeatmapProvider) CaretJumped(offset int) {
+	p.RecordCaret(offset)
+}
+
+// TextReplaced implements gutter.EditObserver. It records the edit, and
+// moves the recorded positions after it.
+func (p *HeatmapProvider) TextReplaced(start, end, newEnd int) {
+	for i := range p.entries {
+		off := p.entries[i].offset
+		switch {
+		case newEnd < off && off <= end:
+			off = newEnd
+		case end < off:
+			off += newEnd - end
+		}
+		p.entries[i].offset = off
+	}
+	p.record(start, true)
+}
+
+// Spots returns the heat of the recorded positions at the time of the last
+// frame, merged by offset.
+func (p *HeatmapProvider) Spots() []HeatSpot {
+	p.prune()
+	now := p.now
+	index := make(map[int]int)
+	var spots []HeatSpot
+	for _, entry := range p.entries {
+		heat := p.heat(entry, now)
+		i, ok := index[entry.offset]
+		if !ok {
+			index[entry.offset] = len(spots)
+			spots = append(spots, HeatSpot{Offset: entry.offset, Heat: heat, Edit: entry.edit})
+			continue
+		}
+		spot := &spots[i]
+		spot.Edit = spot.Edit || entry.edit
+		spot.Heat = min(spot.Heat+heat, 1)
+	}
+	return spots
+}
+
+// heat returns the heat of the entry at now, halved every half life. The
+// entries not stamped yet are the hottest.
+func (p *HeatmapProvider) heat(entry heatEntry, now time.Time) float32 {
+	if entry.at.IsZero() {
+		return 1
+	}
+	age := now.Sub(entry.at)
+	return float32(math.Exp2(-age.Seconds() / p.halfLife.Seconds()))
+}
+
+// prune drops the visits whose heat is negligible.
+func (p *HeatmapProvider) prune() {
+	cutoff := p.now.Add(-heatLifetime * p.halfLife)
+	i := 0
+	for i < len(p.entries) && !p.entries[i].at.IsZero() && p.entries[i].at.Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		p.entries = append(p.entries[:0], p.entries[i:]...)
+	}
+}
+
+// ID returns the unique identifier for this provider.
+func (p *HeatmapProvider) ID() string {
+	return HeatmapProviderID
+}
+
+// Priority returns the rendering priority. The heatmap is rendered leftmost,
+// after the diff indicators.
+func (p *HeatmapProvider) Priority() int {
+	return 210
+}
+
+// Width returns the width needed for the heat bars.
+func (p *HeatmapProvider) Width(gtx layout.Context, shaper *text.Shaper, params text.Parameters, lineCount int) unit.Dp {
+	return p.width
+}
+
+// Layout records the caret line, and renders the heat of the visible lines.
+func (p *HeatmapProvider) Layout(gtx layout.Context, ctx gutter.GutterContext) layout.Dimensions {
+	width := gtx.Dp(p.width)
+	dims := layout.Dimensions{Size: image.Point{X: width, Y: gtx.Constraints.Max.Y}}
+
+	if ctx.CurrentLine != p.lastLine {
+		for _, para := range ctx.Paragraphs {
+			if para.Index == ctx.CurrentLine {
+				p.RecordCaret(para.RuneOff)
+				p.lastLine = ctx.CurrentLine
+				break
+			}
+		}
+	}
+	p.stamp(gtx.Now)
+
+	spots := p.Spots()
+	if len(spots) == 0 {
+		return dims
+	}
+
+	lineHeight := ctx.LineHeight.Ceil()
+	scrollOffY := ctx.Viewport.Min.Y
+	for _, para := range ctx.Paragraphs {
+		var caretHeat, editHeat float32
+		for _, spot := range spots {
+			if spot.Offset < para.RuneOff || spot.Offset > para.RuneOff+para.Runes {
+				continue
+			}
+			if spot.Edit {
+				editHeat = max(editHeat, spot.Heat)
+			} else {
+				caretHeat = max(caretHeat, spot.Heat)
+			}
+		}
+
+		c, heat := p.caretColor, caretHeat
+		if editHeat >= caretHeat {
+			c, heat = p.editColor, editHeat
+		}
+		if heat <= 0 || !c.IsSet() {
+			continue
+		}
+		p.drawHeatBar(gtx, para, width, lineHeight, scrollOffY, c.MulAlpha(uint8(heat*0xFF)))
+	}
+
+	// repaint as the heat fades out.
+	gtx.Execute(op.InvalidateCmd{At: gtx.Now.Add(p.halfLife / 8)})
+	return dims
+}
+
+// drawHeatBar draws a colored vertical bar covering the line.
+func (p *HeatmapProvider) drawHeatBar(gtx layout.Context, para gutter.Paragraph, width, lineHeight, scrollOffY int, c gvcolor.Color) {
+	ascent := para.Ascent.Ceil()
+	descent := para.Descent.Ceil()
+	leading := max(lineHeight-ascent-descent, 0)
+	leadingTop := leading / 2
+
+	rect := image.Rectangle{
+		Min: image.Point{X: 0, Y: para.StartY - ascent - leadingTop - scrollOffY},
+		Max: image.Point{X: width, Y: para.EndY + descent + leading - leadingTop - scrollOffY},
+	}
+
+	stack := clip.Rect(rect).Push(gtx.Ops)
+	paint.ColorOp{Color: c.NRGBA()}.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+	stack.Pop()
+}
@@ -0,0 +1,59 @@
+package providers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeatmapSpots(t *testing.T) {
+	now := time.Unix(0, 0)
+	p := NewHeatmapProvider()
+	p.SetHalfLife(time.Minute)
+
+	p.RecordCaret(10)
+	if spots := p.Spots(); len(spots) != 1 || spots[0].Heat != 1 {
+		t.Errorf("got spots %+v, want a visit with heat 1 before the frame", spots)
+	}
+	p.stamp(now)
+	now = now.Add(time.Minute)
+	p.RecordEdit(20)
+	p.stamp(now)
+
+	spots := p.Spots()
+	if len(spots) != 2 {
+		t.Fatalf("got %d spots, want 2", len(spots))
+	}
+	if spots[0].Offset != 10 || spots[0].Edit || spots[0].Heat != 0.5 {
+		t.Errorf("caret spot: got %+v, want heat 0.5 at 10", spots[0])
+	}
+	if spots[1].Offset != 20 || !spots[1].Edit || spots[1].Heat != 1 {
+		t.Errorf("edit spot: got %+v, want heat 1 at 20", spots[1])
+	}
+
+	// the visits fade out after their lifetime.
+	now = now.Add(heatLifetime * time.Minute)
+	p.stamp(now)
+	if spots := p.Spots(); len(spots) != 1 || spots[0].Offset != 20 {
+		t.Errorf("got spots %+v, want the edit only", spots)
+	}
+}
+
+func TestHeatmapTextReplaced(t *testing.T) {
+	p := NewHeatmapProvider()
+	p.RecordCaret(5)
+	p.RecordCaret(30)
+
+	// replace [10, 20) with 3 runes.
+	p.TextReplaced(10, 20, 13)
+
+	spots := p.Spots()
+	if len(spots) != 3 {
+		t.Fatalf("got %d spots, want 3", len(spots))
+	}
+	if spots[0].Offset != 5 || spots[1].Offset != 23 {
+		t.Errorf("got offsets %d and %d, want 5 and 23", spots[0].Offset, spots[1].Offset)
+	}
+	if spots[2].Offset != 10 || !spots[2].Edit {
+		t.Errorf("got %+v, want an edit at 10", spots[2])
+	}
+}
@@ -0,0 +1,128 @@
+package providers
+
+import (
+	"image"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/gutter"
+)
+
+const (
+	// ReadOnlyProviderID is the unique identifier for the read-only provider.
+	ReadOnlyProviderID = "readonly"
+)
+
+// ReadOnlyProvider shows a lock icon in the gutter next to the lines of the
+// read-only regions. It is notified of the regions by the editor as a
+// gutter.ReadOnlyObserver, and the editor registers it when regions are
+// configured, so it rarely needs to be added by hand.
+type ReadOnlyProvider struct {
+	regions []gutter.RuneRange
+	color   gvcolor.Color
+	width   unit.Dp
+}
+
+// NewReadOnlyProvider creates a read-only provider drawing the locks with
+// the text color of the gutter.
+func NewReadOnlyProvider() *ReadOnlyProvider {
+	return &ReadOnlyProvider{width: unit.Dp(12)}
+}
+
+// SetColor sets the color of the locks. The text color of the gutter is used
+// if it is not set.
+func (p *ReadOnlyProvider) SetColor(c gvcolor.Color) {
+	p.color = c
+}
+
+// SetReadOnlyRegions implements gutter.ReadOnlyObserver.
+func (p *ReadOnlyProvider) SetReadOnlyRegions(regions []gutter.RuneRange) {
+	p.regions = append(p.regions[:0], regions...)
+}
+
+// ID returns the unique identifier for this provider.
+func (p *ReadOnlyProvider) ID() string {
+	return ReadOnlyProviderID
+}
+
+// Priority returns the rendering priority. The locks are rendered left of
+// the line numbers and the fold buttons.
+func (p *ReadOnlyProvider) Priority() int {
+	return 130
+}
+
+// Width returns the width needed for the locks.
+func (p *ReadOnlyProvider) Width(gtx layout.Context, shaper *text.Shaper, params text.Parameters, lineCount int) unit.Dp {
+	return p.width
+}
+
+// Layout draws a lock next to the visible lines overlapping a read-only
+// region.
+func (p *ReadOnlyProvider) Layout(gtx layout.Context, ctx gutter.GutterContext) layout.Dimensions {
+	width := gtx.Dp(p.width)
+	dims := layout.Dimensions{Size: image.Point{X: width, Y: gtx.Constraints.Max.Y}}
+	if len(p.regions) == 0 {
+		return dims
+	}
+
+	c := p.color
+	if !c.IsSet() && ctx.Colors != nil {
+		c = ctx.Colors.Text.MulAlpha(0xA0)
+	}
+	if !c.IsSet() {
+		return dims
+	}
+
+	for _, para := range ctx.Paragraphs {
+		if p.locked(para) {
+			p.drawLock(gtx, para, width, ctx.Viewport.Min.Y, c)
+		}
+	}
+	return dims
+}
+
+// locked reports whether the line overlaps a read-only region.
+func (p *ReadOnlyProvider) locked(para gutter.Paragraph) bool {
+	for _, r := range p.regions {
+		if r.Start >= para.RuneOff+para.Runes {
+			// the regions are sorted by their start.
+			break
+		}
+		if r.End > para.RuneOff && r.Start < r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// drawLock draws a padlock centered on the first screen line of the line.
+func (p *ReadOnlyProvider) drawLock(gtx layout.Context, para gutter.Paragraph, width, scrollOffY int, c gvcolor.Color) {
+	ascent := float32(para.Ascent.Ceil())
+	size := min(float32(width)*0.7, ascent)
+	if size <= 0 {
+		return
+	}
+	x := (float32(width) - size) / 2
+	// the body is the lower half of the lock, sitting on the baseline.
+	bottom := float32(para.StartY - scrollOffY)
+	body := image.Rect(int(x), int(bottom-size*0.55), int(x+size), int(bottom))
+	paint.FillShape(gtx.Ops, c.NRGBA(), clip.UniformRRect(body, int(size/8)).Op(gtx.Ops))
+
+	// the shackle is an arch above the body.
+	stroke := max(size/6, 1)
+	left, right := x+size*0.25, x+size*0.75
+	top := bottom - size
+	var path clip.Path
+	path.Begin(gtx.Ops)
+	path.MoveTo(f32.Pt(left, float32(body.Min.Y)))
+	path.LineTo(f32.Pt(left, top+size*0.25))
+	path.QuadTo(f32.Pt(left, top), f32.Pt((left+right)/2, top))
+	path.QuadTo(f32.Pt(right, top), f32.Pt(right, top+size*0.25))
+	path.LineTo(f32.Pt(right, float32(body.Min.Y)))
+	paint.FillShape(gtx.Ops, c.NRGBA(), clip.Stroke{Path: path.End(), Width: stroke}.Op())
+}
@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 	"regexp"
@@ -9,6 +10,7 @@ import (
 	"gioui.org/gesture"
 	"gioui.org/io/key"
 	"gioui.org/io/pointer"
+	"gioui.org/io/semantic"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/op/clip"
@@ -61,6 +63,31 @@ type RunButtonProvider struct {
 
 	// pending holds run button events that haven't been consumed yet.
 	pending []RunButtonEvent
+
+	// patterns detects the lines to show run buttons.
+	patterns RunPatterns
+}
+
+// RunPatterns are the language specific patterns that detect runnable
+// lines. They are matched against lines with whitespace and trailing line
+// comments trimmed.
+type RunPatterns struct {
+	// Main matches the entry points of programs.
+	Main []*regexp.Regexp
+	// Test matches tests and benchmarks.
+	Test []*regexp.Regexp
+}
+
+// DefaultRunPatterns returns the patterns of Go main, test and benchmark
+// functions.
+func DefaultRunPatterns() RunPatterns {
+	return RunPatterns{
+		Main: []*regexp.Regexp{regexp.MustCompile(`^func\s+main\s*\(`)},
+		Test: []*regexp.Regexp{
+			regexp.MustCompile(`^func\s+Test\w+\s*\(`),
+			regexp.MustCompile(`^func\s+Benchmark\w+\s*\(`),
+		},
+	}
 }
 
 // NewRunButtonProvider creates a new run button provider with default settings.
@@ -70,9 +97,16 @@ func NewRunButtonProvider() *RunButtonProvider {
 		buttonTexts: make(map[int]string),
 		paragraphs:  make([]gutter.Paragraph, 0),
 		pending:     make([]RunButtonEvent, 0),
+		patterns:    DefaultRunPatterns(),
 	}
 }
 
+// SetPatterns sets the patterns detecting runnable lines. The buttons are
+// updated on the next call of SetLineContents.
+func (p *RunButtonProvider) SetPatterns(patterns RunPatterns) {
+	p.patterns = patterns
+}
+
 // ID returns the unique identifier for this provider.
 func (p *RunButtonProvider) ID() string {
 	return RunButtonProviderID
@@ -146,7 +180,10 @@ func (p *RunButtonProvider) Layout(gtx layout.Context, ctx gutter.GutterContext)
 
 		// Register click handler using clip (use full button area for easier clicking)
 		pointer.CursorPointer.Add(gtx.Ops)
-		clip.Rect(image.Rect(xPos, buttonY, xPos+buttonSizePx, buttonY+p.lineHeight)).Push(gtx.Ops).Pop()
+		btnArea := clip.Rect(image.Rect(xPos, buttonY, xPos+buttonSizePx, buttonY+p.lineHeight)).Push(gtx.Ops)
+		semantic.Button.Add(gtx.Ops)
+		semantic.LabelOp(fmt.Sprintf("%s at line %d", p.HandleHover(para.Index).Text, para.Index+1)).Add(gtx.Ops)
+		btnArea.Pop()
 		p.clicker.Add(gtx.Ops)
 
 		// Choose color based on button type
@@ -270,11 +307,6 @@ func (p *RunButtonProvider) hitTestLine(y int) int {
 
 // analyzeLines analyzes line contents to determine if they should have run buttons.
 func (p *RunButtonProvider) analyzeLines(lines []string, startLine int) {
-	// Patterns for detecting main and test functions
-	mainPattern := regexp.MustCompile(`^func\s+main\s*\(`)
-	testPattern := regexp.MustCompile(`^func\s+Test\w+\s*\(`)
-	benchmarkPattern := regexp.MustCompile(`^func\s+Benchmark\w+\s*\(`)
-
 	// Clear previous button types
 	p.buttonTypes = make(map[int]RunButtonType)
 	p.buttonTexts = make(map[int]string)
@@ -284,25 +316,28 @@ func (p *RunButtonProvider) analyzeLines(lines []string, startLine int) {
 		absoluteLine := startLine + i
 
 		// Check for main function
-		if mainPattern.MatchString(line) {
+		if matchAny(p.patterns.Main, line) {
 			p.buttonTypes[absoluteLine] = RunButtonMain
 			p.buttonTexts[absoluteLine] = line
 			continue
 		}
 
-		// Check for test function
-		if testPattern.MatchString(line) {
+		// Check for test or benchmark function
+		if matchAny(p.patterns.Test, line) {
 			p.buttonTypes[absoluteLine] = RunButtonTest
 			p.buttonTexts[absoluteLine] = line
-			continue
 		}
+	}
+}
 
-		// Check for benchmark function
-		if benchmarkPattern.MatchString(line) {
-			p.buttonTypes[absoluteLine] = RunButtonTest
-			p.buttonTexts[absoluteLine] = line
+// matchAny checks if any of the patterns matches line.
+func matchAny(patterns []*regexp.Regexp, line string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(line) {
+			return true
 		}
 	}
+	return false
 }
 
 // trimLine removes leading/trailing whitespace and comments from a line.
@@ -3,21 +3,16 @@ package providers
 import (
 	"image"
 	"image/color"
-	"regexp"
-	"strings"
 
-	"gioui.org/f32"
 	"gioui.org/gesture"
 	"gioui.org/io/key"
 	"gioui.org/io/pointer"
 	"gioui.org/layout"
-	"gioui.org/op"
-	"gioui.org/op/clip"
-	"gioui.org/op/paint"
 	"gioui.org/text"
 	"gioui.org/unit"
 	gvcolor "github.com/oligo/gvcode/color"
 	"github.com/oligo/gvcode/gutter"
+	"github.com/oligo/gvcode/internal/folding"
 )
 
 const (
@@ -55,8 +50,8 @@ type StickyLinesProvider struct {
 	// allLines caches all lines from the document for structure analysis.
 	allLines []string
 
-	// structureCache caches the code structure analysis results.
-	structureCache []StickyLineInfo
+	// foldManager detects the scopes of the lines.
+	foldManager *folding.Manager
 
 	// clicker handles click events on sticky lines.
 	clicker gesture.Click
@@ -103,7 +98,7 @@ func NewStickyLinesProvider() *StickyLinesProvider {
 		enabled:        true,
 		maxStickyLines: DefaultMaxStickyLines,
 		stickyLines:    make([]StickyLineInfo, 0),
-		structureCache: make([]StickyLineInfo, 0),
+		foldManager:    folding.NewManager(),
 		pending:        make([]StickyLineEvent, 0),
 	}
 }
@@ -148,76 +143,45 @@ func (p *StickyLinesProvider) Width(gtx layout.Context, shaper *text.Shaper, par
 	return unit.Dp(0)
 }
 
+// SetFoldManager sets the fold manager whose fold ranges delimit the scopes
+// of the sticky lines. The editor shares its own fold manager when code
+// folding is enabled, otherwise the provider detects the folds itself.
+func (p *StickyLinesProvider) SetFoldManager(manager *folding.Manager) {
+	if manager != nil {
+		p.foldManager = manager
+	}
+}
+
 // SetLineContents sets the contents of all lines for structure analysis.
 // This implements the gutter.LineContentProvider interface.
 func (p *StickyLinesProvider) SetLineContents(lines []string, startLine int) {
-	// Only update if the content has changed
-	if p.allLines == nil || len(p.allLines) != len(lines) {
-		p.allLines = lines
-		p.analyzeStructure()
-	}
+	p.allLines = lines
+	// the fold manager skips the analysis if the lines did not change.
+	p.foldManager.AnalyzeLines(lines)
 }
 
-// analyzeStructure analyzes the code structure to identify lines that can be sticky.
-// This includes functions, types, constants, variables, etc.
-func (p *StickyLinesProvider) analyzeStructure() {
-	if len(p.allLines) == 0 {
-		p.structureCache = make([]StickyLineInfo, 0)
-		return
-	}
-
-	p.structureCache = make([]StickyLineInfo, 0)
-
-	// Regular expressions for different code structures (Go-specific patterns)
-	functionPattern := regexp.MustCompile(`^\s*(func|func\s+\(\s*\w+\s*\*?\s*\w+\s*\))\s+(\w+)\s*\(`)
-	typePattern := regexp.MustCompile(`^\s*type\s+(\w+)\s+(struct|interface|map|chan|func)`)
-	constPattern := regexp.MustCompile(`^\s*(const|var)\s+\(`)
-	importPattern := regexp.MustCompile(`^\s*import\s*\(`)
-	simpleConstPattern := regexp.MustCompile(`^\s*const\s+\w+`)
-	simpleVarPattern := regexp.MustCompile(`^\s*var\s+\w+`)
-
-	for i, line := range p.allLines {
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" {
+// enclosingScopes returns the scopes enclosing line, from the outermost to
+// the innermost one. The comments are folded, but they are not scopes.
+func (p *StickyLinesProvider) enclosingScopes(line int) []StickyLineInfo {
+	var scopes []StickyLineInfo
+	for _, fold := range p.foldManager.GetFoldRanges() {
+		if fold.Type == folding.FoldTypeComment || fold.StartLine >= line || fold.EndLine < line {
 			continue
 		}
-
-		// Calculate indentation level
-		indent := p.calculateIndent(line)
-
-		var stickyType string
-		var shouldStick bool
-
-		// Check for function declarations
-		if functionPattern.MatchString(line) {
-			stickyType = "function"
-			shouldStick = true
-		} else if typePattern.MatchString(line) {
-			stickyType = "type"
-			shouldStick = true
-		} else if constPattern.MatchString(line) || importPattern.MatchString(line) {
-			stickyType = "block"
-			shouldStick = true
-		} else if simpleConstPattern.MatchString(line) {
-			stickyType = "const"
-			shouldStick = true
-		} else if simpleVarPattern.MatchString(line) {
-			// Only stick top-level variables (indentation 0 or 1)
-			if indent <= 1 {
-				stickyType = "var"
-				shouldStick = true
-			}
-		}
-
-		if shouldStick {
-			p.structureCache = append(p.structureCache, StickyLineInfo{
-				Line:   i,
-				Text:   line,
-				Indent: indent,
-				Type:   stickyType,
-			})
+		if fold.StartLine >= len(p.allLines) {
+			continue
 		}
+		text := p.allLines[fold.StartLine]
+		scopes = append(scopes, StickyLineInfo{
+			Line:   fold.StartLine,
+			Text:   text,
+			Indent: p.calculateIndent(text),
+			Type:   fold.Type.String(),
+		})
 	}
+	// the fold ranges are sorted by start line, so the enclosing scopes are
+	// ordered from the outermost one.
+	return scopes
 }
 
 // calculateIndent calculates the indentation level of a line.
@@ -247,17 +211,19 @@ func (p *StickyLinesProvider) Layout(gtx layout.Context, ctx gutter.GutterContex
 	// Set up colors
 	p.setupColors(ctx.Colors)
 
-	// Calculate which lines should be sticky based on current scroll position
+	// Calculate which lines should be sticky based on current scroll position.
+	// The sticky lines are painted over the text by the editor.
 	p.calculateStickyLines(ctx)
 
-	// Render sticky lines (if any - though rendering is done by editor)
-	if len(p.stickyLines) > 0 {
-		// p.renderStickyLines is not used here, editor handles rendering
-	}
-
 	return layout.Dimensions{Size: image.Point{X: 0, Y: p.stickyAreaHeight}}
 }
 
+// PaletteChanged implements gutter.PaletteObserver, updating the colors of the
+// sticky lines before the next layout.
+func (p *StickyLinesProvider) PaletteChanged(colors *gutter.GutterColors) {
+	p.setupColors(colors)
+}
+
 // setupColors sets up the colors for sticky lines based on the context.
 func (p *StickyLinesProvider) setupColors(colors *gutter.GutterColors) {
 	if colors != nil {
@@ -295,121 +261,47 @@ func (p *StickyLinesProvider) setupColors(colors *gutter.GutterColors) {
 
 // calculateStickyLines determines which lines should be sticky based on scroll position.
 func (p *StickyLinesProvider) calculateStickyLines(ctx gutter.GutterContext) {
-	if !p.enabled || len(p.structureCache) == 0 {
-		p.stickyLines = p.stickyLines[:0]
-		p.stickyAreaHeight = 0
+	p.stickyLines = p.stickyLines[:0]
+	p.stickyAreaHeight = 0
+	if !p.enabled || len(p.allLines) == 0 {
 		return
 	}
 
-	// Find the first visible paragraph
-	firstVisibleLine := -1
-	for _, para := range ctx.Paragraphs {
-		if para.EndY >= ctx.Viewport.Min.Y && para.StartY <= ctx.Viewport.Max.Y {
-			firstVisibleLine = para.Index
+	// The sticky lines are the starts of the scopes enclosing the first line
+	// visible below them. As the area grows, it covers more lines, so the
+	// number of sticky lines is increased until it covers all of them.
+	for {
+		firstVisibleLine := p.lineAt(ctx, ctx.Viewport.Min.Y+len(p.stickyLines)*p.lineHeight)
+		if firstVisibleLine == -1 {
+			p.stickyLines = p.stickyLines[:0]
 			break
 		}
-	}
 
-	if firstVisibleLine == -1 {
-		p.stickyLines = p.stickyLines[:0]
-		p.stickyAreaHeight = 0
-		return
-	}
-
-	// Find all structure lines that are above or at the first visible line
-	p.stickyLines = p.stickyLines[:0]
-	for _, info := range p.structureCache {
-		if info.Line <= firstVisibleLine {
-			p.stickyLines = append(p.stickyLines, info)
-		} else {
+		// keep the innermost scopes if they exceed the max sticky lines.
+		scopes := p.enclosingScopes(firstVisibleLine)
+		count := min(len(scopes), p.maxStickyLines)
+		if count <= len(p.stickyLines) {
+			// the lines covered by the area may end some of the scopes, which
+			// are no longer sticky then.
+			p.stickyLines = append(p.stickyLines[:0], scopes[len(scopes)-count:]...)
 			break
 		}
-	}
-
-	// Limit to max sticky lines
-	if len(p.stickyLines) > p.maxStickyLines {
-		p.stickyLines = p.stickyLines[len(p.stickyLines)-p.maxStickyLines:]
+		p.stickyLines = append(p.stickyLines[:0], scopes[len(scopes)-count:]...)
 	}
 
 	// Calculate sticky area height
 	p.stickyAreaHeight = len(p.stickyLines) * p.lineHeight
 }
 
-// renderStickyLines renders the sticky lines at the top of the viewport.
-func (p *StickyLinesProvider) renderStickyLines(gtx layout.Context, ctx gutter.GutterContext) {
-	lineHeightPx := p.lineHeight
-
-	// Draw background and text for each sticky line
-	for i, sticky := range p.stickyLines {
-		stickyY := i * lineHeightPx
-
-		// Draw background
-		bgRect := image.Rect(0, stickyY, gtx.Constraints.Max.X, stickyY+lineHeightPx)
-		bgStack := clip.Rect(bgRect).Push(gtx.Ops)
-		paint.ColorOp{Color: p.stickyBackgroundColor.NRGBA()}.Add(gtx.Ops)
-		paint.PaintOp{}.Add(gtx.Ops)
-		bgStack.Pop()
-
-		// Draw border at bottom
-		if i < len(p.stickyLines)-1 {
-			borderRect := image.Rect(0, stickyY+lineHeightPx-1, gtx.Constraints.Max.X, stickyY+lineHeightPx)
-			borderStack := clip.Rect(borderRect).Push(gtx.Ops)
-			paint.ColorOp{Color: p.stickyBorderColor.NRGBA()}.Add(gtx.Ops)
-			paint.PaintOp{}.Add(gtx.Ops)
-			borderStack.Pop()
-		}
-
-		// Register click handler
-		pointer.CursorPointer.Add(gtx.Ops)
-		clip.Rect(bgRect).Push(gtx.Ops).Pop()
-		p.clicker.Add(gtx.Ops)
-
-		// Draw text
-		params := ctx.TextParams
-		params.MinWidth = 0
-		params.MaxLines = 1
-
-		// Trim whitespace for display
-		displayText := strings.TrimLeft(sticky.Text, "\t")
-		displayText = strings.TrimRight(displayText, " \t\r\n")
-
-		ctx.Shaper.LayoutString(params, displayText)
-
-		glyphs := make([]text.Glyph, 0)
-		var bounds image.Rectangle
-
-		for {
-			g, ok := ctx.Shaper.NextGlyph()
-			if !ok {
-				break
-			}
-
-			bounds.Min.X = min(bounds.Min.X, g.X.Floor())
-			bounds.Min.Y = min(bounds.Min.Y, int(g.Y)-g.Ascent.Floor())
-			bounds.Max.X = max(bounds.Max.X, (g.X + g.Advance).Ceil())
-			bounds.Max.Y = max(bounds.Max.Y, int(g.Y)+g.Descent.Ceil())
-
-			glyphs = append(glyphs, g)
-		}
-
-		if len(glyphs) > 0 {
-			// Transform to the correct position
-			yPos := float32(stickyY) + float32(lineHeightPx)/2
-			trans := op.Affine(f32.Affine2D{}.Offset(
-				f32.Point{X: float32(glyphs[0].X.Floor()) + 8, Y: yPos},
-			)).Push(gtx.Ops)
-
-			// Draw the glyphs
-			path := ctx.Shaper.Shape(glyphs)
-			outline := clip.Outline{Path: path}.Op().Push(gtx.Ops)
-
-			paint.ColorOp{Color: p.stickyTextColor.NRGBA()}.Add(gtx.Ops)
-			paint.PaintOp{}.Add(gtx.Ops)
-
-			outline.Pop()
-			trans.Pop()
+// lineAt returns the index of the first visible paragraph ending below y, or
+// -1 if there is none.
+func (p *StickyLinesProvider) lineAt(ctx gutter.GutterContext, y int) int {
+	for _, para := range ctx.Paragraphs {
+		if para.EndY >= y && para.StartY <= ctx.Viewport.Max.Y {
+			return para.Index
 		}
 	}
+	return -1
 }
 
 // GetPendingEvents returns pending sticky line events and clears the pending list.
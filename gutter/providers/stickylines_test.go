@@ -0,0 +1,64 @@
+package providers
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/oligo/gvcode/gutter"
+)
+
+func TestStickyLinesScopes(t *testing.T) {
+	src := `package main
+
+func a() {
+	x++
+}
+
+func b() {
+	/*
+	 comment
+	*/
+	y++
+	y++
+	y++
+	y++
+}
+
+var c = 1
+`
+	lines := strings.Split(src, "\n")
+	p := NewStickyLinesProvider()
+	p.SetLineContents(lines, 0)
+	p.lineHeight = 10
+
+	layoutAt := func(top int) {
+		ctx := gutter.GutterContext{Viewport: image.Rect(0, top*10, 800, top*10+100)}
+		for i := range lines {
+			ctx.Paragraphs = append(ctx.Paragraphs, gutter.Paragraph{StartY: i*10 + 8, EndY: i*10 + 8, Index: i})
+		}
+		p.calculateStickyLines(ctx)
+	}
+
+	// only the function enclosing the first visible line is sticky, not the
+	// declarations above it nor the comment.
+	layoutAt(9)
+	if len(p.stickyLines) != 1 || p.stickyLines[0].Line != 6 || p.stickyLines[0].Type != "function" {
+		t.Fatalf("got sticky lines %+v, want func b", p.stickyLines)
+	}
+	if p.StickyLinesHeight() != 10 {
+		t.Errorf("got sticky height %d, want 10", p.StickyLinesHeight())
+	}
+
+	// no scope encloses the lines between the functions.
+	layoutAt(5)
+	if len(p.stickyLines) != 0 {
+		t.Fatalf("got sticky lines %+v, want none", p.stickyLines)
+	}
+
+	// the function is no longer sticky when its last line is covered by it.
+	layoutAt(14)
+	if len(p.stickyLines) != 0 {
+		t.Fatalf("got sticky lines %+v at the end of func b, want none", p.stickyLines)
+	}
+}
@@ -0,0 +1,122 @@
+package gvcode
+
+import (
+	"image"
+	"strings"
+	"testing"
+	"time"
+
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"github.com/oligo/gvcode/gutter"
+	"github.com/oligo/gvcode/gutter/providers"
+)
+
+func TestGutterTooltip(t *testing.T) {
+	provider := providers.NewVCSDiffProvider()
+	e := newTestEditor(t, strings.Repeat("a\n", 20), WithGutter(provider))
+	provider.UpdateDiff([]*providers.DiffHunk{{Type: providers.DiffAdded, StartLine: 2, EndLine: 4}})
+
+	var router input.Router
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	now := time.Now()
+	var hovers []gutter.GutterHoverEvent
+	frame := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 300)), Source: router.Source(), Now: now}
+		for {
+			evt, ok := e.Update(gtx)
+			if !ok {
+				break
+			}
+			if w, ok := evt.(GutterEventWrapper); ok {
+				if hover, ok := w.Event.(gutter.GutterHoverEvent); ok {
+					hovers = append(hovers, hover)
+				}
+			}
+		}
+		e.Layout(gtx, shaper)
+		router.Frame(gtx.Ops)
+	}
+	hover := func(pos image.Point) {
+		router.Queue(pointer.Event{Kind: pointer.Move, Source: pointer.Mouse, Position: layout.FPt(pos)})
+		frame()
+		now = now.Add(time.Second)
+		frame()
+		frame()
+	}
+	frame()
+	frame()
+	// the middle of a line, above its baseline.
+	middle := func(line int) int {
+		return lineY(e, line) - (lineY(e, 1)-lineY(e, 0))/2
+	}
+
+	// a line without a hunk has nothing to tell.
+	pos := image.Pt(1, middle(0))
+	hover(pos)
+	if tooltip := e.gutterManager.Tooltip(); tooltip != nil {
+		t.Fatalf("got tooltip %+v over a line without changes", tooltip)
+	}
+
+	pos = image.Pt(1, middle(3))
+	hover(pos)
+	tooltip := e.gutterManager.Tooltip()
+	if tooltip == nil || tooltip.Line != 3 || tooltip.Position != pos || tooltip.Info.Text == "" {
+		t.Fatalf("got tooltip %+v, want the hunk of line 3 at the pointer", tooltip)
+	}
+	if len(hovers) != 1 || hovers[0].Line != 3 || hovers[0].ProviderID != provider.ID() {
+		t.Errorf("got hover events %+v, want one for line 3", hovers)
+	}
+
+	// moving to the text dismisses the tooltip.
+	hover(image.Pt(200, pos.Y))
+	if tooltip := e.gutterManager.Tooltip(); tooltip != nil {
+		t.Errorf("got tooltip %+v over the text", tooltip)
+	}
+}
+
+func TestGutterHeatmapHistory(t *testing.T) {
+	heatmap := providers.NewHeatmapProvider()
+	e := newTestEditor(t, "abc\ndef\nghi\n")
+	// registered to the manager directly, after the editor is created.
+	e.WithOptions(WithDefaultGutters())
+	e.GetGutterManager().Register(heatmap)
+
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 300)), Now: time.Now()}
+	e.Layout(gtx, shaper)
+	heatmap.Clear()
+
+	spotAt := func(offset int) (providers.HeatSpot, bool) {
+		for _, spot := range heatmap.Spots() {
+			if spot.Offset == offset {
+				return spot, true
+			}
+		}
+		return providers.HeatSpot{}, false
+	}
+
+	e.RevealRange(8, 9, RevealCenter)
+	if spot, ok := spotAt(8); !ok || spot.Edit {
+		t.Fatalf("got spots %+v, want the jump to 8", heatmap.Spots())
+	}
+
+	e.SetCaret(0, 0)
+	e.Insert("xx")
+	if spot, ok := spotAt(0); !ok || !spot.Edit {
+		t.Fatalf("got spots %+v, want the edit at 0", heatmap.Spots())
+	}
+	if _, ok := spotAt(10); !ok {
+		t.Fatalf("got spots %+v, want the jump moved to 10", heatmap.Spots())
+	}
+
+	// the undo is an edit too.
+	e.undo()
+	if _, ok := spotAt(8); !ok {
+		t.Errorf("got spots %+v, want the jump moved back to 8", heatmap.Spots())
+	}
+}
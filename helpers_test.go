@@ -0,0 +1,35 @@
+package gvcode
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+// newTestEditor creates an editor of content with the options, laid out once
+// so that the text can be edited and the caret placed.
+func newTestEditor(t testing.TB, content string, opts ...EditorOption) *Editor {
+	t.Helper()
+	e := &Editor{}
+	e.WithOptions(append([]EditorOption{WithColorScheme(syntax.ColorScheme{}), WithTextSize(14)}, opts...)...)
+	e.SetText(content)
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+	e.Layout(gtx, text.NewShaper(text.WithCollection(gofont.Collection())))
+	return e
+}
+
+// newGoEditor creates an editor like newTestEditor, with the Go language set
+// for the features depending on it, e.g., comments and folding.
+func newGoEditor(t testing.TB, content string, opts ...EditorOption) *Editor {
+	t.Helper()
+	e := newTestEditor(t, content, opts...)
+	if err := e.SetLanguage("go"); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
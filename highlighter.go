@@ -2,6 +2,7 @@ package gvcode
 
 import (
 	stdColor "image/color"
+	"strings"
 
 	"github.com/oligo/gvcode/color"
 	"github.com/oligo/gvcode/textstyle/decoration"
@@ -91,9 +92,10 @@ func (wh *wordHighlighter) MarkActive(active bool) {
 	}
 }
 
-// selectionHighlighter finds the currently selected text and highlights
-// all occurrences of that text in the document. If there's no text selection,
-// no highlighting is performed.
+// selectionHighlighter finds the currently selected text and highlights the
+// other occurrences of that text in the visible lines. If there's no text
+// selection, or the selection spans multiple lines or is shorter than
+// minLength, no highlighting is performed.
 //
 // The highlightColor parameter sets the background color for the text occurrences.
 // If highlightColor is zero, a default color will be used.
@@ -101,7 +103,13 @@ type selectionHighlighter struct {
 	editor          *Editor
 	lastSelection   string
 	lastSelectionID int // track selection changes by comparing start/end positions
-	dirty           bool
+	// lastVisible is the range of the paragraphs searched for occurrences.
+	lastVisible [2]int
+	dirty       bool
+	// disabled turns the highlighting off.
+	disabled bool
+	// minLength is the minimum number of runes of a highlighted selection.
+	minLength int
 }
 
 func (sh *selectionHighlighter) HighlightSelection(highlightColor color.Color) error {
@@ -111,19 +119,30 @@ func (sh *selectionHighlighter) HighlightSelection(highlightColor color.Color) e
 	// Compute selection ID for state tracking
 	start, end := sh.editor.Selection()
 	selectionID := start<<32 | end
+	first, last := sh.editor.visibleParagraphs()
 
 	defer func() {
 		sh.lastSelectionID = selectionID
+		sh.lastVisible = [2]int{first, last}
 		sh.lastSelection = sh.editor.SelectedText()
 		sh.dirty = false
 	}()
 
 	// No highlighting if there's no selection
-	if sh.editor.SelectionLen() == 0 {
+	if sh.disabled || sh.editor.SelectionLen() == 0 || sh.editor.SelectionLen() < sh.minLength {
+		return nil
+	}
+	if strings.Contains(sh.editor.SelectedText(), "\n") {
 		return nil
 	}
 
-	occurrences := sh.editor.text.FindAllTextOccurrences(start, end)
+	paragraphs := sh.editor.text.TextLayout().Paragraphs
+	if first >= last {
+		return nil
+	}
+	from := paragraphs[first].RuneOff
+	to := paragraphs[last-1].RuneOff + paragraphs[last-1].Runes
+	occurrences := sh.editor.text.FindTextOccurrencesIn(start, end, from, to)
 	if len(occurrences) == 0 {
 		return nil
 	}
@@ -169,6 +188,10 @@ func (sh *selectionHighlighter) IsDirty() bool {
 	if selectionID != sh.lastSelectionID {
 		return true
 	}
+	// the occurrences scrolled into view are highlighted too.
+	if first, last := sh.editor.visibleParagraphs(); sh.lastVisible != [2]int{first, last} && start != end {
+		return true
+	}
 	return sh.dirty
 }
 
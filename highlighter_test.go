@@ -0,0 +1,51 @@
+package gvcode
+
+import (
+	"strings"
+	"testing"
+)
+
+// selectionHighlights returns the ranges of the selection highlights.
+func selectionHighlights(e *Editor) [][2]int {
+	var ranges [][2]int
+	for _, deco := range e.text.QueryDecorations(0, e.Len()) {
+		if deco.Source == selectionHighlightSource {
+			ranges = append(ranges, [2]int{deco.Start, deco.End})
+		}
+	}
+	return ranges
+}
+
+func TestSelectionHighlight(t *testing.T) {
+	e := newTestEditor(t, "foo bar foo\nfoo\n"+strings.Repeat("\n", 200)+"foo\n")
+	e.SetCaret(0, 3)
+	e.selectionHighlighter.HighlightSelection(e.colorPalette.SelectColor)
+	// the current selection and the occurrence out of the viewport are not
+	// highlighted.
+	if got := selectionHighlights(e); len(got) != 2 || got[0] != [2]int{8, 11} || got[1] != [2]int{12, 15} {
+		t.Fatalf("got highlights %v", got)
+	}
+
+	e.WithOptions(WithSelectionHighlight(true, 4))
+	if !e.selectionHighlighter.IsDirty() {
+		t.Fatal("expected the highlights to be updated")
+	}
+	e.selectionHighlighter.HighlightSelection(e.colorPalette.SelectColor)
+	if got := selectionHighlights(e); len(got) != 0 {
+		t.Fatalf("got highlights %v of a selection shorter than the minimum", got)
+	}
+
+	e.WithOptions(WithSelectionHighlight(false, 0))
+	e.selectionHighlighter.HighlightSelection(e.colorPalette.SelectColor)
+	if got := selectionHighlights(e); len(got) != 0 {
+		t.Fatalf("got highlights %v while disabled", got)
+	}
+
+	// selections of multiple lines are not highlighted.
+	e.WithOptions(WithSelectionHighlight(true, 0))
+	e.SetCaret(0, 13)
+	e.selectionHighlighter.HighlightSelection(e.colorPalette.SelectColor)
+	if got := selectionHighlights(e); len(got) != 0 {
+		t.Fatalf("got highlights %v of a multi-line selection", got)
+	}
+}
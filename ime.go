@@ -0,0 +1,102 @@
+package gvcode
+
+import (
+	"image"
+	"unicode/utf8"
+
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	gvcolor "github.com/oligo/gvcode/color"
+)
+
+// compositionDash is the length of the dashes and the gaps of the underline
+// of the composing text.
+const compositionDash = unit.Dp(2)
+
+// SetComposingRegion marks the runes [start, end) as the text an input method
+// is composing, which is underlined with dashes until it is committed. Gio
+// does not report the composing region to the widgets, so hosts knowing it,
+// e.g., from the input method of the platform, set it after each edit, and
+// clear it with an empty region. Web builds track it with the composition
+// events of the browser.
+func (e *Editor) SetComposingRegion(start, end int) {
+	e.initBuffer()
+	if start > end {
+		start, end = end, start
+	}
+	length := e.Len()
+	start = max(0, min(start, length))
+	end = max(0, min(end, length))
+	e.ime.compose = key.Range{Start: start, End: end}
+}
+
+// ComposingRegion returns the region set by SetComposingRegion, which is
+// empty when no text is being composed.
+func (e *Editor) ComposingRegion() (start, end int) {
+	return e.ime.compose.Start, e.ime.compose.End
+}
+
+// syncComposition updates the composing region with the composition text of
+// the browser: it starts at the caret when the composition starts, and spans
+// the composed text.
+func (e *Editor) syncComposition(composing bool, text string) {
+	if !composing {
+		if e.ime.composing {
+			e.ime.composing = false
+			e.SetComposingRegion(0, 0)
+		}
+		return
+	}
+	start := e.ime.compose.Start
+	if !e.ime.composing {
+		e.ime.composing = true
+		start, _ = e.text.Selection()
+		if _, end := e.text.Selection(); end < start {
+			start = end
+		}
+	}
+	e.SetComposingRegion(start, start+utf8.RuneCountInString(text))
+}
+
+// syncIMESelection tells the input method about the selection and the caret
+// rectangle, next to which the candidate windows are shown. It is called
+// after the layout too, as scrolling to the caret moves it.
+func (e *Editor) syncIMESelection(gtx layout.Context) {
+	newSel := e.ime.selection
+	start, end := e.text.Selection()
+	newSel.rng = key.Range{
+		Start: start,
+		End:   end,
+	}
+	caretPos, carAsc, carDesc := e.text.CaretInfo()
+	newSel.caret = key.Caret{
+		Pos:     layout.FPt(caretPos),
+		Ascent:  float32(carAsc),
+		Descent: float32(carDesc),
+	}
+	if newSel != e.ime.selection {
+		e.ime.selection = newSel
+		gtx.Execute(key.SelectionCmd{Tag: e, Range: newSel.rng, Caret: newSel.caret})
+	}
+}
+
+// paintComposition underlines the composing text with dashes, which tells it
+// from the committed text and its solid underlines.
+func (e *Editor) paintComposition(gtx layout.Context, material gvcolor.Color) {
+	start, end := e.ComposingRegion()
+	if start >= end {
+		return
+	}
+	thickness := max(gtx.Dp(unit.Dp(1)), 1)
+	dash := max(gtx.Dp(compositionDash), 1)
+	for _, r := range e.text.Regions(start, end, nil) {
+		y := r.Bounds.Max.Y - r.Baseline + thickness
+		for x := r.Bounds.Min.X; x < r.Bounds.Max.X; x += 2 * dash {
+			rect := image.Rect(x, y, min(x+dash, r.Bounds.Max.X), y+thickness)
+			paint.FillShape(gtx.Ops, material.NRGBA(), clip.Rect(rect).Op())
+		}
+	}
+}
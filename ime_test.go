@@ -0,0 +1,66 @@
+package gvcode
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestComposingRegion(t *testing.T) {
+	e := newTestEditor(t, "hello world")
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	e.Layout(layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 200))}, shaper)
+
+	e.SetComposingRegion(8, 6)
+	if start, end := e.ComposingRegion(); start != 6 || end != 8 {
+		t.Fatalf("got region [%d, %d], want [6, 8]", start, end)
+	}
+	// the region follows the edits before it.
+	e.SetCaret(0, 0)
+	e.Insert(">> ")
+	if start, end := e.ComposingRegion(); start != 9 || end != 11 {
+		t.Errorf("got region [%d, %d] after an insertion, want [9, 11]", start, end)
+	}
+	e.SetComposingRegion(0, 0)
+
+	// the compositions of the browser start at the caret.
+	e.SetCaret(3, 3)
+	e.syncComposition(true, "")
+	e.Insert("ni")
+	e.syncComposition(true, "ni")
+	if start, end := e.ComposingRegion(); start != 3 || end != 5 {
+		t.Errorf("got region [%d, %d] while composing, want [3, 5]", start, end)
+	}
+	e.replace(3, 5, "你")
+	e.syncComposition(true, "你")
+	if start, end := e.ComposingRegion(); start != 3 || end != 4 {
+		t.Errorf("got region [%d, %d] after the candidate, want [3, 4]", start, end)
+	}
+	e.syncComposition(false, "")
+	if start, end := e.ComposingRegion(); start != end {
+		t.Errorf("got region [%d, %d] after the composition", start, end)
+	}
+}
+
+func TestIMECaretAfterScroll(t *testing.T) {
+	e := newTestEditor(t, strings.Repeat("line\n", 100))
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 200))}
+	e.Layout(gtx, shaper)
+
+	// the caret reported to the input method is the one scrolled into view.
+	off := e.text.ConvertPos(80, 2)
+	e.SetCaret(off, off)
+	e.SetComposingRegion(off-2, off)
+	e.Layout(gtx, shaper)
+	pos, _, _ := e.text.CaretInfo()
+	if got := e.ime.selection.caret.Pos; got != f32.Pt(float32(pos.X), float32(pos.Y)) || pos.Y > 200 {
+		t.Errorf("got the IME caret at %v, want the visible caret at %v", got, pos)
+	}
+}
@@ -15,6 +15,9 @@ var errReadRune = errors.New("read rune error")
 // rune location to byte location efficiently.
 type textBuffer struct {
 	buf []byte
+	// file backs the buffer instead of buf if it is set. A file buffer is
+	// read only.
+	file *pagedFile
 	runeOffIndex
 	// Length of the  buffer in runes.
 	length int
@@ -29,14 +32,29 @@ func newTextBuffer() *textBuffer {
 
 // ReadRuneAt implements [runeReader].
 func (tb *textBuffer) ReadRuneAt(byteOff int64) (rune, int, error) {
-	if int(byteOff) >= len(tb.buf) {
+	if int(byteOff) >= tb.size() {
 		return 0, 0, io.EOF
 	}
 
+	if tb.file != nil {
+		var b [utf8.UTFMax]byte
+		n := tb.file.copyAt(b[:], int(byteOff))
+		c, s := utf8.DecodeRune(b[:n])
+		return c, s, nil
+	}
+
 	c, s := utf8.DecodeRune(tb.buf[byteOff:])
 	return c, s, nil
 }
 
+// size returns the size of the buffer in bytes.
+func (tb *textBuffer) size() int {
+	if tb.file != nil {
+		return tb.file.size
+	}
+	return len(tb.buf)
+}
+
 // set the inital buffer.
 func (tb *textBuffer) set(buf []byte) int {
 	tb.buf = buf
@@ -44,6 +62,19 @@ func (tb *textBuffer) set(buf []byte) int {
 	return tb.length
 }
 
+// setFile sets the file backing the buffer. It reads the whole file once to
+// count its runes.
+func (tb *textBuffer) setFile(f *pagedFile) (int, error) {
+	runes, err := f.countRunes()
+	if err != nil {
+		return 0, err
+	}
+	tb.file = f
+	tb.length = runes
+	tb.stride = fileRunesPerIndexEntry
+	return runes, nil
+}
+
 func (tb *textBuffer) ensure(n int) {
 	if cap(tb.buf)-len(tb.buf) >= n {
 		return
@@ -78,27 +109,55 @@ func (tb *textBuffer) bytesForRange(runeIdx int, runeLen int) int {
 }
 
 func (tb *textBuffer) getTextByRange(byteIdx int, size int) []byte {
-	if byteIdx < 0 || byteIdx >= len(tb.buf) {
+	if byteIdx < 0 || byteIdx >= tb.size() {
 		return nil
 	}
 
+	if tb.file != nil {
+		buf := make([]byte, size)
+		return buf[:tb.file.copyAt(buf, byteIdx)]
+	}
+
 	return tb.buf[byteIdx : byteIdx+size]
 }
 
+// copyAt copies the bytes starting at byteIdx to p, and returns the number of
+// bytes copied.
+func (tb *textBuffer) copyAt(p []byte, byteIdx int) int {
+	if byteIdx < 0 || byteIdx >= tb.size() {
+		return 0
+	}
+
+	if tb.file != nil {
+		return tb.file.copyAt(p, byteIdx)
+	}
+	return copy(p, tb.buf[byteIdx:])
+}
+
+// chunks calls fn with the successive slices of the size bytes starting at
+// byteIdx, without loading the whole range of a file buffer in memory. The
+// slices must not be retained.
+func (tb *textBuffer) chunks(byteIdx int, size int, fn func([]byte)) {
+	if tb.file != nil {
+		tb.file.chunks(byteIdx, size, fn)
+		return
+	}
+	fn(tb.buf[byteIdx : byteIdx+size])
+}
+
 // getTextByRuneRange reads runes starting at the given rune offset.
 func (tb *textBuffer) getTextByRuneRange(runeIdx int, size int) []rune {
 	start := tb.RuneOffset(runeIdx)
 	end := tb.RuneOffset(runeIdx + size)
 
-	textBytes := tb.buf[start:end]
+	textBytes := tb.getTextByRange(start, end-start)
 	runes := make([]rune, size)
 
 	i := 0
 	for len(textBytes) > 0 {
+		// invalid bytes are read as utf8.RuneError, one rune per byte,
+		// like they are counted.
 		c, s := utf8.DecodeRune(textBytes)
-		if c == utf8.RuneError && s == 1 {
-			break
-		}
 
 		runes[i] = c
 		i++
@@ -112,12 +171,19 @@ func (tb *textBuffer) getTextByRuneRange(runeIdx int, size int) []rune {
 // zero-allocation method compared to getTextByRuneRange.
 func (tb *textBuffer) getRuneAt(runeIdx int) (rune, error) {
 	start := tb.RuneOffset(runeIdx)
+	if tb.file != nil {
+		r, s, _ := tb.ReadRuneAt(int64(start))
+		if s == 0 {
+			return utf8.RuneError, errReadRune
+		}
+		return r, nil
+	}
 
 	// Slice into the buffer directly
 	b := tb.buf[start:]
 
 	r, s := utf8.DecodeRune(b)
-	if r == utf8.RuneError && s == 1 {
+	if s == 0 {
 		return r, errReadRune
 	}
 	return r, nil
@@ -0,0 +1,110 @@
+package buffer
+
+// checkpoint identifies a state of the undo history: the operation on top of
+// the undo stack, and the depth of the stack. Operations are never reused
+// once they are dropped from the undo and redo stacks, so the state is
+// reached again only by undoing or redoing to it.
+type checkpoint struct {
+	top   *pieceRange
+	depth int
+}
+
+// state returns the current state of the undo history.
+func (pt *PieceTable) state() checkpoint {
+	return checkpoint{top: pt.undoStack.peek(), depth: pt.undoStack.depth()}
+}
+
+// Checkpoint marks the current state of the text sequence with name,
+// replacing the previous checkpoint of the name. A checkpoint survives undo
+// and redo: the text is not dirty since the checkpoint whenever undo or redo
+// restores it.
+func (pt *PieceTable) Checkpoint(name string) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	if pt.checkpoints == nil {
+		pt.checkpoints = make(map[string]checkpoint)
+	}
+	pt.checkpoints[name] = pt.state()
+	// the following input must not be merged into the last piece, or the
+	// text would change without changing the state.
+	pt.lastInsertPiece = nil
+}
+
+// RemoveCheckpoint removes the checkpoint of name.
+func (pt *PieceTable) RemoveCheckpoint(name string) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	delete(pt.checkpoints, name)
+}
+
+// IsDirtySince reports whether the text sequence has changed since the
+// checkpoint of name. It reports true if the checkpoint does not exist.
+func (pt *PieceTable) IsDirtySince(name string) bool {
+	pt.mu.RLock()
+	defer pt.mu.RUnlock()
+
+	cp, ok := pt.checkpoints[name]
+	return !ok || cp != pt.state()
+}
+
+// RevertTo undoes or redoes the operations made since the checkpoint of
+// name, restoring the text sequence to it. It returns the cursor positions
+// of the restored operations, and false if the checkpoint does not exist or
+// can not be reached, e.g., the operations undone before it were dropped by
+// a later edit, in which case the text is left untouched.
+func (pt *PieceTable) RevertTo(name string) ([]CursorPos, bool) {
+	defer pt.notifyEdits()
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	defer pt.inspect()
+
+	cp, ok := pt.checkpoints[name]
+	if !ok || !pt.reachable(cp) {
+		return nil, false
+	}
+
+	src, dest := pt.undoStack, pt.redoStack
+	if cp.depth > pt.undoStack.depth() {
+		src, dest = pt.redoStack, pt.undoStack
+	}
+
+	var cursors []CursorPos
+	for pt.state() != cp {
+		restored, _ := pt.undoRedo(src, dest)
+		cursors = append(cursors, restored...)
+	}
+	return cursors, true
+}
+
+// historyAt returns the i-th operation of the undo history, counting the
+// undo stack from its bottom, then the redo stack from its top.
+func (pt *PieceTable) historyAt(i int) *pieceRange {
+	if i < pt.undoStack.depth() {
+		return pt.undoStack.ranges[i]
+	}
+	return pt.redoStack.ranges[pt.redoStack.depth()-1-(i-pt.undoStack.depth())]
+}
+
+// reachable reports whether undo or redo can restore the checkpoint: its
+// operation is still in the history, and it is not inside of a group of
+// operations, which are undone and redone at once.
+func (pt *PieceTable) reachable(cp checkpoint) bool {
+	total := pt.undoStack.depth() + pt.redoStack.depth()
+	if cp.depth < 0 || cp.depth > total {
+		return false
+	}
+	if cp.depth == 0 {
+		return cp.top == nil
+	}
+	prev := pt.historyAt(cp.depth - 1)
+	if prev != cp.top {
+		return false
+	}
+	if cp.depth == total {
+		return true
+	}
+	next := pt.historyAt(cp.depth)
+	return prev.batchId == nil || prev.batchId != next.batchId
+}
@@ -0,0 +1,93 @@
+package buffer
+
+import "testing"
+
+func TestCheckpointDirty(t *testing.T) {
+	pt := NewPieceTable([]byte("Hello"))
+	pt.Replace(5, 5, ",")
+	pt.Checkpoint("saved")
+	if pt.IsDirtySince("saved") {
+		t.Fatal("expected clean state at the checkpoint")
+	}
+
+	// single runes typed after the checkpoint are merged into one piece.
+	pt.Replace(6, 6, " ")
+	pt.Replace(7, 7, "w")
+	if !pt.IsDirtySince("saved") {
+		t.Fatal("expected dirty state after the edits")
+	}
+
+	pt.Undo()
+	if pt.IsDirtySince("saved") || readTableContent(pt) != "Hello," {
+		t.Fatalf("expected clean state after undo, got %q", readTableContent(pt))
+	}
+	pt.Undo()
+	if !pt.IsDirtySince("saved") {
+		t.Fatal("expected dirty state after undoing past the checkpoint")
+	}
+	pt.Redo()
+	if pt.IsDirtySince("saved") {
+		t.Fatal("expected clean state after redo")
+	}
+
+	if !pt.IsDirtySince("unknown") {
+		t.Fatal("expected dirty state for an unknown checkpoint")
+	}
+}
+
+func TestRevertTo(t *testing.T) {
+	pt := NewPieceTable([]byte("Hello"))
+	pt.Checkpoint("start")
+	pt.Replace(5, 5, ", world")
+	pt.Checkpoint("saved")
+	pt.Replace(0, 5, "Bye")
+	pt.Replace(pt.Len(), pt.Len(), "!")
+
+	if _, ok := pt.RevertTo("saved"); !ok || readTableContent(pt) != "Hello, world" {
+		t.Fatalf("revert backward: got %q, %v", readTableContent(pt), ok)
+	}
+	if _, ok := pt.RevertTo("start"); !ok || readTableContent(pt) != "Hello" {
+		t.Fatalf("revert to start: got %q, %v", readTableContent(pt), ok)
+	}
+	if _, ok := pt.RevertTo("saved"); !ok || readTableContent(pt) != "Hello, world" {
+		t.Fatalf("revert forward: got %q, %v", readTableContent(pt), ok)
+	}
+
+	// an edit after undoing past a checkpoint drops it from the history.
+	pt.Undo()
+	pt.Replace(0, 0, "Oh, ")
+	if _, ok := pt.RevertTo("saved"); ok {
+		t.Fatal("expected the checkpoint to be unreachable")
+	}
+	if readTableContent(pt) != "Oh, Hello" {
+		t.Fatalf("unexpected content: %q", readTableContent(pt))
+	}
+}
+
+func TestRevertToInsideGroup(t *testing.T) {
+	pt := NewPieceTable([]byte("Hello"))
+	pt.GroupOp()
+	pt.Replace(5, 5, ",")
+	pt.Checkpoint("inside")
+	pt.Replace(6, 6, " world")
+	pt.UnGroupOp()
+	pt.Replace(0, 0, "Oh, ")
+
+	// the group is undone at once, so the checkpoint can not be restored,
+	// and nothing is undone.
+	if _, ok := pt.RevertTo("inside"); ok {
+		t.Fatal("expected the checkpoint inside of the group to be unreachable")
+	}
+	if got := readTableContent(pt); got != "Oh, Hello, world" {
+		t.Fatalf("got %q, want the text unchanged", got)
+	}
+
+	pt.Undo()
+	pt.Undo()
+	if _, ok := pt.RevertTo("inside"); ok {
+		t.Fatal("expected the checkpoint inside of the group to be unreachable by redo")
+	}
+	if got := readTableContent(pt); got != "Hello" {
+		t.Fatalf("got %q, want the text unchanged", got)
+	}
+}
@@ -0,0 +1,85 @@
+package buffer
+
+// EditDelta describes an edit reported to the listeners registered by
+// OnEdit. In addition to the rune offsets of Edit, it carries the byte offsets
+// of the edit and the inserted text, so that listeners, e.g., incremental
+// parsers and LSP clients, can apply it without reading the text.
+type EditDelta struct {
+	Edit
+	// StartByte and OldEndByte are the byte offsets of the replaced text
+	// before the edit, and NewEndByte is the end byte offset of the inserted
+	// text after the edit.
+	StartByte  int
+	OldEndByte int
+	NewEndByte int
+	// Text is the inserted text. It is empty for deletions.
+	Text string
+}
+
+// editListener is a callback registered by OnEdit.
+type editListener struct {
+	fn func(EditDelta)
+}
+
+// OnEdit registers fn to be called after each insertion, deletion or
+// replacement of the text sequence, including the ones made by undo, redo
+// and SetText. A replacement may be reported as a deletion followed by an
+// insertion. fn is called after the piece table is unlocked, so it may read
+// the text. It returns a function to unregister fn.
+func (pt *PieceTable) OnEdit(fn func(EditDelta)) (remove func()) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	l := &editListener{fn: fn}
+	pt.editListeners = append(pt.editListeners, l)
+	return func() {
+		pt.mu.Lock()
+		defer pt.mu.Unlock()
+		for i, listener := range pt.editListeners {
+			if listener == l {
+				pt.editListeners = append(pt.editListeners[:i], pt.editListeners[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// queueDelta builds the delta of the last recorded edit for the listeners.
+// It must be called after the edit is applied, while the piece table is
+// locked.
+func (pt *PieceTable) queueDelta(edit Edit) {
+	bytesDelta := pt.seqBytes - pt.deltaBytes
+	pt.deltaBytes = pt.seqBytes
+	if len(pt.editListeners) == 0 {
+		return
+	}
+
+	delta := EditDelta{
+		Edit:       edit,
+		StartByte:  pt.runeOffset(edit.Start),
+		NewEndByte: pt.runeOffset(edit.NewEnd),
+	}
+	delta.OldEndByte = delta.NewEndByte - bytesDelta
+	if n := delta.NewEndByte - delta.StartByte; n > 0 {
+		buf := make([]byte, n)
+		n, _ = pt.readAt(buf, int64(delta.StartByte))
+		delta.Text = string(buf[:n])
+	}
+	pt.pendingDeltas = append(pt.pendingDeltas, delta)
+}
+
+// notifyEdits calls the listeners with the queued deltas. It must be called
+// without holding the lock.
+func (pt *PieceTable) notifyEdits() {
+	pt.mu.Lock()
+	deltas := pt.pendingDeltas
+	pt.pendingDeltas = nil
+	listeners := append([]*editListener(nil), pt.editListeners...)
+	pt.mu.Unlock()
+
+	for _, delta := range deltas {
+		for _, l := range listeners {
+			l.fn(delta)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package buffer
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOnEdit(t *testing.T) {
+	pt := NewPieceTable([]byte("héllo"))
+	var deltas []EditDelta
+	remove := pt.OnEdit(func(d EditDelta) {
+		// the listener is called without the lock held.
+		_ = pt.Len()
+		d.Version = 0
+		deltas = append(deltas, d)
+	})
+
+	pt.Replace(1, 1, "ü")
+	pt.Replace(0, 3, "")
+	pt.Undo()
+	pt.SetText([]byte("ok"))
+
+	want := []EditDelta{
+		{Edit: Edit{Start: 1, OldEnd: 1, NewEnd: 2}, StartByte: 1, OldEndByte: 1, NewEndByte: 3, Text: "ü"},
+		{Edit: Edit{Start: 0, OldEnd: 3, NewEnd: 0}, StartByte: 0, OldEndByte: 5, NewEndByte: 0},
+		{Edit: Edit{Start: 0, OldEnd: 0, NewEnd: 3}, StartByte: 0, OldEndByte: 0, NewEndByte: 5, Text: "hüé"},
+		{Edit: Edit{Start: 0, OldEnd: 6, NewEnd: 2}, StartByte: 0, OldEndByte: 8, NewEndByte: 2, Text: "ok"},
+	}
+	if !slices.Equal(deltas, want) {
+		t.Fatalf("got deltas %+v, want %+v", deltas, want)
+	}
+
+	remove()
+	pt.Replace(0, 0, "!")
+	if len(deltas) != len(want) {
+		t.Fatal("expected no delta after removing the listener")
+	}
+}
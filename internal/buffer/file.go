@@ -0,0 +1,134 @@
+package buffer
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// filePageSize is the size of the pages a file is read in.
+	filePageSize = 64 << 10
+	// maxFilePages is the number of pages of a file kept in memory.
+	maxFilePages = 64
+	// fileRunesPerIndexEntry is the stride of the rune offset index of a
+	// file. It is coarser than the one of the in memory buffers to keep the
+	// index of large files small.
+	fileRunesPerIndexEntry = 4096
+)
+
+// pagedFile reads a file on demand, in pages. Only the recently read pages
+// are kept in memory, so that the regions of a large file which are not
+// viewed or edited are not resident.
+//
+// Pages are read while the piece table is locked for reading, so the page
+// cache is guarded by its own lock.
+type pagedFile struct {
+	r    io.ReaderAt
+	size int
+
+	mu    sync.Mutex
+	pages map[int]*filePage
+	// clock is increased by every page access to find the least recently
+	// used page.
+	clock int
+}
+
+type filePage struct {
+	data     []byte
+	lastUsed int
+}
+
+func newPagedFile(r io.ReaderAt, size int) *pagedFile {
+	return &pagedFile{r: r, size: size, pages: make(map[int]*filePage)}
+}
+
+// page returns the page of index idx, reading it from the file if it is not
+// cached. Bytes which can not be read, e.g., because the file was truncated,
+// are read as zeros.
+func (f *pagedFile) page(idx int) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.clock++
+	if p, ok := f.pages[idx]; ok {
+		p.lastUsed = f.clock
+		return p.data
+	}
+
+	if len(f.pages) >= maxFilePages {
+		f.evict()
+	}
+	off := idx * filePageSize
+	data := make([]byte, min(filePageSize, f.size-off))
+	f.r.ReadAt(data, int64(off))
+	f.pages[idx] = &filePage{data: data, lastUsed: f.clock}
+	return data
+}
+
+// evict drops the least recently used page.
+func (f *pagedFile) evict() {
+	victim, lastUsed := -1, f.clock
+	for idx, p := range f.pages {
+		if p.lastUsed < lastUsed {
+			victim, lastUsed = idx, p.lastUsed
+		}
+	}
+	delete(f.pages, victim)
+}
+
+// chunks calls fn with the successive slices of the n bytes of the file
+// starting at byteOff. The slices must not be retained.
+func (f *pagedFile) chunks(byteOff, n int, fn func([]byte)) {
+	end := min(byteOff+n, f.size)
+	for off := byteOff; off < end; {
+		data := f.page(off / filePageSize)
+		inPage := off % filePageSize
+		chunk := data[inPage:min(len(data), inPage+end-off)]
+		fn(chunk)
+		off += len(chunk)
+	}
+}
+
+// copyAt copies the bytes of the file starting at byteOff to p, and returns
+// the number of bytes copied.
+func (f *pagedFile) copyAt(p []byte, byteOff int) int {
+	total := 0
+	f.chunks(byteOff, len(p), func(chunk []byte) {
+		total += copy(p[total:], chunk)
+	})
+	return total
+}
+
+// countRunes reads the whole file to count its runes. Invalid bytes are
+// counted as one rune each, like they are decoded.
+func (f *pagedFile) countRunes() (int, error) {
+	buf := make([]byte, filePageSize+utf8.UTFMax)
+	runes := 0
+	// carry is the number of bytes of an incomplete rune at the end of the
+	// previous read.
+	carry := 0
+	for off := 0; off < f.size; {
+		n, err := f.r.ReadAt(buf[carry:carry+min(filePageSize, f.size-off)], int64(off))
+		if n == 0 && err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		if n == 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		off += n
+
+		data := buf[:carry+n]
+		for len(data) > 0 {
+			if off < f.size && !utf8.FullRune(data) {
+				break
+			}
+			_, s := utf8.DecodeRune(data)
+			data = data[s:]
+			runes++
+		}
+		carry = copy(buf, data)
+	}
+	return runes, nil
+}
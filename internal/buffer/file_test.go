@@ -0,0 +1,109 @@
+package buffer
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	r  *bytes.Reader
+	mu sync.Mutex
+	n  int
+}
+
+func (c *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	c.mu.Lock()
+	c.n += len(p)
+	c.mu.Unlock()
+	return c.r.ReadAt(p, off)
+}
+
+// largeText returns text of multiple pages with multi-byte runes spanning
+// the page boundaries.
+func largeText() string {
+	return strings.Repeat("héllo wörld 世界\n", 3*filePageSize/20) + "tail"
+}
+
+func newFileSource(t *testing.T, text string) (*PieceTable, *countingReader) {
+	t.Helper()
+	r := &countingReader{r: bytes.NewReader([]byte(text))}
+	pt, err := NewFileTextSource(r, int64(len(text)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pt, r
+}
+
+func TestFileTextSource(t *testing.T) {
+	text := largeText()
+	pt, _ := newFileSource(t, text)
+	mem := NewPieceTable([]byte(text))
+
+	if pt.Len() != utf8.RuneCountInString(text) || pt.Size() != len(text) {
+		t.Fatalf("got %d runes and %d bytes, want %d and %d", pt.Len(), pt.Size(), utf8.RuneCountInString(text), len(text))
+	}
+	if pt.Lines() != mem.Lines() {
+		t.Errorf("got %d lines, want %d", pt.Lines(), mem.Lines())
+	}
+	if got := readTableContent(pt); got != text {
+		t.Error("content of the file source differs from the file")
+	}
+
+	for _, off := range []int{0, 1, 7, filePageSize / 3, pt.Len() / 2, pt.Len() - 1} {
+		if got, want := pt.RuneOffset(off), mem.RuneOffset(off); got != want {
+			t.Errorf("rune %d: got byte offset %d, want %d", off, got, want)
+		}
+		got, _ := pt.ReadRuneAt(off)
+		want, _ := mem.ReadRuneAt(off)
+		if got != want {
+			t.Errorf("rune %d: got %q, want %q", off, got, want)
+		}
+	}
+}
+
+func TestFileTextSourceEdit(t *testing.T) {
+	text := largeText()
+	pt, _ := newFileSource(t, text)
+	mem := NewPieceTable([]byte(text))
+
+	mid := pt.Len() / 2
+	for _, table := range []*PieceTable{pt, mem} {
+		table.Replace(mid, mid+5, "世界")
+		table.Replace(10, 10, "inserted\n")
+		table.Replace(table.Len()-4, table.Len(), "")
+	}
+	if readTableContent(pt) != readTableContent(mem) || pt.Lines() != mem.Lines() {
+		t.Fatal("edited file source differs from the edited text")
+	}
+	if got, want := string(pt.Snapshot().Bytes()), readTableContent(mem); got != want {
+		t.Error("snapshot of the file source differs from the edited text")
+	}
+
+	for range 3 {
+		pt.Undo()
+	}
+	if readTableContent(pt) != text {
+		t.Error("undo did not restore the file content")
+	}
+}
+
+func TestFileTextSourceOnDemand(t *testing.T) {
+	text := strings.Repeat("x", (maxFilePages+8)*filePageSize)
+	pt, r := newFileSource(t, text)
+
+	r.n = 0
+	buf := make([]byte, 100)
+	pt.ReadAt(buf, int64(len(text)/2))
+	if r.n > filePageSize {
+		t.Errorf("read %d bytes to read 100 bytes, want at most one page", r.n)
+	}
+
+	readTableContent(pt)
+	if pages := len(pt.originalBuf.file.pages); pages > maxFilePages {
+		t.Errorf("%d pages are resident, want at most %d", pages, maxFilePages)
+	}
+}
@@ -62,10 +62,19 @@ type PieceTable struct {
 	currentBatch *int
 	mu           sync.RWMutex
 
-	// Index of the slice saves the continuous line number starting from zero.
-	// The value contains the rune length of the line.
-	lines   []lineInfo
 	markers []*Marker
+
+	// version is increased by every edit, and edits logs the recent edits.
+	version int
+	edits   []Edit
+	// checkpoints are the named states of the undo history.
+	checkpoints map[string]checkpoint
+
+	// editListeners are notified of the deltas queued by the edits.
+	// deltaBytes is the byte size of the sequence after the last edit.
+	editListeners []*editListener
+	pendingDeltas []EditDelta
+	deltaBytes    int
 }
 
 func NewPieceTable(text []byte) *PieceTable {
@@ -77,14 +86,19 @@ func NewPieceTable(text []byte) *PieceTable {
 		redoStack:   &pieceRangeStack{},
 	}
 	pt.init(text)
+	pt.deltaBytes = pt.seqBytes
 
 	return pt
 }
 
 func (pt *PieceTable) SetText(text []byte) {
+	defer pt.notifyEdits()
 	pt.mu.Lock()
 	defer pt.mu.Unlock()
 
+	oldLen := pt.seqLength
+	defer func() { pt.recordEdit(0, oldLen, pt.seqLength) }()
+
 	pt.originalBuf = newTextBuffer()
 	pt.modifyBuf = newTextBuffer()
 	pt.pieces = newPieceList()
@@ -98,6 +112,7 @@ func (pt *PieceTable) SetText(text []byte) {
 	pt.changed = false
 	pt.currentBatch = nil
 	pt.markers = pt.markers[:0]
+	clear(pt.checkpoints)
 	pt.init(text)
 }
 
@@ -105,6 +120,11 @@ func (pt *PieceTable) SetText(text []byte) {
 // and create the first piece point to the buffer.
 func (pt *PieceTable) init(text []byte) {
 	_, _, runeCnt := pt.addToBuffer(original, text)
+	pt.initPiece(runeCnt, len(text))
+}
+
+// initPiece creates the first piece covering the whole original buffer.
+func (pt *PieceTable) initPiece(runeCnt, byteLen int) {
 	if runeCnt <= 0 {
 		return
 	}
@@ -114,7 +134,7 @@ func (pt *PieceTable) init(text []byte) {
 		offset:     0,
 		length:     runeCnt,
 		byteOff:    0,
-		byteLength: len(text),
+		byteLength: byteLen,
 	}
 
 	pt.pieces.Append(piece)
@@ -173,6 +193,8 @@ func (pt *PieceTable) insert(runeIndex int, text string) bool {
 	pt.redoStack.clear()
 
 	// special-case: inserting at the end of a prior insertion at a piece boundary.
+	defer pt.recordEdit(runeIndex, runeIndex, runeIndex+utf8.RuneCountInString(text))
+
 	if pt.tryAppendToLastPiece(runeIndex, text) {
 		pt.changed = true
 		return true
@@ -308,6 +330,12 @@ func (pt *PieceTable) undoRedo(src *pieceRangeStack, dest *pieceRangeStack) ([]C
 
 	restoreFunc := func(rng *pieceRange) CursorPos {
 		newRuneLen, newBytes := rng.Size()
+		prev := rng.first
+		if !rng.boundary {
+			prev = rng.first.prev
+		}
+		start := pt.pieceEndOffset(prev)
+		restored := rng.Pieces()
 
 		// restore to the old piece range.
 		rng.Restore()
@@ -319,6 +347,12 @@ func (pt *PieceTable) undoRedo(src *pieceRangeStack, dest *pieceRangeStack) ([]C
 		pt.seqBytes += newBytes - lastBytes
 		pt.changed = true
 		pt.pieces.invalidateCache()
+		// trim the text shared by the swapped pieces to get the exact edit.
+		prefix, suffix := commonRunes(rng.Pieces(), restored)
+		start += prefix
+		oldEnd, newEnd := start+lastRuneLen-prefix-suffix, start+newRuneLen-prefix-suffix
+		pt.recordEdit(start, oldEnd, newEnd)
+		pt.remapMarkers(start, oldEnd, newEnd)
 		return rng.cursor
 	}
 
@@ -362,6 +396,7 @@ func (pt *PieceTable) erase(startOff, endOff int) bool {
 	defer func() {
 		pt.changed = true
 		pt.recordAction(actionErase, startOff)
+		pt.recordEdit(startOff, endOff, startOff)
 	}()
 
 	startPiece, inRuneOff, _ := pt.pieces.FindPiece(startOff)
@@ -475,6 +510,7 @@ func (pt *PieceTable) erase(startOff, endOff int) bool {
 
 // Replace removes text from startOff to endOff(exclusive), and insert text at the position of startOff.
 func (pt *PieceTable) Replace(startOff, endOff int, text string) bool {
+	defer pt.notifyEdits()
 	pt.mu.Lock()
 	defer pt.mu.Unlock()
 
@@ -505,6 +541,7 @@ func (pt *PieceTable) Replace(startOff, endOff int, text string) bool {
 }
 
 func (pt *PieceTable) Undo() ([]CursorPos, bool) {
+	defer pt.notifyEdits()
 	pt.mu.Lock()
 	defer pt.mu.Unlock()
 
@@ -513,6 +550,7 @@ func (pt *PieceTable) Undo() ([]CursorPos, bool) {
 }
 
 func (pt *PieceTable) Redo() ([]CursorPos, bool) {
+	defer pt.notifyEdits()
 	pt.mu.Lock()
 	defer pt.mu.Unlock()
 
@@ -581,7 +619,10 @@ func (pt *PieceTable) Changed() bool {
 func (pt *PieceTable) ReadAt(p []byte, offset int64) (total int, err error) {
 	pt.mu.RLock()
 	defer pt.mu.RUnlock()
+	return pt.readAt(p, offset)
+}
 
+func (pt *PieceTable) readAt(p []byte, offset int64) (total int, err error) {
 	if len(p) == 0 {
 		return 0, nil
 	}
@@ -599,11 +640,11 @@ func (pt *PieceTable) ReadAt(p []byte, offset int64) (total int, err error) {
 
 	for n := first; n != pt.pieces.tail; n = n.next {
 		readSize := min(n.byteLength-bytesOff, expected-total)
-		fragment := pt.getBuf(n.source).getTextByRange(
+		copied := pt.getBuf(n.source).copyAt(
+			p[:readSize],
 			n.byteOff+bytesOff, // calculate the offset in the source buffer.
-			readSize)
+		)
 
-		copied := copy(p, fragment)
 		p = p[copied:]
 		total += copied
 		bytesOff = 0 // reset for pieces other than the first one.
@@ -755,6 +796,10 @@ func (pt *PieceTable) updateMarkersOnErase(oldPieces *pieceRange, newPieces *pie
 				} else if marker.pieceOffset >= start.length-tailLen {
 					if tail != nil {
 						marker.update(tail, marker.pieceOffset-(start.length-tailLen))
+					} else if marker.bias == BiasForward {
+						// the marker at the end of the erased text stays
+						// before the text following it.
+						marker.update(end.next, 0)
 					} else {
 						marker.update(head, headLen)
 					}
@@ -795,6 +840,42 @@ func (pt *PieceTable) syncMarkerOffset(marker *Marker) {
 
 		absOff += n.length
 	}
+
+	// the markers moved past the last piece by an erase are at the end of
+	// the document.
+	for _, m := range pt.markers {
+		if m.piece == pt.pieces.tail && (marker == nil || m == marker) {
+			m.offset = absOff
+		}
+	}
+}
+
+// remapMarkers moves the markers to follow the replacement of the rune range
+// [start, oldEnd) with text ending at newEnd, and anchors them to the pieces
+// in the list. It is used when pieces are swapped by undo or redo, as the
+// markers may point to the pieces being unlinked.
+func (pt *PieceTable) remapMarkers(start, oldEnd, newEnd int) {
+	for _, m := range pt.markers {
+		off := m.offset
+		switch {
+		case off < start:
+			// before the edit.
+		case off > oldEnd || (off == oldEnd && start < oldEnd):
+			off += newEnd - oldEnd
+		case m.bias == BiasForward:
+			off = newEnd
+		default:
+			off = start
+		}
+
+		p, inRuneOff, _ := pt.pieces.FindPiece(off)
+		if p == pt.pieces.tail {
+			p = pt.pieces.Tail()
+			inRuneOff = p.length
+		}
+		m.update(p, inRuneOff)
+		m.offset = off
+	}
 }
 
 func (pt *PieceTable) RemoveMarker(m *Marker) {
@@ -431,3 +431,50 @@ func TestMarkerOnErase(t *testing.T) {
 		})
 	}
 }
+
+func TestMarkerOnReplaceAtEnd(t *testing.T) {
+	pt := NewPieceTable([]byte(""))
+	pt.Replace(0, 0, "a = a")
+	start, _ := pt.CreateMarker(4, BiasBackward)
+	end, _ := pt.CreateMarker(5, BiasForward)
+
+	// the forward marker is moved past the last piece by the erase, and
+	// stays at the end of the document.
+	pt.Replace(4, 5, "xy")
+	if start.Offset() != 4 || end.Offset() != 6 {
+		t.Fatalf("unexpected marker offsets: %d, %d", start.Offset(), end.Offset())
+	}
+	pt.Replace(6, 6, "z")
+	if start.Offset() != 4 || end.Offset() != 7 {
+		t.Fatalf("unexpected marker offsets after appending: %d, %d", start.Offset(), end.Offset())
+	}
+}
+
+func TestMarkerOnUndo(t *testing.T) {
+	pt := NewPieceTable([]byte("Hello, world"))
+	forward, _ := pt.CreateMarker(7, BiasForward)
+	backward, _ := pt.CreateMarker(12, BiasBackward)
+
+	pt.Replace(0, 0, "Oh, ")
+	pt.Replace(pt.Len(), pt.Len(), "!")
+	if forward.Offset() != 11 || backward.Offset() != 16 {
+		t.Fatalf("unexpected marker offsets: %d, %d", forward.Offset(), backward.Offset())
+	}
+
+	pt.Undo()
+	pt.Undo()
+	if forward.Offset() != 7 || backward.Offset() != 12 {
+		t.Fatalf("unexpected marker offsets after undo: %d, %d", forward.Offset(), backward.Offset())
+	}
+
+	pt.Redo()
+	if forward.Offset() != 11 || backward.Offset() != 16 {
+		t.Fatalf("unexpected marker offsets after redo: %d, %d", forward.Offset(), backward.Offset())
+	}
+
+	// Markers should keep working with the edits after undo.
+	pt.Replace(4, 4, "Oh, ")
+	if forward.Offset() != 15 || backward.Offset() != 20 {
+		t.Fatalf("unexpected marker offsets: %d, %d", forward.Offset(), backward.Offset())
+	}
+}
@@ -1,6 +1,7 @@
 package buffer
 
 import (
+	"bytes"
 	"io"
 )
 
@@ -10,7 +11,10 @@ var _ TextSource = (*PieceTable)(nil)
 func (pt *PieceTable) RuneOffset(runeOff int) int {
 	pt.mu.RLock()
 	defer pt.mu.RUnlock()
+	return pt.runeOffset(runeOff)
+}
 
+func (pt *PieceTable) runeOffset(runeOff int) int {
 	if pt.seqLength == 0 {
 		return 0
 	}
@@ -31,6 +35,9 @@ func (pt *PieceTable) ReadRuneAt(runeOff int) (rune, error) {
 	pt.mu.RLock()
 	defer pt.mu.RUnlock()
 
+	if runeOff < 0 || runeOff >= pt.seqLength {
+		return 0, io.EOF
+	}
 	n, off, _ := pt.pieces.FindPiece(runeOff)
 	if n == nil {
 		return 0, io.EOF
@@ -39,49 +46,34 @@ func (pt *PieceTable) ReadRuneAt(runeOff int) (rune, error) {
 	return pt.getBuf(n.source).getRuneAt(n.offset + off)
 }
 
-func (r *PieceTable) parseLine(text []byte) []lineInfo {
-	var lines []lineInfo
-
-	n := 0
-	for _, c := range string(text) {
-		n++
-		if c == lineBreak {
-			lines = append(lines, lineInfo{length: n, hasLineBreak: true})
-			n = 0
-		}
-	}
-
-	// The remaining bytes that don't end with a line break.
-	if n > 0 {
-		lines = append(lines, lineInfo{length: n})
-	}
-
-	return lines
-}
-
+// Lines returns the number of lines of the text sequence. The last line is
+// counted only if it is not empty.
 func (pt *PieceTable) Lines() int {
 	pt.mu.RLock()
 	defer pt.mu.RUnlock()
 
-	pt.lines = pt.lines[:0]
+	lines := 0
+	// pending is true if the last line read has no line break yet.
+	pending := false
 	for n := pt.pieces.Head(); n != pt.pieces.tail; n = n.next {
-		pieceText := pt.getBuf(n.source).getTextByRange(n.byteOff, n.byteLength)
-		lines := pt.parseLine(pieceText)
-		if len(lines) > 0 {
-			if len(pt.lines) > 0 {
-				lastLine := pt.lines[len(pt.lines)-1]
-				if !lastLine.hasLineBreak {
-					// merge with lastLine
-					lines[0].length += lastLine.length
-					pt.lines = pt.lines[:len(pt.lines)-1]
-				}
-			}
-
-			pt.lines = append(pt.lines, lines...)
+		if n.byteLength <= 0 {
+			continue
 		}
+		pt.getBuf(n.source).chunks(n.byteOff, n.byteLength, func(chunk []byte) {
+			breaks := bytes.Count(chunk, []byte{lineBreak})
+			lines += breaks
+			if breaks > 0 {
+				pending = chunk[len(chunk)-1] != lineBreak
+			} else {
+				pending = pending || len(chunk) > 0
+			}
+		})
+	}
+	if pending {
+		lines++
 	}
 
-	return len(pt.lines)
+	return lines
 }
 
 // pieceTableReader implements a [TextSource].
@@ -126,6 +118,23 @@ func NewTextSource() *PieceTable {
 	return NewPieceTable([]byte(""))
 }
 
+// NewFileTextSource creates a text source backed by the file r of size
+// bytes. The file is read on demand in pages, and only the recently read
+// pages and the edited text are kept in memory, so that large files can be
+// opened without loading them. The file must not be changed while it is
+// used; it is read once to count its runes, and the error of this read is
+// returned. Invalid UTF-8 bytes are read as U+FFFD, one rune per byte.
+func NewFileTextSource(r io.ReaderAt, size int64) (*PieceTable, error) {
+	pt := NewPieceTable(nil)
+	runes, err := pt.originalBuf.setFile(newPagedFile(r, int(size)))
+	if err != nil {
+		return nil, err
+	}
+	pt.initPiece(runes, int(size))
+	pt.deltaBytes = pt.seqBytes
+	return pt, nil
+}
+
 func NewReader(src TextSource) TextReader {
 	return &pieceTableReader{src: src}
 }
@@ -1,6 +1,7 @@
 package buffer
 
 import (
+	"io"
 	"testing"
 	"unicode/utf8"
 )
@@ -67,4 +68,12 @@ func TestReadRuneAt(t *testing.T) {
 	if r != '你' {
 		t.Fail()
 	}
+
+	// there is no rune at the end of the text, even after edits.
+	src.Replace(src.Len(), src.Len(), "!")
+	for _, off := range []int{-1, src.Len()} {
+		if r, err := src.ReadRuneAt(off); err != io.EOF {
+			t.Errorf("ReadRuneAt(%d) = %q, %v, want io.EOF", off, r, err)
+		}
+	}
 }
@@ -11,6 +11,9 @@ type offsetEntry struct {
 	bytes int
 }
 
+// runesPerIndexEntry is the default stride of runeOffIndex.
+const runesPerIndexEntry = 50
+
 // runeReader defines a ReadRuneAt API to reads the rune starting at the given byte offset, if any.
 type runeReader interface {
 	ReadRuneAt(byteOff int64) (rune, int, error)
@@ -24,6 +27,9 @@ type runeReader interface {
 type runeOffIndex struct {
 	src      runeReader
 	offIndex []offsetEntry
+	// stride is the number of runes between the index entries. The default
+	// is runesPerIndexEntry.
+	stride int
 }
 
 // indexOfRune returns the latest rune index and byte offset no later than runeIndex.
@@ -49,12 +55,15 @@ func (r *runeOffIndex) indexOfRune(runeIndex int) offsetEntry {
 // runeOffset returns the byte offset of the source buffer's runeIndex'th rune.
 // runeIndex must be a valid rune index.
 func (r *runeOffIndex) RuneOffset(runeIndex int) int {
-	const runesPerIndexEntry = 50
+	stride := r.stride
+	if stride <= 0 {
+		stride = runesPerIndexEntry
+	}
 	entry := r.indexOfRune(runeIndex)
 	lastEntry := r.offIndex[len(r.offIndex)-1].runes
 
 	for entry.runes < runeIndex {
-		if entry.runes > lastEntry && entry.runes%runesPerIndexEntry == runesPerIndexEntry-1 {
+		if entry.runes > lastEntry && entry.runes%stride == stride-1 {
 			r.offIndex = append(r.offIndex, entry)
 		}
 		_, s, _ := r.src.ReadRuneAt(int64(entry.bytes))
@@ -0,0 +1,239 @@
+package buffer
+
+import (
+	"io"
+)
+
+// maxEditLog is the number of recent edits kept by the piece table.
+const maxEditLog = 4096
+
+// Edit describes a change made to the text sequence. Start and OldEnd
+// are the rune offsets of the replaced text before the change, while
+// NewEnd is the end rune offset of the inserted text after the change.
+type Edit struct {
+	// Version is the version of the text sequence after the edit.
+	Version int
+	Start   int
+	OldEnd  int
+	NewEnd  int
+}
+
+// Overlaps reports whether the edit touches the inside of the rune range
+// [start, end) at the time of the edit. Insertions at the boundaries of the
+// range do not overlap with it.
+func (e Edit) Overlaps(start, end int) bool {
+	if e.Start == e.OldEnd {
+		return start < e.Start && e.Start < end
+	}
+	return e.Start < end && start < e.OldEnd
+}
+
+// recordEdit appends an edit to the edit log and bumps the version.
+func (pt *PieceTable) recordEdit(start, oldEnd, newEnd int) {
+	pt.version++
+	if len(pt.edits) >= maxEditLog {
+		pt.edits = append(pt.edits[:0], pt.edits[len(pt.edits)-maxEditLog/2:]...)
+	}
+	edit := Edit{Version: pt.version, Start: start, OldEnd: oldEnd, NewEnd: newEnd}
+	pt.edits = append(pt.edits, edit)
+	pt.queueDelta(edit)
+}
+
+// Version returns the version of the text sequence, which is increased
+// by each edit, including the ones made by undo and redo.
+func (pt *PieceTable) Version() int {
+	pt.mu.RLock()
+	defer pt.mu.RUnlock()
+	return pt.version
+}
+
+// EditsSince returns the edits made after version, in the order they were
+// made. It returns false if some of them have been dropped from the edit log.
+func (pt *PieceTable) EditsSince(version int) ([]Edit, bool) {
+	pt.mu.RLock()
+	defer pt.mu.RUnlock()
+
+	if version >= pt.version {
+		return nil, true
+	}
+	if len(pt.edits) == 0 || pt.edits[0].Version > version+1 {
+		return nil, false
+	}
+
+	idx := version + 1 - pt.edits[0].Version
+	edits := make([]Edit, len(pt.edits)-idx)
+	copy(edits, pt.edits[idx:])
+	return edits, true
+}
+
+// pieceEndOffset returns the rune offset of the end of p in the sequence.
+func (pt *PieceTable) pieceEndOffset(p *piece) int {
+	offset := 0
+	if p == pt.pieces.head {
+		return offset
+	}
+	for n := pt.pieces.head.next; n != pt.pieces.tail; n = n.next {
+		offset += n.length
+		if n == p {
+			break
+		}
+	}
+	return offset
+}
+
+// commonRunes returns the number of runes at the start and the end of the text
+// covered by the piece lists a and b that refer to the same buffer locations.
+func commonRunes(a, b []*piece) (prefix, suffix int) {
+	lenA, lenB := 0, 0
+	for _, p := range a {
+		lenA += p.length
+	}
+	for _, p := range b {
+		lenB += p.length
+	}
+	limit := min(lenA, lenB)
+
+	ai, bi, aoff, boff := 0, 0, 0, 0
+	for ai < len(a) && bi < len(b) && prefix < limit {
+		pa, pb := a[ai], b[bi]
+		if pa.source != pb.source || pa.offset+aoff != pb.offset+boff {
+			break
+		}
+		n := min(pa.length-aoff, pb.length-boff, limit-prefix)
+		prefix += n
+		aoff += n
+		boff += n
+		if aoff == pa.length {
+			ai, aoff = ai+1, 0
+		}
+		if boff == pb.length {
+			bi, boff = bi+1, 0
+		}
+	}
+
+	limit -= prefix
+	ai, bi, aoff, boff = len(a)-1, len(b)-1, 0, 0
+	for ai >= 0 && bi >= 0 && suffix < limit {
+		pa, pb := a[ai], b[bi]
+		// compare the ends of the remaining parts of the pieces.
+		if pa.source != pb.source || pa.offset+pa.length-aoff != pb.offset+pb.length-boff {
+			break
+		}
+		n := min(pa.length-aoff, pb.length-boff, limit-suffix)
+		suffix += n
+		aoff += n
+		boff += n
+		if aoff == pa.length {
+			ai, aoff = ai-1, 0
+		}
+		if boff == pb.length {
+			bi, boff = bi-1, 0
+		}
+	}
+	return prefix, suffix
+}
+
+// Snapshot is an immutable view of the text sequence at a version. It is
+// safe to read a snapshot from other goroutines while the piece table
+// is being edited.
+type Snapshot struct {
+	version   int
+	fragments []fragment
+	runes     int
+	bytes     int
+}
+
+// Snapshot takes a snapshot of the current text sequence. It only copies the
+// piece descriptors, as the text buffers are append only.
+func (pt *PieceTable) Snapshot() *Snapshot {
+	pt.mu.RLock()
+	defer pt.mu.RUnlock()
+
+	s := &Snapshot{
+		version: pt.version,
+		runes:   pt.seqLength,
+		bytes:   pt.seqBytes,
+	}
+	for n := pt.pieces.head.next; n != pt.pieces.tail; n = n.next {
+		if n.byteLength <= 0 {
+			continue
+		}
+		tb := pt.getBuf(n.source)
+		if tb.file != nil {
+			s.fragments = append(s.fragments, fragment{file: tb.file, off: n.byteOff, n: n.byteLength})
+			continue
+		}
+		buf := tb.buf[n.byteOff : n.byteOff+n.byteLength : n.byteOff+n.byteLength]
+		s.fragments = append(s.fragments, fragment{data: buf, n: n.byteLength})
+	}
+	return s
+}
+
+// Version returns the version of the text sequence when the snapshot was taken.
+func (s *Snapshot) Version() int {
+	return s.version
+}
+
+// Len returns the length of the snapshot in runes.
+func (s *Snapshot) Len() int {
+	return s.runes
+}
+
+// Size returns the size of the snapshot in bytes.
+func (s *Snapshot) Size() int {
+	return s.bytes
+}
+
+// ReadAt implements [io.ReaderAt].
+func (s *Snapshot) ReadAt(p []byte, offset int64) (int, error) {
+	if offset >= int64(s.bytes) {
+		return 0, io.EOF
+	}
+
+	total := 0
+	for _, frag := range s.fragments {
+		if offset >= int64(frag.n) {
+			offset -= int64(frag.n)
+			continue
+		}
+		n := frag.copyAt(p[total:], int(offset))
+		total += n
+		offset = 0
+		if total >= len(p) {
+			return total, nil
+		}
+	}
+	return total, io.EOF
+}
+
+// Bytes returns the content of the snapshot.
+func (s *Snapshot) Bytes() []byte {
+	buf := make([]byte, 0, s.bytes)
+	for _, frag := range s.fragments {
+		if frag.file != nil {
+			frag.file.chunks(frag.off, frag.n, func(chunk []byte) {
+				buf = append(buf, chunk...)
+			})
+			continue
+		}
+		buf = append(buf, frag.data...)
+	}
+	return buf
+}
+
+// fragment is a piece of the text of a snapshot. It is either a slice of an
+// in memory buffer, or a range of a file read on demand.
+type fragment struct {
+	data []byte
+	file *pagedFile
+	off  int
+	n    int
+}
+
+// copyAt copies the bytes of the fragment starting at off to p.
+func (f fragment) copyAt(p []byte, off int) int {
+	if f.file != nil {
+		return f.file.copyAt(p[:min(len(p), f.n-off)], f.off+off)
+	}
+	return copy(p, f.data[off:])
+}
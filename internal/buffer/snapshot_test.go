@@ -0,0 +1,86 @@
+package buffer
+
+import (
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	pt := NewPieceTable([]byte("Hello, world"))
+	pt.Replace(5, 5, " Go")
+
+	snapshot := pt.Snapshot()
+	pt.Replace(0, 5, "Bye")
+	pt.Replace(pt.Len(), pt.Len(), "!")
+
+	if got := string(snapshot.Bytes()); got != "Hello Go, world" {
+		t.Fatalf("unexpected snapshot content: %q", got)
+	}
+	if snapshot.Len() != 15 || snapshot.Size() != 15 {
+		t.Fatalf("unexpected snapshot size: %d, %d", snapshot.Len(), snapshot.Size())
+	}
+
+	buf := make([]byte, 5)
+	n, err := snapshot.ReadAt(buf, 6)
+	if err != nil || string(buf[:n]) != "Go, w" {
+		t.Fatalf("unexpected ReadAt result: %q, %v", buf[:n], err)
+	}
+
+	if got := readTableContent(pt); got != "Bye Go, world!" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+}
+
+func TestEditsSince(t *testing.T) {
+	pt := NewPieceTable([]byte("Hello, world"))
+	version := pt.Version()
+
+	pt.Replace(5, 5, " Go")
+	pt.Replace(0, 5, "Bye")
+	pt.Undo()
+
+	edits, ok := pt.EditsSince(version)
+	if !ok {
+		t.Fatal("edits should be tracked")
+	}
+
+	expected := []Edit{
+		{Start: 5, OldEnd: 5, NewEnd: 8},
+		{Start: 0, OldEnd: 5, NewEnd: 0},
+		{Start: 0, OldEnd: 0, NewEnd: 3},
+		// undo of the replace.
+		{Start: 0, OldEnd: 3, NewEnd: 0},
+		{Start: 0, OldEnd: 0, NewEnd: 5},
+	}
+	if len(edits) != len(expected) {
+		t.Fatalf("expected %d edits, got %d: %v", len(expected), len(edits), edits)
+	}
+	for i, edit := range edits {
+		edit.Version = 0
+		if edit != expected[i] {
+			t.Errorf("edit %d: expected %v, got %v", i, expected[i], edit)
+		}
+	}
+
+	if edits, ok := pt.EditsSince(pt.Version()); !ok || len(edits) != 0 {
+		t.Fatalf("expected no edits, got %v", edits)
+	}
+
+	for i := 0; i < maxEditLog; i++ {
+		pt.Replace(0, 0, "a")
+	}
+	if _, ok := pt.EditsSince(version); ok {
+		t.Fatal("old edits should be dropped")
+	}
+}
+
+func TestEditOverlaps(t *testing.T) {
+	insert := Edit{Start: 5, OldEnd: 5, NewEnd: 6}
+	if insert.Overlaps(5, 8) || insert.Overlaps(2, 5) || !insert.Overlaps(4, 6) {
+		t.Error("insertion only overlaps the inside of a range")
+	}
+
+	erase := Edit{Start: 5, OldEnd: 7, NewEnd: 5}
+	if erase.Overlaps(7, 9) || erase.Overlaps(3, 5) || !erase.Overlaps(6, 9) {
+		t.Error("erasure overlaps the ranges it intersects")
+	}
+}
@@ -34,3 +34,15 @@ func (s *pieceRangeStack) depth() int {
 func (s *pieceRangeStack) clear() {
 	s.ranges = s.ranges[:0]
 }
+
+// steps returns the number of undo or redo steps in the stack, counting the
+// batched ranges once.
+func (s *pieceRangeStack) steps() int {
+	n := 0
+	for i, rng := range s.ranges {
+		if rng.batchId == nil || i == 0 || s.ranges[i-1].batchId != rng.batchId {
+			n++
+		}
+	}
+	return n
+}
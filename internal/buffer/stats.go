@@ -0,0 +1,34 @@
+package buffer
+
+// Stats describes the internal state of a text source, to measure the cost
+// of the editing history.
+type Stats struct {
+	// Pieces is the number of pieces of the text sequence.
+	Pieces int
+	// UndoDepth and RedoDepth are the number of operations which can be
+	// undone and redone. A group of operations counts as one.
+	UndoDepth int
+	RedoDepth int
+	// OriginalBytes is the size of the original text, and AddedBytes the
+	// size of the text inserted since then, including the text removed or
+	// undone which is kept for the history.
+	OriginalBytes int
+	AddedBytes    int
+	// Markers is the number of markers tracking positions of the text.
+	Markers int
+}
+
+// Stats returns the internal state of the piece table.
+func (pt *PieceTable) Stats() Stats {
+	pt.mu.RLock()
+	defer pt.mu.RUnlock()
+
+	return Stats{
+		Pieces:        pt.pieces.Length(),
+		UndoDepth:     pt.undoStack.steps(),
+		RedoDepth:     pt.redoStack.steps(),
+		OriginalBytes: pt.originalBuf.size(),
+		AddedBytes:    pt.modifyBuf.size(),
+		Markers:       len(pt.markers),
+	}
+}
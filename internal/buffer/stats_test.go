@@ -0,0 +1,21 @@
+package buffer
+
+import "testing"
+
+func TestStats(t *testing.T) {
+	pt := NewPieceTable([]byte("hello world"))
+	pt.Replace(5, 5, ",")
+	pt.GroupOp()
+	pt.Replace(0, 1, "H")
+	pt.Replace(7, 8, "W")
+	pt.UnGroupOp()
+	pt.Undo()
+	if _, err := pt.CreateMarker(3, BiasForward); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Stats{Pieces: 3, UndoDepth: 1, RedoDepth: 1, OriginalBytes: 11, AddedBytes: 3, Markers: 1}
+	if got := pt.Stats(); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
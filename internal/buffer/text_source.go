@@ -55,6 +55,32 @@ type TextSource interface {
 
 	// Changed report whether the contents have changed since the last call to Changed.
 	Changed() bool
+
+	// Version returns the version of the contents, which is increased by every edit.
+	Version() int
+	// EditsSince returns the edits made after version. It returns false if the edits
+	// are too old to be tracked.
+	EditsSince(version int) ([]Edit, bool)
+	// OnEdit registers fn to be called after each edit of the contents with
+	// the rune and byte ranges of the edit and the inserted text. It returns
+	// a function to unregister fn.
+	OnEdit(fn func(EditDelta)) (remove func())
+	// Checkpoint marks the current state of the contents with name, e.g., the
+	// saved state of a file.
+	Checkpoint(name string)
+	// RemoveCheckpoint removes the checkpoint of name.
+	RemoveCheckpoint(name string)
+	// IsDirtySince reports whether the contents have changed since the
+	// checkpoint of name, taking undo and redo into account.
+	IsDirtySince(name string) bool
+	// RevertTo undoes or redoes the operations made since the checkpoint of
+	// name. It returns the cursor positions after the restoration.
+	RevertTo(name string) ([]CursorPos, bool)
+	// Snapshot takes an immutable snapshot of the contents, which can be read
+	// from other goroutines.
+	Snapshot() *Snapshot
+	// Stats returns the internal state of the source.
+	Stats() Stats
 }
 
 type TextReader interface {
@@ -0,0 +1,37 @@
+// Package editortest provides the editor fixture shared by the tests of the
+// addons.
+package editortest
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"github.com/oligo/gvcode"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+// NewShaper returns a shaper of the Go fonts.
+func NewShaper() *text.Shaper {
+	return text.NewShaper(text.WithCollection(gofont.Collection()))
+}
+
+// NewContext returns a layout context of 800x600 pixels.
+func NewContext() layout.Context {
+	return layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+}
+
+// NewEditor creates an editor of content with the options, laid out once by
+// a context of NewContext, so that the text can be edited and the caret
+// placed.
+func NewEditor(t testing.TB, content string, opts ...gvcode.EditorOption) *gvcode.Editor {
+	t.Helper()
+	e := &gvcode.Editor{}
+	e.WithOptions(append([]gvcode.EditorOption{gvcode.WithColorScheme(syntax.ColorScheme{}), gvcode.WithTextSize(14)}, opts...)...)
+	e.SetText(content)
+	e.Layout(NewContext(), NewShaper())
+	return e
+}
@@ -4,6 +4,7 @@ package folding
 
 import (
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -83,6 +84,36 @@ type Manager struct {
 
 	// foldMarkers caches the positions of fold markers in the text.
 	foldMarkers []FoldMarker
+
+	// markers are the language tokens recognized when detecting folds.
+	markers Markers
+
+	// version is incremented whenever the collapsed lines are rebuilt.
+	version int
+}
+
+// Markers are the language specific tokens used to detect fold regions.
+type Markers struct {
+	// LineComment starts a line comment, e.g., "//". Lines starting with it
+	// never start a fold, except for regions.
+	LineComment string
+	// BlockCommentStart and BlockCommentEnd delimit a block comment, which is
+	// folded if it spans multiple lines.
+	BlockCommentStart string
+	BlockCommentEnd   string
+	// Region matches lines starting a named region, e.g., "//region Name".
+	// The first submatch is used as the name of the region.
+	Region *regexp.Regexp
+}
+
+// DefaultMarkers returns the markers of Go and other C like languages.
+func DefaultMarkers() Markers {
+	return Markers{
+		LineComment:       "//",
+		BlockCommentStart: "/*",
+		BlockCommentEnd:   "*/",
+		Region:            regexp.MustCompile(`^//\s*region\s+(\w+)`),
+	}
 }
 
 // FoldMarker represents a fold marker (opening or closing brace).
@@ -107,9 +138,68 @@ func NewManager() *Manager {
 	return &Manager{
 		foldRanges:     make([]FoldRange, 0),
 		collapsedLines: make(map[int]bool),
+		markers:        DefaultMarkers(),
 	}
 }
 
+// SetMarkers sets the tokens used to detect fold regions. The folds are
+// detected again on the next call of AnalyzeLines.
+func (m *Manager) SetMarkers(markers Markers) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.markers = markers
+	m.lineCache = nil
+}
+
+// Markers returns the tokens used to detect fold regions.
+func (m *Manager) Markers() Markers {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.markers
+}
+
+// State is a snapshot of the folds detected in a document and their
+// collapsed states, used to switch a Manager between documents.
+type State struct {
+	foldRanges  []FoldRange
+	lineCache   []string
+	foldMarkers []FoldMarker
+	markers     Markers
+	// saved is false for the zero State.
+	saved bool
+}
+
+// State returns a snapshot of the folds of the current document.
+func (m *Manager) State() State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return State{
+		foldRanges:  slices.Clone(m.foldRanges),
+		lineCache:   m.lineCache,
+		foldMarkers: slices.Clone(m.foldMarkers),
+		markers:     m.markers,
+		saved:       true,
+	}
+}
+
+// SetState restores the folds and the markers saved by State. The zero State
+// clears the folds, which are detected again with the current markers on the
+// next call of AnalyzeLines.
+func (m *Manager) SetState(st State) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.foldRanges = slices.Clone(st.foldRanges)
+	m.lineCache = st.lineCache
+	m.foldMarkers = slices.Clone(st.foldMarkers)
+	if st.saved {
+		m.markers = st.markers
+	}
+	m.rebuildCollapsedLines()
+}
+
 // AnalyzeLines analyzes the given lines and detects foldable regions.
 // This should be called whenever the document content changes.
 func (m *Manager) AnalyzeLines(lines []string) {
@@ -173,13 +263,16 @@ func (m *Manager) detectFolds(lines []string) {
 		trimmed := strings.TrimSpace(line)
 
 		// Handle multi-line comments
-		if strings.HasPrefix(trimmed, "/*") && !inMultiLineComment {
+		blockStart, blockEnd := m.markers.BlockCommentStart, m.markers.BlockCommentEnd
+		if blockStart != "" && strings.HasPrefix(trimmed, blockStart) && !inMultiLineComment {
 			inMultiLineComment = true
 			commentStartLine = i
+			// the end token may follow the start token on the same line.
+			trimmed = trimmed[len(blockStart):]
 		}
 
 		if inMultiLineComment {
-			if strings.Contains(trimmed, "*/") {
+			if blockEnd == "" || strings.Contains(trimmed, blockEnd) {
 				// End of multi-line comment
 				if i > commentStartLine {
 					m.foldRanges = append(m.foldRanges, FoldRange{
@@ -196,7 +289,10 @@ func (m *Manager) detectFolds(lines []string) {
 		}
 
 		// Skip single-line comments and empty lines for fold detection
-		if strings.HasPrefix(trimmed, "//") || trimmed == "" {
+		if trimmed == "" {
+			continue
+		}
+		if lc := m.markers.LineComment; lc != "" && strings.HasPrefix(trimmed, lc) {
 			continue
 		}
 
@@ -262,10 +358,12 @@ func (m *Manager) detectFolds(lines []string) {
 
 		// Check for fold ends
 		if closeCount > 0 && len(foldStack) > 0 {
-			// Pop folds that end at this brace level
+			// Pop the folds closed by this line: a fold ends once the depth
+			// is back to the level of its opening line, e.g., at the "}" of a
+			// top level function. Folds still nested deeper stay open.
 			for len(foldStack) > 0 {
 				entry := foldStack[len(foldStack)-1]
-				if braceDepth <= entry.braceLevel {
+				if braceDepth > entry.braceLevel {
 					break
 				}
 
@@ -322,9 +420,10 @@ func (m *Manager) detectFoldType(line string) (FoldType, string) {
 	}
 
 	// Region pattern: //region Name or // region Name
-	regionPattern := regexp.MustCompile(`^//\s*region\s+(\w+)`)
-	if matches := regionPattern.FindStringSubmatch(trimmed); matches != nil {
-		return FoldTypeRegion, matches[1]
+	if m.markers.Region != nil {
+		if matches := m.markers.Region.FindStringSubmatch(trimmed); len(matches) > 1 {
+			return FoldTypeRegion, matches[1]
+		}
 	}
 
 	return -1, ""
@@ -332,6 +431,7 @@ func (m *Manager) detectFoldType(line string) (FoldType, string) {
 
 // rebuildCollapsedLines rebuilds the map of collapsed lines.
 func (m *Manager) rebuildCollapsedLines() {
+	m.version++
 	m.collapsedLines = make(map[int]bool)
 	for _, fold := range m.foldRanges {
 		if fold.Collapsed {
@@ -342,6 +442,21 @@ func (m *Manager) rebuildCollapsedLines() {
 	}
 }
 
+// Version returns a counter incremented whenever the folds are detected
+// again, collapsed or expanded, to tell whether the visible lines changed.
+func (m *Manager) Version() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.version
+}
+
+// HasCollapsed reports whether any line is hidden by a collapsed fold.
+func (m *Manager) HasCollapsed() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.collapsedLines) > 0
+}
+
 // GetFoldRanges returns all fold ranges.
 func (m *Manager) GetFoldRanges() []FoldRange {
 	m.mu.RLock()
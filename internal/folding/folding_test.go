@@ -0,0 +1,54 @@
+package folding
+
+import (
+	"strings"
+	"testing"
+)
+
+const foldSource = `package main
+
+func a() {
+	if x {
+		y()
+	}
+}
+
+type T struct {
+	f int
+}
+
+func b() { c() }
+func d() {
+	return
+}
+`
+
+func TestDetectFoldEnds(t *testing.T) {
+	m := NewManager()
+	m.AnalyzeLines(strings.Split(foldSource, "\n"))
+
+	// each fold ends at the closing brace of its opening line, not at the
+	// end of the text or at a nested closing brace.
+	want := []FoldRange{
+		{StartLine: 2, EndLine: 6, Type: FoldTypeFunction, Name: "a"},
+		{StartLine: 8, EndLine: 10, Type: FoldTypeType, Name: "T"},
+		{StartLine: 13, EndLine: 15, Type: FoldTypeFunction, Name: "d"},
+	}
+	got := m.GetFoldRanges()
+	if len(got) != len(want) {
+		t.Fatalf("got folds %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got fold %+v, want %+v", got[i], want[i])
+		}
+	}
+
+	// collapsing a fold hides only its lines.
+	m.CollapseFold(2)
+	for line := range 17 {
+		if hidden := line > 2 && line <= 6; m.IsLineVisible(line) == hidden {
+			t.Errorf("line %d: got visible %v, want %v", line, !hidden, !hidden)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package scroll
 
 import (
+	"image"
 	"math"
 	"runtime"
 	"time"
@@ -29,7 +30,7 @@ type Scroll struct {
 	pid       pointer.ID
 	last      int
 	// Leftover scroll.
-	scroll float32
+	scroll f32.Point
 	// Position of the initial pointer.Press.
 	initialPos f32.Point
 	// The initial pointer press duration.
@@ -38,6 +39,38 @@ type Scroll struct {
 	scrollAxis Axis
 	// True if the axis for the current gesture/scrolling has been determined.
 	axisLocked bool
+
+	// Kinetic continues the scrolling of trackpads with a fling when their
+	// stream of scroll events stops, like a touch drag is continued when
+	// the finger is lifted.
+	Kinetic bool
+	// wheelEstimator estimates the velocity of the stream of scroll events.
+	wheelEstimator fling.Extrapolation
+	// lastWheel is the time of the last scroll event, and wheeling reports
+	// whether a fling may follow it.
+	lastWheel time.Time
+	wheeling  bool
+
+	// Zoomable reports the scrolling of wheels with Ctrl held, and the
+	// pinches of two touches, as zoom factors instead of scrolling. As the
+	// zooming wheels must not be clamped, the vertical distances of wheels
+	// are not passed to the outer scrollables at the ends of the range.
+	Zoomable bool
+	// pos is the position of the dragging touch, and pinch is the second
+	// touch of a pinch.
+	pos   f32.Point
+	pinch pinch
+	// zoom is the zoom factor of the last Update.
+	zoom float32
+}
+
+// pinch tracks the second touch of a pinch gesture.
+type pinch struct {
+	active bool
+	pid    pointer.ID
+	pos    f32.Point
+	// dist is the distance between the two touches.
+	dist float32
 }
 
 type ScrollState uint8
@@ -61,6 +94,16 @@ const (
 
 const touchSlop = unit.Dp(3)
 
+// wheelZoomStep is the wheel distance zooming by a factor of zoomStep.
+const (
+	wheelZoomStep = unit.Dp(40)
+	zoomStep      = 1.1
+)
+
+// wheelIdle is the time without scroll events after which the scrolling of
+// a trackpad is continued with a fling.
+const wheelIdle = 50 * time.Millisecond
+
 // Add the handler to the operation list to receive scroll events.
 // The bounds variable refers to the scrolling boundaries
 // as defined in [pointer.Filter].
@@ -71,26 +114,30 @@ func (s *Scroll) Add(ops *op.Ops) {
 // Stop any remaining fling movement.
 func (s *Scroll) Stop() {
 	s.flinger = fling.Animation{}
+	s.wheeling = false
 }
 
 // Direction returns the last scrolling axis detected by Update.
 func (s *Scroll) Direction() Axis {
-	// if s.axisLocked || s.flinger.Active() {
-	// 	return s.scrollAxis
-	// }
-	// slog.Info("returning default direction", "direction", Vertical)
-	// return Vertical
 	return s.scrollAxis
 }
 
-// Update state and report the scroll distance along axis.
-func (s *Scroll) Update(cfg unit.Metric, q input.Source, t time.Time, scrollx, scrolly pointer.ScrollRange) int {
-	total := 0
+// Update state and report the scroll distances along both axes. Wheels
+// scroll horizontally with Shift held, and trackpads report the distances
+// along both axes at once.
+func (s *Scroll) Update(cfg unit.Metric, q input.Source, t time.Time, scrollx, scrolly pointer.ScrollRange) image.Point {
+	var total image.Point
+	s.zoom = 1
 	f := pointer.Filter{
 		Target:  s,
 		Kinds:   pointer.Press | pointer.Drag | pointer.Release | pointer.Scroll | pointer.Cancel,
 		ScrollX: scrollx,
-		ScrollY: scrolly,
+		// the vertical distance of a wheel is scrolled horizontally with
+		// Shift held, so it must not be clamped to the vertical range.
+		ScrollY: pointer.ScrollRange{Min: min(scrollx.Min, scrolly.Min), Max: max(scrollx.Max, scrolly.Max)},
+	}
+	if s.Zoomable {
+		f.ScrollY = pointer.ScrollRange{Min: math.MinInt32, Max: math.MaxInt32}
 	}
 	for {
 		evt, ok := q.Event(f)
@@ -105,6 +152,9 @@ func (s *Scroll) Update(cfg unit.Metric, q input.Source, t time.Time, scrollx, s
 		switch e.Kind {
 		case pointer.Press:
 			if s.dragging {
+				if s.Zoomable && !s.pinch.active && e.Source == pointer.Touch && e.PointerID != s.pid {
+					s.startPinch(q, e)
+				}
 				break
 			}
 			// Only scroll on touch drags, or on Android where mice
@@ -118,11 +168,18 @@ func (s *Scroll) Update(cfg unit.Metric, q input.Source, t time.Time, scrollx, s
 			s.initialPosTime = e.Time
 			s.dragging = true
 			s.pid = e.PointerID
+			s.pos = e.Position
 			// Reset axis lock for the new gesture
 			s.axisLocked = false
 			// Reset estimator
 			s.estimator = fling.Extrapolation{}
 		case pointer.Release:
+			if s.pinch.active && (e.PointerID == s.pid || e.PointerID == s.pinch.pid) {
+				// the touch left after a pinch does not scroll.
+				s.pinch = pinch{}
+				s.dragging = false
+				break
+			}
 			if s.pid != e.PointerID {
 				break
 			}
@@ -133,28 +190,44 @@ func (s *Scroll) Update(cfg unit.Metric, q input.Source, t time.Time, scrollx, s
 			fallthrough
 		case pointer.Cancel:
 			s.dragging = false
+			s.pinch = pinch{}
 			// s.axisLocked = false
 		case pointer.Scroll:
-			if e.Modifiers.Contain(key.ModShift) {
+			dist := e.Scroll
+			if s.Zoomable && e.Modifiers.Contain(key.ModCtrl) {
+				s.zoom *= float32(math.Pow(zoomStep, float64(-dist.Y)/float64(cfg.Dp(wheelZoomStep))))
+				break
+			}
+			// the filter does not clamp the wheels of a zoomable scroll.
+			dist.Y = min(max(dist.Y, float32(min(scrollx.Min, scrolly.Min))), float32(max(scrollx.Max, scrolly.Max)))
+			if e.Modifiers.Contain(key.ModShift) && dist.X == 0 {
+				dist = f32.Pt(dist.Y, 0)
+			}
+			dist.X = min(max(dist.X, float32(scrollx.Min)), float32(scrollx.Max))
+			dist.Y = min(max(dist.Y, float32(scrolly.Min)), float32(scrolly.Max))
+			if math.Abs(float64(dist.X)) > math.Abs(float64(dist.Y)) {
 				s.scrollAxis = Horizontal
 			} else {
 				s.scrollAxis = Vertical
 			}
 			s.axisLocked = true
 
-			switch s.scrollAxis {
-			case Horizontal:
-				s.scroll += e.Scroll.X
-			case Vertical:
-				s.scroll += e.Scroll.Y
+			s.scroll = s.scroll.Add(dist)
+			iscroll := image.Pt(int(s.scroll.X), int(s.scroll.Y))
+			s.scroll = s.scroll.Sub(f32.Pt(float32(iscroll.X), float32(iscroll.Y)))
+			total = total.Add(iscroll)
+			if s.Kinetic {
+				s.sampleWheel(t, e.Time, s.val(s.scrollAxis, dist))
 			}
-			iscroll := int(s.scroll)
-			s.scroll -= float32(iscroll)
-			total += iscroll
 		case pointer.Drag:
+			if s.pinch.active {
+				s.updatePinch(e)
+				continue
+			}
 			if !s.dragging || s.pid != e.PointerID {
 				continue
 			}
+			s.pos = e.Position
 
 			var scrollDelta int
 
@@ -196,11 +269,23 @@ func (s *Scroll) Update(cfg unit.Metric, q input.Source, t time.Time, scrollx, s
 				s.last = v
 			}
 
-			total += scrollDelta
+			total = total.Add(s.axisPoint(scrollDelta))
 		}
 	}
 
-	total += s.flinger.Tick(t)
+	if s.wheeling {
+		if idle := t.Sub(s.lastWheel); idle >= wheelIdle {
+			s.wheeling = false
+			// the fling continues from the last scroll event.
+			if fling := s.wheelEstimator.Estimate(); fling.Distance != 0 {
+				s.flinger.Start(cfg, s.lastWheel, fling.Velocity)
+			}
+		} else {
+			q.Execute(op.InvalidateCmd{At: s.lastWheel.Add(wheelIdle)})
+		}
+	}
+
+	total = total.Add(s.axisPoint(s.flinger.Tick(t)))
 	if s.flinger.Active() || (s.dragging && s.axisLocked) {
 		q.Execute(op.InvalidateCmd{})
 	}
@@ -208,6 +293,68 @@ func (s *Scroll) Update(cfg unit.Metric, q input.Source, t time.Time, scrollx, s
 	return total
 }
 
+// Zoom returns the zoom factor of the last Update, 1 if there is no zoom.
+func (s *Scroll) Zoom() float32 {
+	if s.zoom == 0 {
+		return 1
+	}
+	return s.zoom
+}
+
+// startPinch starts a pinch with the second touch e, taking both touches
+// from the other handlers.
+func (s *Scroll) startPinch(q input.Source, e pointer.Event) {
+	s.Stop()
+	s.estimator = fling.Extrapolation{}
+	s.pinch = pinch{active: true, pid: e.PointerID, pos: e.Position, dist: distance(s.pos, e.Position)}
+	q.Execute(pointer.GrabCmd{Tag: s, ID: s.pid})
+	q.Execute(pointer.GrabCmd{Tag: s, ID: e.PointerID})
+}
+
+// updatePinch accumulates the zoom factor of a pinch when one of its touches
+// moves.
+func (s *Scroll) updatePinch(e pointer.Event) {
+	switch e.PointerID {
+	case s.pid:
+		s.pos = e.Position
+	case s.pinch.pid:
+		s.pinch.pos = e.Position
+	default:
+		return
+	}
+	dist := distance(s.pos, s.pinch.pos)
+	if s.pinch.dist > 0 && dist > 0 {
+		s.zoom *= dist / s.pinch.dist
+	}
+	s.pinch.dist = dist
+}
+
+func distance(a, b f32.Point) float32 {
+	d := a.Sub(b)
+	return float32(math.Hypot(float64(d.X), float64(d.Y)))
+}
+
+// sampleWheel samples the scroll distance dist of a scroll event at time
+// for the fling continuing the scrolling. now is the time of the frame.
+func (s *Scroll) sampleWheel(now time.Time, at time.Duration, dist float32) {
+	if !s.wheeling {
+		s.Stop()
+		s.wheelEstimator = fling.Extrapolation{}
+	}
+	s.wheeling = true
+	s.lastWheel = now
+	// the estimator follows the pointer, which moves against the scrolling.
+	s.wheelEstimator.SampleDelta(at, -dist)
+}
+
+// axisPoint returns the distance along the scrolling axis as a point.
+func (s *Scroll) axisPoint(dist int) image.Point {
+	if s.scrollAxis == Horizontal {
+		return image.Pt(dist, 0)
+	}
+	return image.Pt(0, dist)
+}
+
 func (s *Scroll) val(axis Axis, p f32.Point) float32 {
 	switch axis {
 	case Horizontal:
@@ -0,0 +1,78 @@
+package layout
+
+import (
+	"slices"
+)
+
+// VisualStops returns the caret positions of the screen line in the visual
+// order, from the left to the right, appending them to stops. At the boundary
+// of two runs of different directions, a rune has a position at the end of
+// both runs; only the first one, where the caret of the rune is drawn, is a
+// stop. Positions at the same X are ordered by their runes.
+func (tl *TextLayout) VisualStops(line int, stops []CombinedPos) []CombinedPos {
+	stops = stops[:0]
+	if line < 0 || line >= len(tl.Lines) || len(tl.Positions) == 0 {
+		return stops
+	}
+	first := tl.ClosestToLineCol(ScreenPos{Line: line})
+	if first.LineCol.Line != line {
+		return stops
+	}
+	_, i := tl.ClosestToRune(first.Runes)
+	for ; i < len(tl.Positions) && tl.Positions[i].LineCol.Line <= line; i++ {
+		pos := tl.Positions[i]
+		if pos.LineCol.Line != line {
+			continue
+		}
+		if i > 0 && tl.Positions[i-1].Runes == pos.Runes {
+			continue
+		}
+		stops = append(stops, pos)
+	}
+
+	slices.SortStableFunc(stops, func(a, b CombinedPos) int {
+		if a.X != b.X {
+			return int(a.X - b.X)
+		}
+		return a.Runes - b.Runes
+	})
+	return stops
+}
+
+// VisualMove returns the rune offset of the caret moved from runeIdx by one
+// position to the right if direction is positive, or to the left otherwise.
+// The caret moves by the visual order of the positions on the screen lines,
+// rather than by the logical order of the runes, so that the caret always
+// moves in the direction of the arrow key in text mixing LTR and RTL runs.
+// Moving off the end of a screen line continues at the start of the next one.
+func (tl *TextLayout) VisualMove(runeIdx int, direction int) int {
+	caret, _ := tl.ClosestToRune(runeIdx)
+	line := caret.LineCol.Line
+	stops := tl.VisualStops(line, nil)
+	idx := slices.IndexFunc(stops, func(pos CombinedPos) bool { return pos.Runes == caret.Runes })
+	if idx < 0 {
+		return runeIdx
+	}
+
+	if direction > 0 {
+		idx++
+	} else {
+		idx--
+	}
+	if idx >= 0 && idx < len(stops) {
+		return stops[idx].Runes
+	}
+
+	// continue on the adjacent screen line.
+	for next := line + direction; next >= 0 && next < len(tl.Lines); next += direction {
+		stops = tl.VisualStops(next, stops)
+		if len(stops) == 0 {
+			continue
+		}
+		if direction > 0 {
+			return stops[0].Runes
+		}
+		return stops[len(stops)-1].Runes
+	}
+	return runeIdx
+}
@@ -1,10 +1,13 @@
 package layout
 
 import (
+	"image"
+	"slices"
 	"testing"
 
 	"gioui.org/font"
 	"gioui.org/text"
+	"github.com/oligo/gvcode/internal/buffer"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -520,3 +523,98 @@ func TestRecomputeTotalWidth(t *testing.T) {
 		})
 	}
 }
+
+func layoutBidiText(t *testing.T, input string) *TextLayout {
+	t.Helper()
+	shaper, params, _ := setupShaper()
+	buf := buffer.NewTextSource()
+	buf.SetText([]byte(input))
+	tl := NewTextLayout(buf)
+	params.MaxWidth = 1000
+	tl.Layout(shaper, &params, 4, false)
+	return &tl
+}
+
+func TestVisualMove(t *testing.T) {
+	// "ab " is followed by an RTL run of 4 runes and " cd".
+	tl := layoutBidiText(t, "ab שלום cd")
+
+	var xs []fixed.Int26_6
+	for _, pos := range tl.VisualStops(0, nil) {
+		xs = append(xs, pos.X)
+	}
+	if !slices.IsSorted(xs) {
+		t.Fatalf("stops are not in the visual order: %v", xs)
+	}
+
+	// moving right visits every rune once, from the left to the right.
+	var visited []int
+	caret := 0
+	for range 12 {
+		next := tl.VisualMove(caret, 1)
+		if next == caret {
+			break
+		}
+		visited = append(visited, next)
+		caret = next
+	}
+	want := []int{1, 2, 3, 7, 6, 5, 4, 8, 9, 10}
+	if !slices.Equal(visited, want) {
+		t.Errorf("moving right visits %v, want %v", visited, want)
+	}
+
+	// and moving left visits them back.
+	var back []int
+	for range 12 {
+		next := tl.VisualMove(caret, -1)
+		if next == caret {
+			break
+		}
+		back = append(back, next)
+		caret = next
+	}
+	slices.Reverse(want)
+	want = append(want[1:], 0)
+	if !slices.Equal(back, want) {
+		t.Errorf("moving left visits %v, want %v", back, want)
+	}
+}
+
+func TestVisualMoveAcrossLines(t *testing.T) {
+	tl := layoutBidiText(t, "ab\nשל")
+
+	// the end of the first line continues at the left of the second line,
+	// which is the end of the RTL run.
+	if got := tl.VisualMove(2, 1); got != 5 {
+		t.Errorf("moving right from the end of the line = %d, want 5", got)
+	}
+	if got := tl.VisualMove(5, -1); got != 2 {
+		t.Errorf("moving left from the start of the line = %d, want 2", got)
+	}
+}
+
+func TestLocateAtRunBoundary(t *testing.T) {
+	tl := layoutBidiText(t, "ab שלום cd")
+	viewport := image.Rect(0, 0, 1000, 1000)
+
+	// selecting the first RTL rune must not produce an empty region at the
+	// end of the LTR run.
+	regions := tl.Locate(viewport, 3, 4, nil)
+	if len(regions) != 1 {
+		t.Fatalf("got %d regions, want 1: %v", len(regions), regions)
+	}
+	if regions[0].Bounds.Dx() == 0 {
+		t.Errorf("got an empty region: %v", regions)
+	}
+
+	// a selection across the run boundary covers both runs.
+	regions = tl.Locate(viewport, 1, 5, nil)
+	if len(regions) != 2 {
+		t.Fatalf("got %d regions, want 2: %v", len(regions), regions)
+	}
+	for _, r := range regions {
+		if r.Bounds.Dx() == 0 {
+			t.Errorf("got an empty region: %v", regions)
+		}
+	}
+}
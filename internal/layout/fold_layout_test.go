@@ -0,0 +1,194 @@
+package layout
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/oligo/gvcode/internal/buffer"
+	"github.com/oligo/gvcode/internal/folding"
+	"golang.org/x/image/math/fixed"
+)
+
+// foldedBidiText mixes LTR and RTL runs in lines long enough to be wrapped,
+// with a function whose body is folded.
+var foldedBidiText = strings.Join([]string{
+	"Hello שלום World עולם again and again",
+	"func f() {",
+	"\tשלום עולם hello world שלום עולם",
+	"\treturn",
+	"}",
+	"مرحبا بالعالم and more words here",
+}, "\n")
+
+// foldedLayout lays out src soft-wrapped to columns monospace glyphs, with
+// the folds starting at the collapsed lines collapsed.
+func foldedLayout(t *testing.T, src string, columns int, collapsed ...int) (*TextLayout, *folding.Manager) {
+	t.Helper()
+	shaper, params, spaceGlyph := setupShaper()
+	params.MaxWidth = spaceGlyph.Advance.Mul(fixed.I(columns)).Ceil()
+
+	fm := folding.NewManager()
+	fm.AnalyzeLines(strings.Split(src, "\n"))
+	for _, line := range collapsed {
+		if !fm.CollapseFold(line) {
+			t.Fatalf("no fold at line %d", line)
+		}
+	}
+
+	buf := buffer.NewTextSource()
+	buf.SetText([]byte(src))
+	tl := NewTextLayout(buf)
+	tl.SetFoldManager(fm)
+	tl.Layout(shaper, &params, 4, true)
+	return &tl, fm
+}
+
+// paragraphOf returns the index of the paragraph containing the rune.
+func paragraphOf(tl *TextLayout, runeIdx int) int {
+	for i, p := range tl.Paragraphs {
+		if runeIdx < p.RuneOff+p.Runes {
+			return i
+		}
+	}
+	return len(tl.Paragraphs) - 1
+}
+
+func TestFoldedLayoutParagraphs(t *testing.T) {
+	tl, fm := foldedLayout(t, foldedBidiText, 12, 1)
+	lines := strings.Split(foldedBidiText, "\n")
+
+	if len(tl.Paragraphs) != len(lines) {
+		t.Fatalf("got %d paragraphs, want one per document line: %d", len(tl.Paragraphs), len(lines))
+	}
+
+	runeOff := 0
+	lastY := 0
+	for i, p := range tl.Paragraphs {
+		if p.RuneOff != runeOff {
+			t.Errorf("paragraph %d: got rune offset %d, want %d", i, p.RuneOff, runeOff)
+		}
+		runeOff += len([]rune(lines[i])) + 1
+
+		if fm.IsLineVisible(i) {
+			if p.StartY <= lastY {
+				t.Errorf("paragraph %d: starts at %d, above the paragraph before it at %d", i, p.StartY, lastY)
+			}
+		} else if p.StartY != lastY || p.EndY != lastY {
+			t.Errorf("paragraph %d: folded paragraph at [%d, %d], want it collapsed to %d", i, p.StartY, p.EndY, lastY)
+		}
+		lastY = p.EndY
+	}
+
+	// the first and the last paragraphs are wrapped.
+	for _, i := range []int{0, len(lines) - 1} {
+		if p := tl.Paragraphs[i]; p.EndY <= p.StartY {
+			t.Errorf("paragraph %d is not wrapped", i)
+		}
+	}
+}
+
+func TestFoldedLayoutLines(t *testing.T) {
+	tl, _ := foldedLayout(t, foldedBidiText, 12, 1)
+	lineHeight := tl.calcLineHeight(&tl.params).Round()
+
+	lastY := -1
+	for i, line := range tl.Lines {
+		if line.hidden {
+			if len(line.Glyphs) > 0 {
+				t.Errorf("line %d: folded line has %d glyphs", i, len(line.Glyphs))
+			}
+			continue
+		}
+		if lastY >= 0 && line.YOff != lastY+lineHeight {
+			t.Errorf("line %d: got y %d, want %d", i, line.YOff, lastY+lineHeight)
+		}
+		lastY = line.YOff
+	}
+}
+
+func TestFoldedLayoutHitTesting(t *testing.T) {
+	tl, fm := foldedLayout(t, foldedBidiText, 12, 1)
+
+	for _, pos := range tl.Positions {
+		if para := paragraphOf(tl, pos.Runes); !fm.IsLineVisible(para) {
+			t.Fatalf("rune %d of folded paragraph %d has a caret position", pos.Runes, para)
+		}
+
+		if got, _ := tl.ClosestToRune(pos.Runes); got.Runes != pos.Runes {
+			t.Errorf("rune %d: closest position is at rune %d", pos.Runes, got.Runes)
+		}
+
+		// positions at the boundary of bidi runs may share a location, so
+		// compare the locations.
+		got := tl.ClosestToXY(pos.X, pos.Y)
+		if got.Y != pos.Y || dist(got.X, pos.X).Round() > 0 {
+			t.Errorf("rune %d at (%v, %d): hit-tested to rune %d at (%v, %d)",
+				pos.Runes, pos.X, pos.Y, got.Runes, got.X, got.Y)
+		}
+	}
+
+	// runes of the folded paragraphs resolve to the end of the fold start line.
+	foldStart := tl.Paragraphs[1]
+	for _, para := range tl.Paragraphs[2:5] {
+		for r := para.RuneOff; r < para.RuneOff+para.Runes; r++ {
+			if got, _ := tl.ClosestToRune(r); got.Runes != foldStart.RuneOff+foldStart.Runes-1 {
+				t.Errorf("folded rune %d: got position at rune %d, want the end of the fold start line", r, got.Runes)
+			}
+		}
+	}
+
+	// the caret moves over the folded lines.
+	end, _ := tl.ClosestToRune(foldStart.RuneOff + foldStart.Runes - 1)
+	next, _ := tl.incrementPosition(end)
+	if want := tl.Paragraphs[5].RuneOff; next.Runes != want {
+		t.Errorf("got next position at rune %d, want %d", next.Runes, want)
+	}
+}
+
+func TestFoldedLayoutSelection(t *testing.T) {
+	tl, _ := foldedLayout(t, foldedBidiText, 12, 1)
+	viewport := image.Rect(0, 0, 1000, 1000)
+
+	// select from the middle of the first paragraph to the middle of the
+	// last, across the fold.
+	first, last := tl.Paragraphs[0], tl.Paragraphs[len(tl.Paragraphs)-1]
+	regions := tl.Locate(viewport, first.RuneOff+8, last.RuneOff+last.Runes/2, nil)
+	if len(regions) == 0 {
+		t.Fatal("no selection regions")
+	}
+
+	visible := make(map[int]Line)
+	for _, line := range tl.Lines {
+		if !line.hidden {
+			visible[line.YOff] = line
+		}
+	}
+
+	covered := make(map[int]bool)
+	for _, r := range regions {
+		baseline := r.Bounds.Max.Y - r.Baseline
+		line, ok := visible[baseline]
+		if !ok {
+			t.Errorf("region %v is not on a visible line", r.Bounds)
+			continue
+		}
+		covered[baseline] = true
+
+		if r.Bounds.Dx() < 0 {
+			t.Errorf("region %v has a negative width", r.Bounds)
+		}
+		if r.Bounds.Min.X < line.XOff.Floor() || r.Bounds.Max.X > (line.XOff+line.Width).Ceil() {
+			t.Errorf("region %v is out of the line [%d, %d]", r.Bounds, line.XOff.Floor(), (line.XOff + line.Width).Ceil())
+		}
+	}
+
+	// every visible line between the ends of the selection is selected.
+	start, _ := tl.ClosestToRune(first.RuneOff + 8)
+	end, _ := tl.ClosestToRune(last.RuneOff + last.Runes/2)
+	for y := range visible {
+		if y > start.Y && y < end.Y && !covered[y] {
+			t.Errorf("visible line at %d is not selected", y)
+		}
+	}
+}
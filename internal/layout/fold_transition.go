@@ -0,0 +1,129 @@
+package layout
+
+import "slices"
+
+// foldTransition slides the paragraphs from their positions in the layout
+// before folds are collapsed or expanded to their positions in the new
+// layout, and fades in the revealed paragraphs. The collapsed paragraphs are
+// not faded out, as their lines are placeholders without glyphs in the new
+// layout.
+type foldTransition struct {
+	enabled bool
+	// hidden and startY are the folded state and the shown top of the
+	// paragraphs in the last layout.
+	hidden []bool
+	startY []int
+
+	active bool
+	// offsets are the distances of the paragraphs from their new tops at
+	// the start of the transition.
+	offsets []int
+	// revealed marks the paragraphs expanded by the transition.
+	revealed []bool
+	// progress of the transition in [0, 1].
+	progress float32
+}
+
+// SetFoldTransition enables the transition of the paragraphs after a fold is
+// toggled. The transition is started by the next layout in which folded
+// paragraphs are shown or hidden, and lasts until its progress reaches 1.
+func (tl *TextLayout) SetFoldTransition(enabled bool) {
+	tl.transition.enabled = enabled
+	if !enabled {
+		tl.transition.active = false
+	}
+}
+
+// FoldTransitionActive reports whether the fold transition is in progress.
+func (tl *TextLayout) FoldTransitionActive() bool {
+	return tl.transition.active
+}
+
+// FoldTransitionProgress returns the eased progress of the fold transition.
+func (tl *TextLayout) FoldTransitionProgress() float32 {
+	return tl.transition.progress
+}
+
+// SetFoldTransitionProgress sets the eased progress of the fold transition in
+// [0, 1], applied by the next layout. The transition ends at 1.
+func (tl *TextLayout) SetFoldTransitionProgress(progress float32) {
+	tl.transition.progress = min(max(progress, 0), 1)
+}
+
+// FoldTransitionFade returns the lines revealed by the fold transition, as
+// ranges of indices into Lines, and their opacity.
+func (tl *TextLayout) FoldTransitionFade() ([][2]int, float32) {
+	t := &tl.transition
+	if !t.active {
+		return nil, 1
+	}
+	var ranges [][2]int
+	for i, line := range tl.Lines {
+		if line.paragraph >= len(t.revealed) || !t.revealed[line.paragraph] {
+			continue
+		}
+		if n := len(ranges); n > 0 && ranges[n-1][1] == i {
+			ranges[n-1][1]++
+		} else {
+			ranges = append(ranges, [2]int{i, i + 1})
+		}
+	}
+	return ranges, t.progress
+}
+
+// applyFoldTransition moves the lines laid out by calculateYOffsets to their
+// positions in the current frame of the transition, starting it if the
+// folded paragraphs changed since the last layout.
+func (tl *TextLayout) applyFoldTransition() {
+	t := &tl.transition
+	if !t.enabled {
+		t.hidden, t.startY = t.hidden[:0], t.startY[:0]
+		return
+	}
+
+	// the tops of the paragraphs in the new layout.
+	count := 0
+	if len(tl.Lines) > 0 {
+		count = tl.Lines[len(tl.Lines)-1].paragraph + 1
+	}
+	hidden := make([]bool, count)
+	startY := make([]int, count)
+	for i := len(tl.Lines) - 1; i >= 0; i-- {
+		line := tl.Lines[i]
+		hidden[line.paragraph] = line.hidden
+		startY[line.paragraph] = line.YOff
+	}
+
+	// the edits change the paragraphs, and stop the transition.
+	if len(t.hidden) != count {
+		t.active = false
+	} else if !slices.Equal(t.hidden, hidden) {
+		// the transition starts from the positions shown, which are
+		// interpolated if a transition is in progress.
+		t.offsets = make([]int, count)
+		t.revealed = make([]bool, count)
+		for p := range count {
+			t.offsets[p] = t.startY[p] - startY[p]
+			t.revealed[p] = t.hidden[p] && !hidden[p]
+		}
+		t.active = true
+		t.progress = 0
+	}
+	if t.active && t.progress >= 1 {
+		t.active = false
+	}
+
+	if t.active {
+		k := 1 - t.progress
+		for i := range tl.Lines {
+			line := &tl.Lines[i]
+			if off := int(float32(t.offsets[line.paragraph]) * k); off != 0 {
+				line.adjustYOff(line.YOff + off)
+			}
+		}
+		for p := range startY {
+			startY[p] += int(float32(t.offsets[p]) * k)
+		}
+	}
+	t.hidden, t.startY = hidden, startY
+}
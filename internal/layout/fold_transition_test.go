@@ -0,0 +1,78 @@
+package layout
+
+import "testing"
+
+func TestFoldTransition(t *testing.T) {
+	tl, fm := foldedLayout(t, foldedBidiText, 12, 1)
+	shaper, params, _ := setupShaper()
+	params.MaxWidth = tl.params.MaxWidth
+	tl.SetFoldTransition(true)
+	tl.Layout(shaper, &params, 4, true)
+	if tl.FoldTransitionActive() {
+		t.Fatal("the transition started without toggling a fold")
+	}
+	foldedLast := tl.Paragraphs[5].StartY
+
+	fm.ExpandFold(1)
+	tl.Layout(shaper, &params, 4, true)
+	if !tl.FoldTransitionActive() {
+		t.Fatal("expanding the fold did not start the transition")
+	}
+	// the lines start where they were before the fold was expanded.
+	if got := tl.Paragraphs[5].StartY; got != foldedLast {
+		t.Errorf("got the last paragraph at %d, want it at its folded position %d", got, foldedLast)
+	}
+	for _, p := range tl.Paragraphs[2:5] {
+		if p.StartY != tl.Paragraphs[1].EndY {
+			t.Errorf("got expanded paragraph at %d, want it at the fold start line %d", p.StartY, tl.Paragraphs[1].EndY)
+		}
+	}
+
+	fading, opacity := tl.FoldTransitionFade()
+	if opacity != 0 || len(fading) != 1 {
+		t.Fatalf("got fading lines %v with opacity %v, want the expanded lines transparent", fading, opacity)
+	}
+	for i := fading[0][0]; i < fading[0][1]; i++ {
+		if p := tl.Lines[i].paragraph; p < 2 || p > 4 {
+			t.Errorf("line %d of paragraph %d fades in, want only the expanded paragraphs", i, p)
+		}
+	}
+
+	tl.SetFoldTransitionProgress(0.5)
+	tl.Layout(shaper, &params, 4, true)
+	half := tl.Paragraphs[5].StartY
+
+	tl.SetFoldTransitionProgress(1)
+	tl.Layout(shaper, &params, 4, true)
+	if tl.FoldTransitionActive() {
+		t.Error("the transition did not end")
+	}
+	expandedLast := tl.Paragraphs[5].StartY
+	if half <= foldedLast || half >= expandedLast {
+		t.Errorf("got the last paragraph at %d halfway, want it between %d and %d", half, foldedLast, expandedLast)
+	}
+
+	// the final layout is the layout without the transition.
+	want, _ := foldedLayout(t, foldedBidiText, 12)
+	for i, p := range tl.Paragraphs {
+		if p.StartY != want.Paragraphs[i].StartY {
+			t.Errorf("paragraph %d: got y %d after the transition, want %d", i, p.StartY, want.Paragraphs[i].StartY)
+		}
+	}
+
+	// collapsing the fold slides the lines up from their expanded positions.
+	fm.CollapseFold(1)
+	tl.Layout(shaper, &params, 4, true)
+	if got := tl.Paragraphs[5].StartY; !tl.FoldTransitionActive() || got != expandedLast {
+		t.Errorf("got the last paragraph at %d after collapsing, want it at %d", got, expandedLast)
+	}
+	if fading, _ := tl.FoldTransitionFade(); len(fading) != 0 {
+		t.Errorf("got fading lines %v after collapsing, want none", fading)
+	}
+
+	tl.SetFoldTransition(false)
+	tl.Layout(shaper, &params, 4, true)
+	if got := tl.Paragraphs[5].StartY; tl.FoldTransitionActive() || got != foldedLast {
+		t.Errorf("got the last paragraph at %d with the transition disabled, want %d", got, foldedLast)
+	}
+}
@@ -0,0 +1,36 @@
+package layout
+
+import "maps"
+
+// SetLineGaps sets the number of blank screen lines laid out above the
+// paragraphs, keyed by the index of the paragraph. The gaps of the index past
+// the last paragraph are laid out below it. The gaps above the folded
+// paragraphs are ignored. They take effect on the next layout.
+func (tl *TextLayout) SetLineGaps(gaps map[int]int) {
+	if maps.Equal(tl.gaps, gaps) {
+		return
+	}
+	tl.gaps = maps.Clone(gaps)
+	tl.gapsChanged = true
+}
+
+// LineGap returns the number of blank screen lines laid out above the
+// paragraph of index idx.
+func (tl *TextLayout) LineGap(idx int) int {
+	return tl.gaps[idx]
+}
+
+// addGapBounds extends the bounds of the text over the gaps above the first
+// paragraph and below the last one.
+func (tl *TextLayout) addGapBounds() {
+	if len(tl.gaps) == 0 || len(tl.Lines) == 0 {
+		return
+	}
+	lineHeight := tl.calcLineHeight(&tl.params).Round()
+	if tl.gaps[0] > 0 {
+		tl.bounds.Min.Y = min(tl.bounds.Min.Y, 0)
+	}
+	if gap := tl.gaps[tl.Lines[len(tl.Lines)-1].paragraph+1]; gap > 0 {
+		tl.bounds.Max.Y += gap * lineHeight
+	}
+}
@@ -0,0 +1,47 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/oligo/gvcode/internal/buffer"
+)
+
+func TestLineGaps(t *testing.T) {
+	shaper, params, _ := setupShaper()
+	buf := buffer.NewTextSource()
+	buf.SetText([]byte("one\ntwo\nthree"))
+	tl := NewTextLayout(buf)
+	dims := tl.Layout(shaper, &params, 4, false)
+	lineHeight := tl.calcLineHeight(&params).Round()
+	starts := []int{tl.Paragraphs[0].StartY, tl.Paragraphs[1].StartY, tl.Paragraphs[2].StartY}
+
+	tl.SetLineGaps(map[int]int{0: 1, 2: 2, 3: 1})
+	gapped := tl.Layout(shaper, &params, 4, false)
+	for i, gap := range []int{1, 1, 3} {
+		if got, want := tl.Paragraphs[i].StartY, starts[i]+gap*lineHeight; got != want {
+			t.Errorf("paragraph %d starts at %d, want %d", i, got, want)
+		}
+	}
+	if got, want := gapped.Size.Y, dims.Size.Y+4*lineHeight; got != want {
+		t.Errorf("got height %d, want %d", got, want)
+	}
+
+	// the caret positions follow the lines.
+	pos, _ := tl.ClosestToRune(8)
+	if pos.Y != tl.Paragraphs[2].StartY {
+		t.Errorf("got the position of the third line at %d, want %d", pos.Y, tl.Paragraphs[2].StartY)
+	}
+
+	// editing a line keeps the gaps.
+	buf.Replace(0, 0, "x")
+	tl.Layout(shaper, &params, 4, false)
+	if got, want := tl.Paragraphs[2].StartY, starts[2]+3*lineHeight; got != want {
+		t.Errorf("paragraph 2 starts at %d after the edit, want %d", got, want)
+	}
+
+	tl.SetLineGaps(nil)
+	tl.Layout(shaper, &params, 4, false)
+	if got := tl.Paragraphs[2].StartY; got != starts[2] {
+		t.Errorf("paragraph 2 starts at %d without gaps, want %d", got, starts[2])
+	}
+}
@@ -0,0 +1,162 @@
+package layout
+
+import (
+	"container/list"
+
+	"gioui.org/text"
+)
+
+// defaultGlyphCacheSize is the default number of paragraphs kept by the
+// glyph cache.
+const defaultGlyphCacheSize = 4096
+
+// GlyphCacheStats reports the activity of the glyph cache of a layout.
+type GlyphCacheStats struct {
+	// Hits is the number of paragraphs laid out from the cache, and Misses
+	// the number of paragraphs shaped.
+	Hits, Misses int
+	// Evictions is the number of paragraphs dropped to make room for the
+	// new ones.
+	Evictions int
+	// Entries is the number of paragraphs in the cache, up to Capacity.
+	Entries, Capacity int
+}
+
+// glyphKey identifies the shaping result of a paragraph: its text, and the
+// layout configuration it is shaped with.
+type glyphKey struct {
+	text  string
+	shape shapeKey
+	// last is true for the last paragraph of the document.
+	last bool
+}
+
+type glyphEntry struct {
+	key       glyphKey
+	lines     []Line
+	graphemes []int
+}
+
+// glyphCache memoizes the shaped lines of paragraphs by their content, with
+// a least recently used eviction policy. Unlike shapeCache, which reuses the
+// paragraphs not edited since the previous layout, it finds the paragraphs
+// shaped before wherever they are in the document, e.g., after undoing an
+// edit, moving lines, or switching the width back and forth, and the
+// repeated lines like closing braces.
+type glyphCache struct {
+	// capacity is the maximum number of entries. The default size is used if
+	// it is 0, and the cache is disabled if it is negative.
+	capacity int
+	entries  map[glyphKey]*list.Element
+	// lru is ordered from the most recently used entry.
+	lru   list.List
+	stats GlyphCacheStats
+}
+
+func (c *glyphCache) size() int {
+	if c.capacity == 0 {
+		return defaultGlyphCacheSize
+	}
+	return max(c.capacity, 0)
+}
+
+// get returns a copy of the lines and the grapheme clusters shaped for key.
+// The copy has its own glyphs, as the positions of the glyphs are set in
+// place when the lines are laid out.
+func (c *glyphCache) get(key glyphKey) ([]Line, []int, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, nil, false
+	}
+	c.stats.Hits++
+	c.lru.MoveToFront(elem)
+	entry := elem.Value.(*glyphEntry)
+	return cloneLines(entry.lines), entry.graphemes, true
+}
+
+// put adds the lines and the grapheme clusters shaped for key, evicting the
+// least recently used entries beyond the capacity. The cache keeps lines,
+// which must not be modified except for the positions of their glyphs.
+func (c *glyphCache) put(key glyphKey, lines []Line, graphemes []int) {
+	size := c.size()
+	if size == 0 {
+		return
+	}
+	if c.entries == nil {
+		c.entries = make(map[glyphKey]*list.Element)
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&glyphEntry{key: key, lines: lines, graphemes: graphemes})
+	for c.lru.Len() > size {
+		c.evict()
+	}
+}
+
+func (c *glyphCache) evict() {
+	elem := c.lru.Back()
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*glyphEntry).key)
+	c.stats.Evictions++
+}
+
+// setCapacity sets the maximum number of entries, evicting the ones beyond it.
+func (c *glyphCache) setCapacity(capacity int) {
+	c.capacity = capacity
+	for c.lru.Len() > c.size() {
+		c.evict()
+	}
+}
+
+func (c *glyphCache) statistics() GlyphCacheStats {
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.Capacity = c.size()
+	return stats
+}
+
+// cloneLines copies lines with their glyphs.
+func cloneLines(lines []Line) []Line {
+	n := 0
+	for _, line := range lines {
+		n += len(line.Glyphs)
+	}
+	glyphs := make([]text.Glyph, 0, n)
+	pointers := make([]*text.Glyph, n)
+
+	cloned := make([]Line, len(lines))
+	for i, line := range lines {
+		start := len(glyphs)
+		for _, gl := range line.Glyphs {
+			glyphs = append(glyphs, *gl)
+		}
+		for j := range line.Glyphs {
+			pointers[start+j] = &glyphs[start+j]
+		}
+		line.Glyphs = pointers[start : start+len(line.Glyphs) : start+len(line.Glyphs)]
+		line.OriginalGlyphPositions = nil
+		cloned[i] = line
+	}
+	return cloned
+}
+
+// GlyphCacheStats returns the statistics of the glyph cache.
+func (tl *TextLayout) GlyphCacheStats() GlyphCacheStats {
+	return tl.glyphs.statistics()
+}
+
+// ResetGlyphCacheStats clears the counters of the glyph cache.
+func (tl *TextLayout) ResetGlyphCacheStats() {
+	tl.glyphs.stats = GlyphCacheStats{}
+}
+
+// SetGlyphCacheSize sets the maximum number of paragraphs kept by the glyph
+// cache. The default size is used if size is 0, and the cache is disabled if
+// size is negative.
+func (tl *TextLayout) SetGlyphCacheSize(size int) {
+	tl.glyphs.setCapacity(size)
+}
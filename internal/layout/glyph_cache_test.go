@@ -0,0 +1,100 @@
+package layout
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oligo/gvcode/internal/buffer"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestGlyphCacheEviction(t *testing.T) {
+	var c glyphCache
+	c.setCapacity(2)
+	key := func(text string) glyphKey { return glyphKey{text: text} }
+
+	c.put(key("a"), nil, nil)
+	c.put(key("b"), nil, nil)
+	// a is used more recently than b.
+	c.get(key("a"))
+	c.put(key("c"), nil, nil)
+
+	if _, _, ok := c.get(key("b")); ok {
+		t.Error("the least recently used entry is not evicted")
+	}
+	for _, text := range []string{"a", "c"} {
+		if _, _, ok := c.get(key(text)); !ok {
+			t.Errorf("entry %q is evicted", text)
+		}
+	}
+
+	want := GlyphCacheStats{Hits: 3, Misses: 1, Evictions: 1, Entries: 2, Capacity: 2}
+	if got := c.statistics(); got != want {
+		t.Errorf("got stats %+v, want %+v", got, want)
+	}
+
+	c.setCapacity(1)
+	if got := c.statistics(); got.Entries != 1 || got.Evictions != 2 {
+		t.Errorf("got stats %+v after shrinking the cache", got)
+	}
+	c.setCapacity(-1)
+	c.put(key("d"), nil, nil)
+	if got := c.statistics(); got.Entries != 0 || got.Capacity != 0 {
+		t.Errorf("got stats %+v for a disabled cache", got)
+	}
+}
+
+func TestGlyphCacheRepeatedLines(t *testing.T) {
+	shaper, params, spaceGlyph := setupShaper()
+	params.MaxWidth = spaceGlyph.Advance.Mul(fixed.I(20)).Ceil()
+
+	text := strings.Repeat("func f() {\n\treturn\n}\n\n", 10)
+	src := buffer.NewTextSource()
+	src.SetText([]byte(text))
+	tl := NewTextLayout(src)
+	tl.Layout(shaper, &params, 4, true)
+
+	// the 40 paragraphs have 4 distinct contents, and the last one is shaped
+	// on its own as it keeps the line after the final line break.
+	if stats := tl.GlyphCacheStats(); stats.Misses != 5 || stats.Hits != 35 {
+		t.Errorf("got stats %+v, want 5 misses and 35 hits", stats)
+	}
+	assertSameLayout(t, &tl, src, true, nil)
+
+	// the glyphs of the repeated paragraphs are not shared.
+	first, second := tl.Lines[0].Glyphs[0], tl.Lines[4].Glyphs[0]
+	if first == second || first.Y == second.Y {
+		t.Errorf("the repeated paragraphs share their glyphs")
+	}
+}
+
+func TestGlyphCacheRelayout(t *testing.T) {
+	shaper, params, spaceGlyph := setupShaper()
+	params.MaxWidth = spaceGlyph.Advance.Mul(fixed.I(20)).Ceil()
+
+	src := buffer.NewTextSource()
+	src.SetText([]byte(incrementalText(10)))
+	tl := NewTextLayout(src)
+	tl.Layout(shaper, &params, 4, true)
+	misses := tl.GlyphCacheStats().Misses
+
+	// switching the wrapping back and forth shapes the paragraphs once per
+	// configuration.
+	tl.Layout(shaper, &params, 4, false)
+	tl.Layout(shaper, &params, 4, true)
+	if got := tl.GlyphCacheStats().Misses - misses; got != 11 {
+		t.Errorf("got %d paragraphs shaped again, want 11", got)
+	}
+	assertSameLayout(t, &tl, src, true, nil)
+
+	// undoing an edit restores the paragraphs shaped before.
+	misses = tl.GlyphCacheStats().Misses
+	src.Replace(5, 5, "edit")
+	tl.Layout(shaper, &params, 4, true)
+	src.Undo()
+	tl.Layout(shaper, &params, 4, true)
+	if got := tl.GlyphCacheStats().Misses - misses; got != 1 {
+		t.Errorf("got %d paragraphs shaped again, want only the edited one", got)
+	}
+	assertSameLayout(t, &tl, src, true, nil)
+}
@@ -0,0 +1,204 @@
+package layout
+
+import (
+	"io"
+
+	"gioui.org/text"
+	"github.com/oligo/gvcode/internal/buffer"
+)
+
+// paragraphShape is the shaping result of a paragraph kept across layouts.
+// The lines are kept as wrapped, before their offsets are computed, so that
+// they can be placed again when the paragraphs before them change.
+type paragraphShape struct {
+	// bytes and runes are the size of the paragraph, including its line
+	// break.
+	bytes, runes int
+	lineBreak    bool
+	// lines is nil if the paragraph has not been shaped, e.g., because it
+	// has always been folded or out of the window of the virtualized layout.
+	lines []Line
+	// graphemes are the ends of the grapheme clusters of the paragraph,
+	// relative to its start.
+	graphemes []int
+	// last is true if the paragraph was shaped as the last one of the
+	// document, which keeps the empty screen line after a final line break.
+	last bool
+}
+
+// shapeKey is the layout configuration the cached shapes depend on.
+type shapeKey struct {
+	shaper   *text.Shaper
+	params   text.Parameters
+	tabWidth int
+	wrapLine bool
+	indent   WrapIndent
+}
+
+// normalized drops the parameters which don't change the shapes.
+func (k shapeKey) normalized() shapeKey {
+	if !k.wrapLine {
+		// the width and the indentation only matter to wrapping.
+		k.params.MaxWidth, k.params.MinWidth = 0, 0
+		k.indent = WrapIndent{}
+	}
+	return k
+}
+
+// shapeCache tracks the paragraphs shaped by the previous layout, and the
+// paragraphs edited since then, so that only the dirty paragraphs are shaped
+// again. The dirty paragraphs are found by mapping the edit log of the text
+// source to the paragraphs of the previous layout.
+type shapeCache struct {
+	key shapeKey
+	// version and size are the version and the byte size of the source at
+	// the previous layout.
+	version int
+	size    int
+	// paragraphs are the paragraphs of the previous layout, one for each
+	// line of the document. next is a spare slice the paragraphs of the
+	// current layout are built in.
+	paragraphs []paragraphShape
+	next       []paragraphShape
+	// shaped is the number of paragraphs not reused from the previous layout,
+	// which are shaped or found in the glyph cache.
+	shaped int
+	// window is the window of the virtualized layout of the previous layout.
+	window layoutWindow
+}
+
+// dirtyParagraphs returns the clean paragraphs of the previous layout for the
+// source being laid out with key: the paragraphs before the edits, and the
+// ones after them, which start at suffixByte in the edited text. If the key
+// changed, the paragraphs are returned without their lines, so that they are
+// shaped again, while the ones out of the window of the virtualized layout
+// are estimated without reading them again.
+func (c *shapeCache) dirtyParagraphs(src buffer.TextSource, key shapeKey) (prefix, suffix []paragraphShape, suffixByte int) {
+	if key != c.key {
+		c.key = key
+		c.dropLines()
+	}
+
+	edits, ok := src.EditsSince(c.version)
+	if !ok {
+		return nil, nil, 0
+	}
+	if len(edits) == 0 {
+		return c.paragraphs, nil, 0
+	}
+
+	start, end, delta := dirtyRange(edits)
+	first := c.paragraphAt(start)
+	last := c.paragraphAt(end - delta)
+	if first < 0 || last < 0 {
+		return nil, nil, 0
+	}
+
+	for _, p := range c.paragraphs[:last+1] {
+		suffixByte += p.bytes
+	}
+	suffixByte += src.Size() - c.size
+	return c.paragraphs[:first], c.paragraphs[last+1:], suffixByte
+}
+
+// paragraphAt returns the index of the paragraph of the previous layout
+// containing the rune offset runeOff, or the last paragraph if runeOff is at
+// the end of the text. It returns -1 if there is no paragraph.
+func (c *shapeCache) paragraphAt(runeOff int) int {
+	off := 0
+	for i, p := range c.paragraphs {
+		off += p.runes
+		if runeOff < off {
+			return i
+		}
+	}
+	return len(c.paragraphs) - 1
+}
+
+// dropLines drops the lines of the paragraphs, keeping their sizes.
+func (c *shapeCache) dropLines() {
+	for i := range c.paragraphs {
+		c.paragraphs[i].lines = nil
+		c.paragraphs[i].last = false
+	}
+}
+
+// commit saves the paragraphs of the current layout for the next one.
+func (c *shapeCache) commit(src buffer.TextSource) {
+	c.paragraphs, c.next = c.next, c.paragraphs[:0]
+	c.version = src.Version()
+	c.size = src.Size()
+}
+
+// clear drops the cached shapes.
+func (c *shapeCache) clear() {
+	*c = shapeCache{}
+}
+
+// dirtyRange combines the edits into a single edit replacing the rune range
+// [start, end-delta) of the text before the edits with [start, end).
+func dirtyRange(edits []buffer.Edit) (start, end, delta int) {
+	for i, edit := range edits {
+		editDelta := edit.NewEnd - edit.OldEnd
+		if i == 0 {
+			start, end, delta = edit.Start, edit.NewEnd, editDelta
+			continue
+		}
+
+		// move the end of the range by the edit.
+		switch {
+		case end <= edit.Start:
+		case end >= edit.OldEnd:
+			end += editDelta
+		default:
+			end = edit.NewEnd
+		}
+		start = min(start, edit.Start)
+		end = max(end, edit.NewEnd)
+		delta += editDelta
+	}
+	return start, end, delta
+}
+
+// readParagraph reads the paragraph of the source starting at byteOff.
+// Reading the next paragraph continues from the previous one, while the other
+// offsets are sought.
+func (tl *TextLayout) readParagraph(byteOff int) string {
+	if byteOff != tl.readOff {
+		r := buffer.NewReader(tl.src)
+		r.Seek(int64(byteOff), io.SeekStart)
+		tl.reader.Reset(r)
+	}
+	text, _ := tl.reader.ReadString('\n')
+	tl.readOff = byteOff + len(text)
+	return text
+}
+
+// appendShape adds the cached lines and grapheme clusters of the paragraph
+// of index paraIdx starting at runeOff.
+func (tl *TextLayout) appendShape(shape paragraphShape, paraIdx int, runeOff int) {
+	for _, line := range shape.lines {
+		line.paragraph = paraIdx
+		tl.Lines = append(tl.Lines, line)
+	}
+
+	if len(tl.Graphemes) == 0 {
+		tl.Graphemes = append(tl.Graphemes, runeOff)
+	}
+	for _, g := range shape.graphemes {
+		tl.Graphemes = append(tl.Graphemes, runeOff+g)
+	}
+}
+
+// paragraphGraphemes returns the ends of the grapheme clusters of paragraph,
+// relative to its start.
+func (tl *TextLayout) paragraphGraphemes(paragraph []rune) []int {
+	tl.seg.Init(paragraph)
+	iter := tl.seg.GraphemeIterator()
+	var graphemes []int
+	for iter.Next() {
+		grapheme := iter.Grapheme()
+		graphemes = append(graphemes, grapheme.Offset+len(grapheme.Text))
+	}
+	return graphemes
+}
@@ -0,0 +1,272 @@
+package layout
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/oligo/gvcode/internal/buffer"
+	"github.com/oligo/gvcode/internal/folding"
+	"golang.org/x/image/math/fixed"
+)
+
+func incrementalText(lines int) string {
+	var sb strings.Builder
+	for i := range lines {
+		fmt.Fprintf(&sb, "line %d: שלום a paragraph wrapped over screen lines\n", i)
+	}
+	sb.WriteString("the last line")
+	return sb.String()
+}
+
+// assertSameLayout compares the layout of tl with a layout of the same text
+// from scratch.
+func assertSameLayout(t *testing.T, tl *TextLayout, src buffer.TextSource, wrapLine bool, fm *folding.Manager) {
+	t.Helper()
+	shaper, params, spaceGlyph := setupShaper()
+	params.MaxWidth = spaceGlyph.Advance.Mul(fixed.I(20)).Ceil()
+
+	fresh := NewTextLayout(src)
+	fresh.SetGlyphCacheSize(-1)
+	fresh.SetFoldManager(fm)
+	fresh.Layout(shaper, &params, 4, wrapLine)
+
+	if !slices.Equal(tl.Paragraphs, fresh.Paragraphs) {
+		t.Errorf("paragraphs differ from a layout from scratch")
+	}
+	if !slices.Equal(tl.Positions, fresh.Positions) {
+		t.Errorf("positions differ from a layout from scratch")
+	}
+	if !slices.Equal(tl.Graphemes, fresh.Graphemes) {
+		t.Errorf("graphemes differ from a layout from scratch")
+	}
+	if len(tl.Lines) != len(fresh.Lines) {
+		t.Fatalf("got %d lines, want %d", len(tl.Lines), len(fresh.Lines))
+	}
+	for i, line := range tl.Lines {
+		want := fresh.Lines[i]
+		if line.YOff != want.YOff || line.Width != want.Width || line.RuneOff != want.RuneOff || line.paragraph != want.paragraph {
+			t.Errorf("line %d: got %v, want %v", i, line, want)
+		}
+	}
+}
+
+func TestIncrementalLayout(t *testing.T) {
+	cases := []struct {
+		name string
+		edit func(src *buffer.PieceTable, lineOff func(int) int)
+		// shaped is the number of paragraphs expected to be shaped again.
+		shaped int
+	}{
+		{"type in a line", func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(lineOff(10)+3, lineOff(10)+3, "xyz")
+		}, 1},
+		{"break a line", func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(lineOff(10)+5, lineOff(10)+5, "\n")
+		}, 2},
+		{"join lines", func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(lineOff(11)-1, lineOff(11), "")
+		}, 1},
+		{"delete lines", func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(lineOff(10), lineOff(13), "")
+		}, 1},
+		{"edit the last line", func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(src.Len(), src.Len(), "\nmore")
+		}, 2},
+		{"edit several lines", func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(lineOff(30), lineOff(30), "edited ")
+			src.Replace(lineOff(5), lineOff(5), "new line\n")
+		}, 27},
+		{"undo", func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(lineOff(20), lineOff(20), "a\nb\n")
+			src.Undo()
+		}, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			shaper, params, spaceGlyph := setupShaper()
+			params.MaxWidth = spaceGlyph.Advance.Mul(fixed.I(20)).Ceil()
+
+			src := buffer.NewTextSource()
+			src.SetText([]byte(incrementalText(50)))
+			tl := NewTextLayout(src)
+			tl.Layout(shaper, &params, 4, true)
+			if tl.shapes.shaped != 51 {
+				t.Fatalf("got %d paragraphs shaped by the first layout, want 51", tl.shapes.shaped)
+			}
+
+			c.edit(src, func(line int) int { return tl.Paragraphs[line].RuneOff })
+			tl.Layout(shaper, &params, 4, true)
+			if tl.shapes.shaped != c.shaped {
+				t.Errorf("got %d paragraphs shaped again, want %d", tl.shapes.shaped, c.shaped)
+			}
+			assertSameLayout(t, &tl, src, true, nil)
+		})
+	}
+}
+
+func TestIncrementalLayoutFolded(t *testing.T) {
+	shaper, params, spaceGlyph := setupShaper()
+	params.MaxWidth = spaceGlyph.Advance.Mul(fixed.I(20)).Ceil()
+
+	src := buffer.NewTextSource()
+	src.SetText([]byte(foldedBidiText))
+	fm := folding.NewManager()
+	fm.AnalyzeLines(strings.Split(foldedBidiText, "\n"))
+	tl := NewTextLayout(src)
+	tl.SetFoldManager(fm)
+	tl.Layout(shaper, &params, 4, true)
+
+	// folded paragraphs are shaped when unfolded.
+	fm.CollapseFold(1)
+	tl.Layout(shaper, &params, 4, true)
+	fm.ExpandFold(1)
+	tl.Layout(shaper, &params, 4, true)
+	if tl.shapes.shaped != 0 {
+		t.Errorf("got %d paragraphs shaped again, want 0", tl.shapes.shaped)
+	}
+	assertSameLayout(t, &tl, src, true, fm)
+}
+
+func TestIncrementalLayoutParams(t *testing.T) {
+	shaper, params, spaceGlyph := setupShaper()
+	params.MaxWidth = spaceGlyph.Advance.Mul(fixed.I(20)).Ceil()
+
+	src := buffer.NewTextSource()
+	src.SetText([]byte(incrementalText(10)))
+	tl := NewTextLayout(src)
+	tl.Layout(shaper, &params, 4, true)
+
+	// the width doesn't matter without wrapping.
+	tl.Layout(shaper, &params, 4, false)
+	params.MaxWidth *= 2
+	tl.Layout(shaper, &params, 4, false)
+	if tl.shapes.shaped != 0 {
+		t.Errorf("got %d paragraphs shaped again, want 0", tl.shapes.shaped)
+	}
+
+	tl.Layout(shaper, &params, 4, true)
+	if tl.shapes.shaped != 11 {
+		t.Errorf("got %d paragraphs shaped again, want all the 11 paragraphs", tl.shapes.shaped)
+	}
+}
+
+func TestDirtyRange(t *testing.T) {
+	cases := []struct {
+		name  string
+		edits []buffer.Edit
+		want  [3]int
+	}{
+		{"single", []buffer.Edit{{Start: 5, OldEnd: 8, NewEnd: 6}}, [3]int{5, 6, -2}},
+		{"after", []buffer.Edit{{Start: 5, OldEnd: 5, NewEnd: 7}, {Start: 20, OldEnd: 20, NewEnd: 21}}, [3]int{5, 21, 3}},
+		{"before", []buffer.Edit{{Start: 20, OldEnd: 20, NewEnd: 22}, {Start: 5, OldEnd: 6, NewEnd: 9}}, [3]int{5, 25, 5}},
+		{"inside", []buffer.Edit{{Start: 5, OldEnd: 5, NewEnd: 15}, {Start: 8, OldEnd: 12, NewEnd: 8}}, [3]int{5, 11, 6}},
+		{"covering", []buffer.Edit{{Start: 5, OldEnd: 5, NewEnd: 10}, {Start: 2, OldEnd: 12, NewEnd: 3}}, [3]int{2, 3, -4}},
+	}
+
+	for _, c := range cases {
+		start, end, delta := dirtyRange(c.edits)
+		if got := [3]int{start, end, delta}; got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestEditedParagraphLayout(t *testing.T) {
+	cases := []struct {
+		name     string
+		wrapLine bool
+		edit     func(src *buffer.PieceTable, lineOff func(int) int)
+		// fast is true if the edit takes the fast path.
+		fast bool
+	}{
+		{"type a rune", false, func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(lineOff(10)+3, lineOff(10)+3, "x")
+		}, true},
+		{"delete a rune", false, func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(lineOff(10)+3, lineOff(10)+4, "")
+		}, true},
+		{"type in the first line", false, func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(0, 0, "xy")
+		}, true},
+		{"type in the last line", false, func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(src.Len(), src.Len(), "z")
+		}, true},
+		{"type several times", false, func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(lineOff(20), lineOff(20), "a")
+			src.Replace(lineOff(20)+1, lineOff(20)+1, "b")
+		}, true},
+		{"shorten the widest line", false, func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(lineOff(10)+5, lineOff(11)-1, "")
+		}, true},
+		{"type in a wrapped line", true, func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(lineOff(10)+1, lineOff(10)+2, "x")
+		}, true},
+		{"wrap a new screen line", true, func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(lineOff(10)+3, lineOff(10)+3, strings.Repeat("x", 30))
+		}, false},
+		{"break a line", false, func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(lineOff(10)+5, lineOff(10)+5, "\n")
+		}, false},
+		{"join lines", false, func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(lineOff(11)-1, lineOff(11), "")
+		}, false},
+		{"edit two lines", false, func(src *buffer.PieceTable, lineOff func(int) int) {
+			src.Replace(lineOff(10), lineOff(10), "a")
+			src.Replace(lineOff(30), lineOff(30), "b")
+		}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			shaper, params, spaceGlyph := setupShaper()
+			params.MaxWidth = spaceGlyph.Advance.Mul(fixed.I(20)).Ceil()
+
+			src := buffer.NewTextSource()
+			src.SetText([]byte(incrementalText(50)))
+			tl := NewTextLayout(src)
+			tl.Layout(shaper, &params, 4, c.wrapLine)
+
+			c.edit(src, func(line int) int { return tl.Paragraphs[line].RuneOff })
+			fast := tl.layoutEditedParagraph(shaper, &params, 4, c.wrapLine)
+			if fast != c.fast {
+				t.Fatalf("fast path: got %v, want %v", fast, c.fast)
+			}
+			if !fast {
+				tl.Layout(shaper, &params, 4, c.wrapLine)
+			}
+			assertSameLayout(t, &tl, src, c.wrapLine, nil)
+
+			fresh := NewTextLayout(src)
+			fresh.Layout(shaper, &params, 4, c.wrapLine)
+			if tl.bounds != fresh.bounds || tl.baseline != fresh.baseline {
+				t.Errorf("bounds: got %v, want %v", tl.bounds, fresh.bounds)
+			}
+		})
+	}
+}
+
+func TestEditedParagraphLayoutFinalBreak(t *testing.T) {
+	shaper, params, _ := setupShaper()
+	src := buffer.NewTextSource()
+	src.SetText([]byte(incrementalText(10) + "\n"))
+	tl := NewTextLayout(src)
+	tl.Layout(shaper, &params, 4, false)
+
+	// the paragraphs before the final line break take the fast path, unlike
+	// the one ending with it.
+	src.Replace(tl.Paragraphs[5].RuneOff, tl.Paragraphs[5].RuneOff, "x")
+	if !tl.layoutEditedParagraph(shaper, &params, 4, false) {
+		t.Fatal("expected the fast path")
+	}
+	assertSameLayout(t, &tl, src, false, nil)
+
+	src.Replace(tl.Paragraphs[10].RuneOff, tl.Paragraphs[10].RuneOff, "x")
+	if tl.layoutEditedParagraph(shaper, &params, 4, false) {
+		t.Fatal("expected a full layout")
+	}
+	tl.Layout(shaper, &params, 4, false)
+	assertSameLayout(t, &tl, src, false, nil)
+}
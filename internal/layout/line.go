@@ -6,6 +6,7 @@ import (
 	"iter"
 
 	"gioui.org/text"
+	"github.com/oligo/gvcode/painter"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -24,6 +25,27 @@ type Line struct {
 	// OriginalGlyphPositions stores the original glyph positions before color offsets were applied.
 	// This is used by color indicators to determine where to render the indicators.
 	OriginalGlyphPositions []fixed.Int26_6
+
+	// paragraph is the index of the paragraph the line belongs to.
+	paragraph int
+	// hidden is true if the paragraph is folded. Hidden lines keep their
+	// runes, but have no glyphs and take no vertical space.
+	hidden bool
+	// estimated is the estimated number of screen lines of a paragraph which
+	// is not shaped by the virtualized layout, and zero for shaped lines.
+	estimated int
+	// paragraphBreak is true if the runes of a placeholder line end with a
+	// line break.
+	paragraphBreak bool
+	// indent is the indentation of a continuation line of a wrapped
+	// paragraph, which is included in XOff.
+	indent fixed.Int26_6
+}
+
+// placeholder reports whether the line stands for a paragraph which is not
+// shaped.
+func (li *Line) placeholder() bool {
+	return li.hidden || li.estimated > 0
 }
 
 func (li Line) String() string {
@@ -196,26 +218,16 @@ func (li *Line) bounds() image.Rectangle {
 	}
 }
 
-func (li *Line) GetGlyphs(offset, count int) []text.Glyph {
-	if count <= 0 {
-		return []text.Glyph{}
-	}
-
-	out := make([]text.Glyph, count)
-	for idx, gl := range li.Glyphs[offset : offset+count] {
-		out[idx] = *gl
-	}
-
-	return out
-}
-
-func (li *Line) All() iter.Seq[text.Glyph] {
-	return func(yield func(text.Glyph) bool) {
-		for _, gl := range li.Glyphs {
-			if !yield(*gl) {
-				return
-			}
-		}
+// PainterLine returns the line to paint with the painter package.
+func (li *Line) PainterLine() painter.Line {
+	return painter.Line{
+		XOff:    li.XOff,
+		YOff:    li.YOff,
+		Ascent:  li.Ascent,
+		Descent: li.Descent,
+		Glyphs:  li.Glyphs,
+		Runes:   li.Runes,
+		RuneOff: li.RuneOff,
 	}
 }
 
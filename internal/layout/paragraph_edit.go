@@ -0,0 +1,219 @@
+package layout
+
+import (
+	"image"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gioui.org/text"
+)
+
+// layoutEditedParagraph is the fast path of Layout for the edits confined to
+// a paragraph which keep its number of screen lines, like typing in a line.
+// Only the edited paragraph is shaped and indexed again, and the rune offsets
+// of the text after it are shifted, instead of laying out the whole document
+// again. It reports whether the layout is updated, or a full layout is
+// needed.
+func (tl *TextLayout) layoutEditedParagraph(shaper *text.Shaper, params *text.Parameters, tabWidth int, wrapLine bool) bool {
+	c := &tl.shapes
+	size := tl.src.Size()
+	// a final line break adds an empty paragraph to the layout.
+	if shaper == nil || size == 0 || *params != tl.params || len(c.paragraphs) == 0 || len(tl.Paragraphs)-len(c.paragraphs) > 1 {
+		return false
+	}
+	// the color indicators and the folds change the layout of the lines
+	// without editing them.
+	if len(tl.colorOffsets) > 0 || (tl.foldManager != nil && tl.foldManager.HasCollapsed()) {
+		return false
+	}
+	// changing the gaps and the fold transition move the lines vertically.
+	if tl.gapsChanged || tl.transition.active {
+		return false
+	}
+	if tl.window.enabled != c.window.enabled || tl.window.minY != c.window.minY || tl.window.maxY != c.window.maxY {
+		return false
+	}
+	key := shapeKey{shaper, tl.params, tabWidth, wrapLine, tl.wrapper.indent}.normalized()
+	if key != c.key {
+		return false
+	}
+
+	edits, ok := tl.src.EditsSince(c.version)
+	if !ok || len(edits) == 0 {
+		return false
+	}
+	start, end, delta := dirtyRange(edits)
+	idx := c.paragraphAt(start)
+	if idx < 0 || c.paragraphAt(end-delta) != idx {
+		return false
+	}
+
+	// the lines of the paragraph must be shaped.
+	first := sort.Search(len(tl.Lines), func(i int) bool { return tl.Lines[i].paragraph >= idx })
+	last := first
+	for last < len(tl.Lines) && tl.Lines[last].paragraph == idx {
+		if tl.Lines[last].placeholder() {
+			return false
+		}
+		last++
+	}
+	if first == last {
+		return false
+	}
+
+	old := c.paragraphs[idx]
+	if old.last && old.lineBreak {
+		return false
+	}
+	byteOff, runeOff := 0, 0
+	for _, p := range c.paragraphs[:idx] {
+		byteOff += p.bytes
+		runeOff += p.runes
+	}
+
+	// the edits must not add or remove line breaks.
+	tl.readOff = -1
+	paragraph := tl.readParagraph(byteOff)
+	isLast := byteOff+len(paragraph) == size
+	if len(paragraph) != old.bytes+size-c.size || strings.HasSuffix(paragraph, "\n") != old.lineBreak || isLast != old.last {
+		return false
+	}
+	runes := utf8.RuneCountInString(paragraph)
+	runeDelta := runes - old.runes
+	if !tl.window.pinsShaped(tl, runeOff, runeOff+runes, runeDelta) {
+		return false
+	}
+
+	glyphKey := glyphKey{text: paragraph, shape: key, last: isLast}
+	lines, graphemes, ok := tl.glyphs.get(glyphKey)
+	if !ok {
+		lines = tl.shapeParagraph(shaper, paragraph, isLast, tabWidth, wrapLine)
+		graphemes = tl.paragraphGraphemes([]rune(paragraph))
+		tl.glyphs.put(glyphKey, lines, graphemes)
+	}
+	// the lines after the paragraph keep their vertical offsets if it keeps
+	// its number of screen lines.
+	if len(lines) != last-first {
+		return false
+	}
+	for i, line := range lines {
+		if line.Ascent != tl.Lines[first+i].Ascent || line.Descent != tl.Lines[first+i].Descent {
+			return false
+		}
+	}
+
+	c.paragraphs[idx] = paragraphShape{
+		bytes:     len(paragraph),
+		runes:     runes,
+		lineBreak: old.lineBreak,
+		lines:     lines,
+		graphemes: graphemes,
+		last:      isLast,
+	}
+	c.version = tl.src.Version()
+	c.size = size
+	c.shaped = 1
+
+	// replace the lines, keeping their vertical offsets.
+	shrunk := false
+	off := runeOff
+	for i, line := range lines {
+		prev := tl.Lines[first+i]
+		line.paragraph = idx
+		tl.Lines[first+i] = line
+		tl.calculateLineXOffsets(first+i, off)
+		tl.Lines[first+i].adjustYOff(prev.YOff)
+		off += line.Runes
+
+		// the bounds of the document shrink if the line was at one of its
+		// edges, and is narrower now.
+		was, is := prev.bounds(), tl.Lines[first+i].bounds()
+		if (is.Min.X > was.Min.X && was.Min.X <= tl.bounds.Min.X) || (is.Max.X < was.Max.X && was.Max.X >= tl.bounds.Max.X) {
+			shrunk = true
+		}
+	}
+	for i := last; i < len(tl.Lines); i++ {
+		tl.Lines[i].RuneOff += runeDelta
+	}
+
+	// index the caret positions of the lines, and shift the positions after
+	// them.
+	posStart := sort.Search(len(tl.Positions), func(i int) bool { return tl.Positions[i].LineCol.Line >= first })
+	posEnd := sort.Search(len(tl.Positions), func(i int) bool { return tl.Positions[i].LineCol.Line >= last })
+	positions := tl.Positions
+	tl.Positions = nil
+	for i := first; i < last; i++ {
+		tl.indexGlyphs(i, tl.Lines[i])
+	}
+	n := len(tl.Positions)
+	tl.Positions = slices.Replace(positions, posStart, posEnd, tl.Positions...)
+	for i := posStart + n; i < len(tl.Positions); i++ {
+		tl.Positions[i].Runes += runeDelta
+	}
+
+	// the same for the grapheme clusters, which are the ends of the clusters
+	// of each paragraph.
+	gStart := sort.SearchInts(tl.Graphemes, runeOff+1)
+	gEnd := sort.SearchInts(tl.Graphemes, runeOff+old.runes+1)
+	shifted := make([]int, len(graphemes))
+	for i, g := range graphemes {
+		shifted[i] = runeOff + g
+	}
+	tl.Graphemes = slices.Replace(tl.Graphemes, gStart, gEnd, shifted...)
+	for i := gStart + len(shifted); i < len(tl.Graphemes); i++ {
+		tl.Graphemes[i] += runeDelta
+	}
+
+	var para Paragraph
+	for i := first; i < last; i++ {
+		para.Add(tl.Lines[i])
+	}
+	tl.Paragraphs[idx] = para
+	for i := idx + 1; i < len(tl.Paragraphs); i++ {
+		tl.Paragraphs[i].RuneOff += runeDelta
+	}
+
+	if shrunk {
+		tl.bounds = image.Rectangle{}
+		for _, line := range tl.Lines {
+			if !line.hidden {
+				tl.updateBounds(line)
+			}
+		}
+	} else {
+		for _, line := range tl.Lines[first:last] {
+			tl.updateBounds(line)
+		}
+	}
+	return true
+}
+
+// pinsShaped reports whether the paragraphs containing the pinned rune
+// offsets are shaped, after replacing the paragraph of runes [start, end),
+// whose size changes by delta. The paragraphs shaped by the virtualized
+// layout don't change otherwise.
+func (w *layoutWindow) pinsShaped(tl *TextLayout, start, end, delta int) bool {
+	if !w.enabled {
+		return true
+	}
+	for _, off := range w.pinned {
+		if off >= start && off < end {
+			continue
+		}
+		if off >= end {
+			// the offset before the edit.
+			off -= delta
+		}
+		i := sort.Search(len(tl.Paragraphs), func(i int) bool { return tl.Paragraphs[i].RuneOff > off }) - 1
+		if i < 0 {
+			continue
+		}
+		line := sort.Search(len(tl.Lines), func(j int) bool { return tl.Lines[j].paragraph >= i })
+		if line < len(tl.Lines) && tl.Lines[line].placeholder() {
+			return false
+		}
+	}
+	return true
+}
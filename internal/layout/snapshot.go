@@ -0,0 +1,73 @@
+package layout
+
+import (
+	"image"
+	"slices"
+
+	"gioui.org/text"
+)
+
+// Snapshot is an immutable copy of the result of a layout. The TextLayout
+// updates its lines and glyphs in place when the text is laid out again, so
+// readers on other goroutines, or keeping the geometry across frames, read a
+// snapshot instead.
+type Snapshot struct {
+	// Version is the version of the text source laid out.
+	Version int
+	// Lines are the screen lines. Their glyphs are owned by the snapshot.
+	Lines []Line
+	// Paragraphs are the paragraphs of the text, in document order.
+	Paragraphs []Paragraph
+	// Bounds is the logical bounding box of the text.
+	Bounds image.Rectangle
+	// Baseline is the baseline of the first line.
+	Baseline int
+}
+
+// Snapshot copies the result of the last layout. The glyphs of each line are
+// copied into a single array shared by the lines of the snapshot. The
+// placeholder lines of the virtualized layout have no glyphs to copy.
+func (tl *TextLayout) Snapshot() *Snapshot {
+	n := 0
+	for _, line := range tl.Lines {
+		n += len(line.Glyphs)
+	}
+	glyphs := make([]text.Glyph, 0, n)
+	ptrs := make([]*text.Glyph, n)
+
+	s := &Snapshot{
+		Version:    tl.src.Version(),
+		Lines:      slices.Clone(tl.Lines),
+		Paragraphs: slices.Clone(tl.Paragraphs),
+		Bounds:     tl.bounds,
+		Baseline:   tl.baseline,
+	}
+	for i := range s.Lines {
+		line := &s.Lines[i]
+		start := len(glyphs)
+		for _, g := range line.Glyphs {
+			glyphs = append(glyphs, *g)
+		}
+		for j := start; j < len(glyphs); j++ {
+			ptrs[j] = &glyphs[j]
+		}
+		line.Glyphs = ptrs[start:len(glyphs):len(glyphs)]
+		line.OriginalGlyphPositions = slices.Clone(line.OriginalGlyphPositions)
+	}
+	return s
+}
+
+// ParagraphAt returns the index of the paragraph containing the rune offset,
+// or -1 if the snapshot has no paragraph.
+func (s *Snapshot) ParagraphAt(runeOff int) int {
+	if len(s.Paragraphs) == 0 {
+		return -1
+	}
+	i, found := slices.BinarySearchFunc(s.Paragraphs, runeOff, func(p Paragraph, off int) int {
+		return p.RuneOff - off
+	})
+	if !found {
+		i--
+	}
+	return max(i, 0)
+}
@@ -0,0 +1,60 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/oligo/gvcode/internal/buffer"
+)
+
+func TestSnapshot(t *testing.T) {
+	shaper, params, _ := setupShaper()
+	src := buffer.NewTextSource()
+	src.SetText([]byte(incrementalText(10)))
+	tl := NewTextLayout(src)
+	tl.Layout(shaper, &params, 4, false)
+
+	s := tl.Snapshot()
+	if s.Version != src.Version() || len(s.Lines) != len(tl.Lines) || len(s.Paragraphs) != len(tl.Paragraphs) {
+		t.Fatalf("snapshot does not match the layout")
+	}
+	type glyphPos struct{ x, y int }
+	var before []glyphPos
+	for i, line := range s.Lines {
+		if len(line.Glyphs) != len(tl.Lines[i].Glyphs) {
+			t.Fatalf("line %d: got %d glyphs, want %d", i, len(line.Glyphs), len(tl.Lines[i].Glyphs))
+		}
+		for j, g := range line.Glyphs {
+			if g == tl.Lines[i].Glyphs[j] || *g != *tl.Lines[i].Glyphs[j] {
+				t.Fatalf("line %d: glyph %d is not a copy", i, j)
+			}
+			before = append(before, glyphPos{g.X.Round(), int(g.Y)})
+		}
+	}
+
+	// the next layouts do not modify the snapshot.
+	src.Replace(tl.Paragraphs[2].RuneOff, tl.Paragraphs[2].RuneOff, "inserted\n")
+	tl.Layout(shaper, &params, 4, false)
+	src.Replace(0, 0, "xx")
+	tl.Layout(shaper, &params, 4, false)
+	var after []glyphPos
+	for _, line := range s.Lines {
+		for _, g := range line.Glyphs {
+			after = append(after, glyphPos{g.X.Round(), int(g.Y)})
+		}
+	}
+	if len(after) != len(before) {
+		t.Fatalf("got %d glyphs, want %d", len(after), len(before))
+	}
+	for i := range before {
+		if before[i] != after[i] {
+			t.Fatalf("glyph %d moved from %v to %v", i, before[i], after[i])
+		}
+	}
+
+	if got := s.ParagraphAt(s.Paragraphs[3].RuneOff + 1); got != 3 {
+		t.Errorf("ParagraphAt: got %d, want 3", got)
+	}
+	if got := s.ParagraphAt(s.Paragraphs[3].RuneOff); got != 3 {
+		t.Errorf("ParagraphAt at the start of a paragraph: got %d, want 3", got)
+	}
+}
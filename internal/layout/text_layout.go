@@ -8,6 +8,7 @@ import (
 	"math"
 	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"gioui.org/layout"
 	"gioui.org/text"
@@ -41,11 +42,27 @@ type TextLayout struct {
 
 	// foldManager manages code folding regions.
 	foldManager *folding.Manager
-	// visibleParagraphs maps visible paragraph indices to actual paragraph indices.
-	visibleParagraphs []int
+	// window is the range shaped by the virtualized layout.
+	window layoutWindow
+	// shapes keeps the shaped paragraphs to shape only the edited ones
+	// again.
+	shapes shapeCache
+	// glyphs keeps the shaped paragraphs by content, to reuse them wherever
+	// they are in the document.
+	glyphs glyphCache
+	// readOff is the byte offset the reader reads from.
+	readOff int
 
 	// colorOffsets maps line number to character positions where color indicators should be inserted.
 	colorOffsets map[int]map[int]int
+	// gaps maps the paragraphs to the number of blank screen lines laid out
+	// above them.
+	gaps map[int]int
+	// gapsChanged disables the fast path of the edits until the next full
+	// layout, as the vertical offsets of the lines change.
+	gapsChanged bool
+	// transition animates the paragraphs after the folds are toggled.
+	transition foldTransition
 }
 
 func NewTextLayout(src buffer.TextSource) TextLayout {
@@ -78,7 +95,7 @@ func (tl *TextLayout) calcLineHeight(params *text.Parameters) fixed.Int26_6 {
 
 // reset prepares the index for reuse.
 func (tl *TextLayout) reset() {
-	tl.reader.Reset(buffer.NewReader(tl.src))
+	tl.readOff = -1
 	tl.Positions = tl.Positions[:0]
 	tl.Lines = tl.Lines[:0]
 	tl.Paragraphs = tl.Paragraphs[:0]
@@ -88,48 +105,121 @@ func (tl *TextLayout) reset() {
 }
 
 func (tl *TextLayout) Layout(shaper *text.Shaper, params *text.Parameters, tabWidth int, wrapLine bool) layout.Dimensions {
+	if tl.layoutEditedParagraph(shaper, params, tabWidth, wrapLine) {
+		dims := layout.Dimensions{Size: tl.bounds.Size()}
+		dims.Baseline = dims.Size.Y - tl.baseline
+		return dims
+	}
+
 	tl.reset()
 	tl.params = *params
-	paragraphCount := tl.src.Lines()
+	tl.gapsChanged = false
+	size := tl.src.Size()
 
 	if shaper == nil {
+		tl.shapes.clear()
 		tl.fakeLayout()
 	} else {
 		tl.spaceGlyph, _ = tl.shapeRune(shaper, tl.params, '\u0020')
-		if paragraphCount > 0 {
+		key := shapeKey{shaper, tl.params, tabWidth, wrapLine, tl.wrapper.indent}.normalized()
+		prefix, suffix, suffixByte := tl.shapes.dirtyParagraphs(tl.src, key)
+		tl.shapes.shaped = 0
+		next := tl.shapes.next[:0]
+
+		if size > 0 {
+			lineHeight := tl.calcLineHeight(&tl.params).Round()
+			// y estimates the top of the paragraph for the virtualized layout.
+			y := 0
 			runeOffset := 0
-			currentIdx := 0
-
-			for {
-				text, readErr := tl.reader.ReadString('\n')
-				// the last line returned by ReadBytes returns EOF and may have remaining bytes to process.
-				if len(text) > 0 {
-					tl.layoutNextParagraph(shaper, text, paragraphCount-1 == currentIdx, tabWidth, wrapLine)
-
-					paragraphRunes := []rune(text)
-					tl.indexGraphemeClusters(paragraphRunes, runeOffset)
-					runeOffset += len(paragraphRunes)
-					currentIdx++
-				}
 
-				if readErr != nil {
+			for idx, byteOff := 0, 0; byteOff < size; idx++ {
+				// reuse the paragraphs before and after the edits, and
+				// read the edited ones.
+				var shape paragraphShape
+				var text string
+				switch {
+				case idx < len(prefix):
+					shape = prefix[idx]
+				case byteOff == suffixByte && len(suffix) > 0:
+					shape = suffix[0]
+					suffix = suffix[1:]
+					suffixByte += shape.bytes
+				default:
+					text = tl.readParagraph(byteOff)
+					shape = paragraphShape{
+						bytes:     len(text),
+						runes:     utf8.RuneCountInString(text),
+						lineBreak: strings.HasSuffix(text, "\n"),
+					}
+				}
+				if shape.bytes == 0 {
+					// the source is shorter than expected.
 					break
 				}
+
+				isLast := byteOff+shape.bytes == size
+				height := tl.estimateLines(shape.runes, wrapLine) * lineHeight
+				if tl.foldManager == nil || tl.foldManager.IsLineVisible(idx) {
+					y += tl.gaps[idx] * lineHeight
+				}
+
+				switch {
+				case tl.foldManager != nil && !tl.foldManager.IsLineVisible(idx):
+					tl.appendPlaceholder(shape, idx, runeOffset, true, wrapLine)
+				case !tl.window.shapesParagraph(y, height, runeOffset, shape.runes, isLast):
+					tl.appendPlaceholder(shape, idx, runeOffset, false, wrapLine)
+					y += height
+				default:
+					if shape.lines == nil || shape.last != isLast {
+						if text == "" {
+							text = tl.readParagraph(byteOff)
+						}
+						glyphKey := glyphKey{text: text, shape: key, last: isLast}
+						var ok bool
+						if shape.lines, shape.graphemes, ok = tl.glyphs.get(glyphKey); !ok {
+							shape.lines = tl.shapeParagraph(shaper, text, isLast, tabWidth, wrapLine)
+							shape.graphemes = tl.paragraphGraphemes([]rune(text))
+							tl.glyphs.put(glyphKey, shape.lines, shape.graphemes)
+						}
+						shape.last = isLast
+						tl.shapes.shaped++
+					}
+					tl.appendShape(shape, idx, runeOffset)
+					y += len(shape.lines) * lineHeight
+				}
+
+				next = append(next, shape)
+				runeOffset += shape.runes
+				byteOff += shape.bytes
 			}
 		} else {
-			tl.layoutNextParagraph(shaper, "", true, tabWidth, wrapLine)
+			tl.Lines = append(tl.Lines, tl.shapeParagraph(shaper, "", true, tabWidth, wrapLine)...)
 		}
 
+		tl.shapes.next = next
+		tl.shapes.window = tl.window
+		tl.shapes.commit(tl.src)
+
 		tl.calculateXOffsets()
 		tl.calculateYOffsets()
+		tl.applyFoldTransition()
 
 		// build position index
 		for idx, line := range tl.Lines {
+			if line.hidden {
+				continue
+			}
+			if line.estimated > 0 {
+				tl.indexPlaceholder(idx, line)
+				tl.updateBounds(line)
+				continue
+			}
 			tl.indexGlyphs(idx, line)
 			tl.updateBounds(line)
 			// log.Printf("line[%d]: %s", idx, line)
 
 		}
+		tl.addGapBounds()
 
 		tl.trackLines(tl.Lines)
 	}
@@ -139,7 +229,8 @@ func (tl *TextLayout) Layout(shaper *text.Shaper, params *text.Parameters, tabWi
 	return dims
 }
 
-func (tl *TextLayout) layoutNextParagraph(shaper *text.Shaper, paragraph string, isLastParagrah bool, tabWidth int, wrapLine bool) {
+// shapeParagraph shapes and wraps the paragraph.
+func (tl *TextLayout) shapeParagraph(shaper *text.Shaper, paragraph string, isLastParagrah bool, tabWidth int, wrapLine bool) []Line {
 	params := tl.params
 	maxWidth := params.MaxWidth
 	params.MaxWidth = 1e6
@@ -153,7 +244,7 @@ func (tl *TextLayout) layoutNextParagraph(shaper *text.Shaper, paragraph string,
 		lines = lines[:len(lines)-1]
 	}
 
-	tl.Lines = append(tl.Lines, lines...)
+	return lines
 }
 
 func (tl *TextLayout) wrapParagraph(glyphs glyphIter, paragraph []rune, maxWidth int, tabWidth int, spaceGlyph *text.Glyph) []Line {
@@ -191,9 +282,16 @@ func (tl *TextLayout) calculateYOffsets() {
 	// Ceil the first value to ensure that we don't baseline it too close to the top of the
 	// viewport and cut off the top pixel.
 	currentY := tl.Lines[0].Ascent.Ceil()
+	// span is the number of screen lines taken by the previous visible line.
+	span := 0
 	for i := range tl.Lines {
-		if i > 0 {
-			currentY += lineHeight.Round()
+		// hidden lines share the baseline of the line before them.
+		if !tl.Lines[i].hidden {
+			currentY += span * lineHeight.Round()
+			if i == 0 || tl.Lines[i-1].paragraph != tl.Lines[i].paragraph {
+				currentY += tl.gaps[tl.Lines[i].paragraph] * lineHeight.Round()
+			}
+			span = max(tl.Lines[i].estimated, 1)
 		}
 		tl.Lines[i].adjustYOff(currentY)
 	}
@@ -202,17 +300,24 @@ func (tl *TextLayout) calculateYOffsets() {
 func (tl *TextLayout) calculateXOffsets() {
 	runeOff := 0
 	for i, line := range tl.Lines {
-		alignOff := tl.params.Alignment.Align(tl.params.Locale.Direction, line.Width, tl.params.MaxWidth)
+		tl.calculateLineXOffsets(i, runeOff)
+		runeOff += line.Runes
+	}
+}
 
-		// Get color offsets for this line
-		var lineColorOffsets map[int]int
-		if tl.colorOffsets != nil {
-			lineColorOffsets = tl.colorOffsets[i]
-		}
+// calculateLineXOffsets places the glyphs of the line of index i, starting
+// at runeOff, horizontally.
+func (tl *TextLayout) calculateLineXOffsets(i int, runeOff int) {
+	line := &tl.Lines[i]
+	alignOff := tl.params.Alignment.Align(tl.params.Locale.Direction, line.Width+line.indent, tl.params.MaxWidth) + line.indent
 
-		tl.Lines[i].recompute(alignOff, runeOff, lineColorOffsets)
-		runeOff += line.Runes
+	// Get color offsets for this line
+	var lineColorOffsets map[int]int
+	if tl.colorOffsets != nil {
+		lineColorOffsets = tl.colorOffsets[i]
 	}
+
+	line.recompute(alignOff, runeOff, lineColorOffsets)
 }
 
 // SetColorOffsets sets the color offsets for the text layout.
@@ -230,27 +335,11 @@ func (tl *TextLayout) shapeRune(shaper *text.Shaper, params text.Parameters, r r
 	return glyph, nil
 }
 
-func (tl *TextLayout) indexGraphemeClusters(paragraph []rune, runeOffset int) {
-	tl.seg.Init(paragraph)
-	iter := tl.seg.GraphemeIterator()
-	if len(tl.Graphemes) == 0 {
-		if iter.Next() {
-			grapheme := iter.Grapheme()
-			tl.Graphemes = append(tl.Graphemes,
-				runeOffset+grapheme.Offset,
-				runeOffset+grapheme.Offset+len(grapheme.Text),
-			)
-		}
-	}
-
-	for iter.Next() {
-		grapheme := iter.Grapheme()
-		tl.Graphemes = append(tl.Graphemes, runeOffset+grapheme.Offset+len(grapheme.Text))
-	}
-}
-
 func (tl *TextLayout) updateBounds(line Line) {
 	logicalBounds := line.bounds()
+	if line.estimated > 1 {
+		logicalBounds.Max.Y += (line.estimated - 1) * tl.calcLineHeight(&tl.params).Round()
+	}
 	if tl.bounds == (image.Rectangle{}) {
 		tl.baseline = int(line.YOff)
 		tl.bounds = logicalBounds
@@ -269,25 +358,44 @@ func (tl *TextLayout) trackLines(lines []Line) {
 	}
 
 	rng := Paragraph{}
-	paraIdx := 0
-	for _, l := range lines {
-		hasBreak := rng.Add(l)
-
-		if hasBreak {
-			// Check if this paragraph should be visible (not folded)
-			if tl.foldManager == nil || tl.foldManager.IsLineVisible(paraIdx) {
+	for i, l := range lines {
+		if l.estimated > 0 {
+			// An estimated paragraph has a single line standing for all of
+			// its screen lines.
+			endY := l.YOff + (l.estimated-1)*tl.calcLineHeight(&tl.params).Round()
+			tl.Paragraphs = append(tl.Paragraphs, Paragraph{
+				StartX: l.XOff, StartY: l.YOff, EndX: l.XOff + l.Width, EndY: endY,
+				Ascent: l.Ascent, Descent: l.Descent, Runes: l.Runes, RuneOff: l.RuneOff,
+			})
+			continue
+		}
+		if l.hidden {
+			// A folded paragraph is kept to map the document lines, but it
+			// takes no space: it is collapsed to the end of the line before it.
+			if rng == (Paragraph{}) {
+				rng = Paragraph{RuneOff: l.RuneOff, StartY: l.YOff, EndY: l.YOff}
+				if len(tl.Paragraphs) > 0 {
+					prev := tl.Paragraphs[len(tl.Paragraphs)-1]
+					rng.StartX, rng.EndX = prev.EndX, prev.EndX
+					rng.Ascent, rng.Descent = prev.Ascent, prev.Descent
+				}
+			}
+			rng.Runes += l.Runes
+			if i == len(lines)-1 || lines[i+1].paragraph != l.paragraph {
 				tl.Paragraphs = append(tl.Paragraphs, rng)
+				rng = Paragraph{}
 			}
-			paraIdx++
+			continue
+		}
+
+		if hasBreak := rng.Add(l); hasBreak {
+			tl.Paragraphs = append(tl.Paragraphs, rng)
 			rng = Paragraph{}
 		}
 	}
 
 	if rng != (Paragraph{}) {
-		// Check if this paragraph should be visible (not folded)
-		if tl.foldManager == nil || tl.foldManager.IsLineVisible(paraIdx) {
-			tl.Paragraphs = append(tl.Paragraphs, rng)
-		}
+		tl.Paragraphs = append(tl.Paragraphs, rng)
 	}
 }
 
@@ -498,13 +606,24 @@ func (tl *TextLayout) Locate(viewport image.Rectangle, startRune, endRune int, r
 		startRune, endRune = endRune, startRune
 	}
 	rects = rects[:0]
-	caretStart, _ := tl.ClosestToRune(startRune)
+	caretStart, startIdx := tl.ClosestToRune(startRune)
 	caretEnd, _ := tl.ClosestToRune(endRune)
+	// At the boundary of two runs, the selection starts at the position
+	// before the first selected glyph, i.e., the one of the following run.
+	if startRune < endRune && startIdx+1 < len(tl.Positions) {
+		if next := tl.Positions[startIdx+1]; next.Runes == caretStart.Runes && next.LineCol.Line == caretStart.LineCol.Line {
+			caretStart = next
+		}
+	}
 
 	for lineIdx := caretStart.LineCol.Line; lineIdx < len(tl.Lines); lineIdx++ {
 		if lineIdx > caretEnd.LineCol.Line {
 			break
 		}
+		if tl.Lines[lineIdx].placeholder() {
+			// folded and estimated lines have no glyphs.
+			continue
+		}
 		pos := tl.ClosestToLineCol(ScreenPos{Line: lineIdx})
 		if int(pos.Y)+pos.Descent.Ceil() < viewport.Min.Y {
 			continue
@@ -0,0 +1,152 @@
+package layout
+
+import (
+	"sort"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// layoutWindow is the vertical range of the document shaped by the
+// virtualized layout.
+type layoutWindow struct {
+	enabled    bool
+	minY, maxY int
+	// pinned are the rune offsets whose paragraphs are always shaped.
+	pinned []int
+}
+
+// SetWindow enables the virtualized layout: only the paragraphs overlapping
+// the vertical range [minY, maxY) of the document, the paragraphs containing
+// the pinned rune offsets and the last paragraph are shaped. The other
+// paragraphs get a single placeholder line with an estimated height and two
+// caret positions at their ends, which keeps the layout of huge documents
+// fast. The window is in the coordinates of the estimated layout, so it
+// should be derived from the viewport of the previous layout.
+func (tl *TextLayout) SetWindow(minY, maxY int, pinned ...int) {
+	tl.window = layoutWindow{enabled: true, minY: minY, maxY: maxY, pinned: pinned}
+}
+
+// ClearWindow disables the virtualized layout, shaping all the paragraphs.
+func (tl *TextLayout) ClearWindow() {
+	tl.window = layoutWindow{}
+}
+
+// Covers reports whether all the lines overlapping the vertical range
+// [minY, maxY) of the document are shaped, rather than estimated.
+func (tl *TextLayout) Covers(minY, maxY int) bool {
+	lineHeight := tl.calcLineHeight(&tl.params).Round()
+	// lines are sorted by their baselines, so skip the lines ending above
+	// minY, allowing for the estimated ones spanning multiple lines.
+	i := sort.Search(len(tl.Lines), func(i int) bool {
+		return tl.Lines[i].YOff+tl.Lines[i].Descent.Ceil() >= minY
+	})
+	for i = max(i-1, 0); i < len(tl.Lines); i++ {
+		line := tl.Lines[i]
+		top := line.YOff - line.Ascent.Ceil()
+		if top >= maxY {
+			break
+		}
+		bottom := line.YOff + line.Descent.Ceil() + max(line.estimated-1, 0)*lineHeight
+		if line.estimated > 0 && bottom > minY {
+			return false
+		}
+	}
+	return true
+}
+
+// Estimated reports whether the rune offset is inside of a paragraph whose
+// lines are estimated by the virtualized layout. The caret positions of such
+// a paragraph are only at its ends, so the paragraph should be pinned to the
+// window to place the caret inside of it.
+func (tl *TextLayout) Estimated(runeOff int) bool {
+	i := sort.Search(len(tl.Lines), func(i int) bool { return tl.Lines[i].RuneOff > runeOff }) - 1
+	if i < 0 {
+		return false
+	}
+	line := tl.Lines[i]
+	return line.estimated > 0 && runeOff > line.RuneOff && runeOff < line.RuneOff+line.Runes
+}
+
+// shapesParagraph reports whether the paragraph of runes starting at runeOff,
+// whose top is at y, is shaped by the virtualized layout.
+func (w *layoutWindow) shapesParagraph(y, height, runeOff, runes int, last bool) bool {
+	if !w.enabled || last {
+		return true
+	}
+	if y < w.maxY && y+height > w.minY {
+		return true
+	}
+	for _, off := range w.pinned {
+		if off >= runeOff && off < runeOff+runes {
+			return true
+		}
+	}
+	return false
+}
+
+// estimateLines estimates the number of screen lines of a paragraph of
+// runes, assuming the glyphs are as wide as a space.
+func (tl *TextLayout) estimateLines(runes int, wrapLine bool) int {
+	if !wrapLine || tl.params.MaxWidth <= 0 {
+		return 1
+	}
+	width := tl.spaceGlyph.Advance.Mul(fixed.I(runes)).Ceil()
+	return max((width+tl.params.MaxWidth-1)/tl.params.MaxWidth, 1)
+}
+
+// appendPlaceholder adds a line standing for a paragraph which is not shaped,
+// either because it is hidden by a collapsed fold, or because it is outside of
+// the window of the virtualized layout. A placeholder of a hidden paragraph
+// takes no space, while the other ones take the estimated number of lines.
+func (tl *TextLayout) appendPlaceholder(shape paragraphShape, paraIdx int, runeOff int, hidden bool, wrapLine bool) {
+	runes := shape.runes
+	line := Line{
+		Ascent:         tl.spaceGlyph.Ascent,
+		Descent:        tl.spaceGlyph.Descent,
+		Runes:          runes,
+		paragraph:      paraIdx,
+		hidden:         hidden,
+		paragraphBreak: shape.lineBreak,
+	}
+	if !hidden {
+		line.estimated = tl.estimateLines(runes, wrapLine)
+		line.Width = tl.spaceGlyph.Advance.Mul(fixed.I(runes))
+		if wrapLine {
+			line.Width = min(line.Width, fixed.I(tl.params.MaxWidth))
+		}
+	}
+	tl.Lines = append(tl.Lines, line)
+
+	// the caret is not moved inside of placeholders, so only their ends are
+	// grapheme boundaries.
+	if len(tl.Graphemes) == 0 {
+		tl.Graphemes = append(tl.Graphemes, runeOff)
+	}
+	tl.Graphemes = append(tl.Graphemes, runeOff+runes)
+}
+
+// indexPlaceholder adds the caret positions of the ends of an estimated line.
+func (tl *TextLayout) indexPlaceholder(idx int, line Line) {
+	lineHeight := tl.calcLineHeight(&tl.params).Round()
+	pos := CombinedPos{
+		Runes:   line.RuneOff,
+		LineCol: ScreenPos{Line: idx},
+		X:       line.XOff,
+		Y:       line.YOff,
+		Ascent:  line.Ascent,
+		Descent: line.Descent,
+	}
+	tl.insertPosition(pos)
+
+	end := line.Runes
+	if line.paragraphBreak {
+		end--
+	}
+	if end <= 0 {
+		return
+	}
+	pos.Runes += end
+	pos.LineCol.Col = end
+	pos.Y += (line.estimated - 1) * lineHeight
+	tl.insertPosition(pos)
+}
@@ -0,0 +1,146 @@
+package layout
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/oligo/gvcode/internal/buffer"
+)
+
+func virtualText(lines int) string {
+	var sb strings.Builder
+	for i := range lines {
+		fmt.Fprintf(&sb, "line %d of a document long enough to be virtualized\n", i)
+	}
+	return sb.String()
+}
+
+func layoutVirtual(t *testing.T, src string, wrapLine bool, window bool, pinned ...int) *TextLayout {
+	t.Helper()
+	shaper, params, _ := setupShaper()
+	params.MaxWidth = 300
+
+	buf := buffer.NewTextSource()
+	buf.SetText([]byte(src))
+	tl := NewTextLayout(buf)
+	if window {
+		tl.SetWindow(0, 200, pinned...)
+	}
+	tl.Layout(shaper, &params, 4, wrapLine)
+	return &tl
+}
+
+func TestVirtualLayout(t *testing.T) {
+	src := virtualText(1000)
+	pinned := len([]rune(src)) / 2
+	tl := layoutVirtual(t, src, true, true, pinned)
+
+	// the text ends with a line break, followed by an empty line.
+	if len(tl.Paragraphs) != 1001 {
+		t.Fatalf("got %d paragraphs, want 1001", len(tl.Paragraphs))
+	}
+	runeOff := 0
+	for i, p := range tl.Paragraphs {
+		if p.RuneOff != runeOff {
+			t.Fatalf("paragraph %d: got rune offset %d, want %d", i, p.RuneOff, runeOff)
+		}
+		runeOff += p.Runes
+	}
+
+	shaped := 0
+	for _, line := range tl.Lines {
+		if line.estimated == 0 {
+			shaped++
+		}
+	}
+	if shaped == 0 || shaped > 100 {
+		t.Errorf("got %d shaped lines, want the lines of the window only", shaped)
+	}
+	if !tl.Covers(0, 200) {
+		t.Error("the window is not shaped")
+	}
+	if tl.Covers(5000, 5200) {
+		t.Error("the paragraphs far from the window are shaped")
+	}
+
+	// the pinned paragraph and the last one are shaped.
+	for _, off := range []int{pinned, runeOff - 1} {
+		pos, _ := tl.ClosestToRune(off)
+		if pos.Runes != off || tl.Lines[pos.LineCol.Line].estimated > 0 {
+			t.Errorf("rune %d: got position at rune %d on an estimated line", off, pos.Runes)
+		}
+	}
+	if pos, _ := tl.ClosestToRune(math.MaxInt); pos.Runes != runeOff {
+		t.Errorf("got end position at rune %d, want %d", pos.Runes, runeOff)
+	}
+
+	// runes of estimated paragraphs resolve to the start of the paragraph.
+	para := tl.Paragraphs[700]
+	if pos, _ := tl.ClosestToRune(para.RuneOff + 3); pos.Runes != para.RuneOff || pos.Y != para.StartY {
+		t.Errorf("got position at rune %d, y %d, want the start of the paragraph at %d, y %d", pos.Runes, pos.Y, para.RuneOff, para.StartY)
+	}
+	if !tl.Estimated(para.RuneOff+3) || tl.Estimated(para.RuneOff) || tl.Estimated(pinned) {
+		t.Error("got the wrong runes inside of estimated paragraphs")
+	}
+}
+
+// countingSource counts the bytes read from the source.
+type countingSource struct {
+	buffer.TextSource
+	read int
+}
+
+func (s *countingSource) ReadAt(p []byte, off int64) (int, error) {
+	n, err := s.TextSource.ReadAt(p, off)
+	s.read += n
+	return n, err
+}
+
+func TestVirtualLayoutRelayout(t *testing.T) {
+	shaper, params, _ := setupShaper()
+	params.MaxWidth = 300
+
+	src := virtualText(1000)
+	buf := buffer.NewTextSource()
+	buf.SetText([]byte(src))
+	counter := &countingSource{TextSource: buf}
+	tl := NewTextLayout(counter)
+	tl.SetWindow(0, 200)
+	tl.Layout(shaper, &params, 4, true)
+
+	// a new width reshapes the window, and estimates the other paragraphs
+	// without reading them again.
+	counter.read = 0
+	params.MaxWidth = 200
+	tl.Layout(shaper, &params, 4, true)
+	if counter.read == 0 || counter.read > len(src)/10 {
+		t.Errorf("read %d bytes of %d, want the window only", counter.read, len(src))
+	}
+
+	want := NewTextLayout(buf)
+	want.Layout(shaper, &params, 4, true)
+	for i := 0; i < 5; i++ {
+		if got := tl.Paragraphs[i]; got.EndY-got.StartY != want.Paragraphs[i].EndY-want.Paragraphs[i].StartY {
+			t.Errorf("paragraph %d: got height %d, want it wrapped at the new width", i, got.EndY-got.StartY)
+		}
+	}
+}
+
+func TestVirtualLayoutWithoutWrapping(t *testing.T) {
+	src := virtualText(500)
+	full := layoutVirtual(t, src, false, false)
+	virtual := layoutVirtual(t, src, false, true)
+
+	// unwrapped paragraphs take a single line, so the estimates are exact.
+	for i, p := range virtual.Paragraphs {
+		want := full.Paragraphs[i]
+		if p.StartY != want.StartY || p.EndY != want.EndY || p.RuneOff != want.RuneOff || p.Runes != want.Runes {
+			t.Fatalf("paragraph %d: got %+v, want %+v", i, p, want)
+		}
+	}
+	if virtual.bounds.Dy() != full.bounds.Dy() {
+		t.Errorf("got height %d, want %d", virtual.bounds.Dy(), full.bounds.Dy())
+	}
+}
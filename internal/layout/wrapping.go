@@ -2,6 +2,7 @@ package layout
 
 import (
 	"iter"
+	"sort"
 
 	"gioui.org/text"
 	"github.com/go-text/typesetting/segmenter"
@@ -203,6 +204,21 @@ type lineWrapper struct {
 	currentLine     Line
 	glyphBuf        glyphReader
 	glyphs          []text.Glyph
+	// indent configures the indentation of the continuation lines.
+	indent WrapIndent
+	// lineIndent is the indentation of the line being wrapped.
+	lineIndent fixed.Int26_6
+}
+
+// WrapIndent configures the indentation of the continuation lines of wrapped
+// paragraphs.
+type WrapIndent struct {
+	// Preserve indents the continuation lines as much as the first line of
+	// the paragraph.
+	Preserve bool
+	// Hanging is the number of spaces the continuation lines are indented by,
+	// in addition to the preserved indentation.
+	Hanging int
 }
 
 func (w *lineWrapper) setup(nextGlyph func() (text.Glyph, bool), paragraph []rune, maxWidth int, tabWidth int, spaceGlyph *text.Glyph) {
@@ -211,6 +227,7 @@ func (w *lineWrapper) setup(nextGlyph func() (text.Glyph, bool), paragraph []run
 	w.tabStopInterval = spaceGlyph.Advance.Mul(fixed.I(tabWidth))
 	w.spaceGlyph = spaceGlyph
 	w.currentLine = Line{}
+	w.lineIndent = 0
 	w.glyphBuf.nextGlyph = nextGlyph
 	w.glyphBuf.reset()
 	w.glyphs = w.glyphs[:0]
@@ -231,6 +248,13 @@ func (w *lineWrapper) WrapParagraph(glyphsIter iter.Seq[text.Glyph], paragraph [
 			break
 		}
 
+		if len(lines) == 0 {
+			// the continuation lines are narrower by their indentation.
+			w.lineIndent = w.continuationIndent(l, paragraph)
+		} else {
+			l.XOff += w.lineIndent
+			l.indent = w.lineIndent
+		}
 		lines = append(lines, l)
 		w.currentLine = Line{}
 	}
@@ -238,6 +262,32 @@ func (w *lineWrapper) WrapParagraph(glyphsIter iter.Seq[text.Glyph], paragraph [
 	return lines
 }
 
+// continuationIndent returns the indentation of the continuation lines of the
+// paragraph starting with the first line. It is at most half of the maximum
+// width, leaving room for the text.
+func (w *lineWrapper) continuationIndent(first Line, paragraph []rune) fixed.Int26_6 {
+	indent := fixed.I(0)
+	if w.indent.Preserve {
+		leading := 0
+		for leading < len(paragraph) && (paragraph[leading] == ' ' || paragraph[leading] == '\t') {
+			leading++
+		}
+		// the tabs are expanded already.
+		runes := 0
+		for _, gl := range first.Glyphs {
+			if runes >= leading {
+				break
+			}
+			indent += gl.Advance
+			runes += int(gl.Runes)
+		}
+	}
+	if w.indent.Hanging > 0 {
+		indent += w.spaceGlyph.Advance.Mul(fixed.I(w.indent.Hanging))
+	}
+	return min(indent, fixed.I(w.maxWidth/2))
+}
+
 // wrapNextLine breaking lines by looking at the break opportunities defined in https://unicode.org/reports/tr14 first.
 // If no break opportunities can be found, it'll try to break at the grapheme cluster bounderies.
 func (w *lineWrapper) wrapNextLine(paragraph []rune) Line {
@@ -251,7 +301,7 @@ func (w *lineWrapper) wrapNextLine(paragraph []rune) Line {
 		lastOff := w.glyphBuf.offset
 		glyphs := w.readToNextBreak(nextBreak, paragraph)
 		// check if the line will exceeds the maxWidth if we put the glyph in the current line.
-		if w.currentLine.Width+advanceOfGlyphs(glyphs) > fixed.I(w.maxWidth) {
+		if w.currentLine.Width+advanceOfGlyphs(glyphs) > fixed.I(w.maxWidth)-w.lineIndent {
 			w.breaker.markPrevWordUnread()
 			w.glyphBuf.seekTo(lastOff)
 			break
@@ -275,7 +325,7 @@ func (w *lineWrapper) wrapNextLine(paragraph []rune) Line {
 		lastOff := w.glyphBuf.offset
 		glyphs := w.readToNextBreak(nextBreak, paragraph)
 		// check if the line will exceeds the maxWidth if we put the glyph in the current line.
-		if w.currentLine.Width+advanceOfGlyphs(glyphs) > fixed.I(w.maxWidth) {
+		if w.currentLine.Width+advanceOfGlyphs(glyphs) > fixed.I(w.maxWidth)-w.lineIndent {
 			w.breaker.markPrevGraphemeUnread()
 			w.glyphBuf.seekTo(lastOff)
 			break
@@ -348,3 +398,37 @@ func (w *lineWrapper) expandTabGlyph(lineWidth fixed.Int26_6, gl *text.Glyph) {
 	gl.Ascent = w.spaceGlyph.Ascent
 	gl.Descent = w.spaceGlyph.Descent
 }
+
+// SetWrapIndent sets the indentation of the continuation lines of wrapped
+// paragraphs, which is applied by the next layout.
+func (tl *TextLayout) SetWrapIndent(indent WrapIndent) {
+	indent.Hanging = max(indent.Hanging, 0)
+	tl.wrapper.indent = indent
+}
+
+// WrapIndent returns the indentation of the continuation lines of wrapped
+// paragraphs.
+func (tl *TextLayout) WrapIndent() WrapIndent {
+	return tl.wrapper.indent
+}
+
+// SoftBreaks returns the screen lines whose baseline is in [minY, maxY) and
+// which are continued on the next screen line, because their paragraph is
+// wrapped.
+func (tl *TextLayout) SoftBreaks(minY, maxY int) []Line {
+	start := sort.Search(len(tl.Lines), func(i int) bool {
+		return tl.Lines[i].YOff >= minY
+	})
+
+	var lines []Line
+	for i := start; i < len(tl.Lines)-1 && tl.Lines[i].YOff < maxY; i++ {
+		line := tl.Lines[i]
+		if line.placeholder() || len(line.Glyphs) == 0 || tl.Lines[i+1].placeholder() {
+			continue
+		}
+		if line.Glyphs[len(line.Glyphs)-1].Flags&text.FlagParagraphBreak == 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
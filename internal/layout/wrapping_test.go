@@ -8,6 +8,7 @@ import (
 
 	"gioui.org/font"
 	"gioui.org/text"
+	"github.com/oligo/gvcode/internal/buffer"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -63,3 +64,62 @@ func TestWrapParagraph(t *testing.T) {
 		})
 	}
 }
+
+func TestWrapIndent(t *testing.T) {
+	shaper, params, spaceGlyph := setupShaper()
+	params.MaxWidth = spaceGlyph.Advance.Mul(fixed.I(20)).Ceil()
+	space := spaceGlyph.Advance
+
+	paragraph := "\taaaa bbbb cccc dddd eeee ffff gggg hhhh\n"
+	src := buffer.NewTextSource()
+	src.SetText([]byte(paragraph + "short"))
+	tl := NewTextLayout(src)
+
+	for _, tc := range []struct {
+		indent WrapIndent
+		want   fixed.Int26_6
+	}{
+		{indent: WrapIndent{}, want: 0},
+		// the tab is expanded to 4 spaces.
+		{indent: WrapIndent{Preserve: true}, want: space * 4},
+		{indent: WrapIndent{Preserve: true, Hanging: 2}, want: space * 6},
+		{indent: WrapIndent{Hanging: 2}, want: space * 2},
+	} {
+		tl.SetWrapIndent(tc.indent)
+		tl.Layout(shaper, &params, 4, true)
+
+		wrapped := 0
+		for _, line := range tl.Lines {
+			if line.RuneOff >= len(paragraph) {
+				break
+			}
+			wrapped++
+			if line.XOff+line.Width > fixed.I(params.MaxWidth) {
+				t.Errorf("%+v: line at rune %d overflows", tc.indent, line.RuneOff)
+			}
+		}
+		if wrapped < 3 {
+			t.Fatalf("%+v: got %d lines, want the paragraph wrapped", tc.indent, wrapped)
+		}
+		for _, line := range tl.Lines[1:wrapped] {
+			if got := line.Glyphs[0].X; got != tc.want {
+				t.Errorf("%+v: continuation line starts at %v, want %v", tc.indent, got, tc.want)
+			}
+			if pos, _ := tl.ClosestToRune(line.RuneOff); pos.X != tc.want {
+				t.Errorf("%+v: caret at %v, want %v", tc.indent, pos.X, tc.want)
+			}
+		}
+
+		// every screen line of the first paragraph but its last is continued.
+		breaks := tl.SoftBreaks(0, tl.bounds.Max.Y)
+		if len(breaks) != wrapped-1 {
+			t.Errorf("%+v: got %d soft breaks, want %d", tc.indent, len(breaks), wrapped-1)
+		}
+	}
+
+	// the indentation only applies to wrapped lines.
+	tl.Layout(shaper, &params, 4, false)
+	if breaks := tl.SoftBreaks(0, tl.bounds.Max.Y); len(breaks) != 0 {
+		t.Errorf("got %d soft breaks without wrapping", len(breaks))
+	}
+}
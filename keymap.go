@@ -0,0 +1,448 @@
+package gvcode
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gioui.org/io/event"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+)
+
+// Command is a named editor action that can be bound to key strokes in a
+// Keymap.
+type Command struct {
+	// Name identifies the command, e.g., "duplicateLine".
+	Name string
+	// Run executes the command in the editor. It returns an EditorEvent if
+	// there is any.
+	Run func(gtx layout.Context, e *Editor) EditorEvent
+}
+
+// KeyStroke is a key pressed together with a set of modifiers.
+type KeyStroke struct {
+	Name      key.Name
+	Modifiers key.Modifiers
+}
+
+// keyAliases maps the names used in key binding strings to the key names.
+var keyAliases = map[string]key.Name{
+	"left":      key.NameLeftArrow,
+	"right":     key.NameRightArrow,
+	"up":        key.NameUpArrow,
+	"down":      key.NameDownArrow,
+	"return":    key.NameReturn,
+	"enter":     key.NameEnter,
+	"esc":       key.NameEscape,
+	"escape":    key.NameEscape,
+	"home":      key.NameHome,
+	"end":       key.NameEnd,
+	"backspace": key.NameDeleteBackward,
+	"delete":    key.NameDeleteForward,
+	"del":       key.NameDeleteForward,
+	"pageup":    key.NamePageUp,
+	"pagedown":  key.NamePageDown,
+	"tab":       key.NameTab,
+	"space":     key.NameSpace,
+}
+
+// keyDisplayNames are the names of the keys in KeyStroke.String.
+var keyDisplayNames = map[key.Name]string{
+	key.NameLeftArrow:      "Left",
+	key.NameRightArrow:     "Right",
+	key.NameUpArrow:        "Up",
+	key.NameDownArrow:      "Down",
+	key.NameReturn:         "Return",
+	key.NameEnter:          "Enter",
+	key.NameEscape:         "Esc",
+	key.NameHome:           "Home",
+	key.NameEnd:            "End",
+	key.NameDeleteBackward: "Backspace",
+	key.NameDeleteForward:  "Delete",
+	key.NamePageUp:         "PageUp",
+	key.NamePageDown:       "PageDown",
+}
+
+// ParseKeyStroke parses a key stroke like "Ctrl+Shift+K". Modifiers are
+// Ctrl, Shift, Alt, Cmd, Super, and the platform dependent Shortcut and
+// ShortcutAlt. Keys are letters, digits and symbols, F1 to F12, or one of
+// Left, Right, Up, Down, Enter, Return, Esc, Home, End, Backspace, Delete,
+// PageUp, PageDown, Tab and Space. Names are case-insensitive.
+func ParseKeyStroke(s string) (KeyStroke, error) {
+	var stroke KeyStroke
+
+	parts := strings.Split(s, "+")
+	if strings.HasSuffix(s, "++") {
+		// the "+" key.
+		parts = append(parts[:len(parts)-2], "+")
+	}
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if i < len(parts)-1 {
+			var mod key.Modifiers
+			switch strings.ToLower(part) {
+			case "ctrl", "control":
+				mod = key.ModCtrl
+			case "shift":
+				mod = key.ModShift
+			case "alt", "option":
+				mod = key.ModAlt
+			case "cmd", "command":
+				mod = key.ModCommand
+			case "super", "meta", "win":
+				mod = key.ModSuper
+			case "shortcut":
+				mod = key.ModShortcut
+			case "shortcutalt":
+				mod = key.ModShortcutAlt
+			default:
+				return KeyStroke{}, fmt.Errorf("invalid modifier %q in key stroke %q", part, s)
+			}
+			stroke.Modifiers |= mod
+			continue
+		}
+
+		switch {
+		case part == "":
+			return KeyStroke{}, fmt.Errorf("missing key in key stroke %q", s)
+		case keyAliases[strings.ToLower(part)] != "":
+			stroke.Name = keyAliases[strings.ToLower(part)]
+		default:
+			stroke.Name = key.Name(strings.ToUpper(part))
+		}
+	}
+
+	return stroke, nil
+}
+
+// String returns the key stroke in the form accepted by ParseKeyStroke.
+func (k KeyStroke) String() string {
+	var parts []string
+	if k.Modifiers.Contain(key.ModCtrl) {
+		parts = append(parts, "Ctrl")
+	}
+	if k.Modifiers.Contain(key.ModCommand) {
+		parts = append(parts, "Cmd")
+	}
+	if k.Modifiers.Contain(key.ModShift) {
+		parts = append(parts, "Shift")
+	}
+	if k.Modifiers.Contain(key.ModAlt) {
+		parts = append(parts, "Alt")
+	}
+	if k.Modifiers.Contain(key.ModSuper) {
+		parts = append(parts, "Super")
+	}
+
+	name := string(k.Name)
+	if display, ok := keyDisplayNames[k.Name]; ok {
+		name = display
+	}
+	return strings.Join(append(parts, name), "+")
+}
+
+// shiftedKeys maps the symbol and digit keys to the symbols typed with Shift
+// on a US keyboard. X11 and Wayland name a key pressed with Shift by its
+// shifted symbol, e.g., "{" for Shift+[, while the other platforms name it by
+// the key.
+var shiftedKeys = map[key.Name]key.Name{
+	"[": "{", "]": "}", "/": "?", "\\": "|", ",": "<", ".": ">", ";": ":", "'": "\"",
+	"-": "_", "=": "+", "`": "~", "1": "!", "2": "@", "3": "#", "4": "$", "5": "%",
+	"6": "^", "7": "&", "8": "*", "9": "(", "0": ")",
+}
+
+// unshiftedKeys is the inverse of shiftedKeys.
+var unshiftedKeys = func() map[key.Name]key.Name {
+	m := make(map[key.Name]key.Name, len(shiftedKeys))
+	for k, shifted := range shiftedKeys {
+		m[shifted] = k
+	}
+	return m
+}()
+
+// canonical returns the stroke with a shifted symbol replaced by its key
+// pressed with Shift, so that "Ctrl+{" and "Ctrl+Shift+[" are the same stroke.
+func (k KeyStroke) canonical() KeyStroke {
+	if name, ok := unshiftedKeys[k.Name]; ok {
+		return KeyStroke{Name: name, Modifiers: k.Modifiers | key.ModShift}
+	}
+	return k
+}
+
+// shiftVariant returns the stroke as named by the platforms naming the key
+// pressed with Shift differently: the shifted symbol of a key pressed with
+// Shift, or the key pressed with Shift of a shifted symbol.
+func (k KeyStroke) shiftVariant() (KeyStroke, bool) {
+	if name, ok := unshiftedKeys[k.Name]; ok {
+		return KeyStroke{Name: name, Modifiers: k.Modifiers | key.ModShift}, true
+	}
+	if name, ok := shiftedKeys[k.Name]; ok && k.Modifiers.Contain(key.ModShift) {
+		return KeyStroke{Name: name, Modifiers: k.Modifiers}, true
+	}
+	return KeyStroke{}, false
+}
+
+// sameStrokes reports whether the key strokes are the same, see canonical.
+func sameStrokes(a, b []KeyStroke) bool {
+	return slices.EqualFunc(a, b, func(x, y KeyStroke) bool { return x.canonical() == y.canonical() })
+}
+
+// parseKeys parses a sequence of key strokes separated by spaces, e.g.,
+// "Ctrl+K Ctrl+C".
+func parseKeys(keys string) ([]KeyStroke, error) {
+	fields := strings.Fields(keys)
+	if len(fields) == 0 {
+		return nil, errors.New("empty key binding")
+	}
+
+	strokes := make([]KeyStroke, 0, len(fields))
+	for _, f := range fields {
+		stroke, err := ParseKeyStroke(f)
+		if err != nil {
+			return nil, err
+		}
+		strokes = append(strokes, stroke)
+	}
+	return strokes, nil
+}
+
+func formatKeys(strokes []KeyStroke) string {
+	parts := make([]string, 0, len(strokes))
+	for _, s := range strokes {
+		parts = append(parts, s.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// KeyConflictError is returned when binding keys that are a prefix of an
+// existing binding, or the other way around. One of the bindings would never
+// be triggered.
+type KeyConflictError struct {
+	Keys     string
+	Existing string
+}
+
+func (e *KeyConflictError) Error() string {
+	return fmt.Sprintf("key binding %q conflicts with %q", e.Keys, e.Existing)
+}
+
+// Binding is a sequence of key strokes bound to a command.
+type Binding struct {
+	// Keys are the key strokes separated by spaces, e.g., "Ctrl+K Ctrl+C".
+	Keys    string
+	Command Command
+}
+
+type keyBinding struct {
+	strokes []KeyStroke
+	command Command
+}
+
+// Keymap is a table of key bindings dispatching key strokes to commands. A
+// binding is a single key stroke like "Ctrl+D", or a chord of key strokes
+// like "Ctrl+K Ctrl+C".
+type Keymap struct {
+	bindings []keyBinding
+	// strokes are the distinct key strokes used by the bindings.
+	strokes []KeyStroke
+}
+
+// NewKeymap creates an empty keymap.
+func NewKeymap() *Keymap {
+	return &Keymap{}
+}
+
+// Bind binds the keys to cmd, replacing the command bound to the same keys.
+// It is an error to bind keys that conflict with an existing binding.
+func (km *Keymap) Bind(keys string, cmd Command) error {
+	if cmd.Run == nil {
+		return fmt.Errorf("command %q has no Run function", cmd.Name)
+	}
+	strokes, err := parseKeys(keys)
+	if err != nil {
+		return err
+	}
+
+	for i, b := range km.bindings {
+		n := min(len(b.strokes), len(strokes))
+		if !sameStrokes(b.strokes[:n], strokes[:n]) {
+			continue
+		}
+		if len(b.strokes) == len(strokes) {
+			km.bindings[i].command = cmd
+			return nil
+		}
+		return &KeyConflictError{Keys: formatKeys(strokes), Existing: formatKeys(b.strokes)}
+	}
+
+	km.bindings = append(km.bindings, keyBinding{strokes: strokes, command: cmd})
+	km.updateStrokes()
+	return nil
+}
+
+// Unbind removes the binding of keys.
+func (km *Keymap) Unbind(keys string) error {
+	strokes, err := parseKeys(keys)
+	if err != nil {
+		return err
+	}
+
+	idx := slices.IndexFunc(km.bindings, func(b keyBinding) bool { return sameStrokes(b.strokes, strokes) })
+	if idx < 0 {
+		return fmt.Errorf("key binding %q not found", keys)
+	}
+	km.bindings = slices.Delete(km.bindings, idx, idx+1)
+	km.updateStrokes()
+	return nil
+}
+
+// Lookup returns the command bound to keys.
+func (km *Keymap) Lookup(keys string) (Command, bool) {
+	strokes, err := parseKeys(keys)
+	if err != nil {
+		return Command{}, false
+	}
+	cmd, exact, _ := km.match(strokes)
+	return cmd, exact
+}
+
+// Bindings returns the bindings of the keymap in the order they were bound.
+func (km *Keymap) Bindings() []Binding {
+	bindings := make([]Binding, 0, len(km.bindings))
+	for _, b := range km.bindings {
+		bindings = append(bindings, Binding{Keys: formatKeys(b.strokes), Command: b.command})
+	}
+	return bindings
+}
+
+// match looks up the sequence of key strokes. exact reports if the sequence
+// is bound to cmd, and prefix reports if it is the start of a chord.
+func (km *Keymap) match(strokes []KeyStroke) (cmd Command, exact, prefix bool) {
+	for _, b := range km.bindings {
+		if len(b.strokes) < len(strokes) || !sameStrokes(b.strokes[:len(strokes)], strokes) {
+			continue
+		}
+		if len(b.strokes) == len(strokes) {
+			return b.command, true, false
+		}
+		prefix = true
+	}
+	return Command{}, false, prefix
+}
+
+func (km *Keymap) updateStrokes() {
+	km.strokes = km.strokes[:0]
+	for _, b := range km.bindings {
+		for _, s := range b.strokes {
+			if !slices.Contains(km.strokes, s) {
+				km.strokes = append(km.strokes, s)
+			}
+		}
+	}
+}
+
+// Keymap returns the keymap of the editor. The built-in commands are bound
+// by default, and can be rebound by the application.
+func (e *Editor) Keymap() *Keymap {
+	if e.keymap == nil {
+		e.keymap = DefaultKeymap()
+	}
+	return e.keymap
+}
+
+// PendingKeys returns the key strokes typed so far of an incomplete chord,
+// e.g., "Ctrl+K", or an empty string if there is none.
+func (e *Editor) PendingKeys() string {
+	return formatKeys(e.pendingKeys)
+}
+
+// processKeymap dispatches the key strokes of the keymap to their commands.
+// Keys handled by the commands registered by RegisterCommand are skipped.
+// A stroke bound with a shifted symbol key also matches the key named by its
+// shifted symbol, and the other way around. Any other key pressed ends the
+// pending chord.
+func (e *Editor) processKeymap(gtx layout.Context) EditorEvent {
+	km := e.Keymap()
+	for _, stroke := range km.strokes {
+		if len(e.commands[stroke.Name]) > 0 {
+			continue
+		}
+
+		filters := []event.Filter{key.Filter{Focus: e, Name: stroke.Name, Required: stroke.Modifiers}}
+		if variant, ok := stroke.shiftVariant(); ok && len(e.commands[variant.Name]) == 0 &&
+			!slices.Contains(km.strokes, variant) {
+			filters = append(filters, key.Filter{Focus: e, Name: variant.Name, Required: variant.Modifiers})
+		}
+		for {
+			ev, ok := gtx.Event(filters...)
+			if !ok {
+				break
+			}
+
+			e.blinkStart = gtx.Now
+			ke, ok := ev.(key.Event)
+			if !ok || !gtx.Focused(e) || ke.State != key.Press {
+				continue
+			}
+
+			cmd, ok := e.dispatchKeyStroke(km, stroke)
+			if !ok {
+				continue
+			}
+			e.scrollCaret = true
+			e.scroller.Stop()
+			e.cancelCompletor()
+			if evt := cmd.Run(gtx, e); evt != nil {
+				return evt
+			}
+		}
+	}
+
+	if len(e.pendingKeys) > 0 {
+		// the keys not bound in the keymap end the chord.
+		for {
+			ev, ok := gtx.Event(key.Filter{Focus: e, Optional: allModifiers})
+			if !ok {
+				break
+			}
+			if ke, ok := ev.(key.Event); ok && ke.State == key.Press && !isModifierKey(ke.Name) {
+				e.pendingKeys = nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// allModifiers are all of the key modifiers.
+const allModifiers = key.ModCtrl | key.ModCommand | key.ModShift | key.ModAlt | key.ModSuper
+
+// isModifierKey reports whether name is the key of a modifier, pressed on
+// its own while typing a key stroke.
+func isModifierKey(name key.Name) bool {
+	switch name {
+	case key.NameCtrl, key.NameShift, key.NameAlt, key.NameSuper, key.NameCommand:
+		return true
+	}
+	return false
+}
+
+// dispatchKeyStroke appends stroke to the pending chord, and returns the
+// command if the chord is complete. A stroke not continuing the pending chord
+// starts a new one.
+func (e *Editor) dispatchKeyStroke(km *Keymap, stroke KeyStroke) (Command, bool) {
+	strokes := append(e.pendingKeys, stroke)
+	cmd, exact, prefix := km.match(strokes)
+	if !exact && !prefix && len(e.pendingKeys) > 0 {
+		strokes = []KeyStroke{stroke}
+		cmd, exact, prefix = km.match(strokes)
+	}
+
+	e.pendingKeys = nil
+	if prefix {
+		e.pendingKeys = strokes
+	}
+	return cmd, exact
+}
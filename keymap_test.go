@@ -0,0 +1,187 @@
+package gvcode
+
+import (
+	"errors"
+	"image"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestParseKeyStroke(t *testing.T) {
+	tests := []struct {
+		input string
+		want  KeyStroke
+		str   string
+	}{
+		{"Ctrl+D", KeyStroke{Name: "D", Modifiers: key.ModCtrl}, "Ctrl+D"},
+		{"ctrl+shift+k", KeyStroke{Name: "K", Modifiers: key.ModCtrl | key.ModShift}, "Ctrl+Shift+K"},
+		{"Alt+PageDown", KeyStroke{Name: key.NamePageDown, Modifiers: key.ModAlt}, "Alt+PageDown"},
+		{"Ctrl++", KeyStroke{Name: "+", Modifiers: key.ModCtrl}, "Ctrl++"},
+		{"F3", KeyStroke{Name: key.NameF3}, "F3"},
+	}
+
+	for _, tc := range tests {
+		got, err := ParseKeyStroke(tc.input)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.input, err)
+		}
+		if got != tc.want {
+			t.Errorf("%q: got %+v, want %+v", tc.input, got, tc.want)
+		}
+		if got.String() != tc.str {
+			t.Errorf("%q: got string %q, want %q", tc.input, got.String(), tc.str)
+		}
+	}
+
+	for _, input := range []string{"Hyper+D", "Ctrl+"} {
+		if _, err := ParseKeyStroke(input); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
+
+func TestKeymapBind(t *testing.T) {
+	km := DefaultKeymap()
+	if cmd, ok := km.Lookup("Shortcut+D"); !ok || cmd.Name != SelectNextOccurrence.Name {
+		t.Fatalf("default binding: got %q, %v", cmd.Name, ok)
+	}
+	if cmd, ok := km.Lookup("Shortcut+Shift+D"); !ok || cmd.Name != DuplicateLine.Name {
+		t.Fatalf("default binding: got %q, %v", cmd.Name, ok)
+	}
+	if cmd, ok := km.Lookup("Shortcut+Shift+W"); !ok || cmd.Name != ShrinkSelection.Name {
+		t.Fatalf("default binding: got %q, %v", cmd.Name, ok)
+	}
+	if cmd, ok := km.Lookup("Alt+Shift+]"); !ok || cmd.Name != SelectBlockDown.Name {
+		t.Fatalf("default binding: got %q, %v", cmd.Name, ok)
+	}
+
+	// Rebinding the same keys replaces the command.
+	if err := km.Bind("Shortcut+D", SelectAll); err != nil {
+		t.Fatal(err)
+	}
+	if cmd, _ := km.Lookup("Shortcut+D"); cmd.Name != SelectAll.Name {
+		t.Fatalf("rebound command: got %q", cmd.Name)
+	}
+
+	if err := km.Bind("Ctrl+K Ctrl+C", ToggleLineComment); err != nil {
+		t.Fatal(err)
+	}
+	var conflict *KeyConflictError
+	if err := km.Bind("Ctrl+K", Copy); !errors.As(err, &conflict) || conflict.Existing != "Ctrl+K Ctrl+C" {
+		t.Fatalf("expected a conflict with the chord, got %v", err)
+	}
+	if err := km.Bind("Ctrl+K Ctrl+C Ctrl+D", Copy); !errors.As(err, &conflict) {
+		t.Fatalf("expected a conflict with the chord, got %v", err)
+	}
+
+	if err := km.Unbind("Ctrl+K Ctrl+C"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := km.Lookup("Ctrl+K Ctrl+C"); ok {
+		t.Fatal("expected the chord to be unbound")
+	}
+}
+
+func TestKeymapChord(t *testing.T) {
+	e := &Editor{}
+	km := NewKeymap()
+	e.WithOptions(WithKeymap(km))
+	if err := km.Bind("Ctrl+K Ctrl+C", ToggleLineComment); err != nil {
+		t.Fatal(err)
+	}
+
+	ctrlK := KeyStroke{Name: "K", Modifiers: key.ModCtrl}
+	ctrlC := KeyStroke{Name: "C", Modifiers: key.ModCtrl}
+
+	if _, ok := e.dispatchKeyStroke(km, ctrlK); ok {
+		t.Fatal("expected the chord to be pending")
+	}
+	if got := e.PendingKeys(); got != "Ctrl+K" {
+		t.Fatalf("pending keys: got %q", got)
+	}
+	if cmd, ok := e.dispatchKeyStroke(km, ctrlC); !ok || cmd.Name != ToggleLineComment.Name {
+		t.Fatalf("chord: got %q, %v", cmd.Name, ok)
+	}
+	if got := e.PendingKeys(); got != "" {
+		t.Fatalf("pending keys after the chord: got %q", got)
+	}
+
+	// A stroke not continuing the chord cancels it.
+	e.dispatchKeyStroke(km, ctrlK)
+	if _, ok := e.dispatchKeyStroke(km, KeyStroke{Name: "X", Modifiers: key.ModCtrl}); ok {
+		t.Fatal("expected no command")
+	}
+	if _, ok := e.dispatchKeyStroke(km, ctrlC); ok {
+		t.Fatal("expected the chord to be cancelled")
+	}
+}
+
+func TestKeymapKeyEvents(t *testing.T) {
+	km := NewKeymap()
+	e := newTestEditor(t, "abc", WithKeymap(km))
+
+	var count int
+	counter := Command{Name: "count", Run: func(gtx layout.Context, e *Editor) EditorEvent {
+		count++
+		return nil
+	}}
+	for _, keys := range []string{"Ctrl+Shift+[", "Ctrl+K Ctrl+C"} {
+		if err := km.Bind(keys, counter); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cmd, ok := km.Lookup("Ctrl+{"); !ok || cmd.Name != counter.Name {
+		t.Fatalf("got %q bound to Ctrl+{, want the Ctrl+Shift+[ binding", cmd.Name)
+	}
+
+	var router input.Router
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	frame := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 200)), Source: router.Source()}
+		for {
+			if _, ok := e.Update(gtx); !ok {
+				break
+			}
+		}
+		e.Layout(gtx, shaper)
+		if !gtx.Focused(e) {
+			gtx.Execute(key.FocusCmd{Tag: e})
+		}
+		router.Frame(gtx.Ops)
+	}
+	press := func(name key.Name, mods key.Modifiers) {
+		router.Queue(key.Event{Name: name, Modifiers: mods, State: key.Press})
+		frame()
+	}
+	frame()
+	frame()
+
+	// X11 and Wayland name the key by its shifted symbol.
+	press("{", key.ModCtrl|key.ModShift)
+	press("[", key.ModCtrl|key.ModShift)
+	if count != 2 {
+		t.Fatalf("got the command run %d times, want 2", count)
+	}
+
+	// a key not bound in the keymap ends the pending chord, unlike the
+	// modifier keys.
+	press("K", key.ModCtrl)
+	press(key.NameShift, key.ModCtrl|key.ModShift)
+	if got := e.PendingKeys(); got != "Ctrl+K" {
+		t.Fatalf("got pending keys %q after a modifier key, want Ctrl+K", got)
+	}
+	press("X", 0)
+	if got := e.PendingKeys(); got != "" {
+		t.Fatalf("got pending keys %q after an unbound key, want none", got)
+	}
+	press("C", key.ModCtrl)
+	if count != 2 {
+		t.Error("the chord completed after an unbound key")
+	}
+}
@@ -0,0 +1,160 @@
+package gvcode
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/oligo/gvcode/gutter/providers"
+	"github.com/oligo/gvcode/internal/folding"
+)
+
+// CommentTokens are the tokens that start comments in a language. Empty
+// tokens mean the language has no such comments.
+type CommentTokens struct {
+	// Line starts a comment that ends at the line end, e.g., "//".
+	Line string
+	// BlockStart and BlockEnd delimit a block comment, e.g., "/*" and "*/".
+	BlockStart string
+	BlockEnd   string
+}
+
+// IndentRules controls the indentation of a language.
+type IndentRules struct {
+	// TabWidth is the number of spaces a tab represents. Zero keeps the
+	// setting of the editor.
+	TabWidth int
+	// SoftTab inserts spaces instead of tab characters. It is applied only if
+	// TabWidth is set.
+	SoftTab bool
+	// VisualTabWidth is the width tab characters are rendered with, leaving
+	// the editing operations to TabWidth. Zero renders tabs with TabWidth.
+	VisualTabWidth int
+}
+
+// LanguageConfig gathers the language specific behaviors of the editor.
+type LanguageConfig struct {
+	// ID identifies the language, e.g., "go".
+	ID string
+	// Comments are the comment tokens, used by comment commands and to
+	// detect folded comments.
+	Comments CommentTokens
+	// Pairs are the pairs auto-completed by the editor.
+	Pairs PairProfile
+	// Indent controls the indentation.
+	Indent IndentRules
+	// FoldRegion matches lines starting a named fold region, with the name as
+	// the first submatch. Nil disables fold regions.
+	FoldRegion *regexp.Regexp
+	// WordSeperators is the set of characters separating words. Empty means
+	// the built-in set.
+	WordSeperators string
+	// WordChars are characters of the words, even if they are word
+	// separators, e.g., "-" in CSS.
+	WordChars string
+	// RunPatterns detects the lines showing run buttons in the gutter.
+	RunPatterns providers.RunPatterns
+}
+
+var languages = struct {
+	sync.RWMutex
+	configs map[string]LanguageConfig
+}{
+	configs: map[string]LanguageConfig{
+		"go": {
+			ID:          "go",
+			Comments:    CommentTokens{Line: "//", BlockStart: "/*", BlockEnd: "*/"},
+			Indent:      IndentRules{TabWidth: 4},
+			FoldRegion:  folding.DefaultMarkers().Region,
+			RunPatterns: providers.DefaultRunPatterns(),
+		},
+	},
+}
+
+// RegisterLanguage adds config to the language registry, replacing the
+// config registered with the same ID.
+func RegisterLanguage(config LanguageConfig) {
+	languages.Lock()
+	defer languages.Unlock()
+	languages.configs[config.ID] = config
+}
+
+// LookupLanguage returns the config registered for the language id.
+func LookupLanguage(id string) (LanguageConfig, bool) {
+	languages.RLock()
+	defer languages.RUnlock()
+	config, ok := languages.configs[id]
+	return config, ok
+}
+
+// SetLanguage applies the registered config of the language id to the
+// editor, swapping the indentation, pairs, comment tokens, folding and run
+// buttons in one step. The language ID is also the language of the metadata.
+func (e *Editor) SetLanguage(id string) error {
+	config, ok := LookupLanguage(id)
+	if !ok {
+		return fmt.Errorf("language %q not registered", id)
+	}
+
+	e.initBuffer()
+	e.SetPairProfile(config.Pairs)
+	if config.Indent.TabWidth > 0 {
+		e.text.TabWidth = config.Indent.TabWidth
+		e.text.SoftTab = config.Indent.SoftTab
+	}
+	e.text.VisualTabWidth = config.Indent.VisualTabWidth
+	e.useLanguage(config)
+	e.text.Invalidate()
+	return nil
+}
+
+// useLanguage applies the behaviors of config which are not kept with the
+// indentation and the pairs of a buffer: the comment tokens, the word
+// characters, the fold markers and the run patterns. The zero config restores
+// the defaults of the editor.
+func (e *Editor) useLanguage(config LanguageConfig) {
+	e.language = config
+	e.text.WordSeperators = config.WordSeperators
+	e.text.WordChars = config.WordChars
+
+	markers, patterns := folding.DefaultMarkers(), providers.DefaultRunPatterns()
+	if config.ID != "" {
+		markers = folding.Markers{
+			LineComment:       config.Comments.Line,
+			BlockCommentStart: config.Comments.BlockStart,
+			BlockCommentEnd:   config.Comments.BlockEnd,
+			Region:            config.FoldRegion,
+		}
+		patterns = config.RunPatterns
+	}
+	// the folds are detected again only if the markers changed, keeping the
+	// folds restored with a buffer.
+	if fm := e.text.FoldManager(); fm != nil && !sameMarkers(fm.Markers(), markers) {
+		fm.SetMarkers(markers)
+	}
+
+	if e.gutterManager != nil {
+		for _, p := range e.gutterManager.Providers() {
+			if rb, ok := p.(*providers.RunButtonProvider); ok {
+				rb.SetPatterns(patterns)
+			}
+		}
+	}
+}
+
+// sameMarkers reports whether the fold markers a and b detect the same folds.
+func sameMarkers(a, b folding.Markers) bool {
+	region := func(m folding.Markers) string {
+		if m.Region == nil {
+			return ""
+		}
+		return m.Region.String()
+	}
+	return a.LineComment == b.LineComment && a.BlockCommentStart == b.BlockCommentStart &&
+		a.BlockCommentEnd == b.BlockCommentEnd && (a.Region == nil) == (b.Region == nil) && region(a) == region(b)
+}
+
+// Language returns the config of the language set by SetLanguage.
+func (e *Editor) Language() LanguageConfig {
+	return e.language
+}
@@ -0,0 +1,81 @@
+package gvcode
+
+import (
+	"image"
+	"regexp"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestSetLanguage(t *testing.T) {
+	RegisterLanguage(LanguageConfig{
+		ID:         "python",
+		Comments:   CommentTokens{Line: "#", BlockStart: `"""`, BlockEnd: `"""`},
+		Pairs:      PairProfile{Quotes: map[rune]rune{'\'': '\''}},
+		Indent:     IndentRules{TabWidth: 4, SoftTab: true, VisualTabWidth: 8},
+		FoldRegion: regexp.MustCompile(`^#\s*region\s+(\w+)`),
+	})
+	defer func() {
+		languages.Lock()
+		delete(languages.configs, "python")
+		languages.Unlock()
+	}()
+
+	e := newTestEditor(t, "\"\"\"doc\nmore\n\"\"\"\nx = 1\n", WithCodeFolding())
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	frame := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 300))}
+		e.Layout(gtx, shaper)
+	}
+	frame()
+	frame()
+	if folds := e.text.FoldManager().GetFoldRanges(); len(folds) != 0 {
+		t.Fatalf("got folds %+v without a language", folds)
+	}
+
+	if err := e.SetLanguage("cobol"); err == nil {
+		t.Fatal("got no error setting an unregistered language")
+	}
+	if err := e.SetLanguage("python"); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Language().ID; got != "python" {
+		t.Errorf("got language %q, want python", got)
+	}
+	if e.text.TabWidth != 4 || !e.text.SoftTab || e.text.VisualTabWidth != 8 {
+		t.Errorf("got tab width %d, soft tab %v, visual tab width %d", e.text.TabWidth, e.text.SoftTab, e.text.VisualTabWidth)
+	}
+
+	if _, ok := e.text.BracketsQuotes.GetCounterpart('"'); ok {
+		t.Error("got the double quote paired, want only the quotes of the language")
+	}
+
+	// the docstring is folded as a block comment.
+	frame()
+	folds := e.text.FoldManager().GetFoldRanges()
+	if len(folds) != 1 || folds[0].StartLine != 0 || folds[0].EndLine != 2 {
+		t.Fatalf("got folds %+v, want the docstring folded", folds)
+	}
+
+	e.SetCaret(e.text.ConvertPos(3, 0), e.text.ConvertPos(3, 0))
+	e.ToggleLineComment()
+	if got, want := e.Text(), "\"\"\"doc\nmore\n\"\"\"\n# x = 1\n"; got != want {
+		t.Errorf("got %q after toggling the comment, want %q", got, want)
+	}
+
+	// another language replaces the config as a whole.
+	if err := e.SetLanguage("go"); err != nil {
+		t.Fatal(err)
+	}
+	frame()
+	if folds := e.text.FoldManager().GetFoldRanges(); len(folds) != 0 {
+		t.Errorf("got folds %+v after switching to go", folds)
+	}
+	if e.text.VisualTabWidth != 0 {
+		t.Errorf("got visual tab width %d after switching to go", e.text.VisualTabWidth)
+	}
+}
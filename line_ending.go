@@ -0,0 +1,174 @@
+package gvcode
+
+import (
+	"bytes"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/internal/buffer"
+	"github.com/oligo/gvcode/painter"
+)
+
+// LineEnding is the sequence terminating the lines of a document.
+type LineEnding uint8
+
+const (
+	LF LineEnding = iota
+	CRLF
+)
+
+func (l LineEnding) String() string {
+	if l == CRLF {
+		return "CRLF"
+	}
+	return "LF"
+}
+
+// GuessLineEnding returns the line ending used by most of the lines of text,
+// and whether both line endings are used. Text without line breaks uses LF.
+func GuessLineEnding(text string) (LineEnding, bool) {
+	lf, crlf := countLineEndings([]byte(text))
+	return majorityLineEnding(lf, crlf), lf > 0 && crlf > 0
+}
+
+// countLineEndings counts the lines of text ending with a single LF, and the
+// lines ending with CR LF.
+func countLineEndings(text []byte) (lf, crlf int) {
+	for i := bytes.IndexByte(text, '\n'); i >= 0; {
+		if i > 0 && text[i-1] == '\r' {
+			crlf++
+		} else {
+			lf++
+		}
+		next := bytes.IndexByte(text[i+1:], '\n')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return lf, crlf
+}
+
+func majorityLineEnding(lf, crlf int) LineEnding {
+	if crlf > lf {
+		return CRLF
+	}
+	return LF
+}
+
+// lineEndingState caches the line ending detected in a version of the text.
+type lineEndingState struct {
+	src     buffer.TextSource
+	version int
+	ending  LineEnding
+	mixed   bool
+}
+
+// LineEnding returns the line ending used by most of the lines of the
+// document, and whether the document mixes LF and CRLF line endings. The
+// result is cached until the text is changed.
+func (e *Editor) LineEnding() (LineEnding, bool) {
+	e.initBuffer()
+	state := &e.lineEnding
+	if state.src != e.buffer || state.version != e.buffer.Version() {
+		e.scratch = buffer.NewReader(e.buffer).ReadAll(e.scratch)
+		lf, crlf := countLineEndings(e.scratch)
+		*state = lineEndingState{
+			src:     e.buffer,
+			version: e.buffer.Version(),
+			ending:  majorityLineEnding(lf, crlf),
+			mixed:   lf > 0 && crlf > 0,
+		}
+	}
+	return state.ending, state.mixed
+}
+
+// SetLineEnding rewrites the line endings of the document to ending, in a
+// single undo group. Lone CR characters are left unchanged. It returns the
+// number of rewritten line endings.
+func (e *Editor) SetLineEnding(ending LineEnding) int {
+	e.initBuffer()
+	if e.mode == ModeReadOnly {
+		return 0
+	}
+
+	// the rune offsets of the line breaks to rewrite: of the LF to prefix
+	// with a CR, or of the CR to remove.
+	var offsets []int
+	e.scratch = buffer.NewReader(e.buffer).ReadAll(e.scratch)
+	runeOff := 0
+	for i, r := range string(e.scratch) {
+		if r == '\n' {
+			crlf := i > 0 && e.scratch[i-1] == '\r'
+			switch {
+			case ending == CRLF && !crlf:
+				offsets = append(offsets, runeOff)
+			case ending == LF && crlf:
+				offsets = append(offsets, runeOff-1)
+			}
+		}
+		runeOff++
+	}
+	if len(offsets) == 0 {
+		return 0
+	}
+
+	e.Transaction(func(tx *EditTx) {
+		// edit from the bottom, so that the offsets above are not shifted.
+		for i := len(offsets) - 1; i >= 0; i-- {
+			if ending == CRLF {
+				tx.Insert(offsets[i], "\r")
+			} else {
+				tx.Delete(offsets[i], offsets[i]+1)
+			}
+		}
+	})
+	return len(offsets)
+}
+
+// paintLineEndMarkers draws a pilcrow after the visible lines ending with a
+// LF, and a currency sign and a pilcrow after the lines ending with CR LF.
+func (e *Editor) paintLineEndMarkers(gtx layout.Context, shaper *text.Shaper, markerColor gvcolor.Color) {
+	if shaper == nil {
+		return
+	}
+	paragraphs := e.text.TextLayout().Paragraphs
+	foldManager := e.text.FoldManager()
+
+	params := e.text.Params()
+	params.MinWidth = 0
+	params.MaxWidth = 1 << 24
+	params.MaxLines = 1
+
+	first, last := e.visibleParagraphs()
+	for i := first; i < last; i++ {
+		para := paragraphs[i]
+		if para.Runes == 0 || (foldManager != nil && !foldManager.IsLineVisible(i)) {
+			continue
+		}
+		end := para.RuneOff + para.Runes
+		suffix := e.ReadRange(max(end-2, para.RuneOff), end)
+		if !strings.HasSuffix(suffix, "\n") {
+			continue
+		}
+
+		marker, breakOff := "¶", end-1
+		if strings.HasSuffix(suffix, "\r\n") {
+			marker, breakOff = "¤¶", end-2
+		}
+		pos := e.text.RuneCoords(breakOff)
+		glyphs, _ := shapeLine(shaper, params, marker)
+		if len(glyphs) == 0 {
+			continue
+		}
+		trans := op.Affine(f32.Affine2D{}.Offset(f32.Point{
+			X: float32(int(pos.X) + glyphs[0].X.Floor()), Y: float32(int(pos.Y)),
+		})).Push(gtx.Ops)
+		painter.DrawGlyphs(gtx, shaper, glyphs, markerColor.Op(gtx.Ops))
+		trans.Pop()
+	}
+}
@@ -0,0 +1,62 @@
+package gvcode
+
+import (
+	"testing"
+)
+
+func TestGuessLineEnding(t *testing.T) {
+	cases := []struct {
+		text   string
+		ending LineEnding
+		mixed  bool
+	}{
+		{"", LF, false},
+		{"no break", LF, false},
+		{"a\nb\n", LF, false},
+		{"a\r\nb\r\n", CRLF, false},
+		{"a\r\nb\r\nc\n", CRLF, true},
+		{"a\r\nb\nc\n", LF, true},
+		{"lone\rcr\n", LF, false},
+	}
+	for _, tc := range cases {
+		ending, mixed := GuessLineEnding(tc.text)
+		if ending != tc.ending || mixed != tc.mixed {
+			t.Errorf("GuessLineEnding(%q) = %v, %v, want %v, %v", tc.text, ending, mixed, tc.ending, tc.mixed)
+		}
+	}
+}
+
+func TestSetLineEnding(t *testing.T) {
+	e := newTestEditor(t, "a\r\nb\nc\r\n€\n")
+	if ending, mixed := e.LineEnding(); ending != LF || !mixed {
+		t.Fatalf("LineEnding() = %v, %v, want LF, true", ending, mixed)
+	}
+
+	if n := e.SetLineEnding(CRLF); n != 2 {
+		t.Fatalf("SetLineEnding(CRLF) rewrote %d lines, want 2", n)
+	}
+	if got, want := e.Text(), "a\r\nb\r\nc\r\n€\r\n"; got != want {
+		t.Fatalf("CRLF: got %q, want %q", got, want)
+	}
+	if ending, mixed := e.LineEnding(); ending != CRLF || mixed {
+		t.Fatalf("LineEnding() = %v, %v after the rewrite, want CRLF, false", ending, mixed)
+	}
+	if n := e.SetLineEnding(CRLF); n != 0 {
+		t.Fatalf("SetLineEnding(CRLF) rewrote %d lines again", n)
+	}
+
+	e.SetLineEnding(LF)
+	if got, want := e.Text(), "a\nb\nc\n€\n"; got != want {
+		t.Fatalf("LF: got %q, want %q", got, want)
+	}
+
+	// each rewrite is undone in a single step.
+	e.undo()
+	if got, want := e.Text(), "a\r\nb\r\nc\r\n€\r\n"; got != want {
+		t.Fatalf("undo: got %q, want %q", got, want)
+	}
+	e.undo()
+	if got, want := e.Text(), "a\r\nb\nc\r\n€\n"; got != want {
+		t.Fatalf("second undo: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,149 @@
+package gvcode
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// SortLines sorts the selected lines, or all the lines if the selection does
+// not span multiple lines. Lines are compared by their text, ignoring the
+// letter case if ignoreCase is true. Equal lines keep their order. The lines
+// are replaced in one edit, so that a single undo restores them. It reports
+// whether the text is changed.
+func (e *Editor) SortLines(descending, ignoreCase bool) bool {
+	return e.transformLines(func(lines []string) []string {
+		key := func(s string) string { return s }
+		if ignoreCase {
+			key = strings.ToLower
+		}
+		slices.SortStableFunc(lines, func(a, b string) int {
+			if descending {
+				return cmp.Compare(key(b), key(a))
+			}
+			return cmp.Compare(key(a), key(b))
+		})
+		return lines
+	})
+}
+
+// RemoveDuplicateLines removes the selected lines equal to a previous line,
+// or does so for all the lines if the selection does not span multiple lines.
+// The lines are replaced in one edit, so that a single undo restores them. It
+// reports whether the text is changed.
+func (e *Editor) RemoveDuplicateLines() bool {
+	return e.transformLines(func(lines []string) []string {
+		seen := make(map[string]bool, len(lines))
+		return slices.DeleteFunc(lines, func(line string) bool {
+			if seen[line] {
+				return true
+			}
+			seen[line] = true
+			return false
+		})
+	})
+}
+
+// transformLines replaces the selected lines, or all the lines, with the
+// result of fn, and selects the replaced lines.
+func (e *Editor) transformLines(fn func(lines []string) []string) bool {
+	e.initBuffer()
+	if e.mode == ModeReadOnly {
+		return false
+	}
+
+	startLine, endLine := e.selectedLines()
+	if startLine == endLine {
+		startLine, endLine = 0, e.text.Paragraphs()-1
+		if endLine > 0 && e.text.ConvertPos(endLine, 0) == e.columnLineEnd(endLine) {
+			// skip the empty line after the trailing line break.
+			endLine--
+		}
+	}
+	if startLine >= endLine {
+		return false
+	}
+
+	start, end := e.text.ConvertPos(startLine, 0), e.columnLineEnd(endLine)
+	text := e.ReadRange(start, end)
+	lines := strings.Split(text, "\n")
+	newText := strings.Join(fn(slices.Clone(lines)), "\n")
+	if newText == text {
+		return false
+	}
+
+	moves, result := e.replace(start, end, newText)
+	if result != editApplied {
+		return result == editRewritten
+	}
+	e.text.MoveCaret(0, 0)
+	e.SetCaret(start+moves, start)
+	return true
+}
+
+// ConvertIndentation rewrites the leading whitespace of the selected lines,
+// or of all the lines if the selection does not span multiple lines, to
+// spaces or to tabs of width columns. When converting to tabs, the columns
+// not filling a whole tab are kept as spaces, so that the alignment of the
+// text after the indentation is preserved. A width <= 0 uses the tab width of
+// the editor. The lines are rewritten in a single undo group. It returns the
+// number of changed lines.
+func (e *Editor) ConvertIndentation(toSpaces bool, width int) int {
+	return e.convertIndentation(toSpaces, width, false)
+}
+
+// IndentationChanges is the dry run of ConvertIndentation. It returns the
+// number of lines that would be changed, without changing the text.
+func (e *Editor) IndentationChanges(toSpaces bool, width int) int {
+	return e.convertIndentation(toSpaces, width, true)
+}
+
+func (e *Editor) convertIndentation(toSpaces bool, width int, dryRun bool) int {
+	e.initBuffer()
+	if !dryRun && e.mode == ModeReadOnly {
+		return 0
+	}
+	if width <= 0 {
+		width = max(e.text.TabWidth, 1)
+	}
+
+	startLine, endLine := e.selectedLines()
+	if startLine == endLine {
+		startLine, endLine = 0, e.text.Paragraphs()-1
+	}
+
+	var edits []Edit
+	for line := startLine; line <= endLine; line++ {
+		start := e.text.ConvertPos(line, 0)
+		text := e.ReadRange(start, e.columnLineEnd(line))
+		indent := text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+		if indent == "" {
+			continue
+		}
+		if converted := convertIndent(indent, toSpaces, width); converted != indent {
+			edits = append(edits, Edit{Start: start, End: start + len(indent), Text: converted})
+		}
+	}
+	if dryRun || len(edits) == 0 {
+		return len(edits)
+	}
+
+	result := editVetoed
+	e.Transaction(func(tx *EditTx) {
+		_, result = tx.replaceEdits(edits)
+	})
+	if result == editVetoed {
+		return 0
+	}
+	return len(edits)
+}
+
+// convertIndent converts the whitespace indent to spaces or to tabs of width
+// columns, keeping its visual width.
+func convertIndent(indent string, toSpaces bool, width int) string {
+	cols := indentColumns(indent, width)
+	if toSpaces {
+		return strings.Repeat(" ", cols)
+	}
+	return strings.Repeat("\t", cols/width) + strings.Repeat(" ", cols%width)
+}
@@ -0,0 +1,91 @@
+package gvcode
+
+import (
+	"testing"
+)
+
+func TestSortLines(t *testing.T) {
+	e := newTestEditor(t, "b\nC\na\nc\n")
+
+	if !e.SortLines(false, false) {
+		t.Fatal("expected the text to be changed")
+	}
+	if got, want := e.Text(), "C\na\nb\nc\n"; got != want {
+		t.Fatalf("ascending: got %q, want %q", got, want)
+	}
+
+	e.SortLines(true, true)
+	if got, want := e.Text(), "C\nc\nb\na\n"; got != want {
+		t.Fatalf("descending, ignoring case: got %q, want %q", got, want)
+	}
+
+	e.undo()
+	if got, want := e.Text(), "C\na\nb\nc\n"; got != want {
+		t.Fatalf("undo: got %q, want %q", got, want)
+	}
+
+	// Only the selected lines are sorted.
+	e.SetCaret(2, 5)
+	e.SortLines(true, false)
+	if got, want := e.Text(), "C\nb\na\nc\n"; got != want {
+		t.Fatalf("selection: got %q, want %q", got, want)
+	}
+}
+
+func TestRemoveDuplicateLines(t *testing.T) {
+	e := newTestEditor(t, "a\nb\na\nc\nb")
+
+	if !e.RemoveDuplicateLines() {
+		t.Fatal("expected the text to be changed")
+	}
+	if got, want := e.Text(), "a\nb\nc"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if e.RemoveDuplicateLines() {
+		t.Fatal("expected no change without duplicates")
+	}
+
+	e.undo()
+	if got, want := e.Text(), "a\nb\na\nc\nb"; got != want {
+		t.Fatalf("undo: got %q, want %q", got, want)
+	}
+}
+
+func TestConvertIndentation(t *testing.T) {
+	e := newTestEditor(t, "\tfoo\n  \tbar\n      baz\nqux")
+
+	if got := e.IndentationChanges(true, 4); got != 2 {
+		t.Fatalf("dry run: got %d lines, want 2", got)
+	}
+	if got, want := e.Text(), "\tfoo\n  \tbar\n      baz\nqux"; got != want {
+		t.Fatalf("dry run changed the text: %q", got)
+	}
+
+	if got := e.ConvertIndentation(true, 4); got != 2 {
+		t.Fatalf("to spaces: got %d lines, want 2", got)
+	}
+	if got, want := e.Text(), "    foo\n    bar\n      baz\nqux"; got != want {
+		t.Fatalf("to spaces: got %q, want %q", got, want)
+	}
+
+	if got := e.ConvertIndentation(false, 4); got != 3 {
+		t.Fatalf("to tabs: got %d lines, want 3", got)
+	}
+	if got, want := e.Text(), "\tfoo\n\tbar\n\t  baz\nqux"; got != want {
+		t.Fatalf("to tabs: got %q, want %q", got, want)
+	}
+
+	e.undo()
+	if got, want := e.Text(), "    foo\n    bar\n      baz\nqux"; got != want {
+		t.Fatalf("undo: got %q, want %q", got, want)
+	}
+
+	// Only the selected lines are converted.
+	e.SetCaret(0, 12)
+	if got := e.ConvertIndentation(false, 4); got != 2 {
+		t.Fatalf("selection: got %d lines, want 2", got)
+	}
+	if got, want := e.Text(), "\tfoo\n\tbar\n      baz\nqux"; got != want {
+		t.Fatalf("selection: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,183 @@
+package gvcode
+
+import (
+	"slices"
+	"unicode/utf8"
+
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/internal/buffer"
+	"github.com/oligo/gvcode/textstyle/decoration"
+)
+
+const linkedEditDeco = "_linked_edit_deco"
+
+// linkedEdit tracks the regions of a linked editing session. The regions are
+// the occurrences of a word, and an edit made in one of them is applied to all
+// of them. The markers of the region decorations keep the text inserted at
+// the boundaries of a region inside of it.
+type linkedEdit struct {
+	regions [][2]*buffer.Marker
+	// version is the buffer version left by the last linked edit. The session
+	// ends if the text is changed in another way, e.g., by undo.
+	version int
+}
+
+// StartLinkedEdit links the whole word occurrences of the word at the caret
+// within the rune range [start, end), e.g., the body of the function declaring
+// a local variable. Typing and deleting in one of the occurrences then updates
+// all of them in a single undo step, which renames the variable in place.
+//
+// The session ends when the caret leaves the occurrences, when the text is
+// changed outside of them, on Esc, or with StopLinkedEdit. It returns the
+// number of linked occurrences, or 0 if there is no word at the caret.
+func (e *Editor) StartLinkedEdit(start, end int) int {
+	e.initBuffer()
+	e.StopLinkedEdit()
+	if e.mode == ModeReadOnly {
+		return 0
+	}
+	if start > end {
+		start, end = end, start
+	}
+	start = max(0, min(start, e.text.Len()))
+	end = max(0, min(end, e.text.Len()))
+
+	caret, _ := e.text.Selection()
+	wordStart, wordEnd := e.text.WordBoundariesAt(caret, false)
+	if wordStart >= wordEnd || wordStart < start || wordEnd > end {
+		return 0
+	}
+
+	var border gvcolor.Color
+	if e.colorPalette != nil {
+		border = e.colorPalette.Foreground
+	}
+	decos := make([]decoration.Decoration, 0)
+	for _, occ := range e.text.FindTextOccurrencesIn(wordStart, wordEnd, start, end) {
+		if !e.isWholeWord(occ[0], occ[1]) {
+			continue
+		}
+		decos = append(decos, decoration.Decoration{
+			Source: linkedEditDeco,
+			Start:  occ[0],
+			End:    occ[1],
+			Border: &decoration.Border{Color: border},
+		})
+	}
+	if len(decos) == 0 || e.AddDecorations(decos...) != nil {
+		e.ClearDecorations(linkedEditDeco)
+		return 0
+	}
+	for _, deco := range decos {
+		startMarker, endMarker := deco.Range()
+		if startMarker == nil || endMarker == nil {
+			e.StopLinkedEdit()
+			return 0
+		}
+		e.linked.regions = append(e.linked.regions, [2]*buffer.Marker{startMarker, endMarker})
+	}
+	e.linked.version = e.buffer.Version()
+	return len(e.linked.regions)
+}
+
+// StopLinkedEdit ends the linked editing session, if any.
+func (e *Editor) StopLinkedEdit() {
+	if len(e.linked.regions) > 0 {
+		e.ClearDecorations(linkedEditDeco)
+	}
+	e.linked = linkedEdit{}
+}
+
+// LinkedEditRanges returns the ranges of the linked occurrences in document
+// order, or nil if there is no linked editing session.
+func (e *Editor) LinkedEditRanges() []TextRange {
+	e.initBuffer()
+	e.validateLinkedEdit()
+	return e.linkedRanges()
+}
+
+func (e *Editor) linkedRanges() []TextRange {
+	if len(e.linked.regions) == 0 {
+		return nil
+	}
+	ranges := make([]TextRange, 0, len(e.linked.regions))
+	for _, r := range e.linked.regions {
+		ranges = append(ranges, TextRange{Start: r[0].Offset(), End: r[1].Offset()})
+	}
+	slices.SortFunc(ranges, func(a, b TextRange) int { return a.Start - b.Start })
+	return ranges
+}
+
+// linkedRangeAt returns the index of the linked range containing the rune
+// range [start, end), boundaries included.
+func linkedRangeAt(ranges []TextRange, start, end int) int {
+	return slices.IndexFunc(ranges, func(r TextRange) bool {
+		return r.Start <= start && end <= r.End
+	})
+}
+
+// validateLinkedEdit ends the linked editing session if the text was changed
+// by another edit, or if the caret left the linked ranges.
+func (e *Editor) validateLinkedEdit() {
+	if len(e.linked.regions) == 0 {
+		return
+	}
+	if e.linked.version != e.buffer.Version() {
+		e.StopLinkedEdit()
+		return
+	}
+	start, end := e.text.Selection()
+	if linkedRangeAt(e.linkedRanges(), min(start, end), max(start, end)) < 0 {
+		e.StopLinkedEdit()
+	}
+}
+
+// linkedReplace replaces the rune range [start, end) with s in all the linked
+// ranges, if the range is inside of one of them. It reports whether the text
+// was changed, and whether the edit was handled, which is also the case when
+// it is refused in a read-only region. The caret is left after the text
+// inserted in the range edited by the user.
+func (e *Editor) linkedReplace(start, end int, s string) (changed, handled bool) {
+	e.validateLinkedEdit()
+	if len(e.linked.regions) == 0 {
+		return false, false
+	}
+	if start > end {
+		start, end = end, start
+	}
+	ranges := e.linkedRanges()
+	current := linkedRangeAt(ranges, start, end)
+	if current < 0 {
+		// the edit is outside of the linked ranges, which ends the session.
+		e.StopLinkedEdit()
+		return false, false
+	}
+
+	relStart, relEnd := start-ranges[current].Start, end-ranges[current].Start
+	for _, r := range ranges {
+		if e.denyReadOnlyEdit(r.Start+relStart, r.Start+relEnd) {
+			return false, true
+		}
+	}
+
+	edits := make([]Edit, len(ranges))
+	for i, r := range ranges {
+		edits[i] = Edit{Start: r.Start + relStart, End: r.Start + relEnd, Text: s}
+	}
+	result := editVetoed
+	e.Transaction(func(tx *EditTx) {
+		_, result = tx.replaceEdits(edits)
+	})
+	switch result {
+	case editVetoed:
+		return false, true
+	case editRewritten:
+		e.StopLinkedEdit()
+		return true, true
+	}
+	inserted := utf8.RuneCountInString(s)
+	caret := start + inserted + current*(inserted-(relEnd-relStart))
+	e.text.SetCaret(caret, caret)
+	e.linked.version = e.buffer.Version()
+	return true, true
+}
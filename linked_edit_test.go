@@ -0,0 +1,68 @@
+package gvcode
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLinkedEdit(t *testing.T) {
+	src := "func f() {\n\tn := 1\n\tn2 := n + n\n}\nvar n = 0\n"
+	e := newTestEditor(t, src)
+	body := TextRange{Start: 10, End: 32}
+	// put the caret in the declaration of n.
+	e.SetCaret(13, 13)
+
+	if n := e.StartLinkedEdit(body.Start, body.End); n != 3 {
+		t.Fatalf("linked %d occurrences, want 3", n)
+	}
+
+	e.Delete(-1)
+	e.Insert("count")
+	want := "func f() {\n\tcount := 1\n\tn2 := count + count\n}\nvar n = 0\n"
+	if got := e.Text(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if start, end := e.Selection(); start != 17 || end != 17 {
+		t.Fatalf("got caret %d-%d, want 17-17", start, end)
+	}
+	wantRanges := []TextRange{{Start: 12, End: 17}, {Start: 30, End: 35}, {Start: 38, End: 43}}
+	if got := e.LinkedEditRanges(); !slices.Equal(got, wantRanges) {
+		t.Fatalf("got ranges %v, want %v", got, wantRanges)
+	}
+
+	// each edit is undone in a single step.
+	e.undo()
+	if got, want := e.Text(), "func f() {\n\t := 1\n\tn2 :=  + \n}\nvar n = 0\n"; got != want {
+		t.Fatalf("undo: got %q, want %q", got, want)
+	}
+	// undoing ends the session.
+	if got := e.LinkedEditRanges(); got != nil {
+		t.Fatalf("got ranges %v after undo", got)
+	}
+}
+
+func TestLinkedEditEndsOutside(t *testing.T) {
+	e := newTestEditor(t, "a := a\n")
+	e.SetCaret(0, 0)
+	if n := e.StartLinkedEdit(0, e.Len()); n != 2 {
+		t.Fatalf("linked %d occurrences, want 2", n)
+	}
+
+	// the caret leaves the occurrences.
+	e.SetCaret(3, 3)
+	e.Insert("=")
+	if got, want := e.Text(), "a :== a\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got := e.LinkedEditRanges(); got != nil {
+		t.Fatalf("got ranges %v, want none", got)
+	}
+}
+
+func TestLinkedEditNoWord(t *testing.T) {
+	e := newTestEditor(t, "a  := a\n")
+	e.SetCaret(2, 2)
+	if n := e.StartLinkedEdit(0, e.Len()); n != 0 {
+		t.Fatalf("linked %d occurrences, want 0", n)
+	}
+}
@@ -0,0 +1,34 @@
+package gvcode
+
+import (
+	"github.com/oligo/gvcode/gutter"
+)
+
+// SetMetadata replaces the metadata of the document. Copies of the metadata
+// are passed to the gutter providers and to the completion context. The
+// Language field is ignored, as it is the ID of the language set by
+// SetLanguage.
+func (e *Editor) SetMetadata(md gutter.Metadata) {
+	e.metadata = md.Clone()
+	e.metadata.Language = ""
+}
+
+// Metadata returns a copy of the metadata of the document.
+func (e *Editor) Metadata() gutter.Metadata {
+	md := e.metadata.Clone()
+	md.Language = e.language.ID
+	return md
+}
+
+// SetMetadataValue sets the custom metadata value of key. A nil value deletes
+// the key.
+func (e *Editor) SetMetadataValue(key string, value any) {
+	if value == nil {
+		delete(e.metadata.Values, key)
+		return
+	}
+	if e.metadata.Values == nil {
+		e.metadata.Values = make(map[string]any)
+	}
+	e.metadata.Values[key] = value
+}
@@ -0,0 +1,63 @@
+package gvcode
+
+import (
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"github.com/oligo/gvcode/gutter"
+)
+
+func TestMetadata(t *testing.T) {
+	e := newGoEditor(t, "package main\n")
+	e.SetMetadata(gutter.Metadata{FilePath: "main.go", ReadOnly: true})
+	e.SetMetadataValue("revision", "HEAD")
+
+	md := e.Metadata()
+	if md.FilePath != "main.go" || !md.ReadOnly {
+		t.Fatalf("unexpected metadata: %+v", md)
+	}
+	if v, ok := md.Value("revision"); !ok || v != "HEAD" {
+		t.Fatalf("revision: got %v, %v", v, ok)
+	}
+
+	// the returned metadata is a copy.
+	md.Values["revision"] = "main"
+	if v, _ := e.Metadata().Value("revision"); v != "HEAD" {
+		t.Fatalf("metadata modified through a copy: %v", v)
+	}
+
+	e.SetMetadataValue("revision", nil)
+	if _, ok := e.Metadata().Value("revision"); ok {
+		t.Fatal("expected the revision to be deleted")
+	}
+
+	if err := e.SetLanguage("go"); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Metadata().Language; got != "go" {
+		t.Fatalf("language: got %q", got)
+	}
+	if got := e.GetCompletionContext().Metadata.FilePath; got != "main.go" {
+		t.Fatalf("completion context file path: got %q", got)
+	}
+}
+
+func TestMetadataCopies(t *testing.T) {
+	e := newGoEditor(t, "package main\n")
+	e.WithOptions(WithCodeFolding())
+	e.SetMetadata(gutter.Metadata{Language: "python", Values: map[string]any{"revision": "HEAD"}})
+	if got := e.Metadata().Language; got != "go" {
+		t.Errorf("got language %q, want the language set by SetLanguage", got)
+	}
+
+	// the completion context and the gutter context hold copies.
+	ctx := e.GetCompletionContext()
+	ctx.Metadata.Values["revision"] = "main"
+	ctx.Metadata.FilePath = "x.go"
+	gtx := e.buildGutterContext(layout.Context{Ops: new(op.Ops)}, nil)
+	gtx.Metadata.Values["revision"] = "main"
+	if md := e.Metadata(); md.Values["revision"] != "HEAD" || md.FilePath != "" {
+		t.Errorf("got metadata %+v modified through a context", md)
+	}
+}
@@ -0,0 +1,39 @@
+package gvcode
+
+import (
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalizeInput returns the text typed over [start, end) by the single caret
+// in the Unicode normalization form C, if the normalization is enabled. When
+// the text starts with combining marks, e.g., a dead key typing an accent
+// after its letter, the range is extended to the character before it, so
+// that they are composed together.
+func (e *Editor) normalizeInput(start, end int, s string) (int, int, string) {
+	if !e.normalizeNFC || e.ime.composing {
+		return start, end, s
+	}
+	s = norm.NFC.String(s)
+	if e.ColumnEditEnabled() && len(e.columnEdit.selections) > 1 {
+		return start, end, s
+	}
+	if start != end || start == 0 || norm.NFC.FirstBoundaryInString(s) == 0 {
+		return start, end, s
+	}
+
+	prevStart := e.text.MoveGraphemes(start, -1)
+	prev := e.ReadRange(prevStart, start)
+	if composed := norm.NFC.String(prev + s); composed != prev+s && !e.denyReadOnlyEdit(prevStart, start) {
+		return prevStart, end, composed
+	}
+	return start, end, s
+}
+
+// normalizeText returns the pasted or typed text in the Unicode normalization
+// form C, if the normalization is enabled.
+func (e *Editor) normalizeText(s string) string {
+	if !e.normalizeNFC {
+		return s
+	}
+	return norm.NFC.String(s)
+}
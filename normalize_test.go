@@ -0,0 +1,34 @@
+package gvcode
+
+import (
+	"testing"
+
+	"gioui.org/io/key"
+)
+
+func TestNFCNormalization(t *testing.T) {
+	e := newTestEditor(t, "cafe")
+	e.WithOptions(WithNFCNormalization(true))
+
+	// a combining accent typed after its letter is composed with it.
+	e.SetCaret(4, 4)
+	e.onTextInput(key.EditEvent{Range: key.Range{Start: 4, End: 4}, Text: "\u0301"})
+	if got, want := e.Text(), "café"; got != want {
+		t.Fatalf("typing: got %q, want %q", got, want)
+	}
+	if start, end := e.Selection(); start != 4 || end != 4 {
+		t.Errorf("got caret [%d, %d], want 4", start, end)
+	}
+
+	e.onPasteText(" Cre\u0300me")
+	if got, want := e.Text(), "café Crème"; got != want {
+		t.Fatalf("paste: got %q, want %q", got, want)
+	}
+
+	// the text is kept unchanged without the normalization.
+	e.WithOptions(WithNFCNormalization(false))
+	e.onPasteText("e\u0301")
+	if got, want := e.Text(), "café Crèmee\u0301"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
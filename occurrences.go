@@ -0,0 +1,324 @@
+package gvcode
+
+import (
+	"image"
+	"slices"
+	"unicode/utf8"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/textstyle/decoration"
+)
+
+const extraSelectionSource = "_extra_selection"
+
+// multiSelection tracks the selections added besides the primary one by
+// SelectNextOccurrence and SelectAllOccurrences. Typing and deleting apply to
+// all the selections. The extra selections are dropped when the primary
+// selection is moved or the text is changed in another way.
+type multiSelection struct {
+	// extras are the extra selections, with the caret at End.
+	extras []TextRange
+	// wholeWord is true if the occurrences match whole words only, when
+	// the first occurrence is the word at the caret.
+	wholeWord bool
+	// primary and version are the primary selection and the buffer version
+	// left by the last multi-selection operation.
+	primary [2]int
+	version int
+}
+
+// Selections returns the selections of the editor in document order, the
+// primary one included. The Start of each range is its caret.
+func (e *Editor) Selections() []TextRange {
+	e.initBuffer()
+	e.validateExtraSelections()
+	start, end := e.text.Selection()
+	selections := append(slices.Clone(e.multiSel.extras), TextRange{Start: start, End: end})
+	for i, sel := range selections[:len(selections)-1] {
+		// the extra selections keep the caret at End.
+		selections[i] = TextRange{Start: sel.End, End: sel.Start}
+	}
+	slices.SortFunc(selections, func(a, b TextRange) int {
+		return min(a.Start, a.End) - min(b.Start, b.End)
+	})
+	return selections
+}
+
+// SelectNextOccurrence selects the word at the caret if the selection is
+// empty. Otherwise, it keeps the selection and adds the next occurrence of the
+// selected text as the new primary selection, wrapping around the end of the
+// document. It reports whether the selection is changed.
+func (e *Editor) SelectNextOccurrence() bool {
+	return e.selectNextOccurrence(true)
+}
+
+// SkipOccurrence moves the primary selection to the next occurrence of the
+// selected text, without keeping the selected occurrence.
+func (e *Editor) SkipOccurrence() bool {
+	return e.selectNextOccurrence(false)
+}
+
+func (e *Editor) selectNextOccurrence(keep bool) bool {
+	e.initBuffer()
+	e.validateExtraSelections()
+	start, end := e.text.Selection()
+	if start == end {
+		if !keep {
+			return false
+		}
+		wordStart, wordEnd := e.text.WordBoundariesAt(start, false)
+		if wordStart >= wordEnd {
+			return false
+		}
+		e.SetCaret(wordEnd, wordStart)
+		e.multiSel.wholeWord = true
+		e.recordMultiSelection()
+		return true
+	}
+
+	next, ok := e.nextOccurrence(min(start, end), max(start, end))
+	if !ok {
+		return false
+	}
+	if keep {
+		e.multiSel.extras = append(e.multiSel.extras, TextRange{Start: min(start, end), End: max(start, end)})
+	}
+	e.SetCaret(next.End, next.Start)
+	e.recordMultiSelection()
+	return true
+}
+
+// SelectAllOccurrences selects all the occurrences of the selected text, or
+// of the word at the caret if the selection is empty. The occurrence at the
+// caret is the primary selection. It returns the number of selections.
+func (e *Editor) SelectAllOccurrences() int {
+	e.initBuffer()
+	e.validateExtraSelections()
+	start, end := e.text.Selection()
+	if start == end {
+		if !e.SelectNextOccurrence() {
+			return 0
+		}
+		start, end = e.text.Selection()
+	}
+	start, end = min(start, end), max(start, end)
+
+	occurrences := e.occurrences(start, end)
+	e.multiSel.extras = e.multiSel.extras[:0]
+	for _, occ := range occurrences {
+		if occ.Start != start {
+			e.multiSel.extras = append(e.multiSel.extras, occ)
+		}
+	}
+	e.SetCaret(end, start)
+	e.recordMultiSelection()
+	return len(e.multiSel.extras) + 1
+}
+
+// occurrences returns the occurrences of the text between start and end,
+// matching whole words only if the multi-selection started from a word.
+func (e *Editor) occurrences(start, end int) []TextRange {
+	var occurrences []TextRange
+	for _, occ := range e.text.FindAllTextOccurrences(start, end) {
+		if e.multiSel.wholeWord && !e.isWholeWord(occ[0], occ[1]) {
+			continue
+		}
+		occurrences = append(occurrences, TextRange{Start: occ[0], End: occ[1]})
+	}
+	return occurrences
+}
+
+// isWholeWord reports whether the text between start and end is not part of a
+// longer word.
+func (e *Editor) isWholeWord(start, end int) bool {
+	if start > 0 {
+		if r, err := e.text.ReadRuneAt(start - 1); err == nil && !e.text.IsWordSeperator(r) {
+			return false
+		}
+	}
+	if end < e.text.Len() {
+		if r, err := e.text.ReadRuneAt(end); err == nil && !e.text.IsWordSeperator(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextOccurrence returns the first occurrence after end not selected yet,
+// wrapping around the end of the document.
+func (e *Editor) nextOccurrence(start, end int) (TextRange, bool) {
+	occurrences := e.occurrences(start, end)
+	selected := func(occ TextRange) bool {
+		if occ.Start == start {
+			return true
+		}
+		return slices.ContainsFunc(e.multiSel.extras, func(sel TextRange) bool {
+			return sel.Start < occ.End && occ.Start < sel.End
+		})
+	}
+	i, _ := slices.BinarySearchFunc(occurrences, end, func(occ TextRange, off int) int {
+		return occ.Start - off
+	})
+	for n := range occurrences {
+		occ := occurrences[(i+n)%len(occurrences)]
+		if !selected(occ) {
+			return occ, true
+		}
+	}
+	return TextRange{}, false
+}
+
+// recordMultiSelection records the state left by a multi-selection
+// operation, and updates the decorations of the extra selections.
+func (e *Editor) recordMultiSelection() {
+	start, end := e.text.Selection()
+	e.multiSel.primary = [2]int{start, end}
+	e.multiSel.version = e.buffer.Version()
+
+	e.text.ClearDecorations(extraSelectionSource)
+	var selectColor gvcolor.Color
+	if e.colorPalette != nil {
+		selectColor = e.colorPalette.SelectColor
+	}
+	decos := make([]decoration.Decoration, 0, len(e.multiSel.extras))
+	for _, sel := range e.multiSel.extras {
+		if sel.Start == sel.End || !selectColor.IsSet() {
+			continue
+		}
+		decos = append(decos, decoration.Decoration{
+			Source:     extraSelectionSource,
+			Start:      sel.Start,
+			End:        sel.End,
+			Background: &decoration.Background{Color: selectColor},
+			Priority:   1,
+		})
+	}
+	e.text.AddDecorations(decos...)
+}
+
+// validateExtraSelections drops the extra selections if the primary
+// selection or the text was changed since the last multi-selection
+// operation.
+func (e *Editor) validateExtraSelections() {
+	start, end := e.text.Selection()
+	if e.multiSel.primary == [2]int{start, end} && e.multiSel.version == e.buffer.Version() {
+		return
+	}
+	e.clearExtraSelections()
+}
+
+func (e *Editor) clearExtraSelections() {
+	if len(e.multiSel.extras) > 0 {
+		e.text.ClearDecorations(extraSelectionSource)
+	}
+	e.multiSel = multiSelection{}
+}
+
+// hasExtraSelections reports whether there are selections besides the
+// primary one.
+func (e *Editor) hasExtraSelections() bool {
+	e.validateExtraSelections()
+	return len(e.multiSel.extras) > 0
+}
+
+// replaceSelections replaces the text of all the selections with s, leaving
+// a caret after each insertion. It returns the number of inserted runes.
+func (e *Editor) replaceSelections(s string) int {
+	inserted := 0
+	e.editSelections(func(sel TextRange) (int, int, string) {
+		inserted += utf8.RuneCountInString(s)
+		return sel.Start, sel.End, s
+	})
+	return inserted
+}
+
+// deleteAtSelections deletes the text of the non-empty selections, and the
+// grapheme clusters before, or after if graphemeClusters is positive, of the
+// carets.
+func (e *Editor) deleteAtSelections(graphemeClusters int) int {
+	deleted := 0
+	e.editSelections(func(sel TextRange) (int, int, string) {
+		start, end := sel.Start, sel.End
+		if start == end {
+			if graphemeClusters < 0 {
+				start = e.text.MoveGraphemes(start, graphemeClusters)
+			} else {
+				end = e.text.MoveGraphemes(end, graphemeClusters)
+			}
+		}
+		deleted += end - start
+		return start, end, ""
+	})
+	return deleted
+}
+
+// editSelections replaces the range returned by edit for each selection, in
+// a single undo step. The primary selection is kept the last one edited, so
+// that it stays the primary selection.
+func (e *Editor) editSelections(edit func(sel TextRange) (start, end int, s string)) {
+	start, end := e.text.Selection()
+	primary := TextRange{Start: min(start, end), End: max(start, end)}
+	selections := append(slices.Clone(e.multiSel.extras), primary)
+	slices.SortFunc(selections, func(a, b TextRange) int { return a.Start - b.Start })
+
+	edits := make([]Edit, len(selections))
+	for i, sel := range selections {
+		start, end, s := edit(sel)
+		if i > 0 {
+			// overlapping deletions are merged.
+			start = max(start, edits[i-1].End)
+			end = max(end, start)
+		}
+		edits[i] = Edit{Start: start, End: end, Text: s}
+	}
+
+	result := editVetoed
+	e.Transaction(func(tx *EditTx) {
+		_, result = tx.replaceEdits(edits)
+	})
+	if result != editApplied {
+		return
+	}
+	carets := make([]int, len(edits))
+	shift := 0
+	for i, r := range edits {
+		carets[i] = r.Start + shift + utf8.RuneCountInString(r.Text)
+		shift += utf8.RuneCountInString(r.Text) - (r.End - r.Start)
+	}
+
+	e.setSelectionCarets(carets, slices.Index(selections, primary))
+}
+
+// setSelectionCarets leaves a caret at each of the offsets, the one at index
+// primary being the primary selection.
+func (e *Editor) setSelectionCarets(carets []int, primary int) {
+	e.multiSel.extras = e.multiSel.extras[:0]
+	for i, caret := range carets {
+		if i != primary {
+			e.multiSel.extras = append(e.multiSel.extras, TextRange{Start: caret, End: caret})
+		}
+	}
+	e.SetCaret(carets[primary], carets[primary])
+	e.recordMultiSelection()
+}
+
+// paintExtraCarets paints the carets of the extra selections.
+func (e *Editor) paintExtraCarets(gtx layout.Context, material gvcolor.Color) {
+	if !e.showCaret || e.mode == ModeReadOnly || !e.hasExtraSelections() {
+		return
+	}
+	_, ascent, descent := e.text.CaretInfo()
+	width := max(gtx.Dp(e.caretStyle.width()/2), 1)
+	view := image.Rectangle{Max: gtx.Constraints.Max}
+	for _, sel := range e.multiSel.extras {
+		pos := e.text.RuneCoords(sel.End)
+		x, y := int(pos.X), int(pos.Y)
+		rect := view.Intersect(image.Rect(x-width, y-ascent, x+width, y+descent))
+		if !rect.Empty() {
+			paint.FillShape(gtx.Ops, material.NRGBA(), clip.Rect(rect).Op())
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package gvcode
+
+import (
+	"slices"
+	"testing"
+)
+
+func selectionTexts(e *Editor) []string {
+	var texts []string
+	for _, sel := range e.Selections() {
+		texts = append(texts, string([]rune(e.Text())[min(sel.Start, sel.End):max(sel.Start, sel.End)]))
+	}
+	return texts
+}
+
+func TestSelectNextOccurrence(t *testing.T) {
+	e := newTestEditor(t, "foo := fooBar(foo)\nfoo++\n")
+	e.SetCaret(1, 1)
+
+	if !e.SelectNextOccurrence() {
+		t.Fatal("expected the word at the caret to be selected")
+	}
+	if start, end := e.Selection(); start != 3 || end != 0 {
+		t.Fatalf("got selection %d-%d, want 3-0", start, end)
+	}
+
+	// fooBar is skipped as the occurrences match whole words.
+	e.SelectNextOccurrence()
+	e.SelectNextOccurrence()
+	want := []TextRange{{Start: 3, End: 0}, {Start: 17, End: 14}, {Start: 22, End: 19}}
+	if got := e.Selections(); !slices.Equal(got, want) {
+		t.Fatalf("got selections %v, want %v", got, want)
+	}
+
+	// all the occurrences are selected.
+	if e.SelectNextOccurrence() {
+		t.Fatal("expected no more occurrences")
+	}
+}
+
+func TestSelectNextOccurrenceWraps(t *testing.T) {
+	e := newTestEditor(t, "ab ab ab")
+	e.SetCaret(8, 6)
+
+	e.SelectNextOccurrence()
+	if start, end := e.Selection(); start != 2 || end != 0 {
+		t.Fatalf("got selection %d-%d, want 2-0", start, end)
+	}
+	if got := len(e.Selections()); got != 2 {
+		t.Fatalf("got %d selections, want 2", got)
+	}
+}
+
+func TestSkipOccurrence(t *testing.T) {
+	e := newTestEditor(t, "x y x y x")
+	e.SetCaret(0, 0)
+	e.SelectNextOccurrence()
+	e.SelectNextOccurrence()
+	if !e.SkipOccurrence() {
+		t.Fatal("expected the selection to move")
+	}
+	want := []TextRange{{Start: 1, End: 0}, {Start: 9, End: 8}}
+	if got := e.Selections(); !slices.Equal(got, want) {
+		t.Fatalf("got selections %v, want %v", got, want)
+	}
+}
+
+func TestSelectAllOccurrences(t *testing.T) {
+	e := newTestEditor(t, "a.b a.b ab a.b")
+	e.SetCaret(4, 7)
+	if n := e.SelectAllOccurrences(); n != 3 {
+		t.Fatalf("got %d selections, want 3", n)
+	}
+	// the selected occurrence stays the primary selection.
+	if start, end := e.Selection(); start != 7 || end != 4 {
+		t.Fatalf("got selection %d-%d, want 7-4", start, end)
+	}
+	if got, want := selectionTexts(e), []string{"a.b", "a.b", "a.b"}; !slices.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestEditMultipleSelections(t *testing.T) {
+	e := newTestEditor(t, "v := v + v\n")
+	e.SetCaret(0, 0)
+	e.SelectAllOccurrences()
+
+	e.Insert("val")
+	if got, want := e.Text(), "val := val + val\n"; got != want {
+		t.Fatalf("insert: got %q, want %q", got, want)
+	}
+	want := []TextRange{{Start: 3, End: 3}, {Start: 10, End: 10}, {Start: 16, End: 16}}
+	if got := e.Selections(); !slices.Equal(got, want) {
+		t.Fatalf("got carets %v, want %v", got, want)
+	}
+
+	if n := e.Delete(-1); n != 3 {
+		t.Fatalf("deleted %d runes, want 3", n)
+	}
+	if got, want := e.Text(), "va := va + va\n"; got != want {
+		t.Fatalf("delete: got %q, want %q", got, want)
+	}
+
+	// each edit of all the selections is undone in a single step.
+	e.undo()
+	e.undo()
+	if got, want := e.Text(), "v := v + v\n"; got != want {
+		t.Fatalf("undo: got %q, want %q", got, want)
+	}
+}
+
+func TestDeleteGraphemesAtSelections(t *testing.T) {
+	// the emoji is a ZWJ sequence of 5 runes, followed by an e with a
+	// combining accent.
+	e := newTestEditor(t, "a,👨\u200d👩\u200d👧 a,👨\u200d👩\u200d👧 a,e\u0301\n")
+	e.SetCaret(0, 0)
+	e.SelectAllOccurrences()
+	e.Delete(1)
+	if n := e.Delete(1); n != 3 {
+		t.Fatalf("deleted %d runes, want 3", n)
+	}
+
+	// the carets delete the clusters after them.
+	if n := e.Delete(1); n != 12 {
+		t.Fatalf("deleted %d runes, want 12", n)
+	}
+	if got, want := e.Text(), "  \n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestMoveCaretDropsExtraSelections(t *testing.T) {
+	e := newTestEditor(t, "a a a")
+	e.SetCaret(0, 0)
+	e.SelectAllOccurrences()
+	if got := len(e.Selections()); got != 3 {
+		t.Fatalf("got %d selections, want 3", got)
+	}
+
+	e.SetCaret(2, 2)
+	if got := len(e.Selections()); got != 1 {
+		t.Fatalf("got %d selections after moving the caret, want 1", got)
+	}
+	e.Insert("b")
+	if got, want := e.Text(), "a ba a"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
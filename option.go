@@ -1,12 +1,17 @@
 package gvcode
 
 import (
+	"time"
+
 	"gioui.org/font"
+	"gioui.org/layout"
 	"gioui.org/text"
 	"gioui.org/unit"
+	gvcolor "github.com/oligo/gvcode/color"
 	"github.com/oligo/gvcode/gutter"
 	"github.com/oligo/gvcode/gutter/providers"
 	"github.com/oligo/gvcode/internal/folding"
+	"github.com/oligo/gvcode/snippet"
 	"github.com/oligo/gvcode/textstyle/syntax"
 )
 
@@ -83,6 +88,16 @@ func WithTabWidth(tabWidth int) EditorOption {
 	}
 }
 
+// WithVisualTabWidth renders tab characters as wide as tabWidth spaces, without
+// changing the TabWidth used by the editing operations. Zero renders tabs with
+// TabWidth.
+func WithVisualTabWidth(tabWidth int) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		e.text.VisualTabWidth = tabWidth
+	}
+}
+
 // WithSoftTab controls the behaviour when user try to insert a Tab character.
 // If set to true, the editor will insert the amount of space characters specified by
 // TabWidth, else the editor insert a \t character.
@@ -102,6 +117,25 @@ func WithWordSeperators(seperators string) EditorOption {
 	}
 }
 
+// WithWordChars configures a set of characters that are part of words, even
+// if they are word separators.
+func WithWordChars(chars string) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		e.text.WordChars = chars
+	}
+}
+
+// WithSubwordMotion enables or disables stopping at the camelCase and
+// snake_case parts of the words when moving by words with Ctrl+Left/Right,
+// and when selecting words by double-clicking.
+func WithSubwordMotion(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		e.text.SubwordMotion = enabled
+	}
+}
+
 // WithQuotePairs configures a set of quote pairs that can be auto-completed when the left
 // half is entered.
 func WithQuotePairs(quotePairs map[rune]rune) EditorOption {
@@ -120,6 +154,23 @@ func WithBracketPairs(bracketPairs map[rune]rune) EditorOption {
 	}
 }
 
+// WithPairs configures a set of multi-rune pairs, like "/*" and "*/", that can be
+// auto-completed when the opening half is entered. The editor also puts the
+// closing half on its own line when a line break is inserted between the halves.
+func WithPairs(pairs map[string]string) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		e.text.BracketsQuotes.SetPairs(pairs)
+	}
+}
+
+// WithPairProfile configures the bracket, quote and multi-rune pairs all at once.
+func WithPairProfile(profile PairProfile) EditorOption {
+	return func(e *Editor) {
+		e.SetPairProfile(profile)
+	}
+}
+
 // ReadOnlyMode controls whether the contents of the editor can be altered by
 // user interaction. If set to true, the editor will allow selecting text
 // and copying it interactively, but not modifying it.
@@ -142,6 +193,77 @@ func WrapLine(enabled bool) EditorOption {
 	}
 }
 
+// WithWrapIndent configures the indentation of the continuation lines when
+// the lines are wrapped. If preserve is true, they are indented as much as the
+// first line of their paragraph. hanging is the number of spaces they are
+// further indented by.
+func WithWrapIndent(preserve bool, hanging int) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		e.text.SetWrapIndent(preserve, hanging)
+	}
+}
+
+// WithWrapIndicators configures whether an arrow is drawn at the end of the
+// screen lines continued on the next one when the lines are wrapped.
+func WithWrapIndicators(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.wrapIndicators = enabled
+	}
+}
+
+// WithCurrentLineHighlight configures whether the background of the caret
+// line is filled with the LineColor of the color scheme, over the whole width
+// of the editor. With hideOnSelection, it is hidden while text is selected.
+// The highlight replaces the one of the line number gutter.
+func WithCurrentLineHighlight(enabled, hideOnSelection bool) EditorOption {
+	return func(e *Editor) {
+		e.currentLine = currentLineHighlight{enabled: enabled, hideOnSelection: hideOnSelection}
+	}
+}
+
+// WithSelectionHighlight configures whether the other occurrences of the
+// selected text in the visible lines are highlighted with a lighter selection
+// color. Selections spanning multiple lines or shorter than minLength runes
+// are not highlighted. The highlight is enabled by default.
+func WithSelectionHighlight(enabled bool, minLength int) EditorOption {
+	return func(e *Editor) {
+		e.selectionHighlighter.disabled = !enabled
+		e.selectionHighlighter.minLength = minLength
+		e.selectionHighlighter.MarkDirty()
+	}
+}
+
+// WithLineEndMarkers configures whether a pilcrow is drawn at the end of
+// each line, preceded by a currency sign for the lines ending with CR LF.
+func WithLineEndMarkers(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.lineEndMarkers = enabled
+	}
+}
+
+// WithVirtualLayout configures whether only the text around the viewport is
+// shaped, estimating the heights of the other lines. It keeps documents of
+// millions of lines responsive, at the cost of scroll extents and positions
+// being approximate until the lines are scrolled into view.
+func WithVirtualLayout(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		e.text.SetVirtualLayout(enabled)
+	}
+}
+
+// WithLayoutSnapshots configures whether an immutable snapshot of the layout
+// is published after each layout of the text, for [Editor.LayoutSnapshot].
+// The gutter providers read the lines of the snapshot too.
+func WithLayoutSnapshots(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		e.text.SetLayoutSnapshots(enabled)
+		e.snapshot.Store(e.text.LayoutSnapshot())
+	}
+}
+
 // Deprecated. Please use [WithGutter] or [WithDefaultGutters]
 // WithLineNumber configures whether to show line number or not.
 func WithLineNumber(enabled bool) EditorOption {
@@ -171,6 +293,7 @@ func WithAutoCompletion(completor Completion) EditorOption {
 func WithColorScheme(scheme syntax.ColorScheme) EditorOption {
 	return func(e *Editor) {
 		e.initBuffer()
+		e.paletteFade.stop()
 		e.colorPalette = &scheme.ColorPalette
 		e.text.SetColorScheme(&scheme)
 	}
@@ -185,6 +308,30 @@ func AddBeforePasteHook(hook BeforePasteHook) EditorOption {
 	}
 }
 
+// WithPrimarySelection enables the primary selection of the X11 convention on
+// Linux: the selected text is written to sel, and a middle-click pastes its
+// text at the click position. A nil sel, the default, disables it.
+func WithPrimarySelection(sel PrimarySelection) EditorOption {
+	return func(e *Editor) {
+		e.primary.sel = sel
+		e.primary.synced = [3]int{}
+	}
+}
+
+// WithPasteReindent enables or disables re-indenting the lines of multi-line
+// pastes, so that the pasted block keeps its relative indentation from the
+// indentation of the caret line.
+func WithPasteReindent(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.pasteReindent = enabled
+	}
+}
+
+// TextInputHook defines a hook to be called before the text typed by the user
+// is inserted. If it returns true, the text is consumed by the hook and not
+// inserted. This is used by modal editing addons.
+type TextInputHook func(gtx layout.Context, text string) bool
+
 // WithGutter adds a gutter provider to the editor. Creates a gutter manager if needed.
 // Multiple providers can be added by calling this function multiple times.
 func WithGutter(provider gutter.GutterProvider) EditorOption {
@@ -194,6 +341,7 @@ func WithGutter(provider gutter.GutterProvider) EditorOption {
 			e.gutterManager = gutter.NewManager()
 		}
 		e.gutterManager.Register(provider)
+		e.observeGutterEdits()
 	}
 }
 
@@ -235,7 +383,9 @@ func WithStickyLines() EditorOption {
 
 // WithCodeFolding enables code folding functionality.
 // Code folding allows users to collapse and expand code blocks (functions, types, imports, etc.).
-// Shortcuts: Alt+C toggles column mode, Ctrl+[ / Ctrl+] for fold/unfold, Ctrl+Shift+[ / Ctrl+Shift+] for fold/unfold all.
+// Shortcut+Shift+[ folds or unfolds the block at the caret line. The key is
+// also matched when it is named by its shifted symbol "{", as on X11 and
+// Wayland.
 func WithCodeFolding() EditorOption {
 	return func(e *Editor) {
 		e.initBuffer()
@@ -250,6 +400,15 @@ func WithCodeFolding() EditorOption {
 	}
 }
 
+// WithFoldPreview sets the maximum number of hidden lines previewed when
+// hovering over the placeholder or the gutter button of a collapsed fold. A
+// negative value disables the preview, and 0 uses the default of 10 lines.
+func WithFoldPreview(lines int) EditorOption {
+	return func(e *Editor) {
+		e.foldPreviewLines = lines
+	}
+}
+
 // WithColumnEdit enables column (vertical) editing mode.
 // Column editing allows selecting and editing a rectangular block of text across multiple lines.
 // Shortcut: Alt+C toggles column mode on/off.
@@ -283,3 +442,255 @@ func WithColorIndicators() EditorOption {
 		}
 	}
 }
+
+// WithErrorLens enables or disables the error lens, which renders the
+// message of the diagnostics after the end of the offending lines. The
+// message uses the text size of the editor so it zooms with the text.
+// Clicking the message generates a DiagnosticEvent.
+func WithErrorLens(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		e.diagnostics.lens = enabled
+	}
+}
+
+// WithDiagnosticSquiggles controls whether diagnostics are underlined with
+// squiggles. It is enabled by default, and is independent of the error lens.
+func WithDiagnosticSquiggles(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		if e.diagnostics.noSquiggles == !enabled {
+			return
+		}
+		e.diagnostics.noSquiggles = !enabled
+		if len(e.diagnostics.items) > 0 {
+			e.SetDiagnostics(e.Diagnostics()...)
+		}
+	}
+}
+
+// WithScrollAnimation sets the duration of the easing animation scrolling
+// the viewport to the targets of ScrollToLine, ScrollToCaret and RevealRange.
+// Zero, the default, disables the animation.
+func WithScrollAnimation(duration time.Duration) EditorOption {
+	return func(e *Editor) {
+		e.scrollAnim.duration = max(duration, 0)
+	}
+}
+
+// WithPaletteTransition sets the duration of the crossfade from the colors
+// of the editor to the palette set by SetColorPalette. Zero, the default,
+// swaps the colors at once.
+func WithPaletteTransition(duration time.Duration) EditorOption {
+	return func(e *Editor) {
+		e.paletteFade.duration = max(duration, 0)
+	}
+}
+
+// WithFoldAnimation sets the duration of the animation sliding the lines to
+// their new positions, and fading in the expanded lines, when folds are
+// collapsed or expanded, e.g., 120ms. Zero, the default, disables the
+// animation. The animation is not symmetric: the collapsed lines are hidden
+// at once rather than faded out, as they are no longer laid out, while the
+// lines below them slide up.
+func WithFoldAnimation(duration time.Duration) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		e.foldAnim.duration = max(duration, 0)
+		e.text.SetFoldAnimation(e.foldAnim.duration > 0 && !e.reducedMotion)
+	}
+}
+
+// WithReducedMotion disables the scroll, palette and fold animations if
+// enabled, e.g., to follow the reduced motion setting of the platform. The
+// animations in progress end in the next frame, and their durations are kept
+// to restore them once disabled.
+func WithReducedMotion(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		e.reducedMotion = enabled
+		e.text.SetFoldAnimation(e.foldAnim.duration > 0 && !e.reducedMotion)
+	}
+}
+
+// WithColorSwatches paints a swatch of the color before the hex and rgb()
+// color literals of the visible lines, like "#ff8800" or
+// "rgba(0, 0, 0, 0.5)". Clicking a swatch generates a ColorPickEvent.
+func WithColorSwatches(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.swatches.enabled = enabled
+		e.swatches.valid = false
+	}
+}
+
+// WithKineticScrolling enables continuing the scrolling of trackpads with a
+// decelerating fling when the fingers are lifted, for the platforms not
+// doing it natively.
+func WithKineticScrolling(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.scroller.Kinetic = enabled
+	}
+}
+
+// WithReferenceColors overrides the colors used to paint the references of
+// each kind set by SetReferenceHighlights.
+func WithReferenceColors(colors map[ReferenceKind]gvcolor.Color) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		e.references.colors = colors
+		if len(e.references.items) > 0 {
+			e.SetReferenceHighlights(e.ReferenceHighlights())
+		}
+	}
+}
+
+// WithSeverityColors overrides the colors used to paint the squiggles and
+// the error lens messages of each diagnostic severity.
+func WithSeverityColors(colors map[DiagnosticSeverity]gvcolor.Color) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		e.diagnostics.colors = colors
+	}
+}
+
+// WithEmptyAreaWidget sets a widget to render in the area below the last line
+// of text, e.g., a watermark or a button to append content. The widget gets
+// the exact size of the visible empty area, and clicks inside of it are not
+// used to place the caret. Scrolling the editor still works over the area.
+func WithEmptyAreaWidget(w layout.Widget) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		e.emptyArea = w
+	}
+}
+
+// WithVariableResolver sets a resolver for snippet variables that only the
+// host knows about, such as TM_FILENAME, TM_FILEPATH or CLIPBOARD. Variables
+// it does not resolve fall back to the ones known to the editor.
+func WithVariableResolver(resolver snippet.VariableResolver) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		e.variableResolver = resolver
+	}
+}
+
+// WithPagingMode sets how PageUp/PageDown and the half-page motions
+// (Alt+PageUp/PageDown) treat the caret. The default is PageMoveCaret.
+func WithPagingMode(mode PagingMode) EditorOption {
+	return func(e *Editor) {
+		e.initBuffer()
+		e.pagingMode = mode
+	}
+}
+
+// WithCaretMovement sets how the Left and Right arrow keys move the caret in
+// text mixing LTR and RTL runs. The default is CaretLogical.
+func WithCaretMovement(mode CaretMovement) EditorOption {
+	return func(e *Editor) {
+		e.caretMovement = mode
+	}
+}
+
+// WithKeymap sets the keymap dispatching key strokes to commands, replacing
+// DefaultKeymap. A keymap can be shared by multiple editors.
+func WithKeymap(km *Keymap) EditorOption {
+	return func(e *Editor) {
+		e.keymap = km
+	}
+}
+
+// WithInvalidUTF8Policy sets how the text with invalid UTF-8 set by SetText
+// or pasted is handled. The default policy is InvalidUTF8Replace.
+func WithInvalidUTF8Policy(policy InvalidUTF8Policy) EditorOption {
+	return func(e *Editor) {
+		e.invalidUTF8 = policy
+	}
+}
+
+// WithNFCNormalization normalizes the typed and pasted text to the Unicode
+// normalization form C, so that the same text is always encoded by the same
+// code points, e.g., a letter followed by a combining accent is replaced by
+// the accented letter.
+func WithNFCNormalization(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.normalizeNFC = enabled
+	}
+}
+
+// WithUnicodeWarnings highlights the characters which may hide what the code
+// does: the bidirectional controls, the invisible characters, and the
+// characters looking like ASCII ones in Latin words. They are reported by
+// UnicodeWarnings, and a UnicodeWarningsEvent is generated when they change.
+func WithUnicodeWarnings(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.unicodeWarnings.enabled = enabled
+		if !enabled && e.buffer != nil {
+			e.unicodeWarnings.reset(e.buffer)
+			e.ClearDecorations(unicodeWarningSource)
+		}
+	}
+}
+
+// WithBreadcrumbs enables the BreadcrumbsEvent generated when the scopes
+// enclosing the caret, returned by Breadcrumbs, change.
+func WithBreadcrumbs(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.breadcrumbs.enabled = enabled
+		e.breadcrumbs.synced = false
+		e.breadcrumbs.path = nil
+	}
+}
+
+// WithFrameBudget sets the time budget of a frame. A SlowFrameEvent is
+// generated for each frame exceeding it. Zero, the default, disables the
+// events, while the frames are still measured by Stats.
+func WithFrameBudget(budget time.Duration) EditorOption {
+	return func(e *Editor) {
+		e.frames.budget = budget
+	}
+}
+
+// WithBinaryDetection enables or disables the detection of binary content in
+// SetText, which is enabled by default. It is also disabled by the
+// InvalidUTF8PassThrough policy.
+func WithBinaryDetection(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.allowBinary = !enabled
+	}
+}
+
+// WithClipboardRing sets the clipboard ring keeping the recently copied
+// texts. A ring can be shared by multiple editors.
+func WithClipboardRing(ring *ClipboardRing) EditorOption {
+	return func(e *Editor) {
+		e.clipboardRing = ring
+	}
+}
+
+// WithGoToFlash enables or disables flashing the line that GoToLineColumn
+// moves the caret to.
+func WithGoToFlash(enabled bool) EditorOption {
+	return func(e *Editor) {
+		e.gotoFlash = enabled
+	}
+}
+
+// WithZoom enables zooming the text with pinches and Ctrl+wheels, between
+// minLevel and maxLevel times the text size. They default to 0.5 and 3 if
+// not positive. The wheels scrolling the editor are not passed to the outer
+// scrollables when it is zoomable.
+func WithZoom(minLevel, maxLevel float32) EditorOption {
+	return func(e *Editor) {
+		e.zoom.minLevel = minLevel
+		e.zoom.maxLevel = maxLevel
+		e.scroller.Zoomable = true
+	}
+}
+
+// WithAccessibleName sets the name of the editor read out by the screen
+// readers before the caret position, e.g., the name of the edited file.
+func WithAccessibleName(name string) EditorOption {
+	return func(e *Editor) {
+		e.a11y.name = name
+	}
+}
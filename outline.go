@@ -0,0 +1,231 @@
+package gvcode
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/oligo/gvcode/internal/folding"
+)
+
+// SymbolKind is the kind of a symbol of the document outline.
+type SymbolKind uint8
+
+const (
+	// SymbolFunction is a function or a method.
+	SymbolFunction SymbolKind = iota
+	// SymbolType is a type definition.
+	SymbolType
+	// SymbolRegion is a user-defined region, e.g., "//region Name".
+	SymbolRegion
+	// SymbolPackage is the package of the file, only reported by
+	// Breadcrumbs.
+	SymbolPackage
+)
+
+// String returns the name of the kind.
+func (k SymbolKind) String() string {
+	switch k {
+	case SymbolFunction:
+		return "function"
+	case SymbolType:
+		return "type"
+	case SymbolRegion:
+		return "region"
+	case SymbolPackage:
+		return "package"
+	default:
+		return "unknown"
+	}
+}
+
+// Symbol is an entry of the document outline.
+type Symbol struct {
+	// Name is the name of the symbol, e.g., the name of the function.
+	Name string
+	// Kind is the kind of the symbol.
+	Kind SymbolKind
+	// Line is the 0-based line declaring the symbol.
+	Line int
+	// Range is the rune range of the symbol, from the start of its first
+	// line to the end of its last line, the line break excluded.
+	Range TextRange
+	// NameRange is the rune range of the name in the declaring line. It is
+	// empty at the start of the line if the name is not found.
+	NameRange TextRange
+	// Children are the symbols nested in the symbol, e.g., the functions of
+	// a region.
+	Children []Symbol
+}
+
+// Outline returns the symbols of the document, the functions, the types and
+// the regions detected by the folding backend, nested by their ranges and
+// sorted by position. The text is analyzed with the fold markers of the
+// language set by SetLanguage, even if code folding is not enabled. Hosts
+// can render it as a symbol sidebar, and call JumpToSymbol when an entry is
+// clicked.
+func (e *Editor) Outline() []Symbol {
+	s := e.analyzeStructure()
+	return s.symbols()
+}
+
+// JumpToSymbol moves the caret to the name of sym and scrolls it to the
+// center of the viewport, expanding the collapsed folds hiding it. Symbols
+// not declared in the document, with a negative Line, are ignored.
+func (e *Editor) JumpToSymbol(sym Symbol) {
+	if sym.Line < 0 {
+		return
+	}
+	e.initBuffer()
+	e.GoToLineColumn(sym.Line, sym.NameRange.Start-e.text.ConvertPos(sym.Line, 0))
+}
+
+// structure is the result of the analysis of the document structure: the
+// lines of the text and their fold ranges.
+type structure struct {
+	lines []string
+	// lineStarts are the rune offsets of the lines.
+	lineStarts []int
+	folds      []folding.FoldRange
+}
+
+// analyzeStructure detects the fold ranges of the text. The fold manager of
+// the editor is reused if code folding is enabled, as its analysis is cached
+// until the text changes.
+func (e *Editor) analyzeStructure() structure {
+	e.initBuffer()
+	text := e.Text()
+	lines := strings.Split(text, "\n")
+
+	fm := e.text.FoldManager()
+	if fm == nil {
+		fm = folding.NewManager()
+		if e.language.ID != "" {
+			fm.SetMarkers(folding.Markers{
+				LineComment:       e.language.Comments.Line,
+				BlockCommentStart: e.language.Comments.BlockStart,
+				BlockCommentEnd:   e.language.Comments.BlockEnd,
+				Region:            e.language.FoldRegion,
+			})
+		}
+	}
+	fm.AnalyzeLines(lines)
+
+	lineStarts := make([]int, len(lines))
+	off := 0
+	for i, line := range lines {
+		lineStarts[i] = off
+		off += utf8.RuneCountInString(line) + 1
+	}
+	return structure{lines: lines, lineStarts: lineStarts, folds: fm.GetFoldRanges()}
+}
+
+// symbolKind maps the fold types to the kinds of symbols. The other folds,
+// like comments and import blocks, are not symbols.
+func symbolKind(t folding.FoldType) (SymbolKind, bool) {
+	switch t {
+	case folding.FoldTypeFunction:
+		return SymbolFunction, true
+	case folding.FoldTypeType:
+		return SymbolType, true
+	case folding.FoldTypeRegion:
+		return SymbolRegion, true
+	}
+	return 0, false
+}
+
+// symbolFolds returns the folds of the symbols, sorted by start line, the
+// outer folds first.
+func (s structure) symbolFolds() []folding.FoldRange {
+	folds := make([]folding.FoldRange, 0, len(s.folds))
+	for _, fold := range s.folds {
+		if _, ok := symbolKind(fold.Type); ok {
+			folds = append(folds, fold)
+		}
+	}
+	slices.SortStableFunc(folds, func(a, b folding.FoldRange) int {
+		if a.StartLine != b.StartLine {
+			return a.StartLine - b.StartLine
+		}
+		return b.EndLine - a.EndLine
+	})
+	return folds
+}
+
+// symbol converts fold to a symbol without children.
+func (s structure) symbol(fold folding.FoldRange) Symbol {
+	kind, _ := symbolKind(fold.Type)
+	startLine := min(fold.StartLine, len(s.lines)-1)
+	endLine := min(fold.EndLine, len(s.lines)-1)
+	sym := Symbol{
+		Name: fold.Name,
+		Kind: kind,
+		Line: startLine,
+		Range: TextRange{
+			Start: s.lineStarts[startLine],
+			End:   s.lineStarts[endLine] + utf8.RuneCountInString(strings.TrimSuffix(s.lines[endLine], "\r")),
+		},
+	}
+
+	sym.NameRange = TextRange{Start: sym.Range.Start, End: sym.Range.Start}
+	if col, ok := symbolNameColumn(s.lines[startLine], fold); ok {
+		sym.NameRange.Start += col
+		sym.NameRange.End = sym.NameRange.Start + utf8.RuneCountInString(fold.Name)
+	}
+	return sym
+}
+
+// symbols nests the symbols by their ranges.
+func (s structure) symbols() []Symbol {
+	var roots []Symbol
+	// stack holds the paths of indices from the roots to the innermost
+	// open symbol.
+	var stack []int
+	var ends []int
+	for _, fold := range s.symbolFolds() {
+		for len(stack) > 0 && fold.StartLine > ends[len(ends)-1] {
+			stack = stack[:len(stack)-1]
+			ends = ends[:len(ends)-1]
+		}
+
+		sym := s.symbol(fold)
+		children := &roots
+		for _, idx := range stack {
+			children = &(*children)[idx].Children
+		}
+		*children = append(*children, sym)
+		stack = append(stack, len(*children)-1)
+		ends = append(ends, fold.EndLine)
+	}
+	return roots
+}
+
+// symbolNameColumn returns the rune column of the name of fold in line,
+// searched after the keyword of the declaration and the receiver of the
+// methods.
+func symbolNameColumn(line string, fold folding.FoldRange) (int, bool) {
+	if fold.Name == "" {
+		return 0, false
+	}
+
+	from := len(line) - len(strings.TrimLeft(line, " \t"))
+	rest := line[from:]
+	for _, keyword := range []string{"func", "type"} {
+		if strings.HasPrefix(rest, keyword) {
+			rest = strings.TrimLeft(rest[len(keyword):], " \t")
+			break
+		}
+	}
+	if fold.Type == folding.FoldTypeFunction && strings.HasPrefix(rest, "(") {
+		if i := strings.IndexByte(rest, ')'); i >= 0 {
+			rest = rest[i+1:]
+		}
+	}
+	from = len(line) - len(rest)
+
+	idx := strings.Index(rest, fold.Name)
+	if idx < 0 {
+		return 0, false
+	}
+	return utf8.RuneCountInString(line[:from+idx]), true
+}
@@ -0,0 +1,86 @@
+package gvcode
+
+import (
+	"testing"
+
+	"github.com/oligo/gvcode/internal/folding"
+)
+
+const outlineSource = `package main
+
+import (
+	"fmt"
+)
+
+type Point struct {
+	X, Y int
+}
+
+func (p *Point) String() string {
+	return fmt.Sprint(p.X, p.Y)
+}
+
+func main() {
+	fmt.Println(Point{})
+}
+`
+
+func TestOutline(t *testing.T) {
+	e := newGoEditor(t, outlineSource)
+
+	symbols := e.Outline()
+	want := []struct {
+		name string
+		kind SymbolKind
+		line int
+	}{
+		{"Point", SymbolType, 6},
+		{"String", SymbolFunction, 10},
+		{"main", SymbolFunction, 14},
+	}
+	if len(symbols) != len(want) {
+		t.Fatalf("got %d symbols, want %d: %+v", len(symbols), len(want), symbols)
+	}
+	for i, w := range want {
+		sym := symbols[i]
+		if sym.Name != w.name || sym.Kind != w.kind || sym.Line != w.line {
+			t.Errorf("symbol %d: got %s %s at line %d, want %s %s at line %d", i, sym.Kind, sym.Name, sym.Line, w.kind, w.name, w.line)
+		}
+		if got := e.ReadRange(sym.NameRange.Start, sym.NameRange.End); got != w.name {
+			t.Errorf("symbol %d: name range reads %q", i, got)
+		}
+	}
+
+	if got := e.ReadRange(symbols[0].Range.Start, symbols[0].Range.End); got != "type Point struct {\n\tX, Y int\n}" {
+		t.Errorf("type range reads %q", got)
+	}
+
+	e.JumpToSymbol(symbols[1])
+	if start, end := e.Selection(); start != symbols[1].NameRange.Start || end != start {
+		t.Errorf("caret at %d-%d after the jump, want %d", start, end, symbols[1].NameRange.Start)
+	}
+}
+
+func TestOutlineNesting(t *testing.T) {
+	s := structure{
+		lines:      []string{"//region Shapes", "func area() {", "}", "//endregion", "func main() {", "}"},
+		lineStarts: []int{0, 16, 30, 32, 44, 58},
+		folds: []folding.FoldRange{
+			{StartLine: 0, EndLine: 3, Type: folding.FoldTypeRegion, Name: "Shapes"},
+			{StartLine: 1, EndLine: 2, Type: folding.FoldTypeFunction, Name: "area"},
+			{StartLine: 4, EndLine: 5, Type: folding.FoldTypeFunction, Name: "main"},
+		},
+	}
+
+	symbols := s.symbols()
+	if len(symbols) != 2 || symbols[0].Name != "Shapes" || symbols[1].Name != "main" {
+		t.Fatalf("got symbols %+v, want the region Shapes and the function main", symbols)
+	}
+	children := symbols[0].Children
+	if len(children) != 1 || children[0].Name != "area" {
+		t.Fatalf("got children %+v, want the function area", children)
+	}
+	if children[0].NameRange != (TextRange{Start: 21, End: 25}) {
+		t.Errorf("got name range %v, want 21-25", children[0].NameRange)
+	}
+}
@@ -0,0 +1,45 @@
+package gvcode
+
+import (
+	"github.com/oligo/gvcode/textview"
+)
+
+// PagingMode controls how PageUp/PageDown and the half-page motions behave.
+type PagingMode uint8
+
+const (
+	// PageMoveCaret moves the caret by a page and scrolls the viewport by the
+	// same distance, so the caret keeps its position on the screen.
+	PageMoveCaret PagingMode = iota
+	// PageScrollOnly scrolls the viewport by a page and keeps the caret where
+	// it is, even if it leaves the viewport.
+	PageScrollOnly
+)
+
+// MoveHalfPages moves by halfPages half pages, positive moves down and negative
+// moves up. The caret is moved or retained according to the paging mode. If
+// extend is true, the selection is extended to the new caret position.
+func (e *Editor) MoveHalfPages(halfPages int, extend bool) {
+	e.initBuffer()
+	if halfPages == 0 {
+		return
+	}
+
+	dy := halfPages * e.text.PageHeight() / 2
+	if e.pagingMode == PageScrollOnly {
+		e.text.ScrollRel(0, dy)
+		// keep the viewport away from the caret.
+		e.scrollCaret = false
+		return
+	}
+
+	selAct := textview.SelectionClear
+	if extend {
+		selAct = textview.SelectionExtend
+	}
+
+	// Scroll along with the caret. The scroll is clamped at the top and the
+	// bottom of the document, while the caret can still move to reach them.
+	e.text.ScrollRel(0, dy)
+	e.text.MoveHalfPages(halfPages, selAct)
+}
@@ -0,0 +1,75 @@
+package gvcode
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestPaging(t *testing.T) {
+	for _, mode := range []PagingMode{PageMoveCaret, PageScrollOnly} {
+		e := newTestEditor(t, strings.Repeat("line\n", 200), WithPagingMode(mode))
+
+		var router input.Router
+		shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+		frame := func() {
+			gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 300)), Source: router.Source()}
+			for {
+				if _, ok := e.Update(gtx); !ok {
+					break
+				}
+			}
+			e.Layout(gtx, shaper)
+			if !gtx.Focused(e) {
+				gtx.Execute(key.FocusCmd{Tag: e})
+			}
+			router.Frame(gtx.Ops)
+		}
+		press := func(name key.Name, mods key.Modifiers) {
+			router.Queue(key.Event{Name: name, Modifiers: mods, State: key.Press})
+			frame()
+		}
+		frame()
+		frame()
+
+		page := e.text.PageHeight()
+		press(key.NamePageDown, key.ModAlt)
+		if got := e.text.ScrollOff().Y; got != page/2 {
+			t.Fatalf("mode %d: got scroll offset %d after a half page, want %d", mode, got, page/2)
+		}
+		press(key.NamePageDown, 0)
+		if got := e.text.ScrollOff().Y; got != page/2+page {
+			t.Fatalf("mode %d: got scroll offset %d after a page, want %d", mode, got, page/2+page)
+		}
+
+		line, _ := e.CaretPos()
+		if mode == PageScrollOnly {
+			// the caret is left behind, and the layout doesn't scroll back to it.
+			if line != 0 {
+				t.Errorf("mode %d: got the caret moved to line %d", mode, line)
+			}
+			continue
+		}
+		// the caret keeps its position on the screen, up to a line.
+		lineHeight := lineY(e, 1) - lineY(e, 0)
+		if got := lineY(e, line) - e.text.ScrollOff().Y; got < lineY(e, 0)-lineHeight || got > lineY(e, 0)+lineHeight {
+			t.Errorf("mode %d: got the caret line at %d on the screen, want %d", mode, got, lineY(e, 0))
+		}
+
+		// Shift extends the selection upwards.
+		press(key.NamePageUp, key.ModAlt|key.ModShift)
+		if start, end := e.Selection(); start >= end {
+			t.Errorf("mode %d: got selection %d-%d, want it extended upwards", mode, start, end)
+		}
+		if got := e.text.ScrollOff().Y; got != page {
+			t.Errorf("mode %d: got scroll offset %d after a half page up, want %d", mode, got, page)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package painter
+
+import (
+	"iter"
+
+	"gioui.org/text"
+	"golang.org/x/image/math/fixed"
+)
+
+// Line is a shaped screen line of text to paint.
+type Line struct {
+	// XOff and YOff are the position of the dot of the first glyph of the
+	// line in the document coordinates. YOff is the baseline.
+	XOff fixed.Int26_6
+	YOff int
+	// Ascent and Descent are the vertical extents of the line.
+	Ascent  fixed.Int26_6
+	Descent fixed.Int26_6
+	// Glyphs of the line in the visual order.
+	Glyphs []*text.Glyph
+	// Runes is the number of runes represented by the line.
+	Runes int
+	// RuneOff is the rune offset of the first rune of the line in the document.
+	RuneOff int
+}
+
+// GetGlyphs returns a copy of count glyphs of the line from offset.
+func (li *Line) GetGlyphs(offset, count int) []text.Glyph {
+	if count <= 0 {
+		return []text.Glyph{}
+	}
+
+	out := make([]text.Glyph, count)
+	for idx, gl := range li.Glyphs[offset : offset+count] {
+		out[idx] = *gl
+	}
+
+	return out
+}
+
+// All iterates over the glyphs of the line.
+func (li *Line) All() iter.Seq[text.Glyph] {
+	return func(yield func(text.Glyph) bool) {
+		for _, gl := range li.Glyphs {
+			if !yield(*gl) {
+				return
+			}
+		}
+	}
+}
@@ -1,3 +1,7 @@
+// Package painter provides the primitives used by the editor to paint text and
+// shapes: runs of styled glyphs, underlines, squiggles, borders and the rounded
+// polygons of multi-line highlights. Gutter providers and host overlays can
+// use them to draw shapes consistent with the editor.
 package painter
 
 import (
@@ -11,8 +15,6 @@ import (
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
 	"gioui.org/text"
-	"gioui.org/unit"
-	lt "github.com/oligo/gvcode/internal/layout"
 
 	"golang.org/x/image/math/fixed"
 )
@@ -33,17 +35,21 @@ type TextPainter struct {
 	lineHeight fixed.Int26_6
 }
 
+// SetViewport sets the rectangle of document coordinates to fill with text,
+// and the scroll offset of the document.
 func (tp *TextPainter) SetViewport(viewport image.Rectangle, scrollOff image.Point) {
 	tp.viewport = viewport
 	tp.scrollOff = scrollOff
 }
 
+// SetLineHeight sets the line height used to pad the backgrounds and borders
+// of the runs vertically.
 func (tp *TextPainter) SetLineHeight(lineHeight fixed.Int26_6) {
 	tp.lineHeight = lineHeight
 }
 
 // Paint paints text and various styles originated from syntax hignlighting or decorations.
-func (tp *TextPainter) Paint(gtx layout.Context, shaper *text.Shaper, lines []lt.Line, defaultColor op.CallOp,
+func (tp *TextPainter) Paint(gtx layout.Context, shaper *text.Shaper, lines []Line, defaultColor op.CallOp,
 	syntaxTokens LineSplitter, decorations LineSplitter,
 ) {
 	m := op.Record(gtx.Ops)
@@ -80,7 +86,7 @@ func (tp *TextPainter) Paint(gtx layout.Context, shaper *text.Shaper, lines []lt
 	call.Add(gtx.Ops)
 }
 
-func (tp *TextPainter) paintText(gtx layout.Context, shaper *text.Shaper, lineOff f32.Point, line lt.Line,
+func (tp *TextPainter) paintText(gtx layout.Context, shaper *text.Shaper, lineOff f32.Point, line Line,
 	defaultMaterial op.CallOp, syntaxTokens LineSplitter,
 ) {
 	// split the line into runs.
@@ -94,29 +100,38 @@ func (tp *TextPainter) paintText(gtx layout.Context, shaper *text.Shaper, lineOf
 		})
 	}
 
-	tp.paintLine(gtx, shaper, lineOff, tp.runBuffer, defaultMaterial, false)
+	tp.paintLine(gtx, shaper, lineOff, line.XOff, tp.runBuffer, defaultMaterial, false)
 }
 
-func (tp *TextPainter) paintDecorations(gtx layout.Context, shaper *text.Shaper, lineOff f32.Point, line lt.Line,
+func (tp *TextPainter) paintDecorations(gtx layout.Context, shaper *text.Shaper, lineOff f32.Point, line Line,
 	defaultMaterial op.CallOp, decorations LineSplitter,
 ) {
 	if isNil(decorations) {
 		return
 	}
 	decorations.Split(line, &tp.runBuffer)
-	tp.paintLine(gtx, shaper, lineOff, tp.runBuffer, defaultMaterial, true)
+	tp.paintLine(gtx, shaper, lineOff, line.XOff, tp.runBuffer, defaultMaterial, true)
 }
 
-func (tp *TextPainter) paintLine(gtx layout.Context, shaper *text.Shaper, lineOffset f32.Point, runs []RenderRun,
-	defaultMaterial op.CallOp, noText bool,
+func (tp *TextPainter) paintLine(gtx layout.Context, shaper *text.Shaper, lineOffset f32.Point, lineX fixed.Int26_6,
+	runs []RenderRun, defaultMaterial op.CallOp, noText bool,
 ) {
 	// Let drawing begin at the offset of the entire line.
 	defer op.Affine(f32.Affine2D{}.Offset(lineOffset)).Push(gtx.Ops).Pop()
 
 	// Iterate through the runs to paint the text.
 	for _, run := range runs {
-		// paint at the run offset.
-		spanOffset := op.Affine(f32.Affine2D{}.Offset(f32.Point{X: float32(run.Offset.Round())})).Push(gtx.Ops)
+		if len(run.Glyphs) == 0 {
+			continue
+		}
+		// paint at the dot of the first glyph of the run. It is not the
+		// advance of the runs before it if the run starts with right-to-left
+		// text, whose glyphs go toward the start of the line.
+		offset := run.Offset
+		if run.Glyphs[0].Flags&text.FlagTowardOrigin != 0 {
+			offset = run.Glyphs[0].X - lineX
+		}
+		spanOffset := op.Affine(f32.Affine2D{}.Offset(f32.Point{X: float32(offset.Round())})).Push(gtx.Ops)
 
 		// draw background
 		if run.Bg != (op.CallOp{}) {
@@ -151,142 +166,62 @@ func (tp *TextPainter) paintLine(gtx layout.Context, shaper *text.Shaper, lineOf
 }
 
 func (tp *TextPainter) drawText(gtx layout.Context, shaper *text.Shaper, run *RenderRun, defaultMaterial op.CallOp) {
-	// draw glyph
-	path := shaper.Shape(run.Glyphs)
-	outline := clip.Outline{Path: path}.Op().Push(gtx.Ops)
 	if run.Fg == (op.CallOp{}) {
 		run.Fg = defaultMaterial
 	}
-	run.Fg.Add(gtx.Ops)
-	paint.PaintOp{}.Add(gtx.Ops)
-	outline.Pop()
-	if call := shaper.Bitmaps(run.Glyphs); call != (op.CallOp{}) {
-		call.Add(gtx.Ops)
-	}
+	DrawGlyphs(gtx, shaper, run.Glyphs, run.Fg)
 }
 
-func (tp *TextPainter) drawStroke(gtx layout.Context, path clip.PathSpec, material op.CallOp) {
-	if material == (op.CallOp{}) {
+// DrawGlyphs fills the outlines of the glyphs shaped by shaper with material,
+// and draws their bitmaps, e.g., of emojis. The dot of the first glyph is at
+// the current origin, on the baseline.
+func DrawGlyphs(gtx layout.Context, shaper *text.Shaper, glyphs []text.Glyph, material op.CallOp) {
+	if len(glyphs) == 0 {
 		return
 	}
-
-	shape := clip.Stroke{
-		Path:  path,
-		Width: float32(gtx.Dp(unit.Dp(1))),
-	}.Op()
-
-	defer shape.Push(gtx.Ops).Pop()
+	outline := clip.Outline{Path: shaper.Shape(glyphs)}.Op().Push(gtx.Ops)
 	material.Add(gtx.Ops)
 	paint.PaintOp{}.Add(gtx.Ops)
+	outline.Pop()
+	if call := shaper.Bitmaps(glyphs); call != (op.CallOp{}) {
+		call.Add(gtx.Ops)
+	}
 }
 
 func (tp *TextPainter) drawUnderline(gtx layout.Context, run *RenderRun, material op.CallOp) {
-	descent := run.Glyphs[0].Descent
-	path := clip.Path{}
-	path.Begin(gtx.Ops)
-	// No need to move in x axis as the outer code already set the x offset.
-	path.Move(f32.Pt(0, fixedToFloat(descent)))
-
-	width := fixedToFloat(run.Advance())
-	path.Line(f32.Point{X: width})
-	path.Close()
-
 	if run.Underline.Color != (op.CallOp{}) {
 		material = run.Underline.Color
 	}
-
-	tp.drawStroke(gtx, path.End(), material)
+	// No need to move in x axis as the outer code already set the x offset.
+	DrawLine(gtx, fixedToFloat(run.Advance()), fixedToFloat(run.Glyphs[0].Descent), material)
 }
 
 func (tp *TextPainter) drawStrikethrough(gtx layout.Context, run *RenderRun, material op.CallOp) {
-	path := clip.Path{}
-	path.Begin(gtx.Ops)
-
 	ascent := run.Glyphs[0].Ascent
 	descent := run.Glyphs[0].Descent
-
 	deltaY := (ascent+descent)/2 - ascent
-	path.Move(f32.Point{X: 0, Y: fixedToFloat(deltaY)})
-
-	width := fixedToFloat(run.Advance())
-	path.Line(f32.Point{X: width})
-	path.Close()
 
 	if run.Strikethrough.Color != (op.CallOp{}) {
 		material = run.Strikethrough.Color
 	}
-
-	tp.drawStroke(gtx, path.End(), material)
+	DrawLine(gtx, fixedToFloat(run.Advance()), fixedToFloat(deltaY), material)
 }
 
 func (tp *TextPainter) drawBorder(gtx layout.Context, run *RenderRun, material op.CallOp) {
-	rect := clip.Rect(tp.adjustPadding(run.Bounds()))
 	if run.Border.Color != (op.CallOp{}) {
 		material = run.Border.Color
 	}
-
-	tp.drawStroke(gtx, rect.Path(), material)
+	DrawBorder(gtx, tp.adjustPadding(run.Bounds()), material)
 }
 
-// drawSquiggleQuad draw a wavy line using quadratic Bézier curve.
-//
-// Some parameters:
-//
-// startPoint: (x, y) - Where the squiggle begins.
-// endPoint: (x, y) - Where the squiggle ends.
-// amplitude: How "tall" each wave is (distance from the center line).
-// numWaves(or frequency): How many complete waves (up-down-up) you want.
-// segmentsPerWave: How many curve segments make up one complete wave. Usually 2 (one up, one down)
-// for quadratic.
-//
-// Calculate Points:
-//
-// Determine the total length of the squiggle (if horizontal, endPoint.x - startPoint.x).
-// Divide the length by the number of segments to find the segmentLength.
-// Iterate along the path, calculating control points and end points for each curve segment.
+// drawSquiggle draws a wavy line below the run, with an amplitude based on the
+// descent size.
 func (tp *TextPainter) drawSquiggle(gtx layout.Context, run *RenderRun, material op.CallOp) {
-	path := clip.Path{}
-	path.Begin(gtx.Ops)
-
-	descent := run.Glyphs[0].Descent
-	// calculate a amplitude based on the descent size.
-	var amplitude fixed.Int26_6 = descent / 2
-	// set the pen relative to the dot of the start glyph.
-	startX := fixed.I(0)
-	startY := descent
-	numWaves := run.Advance() / amplitude.Mul(fixed.I(2))
-	if numWaves <= 0 {
-		path.End() // pop the macro from the stack.
-		return
-	}
-
-	// Each wave has 2 segments (one up, one down)
-	numSegments := numWaves * 2
-	segmentWidth := run.Advance() / numSegments
-
-	path.MoveTo(f32.Pt(fixedToFloat(startX), fixedToFloat(startY)))
-
-	currentX := startX
-	currentAmplitude := amplitude // Start with positive amplitude
-
-	for range numSegments {
-		nextX := currentX + segmentWidth
-		controlX := currentX + segmentWidth/2.0
-		controlY := startY + currentAmplitude // Control point is at the peak or trough
-
-		path.QuadTo(f32.Pt(fixedToFloat(controlX), fixedToFloat(controlY)),
-			f32.Pt(fixedToFloat(nextX), fixedToFloat(startY))) // start and end are equal.
-		currentX = nextX
-		// Alternate amplitude for next segment (up/down)
-		currentAmplitude *= -1
-	}
-
-	// path.Close()
 	if run.Squiggle.Color != (op.CallOp{}) {
 		material = run.Squiggle.Color
 	}
-
-	tp.drawStroke(gtx, path.End(), material)
+	descent := fixedToFloat(run.Glyphs[0].Descent)
+	DrawSquiggle(gtx, fixedToFloat(run.Advance()), descent, descent/2, material)
 }
 
 // adjustPadding adjusts the vertical padding of a bounding box around the texts.
@@ -23,6 +23,9 @@ type PolygonBuilder struct {
 	polygons [][]f32.Point
 }
 
+// NewPolygonBuilder creates a PolygonBuilder. If expandEmpty is true, empty
+// rectangles, e.g. of empty lines, are widened to minWidth. radius is the
+// radius of the rounded corners, in pixels.
 func NewPolygonBuilder(expandEmpty bool, minWidth int, radius float32) *PolygonBuilder {
 	return &PolygonBuilder{
 		expandEmpty: expandEmpty,
@@ -240,6 +243,8 @@ func (pb *PolygonBuilder) Path(gtx layout.Context, points []f32.Point) clip.Path
 	return path.End()
 }
 
+// Paths returns the clip paths of the polygons detected by the last call to
+// Group.
 func (pb *PolygonBuilder) Paths(gtx layout.Context) []clip.PathSpec {
 	paths := make([]clip.PathSpec, 0, len(pb.polygons))
 	for _, points := range pb.polygons {
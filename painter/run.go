@@ -5,22 +5,27 @@ import (
 
 	"gioui.org/op"
 	"gioui.org/text"
-	lt "github.com/oligo/gvcode/internal/layout"
 	"golang.org/x/image/math/fixed"
 )
 
+// UnderlineStyle draws a line below a run. An empty Color uses the text color.
 type UnderlineStyle struct {
 	Color op.CallOp
 }
 
+// SquiggleStyle draws a wavy line below a run. An empty Color uses the text
+// color.
 type SquiggleStyle struct {
 	Color op.CallOp
 }
 
+// StrikethroughStyle draws a line through a run. An empty Color uses the
+// text color.
 type StrikethroughStyle struct {
 	Color op.CallOp
 }
 
+// BorderStyle draws a box around a run. An empty Color uses the text color.
 type BorderStyle struct {
 	Color op.CallOp
 }
@@ -37,7 +42,8 @@ type RenderRun struct {
 	Fg op.CallOp
 	// Bg is the background color encoded to Gio ops.
 	Bg op.CallOp
-	// Underline style for the run.
+	// Underline, Squiggle, Strikethrough and Border are the optional line
+	// styles of the run.
 	Underline     *UnderlineStyle
 	Squiggle      *SquiggleStyle
 	Strikethrough *StrikethroughStyle
@@ -50,15 +56,23 @@ func (s *RenderRun) Bounds() image.Rectangle {
 		return image.Rectangle{}
 	}
 
+	// the glyphs of right-to-left text are placed toward the start of the
+	// line from the first one.
 	rect := fixed.Rectangle26_6{}
+	dot := s.Glyphs[0].X
 	for _, g := range s.Glyphs {
 		rect.Min.Y = min(rect.Min.Y, -g.Ascent)
 		rect.Max.Y = max(rect.Max.Y, g.Descent)
-		rect.Max.X += g.Advance
+		if g.Flags&text.FlagTowardOrigin != 0 {
+			rect.Min.X = min(rect.Min.X, g.X-dot)
+			rect.Max.X = max(rect.Max.X, g.X-dot+g.Advance)
+		} else {
+			rect.Max.X += g.Advance
+		}
 	}
 
 	return image.Rectangle{
-		Min: image.Point{Y: rect.Min.Y.Floor()},
+		Min: image.Point{X: rect.Min.X.Floor(), Y: rect.Min.Y.Floor()},
 		Max: image.Point{
 			X: rect.Max.X.Ceil(),
 			Y: rect.Max.Y.Ceil(),
@@ -80,7 +94,7 @@ func (s *RenderRun) Advance() fixed.Int26_6 {
 	return w
 }
 
-// size returns the number of glyphs this run covers.
+// Size returns the number of glyphs this run covers.
 func (s *RenderRun) Size() int {
 	return len(s.Glyphs)
 }
@@ -90,5 +104,5 @@ func (s *RenderRun) Size() int {
 // RenderRuns.
 type LineSplitter interface {
 	// Split the line into runs and put the result in the runs array.
-	Split(line lt.Line, runs *[]RenderRun)
+	Split(line Line, runs *[]RenderRun)
 }
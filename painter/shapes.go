@@ -0,0 +1,105 @@
+package painter
+
+import (
+	"image"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+)
+
+// StrokePath strokes the path with a 1dp wide line filled with material. It
+// draws nothing if material is empty.
+func StrokePath(gtx layout.Context, path clip.PathSpec, material op.CallOp) {
+	if material == (op.CallOp{}) {
+		return
+	}
+
+	shape := clip.Stroke{
+		Path:  path,
+		Width: float32(gtx.Dp(unit.Dp(1))),
+	}.Op()
+
+	defer shape.Push(gtx.Ops).Pop()
+	material.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+}
+
+// FillPaths fills each of the paths with material.
+func FillPaths(gtx layout.Context, paths []clip.PathSpec, material op.CallOp) {
+	for _, path := range paths {
+		outline := clip.Outline{Path: path}.Op().Push(gtx.Ops)
+		material.Add(gtx.Ops)
+		paint.PaintOp{}.Add(gtx.Ops)
+		outline.Pop()
+	}
+}
+
+// FillRegions merges the rectangles into polygons and fills them with
+// material, rounding the outer corners by radius. It draws the rounded
+// multi-line highlight used by the editor for selections, so that overlays
+// can highlight text ranges the same way.
+func FillRegions(gtx layout.Context, rects []image.Rectangle, radius float32, material op.CallOp) {
+	pb := NewPolygonBuilder(false, 0, radius)
+	pb.Group(rects)
+	FillPaths(gtx, pb.Paths(gtx), material)
+}
+
+// DrawLine draws a horizontal line of the width at the y offset, starting
+// from the current origin.
+func DrawLine(gtx layout.Context, width, y float32, material op.CallOp) {
+	path := clip.Path{}
+	path.Begin(gtx.Ops)
+	path.Move(f32.Pt(0, y))
+	path.Line(f32.Point{X: width})
+	path.Close()
+
+	StrokePath(gtx, path.End(), material)
+}
+
+// DrawSquiggle draws a wavy line of the width at the y offset, starting from
+// the current origin. amplitude is the distance from the peaks of the waves to
+// the center line. Nothing is drawn if the width is less than one wave.
+//
+// The line is built from quadratic Bézier curves, one for each half of a
+// wave, with the control point at the peak or trough.
+func DrawSquiggle(gtx layout.Context, width, y, amplitude float32, material op.CallOp) {
+	if amplitude <= 0 {
+		return
+	}
+	numWaves := int(width / (amplitude * 2))
+	if numWaves <= 0 {
+		return
+	}
+
+	// Each wave has 2 segments (one up, one down)
+	numSegments := numWaves * 2
+	segmentWidth := width / float32(numSegments)
+
+	path := clip.Path{}
+	path.Begin(gtx.Ops)
+	path.MoveTo(f32.Pt(0, y))
+
+	currentX := float32(0)
+	currentAmplitude := amplitude // Start with positive amplitude
+	for range numSegments {
+		nextX := currentX + segmentWidth
+		controlX := currentX + segmentWidth/2
+		controlY := y + currentAmplitude // Control point is at the peak or trough
+
+		path.QuadTo(f32.Pt(controlX, controlY), f32.Pt(nextX, y)) // start and end are equal.
+		currentX = nextX
+		// Alternate amplitude for next segment (up/down)
+		currentAmplitude *= -1
+	}
+
+	StrokePath(gtx, path.End(), material)
+}
+
+// DrawBorder strokes the outline of the rectangle.
+func DrawBorder(gtx layout.Context, rect image.Rectangle, material op.CallOp) {
+	StrokePath(gtx, clip.Rect(rect).Path(), material)
+}
@@ -0,0 +1,24 @@
+package gvcode
+
+// PairProfile is the set of pairs auto-completed by the editor, usually
+// configured per language. A nil map of brackets or quotes means the built-in
+// pairs.
+type PairProfile struct {
+	// Brackets maps opening brackets to closing brackets.
+	Brackets map[rune]rune
+	// Quotes maps opening quotes to closing quotes.
+	Quotes map[rune]rune
+	// Pairs maps the opening halves of multi-rune pairs to their closing
+	// halves, e.g., "/*" to "*/", or `"""` to `"""`.
+	Pairs map[string]string
+}
+
+// SetPairProfile replaces the pairs auto-completed by the editor with the
+// ones of profile.
+func (e *Editor) SetPairProfile(profile PairProfile) {
+	e.initBuffer()
+	e.text.BracketsQuotes.SetBrackets(profile.Brackets)
+	e.text.BracketsQuotes.SetQuotes(profile.Quotes)
+	e.text.BracketsQuotes.SetPairs(profile.Pairs)
+	e.clearAutoInsertions()
+}
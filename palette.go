@@ -0,0 +1,101 @@
+package gvcode
+
+import (
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/gutter"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+// paletteTransition crossfades the colors of the editor from a palette to
+// the one set by SetColorPalette.
+type paletteTransition struct {
+	// duration of the crossfade. The colors are swapped at once if it is
+	// zero.
+	duration time.Duration
+
+	active   bool
+	from, to gvcolor.ColorPalette
+	begin    time.Time
+}
+
+func (t *paletteTransition) stop() {
+	t.active = false
+}
+
+// SetColorPalette replaces the colors of the editor at runtime, e.g., to
+// switch between the light and dark variants of a theme. The syntax tokens
+// refer to the colors added to the palette by their IDs, so the palette is
+// expected to come from a color scheme with the same styles; use
+// WithColorScheme to switch to a scheme with other scopes.
+//
+// The colors are crossfaded over the duration set by WithPaletteTransition,
+// and the gutter providers implementing gutter.PaletteObserver are notified
+// once the new colors are applied.
+func (e *Editor) SetColorPalette(palette gvcolor.ColorPalette) {
+	e.initBuffer()
+	palette = palette.Clone()
+	if e.colorPalette == nil {
+		scheme := syntax.ColorScheme{ColorPalette: palette}
+		e.colorPalette = &scheme.ColorPalette
+		e.text.SetColorScheme(&scheme)
+		e.notifyPalette()
+		return
+	}
+
+	t := &e.paletteFade
+	if t.duration <= 0 || e.reducedMotion {
+		t.stop()
+		*e.colorPalette = palette
+		e.notifyPalette()
+		return
+	}
+	// a transition in progress continues from its current colors.
+	t.from = e.colorPalette.Clone()
+	t.to = palette
+	t.begin = time.Time{}
+	t.active = true
+}
+
+// animatePalette sets the colors of the editor to those of the current frame
+// of the palette transition.
+func (e *Editor) animatePalette(gtx layout.Context) {
+	t := &e.paletteFade
+	if !t.active {
+		return
+	}
+	if t.begin.IsZero() {
+		t.begin = gtx.Now
+	}
+
+	if elapsed := gtx.Now.Sub(t.begin); elapsed < t.duration && !e.reducedMotion {
+		*e.colorPalette = t.from.Mix(&t.to, easeOutCubic(float32(elapsed)/float32(t.duration)))
+		gtx.Execute(op.InvalidateCmd{})
+		return
+	}
+	t.stop()
+	*e.colorPalette = t.to
+	e.notifyPalette()
+}
+
+// notifyPalette notifies the gutter providers implementing
+// gutter.PaletteObserver of the colors of the palette.
+func (e *Editor) notifyPalette() {
+	if e.gutterManager == nil {
+		return
+	}
+	var colors *gutter.GutterColors
+	for _, p := range e.gutterManager.Providers() {
+		observer, ok := p.(gutter.PaletteObserver)
+		if !ok {
+			continue
+		}
+		if colors == nil {
+			colors = e.gutterColors()
+		}
+		observer.PaletteChanged(colors)
+	}
+}
@@ -0,0 +1,120 @@
+package gvcode
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/gutter"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+type paletteObserver struct {
+	colors []*gutter.GutterColors
+}
+
+func (o *paletteObserver) ID() string    { return "palette" }
+func (o *paletteObserver) Priority() int { return 0 }
+
+func (o *paletteObserver) Width(gtx layout.Context, shaper *text.Shaper, params text.Parameters, lineCount int) unit.Dp {
+	return 0
+}
+
+func (o *paletteObserver) Layout(gtx layout.Context, ctx gutter.GutterContext) layout.Dimensions {
+	return layout.Dimensions{}
+}
+
+func (o *paletteObserver) PaletteChanged(colors *gutter.GutterColors) {
+	o.colors = append(o.colors, colors)
+}
+
+func testPalettes() (dark, light gvcolor.ColorPalette) {
+	dark.Foreground = gvcolor.MakeColor(color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+	dark.Background = gvcolor.MakeColor(color.NRGBA{A: 0xff})
+	dark.LineNumberColor = gvcolor.MakeColor(color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff})
+	light.Foreground = dark.Background
+	light.Background = dark.Foreground
+	light.LineNumberColor = gvcolor.MakeColor(color.NRGBA{R: 0x40, G: 0x40, B: 0x40, A: 0xff})
+	return dark, light
+}
+
+func TestSetColorPalette(t *testing.T) {
+	dark, light := testPalettes()
+	observer := &paletteObserver{}
+	e := &Editor{}
+	e.WithOptions(WithColorScheme(syntax.ColorScheme{ColorPalette: dark}), WithGutter(observer))
+
+	e.SetColorPalette(light)
+	if e.ColorPalette().Background.NRGBA() != light.Background.NRGBA() {
+		t.Fatalf("got background %v, want %v", e.ColorPalette().Background, light.Background)
+	}
+	if len(observer.colors) != 1 || observer.colors[0].TextHighlight.NRGBA() != light.LineNumberColor.NRGBA() {
+		t.Fatalf("expected the observer to be notified of the line number color, got %v", observer.colors)
+	}
+}
+
+func TestPaletteTransition(t *testing.T) {
+	dark, light := testPalettes()
+	observer := &paletteObserver{}
+	e := &Editor{}
+	e.WithOptions(
+		WithColorScheme(syntax.ColorScheme{ColorPalette: dark}),
+		WithTextSize(14),
+		WithPaletteTransition(200*time.Millisecond),
+	)
+	e.SetText("package main\n")
+	e.WithOptions(WithGutter(observer))
+
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	now := time.Now()
+	frame := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(300, 200)), Now: now}
+		e.Layout(gtx, shaper)
+	}
+
+	e.SetColorPalette(light)
+	if e.ColorPalette().Background.NRGBA() != dark.Background.NRGBA() {
+		t.Fatal("expected the colors to be crossfaded")
+	}
+	frame()
+	now = now.Add(100 * time.Millisecond)
+	frame()
+	bg := e.ColorPalette().Background.NRGBA()
+	if bg.R == 0 || bg.R == 0xff {
+		t.Fatalf("got background %v in the middle of the transition", bg)
+	}
+	if len(observer.colors) != 0 {
+		t.Fatal("expected the observer to be notified at the end of the transition")
+	}
+
+	now = now.Add(100 * time.Millisecond)
+	frame()
+	if e.ColorPalette().Background.NRGBA() != light.Background.NRGBA() {
+		t.Fatalf("got background %v, want %v", e.ColorPalette().Background, light.Background)
+	}
+	if len(observer.colors) != 1 {
+		t.Fatalf("got %d notifications, want 1", len(observer.colors))
+	}
+}
+
+func TestColorMix(t *testing.T) {
+	black := gvcolor.MakeColor(color.NRGBA{A: 0xff})
+	white := gvcolor.MakeColor(color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+	if got := black.Mix(white, 0.5).NRGBA(); got != (color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}) {
+		t.Errorf("got %v, want a gray", got)
+	}
+	// an unset color fades the other one in.
+	if got := (gvcolor.Color{}).Mix(white, 0.5).NRGBA(); got != (color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x80}) {
+		t.Errorf("got %v, want a translucent white", got)
+	}
+	if got := white.Mix(gvcolor.Color{}, 1); got.IsSet() {
+		t.Errorf("got %v, want an unset color", got)
+	}
+}
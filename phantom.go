@@ -0,0 +1,181 @@
+package gvcode
+
+import (
+	"image"
+	"slices"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	gvcolor "github.com/oligo/gvcode/color"
+)
+
+// PhantomBlock is a block of lines shown above a line of the document, which
+// are not part of the text: they can not be selected or edited, and the
+// caret skips them. They can show the lines deleted by a diff, or fill the
+// space aligning the hunks of two documents shown side by side.
+type PhantomBlock struct {
+	// Line is the 0-based line the block is shown above. The blocks of the
+	// line past the last one are shown below the last line.
+	Line int
+	// Lines are the texts of the lines of the block. Empty lines make a
+	// filler.
+	Lines []string
+	// Background fills the block if it is set.
+	Background gvcolor.Color
+	// TextColor is the color of the text, the foreground color of the editor
+	// if not set.
+	TextColor gvcolor.Color
+	// Highlights are the ranges of the lines painted with a background,
+	// e.g., the changed words of a deleted line.
+	Highlights []PhantomHighlight
+}
+
+// PhantomHighlight is a range of a line of a PhantomBlock painted with a
+// background.
+type PhantomHighlight struct {
+	// Line is the index of the line in the block.
+	Line int
+	// Start and End are the rune offsets of the range in the line.
+	Start, End int
+	// Background fills the range.
+	Background gvcolor.Color
+}
+
+// SetPhantomBlocks replaces the phantom blocks shown in the document. The
+// blocks of the same line are stacked in their order. The blocks are bound to
+// the lines, not to the text: they are not moved by the edits, and they are
+// dropped when the document is switched by a BufferSet.
+func (e *Editor) SetPhantomBlocks(blocks ...PhantomBlock) {
+	e.initBuffer()
+	e.phantoms = slices.Clone(blocks)
+	slices.SortStableFunc(e.phantoms, func(a, b PhantomBlock) int { return a.Line - b.Line })
+
+	gaps := make(map[int]int)
+	for _, block := range e.phantoms {
+		if block.Line >= 0 && len(block.Lines) > 0 {
+			gaps[block.Line] += len(block.Lines)
+		}
+	}
+	e.text.SetLineGaps(gaps)
+}
+
+// PhantomBlocks returns the phantom blocks shown in the document, sorted by
+// line.
+func (e *Editor) PhantomBlocks() []PhantomBlock {
+	return slices.Clone(e.phantoms)
+}
+
+// ClearPhantomBlocks removes all the phantom blocks.
+func (e *Editor) ClearPhantomBlocks() {
+	e.SetPhantomBlocks()
+}
+
+// PhantomBlockBounds returns the vertical extent of the phantom blocks shown
+// above the 0-based line in the document coordinates, which is empty if the
+// line has no blocks. It reports false if the line does not exist or is
+// hidden by a collapsed fold, as the blocks of the hidden lines are not
+// shown.
+func (e *Editor) PhantomBlockBounds(line int) (top, bottom int, ok bool) {
+	e.initBuffer()
+	height := e.text.LineGap(line) * e.text.GetLineHeight().Round()
+	if lines := e.text.Paragraphs(); line == lines && lines > 0 {
+		_, bottom, ok := e.LineBounds(lines - 1)
+		return bottom, bottom + height, ok
+	}
+	top, _, ok = e.LineBounds(line)
+	return top - height, top, ok
+}
+
+// paintPhantomBlocks paints the phantom blocks in the gaps laid out above
+// their lines.
+func (e *Editor) paintPhantomBlocks(gtx layout.Context, shaper *text.Shaper, textColor gvcolor.Color) {
+	if len(e.phantoms) == 0 || shaper == nil {
+		return
+	}
+
+	lineHeight := e.text.GetLineHeight().Round()
+	scrollOff := e.text.ScrollOff()
+	width := gtx.Constraints.Max.X
+
+	params := e.text.Params()
+	params.MinWidth = 0
+	params.MaxWidth = 1 << 20
+	params.MaxLines = 1
+
+	tabWidth := e.text.RenderTabWidth()
+
+	// stacked counts the lines of the blocks already painted above a line.
+	stacked := make(map[int]int)
+	for _, block := range e.phantoms {
+		top, _, ok := e.PhantomBlockBounds(block.Line)
+		if !ok || len(block.Lines) == 0 {
+			continue
+		}
+		top += stacked[block.Line]*lineHeight - scrollOff.Y
+		stacked[block.Line] += len(block.Lines)
+		rect := image.Rect(0, top, width, top+len(block.Lines)*lineHeight)
+		if !rect.Overlaps(image.Rectangle{Max: gtx.Constraints.Max}) {
+			continue
+		}
+
+		if block.Background.IsSet() {
+			paint.FillShape(gtx.Ops, block.Background.NRGBA(), clip.Rect(rect).Op())
+		}
+		c := textColor
+		if block.TextColor.IsSet() {
+			c = block.TextColor
+		}
+		for _, hl := range block.Highlights {
+			if hl.Line < 0 || hl.Line >= len(block.Lines) || !hl.Background.IsSet() {
+				continue
+			}
+			line := []rune(block.Lines[hl.Line])
+			start, end := max(min(hl.Start, len(line)), 0), max(min(hl.End, len(line)), 0)
+			if start >= end {
+				continue
+			}
+			x0 := phantomTextWidth(shaper, params, string(line[:start]), tabWidth)
+			x1 := phantomTextWidth(shaper, params, string(line[:end]), tabWidth)
+			y := rect.Min.Y + hl.Line*lineHeight
+			hlRect := image.Rect(x0-scrollOff.X, y, x1-scrollOff.X, y+lineHeight)
+			paint.FillShape(gtx.Ops, hl.Background.NRGBA(), clip.Rect(hlRect).Op())
+		}
+		for i, line := range block.Lines {
+			line = phantomText(line, tabWidth)
+			if line == "" {
+				continue
+			}
+			shaper.LayoutString(params, line)
+			paintTextGlyphs(gtx, shaper, image.Pt(-scrollOff.X, rect.Min.Y+i*lineHeight), width+scrollOff.X, c)
+		}
+	}
+}
+
+// phantomText returns the text of a line of a phantom block as it is shaped,
+// the tabs replaced by spaces.
+func phantomText(line string, tabWidth int) string {
+	line = expandLeadingTabs(strings.TrimRight(line, "\r"), tabWidth)
+	return strings.ReplaceAll(line, "\t", " ")
+}
+
+// phantomTextWidth returns the advance of the start of a line of a phantom
+// block.
+func phantomTextWidth(shaper *text.Shaper, params text.Parameters, prefix string, tabWidth int) int {
+	prefix = phantomText(prefix, tabWidth)
+	if prefix == "" {
+		return 0
+	}
+	shaper.LayoutString(params, prefix)
+	advance := 0
+	for {
+		g, ok := shaper.NextGlyph()
+		if !ok {
+			break
+		}
+		advance += g.Advance.Ceil()
+	}
+	return advance
+}
@@ -0,0 +1,50 @@
+package gvcode
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestPhantomBlocks(t *testing.T) {
+	e := newTestEditor(t, "a\nb\nc")
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+
+	top, bottom, ok := e.LineBounds(1)
+	if !ok {
+		t.Fatal("line 1 has no bounds")
+	}
+	lineHeight := bottom - top
+	height := e.text.FullDimensions().Size.Y
+
+	e.SetPhantomBlocks(
+		PhantomBlock{Line: 3, Lines: []string{"tail"}},
+		PhantomBlock{Line: 1, Lines: []string{"x", "y"}},
+		PhantomBlock{Line: 1, Lines: []string{"z"}},
+	)
+	e.Layout(gtx, shaper)
+
+	if blocks := e.PhantomBlocks(); blocks[0].Line != 1 || blocks[1].Lines[0] != "z" || blocks[2].Line != 3 {
+		t.Errorf("got blocks %+v, want them sorted by line", blocks)
+	}
+	if got, _, _ := e.LineBounds(1); got != top+3*lineHeight {
+		t.Errorf("line 1 moved to %d, want %d", got, top+3*lineHeight)
+	}
+	if got, _, _ := e.LineBounds(0); got != 0 {
+		t.Errorf("line 0 moved to %d", got)
+	}
+	if got := e.text.FullDimensions().Size.Y; got != height+4*lineHeight {
+		t.Errorf("got document height %d, want %d", got, height+4*lineHeight)
+	}
+
+	e.ClearPhantomBlocks()
+	e.Layout(gtx, shaper)
+	if got, _, _ := e.LineBounds(1); got != top {
+		t.Errorf("line 1 at %d after clearing the blocks, want %d", got, top)
+	}
+}
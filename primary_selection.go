@@ -0,0 +1,105 @@
+package gvcode
+
+import (
+	"image"
+	"math"
+
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+)
+
+// PrimarySelection is the primary selection of the X11 convention on Linux:
+// the text selected last, pasted by a middle-click. Gio has no access to the
+// primary selection of the system, so hosts can implement it to sync with the
+// system, or share the one returned by NewPrimarySelection between their
+// editors.
+type PrimarySelection interface {
+	// SetText is called when text is selected in the editor.
+	SetText(text string)
+	// Text returns the text pasted by a middle-click.
+	Text() string
+}
+
+// NewPrimarySelection returns a primary selection kept in memory.
+func NewPrimarySelection() PrimarySelection {
+	return &memoryPrimarySelection{}
+}
+
+type memoryPrimarySelection struct {
+	text string
+}
+
+func (p *memoryPrimarySelection) SetText(text string) { p.text = text }
+func (p *memoryPrimarySelection) Text() string        { return p.text }
+
+// primarySelectionState updates the primary selection with the selection of
+// the editor, and pastes it on middle-clicks.
+type primarySelectionState struct {
+	sel PrimarySelection
+	// synced is the selection and the text version last written to the
+	// primary selection.
+	synced [3]int
+}
+
+// syncPrimarySelection writes the selected text to the primary selection when
+// the selection changes.
+func (e *Editor) syncPrimarySelection() {
+	p := &e.primary
+	if p.sel == nil {
+		return
+	}
+	start, end := e.text.Selection()
+	if start == end {
+		return
+	}
+	state := [3]int{start, end, e.TextVersion()}
+	if state == p.synced {
+		return
+	}
+	p.synced = state
+	p.sel.SetText(e.SelectedText())
+}
+
+// processPrimaryPaste pastes the primary selection at the position of a
+// middle-click.
+func (e *Editor) processPrimaryPaste(gtx layout.Context) (EditorEvent, bool) {
+	p := &e.primary
+	if p.sel == nil {
+		return nil, false
+	}
+	for {
+		evt, ok := gtx.Event(pointer.Filter{Target: p, Kinds: pointer.Press})
+		if !ok {
+			return nil, false
+		}
+		pe, ok := evt.(pointer.Event)
+		if !ok || pe.Source != pointer.Mouse || pe.Buttons != pointer.ButtonTertiary {
+			continue
+		}
+		pos := image.Point{X: int(math.Round(float64(pe.Position.X))), Y: int(math.Round(float64(pe.Position.Y)))}
+		if e.mode == ModeReadOnly || e.inEmptyArea(pos) || e.inStickyArea(pos) {
+			continue
+		}
+		text := p.sel.Text()
+		if text == "" {
+			continue
+		}
+
+		e.text.MoveCoord(pos)
+		e.text.ClearSelection()
+		if e.hasExtraSelections() {
+			e.clearExtraSelections()
+		}
+		if e.Insert(text) != 0 {
+			return ChangeEvent{}, true
+		}
+	}
+}
+
+// addPrimaryPasteHandler registers the handler of the middle-clicks.
+func (e *Editor) addPrimaryPasteHandler(gtx layout.Context) {
+	if e.primary.sel != nil {
+		event.Op(gtx.Ops, &e.primary)
+	}
+}
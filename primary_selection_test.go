@@ -0,0 +1,62 @@
+package gvcode
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestPrimarySelection(t *testing.T) {
+	primary := NewPrimarySelection()
+	src := newTestEditor(t, "hello world", WithPrimarySelection(primary))
+	dst := newTestEditor(t, "ab\ncd", WithPrimarySelection(primary))
+
+	var router input.Router
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	frame := func(e *Editor) {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 200)), Source: router.Source()}
+		e.Layout(gtx, shaper)
+		router.Frame(gtx.Ops)
+	}
+
+	// selecting text writes it to the primary selection.
+	frame(src)
+	src.SetCaret(6, 11)
+	frame(src)
+	if got := primary.Text(); got != "world" {
+		t.Fatalf("got primary selection %q, want %q", got, "world")
+	}
+
+	// a middle-click pastes it at the click position.
+	frame(dst)
+	// the left edge of the "d".
+	r := dst.text.Regions(4, 5, nil)[0].Bounds
+	pos := f32.Pt(float32(r.Min.X+1), float32(r.Min.Y+r.Dy()/2))
+	router.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonTertiary, Position: pos},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: pos},
+	)
+	frame(dst)
+	if got := dst.Text(); got != "ab\ncworldd" {
+		t.Fatalf("got %q after the middle-click", got)
+	}
+
+	// the primary selection is disabled by default.
+	e := newTestEditor(t, "xy")
+	frame(e)
+	router.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonTertiary, Position: f32.Pt(5, 5)},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: f32.Pt(5, 5)},
+	)
+	frame(e)
+	if got := e.Text(); got != "xy" {
+		t.Fatalf("got %q, want the text unchanged", got)
+	}
+}
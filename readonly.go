@@ -0,0 +1,211 @@
+package gvcode
+
+import (
+	"slices"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"github.com/oligo/gvcode/gutter"
+	"github.com/oligo/gvcode/gutter/providers"
+	"github.com/oligo/gvcode/internal/buffer"
+)
+
+// ReadOnlyEditAttempt is generated when the user tries to edit a read-only
+// region, so that hosts can explain why the edit is refused.
+type ReadOnlyEditAttempt struct {
+	// Region is the read-only region the edit would have changed.
+	Region TextRange
+	// Range is the rune range of the refused edit. It is empty for
+	// insertions.
+	Range TextRange
+}
+
+func (ReadOnlyEditAttempt) isEditorEvent() {}
+
+// readOnlyRegion is a protected range of text. Its markers follow the edits
+// made outside of it, and text inserted at its boundaries stays outside.
+type readOnlyRegion struct {
+	start, end *buffer.Marker
+}
+
+func (r readOnlyRegion) textRange() TextRange {
+	return TextRange{Start: r.start.Offset(), End: r.end.Offset()}
+}
+
+// AddReadOnlyRegion protects the rune range [start, end) from the edits of the
+// user: typing, deleting, cutting, pasting, indenting and commenting in it
+// are refused with a ReadOnlyEditAttempt event. Text can still be inserted at
+// the boundaries of the region, and the programmatic edits like SetText and
+// ReplaceAll are not checked.
+//
+// The regions are tinted, and a lock is shown in the gutter next to their
+// lines if the editor has a gutter.
+func (e *Editor) AddReadOnlyRegion(start, end int) error {
+	e.initBuffer()
+	if start > end {
+		start, end = end, start
+	}
+	start = max(0, min(start, e.buffer.Len()))
+	end = max(0, min(end, e.buffer.Len()))
+	if start == end {
+		return nil
+	}
+
+	startMarker, err := e.buffer.CreateMarker(start, buffer.BiasForward)
+	if err != nil {
+		return err
+	}
+	endMarker, err := e.buffer.CreateMarker(end, buffer.BiasBackward)
+	if err != nil {
+		e.buffer.RemoveMarker(startMarker)
+		return err
+	}
+	e.readOnlyRegions = append(e.readOnlyRegions, readOnlyRegion{start: startMarker, end: endMarker})
+
+	if e.gutterManager != nil && e.gutterManager.GetProvider(providers.ReadOnlyProviderID) == nil {
+		e.gutterManager.Register(providers.NewReadOnlyProvider())
+	}
+	return nil
+}
+
+// ClearReadOnlyRegions removes all the read-only regions.
+func (e *Editor) ClearReadOnlyRegions() {
+	e.initBuffer()
+	for _, r := range e.readOnlyRegions {
+		e.buffer.RemoveMarker(r.start)
+		e.buffer.RemoveMarker(r.end)
+	}
+	e.readOnlyRegions = e.readOnlyRegions[:0]
+}
+
+// ReadOnlyRegions returns the read-only regions sorted by their start, with
+// their ranges updated to follow the edits. Regions whose text was removed
+// are omitted.
+func (e *Editor) ReadOnlyRegions() []TextRange {
+	regions := make([]TextRange, 0, len(e.readOnlyRegions))
+	for _, r := range e.readOnlyRegions {
+		if rng := r.textRange(); rng.Start < rng.End {
+			regions = append(regions, rng)
+		}
+	}
+	slices.SortFunc(regions, func(a, b TextRange) int { return a.Start - b.Start })
+	return regions
+}
+
+// readOnlyRegionAt returns the read-only region changed by replacing the
+// rune range [start, end). An insertion only changes a region if it is
+// strictly inside of it.
+func (e *Editor) readOnlyRegionAt(start, end int) (TextRange, bool) {
+	if start > end {
+		start, end = end, start
+	}
+	for _, r := range e.readOnlyRegions {
+		rng := r.textRange()
+		if rng.Start >= rng.End {
+			continue
+		}
+		if start == end && rng.Start < start && start < rng.End {
+			return rng, true
+		}
+		if start != end && start < rng.End && end > rng.Start {
+			return rng, true
+		}
+	}
+	return TextRange{}, false
+}
+
+// denyReadOnlyEdit reports whether replacing the rune range [start, end)
+// changes a read-only region, generating a ReadOnlyEditAttempt if so.
+func (e *Editor) denyReadOnlyEdit(start, end int) bool {
+	if len(e.readOnlyRegions) == 0 {
+		return false
+	}
+	region, ok := e.readOnlyRegionAt(start, end)
+	if !ok {
+		return false
+	}
+	if start > end {
+		start, end = end, start
+	}
+	e.pending = append(e.pending, ReadOnlyEditAttempt{Region: region, Range: TextRange{Start: start, End: end}})
+	return true
+}
+
+// denyReadOnlyLines is like denyReadOnlyEdit for the edits of the selected
+// lines, e.g., indenting or commenting them.
+func (e *Editor) denyReadOnlyLines() bool {
+	if len(e.readOnlyRegions) == 0 {
+		return false
+	}
+	start, end := e.text.SelectedLineRange()
+	return e.denyReadOnlyEdit(start, end)
+}
+
+// denyReadOnlyColumns is like denyReadOnlyEdit for the edits at the column
+// carets.
+func (e *Editor) denyReadOnlyColumns() bool {
+	if len(e.readOnlyRegions) == 0 {
+		return false
+	}
+	for _, cursor := range e.columnEdit.selections {
+		start, end := e.columnCursorRange(cursor)
+		if start == end {
+			start = e.text.ConvertPos(cursor.line, 0) + cursor.col
+			end = start
+		}
+		if e.denyReadOnlyEdit(start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+// denyReadOnlySelections is like denyReadOnlyEdit for the edits of all the
+// selections of a multi-selection.
+func (e *Editor) denyReadOnlySelections() bool {
+	if len(e.readOnlyRegions) == 0 {
+		return false
+	}
+	for _, sel := range e.Selections() {
+		if e.denyReadOnlyEdit(sel.Start, sel.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// paintReadOnlyRegions paints a faint tint over the visible read-only regions.
+func (e *Editor) paintReadOnlyRegions(gtx layout.Context) {
+	if len(e.readOnlyRegions) == 0 {
+		return
+	}
+	tint := e.colorPalette.Foreground.MulAlpha(0x14)
+	if !tint.IsSet() {
+		return
+	}
+	for _, rng := range e.ReadOnlyRegions() {
+		for _, r := range e.text.Regions(rng.Start, rng.End, nil) {
+			paint.FillShape(gtx.Ops, tint.NRGBA(), clip.Rect(r.Bounds).Op())
+		}
+	}
+}
+
+// feedReadOnlyRegionsToProviders notifies the gutter providers implementing
+// gutter.ReadOnlyObserver of the read-only regions.
+func (e *Editor) feedReadOnlyRegionsToProviders() {
+	var ranges []gutter.RuneRange
+	for _, p := range e.gutterManager.Providers() {
+		observer, ok := p.(gutter.ReadOnlyObserver)
+		if !ok {
+			continue
+		}
+		if ranges == nil {
+			ranges = make([]gutter.RuneRange, 0, len(e.readOnlyRegions))
+			for _, r := range e.ReadOnlyRegions() {
+				ranges = append(ranges, gutter.RuneRange{Start: r.Start, End: r.End})
+			}
+		}
+		observer.SetReadOnlyRegions(ranges)
+	}
+}
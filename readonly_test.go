@@ -0,0 +1,112 @@
+package gvcode
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"github.com/oligo/gvcode/gutter"
+	"github.com/oligo/gvcode/gutter/providers"
+)
+
+func newReadOnlyEditor(t *testing.T, content string, start, end int) *Editor {
+	t.Helper()
+	e := newTestEditor(t, content)
+	if err := e.AddReadOnlyRegion(start, end); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestReadOnlyRegionInput(t *testing.T) {
+	const content = "header\nlocked\nbody"
+	e := newReadOnlyEditor(t, content, 7, 13)
+
+	e.SetCaret(9, 9)
+	e.onTextInput(key.EditEvent{Range: key.Range{Start: 9, End: 9}, Text: "x"})
+	if got := e.Text(); got != content {
+		t.Fatalf("typing in the region changed the text to %q", got)
+	}
+	if len(e.pending) != 1 {
+		t.Fatalf("got %d pending events, want 1", len(e.pending))
+	}
+	attempt, ok := e.pending[0].(ReadOnlyEditAttempt)
+	if !ok {
+		t.Fatalf("got %T, want ReadOnlyEditAttempt", e.pending[0])
+	}
+	if want := (TextRange{Start: 7, End: 13}); attempt.Region != want {
+		t.Errorf("got region %v, want %v", attempt.Region, want)
+	}
+	if want := (TextRange{Start: 9, End: 9}); attempt.Range != want {
+		t.Errorf("got range %v, want %v", attempt.Range, want)
+	}
+	e.pending = nil
+
+	// deleting across the start of the region is refused, and the caret
+	// is kept.
+	e.SetCaret(7, 7)
+	if n := e.Delete(1); n != 0 {
+		t.Errorf("deleted %d runes of the region", n)
+	}
+	if start, end := e.Selection(); start != 7 || end != 7 {
+		t.Errorf("caret moved to (%d, %d)", start, end)
+	}
+	if n := e.Delete(-1); n == 0 {
+		t.Error("deleting before the region is refused")
+	}
+
+	// typing at the boundaries is allowed, and the region follows.
+	e.SetCaret(6, 6)
+	e.onTextInput(key.EditEvent{Range: key.Range{Start: 6, End: 6}, Text: "\n"})
+	e.onTextInput(key.EditEvent{Range: key.Range{Start: 13, End: 13}, Text: "!"})
+	if got, want := e.Text(), "header\nlocked!\nbody"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got, want := e.ReadOnlyRegions(), []TextRange{{Start: 7, End: 13}}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got regions %v, want %v", got, want)
+	}
+
+	e.ClearReadOnlyRegions()
+	e.onTextInput(key.EditEvent{Range: key.Range{Start: 9, End: 9}, Text: "x"})
+	if got, want := e.Text(), "header\nloxcked!\nbody"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReadOnlyRegionLines(t *testing.T) {
+	const content = "a\nlocked\nb"
+	e := newReadOnlyEditor(t, content, 2, 8)
+
+	e.SetCaret(3, 3)
+	if n := e.DeleteLine(); n != 0 {
+		t.Errorf("deleted %d runes of the region", n)
+	}
+	e.onTab(false)
+	if got := e.Text(); got != content {
+		t.Errorf("got %q, want %q", got, content)
+	}
+	if len(e.pending) != 2 {
+		t.Errorf("got %d pending events, want 2", len(e.pending))
+	}
+}
+
+func TestReadOnlyProvider(t *testing.T) {
+	e := newTestEditor(t, "a\nlocked\nb", WithDefaultGutters())
+	if err := e.AddReadOnlyRegion(2, 8); err != nil {
+		t.Fatal(err)
+	}
+
+	p, ok := e.GutterManager().GetProvider(providers.ReadOnlyProviderID).(*providers.ReadOnlyProvider)
+	if !ok {
+		t.Fatal("the read-only provider is not registered")
+	}
+	if _, ok := gutter.GutterProvider(p).(gutter.ReadOnlyObserver); !ok {
+		t.Fatal("the read-only provider is not a gutter.ReadOnlyObserver")
+	}
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+	e.Layout(gtx, text.NewShaper(text.WithCollection(gofont.Collection())))
+}
@@ -0,0 +1,190 @@
+package gvcode
+
+import (
+	"image/color"
+	"unicode/utf16"
+
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/internal/buffer"
+	"github.com/oligo/gvcode/textstyle/decoration"
+)
+
+const (
+	referenceHighlightSource = "_reference_highlight"
+)
+
+// ReferenceKind is the kind of a reference to a symbol. The values up to
+// ReferenceWrite match the DocumentHighlightKind of the Language Server
+// Protocol minus one.
+type ReferenceKind uint8
+
+const (
+	// ReferenceText is a textual occurrence of the symbol.
+	ReferenceText ReferenceKind = iota
+	// ReferenceRead is a read access of the symbol.
+	ReferenceRead
+	// ReferenceWrite is a write access of the symbol.
+	ReferenceWrite
+	// ReferenceDeclaration is the declaration or the definition of the
+	// symbol.
+	ReferenceDeclaration
+)
+
+var defaultReferenceColors = map[ReferenceKind]color.NRGBA{
+	ReferenceText:        {R: 0x9E, G: 0x9E, B: 0x9E, A: 0xFF},
+	ReferenceRead:        {R: 0x1E, G: 0x88, B: 0xE5, A: 0xFF},
+	ReferenceWrite:       {R: 0xF9, G: 0xA8, B: 0x25, A: 0xFF},
+	ReferenceDeclaration: {R: 0x43, G: 0xA0, B: 0x47, A: 0xFF},
+}
+
+// referenceState tracks the references set by SetReferenceHighlights.
+type referenceState struct {
+	items []referenceItem
+	// colors overrides the default color of each kind. The backgrounds
+	// are translucent versions of the colors.
+	colors map[ReferenceKind]gvcolor.Color
+}
+
+type referenceItem struct {
+	kind   ReferenceKind
+	length int
+	// marker tracks the start of the reference as the text changes.
+	marker *buffer.Marker
+}
+
+func (s *referenceState) color(kind ReferenceKind) gvcolor.Color {
+	if c, ok := s.colors[kind]; ok && c.IsSet() {
+		return c
+	}
+	if c, ok := defaultReferenceColors[kind]; ok {
+		return gvcolor.MakeColor(c)
+	}
+	return gvcolor.MakeColor(defaultReferenceColors[ReferenceText])
+}
+
+// SetReferenceHighlights replaces the highlighted references of a symbol,
+// e.g., the results of a textDocument/references or a
+// textDocument/documentHighlight request of a language server. kinds[i] is
+// the kind of ranges[i], and the ranges without a kind are textual
+// references. Reads are painted with a background, writes are underlined too
+// and declarations are boxed, and the references are reported by
+// ScrollbarAnnotations. Use OffsetFromLSP to convert the positions of the
+// language server to rune offsets.
+func (e *Editor) SetReferenceHighlights(ranges []TextRange, kinds []ReferenceKind) error {
+	e.initBuffer()
+	e.ClearReferenceHighlights()
+
+	decos := make([]decoration.Decoration, 0, len(ranges))
+	for i, r := range ranges {
+		if r.Start > r.End {
+			r.Start, r.End = r.End, r.Start
+		}
+		kind := ReferenceText
+		if i < len(kinds) {
+			kind = kinds[i]
+		}
+		marker, err := e.buffer.CreateMarker(r.Start, buffer.BiasBackward)
+		if err != nil {
+			return err
+		}
+		e.references.items = append(e.references.items, referenceItem{kind: kind, length: r.End - r.Start, marker: marker})
+
+		c := e.references.color(kind)
+		deco := decoration.Decoration{
+			Source:     referenceHighlightSource,
+			Start:      r.Start,
+			End:        r.End,
+			Background: &decoration.Background{Color: c.MulAlpha(0x40)},
+			// above the word highlights.
+			Priority: 1,
+		}
+		switch kind {
+		case ReferenceWrite:
+			deco.Underline = &decoration.Underline{Color: c}
+		case ReferenceDeclaration:
+			deco.Border = &decoration.Border{Color: c}
+		}
+		decos = append(decos, deco)
+	}
+
+	if len(decos) > 0 {
+		return e.AddDecorations(decos...)
+	}
+	return nil
+}
+
+// ClearReferenceHighlights removes the highlighted references.
+func (e *Editor) ClearReferenceHighlights() {
+	e.initBuffer()
+	for _, item := range e.references.items {
+		e.buffer.RemoveMarker(item.marker)
+	}
+	e.references.items = e.references.items[:0]
+	e.ClearDecorations(referenceHighlightSource)
+}
+
+// ReferenceHighlights returns the highlighted references and their kinds,
+// with their ranges updated to follow the edits made since they were set.
+func (e *Editor) ReferenceHighlights() ([]TextRange, []ReferenceKind) {
+	ranges := make([]TextRange, 0, len(e.references.items))
+	kinds := make([]ReferenceKind, 0, len(e.references.items))
+	for _, item := range e.references.items {
+		start := item.marker.Offset()
+		ranges = append(ranges, TextRange{Start: start, End: start + item.length})
+		kinds = append(kinds, item.kind)
+	}
+	return ranges, kinds
+}
+
+// ScrollbarAnnotation marks the position of some text, e.g., a reference, on
+// the vertical scrollbar.
+type ScrollbarAnnotation struct {
+	// Start and End are the vertical extent of the lines of the text,
+	// relative to the height of the document.
+	Start, End float32
+	Color      gvcolor.Color
+}
+
+// ScrollbarAnnotations returns the annotations of the highlighted references
+// to paint on the vertical scrollbar, as of the last layout.
+func (e *Editor) ScrollbarAnnotations() []ScrollbarAnnotation {
+	e.initBuffer()
+	height := e.text.FullDimensions().Size.Y
+	if height <= 0 || len(e.references.items) == 0 {
+		return nil
+	}
+
+	annotations := make([]ScrollbarAnnotation, 0, len(e.references.items))
+	for _, item := range e.references.items {
+		_, p := e.text.FindParagraph(item.marker.Offset())
+		top := p.StartY - p.Ascent.Ceil()
+		bottom := p.EndY + p.Descent.Ceil()
+		annotations = append(annotations, ScrollbarAnnotation{
+			Start: float32(top) / float32(height),
+			End:   float32(bottom) / float32(height),
+			Color: e.references.color(item.kind),
+		})
+	}
+	return annotations
+}
+
+// OffsetFromLSP converts a position of the Language Server Protocol, a
+// zero-based line and a character offset counted in UTF-16 code units, to a
+// rune offset. Offsets past the end of the line are clamped to it.
+func (e *Editor) OffsetFromLSP(line, character int) int {
+	e.initBuffer()
+	off := e.text.ConvertPos(line, 0)
+	for units := 0; units < character; {
+		r, err := e.buffer.ReadRuneAt(off)
+		if err != nil || r == '\n' {
+			break
+		}
+		if n := utf16.RuneLen(r); n > 0 {
+			units += n
+		} else {
+			units++
+		}
+		off++
+	}
+	return off
+}
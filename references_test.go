@@ -0,0 +1,89 @@
+package gvcode
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestReferenceHighlights(t *testing.T) {
+	e := newTestEditor(t, "x := 1\nx = 2\nprint(x)\n")
+
+	ranges := []TextRange{{Start: 0, End: 1}, {Start: 7, End: 8}, {Start: 19, End: 20}}
+	kinds := []ReferenceKind{ReferenceDeclaration, ReferenceWrite}
+	if err := e.SetReferenceHighlights(ranges, kinds); err != nil {
+		t.Fatal(err)
+	}
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+	e.Layout(gtx, text.NewShaper(text.WithCollection(gofont.Collection())))
+
+	decos := e.text.QueryDecorations(0, e.Len())
+	if len(decos) != 3 {
+		t.Fatalf("got %d decorations, want 3", len(decos))
+	}
+	for _, deco := range decos {
+		switch deco.Start {
+		case 0:
+			if deco.Border == nil {
+				t.Error("the declaration is not boxed")
+			}
+		case 7:
+			if deco.Underline == nil {
+				t.Error("the write is not underlined")
+			}
+		case 19:
+			if deco.Border != nil || deco.Underline != nil {
+				t.Error("the textual reference is not painted with a background only")
+			}
+		}
+	}
+
+	annotations := e.ScrollbarAnnotations()
+	if len(annotations) != 3 {
+		t.Fatalf("got %d annotations, want 3", len(annotations))
+	}
+	for i := 1; i < len(annotations); i++ {
+		if annotations[i].Start <= annotations[i-1].Start {
+			t.Errorf("annotation %d at %v is not below annotation %d at %v", i, annotations[i].Start, i-1, annotations[i-1].Start)
+		}
+	}
+
+	// the references follow the edits.
+	e.SetCaret(0, 0)
+	e.Insert("// x\n")
+	got, gotKinds := e.ReferenceHighlights()
+	if got[2] != (TextRange{Start: 24, End: 25}) || gotKinds[2] != ReferenceText {
+		t.Errorf("got %v %v, want {24 25} text", got[2], gotKinds[2])
+	}
+
+	e.ClearReferenceHighlights()
+	if decos := e.text.QueryDecorations(0, e.Len()); len(decos) != 0 {
+		t.Errorf("got %d decorations after clearing", len(decos))
+	}
+}
+
+func TestOffsetFromLSP(t *testing.T) {
+	e := newTestEditor(t, "a😀b\nxy")
+
+	tests := []struct {
+		line, character int
+		want            int
+	}{
+		{0, 0, 0},
+		{0, 1, 1},
+		// the emoji is a surrogate pair.
+		{0, 3, 2},
+		{0, 4, 3},
+		{0, 10, 3},
+		{1, 1, 5},
+	}
+	for _, tc := range tests {
+		if got := e.OffsetFromLSP(tc.line, tc.character); got != tc.want {
+			t.Errorf("OffsetFromLSP(%d, %d) = %d, want %d", tc.line, tc.character, got, tc.want)
+		}
+	}
+}
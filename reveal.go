@@ -0,0 +1,203 @@
+package gvcode
+
+import (
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/textview"
+)
+
+// flashDuration is how long the flash highlight of RevealRange lasts.
+const flashDuration = 800 * time.Millisecond
+
+// RevealMode controls how RevealRange shows a range. It is one of
+// RevealMinimal, RevealCenter and RevealTop, optionally combined with
+// RevealSelect and RevealFlash.
+type RevealMode uint8
+
+const (
+	// RevealMinimal scrolls as little as possible to make the range visible.
+	RevealMinimal RevealMode = iota
+	// RevealCenter places the range at the vertical center of the viewport.
+	RevealCenter
+	// RevealTop places the range at the top of the viewport.
+	RevealTop
+)
+
+const (
+	// RevealSelect selects the range.
+	RevealSelect RevealMode = 1 << (iota + 4)
+	// RevealFlash paints a transient highlight over the range, fading out.
+	RevealFlash
+
+	revealAlignMask RevealMode = 0x0F
+)
+
+// revealState is a range waiting to be scrolled into view in the next
+// layout, and the flash highlight of the last revealed range.
+type revealState struct {
+	pending    bool
+	start, end int
+	align      textview.ScrollAlign
+
+	flashing             bool
+	flashStart, flashEnd int
+	flashBegin           time.Time
+
+	// stopTracking unregisters the edit listener shifting the ranges, which
+	// is registered while a range is pending or flashing.
+	stopTracking func()
+}
+
+// RevealRange scrolls the editor to make the rune range [start, end) visible,
+// which is the primitive of going to a search result or a reference. The
+// viewport is scrolled in the next layout, with an animation if enabled by
+// WithScrollAnimation. The collapsed folds hiding the range are expanded,
+// and the range follows the edits made before it is scrolled to. See
+// RevealMode for the options.
+func (e *Editor) RevealRange(start, end int, mode RevealMode) {
+	e.initBuffer()
+	if start > end {
+		start, end = end, start
+	}
+	start = max(0, min(start, e.text.Len()))
+	end = max(0, min(end, e.text.Len()))
+
+	startLine, _ := e.text.FindParagraph(start)
+	endLine, _ := e.text.FindParagraph(end)
+	e.expandFoldsHiding(startLine, endLine)
+
+	if mode&RevealSelect != 0 {
+		e.SetCaret(end, start)
+	}
+
+	e.reveal.pending = true
+	e.reveal.start, e.reveal.end = start, end
+	switch mode & revealAlignMask {
+	case RevealCenter:
+		e.reveal.align = textview.ScrollCenter
+	case RevealTop:
+		e.reveal.align = textview.ScrollTop
+	default:
+		e.reveal.align = textview.ScrollMinimal
+	}
+	e.trackReveal()
+	e.notifyGutterJump(start)
+
+	if mode&RevealFlash != 0 {
+		e.flashRange(start, end)
+	}
+}
+
+// flashRange starts the flash highlight of the range [start, end) in the
+// next frame.
+func (e *Editor) flashRange(start, end int) {
+	if start >= end {
+		return
+	}
+	e.reveal.flashing = true
+	e.reveal.flashStart, e.reveal.flashEnd = start, end
+	e.reveal.flashBegin = time.Time{}
+	e.trackReveal()
+}
+
+// expandFoldsHiding expands the collapsed folds hiding any of the lines from
+// startLine to endLine.
+func (e *Editor) expandFoldsHiding(startLine, endLine int) {
+	fm := e.text.FoldManager()
+	if fm == nil || !fm.HasCollapsed() {
+		return
+	}
+	expanded := false
+	for _, fold := range fm.GetFoldRanges() {
+		// the lines after the start line of a fold are hidden.
+		if fold.Collapsed && fold.StartLine < endLine && startLine <= fold.EndLine {
+			expanded = fm.ExpandFold(fold.StartLine) || expanded
+		}
+	}
+	if expanded {
+		e.text.Invalidate()
+	}
+}
+
+// trackReveal registers the edit listener shifting the pending and the
+// flashing ranges, if it is not registered.
+func (e *Editor) trackReveal() {
+	if e.reveal.stopTracking == nil {
+		e.reveal.stopTracking = e.OnEdit(e.shiftReveal)
+	}
+}
+
+// untrackReveal unregisters the edit listener of trackReveal once no range
+// is pending or flashing.
+func (e *Editor) untrackReveal() {
+	if e.reveal.pending || e.reveal.flashing || e.reveal.stopTracking == nil {
+		return
+	}
+	e.reveal.stopTracking()
+	e.reveal.stopTracking = nil
+}
+
+// shiftReveal moves the pending and the flashing ranges after an edit. The
+// offsets in the replaced text are moved to the end of the inserted text.
+func (e *Editor) shiftReveal(delta EditDelta) {
+	shift := func(pos int) int {
+		switch {
+		case delta.OldEnd <= pos:
+			pos += delta.NewEnd - delta.OldEnd
+		case delta.NewEnd < pos:
+			pos = delta.NewEnd
+		}
+		return pos
+	}
+	r := &e.reveal
+	if r.pending {
+		r.start, r.end = shift(r.start), shift(r.end)
+	}
+	if r.flashing {
+		r.flashStart, r.flashEnd = shift(r.flashStart), shift(r.flashEnd)
+		if r.flashStart >= r.flashEnd {
+			r.flashing = false
+		}
+	}
+}
+
+// scrollToReveal scrolls to the range of the last RevealRange call.
+func (e *Editor) scrollToReveal() {
+	if !e.reveal.pending {
+		return
+	}
+	e.reveal.pending = false
+	e.untrackReveal()
+	// the range takes the place of the caret.
+	e.scrollCaret = false
+	e.scrollSmoothly(func() { e.text.ScrollToRange(e.reveal.start, e.reveal.end, e.reveal.align) })
+}
+
+// paintRevealFlash paints the flash highlight of the revealed range, fading
+// out over flashDuration.
+func (e *Editor) paintRevealFlash(gtx layout.Context, material gvcolor.Color) {
+	if !e.reveal.flashing {
+		return
+	}
+	if e.reveal.flashBegin.IsZero() {
+		e.reveal.flashBegin = gtx.Now
+	}
+
+	elapsed := gtx.Now.Sub(e.reveal.flashBegin)
+	if elapsed >= flashDuration || e.reveal.flashEnd > e.text.Len() {
+		e.reveal.flashing = false
+		e.untrackReveal()
+		return
+	}
+
+	alpha := uint8(0xFF * (1 - float32(elapsed)/float32(flashDuration)))
+	for _, r := range e.text.Regions(e.reveal.flashStart, e.reveal.flashEnd, nil) {
+		paint.FillShape(gtx.Ops, material.MulAlpha(alpha).NRGBA(), clip.Rect(r.Bounds).Op())
+	}
+	gtx.Execute(op.InvalidateCmd{})
+}
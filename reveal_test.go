@@ -0,0 +1,101 @@
+package gvcode
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestRevealRange(t *testing.T) {
+	e := newTestEditor(t, strings.Repeat("line\n", 300))
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	relayout := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+		e.Layout(gtx, shaper)
+	}
+
+	start, _ := e.ConvertPos(200, 0)
+	e.RevealRange(start, start+4, RevealTop|RevealSelect|RevealFlash)
+	relayout()
+
+	if s, end := e.Selection(); s != start+4 || end != start {
+		t.Fatalf("selection: got (%d, %d), want (%d, %d)", s, end, start+4, start)
+	}
+	_, pos := e.ConvertPos(200, 0)
+	lineHeight := e.text.GetLineHeight().Ceil()
+	// the position is relative to the viewport.
+	if pos.Y < 0 || int(pos.Y) > lineHeight {
+		t.Fatalf("line 200 at %v is not at the top of the viewport", pos.Y)
+	}
+	if !e.reveal.flashing {
+		t.Fatal("expected the range to flash")
+	}
+
+	// Revealing a visible range with RevealMinimal does not scroll.
+	top := e.text.ScrollOff().Y
+	start, _ = e.ConvertPos(205, 0)
+	e.RevealRange(start, start+4, RevealMinimal)
+	relayout()
+	if got := e.text.ScrollOff().Y; got != top {
+		t.Fatalf("minimal reveal scrolled from %d to %d", top, got)
+	}
+
+	start, _ = e.ConvertPos(20, 0)
+	e.RevealRange(start, start+4, RevealCenter)
+	relayout()
+	_, pos = e.ConvertPos(20, 0)
+	if d := int(pos.Y) - 300; d < -lineHeight || d > lineHeight {
+		t.Fatalf("line 20 at %v is not centered", pos.Y)
+	}
+}
+
+func TestRevealRangeInFold(t *testing.T) {
+	e := newTestEditor(t, foldAnimationText, WithCodeFolding())
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 300))}
+	// the folds are detected by the gutter in the first frame.
+	e.Layout(gtx, shaper)
+	e.Layout(gtx, shaper)
+	fm := e.text.FoldManager()
+	if !fm.CollapseFold(0) {
+		t.Fatal("no fold at line 0")
+	}
+
+	start, _ := e.ConvertPos(2, 1)
+	e.RevealRange(start, start+6, RevealCenter)
+	if !fm.IsLineVisible(2) {
+		t.Error("the fold hiding the range is still collapsed")
+	}
+}
+
+func TestRevealRangeEdits(t *testing.T) {
+	e := newTestEditor(t, "abc def ghi")
+	e.RevealRange(4, 7, RevealMinimal|RevealFlash)
+
+	e.SetCaret(0, 0)
+	e.Insert("xx")
+	if e.reveal.start != 6 || e.reveal.end != 9 {
+		t.Errorf("got the pending range %d-%d, want 6-9", e.reveal.start, e.reveal.end)
+	}
+	if e.reveal.flashStart != 6 || e.reveal.flashEnd != 9 {
+		t.Errorf("got the flash range %d-%d, want 6-9", e.reveal.flashStart, e.reveal.flashEnd)
+	}
+
+	// deleting the range stops the flash.
+	e.SetCaret(5, 10)
+	e.Delete(1)
+	if e.reveal.flashing {
+		t.Error("the deleted range is still flashing")
+	}
+
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+	e.Layout(gtx, text.NewShaper(text.WithCollection(gofont.Collection())))
+	if e.reveal.stopTracking != nil {
+		t.Error("the edit listener is registered after the range is revealed")
+	}
+}
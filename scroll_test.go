@@ -0,0 +1,128 @@
+package gvcode
+
+import (
+	"image"
+	"strings"
+	"testing"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestHorizontalScroll(t *testing.T) {
+	e := newTestEditor(t, strings.Repeat(strings.Repeat("word ", 100)+"\n", 100), WrapLine(false))
+
+	var router input.Router
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	frame := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(300, 200)), Source: router.Source()}
+		e.Layout(gtx, shaper)
+		router.Frame(gtx.Ops)
+	}
+	scroll := func(dist f32.Point, mods key.Modifiers) image.Point {
+		router.Queue(pointer.Event{Kind: pointer.Scroll, Source: pointer.Mouse, Position: f32.Pt(100, 100), Scroll: dist, Modifiers: mods})
+		frame()
+		// update the scroll ranges for the next event.
+		frame()
+		return e.text.ScrollOff()
+	}
+	// the scroll ranges are known after the first layout.
+	frame()
+	frame()
+
+	// the wheel scrolls horizontally with Shift held.
+	if got := scroll(f32.Pt(0, 50), key.ModShift); got != image.Pt(50, 0) {
+		t.Errorf("Shift+wheel: got scroll offset %v, want (50,0)", got)
+	}
+	// a trackpad scrolls along both axes.
+	if got := scroll(f32.Pt(30, 10), 0); got != image.Pt(80, 10) {
+		t.Errorf("trackpad: got scroll offset %v, want (80,10)", got)
+	}
+	if got := scroll(f32.Pt(-100, 0), 0); got != image.Pt(0, 10) {
+		t.Errorf("got scroll offset %v, want the start of the lines", got)
+	}
+}
+
+func TestScrollAnimation(t *testing.T) {
+	e := newTestEditor(t, strings.Repeat("line\n", 500), WithScrollAnimation(200*time.Millisecond))
+
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	now := time.Now()
+	frame := func(elapsed time.Duration) int {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(300, 200)), Now: now.Add(elapsed)}
+		e.Layout(gtx, shaper)
+		return e.text.ScrollOff().Y
+	}
+	frame(0)
+
+	e.ScrollToLine(100)
+	if got := frame(0); got != 0 {
+		t.Fatalf("the viewport jumped to %d", got)
+	}
+	middle := frame(100 * time.Millisecond)
+	target := frame(200 * time.Millisecond)
+	if middle <= 0 || middle >= target {
+		t.Errorf("got offset %d in the middle of the animation to %d", middle, target)
+	}
+	if line, _ := e.text.FindParagraph(e.text.ConvertPos(100, 0)); line != 100 {
+		t.Fatalf("line 100 is paragraph %d", line)
+	}
+	if _, p := e.text.FindParagraph(e.text.ConvertPos(100, 0)); p.StartY-p.Ascent.Ceil() != target {
+		t.Errorf("got offset %d, want the top of line 100 at %d", target, p.StartY-p.Ascent.Ceil())
+	}
+
+	// moving the caret interrupts the animation.
+	e.ScrollToLine(0)
+	frame(300 * time.Millisecond)
+	frame(350 * time.Millisecond)
+	e.SetCaret(e.text.ConvertPos(120, 0), e.text.ConvertPos(120, 0))
+	before := e.text.ScrollOff().Y
+	if got := frame(400 * time.Millisecond); got <= before {
+		t.Errorf("got offset %d, want the caret below %d to be scrolled into view", got, before)
+	}
+	if e.scrollAnim.active {
+		t.Error("the animation is not interrupted")
+	}
+}
+
+func TestKineticScrolling(t *testing.T) {
+	e := newTestEditor(t, strings.Repeat("line\n", 2000), WithKineticScrolling(true))
+
+	var router input.Router
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	now := time.Now()
+	frame := func(elapsed time.Duration) int {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(300, 200)), Source: router.Source(), Now: now.Add(elapsed)}
+		e.Layout(gtx, shaper)
+		router.Frame(gtx.Ops)
+		return e.text.ScrollOff().Y
+	}
+	frame(0)
+	frame(0)
+
+	// a trackpad swipe of 10 events of 20px, 10ms apart.
+	var elapsed time.Duration
+	for range 10 {
+		elapsed += 10 * time.Millisecond
+		router.Queue(pointer.Event{Kind: pointer.Scroll, Source: pointer.Mouse, Position: f32.Pt(100, 100), Scroll: f32.Pt(0, 20), Time: elapsed})
+		frame(elapsed)
+	}
+	swiped := frame(elapsed)
+	if swiped != 200 {
+		t.Fatalf("got offset %d after the swipe, want 200", swiped)
+	}
+
+	// the scrolling continues after the swipe, and decelerates.
+	first := frame(elapsed+100*time.Millisecond) - swiped
+	second := frame(elapsed+200*time.Millisecond) - swiped - first
+	if first <= 0 || second <= 0 || second >= first {
+		t.Errorf("got fling distances %d and %d, want decelerating scrolling", first, second)
+	}
+}
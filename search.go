@@ -0,0 +1,350 @@
+package gvcode
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/oligo/gvcode/internal/buffer"
+)
+
+// searchChunkSize is the approximate size of the text searched between
+// cancellation checks. Chunks are split at line breaks.
+const searchChunkSize = 1 << 20
+
+// SearchQuery describes what to search in the editor.
+type SearchQuery struct {
+	Pattern string
+	// Regexp interprets Pattern as a regular expression in the syntax of
+	// the regexp package. Matches do not span across chunks of about 1MB.
+	Regexp    bool
+	MatchCase bool
+	WholeWord bool
+}
+
+// SearchMatch is a range of text matched by a search, in rune offsets.
+type SearchMatch struct {
+	Start, End int
+}
+
+// SearchEvent is generated when a background search completes and its
+// matches are applied to the editor.
+type SearchEvent struct {
+	Query SearchQuery
+	// Matches is the number of the matches that are still valid.
+	Matches int
+	// Invalidated is the number of the matches dropped because the text
+	// was edited while searching.
+	Invalidated int
+}
+
+func (SearchEvent) isEditorEvent() {}
+
+// searchState tracks the running search and the matches of the last search.
+type searchState struct {
+	query SearchQuery
+	re    *regexp.Regexp
+	// boundary is set if the matches depend on the text around them, so
+	// edits adjacent to a match invalidate it.
+	boundary bool
+	notify   func()
+	cancel   context.CancelFunc
+	results  chan searchResult
+	// version is the version of the buffer that the matches are synced to.
+	version int
+	matches []searchItem
+}
+
+type searchResult struct {
+	version int
+	matches []SearchMatch
+}
+
+// searchItem is a match anchored to the buffer by markers.
+type searchItem struct {
+	SearchMatch
+	startMarker *buffer.Marker
+	endMarker   *buffer.Marker
+}
+
+// Search starts searching the query in the background, in a snapshot of the
+// text. The previous search is cancelled. As the user may keep editing while
+// searching, the matches are mapped to the latest text when they are applied,
+// and the ones touched by the edits are dropped. A SearchEvent is generated
+// by Update after that.
+//
+// notify, if not nil, is called from the searching goroutine when the search
+// completes, so that the host can invalidate the window, e.g., by calling
+// app.Window.Invalidate.
+func (e *Editor) Search(query SearchQuery, notify func()) error {
+	e.initBuffer()
+
+	pattern := query.Pattern
+	if !query.Regexp {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if query.WholeWord {
+		pattern = `\b(?:` + pattern + `)\b`
+	}
+	if !query.MatchCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+
+	e.ClearSearch()
+	e.search.query = query
+	e.search.re = re
+	e.search.boundary = query.Regexp || query.WholeWord
+	e.search.notify = notify
+	e.startSearch()
+	return nil
+}
+
+// startSearch searches a new snapshot of the text with the compiled query.
+func (e *Editor) startSearch() {
+	if e.search.cancel != nil {
+		e.search.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan searchResult, 1)
+	e.search.cancel = cancel
+	e.search.results = results
+
+	snapshot := e.buffer.Snapshot()
+	re, notify := e.search.re, e.search.notify
+	go func() {
+		matches, err := findMatches(ctx, re, snapshot)
+		if err != nil {
+			return
+		}
+		results <- searchResult{version: snapshot.Version(), matches: matches}
+		if notify != nil {
+			notify()
+		}
+	}()
+}
+
+// Searching reports whether a background search is running.
+func (e *Editor) Searching() bool {
+	return e.search.results != nil
+}
+
+// SearchMatches returns the matches of the last search, with their offsets
+// following the edits made since then.
+func (e *Editor) SearchMatches() []SearchMatch {
+	e.initBuffer()
+	e.syncSearch()
+
+	matches := make([]SearchMatch, 0, len(e.search.matches))
+	for _, item := range e.search.matches {
+		matches = append(matches, item.SearchMatch)
+	}
+	return matches
+}
+
+// ClearSearch cancels the running search, and removes the matches.
+func (e *Editor) ClearSearch() {
+	e.initBuffer()
+	if e.search.cancel != nil {
+		e.search.cancel()
+	}
+	e.removeSearchMatches(e.search.matches)
+	e.search = searchState{}
+}
+
+// syncSearch applies the result of the background search if it is ready, and
+// drops the matches touched by the edits made since the last sync.
+func (e *Editor) syncSearch() {
+	if e.search.results != nil {
+		select {
+		case r := <-e.search.results:
+			e.search.results = nil
+			e.search.cancel = nil
+			e.applySearchResult(r)
+		default:
+		}
+	}
+
+	if len(e.search.matches) == 0 {
+		e.search.version = e.buffer.Version()
+		return
+	}
+
+	edits, ok := e.buffer.EditsSince(e.search.version)
+	if !ok {
+		// Too many edits to track. Search again.
+		e.removeSearchMatches(e.search.matches)
+		e.search.matches = e.search.matches[:0]
+		e.startSearch()
+		return
+	}
+	if len(edits) == 0 {
+		return
+	}
+
+	valid := e.search.matches[:0]
+	var dropped []searchItem
+	for _, item := range e.search.matches {
+		if _, ok := e.mapSearchMatch(item.SearchMatch, edits); !ok {
+			dropped = append(dropped, item)
+			continue
+		}
+		item.Start = item.startMarker.Offset()
+		item.End = item.endMarker.Offset()
+		valid = append(valid, item)
+	}
+	e.removeSearchMatches(dropped)
+	e.search.matches = valid
+	e.search.version = e.buffer.Version()
+}
+
+// applySearchResult maps the matches found in a snapshot to the latest text
+// and anchors them with markers.
+func (e *Editor) applySearchResult(r searchResult) {
+	edits, ok := e.buffer.EditsSince(r.version)
+	if !ok {
+		e.startSearch()
+		return
+	}
+
+	e.search.matches = e.search.matches[:0]
+	invalidated := 0
+	for _, m := range r.matches {
+		m, ok := e.mapSearchMatch(m, edits)
+		if !ok {
+			invalidated++
+			continue
+		}
+
+		// Text inserted at the boundaries is not part of the match.
+		startMarker, err := e.buffer.CreateMarker(m.Start, buffer.BiasForward)
+		if err != nil {
+			invalidated++
+			continue
+		}
+		endMarker, err := e.buffer.CreateMarker(m.End, buffer.BiasBackward)
+		if err != nil {
+			e.buffer.RemoveMarker(startMarker)
+			invalidated++
+			continue
+		}
+		e.search.matches = append(e.search.matches, searchItem{
+			SearchMatch: m,
+			startMarker: startMarker,
+			endMarker:   endMarker,
+		})
+	}
+	e.search.version = e.buffer.Version()
+
+	e.pending = append(e.pending, SearchEvent{
+		Query:       e.search.query,
+		Matches:     len(e.search.matches),
+		Invalidated: invalidated,
+	})
+}
+
+// mapSearchMatch maps the match through the edits. It returns false if any
+// of the edits touches the match.
+func (e *Editor) mapSearchMatch(m SearchMatch, edits []buffer.Edit) (SearchMatch, bool) {
+	for _, edit := range edits {
+		start, end := m.Start, m.End
+		if e.search.boundary {
+			start, end = start-1, end+1
+		}
+		if edit.Overlaps(start, end) {
+			return m, false
+		}
+		if edit.OldEnd <= m.Start {
+			delta := edit.NewEnd - edit.OldEnd
+			m.Start += delta
+			m.End += delta
+		}
+	}
+	return m, true
+}
+
+func (e *Editor) removeSearchMatches(items []searchItem) {
+	for _, item := range items {
+		e.buffer.RemoveMarker(item.startMarker)
+		e.buffer.RemoveMarker(item.endMarker)
+	}
+}
+
+// findMatches finds the non-empty matches of re in the snapshot. The text
+// is searched in chunks so that the search can be cancelled in time.
+func findMatches(ctx context.Context, re *regexp.Regexp, snapshot *buffer.Snapshot) ([]SearchMatch, error) {
+	data := snapshot.Bytes()
+	var matches []SearchMatch
+
+	runeOff := 0
+	for byteOff := 0; byteOff < len(data); {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		end := min(byteOff+searchChunkSize, len(data))
+		if idx := bytes.IndexByte(data[end:], '\n'); idx >= 0 {
+			end += idx + 1
+		} else {
+			end = len(data)
+		}
+
+		chunk := data[byteOff:end]
+		last := 0
+		for _, loc := range re.FindAllIndex(chunk, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			runeOff += utf8.RuneCount(chunk[last:loc[0]])
+			start := runeOff
+			runeOff += utf8.RuneCount(chunk[loc[0]:loc[1]])
+			last = loc[1]
+			matches = append(matches, SearchMatch{Start: start, End: runeOff})
+		}
+		runeOff += utf8.RuneCount(chunk[last:])
+		byteOff = end
+	}
+
+	return matches, nil
+}
+
+// SelectNextMatch selects the first match of the last search after the
+// selection, or before it if backward is true, wrapping around the ends of
+// the document. It reports whether a match is selected.
+func (e *Editor) SelectNextMatch(backward bool) bool {
+	matches := e.SearchMatches()
+	if len(matches) == 0 {
+		return false
+	}
+
+	start, end := e.text.Selection()
+	if start > end {
+		start, end = end, start
+	}
+
+	match := matches[0]
+	if backward {
+		match = matches[len(matches)-1]
+		for i := len(matches) - 1; i >= 0; i-- {
+			if matches[i].End <= start {
+				match = matches[i]
+				break
+			}
+		}
+	} else {
+		for _, m := range matches {
+			if m.Start >= end {
+				match = m
+				break
+			}
+		}
+	}
+
+	e.SetCaret(match.End, match.Start)
+	return true
+}
@@ -0,0 +1,134 @@
+package gvcode
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// selectionExpansion keeps the selections replaced by ExpandSelection, so
+// that ShrinkSelection restores them in the reverse order.
+type selectionExpansion struct {
+	// stack holds the previous selections, the caret first.
+	stack [][2]int
+	// selection and version are the selection and the buffer version left
+	// by the last expansion. The stack is dropped when they change.
+	selection [2]int
+	version   int
+}
+
+// ExpandSelection grows the selection to the next enclosing syntactic unit:
+// the word at the caret, the contents of the enclosing string or brackets,
+// then the string or the brackets themselves, the line, the enclosing fold
+// ranges like blocks and functions, and finally the whole text. It reports
+// whether the selection is changed.
+func (e *Editor) ExpandSelection() bool {
+	e.initBuffer()
+	e.validateExpansion()
+	caret, anchor := e.text.Selection()
+	start, end := min(caret, anchor), max(caret, anchor)
+	newStart, newEnd, ok := e.expandedRange(start, end)
+	if !ok {
+		return false
+	}
+
+	x := &e.expansion
+	x.stack = append(x.stack, [2]int{caret, anchor})
+	e.SetCaret(newEnd, newStart)
+	x.selection = [2]int{newEnd, newStart}
+	x.version = e.TextVersion()
+	return true
+}
+
+// ShrinkSelection restores the selection replaced by the last
+// ExpandSelection, as long as the selection and the text have not been
+// changed since. It reports whether the selection is changed.
+func (e *Editor) ShrinkSelection() bool {
+	e.initBuffer()
+	e.validateExpansion()
+	x := &e.expansion
+	if len(x.stack) == 0 {
+		return false
+	}
+
+	prev := x.stack[len(x.stack)-1]
+	x.stack = x.stack[:len(x.stack)-1]
+	e.SetCaret(prev[0], prev[1])
+	x.selection = prev
+	return true
+}
+
+// validateExpansion drops the expansion stack if the selection or the text
+// have been changed in another way.
+func (e *Editor) validateExpansion() {
+	x := &e.expansion
+	caret, anchor := e.text.Selection()
+	if x.selection != [2]int{caret, anchor} || x.version != e.TextVersion() {
+		x.stack = x.stack[:0]
+	}
+}
+
+// expandedRange returns the smallest syntactic range strictly containing
+// [start, end).
+func (e *Editor) expandedRange(start, end int) (int, int, bool) {
+	best := [2]int{-1, -1}
+	consider := func(s, t int) {
+		if s < 0 || s > start || t < end || t-s <= end-start {
+			return
+		}
+		if best[0] < 0 || t-s < best[1]-best[0] {
+			best = [2]int{s, t}
+		}
+	}
+
+	// the word at the caret.
+	if ws, we := e.text.WordBoundariesAt(start, false); we >= end {
+		consider(ws, we)
+	}
+
+	// the string around the selection, without and with its quotes.
+	for _, tk := range e.text.TokensAt(start) {
+		if tk.Scope.Root() == "string" && tk.End >= end {
+			consider(tk.Start+1, tk.End-1)
+			consider(tk.Start, tk.End)
+		}
+	}
+
+	// the enclosing brackets, without and with them.
+	if left, right := e.text.EnclosingBrackets(start, end); left >= 0 {
+		consider(left+1, right)
+		consider(left, right+1)
+	}
+
+	// the lines spanning the selection, without their indentation and line
+	// break, then as a whole.
+	firstLine, _ := e.text.FindParagraph(start)
+	lastLine, _ := e.text.FindParagraph(max(start, end-1))
+	lineStart, lineEnd := e.linesRange(firstLine, lastLine)
+	text := e.ReadRange(lineStart, lineEnd)
+	trimmed := strings.TrimLeftFunc(text, unicode.IsSpace)
+	contentStart := lineStart + utf8.RuneCountInString(text) - utf8.RuneCountInString(trimmed)
+	contentEnd := contentStart + utf8.RuneCountInString(strings.TrimRightFunc(trimmed, unicode.IsSpace))
+	consider(contentStart, max(contentEnd, contentStart))
+	consider(lineStart, lineEnd)
+
+	// the fold ranges containing the lines, like blocks and functions.
+	if fm := e.text.FoldManager(); fm != nil {
+		for _, fold := range fm.GetFoldRanges() {
+			if fold.StartLine <= firstLine && fold.EndLine >= lastLine {
+				consider(e.linesRange(fold.StartLine, fold.EndLine))
+			}
+		}
+	}
+
+	consider(0, e.text.Len())
+	return best[0], best[1], best[0] >= 0
+}
+
+// linesRange returns the rune range of the lines from first to last, their
+// final line break included.
+func (e *Editor) linesRange(first, last int) (start, end int) {
+	start = e.text.ConvertPos(first, 0)
+	_, para := e.text.FindParagraph(e.text.ConvertPos(last, 0))
+	return start, para.RuneOff + para.Runes
+}
@@ -0,0 +1,76 @@
+package gvcode
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+func TestExpandSelection(t *testing.T) {
+	src := "package main\n\nfunc f() {\n\tif x {\n\t\tg(a, \"hello world\")\n\t}\n}\n"
+	scheme := syntax.ColorScheme{}
+	scheme.AddStyle("string", 0, gvcolor.Color{}, gvcolor.Color{})
+	e := newGoEditor(t, src, WithColorScheme(scheme))
+	strStart := utf8.RuneCountInString("package main\n\nfunc f() {\n\tif x {\n\t\tg(a, ")
+	e.SetSyntaxTokens(syntax.Token{Start: strStart, End: strStart + len(`"hello world"`), Scope: "string"})
+
+	// the caret in "world".
+	caret := strStart + len(`"hello wo`)
+	e.SetCaret(caret, caret)
+
+	want := []string{
+		"world",
+		"hello world",
+		`"hello world"`,
+		`a, "hello world"`,
+		`(a, "hello world")`,
+		`g(a, "hello world")`,
+		"\t\tg(a, \"hello world\")\n",
+		"\n\t\tg(a, \"hello world\")\n\t",
+		"{\n\t\tg(a, \"hello world\")\n\t}",
+		"if x {\n\t\tg(a, \"hello world\")\n\t}",
+		"\tif x {\n\t\tg(a, \"hello world\")\n\t}\n",
+		"\n\tif x {\n\t\tg(a, \"hello world\")\n\t}\n",
+		"{\n\tif x {\n\t\tg(a, \"hello world\")\n\t}\n}",
+		"func f() {\n\tif x {\n\t\tg(a, \"hello world\")\n\t}\n}",
+		"func f() {\n\tif x {\n\t\tg(a, \"hello world\")\n\t}\n}\n",
+		src,
+	}
+	for i, w := range want {
+		if !e.ExpandSelection() {
+			t.Fatalf("step %d: expected the selection to grow", i)
+		}
+		if got := e.SelectedText(); got != w {
+			t.Fatalf("step %d: got %q, want %q", i, got, w)
+		}
+	}
+	if e.ExpandSelection() {
+		t.Fatal("expected the whole text to be the last step")
+	}
+
+	// shrinking reverts the steps.
+	for i := len(want) - 2; i >= 0; i-- {
+		if !e.ShrinkSelection() {
+			t.Fatalf("step %d: expected the selection to shrink", i)
+		}
+		if got := e.SelectedText(); got != want[i] {
+			t.Fatalf("step %d: got %q, want %q", i, got, want[i])
+		}
+	}
+	e.ShrinkSelection()
+	if start, end := e.Selection(); start != caret || end != caret {
+		t.Fatalf("got selection %d-%d, want the caret at %d", start, end, caret)
+	}
+	if e.ShrinkSelection() {
+		t.Fatal("expected nothing to shrink")
+	}
+
+	// moving the selection drops the stack.
+	e.ExpandSelection()
+	e.SetCaret(0, 0)
+	if e.ShrinkSelection() {
+		t.Fatal("expected the stack to be dropped")
+	}
+}
@@ -0,0 +1,120 @@
+package gvcode
+
+import (
+	"image"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"github.com/oligo/gvcode/textview"
+)
+
+// scrollAnimation eases the viewport from a scroll offset to another, and
+// tracks the scrolling requested by ScrollToLine and ScrollToCaret.
+type scrollAnimation struct {
+	// duration of the animations. The viewport jumps if it is zero.
+	duration time.Duration
+
+	active   bool
+	from, to image.Point
+	begin    time.Time
+
+	// line is the line to scroll to in the next layout if lineRequested is
+	// true, and caretRequested requests scrolling to the caret.
+	line           int
+	lineRequested  bool
+	caretRequested bool
+}
+
+func (a *scrollAnimation) stop() {
+	a.active = false
+}
+
+// ScrollToLine scrolls the viewport in the next layout to place the line at
+// its top. The scrolling is animated if enabled by WithScrollAnimation.
+func (e *Editor) ScrollToLine(line int) {
+	e.initBuffer()
+	e.scrollAnim.line = max(line, 0)
+	e.scrollAnim.lineRequested = true
+}
+
+// ScrollToCaret scrolls the viewport in the next layout as little as possible
+// to make the caret visible. The scrolling is animated if enabled by
+// WithScrollAnimation, unlike the scrolling following the caret as the user
+// types or moves it.
+func (e *Editor) ScrollToCaret() {
+	e.initBuffer()
+	e.scrollAnim.caretRequested = true
+}
+
+// scrollToRequested scrolls to the line or the caret requested by
+// ScrollToLine or ScrollToCaret.
+func (e *Editor) scrollToRequested() {
+	a := &e.scrollAnim
+	if a.lineRequested {
+		a.lineRequested = false
+		runeOff := e.text.ConvertPos(a.line, 0)
+		e.scrollSmoothly(func() { e.text.ScrollToRange(runeOff, runeOff, textview.ScrollTop) })
+	}
+	if a.caretRequested {
+		a.caretRequested = false
+		e.scrollCaret = false
+		e.scrollSmoothly(e.text.ScrollToCaret)
+	}
+}
+
+// scrollSmoothly scrolls the viewport with scroll, animating the scrolling
+// from the current offset if the animations are enabled.
+func (e *Editor) scrollSmoothly(scroll func()) {
+	a := &e.scrollAnim
+	from := e.text.ScrollOff()
+	scroll()
+	if a.duration <= 0 || e.reducedMotion {
+		return
+	}
+
+	to := e.text.ScrollOff()
+	if to == from {
+		a.stop()
+		return
+	}
+	// the viewport is moved by animateScroll, starting in the next frame.
+	e.text.ScrollRel(from.X-to.X, from.Y-to.Y)
+	a.active = true
+	a.from, a.to = from, to
+	a.begin = time.Time{}
+}
+
+// animateScroll moves the viewport to its position in the current frame of
+// the scroll animation.
+func (e *Editor) animateScroll(gtx layout.Context) {
+	a := &e.scrollAnim
+	if !a.active {
+		return
+	}
+	if a.begin.IsZero() {
+		a.begin = gtx.Now
+	}
+
+	pos := a.to
+	if elapsed := gtx.Now.Sub(a.begin); elapsed < a.duration && !e.reducedMotion {
+		k := easeOutCubic(float32(elapsed) / float32(a.duration))
+		pos = a.from.Add(image.Point{
+			X: int(float32(a.to.X-a.from.X) * k),
+			Y: int(float32(a.to.Y-a.from.Y) * k),
+		})
+		gtx.Execute(op.InvalidateCmd{})
+	} else {
+		a.active = false
+	}
+
+	off := e.text.ScrollOff()
+	e.text.ScrollRel(pos.X-off.X, pos.Y-off.Y)
+}
+
+// easeOutCubic maps the progress t in [0, 1] of an animation to a distance
+// ratio, decelerating towards the end.
+func easeOutCubic(t float32) float32 {
+	t = 1 - t
+	return 1 - t*t*t
+}
@@ -0,0 +1,64 @@
+package gvcode
+
+import (
+	"image"
+	"sync"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestLayoutSnapshot(t *testing.T) {
+	e := newTestEditor(t, "func f() {\n\tx++\n}\n")
+	if e.LayoutSnapshot() != nil {
+		t.Fatal("expected no snapshot before they are enabled")
+	}
+	e.WithOptions(WithLayoutSnapshots(true))
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	relayout := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+		e.Layout(gtx, shaper)
+	}
+	relayout()
+
+	first := e.LayoutSnapshot()
+	if first == nil || len(first.Paragraphs) != e.Lines() {
+		t.Fatalf("got snapshot %v, want %d paragraphs", first, e.Lines())
+	}
+
+	// the snapshots are read while the editor lays out the edits.
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			s := e.LayoutSnapshot()
+			for _, line := range s.Lines {
+				for _, g := range line.Glyphs {
+					_ = g.X
+				}
+			}
+		}
+	}()
+	for range 20 {
+		e.SetCaret(0, 0)
+		e.Insert("// comment\n")
+		relayout()
+	}
+	close(done)
+	wg.Wait()
+
+	last := e.LayoutSnapshot()
+	if last == first || len(last.Paragraphs) != e.Lines() || len(first.Paragraphs) != 4 {
+		t.Fatalf("got %d paragraphs in the last snapshot, want %d, and 4 in the first one", len(last.Paragraphs), e.Lines())
+	}
+}
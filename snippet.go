@@ -3,6 +3,8 @@ package gvcode
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"gioui.org/io/key"
 	"gioui.org/layout"
@@ -75,11 +77,17 @@ func (sc *snippetContext) NextTabStop() error {
 	}
 
 	sc.currentIdx++
+	// mirrors follow the edits of their primary tabstop, skip them.
+	for sc.currentIdx < sc.state.TabStopSize() && sc.state.IsMirror(sc.currentIdx) {
+		sc.currentIdx++
+	}
 
+	sc.editor.cancelCompletor()
 	// sc.state.tabstops is sorted, so we can just iterate through it.
 	if sc.currentIdx < sc.state.TabStopSize() {
 		start, end := sc.getTabStopPosition(sc.currentIdx)
 		sc.editor.SetCaret(end, start)
+		sc.showChoices()
 	}
 
 	currentTabStop := sc.state.TabStopAt(sc.currentIdx)
@@ -98,10 +106,15 @@ func (sc *snippetContext) PrevTabStop() error {
 	}
 
 	sc.currentIdx--
+	for sc.currentIdx > 0 && sc.state.IsMirror(sc.currentIdx) {
+		sc.currentIdx--
+	}
 
+	sc.editor.cancelCompletor()
 	if sc.currentIdx >= 0 {
 		start, end := sc.getTabStopPosition(sc.currentIdx)
 		sc.editor.SetCaret(end, start)
+		sc.showChoices()
 		return nil
 	} else {
 		// Reached the end of the tabstops
@@ -122,6 +135,75 @@ func (sc *snippetContext) OnInsertAt(runeStart, runeEnd int) {
 	start, end := sc.getTabStopPosition(sc.currentIdx)
 	if runeStart < start || runeEnd > end+1 {
 		sc.editor.setMode(ModeNormal)
+		return
+	}
+
+	sc.syncMirrors()
+}
+
+// showChoices feeds the options of the current tabstop to the completion
+// popup, if the tabstop is a choice tabstop.
+func (sc *snippetContext) showChoices() {
+	choices := sc.state.TabStopAt(sc.currentIdx).Choices()
+	cmp, ok := sc.editor.completor.(ChoiceCompletion)
+	if len(choices) == 0 || !ok {
+		return
+	}
+
+	start, end := sc.getTabStopPosition(sc.currentIdx)
+	startLine, startPara := sc.editor.text.FindParagraph(start)
+	endLine, endPara := sc.editor.text.FindParagraph(end)
+	editRange := EditRange{
+		Start: Position{Line: startLine, Column: start - startPara.RuneOff, Runes: start},
+		End:   Position{Line: endLine, Column: end - endPara.RuneOff, Runes: end},
+	}
+
+	candidates := make([]CompletionCandidate, 0, len(choices))
+	for _, choice := range choices {
+		candidates = append(candidates, CompletionCandidate{
+			Label:    choice,
+			TextEdit: TextEdit{NewText: choice, EditRange: editRange},
+			Kind:     "value",
+		})
+	}
+
+	ctx := sc.editor.currentCompletionCtx()
+	md := sc.editor.Metadata()
+	ctx.Metadata = &md
+	cmp.OnChoices(ctx, candidates)
+}
+
+// groupSnippetEdit opens an undo group in snippet mode, so that an edit of
+// the current tabstop and the sync of its mirrors are undone in one step. It
+// returns the function closing the group.
+func (e *Editor) groupSnippetEdit() func() {
+	if e.mode != ModeSnippet {
+		return func() {}
+	}
+	e.buffer.GroupOp()
+	return e.buffer.UnGroupOp
+}
+
+// syncMirrors copies the content of the current tabstop to the other
+// tabstops sharing the same tabstop number.
+func (sc *snippetContext) syncMirrors() {
+	mirrors := sc.state.Mirrors(sc.currentIdx)
+	if len(mirrors) == 0 {
+		return
+	}
+
+	start, end := sc.getTabStopPosition(sc.currentIdx)
+	content := sc.editor.ReadRange(start, end)
+
+	for _, idx := range mirrors {
+		if idx >= len(sc.markers) {
+			continue
+		}
+		mStart, mEnd := sc.getTabStopPosition(idx)
+		if sc.editor.ReadRange(mStart, mEnd) == content {
+			continue
+		}
+		sc.editor.replace(mStart, mEnd, content)
 	}
 }
 
@@ -179,13 +261,31 @@ func (sc *snippetContext) Cancel() {
 	sc.editor.RemoveCommands(sc)
 }
 
+// InsertSnippet parses body as a LSP snippet and inserts it at the caret.
+// See InsertParsedSnippet for details.
 func (e *Editor) InsertSnippet(body string) (insertedRunes int, err error) {
 	snp := snippet.NewSnippet(body)
+	snp.SetVariableResolver(e.SnippetVariableResolver())
 	err = snp.Parse()
 	if err != nil {
 		return 0, err
 	}
 
+	return e.InsertParsedSnippet(snp)
+}
+
+// InsertParsedSnippet inserts the template of a parsed snippet and puts the
+// editor into snippet mode, where Tab/Shift+Tab cycles through the tabstops.
+// Edits to a tabstop are mirrored to the tabstops sharing the same number.
+// Set the resolver returned by SnippetVariableResolver to the snippet before
+// parsing it to resolve its variables.
+func (e *Editor) InsertParsedSnippet(snp *snippet.Snippet) (insertedRunes int, err error) {
+	if snp == nil {
+		return 0, errors.New("invalid snippet")
+	}
+	e.initBuffer()
+	body := snp.Raw()
+
 	if e.mode == ModeSnippet {
 		// An ongoing snippet session exists. To avoid nested snippet editing,
 		// we try to insert the snippet body as plain text.
@@ -207,3 +307,35 @@ func (e *Editor) InsertSnippet(body string) (insertedRunes int, err error) {
 	insertedRunes, err = e.snippetCtx.SetSnippet(snp)
 	return
 }
+
+// SnippetVariableResolver returns the resolver used to resolve snippet
+// variables. The resolver set by WithVariableResolver is tried first, then
+// the variables known to the editor, such as TM_SELECTED_TEXT and
+// TM_CURRENT_LINE, and finally the date and time variables.
+func (e *Editor) SnippetVariableResolver() snippet.VariableResolver {
+	return snippet.ChainResolvers(e.variableResolver, snippet.VariableResolverFunc(e.resolveVariable), snippet.TimeResolver{})
+}
+
+// resolveVariable resolves the snippet variables derived from the editor state.
+func (e *Editor) resolveVariable(name string) (string, bool) {
+	e.initBuffer()
+
+	switch name {
+	case "TM_SELECTED_TEXT":
+		return e.SelectedText(), true
+	case "TM_CURRENT_LINE":
+		e.scratch, _, _ = e.text.SelectedLineText(e.scratch)
+		return strings.TrimRight(string(e.scratch), "\r\n"), true
+	case "TM_CURRENT_WORD":
+		word, _ := e.text.ReadWord(false)
+		return word, true
+	case "TM_LINE_INDEX":
+		line, _ := e.CaretPos()
+		return strconv.Itoa(line), true
+	case "TM_LINE_NUMBER":
+		line, _ := e.CaretPos()
+		return strconv.Itoa(line + 1), true
+	}
+
+	return "", false
+}
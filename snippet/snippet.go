@@ -42,6 +42,16 @@ func (ts TabStop) IsFinal() bool {
 	return ts.idx == 0 && ts.variable == ""
 }
 
+// Index returns the tabstop number, e.g., 1 for $1 or ${1:foo}.
+func (ts TabStop) Index() int {
+	return ts.idx
+}
+
+// Choices returns the options of a choice tabstop like ${1|one,two|}.
+func (ts TabStop) Choices() []string {
+	return ts.choices
+}
+
 func (sc TabStop) String() string {
 	return fmt.Sprintf("TabStop(%d-%d)[content: %s, idx: %d, placeholder: %s, choices: %v, variable: %s, variableDefault: %s]",
 		sc.location.start, sc.location.end, sc.content, sc.idx, sc.placeholder, sc.choices, sc.variable, sc.variableDefault)
@@ -58,12 +68,19 @@ type Snippet struct {
 	template  string
 	tabStops  []*TabStop
 	locations map[*TabStop]runesOff
+	resolver  VariableResolver
 }
 
 func NewSnippet(content string) *Snippet {
 	return &Snippet{raw: content}
 }
 
+// SetVariableResolver sets the resolver used to resolve variables when
+// parsing the snippet. It must be called before Parse.
+func (s *Snippet) SetVariableResolver(r VariableResolver) {
+	s.resolver = r
+}
+
 func (s *Snippet) Parse() error {
 	err := s.parseTabstops()
 	if err != nil {
@@ -92,6 +109,10 @@ func (s *Snippet) Parse() error {
 			return -1
 		}
 
+		if a.idx == b.idx {
+			// keep mirrors of the same tabstop in the order they appear.
+			return cmp.Compare(a.location.start, b.location.start)
+		}
 		return cmp.Compare(a.idx, b.idx)
 	})
 
@@ -222,6 +243,22 @@ func (s *Snippet) buildTemplate() {
 		return
 	}
 
+	// Mirrors without their own placeholder share the placeholder or choices
+	// of the first tabstop with the same index.
+	for _, st := range s.tabStops {
+		if st.variable != "" || st.placeholder != "" || len(st.choices) > 0 {
+			continue
+		}
+		for _, other := range s.tabStops {
+			if other != st && other.variable == "" && other.idx == st.idx &&
+				(other.placeholder != "" || len(other.choices) > 0) {
+				st.placeholder = other.placeholder
+				st.choices = other.choices
+				break
+			}
+		}
+	}
+
 	bytesOffDelta := 0
 	for _, st := range s.tabStops {
 		var updatedStr string
@@ -229,10 +266,15 @@ func (s *Snippet) buildTemplate() {
 		var delta int
 
 		if st.variable != "" {
-			// TODO: inject variable value here. Use default value for now.
+			value := st.variableDefault
+			if s.resolver != nil {
+				if resolved, ok := s.resolver.ResolveVariable(st.variable); ok {
+					value = resolved
+				}
+			}
 			updatedStr, offset, delta = replaceAtIndex(
 				s.template,
-				st.variableDefault,
+				value,
 				bytesOffDelta+st.location.start,
 				bytesOffDelta+st.location.end)
 			bytesOffDelta += delta
@@ -299,6 +341,42 @@ func (s *Snippet) TabStopOff(idx int) (int, int) {
 	return loc.start, loc.end
 }
 
+// IsMirror reports whether the tabstop at idx repeats a tabstop number that
+// appears earlier in the sorted tabstops. Mirrors are not visited when
+// navigating, their content follows the edits of the first one instead.
+func (s *Snippet) IsMirror(idx int) bool {
+	if idx <= 0 || idx >= len(s.tabStops) {
+		return false
+	}
+
+	ts, prev := s.tabStops[idx], s.tabStops[idx-1]
+	if ts.variable != "" || prev.variable != "" || ts.IsFinal() {
+		return false
+	}
+	return ts.idx == prev.idx
+}
+
+// Mirrors returns the positions of the other tabstops sharing the same
+// tabstop number with the one at idx.
+func (s *Snippet) Mirrors(idx int) []int {
+	if idx < 0 || idx >= len(s.tabStops) {
+		return nil
+	}
+
+	ts := s.tabStops[idx]
+	if ts.variable != "" || ts.IsFinal() {
+		return nil
+	}
+
+	var mirrors []int
+	for i, other := range s.tabStops {
+		if i != idx && other.variable == "" && other.idx == ts.idx {
+			mirrors = append(mirrors, i)
+		}
+	}
+	return mirrors
+}
+
 func replaceAtIndex(text string, replacement string, start, end int) (string, runesOff, int) {
 	start = min(start, end)
 	end = max(start, end)
@@ -44,3 +44,58 @@ func TestSnippetParse(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestSnippetMirrors(t *testing.T) {
+	snp := NewSnippet(`${1:name} := $1 + ${2:val}`)
+	if err := snp.Parse(); err != nil {
+		t.FailNow()
+	}
+
+	if snp.Template() != `name := name + val` {
+		t.Logf("template: %s", snp.Template())
+		t.Fail()
+	}
+
+	if snp.IsMirror(0) || !snp.IsMirror(1) || snp.IsMirror(2) {
+		t.Logf("wrong mirror state: %v", snp.TabStops())
+		t.Fail()
+	}
+
+	mirrors := snp.Mirrors(0)
+	if len(mirrors) != 1 || mirrors[0] != 1 {
+		t.Logf("wrong mirrors: %v", mirrors)
+		t.Fail()
+	}
+
+	start, end := snp.TabStopOff(1)
+	if start != 8 || end != 12 {
+		t.Logf("wrong mirror offset: %d-%d", start, end)
+		t.Fail()
+	}
+}
+
+func TestSnippetVariables(t *testing.T) {
+	resolver := VariableResolverFunc(func(name string) (string, bool) {
+		if name == "TM_SELECTED_TEXT" {
+			return "sel", true
+		}
+		return "", false
+	})
+
+	snp := NewSnippet(`[$TM_SELECTED_TEXT] ${TM_FILENAME:file.go} ${1:x}`)
+	snp.SetVariableResolver(resolver)
+	if err := snp.Parse(); err != nil {
+		t.FailNow()
+	}
+
+	if snp.Template() != `[sel] file.go x` {
+		t.Logf("template: %s", snp.Template())
+		t.Fail()
+	}
+
+	start, end := snp.TabStopOff(0)
+	if start != 14 || end != 15 {
+		t.Logf("wrong tabstop offset: %d-%d", start, end)
+		t.Fail()
+	}
+}
@@ -0,0 +1,82 @@
+package snippet
+
+import (
+	"strconv"
+	"time"
+)
+
+// VariableResolver resolves the value of snippet variables such as
+// TM_SELECTED_TEXT or CURRENT_YEAR at insertion time. It returns false if
+// the variable is unknown to the resolver, in which case the default value
+// of the variable is used.
+type VariableResolver interface {
+	ResolveVariable(name string) (string, bool)
+}
+
+// VariableResolverFunc adapts a function to the VariableResolver interface.
+type VariableResolverFunc func(name string) (string, bool)
+
+func (f VariableResolverFunc) ResolveVariable(name string) (string, bool) {
+	return f(name)
+}
+
+// ChainResolvers returns a resolver that tries each of the resolvers in order
+// and returns the first resolved value. Nil resolvers are skipped.
+func ChainResolvers(resolvers ...VariableResolver) VariableResolver {
+	return VariableResolverFunc(func(name string) (string, bool) {
+		for _, r := range resolvers {
+			if r == nil {
+				continue
+			}
+			if val, ok := r.ResolveVariable(name); ok {
+				return val, true
+			}
+		}
+		return "", false
+	})
+}
+
+// TimeResolver resolves the date and time variables defined by VS Code,
+// e.g., CURRENT_YEAR, CURRENT_MONTH or CURRENT_SECONDS_UNIX, using the
+// time returned by Now. If Now is nil, time.Now is used.
+type TimeResolver struct {
+	Now func() time.Time
+}
+
+func (r TimeResolver) ResolveVariable(name string) (string, bool) {
+	now := time.Now()
+	if r.Now != nil {
+		now = r.Now()
+	}
+
+	switch name {
+	case "CURRENT_YEAR":
+		return strconv.Itoa(now.Year()), true
+	case "CURRENT_YEAR_SHORT":
+		return now.Format("06"), true
+	case "CURRENT_MONTH":
+		return now.Format("01"), true
+	case "CURRENT_MONTH_NAME":
+		return now.Month().String(), true
+	case "CURRENT_MONTH_NAME_SHORT":
+		return now.Format("Jan"), true
+	case "CURRENT_DATE":
+		return now.Format("02"), true
+	case "CURRENT_DAY_NAME":
+		return now.Weekday().String(), true
+	case "CURRENT_DAY_NAME_SHORT":
+		return now.Format("Mon"), true
+	case "CURRENT_HOUR":
+		return now.Format("15"), true
+	case "CURRENT_MINUTE":
+		return now.Format("04"), true
+	case "CURRENT_SECOND":
+		return now.Format("05"), true
+	case "CURRENT_SECONDS_UNIX":
+		return strconv.FormatInt(now.Unix(), 10), true
+	case "CURRENT_TIMEZONE_OFFSET":
+		return now.Format("-07:00"), true
+	}
+
+	return "", false
+}
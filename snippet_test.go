@@ -0,0 +1,72 @@
+package gvcode
+
+import "testing"
+
+func TestSnippetMirrorUndo(t *testing.T) {
+	e := newTestEditor(t, "")
+	if _, err := e.InsertSnippet("${1:a} = ${1:a}"); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.Text(); got != "a = a" {
+		t.Fatalf("got %q after inserting the snippet", got)
+	}
+
+	// the tabstop is selected, and typing replaces it and its mirror.
+	e.Insert("x")
+	if got := e.Text(); got != "x = x" {
+		t.Fatalf("got %q after typing in the tabstop, want the mirror synced", got)
+	}
+	e.Delete(-1)
+	if got := e.Text(); got != " = " {
+		t.Fatalf("got %q after deleting in the tabstop, want the mirror synced", got)
+	}
+
+	// each edit and the sync of its mirror are undone in one step.
+	e.undo()
+	if got := e.Text(); got != "x = x" {
+		t.Fatalf("got %q after undoing the deletion, want %q", got, "x = x")
+	}
+	e.undo()
+	if got := e.Text(); got != "a = a" {
+		t.Fatalf("got %q after undoing the typing, want %q", got, "a = a")
+	}
+}
+
+// choiceStubCompletion records the choices offered by the snippets.
+type choiceStubCompletion struct {
+	stubCompletion
+	choices []CompletionCandidate
+}
+
+func (c *choiceStubCompletion) OnChoices(ctx CompletionContext, candidates []CompletionCandidate) {
+	c.choices = candidates
+}
+
+func TestSnippetChoices(t *testing.T) {
+	e := newTestEditor(t, "")
+	cmp := &choiceStubCompletion{}
+	e.WithOptions(WithAutoCompletion(cmp))
+	if _, err := e.InsertSnippet("x := ${1|one,two|}; ${2:y}"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the options replace the content of the choice tabstop.
+	if len(cmp.choices) != 2 || cmp.choices[0].Label != "one" || cmp.choices[1].Label != "two" {
+		t.Fatalf("got choices %+v, want one and two", cmp.choices)
+	}
+	start, end := e.Selection()
+	if start > end {
+		start, end = end, start
+	}
+	rng := cmp.choices[1].TextEdit.EditRange
+	if rng.Start.Runes != start || rng.End.Runes != end || cmp.choices[1].TextEdit.NewText != "two" {
+		t.Errorf("got the edit %+v, want %q replacing %d-%d", cmp.choices[1].TextEdit, "two", start, end)
+	}
+
+	// the tabstops without choices offer none.
+	cmp.choices = nil
+	e.snippetCtx.NextTabStop()
+	if cmp.choices != nil {
+		t.Errorf("got choices %+v for a placeholder tabstop", cmp.choices)
+	}
+}
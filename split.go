@@ -0,0 +1,58 @@
+package gvcode
+
+// Split creates a second editor showing the document of e, e.g., for the
+// other pane of a split view. The edits made in either editor appear in
+// both, and share one undo history, while the scroll offset, the selection,
+// the folds, the decorations and the syntax tokens are kept per editor. The
+// language and the indentation of e are applied to the new editor, and the
+// options, like WithCodeFolding or WithColorScheme, are applied after
+// them.
+//
+// The edits of the other editor generate a ChangeEvent. Call CloseSplit when
+// the pane of the new editor is closed.
+func (e *Editor) Split(options ...EditorOption) *Editor {
+	e.initBuffer()
+	e.text.SetShared(true)
+
+	s := &Editor{}
+	s.initBuffer()
+	s.text.SetViewState(e.text.NewViewState(e.buffer))
+	s.buffer = s.text.Source()
+	s.text.SetShared(true)
+
+	s.text.Font = e.text.Font
+	s.text.TextSize = e.text.TextSize
+	s.text.SoftTab = e.text.SoftTab
+	s.text.TabWidth = e.text.TabWidth
+	s.text.VisualTabWidth = e.text.VisualTabWidth
+	s.text.WordSeperators = e.text.WordSeperators
+	s.text.WordChars = e.text.WordChars
+	s.text.BracketsQuotes = e.text.BracketsQuotes.Clone()
+	s.language = e.language
+	s.metadata = e.metadata.Clone()
+
+	s.WithOptions(options...)
+	if e.language.ID != "" {
+		// the fold markers are set on the fold manager of the options.
+		s.SetLanguage(e.language.ID)
+	}
+	return s
+}
+
+// CloseSplit stops following the edits made through the other editors
+// showing the document, created by Split. The editor should not be used
+// afterwards.
+func (e *Editor) CloseSplit() {
+	e.initBuffer()
+	e.text.SetShared(false)
+}
+
+// syncPeerEdits generates a ChangeEvent for the edits made through the other
+// editors showing the document. The changed flag of the shared source is
+// cleared too, as the event reports its edits.
+func (e *Editor) syncPeerEdits() {
+	if e.text.Shared() && e.text.PeerEdited() {
+		e.text.Changed()
+		e.pending = append(e.pending, ChangeEvent{})
+	}
+}
@@ -0,0 +1,82 @@
+package gvcode
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+func TestSplit(t *testing.T) {
+	e := newTestEditor(t, "package main\n\nfunc main() {\n}\n")
+	split := e.Split(WithColorScheme(syntax.ColorScheme{}), WithTextSize(14))
+	if split.Text() != e.Text() {
+		t.Fatalf("got %q in the split, want %q", split.Text(), e.Text())
+	}
+
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	changes := func(ed *Editor) int {
+		n := 0
+		for {
+			evt, ok := ed.Update(gtx)
+			if !ok {
+				break
+			}
+			if _, ok := evt.(ChangeEvent); ok {
+				n++
+			}
+		}
+		ed.Layout(gtx, shaper)
+		return n
+	}
+	changes(e)
+	changes(split)
+
+	// the selections are independent, and follow the edits of the other
+	// editor.
+	e.SetCaret(0, 0)
+	split.SetCaret(14, 18)
+	e.Insert("// doc\n")
+	if got := split.Text(); got != "// doc\npackage main\n\nfunc main() {\n}\n" {
+		t.Fatalf("got %q in the split after the edit", got)
+	}
+	if start, end := split.Selection(); start != 21 || end != 25 {
+		t.Errorf("got the split selection %d-%d, want 21-25", start, end)
+	}
+	if got := split.SelectedText(); got != "func" {
+		t.Errorf("got the split selection %q, want func", got)
+	}
+	if start, _ := e.Selection(); start != 7 {
+		t.Errorf("got the caret at %d in the edited editor, want 7", start)
+	}
+	if n := changes(split); n != 1 {
+		t.Errorf("got %d change events in the split, want 1", n)
+	}
+
+	// the edits of the split are undone by the other editor.
+	split.SetCaret(split.Len(), split.Len())
+	split.Insert("// end\n")
+	if got := e.Text(); got != split.Text() {
+		t.Fatalf("got %q, want the text of the split %q", got, split.Text())
+	}
+	e.undo()
+	if got := split.Text(); got != "// doc\npackage main\n\nfunc main() {\n}\n" {
+		t.Errorf("got %q in the split after the undo", got)
+	}
+	if start, _ := split.Selection(); start != split.Len() {
+		t.Errorf("got the split caret at %d after the undo, want %d", start, split.Len())
+	}
+
+	split.CloseSplit()
+	split.SetCaret(0, 0)
+	e.SetCaret(0, 0)
+	e.Insert("x")
+	if start, _ := split.Selection(); start != 0 {
+		t.Errorf("got the caret of the closed split at %d, want 0", start)
+	}
+}
@@ -0,0 +1,150 @@
+package gvcode
+
+import (
+	"image"
+	"image/color"
+	"strings"
+
+	"gioui.org/gesture"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/gutter"
+	"github.com/oligo/gvcode/gutter/providers"
+)
+
+// stickyLinesProvider returns the sticky lines provider registered in the
+// gutter.
+func (e *Editor) stickyLinesProvider() *providers.StickyLinesProvider {
+	if e.gutterManager == nil {
+		return nil
+	}
+
+	for _, p := range e.gutterManager.Providers() {
+		if provider, ok := p.(*providers.StickyLinesProvider); ok {
+			return provider
+		}
+	}
+	return nil
+}
+
+// renderStickyLines renders sticky lines at the top of the editor viewport.
+// Sticky lines show code structure (functions, types, etc.) that has been scrolled
+// out of view, helping users maintain context. Clicking a sticky line moves
+// the caret to it. It returns the height of the sticky lines area, which is
+// also kept clear by the scrolling to the caret.
+func (e *Editor) renderStickyLines(gtx layout.Context, shaper *text.Shaper, textColor gvcolor.Color) int {
+	e.stickyHeight = 0
+	defer func() { e.text.SetScrollInset(e.stickyHeight) }()
+
+	provider := e.stickyLinesProvider()
+	if provider == nil {
+		return 0
+	}
+
+	// the clicks are routed through the provider, which generates the
+	// events of the clicked lines.
+	for {
+		evt, ok := e.stickyLinesClicker.Update(gtx.Source)
+		if !ok {
+			break
+		}
+		if evt.Kind == gesture.KindClick {
+			provider.HandleStickyLineClick(int(evt.Position.Y))
+		}
+	}
+	for _, evt := range provider.GetPendingEvents() {
+		e.jumpToStickyLine(evt.Line)
+		gtx.Execute(op.InvalidateCmd{})
+		e.pending = append(e.pending, StickyLineEventWrapper{
+			Event: gutter.StickyLineEvent{Line: evt.Line, Text: evt.Text},
+		})
+	}
+
+	stickyLines, stickyHeight := provider.GetStickyLinesInfo()
+	if len(stickyLines) == 0 || stickyHeight == 0 || shaper == nil {
+		return 0
+	}
+	e.stickyHeight = stickyHeight
+	lineHeight := stickyHeight / len(stickyLines)
+	area := image.Rect(0, 0, gtx.Constraints.Max.X, stickyHeight)
+
+	bgColor := color.NRGBA{R: 0xF0, G: 0xF0, B: 0xF0, A: 0xFF}
+	if e.colorPalette != nil && e.colorPalette.Background.IsSet() {
+		bgColor = e.colorPalette.Background.NRGBA()
+		bgColor.A = 0xFF
+	}
+	paint.FillShape(gtx.Ops, bgColor, clip.Rect(area).Op())
+
+	borderColor := textColor.NRGBA()
+	borderColor.A = 0x40
+	paint.FillShape(gtx.Ops, borderColor, clip.Rect(image.Rect(0, area.Max.Y-1, area.Max.X, area.Max.Y)).Op())
+
+	params := e.text.Params()
+	params.MinWidth = 0
+	params.MaxWidth = 1 << 20
+	params.MaxLines = 1
+
+	// the lines are aligned with the text below them.
+	tabWidth := e.text.RenderTabWidth()
+	scrollX := e.text.ScrollOff().X
+	for i, sticky := range stickyLines {
+		line := strings.TrimRight(sticky.Text, " \t\r\n")
+		line = expandLeadingTabs(line, tabWidth)
+		if line == "" {
+			continue
+		}
+		shaper.LayoutString(params, line)
+		paintTextGlyphs(gtx, shaper, image.Pt(-scrollX, i*lineHeight), area.Dx()+scrollX, textColor)
+	}
+
+	// the area covers the text, so that clicking it does not move the caret
+	// under it.
+	defer clip.Rect(area).Push(gtx.Ops).Pop()
+	pointer.CursorPointer.Add(gtx.Ops)
+	e.stickyLinesClicker.Add(gtx.Ops)
+
+	return stickyHeight
+}
+
+// inStickyArea reports whether pos falls in the sticky lines area. Pointer
+// events landing there belong to the sticky lines.
+func (e *Editor) inStickyArea(pos image.Point) bool {
+	return pos.Y >= 0 && pos.Y < e.stickyHeight
+}
+
+// jumpToStickyLine moves the caret to the start of the line, and scrolls the
+// line to the top of the viewport below the sticky lines.
+func (e *Editor) jumpToStickyLine(line int) {
+	runeOff := e.text.ConvertPos(line, 0)
+	e.text.SetCaret(runeOff, runeOff)
+	e.ScrollToLine(line)
+	e.notifyGutterJump(runeOff)
+}
+
+// expandLeadingTabs replaces the tabs of the indentation of line by spaces.
+func expandLeadingTabs(line string, tabWidth int) string {
+	indent := len(line) - len(strings.TrimLeft(line, " \t"))
+	if tabWidth <= 0 || !strings.Contains(line[:indent], "\t") {
+		return line
+	}
+
+	var b strings.Builder
+	col := 0
+	for _, r := range line[:indent] {
+		if r == '\t' {
+			n := tabWidth - col%tabWidth
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		b.WriteRune(r)
+		col++
+	}
+	b.WriteString(line[indent:])
+	return b.String()
+}
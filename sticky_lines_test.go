@@ -0,0 +1,66 @@
+package gvcode
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestStickyLines(t *testing.T) {
+	e := newTestEditor(t, "func f() {\n"+strings.Repeat("\tx++\n", 200)+"}\n")
+	e.WithOptions(WithStickyLines())
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	relayout := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+		e.Layout(gtx, shaper)
+	}
+
+	e.ScrollToLine(100)
+	// the gutter computes the sticky lines from the previous layout.
+	relayout()
+	relayout()
+	lineHeight := e.text.GetLineHeight().Ceil()
+	if e.stickyHeight != lineHeight {
+		t.Fatalf("sticky area height: got %d, want %d", e.stickyHeight, lineHeight)
+	}
+
+	// the caret is not scrolled under the sticky line.
+	top, _, _ := e.text.QueryPos(image.Point{})
+	start, _ := e.ConvertPos(top, 0)
+	e.SetCaret(start, start)
+	relayout()
+	if _, pos := e.ConvertPos(top, 0); int(pos.Y) < e.stickyHeight {
+		t.Fatalf("line %d at %v is under the sticky area of height %d", top, pos.Y, e.stickyHeight)
+	}
+
+	// clicking the sticky line moves the caret to it.
+	e.stickyLinesProvider().HandleStickyLineClick(lineHeight / 2)
+	relayout()
+	if start, end := e.Selection(); start != 0 || end != 0 {
+		t.Fatalf("selection after clicking the sticky line: got (%d, %d), want (0, 0)", start, end)
+	}
+	// the event is returned by the next Update.
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+	var clicked bool
+	for {
+		evt, ok := e.Update(gtx)
+		if !ok {
+			break
+		}
+		if evt, ok := evt.(StickyLineEventWrapper); ok && evt.Event.Line == 0 {
+			clicked = true
+		}
+	}
+	if !clicked {
+		t.Fatal("expected a StickyLineEventWrapper for line 0")
+	}
+	relayout()
+	if e.text.ScrollOff().Y != 0 {
+		t.Fatalf("expected the first line at the top, scrolled to %d", e.text.ScrollOff().Y)
+	}
+}
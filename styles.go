@@ -2,6 +2,7 @@ package gvcode
 
 import (
 	"log/slog"
+	"time"
 
 	"github.com/oligo/gvcode/textstyle/decoration"
 	"github.com/oligo/gvcode/textstyle/syntax"
@@ -26,11 +27,78 @@ func (e *Editor) ClearDecorations(source string) {
 	e.text.ClearDecorations(source)
 }
 
+// clickDecoration calls the click handler of the decoration under the caret
+// position runeOff, picking the one with the highest priority. The caret
+// positions at both ends of a decoration are considered on it, as a click on
+// the last half of a rune places the caret after it. It reports whether a
+// handler is called.
+func (e *Editor) clickDecoration(runeOff int) bool {
+	var clicked *decoration.Decoration
+	for _, deco := range e.text.QueryDecorations(max(runeOff-1, 0), runeOff+1) {
+		if deco.Click == nil || deco.Click.OnClick == nil || runeOff < deco.Start || runeOff > deco.End {
+			continue
+		}
+		if clicked == nil || deco.Priority > clicked.Priority {
+			clicked = &deco
+		}
+	}
+	if clicked == nil {
+		return false
+	}
+	clicked.Click.OnClick(clicked.Start, clicked.End)
+	return true
+}
+
 func (e *Editor) SetSyntaxTokens(tokens ...syntax.Token) {
 	e.initBuffer()
 	if e.colorPalette == nil {
 		slog.Info("No color palette configured.")
 		return
 	}
+	start := time.Now()
 	e.text.SetSyntaxTokens(tokens...)
+	e.frames.current.Tokenize += time.Since(start)
+}
+
+// SetSyntaxTokensInRange replaces the syntax tokens in the rune range
+// [start, end) with tokens, keeping the tokens outside of the range. Tokens
+// crossing a boundary of the range are cut at it, and tokens are clipped to
+// the range. External highlighters, like a language server sending semantic
+// token deltas, can use it to update the tokens of the edited lines only,
+// instead of replacing all the tokens with SetSyntaxTokens. The tokens should
+// be sorted by their range in ascending order.
+func (e *Editor) SetSyntaxTokensInRange(start, end int, tokens ...syntax.Token) {
+	e.initBuffer()
+	if e.colorPalette == nil {
+		slog.Info("No color palette configured.")
+		return
+	}
+	start, end = min(start, end), max(start, end)
+	begin := time.Now()
+	e.text.SetSyntaxTokensInRange(start, end, tokens...)
+	e.frames.current.Tokenize += time.Since(begin)
+}
+
+// TokenAt returns the innermost syntax token covering the rune at runeOff,
+// merged across nested tokens, with its original scope. It reports false if
+// no token covers runeOff. Features like hover providers or spell checkers can
+// use it to avoid re-lexing the text.
+func (e *Editor) TokenAt(runeOff int) (syntax.Token, bool) {
+	e.initBuffer()
+	return e.text.TokenAt(runeOff)
+}
+
+// TokensAt returns all the syntax tokens covering the rune at runeOff, from
+// the outermost to the innermost one.
+func (e *Editor) TokensAt(runeOff int) []syntax.Token {
+	e.initBuffer()
+	return e.text.TokensAt(runeOff)
+}
+
+// ScopeAt returns the syntax scope of the innermost token covering the rune
+// at runeOff, or an empty scope if no token covers it. Editing features can
+// consult it to behave differently inside of strings or comments.
+func (e *Editor) ScopeAt(runeOff int) syntax.StyleScope {
+	e.initBuffer()
+	return e.text.ScopeAt(runeOff)
 }
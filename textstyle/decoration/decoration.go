@@ -7,8 +7,7 @@ import (
 
 	"github.com/oligo/gvcode/color"
 	"github.com/oligo/gvcode/internal/buffer"
-	"github.com/oligo/gvcode/internal/layout"
-	"github.com/oligo/gvcode/internal/painter"
+	"github.com/oligo/gvcode/painter"
 	"github.com/rdleal/intervalst/interval"
 )
 
@@ -37,6 +36,13 @@ type Border struct {
 	Color color.Color
 }
 
+// ClickHandler makes the decorated text clickable.
+type ClickHandler struct {
+	// OnClick is called with the current range of the decoration when the
+	// decorated text is clicked.
+	OnClick func(start, end int)
+}
+
 // A decoration represents styles sharing between a range of text. After added
 // to the editor, the decoration position is dynamically updated, so there is no
 // need to re-create it every time the text changed in the editor.
@@ -52,6 +58,7 @@ type Decoration struct {
 	Squiggle      *Squiggle
 	Strikethrough *Strikethrough
 	Border        *Border
+	Click         *ClickHandler
 	Italic        bool
 	Bold          bool
 	startMarker   *buffer.Marker
@@ -342,6 +349,6 @@ func (d *DecorationTree) Refresh() {
 }
 
 // Split implements painter.LineSplitter
-func (t *DecorationTree) Split(line layout.Line, runs *[]painter.RenderRun) {
+func (t *DecorationTree) Split(line painter.Line, runs *[]painter.RenderRun) {
 	t.lineSplitter.Split(line, t, runs)
 }
@@ -6,8 +6,7 @@ import (
 	"slices"
 
 	"gioui.org/text"
-	"github.com/oligo/gvcode/internal/layout"
-	"github.com/oligo/gvcode/internal/painter"
+	"github.com/oligo/gvcode/painter"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -22,7 +21,7 @@ type decorationLineSplitter struct {
 	advance fixed.Int26_6
 }
 
-// func (rb *decorationLineSplitter) setup(line *layout.Line) {
+// func (rb *decorationLineSplitter) setup(line *painter.Line) {
 // 	//lineIter := line.All()
 // 	//rb.nextGlyph, rb.stopFunc = iter.Pull(lineIter)
 // 	rb.current = painter.RenderRun{}
@@ -44,7 +43,7 @@ func (rb *decorationLineSplitter) commitLast(runs *[]painter.RenderRun) {
 // is only interested in the styling fields. We may ommit the glyphs in the
 // future to save memory if the various metrics the painter needed are stored
 // in the RenderRun.
-func (rb *decorationLineSplitter) Split(line layout.Line, decorations *DecorationTree, runs *[]painter.RenderRun) {
+func (rb *decorationLineSplitter) Split(line painter.Line, decorations *DecorationTree, runs *[]painter.RenderRun) {
 	*runs = (*runs)[:0]
 	rb.runeOff = line.RuneOff
 	rb.current = painter.RenderRun{}
@@ -95,7 +94,7 @@ func (rb *decorationLineSplitter) Split(line layout.Line, decorations *Decoratio
 	}
 }
 
-func (rb *decorationLineSplitter) readToRun(line layout.Line, start, end int) error {
+func (rb *decorationLineSplitter) readToRun(line painter.Line, start, end int) error {
 	if rb.runeOff > start {
 		// start reading from the begining.
 		rb.runeOff = line.RuneOff
@@ -126,7 +125,7 @@ func (rb *decorationLineSplitter) readToRun(line layout.Line, start, end int) er
 	return nil
 }
 
-func (rb *decorationLineSplitter) readGlyph(line layout.Line) (*text.Glyph, error) {
+func (rb *decorationLineSplitter) readGlyph(line painter.Line) (*text.Glyph, error) {
 	if rb.glyphOff < len(line.Glyphs) {
 		gl := line.Glyphs[rb.glyphOff]
 		if gl == nil {
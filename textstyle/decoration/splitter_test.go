@@ -9,7 +9,7 @@ import (
 	"gioui.org/text"
 	"github.com/oligo/gvcode/internal/buffer"
 	lt "github.com/oligo/gvcode/internal/layout"
-	"github.com/oligo/gvcode/internal/painter"
+	"github.com/oligo/gvcode/painter"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -79,7 +79,7 @@ func TestLineSplit(t *testing.T) {
 			tree := NewDecorationTree(buf)
 			tree.Insert(tc.decos...)
 			var runs []painter.RenderRun
-			tree.Split(line, &runs)
+			tree.Split(line.PainterLine(), &runs)
 			if len(runs) != tc.wantSize {
 				t.FailNow()
 			}
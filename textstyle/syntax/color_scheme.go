@@ -54,6 +54,13 @@ func (s StyleScope) IsValid() bool {
 	return true
 }
 
+// Root returns the first segment of scope s, e.g., 'string' for
+// 'string.quoted.double'.
+func (s StyleScope) Root() StyleScope {
+	root, _, _ := strings.Cut(string(s), ".")
+	return StyleScope(root)
+}
+
 // IsChild checks if other is a sub scope of s.
 func (s StyleScope) IsChild(other StyleScope) bool {
 	if !s.IsValid() || !other.IsValid() {
@@ -67,6 +74,17 @@ const (
 	defaultScope = StyleScope("_default_")
 )
 
+// MatchingBracketScope is the scope used to style the highlight of the
+// matching bracket pair at the caret. Only the background color and the
+// Border and Underline text styles are applied.
+const MatchingBracketScope = StyleScope("editor.matchingBracket")
+
+// FoldPlaceholderScope is the scope used to style the summary placeholder
+// rendered after the start line of a collapsed fold. The foreground color is
+// applied to the ellipsis and the line count of the summary, and the
+// background color, if set, to a box behind it.
+const FoldPlaceholderScope = StyleScope("editor.foldPlaceholder")
+
 // ColorScheme defines the token types and their styles used for syntax highlighting.
 type ColorScheme struct {
 	// Name is the name of the color scheme.
@@ -144,6 +162,17 @@ func (cs *ColorScheme) GetStyleByID(scopeID int) StyleMeta {
 	return packTokenStyle(scopeID, style.fg, style.bg, style.textStyle)
 }
 
+// LookupStyle returns the style registered for exactly the scope, without
+// falling back to the parent scopes or the default style.
+func (cs *ColorScheme) LookupStyle(scope StyleScope) (StyleMeta, bool) {
+	style, scopeID := cs.getTokenStyle(scope)
+	if style == nil {
+		return StyleMeta(0), false
+	}
+
+	return packTokenStyle(scopeID, style.fg, style.bg, style.textStyle), true
+}
+
 // GetTokenStyle finds a proper StyleMeta for the requested scope.
 // When the scope has no registered style, search upwards using
 // the parent scope. If everything has tried but still failed, it
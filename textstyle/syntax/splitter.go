@@ -4,8 +4,7 @@ import (
 	"iter"
 
 	"gioui.org/text"
-	"github.com/oligo/gvcode/internal/layout"
-	"github.com/oligo/gvcode/internal/painter"
+	"github.com/oligo/gvcode/painter"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -20,7 +19,7 @@ type lineSplitter struct {
 	stopFunc  func()
 }
 
-func (rb *lineSplitter) setup(line layout.Line) {
+func (rb *lineSplitter) setup(line painter.Line) {
 	lineIter := line.All()
 	rb.nextGlyph, rb.stopFunc = iter.Pull(lineIter)
 	rb.current = painter.RenderRun{}
@@ -36,7 +35,7 @@ func (rb *lineSplitter) commitLast(runs *[]painter.RenderRun) {
 	}
 }
 
-func (rb *lineSplitter) Split(line layout.Line, textTokens *TextTokens, runs *[]painter.RenderRun) {
+func (rb *lineSplitter) Split(line painter.Line, textTokens *TextTokens, runs *[]painter.RenderRun) {
 	*runs = (*runs)[:0]
 	rb.runeOff = line.RuneOff
 
@@ -10,7 +10,7 @@ import (
 	"github.com/oligo/gvcode/color"
 	"github.com/oligo/gvcode/internal/buffer"
 	lt "github.com/oligo/gvcode/internal/layout"
-	"github.com/oligo/gvcode/internal/painter"
+	"github.com/oligo/gvcode/painter"
 
 	"golang.org/x/image/math/fixed"
 )
@@ -72,7 +72,7 @@ func TestLineSplit(t *testing.T) {
 			tokens.Set(tc.tokens...)
 
 			var runs []painter.RenderRun
-			tokens.Split(line, &runs)
+			tokens.Split(line.PainterLine(), &runs)
 			if len(runs) != tc.wantSize {
 				t.FailNow()
 			}
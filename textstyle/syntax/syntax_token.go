@@ -1,11 +1,12 @@
 package syntax
 
 import (
+	"cmp"
+	"slices"
 	"sort"
 
 	"github.com/oligo/gvcode/color"
-	"github.com/oligo/gvcode/internal/layout"
-	"github.com/oligo/gvcode/internal/painter"
+	"github.com/oligo/gvcode/painter"
 )
 
 type Token struct {
@@ -16,7 +17,16 @@ type Token struct {
 }
 
 type TextTokens struct {
-	tokens      []TokenStyle
+	tokens []TokenStyle
+	// raw keeps the tokens as they are added, including those without a
+	// style, to answer scope queries.
+	raw []Token
+	// overlapped holds for each raw token the index of the last token before
+	// it ending after its start, or -1, chaining the tokens which may cover
+	// an offset.
+	overlapped []int
+	// version is increased whenever the tokens change.
+	version     int
 	colorScheme *ColorScheme
 	splitter    lineSplitter
 }
@@ -27,9 +37,23 @@ func NewTextTokens(scheme *ColorScheme) *TextTokens {
 	}
 }
 
+// ColorScheme returns the color scheme used to resolve token styles.
+func (t *TextTokens) ColorScheme() *ColorScheme {
+	return t.colorScheme
+}
+
+// Version returns a number increased whenever the tokens change, to let
+// callers cache results derived from them.
+func (t *TextTokens) Version() int {
+	return t.version
+}
+
 // Clear the tokens for reuse.
 func (t *TextTokens) Clear() {
+	t.version++
 	t.tokens = t.tokens[:0]
+	t.raw = t.raw[:0]
+	t.overlapped = t.overlapped[:0]
 }
 
 // Set adds all the tokens, replacing the existing ones.
@@ -38,7 +62,98 @@ func (t *TextTokens) Set(tokens ...Token) {
 	t.Clear()
 	for _, token := range tokens {
 		t.add(token.Scope, token.Start, token.End)
+		if token.Start < token.End {
+			t.raw = append(t.raw, token)
+		}
 	}
+	t.indexRaw()
+}
+
+// SetRange replaces the tokens in the rune range [start, end) with tokens,
+// keeping the tokens outside of the range. Tokens crossing a boundary of the
+// range are cut at it, and the new tokens are clipped to the range. It lets
+// external highlighters update the tokens of the edited lines only.
+// Caller should insures the tokens are sorted by the range in ascending order.
+func (t *TextTokens) SetRange(start, end int, tokens ...Token) {
+	if start >= end {
+		return
+	}
+	t.version++
+
+	var styles []TokenStyle
+	var raw []Token
+	for _, token := range tokens {
+		token.Start, token.End = max(token.Start, start), min(token.End, end)
+		if token.Start >= token.End {
+			continue
+		}
+		if style := t.colorScheme.GetTokenStyle(token.Scope); style != 0 {
+			styles = append(styles, TokenStyle{Start: token.Start, End: token.End, Style: style})
+		}
+		raw = append(raw, token)
+	}
+
+	t.tokens = spliceRange(t.tokens, start, end, styles, func(tk *TokenStyle) (*int, *int) {
+		return &tk.Start, &tk.End
+	})
+	t.raw = spliceRange(t.raw, start, end, raw, func(tk *Token) (*int, *int) {
+		return &tk.Start, &tk.End
+	})
+	t.indexRaw()
+}
+
+// indexRaw links each raw token to the last token before it ending after its
+// start. Following the links from the last token starting at or before an
+// offset visits all the tokens covering the offset.
+func (t *TextTokens) indexRaw() {
+	t.overlapped = slices.Grow(t.overlapped[:0], len(t.raw))[:len(t.raw)]
+	for i, tk := range t.raw {
+		// the tokens skipped by the link of j end before its start, so
+		// before the start of tk too.
+		j := i - 1
+		for j >= 0 && t.raw[j].End <= tk.Start {
+			j = t.overlapped[j]
+		}
+		t.overlapped[i] = j
+	}
+}
+
+// spliceRange replaces the ranges of list in [start, end) with inserted,
+// cutting the ranges crossing start or end. bounds returns the start and end
+// of a range. The order of list is kept.
+func spliceRange[T any](list []T, start, end int, inserted []T, bounds func(*T) (*int, *int)) []T {
+	var before, after []T
+	tail := len(list)
+	for i, tk := range list {
+		tkStart, tkEnd := bounds(&tk)
+		if *tkStart >= end {
+			// the rest of the ranges start after the replaced range.
+			tail = i
+			break
+		}
+		if *tkEnd <= start {
+			before = append(before, tk)
+			continue
+		}
+		if *tkStart < start {
+			left := tk
+			_, leftEnd := bounds(&left)
+			*leftEnd = start
+			before = append(before, left)
+		}
+		if *tkEnd > end {
+			right := tk
+			rightStart, _ := bounds(&right)
+			*rightStart = end
+			after = append(after, right)
+		}
+	}
+
+	result := make([]T, 0, len(before)+len(inserted)+len(after)+len(list)-tail)
+	result = append(result, before...)
+	result = append(result, inserted...)
+	result = append(result, after...)
+	return append(result, list[tail:]...)
 }
 
 func (t *TextTokens) add(scope StyleScope, start, end int) {
@@ -91,12 +206,55 @@ func (t *TextTokens) QueryRange(start, end int) []TokenStyle {
 	return result
 }
 
+// TokensAt returns the tokens covering the rune at runeOff, from the outermost
+// to the innermost one. Tokens nested in another one, like an escape sequence
+// in a string, are considered inner.
+func (t *TextTokens) TokensAt(runeOff int) []Token {
+	// Find the index of the first token starting after runeOff.
+	idx := sort.Search(len(t.raw), func(i int) bool {
+		return t.raw[i].Start > runeOff
+	})
+
+	var result []Token
+	for i := idx - 1; i >= 0; i = t.overlapped[i] {
+		if t.raw[i].End > runeOff {
+			result = append(result, t.raw[i])
+		}
+	}
+
+	// sort from the outermost to the innermost.
+	slices.SortStableFunc(result, func(a, b Token) int {
+		if c := cmp.Compare(a.Start, b.Start); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.End, a.End)
+	})
+	return result
+}
+
+// TokenAt returns the innermost token covering the rune at runeOff. It reports
+// false if no token covers runeOff.
+func (t *TextTokens) TokenAt(runeOff int) (Token, bool) {
+	tokens := t.TokensAt(runeOff)
+	if len(tokens) == 0 {
+		return Token{}, false
+	}
+	return tokens[len(tokens)-1], true
+}
+
+// ScopeAt returns the scope of the innermost token covering the rune at
+// runeOff, or an empty scope if no token covers it.
+func (t *TextTokens) ScopeAt(runeOff int) StyleScope {
+	token, _ := t.TokenAt(runeOff)
+	return token.Scope
+}
+
 // AdjustOffsets shifts token positions after a text edit.
 // start and end define the old replaced range (in runes), newEnd = start + inserted runes.
 // Tokens before the edit are unchanged, tokens after are shifted by delta (newEnd - end),
 // and tokens overlapping the edit are clamped. Collapsed tokens (Start >= End) are removed.
 func (t *TextTokens) AdjustOffsets(start, end, newEnd int) {
-	if len(t.tokens) == 0 {
+	if len(t.tokens) == 0 && len(t.raw) == 0 {
 		return
 	}
 
@@ -104,43 +262,62 @@ func (t *TextTokens) AdjustOffsets(start, end, newEnd int) {
 	if delta == 0 && start == end {
 		return // no-op edit
 	}
+	t.version++
 
 	n := 0
 	for i := range t.tokens {
 		tk := &t.tokens[i]
+		// Keep only tokens that still span at least one rune.
+		if adjustRange(&tk.Start, &tk.End, start, end, newEnd) {
+			t.tokens[n] = *tk
+			n++
+		}
+	}
+	t.tokens = t.tokens[:n]
 
-		// Adjust Start: tokens starting at or after the old end shift;
-		// tokens starting inside the replaced range clamp to newEnd.
-		switch {
-		case tk.Start >= end:
-			tk.Start += delta
-		case tk.Start > start:
-			if tk.Start > newEnd {
-				tk.Start = newEnd
-			}
+	n = 0
+	for i := range t.raw {
+		tk := &t.raw[i]
+		if adjustRange(&tk.Start, &tk.End, start, end, newEnd) {
+			t.raw[n] = *tk
+			n++
 		}
+	}
+	t.raw = t.raw[:n]
+	t.indexRaw()
+}
 
-		// Adjust End: tokens ending past the old end shift;
-		// tokens ending inside the replaced range clamp to newEnd.
-		switch {
-		case tk.End > end:
-			tk.End += delta
-		case tk.End > start:
-			if tk.End > newEnd {
-				tk.End = newEnd
-			}
+// adjustRange shifts the token range [tkStart, tkEnd) after the text between
+// start and end is replaced, so that it ends at newEnd. It reports whether the
+// range still spans at least one rune.
+func adjustRange(tkStart, tkEnd *int, start, end, newEnd int) bool {
+	delta := newEnd - end
+	// Adjust Start: tokens starting at or after the old end shift;
+	// tokens starting inside the replaced range clamp to newEnd.
+	switch {
+	case *tkStart >= end:
+		*tkStart += delta
+	case *tkStart > start:
+		if *tkStart > newEnd {
+			*tkStart = newEnd
 		}
+	}
 
-		// Keep only tokens that still span at least one rune.
-		if tk.Start < tk.End {
-			t.tokens[n] = *tk
-			n++
+	// Adjust End: tokens ending past the old end shift;
+	// tokens ending inside the replaced range clamp to newEnd.
+	switch {
+	case *tkEnd > end:
+		*tkEnd += delta
+	case *tkEnd > start:
+		if *tkEnd > newEnd {
+			*tkEnd = newEnd
 		}
 	}
-	t.tokens = t.tokens[:n]
+
+	return *tkStart < *tkEnd
 }
 
 // Split implements painter.LineSplitter
-func (t *TextTokens) Split(line layout.Line, runs *[]painter.RenderRun) {
+func (t *TextTokens) Split(line painter.Line, runs *[]painter.RenderRun) {
 	t.splitter.Split(line, t, runs)
 }
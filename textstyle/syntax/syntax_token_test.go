@@ -0,0 +1,139 @@
+package syntax
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/oligo/gvcode/color"
+)
+
+func TestScopeAt(t *testing.T) {
+	scheme := &ColorScheme{}
+	scheme.AddStyle("string", 0, color.Color{}, color.Color{})
+	scheme.AddStyle("comment.line", Italic, color.Color{}, color.Color{})
+
+	tokens := NewTextTokens(scheme)
+	tokens.Set(
+		Token{Start: 2, End: 6, Scope: "string.quoted.double"},
+		Token{Start: 3, End: 5, Scope: "constant.character.escape"},
+		Token{Start: 8, End: 12, Scope: "comment.line"},
+		Token{Start: 12, End: 14, Scope: "keyword"},
+	)
+
+	cases := []struct {
+		offset   int
+		expected StyleScope
+	}{
+		{offset: 0, expected: ""},
+		{offset: 2, expected: "string.quoted.double"},
+		// the innermost token wins.
+		{offset: 3, expected: "constant.character.escape"},
+		{offset: 5, expected: "string.quoted.double"},
+		{offset: 6, expected: ""},
+		{offset: 11, expected: "comment.line"},
+		// scopes without a style are kept.
+		{offset: 12, expected: "keyword"},
+	}
+
+	for idx, c := range cases {
+		t.Run(fmt.Sprintf("case-%d: %d", idx, c.offset), func(t *testing.T) {
+			if scope := tokens.ScopeAt(c.offset); scope != c.expected {
+				t.Errorf("ScopeAt(%d) = %q, want %q", c.offset, scope, c.expected)
+			}
+		})
+	}
+}
+
+func TestTokensAtAfterEdit(t *testing.T) {
+	scheme := &ColorScheme{}
+	scheme.AddStyle("string", 0, color.Color{}, color.Color{})
+	tokens := NewTextTokens(scheme)
+	tokens.Set(
+		Token{Start: 2, End: 6, Scope: "string"},
+		Token{Start: 3, End: 5, Scope: "constant.character.escape"},
+	)
+
+	// insert 2 runes at offset 1.
+	tokens.AdjustOffsets(1, 1, 3)
+	got := tokens.TokensAt(5)
+	if len(got) != 2 || got[0].Scope != "string" || got[1].Scope != "constant.character.escape" {
+		t.Fatalf("TokensAt(5) = %v", got)
+	}
+	if got[0].Start != 4 || got[0].End != 8 {
+		t.Errorf("outer token = [%d, %d), want [4, 8)", got[0].Start, got[0].End)
+	}
+}
+
+func TestSetRange(t *testing.T) {
+	scheme := &ColorScheme{}
+	scheme.AddStyle("keyword", Bold, color.Color{}, color.Color{})
+	scheme.AddStyle("string", 0, color.Color{}, color.Color{})
+	tokens := NewTextTokens(scheme)
+	tokens.Set(
+		Token{Start: 0, End: 4, Scope: "keyword"},
+		Token{Start: 5, End: 20, Scope: "string"},
+		Token{Start: 6, End: 8, Scope: "constant.character.escape"},
+		Token{Start: 22, End: 26, Scope: "keyword"},
+	)
+
+	// the string is cut around the range, and the escape in it replaced.
+	tokens.SetRange(6, 10, Token{Start: 4, End: 7, Scope: "keyword"})
+
+	want := []Token{
+		{Start: 0, End: 4, Scope: "keyword"},
+		{Start: 5, End: 6, Scope: "string"},
+		{Start: 6, End: 7, Scope: "keyword"},
+		{Start: 10, End: 20, Scope: "string"},
+		{Start: 22, End: 26, Scope: "keyword"},
+	}
+	if !slices.Equal(tokens.raw, want) {
+		t.Fatalf("got tokens %v, want %v", tokens.raw, want)
+	}
+	// the styled tokens follow, the escape having no style.
+	var ranges [][2]int
+	for _, tk := range tokens.tokens {
+		ranges = append(ranges, [2]int{tk.Start, tk.End})
+	}
+	if want := [][2]int{{0, 4}, {5, 6}, {6, 7}, {10, 20}, {22, 26}}; !slices.Equal(ranges, want) {
+		t.Fatalf("got styled ranges %v, want %v", ranges, want)
+	}
+	if got := tokens.ScopeAt(8); got != "" {
+		t.Fatalf("ScopeAt(8) = %q, want none", got)
+	}
+	if got := tokens.ScopeAt(15); got != "string" {
+		t.Fatalf("ScopeAt(15) = %q, want string", got)
+	}
+}
+
+func TestTokensAtIndex(t *testing.T) {
+	scheme := &ColorScheme{}
+	scheme.AddStyle("keyword", Bold, color.Color{}, color.Color{})
+	tokens := NewTextTokens(scheme)
+	// a long block covering nested and overlapping tokens, and many short
+	// tokens after it.
+	set := []Token{
+		{Start: 0, End: 100, Scope: "comment.block"},
+		{Start: 2, End: 10, Scope: "string"},
+		{Start: 4, End: 6, Scope: "constant.character.escape"},
+		{Start: 8, End: 20, Scope: "markup.bold"},
+		{Start: 30, End: 30, Scope: "empty"},
+		{Start: 95, End: 105, Scope: "markup.italic"},
+	}
+	for i := 100; i < 200; i += 2 {
+		set = append(set, Token{Start: i, End: i + 1, Scope: "keyword"})
+	}
+	tokens.Set(set...)
+
+	for off := -1; off <= 201; off++ {
+		var want []Token
+		for _, tk := range set {
+			if tk.Start <= off && off < tk.End {
+				want = append(want, tk)
+			}
+		}
+		if got := tokens.TokensAt(off); !slices.Equal(got, want) {
+			t.Errorf("TokensAt(%d) = %v, want %v", off, got, want)
+		}
+	}
+}
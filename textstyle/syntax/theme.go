@@ -0,0 +1,284 @@
+package syntax
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	gocolor "image/color"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/oligo/gvcode/color"
+)
+
+const (
+	// maxScopes is the number of scopes a StyleMeta can refer to, the
+	// default scope included.
+	maxScopes = 1 << 7
+	// maxColors is the number of colors a StyleMeta can refer to.
+	maxColors = 1 << 8
+)
+
+// themeFile is the JSON format of the VS Code color themes, and of the
+// TextMate themes converted to JSON, which keep the token rules in settings.
+type themeFile struct {
+	Name        string            `json:"name"`
+	Colors      map[string]string `json:"colors"`
+	TokenColors json.RawMessage   `json:"tokenColors"`
+	Settings    []themeRule       `json:"settings"`
+}
+
+type themeRule struct {
+	Scope    themeScopes   `json:"scope"`
+	Settings themeSettings `json:"settings"`
+}
+
+// themeSettings are the settings of a token rule. The rule without a scope of
+// TextMate themes has the global settings, like the background color.
+type themeSettings struct {
+	Foreground    string  `json:"foreground"`
+	Background    string  `json:"background"`
+	FontStyle     *string `json:"fontStyle"`
+	Selection     string  `json:"selection"`
+	LineHighlight string  `json:"lineHighlight"`
+	Gutter        string  `json:"gutter"`
+	GutterFg      string  `json:"gutterForeground"`
+}
+
+// themeScopes is the scope of a rule: a selector, a comma separated list of
+// selectors, or an array of them.
+type themeScopes []string
+
+func (s *themeScopes) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		var selector string
+		if err := json.Unmarshal(data, &selector); err != nil {
+			return err
+		}
+		list = []string{selector}
+	}
+	*s = (*s)[:0]
+	for _, selectors := range list {
+		for selector := range strings.SplitSeq(selectors, ",") {
+			if selector = strings.TrimSpace(selector); selector != "" {
+				*s = append(*s, selector)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadTheme reads a VS Code color theme, or a TextMate theme converted to
+// JSON, into a color scheme. The editor colors of the theme, like
+// "editor.background", "editor.selectionBackground",
+// "editor.lineHighlightBackground" and "editorLineNumber.foreground", fill
+// the palette, and the token rules become the styles of their scopes.
+// Comments and trailing commas are allowed, as in the theme files of VS Code
+// extensions, but the themes included by the "include" key are not loaded.
+//
+// A selector matching nested scopes, like "source.go keyword", styles its
+// last scope, and the selectors with exclusions are skipped. As a color scheme
+// holds up to 127 scopes and 256 colors, the most specific scopes of the
+// largest themes are dropped.
+func LoadTheme(r io.Reader) (ColorScheme, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return ColorScheme{}, err
+	}
+
+	var file themeFile
+	if err := json.Unmarshal(stripJSONC(data), &file); err != nil {
+		return ColorScheme{}, fmt.Errorf("parse theme: %w", err)
+	}
+	rules := file.Settings
+	if len(file.TokenColors) > 0 {
+		// tokenColors can also be the path of a TextMate theme.
+		var tokenColors []themeRule
+		if err := json.Unmarshal(file.TokenColors, &tokenColors); err == nil {
+			rules = append(rules, tokenColors...)
+		}
+	}
+	if len(file.Colors) == 0 && len(rules) == 0 {
+		return ColorScheme{}, errors.New("parse theme: no colors nor token rules")
+	}
+
+	scheme := ColorScheme{Name: file.Name}
+	var global themeSettings
+	for _, rule := range rules {
+		if len(rule.Scope) == 0 {
+			global = rule.Settings
+		}
+	}
+	editorColor := func(key, fallback string) color.Color {
+		c, _ := parseThemeColor(cmp.Or(file.Colors[key], fallback))
+		return c
+	}
+	scheme.Foreground = editorColor("editor.foreground", cmp.Or(global.Foreground, file.Colors["foreground"]))
+	scheme.Background = editorColor("editor.background", global.Background)
+	scheme.SelectColor = editorColor("editor.selectionBackground", global.Selection)
+	scheme.LineColor = editorColor("editor.lineHighlightBackground", global.LineHighlight)
+	scheme.LineNumberColor = editorColor("editorLineNumber.foreground", global.GutterFg)
+	scheme.LineNumberActiveColor = editorColor("editorLineNumber.activeForeground", "")
+	scheme.GutterBackground = editorColor("editorGutter.background", global.Gutter)
+
+	styles := mergeThemeRules(rules)
+	if bg := editorColor("editorBracketMatch.background", ""); bg.IsSet() {
+		border := editorColor("editorBracketMatch.border", "")
+		var textStyle TextStyle
+		if border.IsSet() {
+			textStyle = Border
+		}
+		styles = append(styles, themeStyle{scope: MatchingBracketScope, fg: border, bg: bg, textStyle: textStyle})
+	}
+
+	// the general scopes are kept first when the theme has too many.
+	slices.SortStableFunc(styles, func(a, b themeStyle) int {
+		return cmp.Compare(strings.Count(string(a.scope), "."), strings.Count(string(b.scope), "."))
+	})
+	colors := map[gocolor.NRGBA]bool{scheme.Foreground.NRGBA(): true, {}: true}
+	for _, style := range styles {
+		colors[style.fg.NRGBA()], colors[style.bg.NRGBA()] = true, true
+		if len(scheme.scopes) >= maxScopes || len(colors) > maxColors {
+			break
+		}
+		scheme.AddStyle(style.scope, style.textStyle, style.fg, style.bg)
+	}
+	return scheme, nil
+}
+
+// themeStyle is the style of a scope, merged from the rules of a theme.
+type themeStyle struct {
+	scope     StyleScope
+	textStyle TextStyle
+	fg, bg    color.Color
+}
+
+// mergeThemeRules merges the settings of the rules styling the same scope,
+// the later rules overriding the settings they set, in the order the scopes
+// first appear.
+func mergeThemeRules(rules []themeRule) []themeStyle {
+	var styles []themeStyle
+	for _, rule := range rules {
+		for _, selector := range rule.Scope {
+			scope, ok := selectorScope(selector)
+			if !ok {
+				continue
+			}
+			idx := slices.IndexFunc(styles, func(s themeStyle) bool { return s.scope == scope })
+			if idx < 0 {
+				styles = append(styles, themeStyle{scope: scope})
+				idx = len(styles) - 1
+			}
+			style := &styles[idx]
+			if c, err := parseThemeColor(rule.Settings.Foreground); err == nil {
+				style.fg = c
+			}
+			if c, err := parseThemeColor(rule.Settings.Background); err == nil {
+				style.bg = c
+			}
+			if rule.Settings.FontStyle != nil {
+				style.textStyle = parseFontStyle(*rule.Settings.FontStyle)
+			}
+		}
+	}
+	return styles
+}
+
+// selectorScope returns the scope styled by a selector: the last scope of a
+// descendant selector. Selectors with exclusions are skipped.
+func selectorScope(selector string) (StyleScope, bool) {
+	if strings.Contains(selector, " -") || strings.HasPrefix(selector, "-") {
+		return "", false
+	}
+	fields := strings.Fields(selector)
+	if len(fields) == 0 {
+		return "", false
+	}
+	scope := StyleScope(fields[len(fields)-1])
+	return scope, scope.IsValid()
+}
+
+// parseFontStyle parses the space separated font styles of a rule. An empty
+// font style resets the styles of the parent scopes.
+func parseFontStyle(fontStyle string) TextStyle {
+	var style TextStyle
+	for _, s := range strings.Fields(fontStyle) {
+		switch s {
+		case "bold":
+			style |= Bold
+		case "italic":
+			style |= Italic
+		case "underline":
+			style |= Underline
+		case "strikethrough":
+			style |= Strikethrough
+		}
+	}
+	return style
+}
+
+// parseThemeColor parses the colors of the themes: "#RGB", "#RGBA",
+// "#RRGGBB" or "#RRGGBBAA".
+func parseThemeColor(s string) (color.Color, error) {
+	hex, ok := strings.CutPrefix(strings.TrimSpace(s), "#")
+	if !ok {
+		return color.Color{}, fmt.Errorf("invalid color %q", s)
+	}
+	if len(hex) == 3 || len(hex) == 4 {
+		var expanded strings.Builder
+		for _, c := range hex {
+			expanded.WriteRune(c)
+			expanded.WriteRune(c)
+		}
+		hex = expanded.String()
+	}
+	return color.Hex2Color(hex)
+}
+
+// stripJSONC removes the comments and the trailing commas of JSON with
+// comments, keeping the strings intact.
+func stripJSONC(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '"':
+			// copy the string, with its escapes.
+			start := i
+			for i++; i < len(data) && data[i] != '"'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			out = append(out, data[start:min(i+1, len(data))]...)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			i--
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return out
+			}
+			i += end + 3
+		case c == ']' || c == '}':
+			// drop a trailing comma before the closing bracket.
+			j := len(out)
+			for j > 0 && strings.IndexByte(" \t\r\n", out[j-1]) >= 0 {
+				j--
+			}
+			if j > 0 && out[j-1] == ',' {
+				out = append(out[:j-1], out[j:]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,160 @@
+package syntax
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oligo/gvcode/color"
+)
+
+const vscodeTheme = `{
+	// a VS Code theme, with comments.
+	"name": "Test Dark",
+	"colors": {
+		"editor.foreground": "#d4d4d4",
+		"editor.background": "#1e1e1e",
+		"editor.selectionBackground": "#264f78",
+		"editorLineNumber.foreground": "#858585",
+		"editorLineNumber.activeForeground": "#c6c6c6", /* block comment */
+		"editorBracketMatch.background": "#0064001a",
+	},
+	"tokenColors": [
+		{
+			"scope": "comment",
+			"settings": { "foreground": "#6A9955", "fontStyle": "italic" }
+		},
+		{
+			"scope": ["keyword", "storage.type"],
+			"settings": { "foreground": "#569cd6" }
+		},
+		{
+			"scope": "string, string.quoted.double",
+			"settings": { "foreground": "#ce9178" }
+		},
+		{
+			"scope": "keyword",
+			"settings": { "fontStyle": "bold underline" }
+		},
+		{
+			"scope": "source.go entity.name.function",
+			"settings": { "foreground": "#DCDCAA" }
+		},
+		{
+			"scope": "variable -variable.parameter",
+			"settings": { "foreground": "#9cdcfe" }
+		},
+	]
+}`
+
+const textMateTheme = `{
+	"name": "Test Light",
+	"settings": [
+		{
+			"settings": {
+				"foreground": "#333",
+				"background": "#fff",
+				"lineHighlight": "#f0f0f0"
+			}
+		},
+		{
+			"scope": "constant.numeric",
+			"settings": { "foreground": "#09885A" }
+		}
+	]
+}`
+
+func mustColor(t *testing.T, hex string) color.Color {
+	t.Helper()
+	c, err := color.Hex2Color(hex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestLoadVSCodeTheme(t *testing.T) {
+	scheme, err := LoadTheme(strings.NewReader(vscodeTheme))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if scheme.Name != "Test Dark" {
+		t.Errorf("name: got %q", scheme.Name)
+	}
+	palette := []struct {
+		name string
+		got  color.Color
+		want string
+	}{
+		{"foreground", scheme.Foreground, "d4d4d4"},
+		{"background", scheme.Background, "1e1e1e"},
+		{"selection", scheme.SelectColor, "264f78"},
+		{"line number", scheme.LineNumberColor, "858585"},
+		{"active line number", scheme.LineNumberActiveColor, "c6c6c6"},
+	}
+	for _, c := range palette {
+		if c.got != mustColor(t, c.want) {
+			t.Errorf("%s: got %v, want #%s", c.name, c.got.NRGBA(), c.want)
+		}
+	}
+
+	styles := []struct {
+		scope     StyleScope
+		fg        string
+		textStyle TextStyle
+	}{
+		{"comment", "6A9955", Italic},
+		{"keyword", "569cd6", Bold | Underline},
+		{"storage.type", "569cd6", 0},
+		{"string", "ce9178", 0},
+		{"string.quoted.double", "ce9178", 0},
+		{"entity.name.function", "DCDCAA", 0},
+	}
+	for _, s := range styles {
+		style, ok := scheme.LookupStyle(s.scope)
+		if !ok {
+			t.Errorf("%s: no style", s.scope)
+			continue
+		}
+		if fg := scheme.GetColor(style.Foreground()); fg != mustColor(t, s.fg) {
+			t.Errorf("%s: got foreground %v, want #%s", s.scope, fg.NRGBA(), s.fg)
+		}
+		if style.TextStyle() != s.textStyle {
+			t.Errorf("%s: got text style %v, want %v", s.scope, style.TextStyle(), s.textStyle)
+		}
+	}
+
+	// selectors with exclusions are skipped.
+	if _, ok := scheme.LookupStyle("variable"); ok {
+		t.Error("expected the selector with an exclusion to be skipped")
+	}
+	if _, ok := scheme.LookupStyle(MatchingBracketScope); !ok {
+		t.Error("expected a style for the matching brackets")
+	}
+}
+
+func TestLoadTextMateTheme(t *testing.T) {
+	scheme, err := LoadTheme(strings.NewReader(textMateTheme))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if scheme.Foreground != mustColor(t, "333333") || scheme.Background != mustColor(t, "ffffff") {
+		t.Errorf("got foreground %v and background %v", scheme.Foreground.NRGBA(), scheme.Background.NRGBA())
+	}
+	if scheme.LineColor != mustColor(t, "f0f0f0") {
+		t.Errorf("got line color %v", scheme.LineColor.NRGBA())
+	}
+	style, ok := scheme.LookupStyle("constant.numeric")
+	if !ok || scheme.GetColor(style.Foreground()) != mustColor(t, "09885A") {
+		t.Error("expected a style for constant.numeric")
+	}
+}
+
+func TestLoadInvalidTheme(t *testing.T) {
+	for _, theme := range []string{`{"name": `, `{"name": "empty"}`} {
+		if _, err := LoadTheme(strings.NewReader(theme)); err == nil {
+			t.Errorf("expected an error for %q", theme)
+		}
+	}
+}
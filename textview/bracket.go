@@ -1,7 +1,14 @@
 package textview
 
 import (
+	"cmp"
 	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/oligo/gvcode/internal/buffer"
+	"github.com/oligo/gvcode/textstyle/syntax"
 )
 
 // Built-in quote pairs used for auto-insertion. This can be
@@ -67,34 +74,115 @@ func (p *runePairs) contains(r rune) (bool, bool) {
 	return false, false
 }
 
-// bracketQuotes holds configured bracket pairs and quote pairs.
-type bracketsQuotes struct {
+// Pair is a pair of opening and closing parts longer than a single rune, like
+// "/*" and "*/", or triple quotes.
+type Pair struct {
+	Opening string
+	Closing string
+}
+
+// BracketsQuotes holds configured bracket pairs, quote pairs and multi-rune pairs.
+type BracketsQuotes struct {
 	// A set of quote pairs that can be auto-completed when the opening half is entered.
 	quotePairs *runePairs
 	// A set of bracket pairs that can be auto-completed when the opening half is entered.
 	bracketPairs *runePairs
+	// Multi-rune pairs that can be auto-completed when the last rune of the
+	// opening half is entered, sorted by the length of the opening half in
+	// descending order.
+	pairs []Pair
+	// version is increased whenever the pairs are configured.
+	version int
 }
 
 // SetBrackets set bracket pairs using a opening bracket to closing bracket map.
-func (bq *bracketsQuotes) SetBrackets(bracketPairs map[rune]rune) {
+// A nil map restores the built-in bracket pairs.
+func (bq *BracketsQuotes) SetBrackets(bracketPairs map[rune]rune) {
+	bq.version++
 	if bq.bracketPairs == nil {
 		bq.bracketPairs = &runePairs{}
 	}
+	if bracketPairs == nil {
+		bracketPairs = builtinBracketPairs
+	}
 	bq.bracketPairs.set(bracketPairs)
 }
 
-// SetQuotes set quote pairs using a opening quote to closing quote map.
-func (bq *bracketsQuotes) SetQuotes(quotePairs map[rune]rune) {
+// SetQuotes set quote pairs using a opening quote to closing quote map. A nil
+// map restores the built-in quote pairs.
+func (bq *BracketsQuotes) SetQuotes(quotePairs map[rune]rune) {
+	bq.version++
 	if bq.quotePairs == nil {
 		bq.quotePairs = &runePairs{}
 	}
+	if quotePairs == nil {
+		quotePairs = builtinQuotePairs
+	}
 
 	bq.quotePairs.set(quotePairs)
 }
 
+// SetPairs set multi-rune pairs using a opening to closing map. Pairs of
+// single runes should be configured as brackets or quotes instead.
+func (bq *BracketsQuotes) SetPairs(pairs map[string]string) {
+	bq.version++
+	bq.pairs = bq.pairs[:0]
+	for opening, closing := range pairs {
+		if utf8.RuneCountInString(opening) < 2 || closing == "" {
+			continue
+		}
+		bq.pairs = append(bq.pairs, Pair{Opening: opening, Closing: closing})
+	}
+
+	slices.SortFunc(bq.pairs, func(a, b Pair) int {
+		if c := cmp.Compare(utf8.RuneCountInString(b.Opening), utf8.RuneCountInString(a.Opening)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Opening, b.Opening)
+	})
+}
+
+// Pairs returns the configured multi-rune pairs.
+func (bq *BracketsQuotes) Pairs() []Pair {
+	return bq.pairs
+}
+
+// MatchOpening finds the longest multi-rune pair whose opening half is a
+// suffix of text.
+func (bq *BracketsQuotes) MatchOpening(text string) (Pair, bool) {
+	for _, p := range bq.pairs {
+		if strings.HasSuffix(text, p.Opening) {
+			return p, true
+		}
+	}
+	return Pair{}, false
+}
+
+// MaxOpeningLen returns the rune length of the longest opening half of the
+// multi-rune pairs.
+func (bq *BracketsQuotes) MaxOpeningLen() int {
+	if len(bq.pairs) == 0 {
+		return 0
+	}
+	return utf8.RuneCountInString(bq.pairs[0].Opening)
+}
+
+// Clone returns a copy of the configured pairs, so that they can be
+// configured separately.
+func (bq *BracketsQuotes) Clone() *BracketsQuotes {
+	clone := &BracketsQuotes{pairs: slices.Clone(bq.pairs)}
+	if bq.bracketPairs != nil {
+		clone.SetBrackets(bq.bracketPairs.opening)
+	}
+	if bq.quotePairs != nil {
+		clone.SetQuotes(bq.quotePairs.opening)
+	}
+	return clone
+}
+
 // Contains check if r is contained in the configured quotes, and if r is a opening
 // quote.
-func (bq bracketsQuotes) ContainsQuote(r rune) (_ bool, isOpening bool) {
+func (bq BracketsQuotes) ContainsQuote(r rune) (_ bool, isOpening bool) {
 	if bq.quotePairs == nil {
 		bq.SetQuotes(builtinQuotePairs)
 	}
@@ -104,7 +192,7 @@ func (bq bracketsQuotes) ContainsQuote(r rune) (_ bool, isOpening bool) {
 
 // Contains check if r is contained in the configured brackets, and if r is a opening
 // bracket.
-func (bq bracketsQuotes) ContainsBracket(r rune) (_ bool, isOpening bool) {
+func (bq BracketsQuotes) ContainsBracket(r rune) (_ bool, isOpening bool) {
 	if bq.bracketPairs == nil {
 		bq.SetBrackets(builtinBracketPairs)
 	}
@@ -114,7 +202,7 @@ func (bq bracketsQuotes) ContainsBracket(r rune) (_ bool, isOpening bool) {
 
 // GetCounterpart check if r is contained in the configured brackets or quotes, it returns the
 // counterpart of r and whether r is a opening half.
-func (bq *bracketsQuotes) GetCounterpart(r rune) (_ rune, isOpening bool) {
+func (bq *BracketsQuotes) GetCounterpart(r rune) (_ rune, isOpening bool) {
 	if bq.quotePairs == nil {
 		bq.SetQuotes(builtinQuotePairs)
 	}
@@ -139,7 +227,7 @@ func (bq *bracketsQuotes) GetCounterpart(r rune) (_ rune, isOpening bool) {
 	return rune(0), false
 }
 
-func (bq *bracketsQuotes) GetOpeningBracket(r rune) (rune, bool) {
+func (bq *BracketsQuotes) GetOpeningBracket(r rune) (rune, bool) {
 	if bq.bracketPairs == nil {
 		bq.SetBrackets(builtinBracketPairs)
 	}
@@ -147,7 +235,7 @@ func (bq *bracketsQuotes) GetOpeningBracket(r rune) (rune, bool) {
 	return bq.bracketPairs.getOpening(r)
 }
 
-func (bq *bracketsQuotes) GetClosingBracket(r rune) (rune, bool) {
+func (bq *BracketsQuotes) GetClosingBracket(r rune) (rune, bool) {
 	if bq.bracketPairs == nil {
 		bq.SetBrackets(builtinBracketPairs)
 	}
@@ -155,125 +243,257 @@ func (bq *bracketsQuotes) GetClosingBracket(r rune) (rune, bool) {
 	return bq.bracketPairs.getClosing(r)
 }
 
-func (bq *bracketsQuotes) GetOpeningQuote(r rune) (rune, bool) {
+func (bq *BracketsQuotes) GetOpeningQuote(r rune) (rune, bool) {
 	if bq.quotePairs == nil {
 		bq.SetQuotes(builtinQuotePairs)
 	}
 	return bq.quotePairs.getOpening(r)
 }
 
-func (bq *bracketsQuotes) GetClosingQuote(r rune) (rune, bool) {
+func (bq *BracketsQuotes) GetClosingQuote(r rune) (rune, bool) {
 	if bq.quotePairs == nil {
 		bq.SetQuotes(builtinQuotePairs)
 	}
 	return bq.quotePairs.getClosing(r)
 }
 
+// bracketScanLimit limits the number of runes scanned when looking for a
+// matching bracket, to keep the cost bounded in large documents.
+const bracketScanLimit = 1 << 16
+
 // NearestMatchingBrackets finds the nearest matching brackets of the caret.
+// Brackets next to the caret are matched first, otherwise the brackets
+// enclosing the caret are returned. An unbalanced bracket is reported with
+// its counterpart set to -1.
 func (e *TextView) NearestMatchingBrackets() (left int, right int) {
-	left, right = -1, -1
 	start, end := e.Selection()
 	if start != end {
-		return
+		return -1, -1
 	}
 
-	stack := &bracketStack{}
-	stack.reset()
+	// the result is painted every frame, and the scans are only repeated
+	// after the caret, the text, the tokens or the brackets change.
+	key := bracketMatchKey{
+		caret:           start,
+		src:             e.src,
+		srcVersion:      e.src.Version(),
+		tokens:          e.syntaxStyles,
+		brackets:        e.BracketsQuotes,
+		bracketsVersion: e.BracketsQuotes.version,
+	}
+	if e.syntaxStyles != nil {
+		key.tokensVersion = e.syntaxStyles.Version()
+	}
+	if m := &e.bracketMatch; m.valid && m.key == key {
+		return m.left, m.right
+	}
+	left, right = e.nearestMatchingBrackets(start)
+	e.bracketMatch = bracketMatch{key: key, valid: true, left: left, right: right}
+	return left, right
+}
+
+// nearestMatchingBrackets implements NearestMatchingBrackets for the caret
+// at start.
+func (e *TextView) nearestMatchingBrackets(start int) (left int, right int) {
 
 	start = min(start, e.Len())
-	nearest, err := e.src.ReadRuneAt(start)
-	isBracket, _ := e.BracketsQuotes.ContainsBracket(nearest)
-	if err != nil || !isBracket {
-		start = max(0, start-1)
-		nearest, _ = e.src.ReadRuneAt(start)
-	}
-
-	if isBracket, isLeft := e.BracketsQuotes.ContainsBracket(nearest); isBracket {
-		if isLeft {
-			left = start
-		} else {
-			right = start
+	for _, off := range []int{start, start - 1} {
+		if !e.isBracketAt(off) {
+			continue
 		}
-		stack.push(nearest, start)
-	}
 
-	offset := start
+		r, _ := e.src.ReadRuneAt(off)
+		if _, isOpening := e.BracketsQuotes.ContainsBracket(r); isOpening {
+			return off, e.MatchingBracket(off)
+		}
+		return e.MatchingBracket(off), off
+	}
 
-	// find the left half.
+	left = e.enclosingBracket(start)
 	if left < 0 {
-		for {
-			offset = max(offset-1, 0)
-			next, err := e.src.ReadRuneAt(offset)
-			if err != nil {
-				break
-			}
+		return -1, -1
+	}
+	return left, e.MatchingBracket(left)
+}
 
-			// Check if next is a opening bracket.
-			if br, ok := e.BracketsQuotes.GetClosingBracket(next); ok {
-				if r, _ := stack.peek(); r == br {
-					stack.pop()
-					if right >= 0 && stack.depth() == 0 {
-						left = offset
-						break
-					}
-				} else {
-					if r == 0 {
-						stack.push(next, offset)
-						left = offset
-					} else {
-						// Found unbalanced bracket, drop it.
-					}
-					break
-				}
-			}
+// MatchingBracket returns the rune offset of the counterpart of the bracket
+// at runeOff, searching across lines. Brackets inside of strings or comments
+// are ignored, unless the bracket at runeOff is inside of one too. It returns
+// -1 if there is no bracket at runeOff, or it is unbalanced.
+func (e *TextView) MatchingBracket(runeOff int) int {
+	r, err := e.src.ReadRuneAt(runeOff)
+	if err != nil {
+		return -1
+	}
+	isBracket, isOpening := e.BracketsQuotes.ContainsBracket(r)
+	if !isBracket {
+		return -1
+	}
 
-			// found a right half bracket.
-			if exists, isOpening := e.BracketsQuotes.ContainsBracket(next); exists && !isOpening {
-				stack.push(next, offset)
-			}
+	var counterpart rune
+	step := 1
+	if isOpening {
+		counterpart, _ = e.BracketsQuotes.GetClosingBracket(r)
+	} else {
+		counterpart, _ = e.BracketsQuotes.GetOpeningBracket(r)
+		step = -1
+	}
+
+	skipped := e.inStringOrComment(runeOff)
+	stack := &bracketStack{}
+	for off, n := runeOff+step, 0; off >= 0 && off < e.Len() && n < bracketScanLimit; off, n = off+step, n+1 {
+		next, err := e.src.ReadRuneAt(off)
+		if err != nil {
+			break
+		}
+		exists, nextOpening := e.BracketsQuotes.ContainsBracket(next)
+		if !exists || e.inStringOrComment(off) != skipped {
+			continue
+		}
 
-			if offset <= 0 {
-				break
+		if nextOpening == isOpening {
+			// nested bracket of the same direction.
+			stack.push(next, off)
+			continue
+		}
+
+		if stack.depth() == 0 {
+			if next == counterpart {
+				return off
 			}
+			// Found a un-balanced bracket.
+			return -1
+		}
+
+		top, _ := stack.peek()
+		if c, _ := e.BracketsQuotes.GetCounterpart(top); c != next {
+			return -1
 		}
+		stack.pop()
 	}
 
-	// find the right half.
-	if right < 0 {
-		for {
-			offset = min(offset+1, e.Len())
-			next, err := e.src.ReadRuneAt(offset)
-			if err != nil {
-				break
-			}
+	return -1
+}
 
-			// found left half bracket
-			if _, isOpening := e.BracketsQuotes.ContainsBracket(next); isOpening {
-				stack.push(next, offset)
-			}
+// EnclosingBrackets returns the rune offsets of the innermost pair of
+// brackets enclosing the range [start, end), outside of strings and comments.
+// It returns -1, -1 if there is none, or the brackets are unbalanced.
+func (e *TextView) EnclosingBrackets(start, end int) (left, right int) {
+	off := start
+	for {
+		left = e.enclosingBracket(off)
+		if left < 0 {
+			return -1, -1
+		}
+		right = e.MatchingBracket(left)
+		if right < 0 {
+			return -1, -1
+		}
+		if right >= end {
+			return left, right
+		}
+		// the pair closes inside of the range, try the outer one.
+		off = left
+	}
+}
 
-			// found a right half bracket.
-			if bl, ok := e.BracketsQuotes.GetOpeningBracket(next); ok {
-				if r, _ := stack.peek(); r == bl {
-					stack.pop()
-					if stack.depth() == 0 {
-						right = offset
-						break
-					}
-				} else {
-					// Found a un-balanced bracket, drop it.
-					// e.idx.push(next, offset)
-				}
-			}
+// MoveToMatchingBracket moves the caret to the counterpart of the bracket
+// next to it. From before a bracket, the caret is moved before its
+// counterpart; from after a bracket, after its counterpart. Otherwise it is
+// moved before the closing bracket enclosing the caret. It reports whether
+// the caret is moved.
+func (e *TextView) MoveToMatchingBracket(selAct SelectionAction) bool {
+	caret := min(e.caret.start, e.Len())
+	target := -1
+	switch {
+	case e.isBracketAt(caret):
+		target = e.MatchingBracket(caret)
+	case e.isBracketAt(caret - 1):
+		if target = e.MatchingBracket(caret - 1); target >= 0 {
+			target++
+		}
+	default:
+		if left := e.enclosingBracket(caret); left >= 0 {
+			target = e.MatchingBracket(left)
+		}
+	}
+	if target < 0 {
+		return false
+	}
 
-			if offset >= e.Len() {
-				break
-			}
+	e.caret.start = target
+	e.caret.xoff = 0
+	e.updateSelection(selAct)
+	return true
+}
+
+// enclosingBracket returns the offset of the nearest unmatched opening
+// bracket before runeOff, or -1 if there is none.
+func (e *TextView) enclosingBracket(runeOff int) int {
+	stack := &bracketStack{}
+	for off, n := runeOff-1, 0; off >= 0 && n < bracketScanLimit; off, n = off-1, n+1 {
+		if !e.isBracketAt(off) {
+			continue
+		}
 
+		r, _ := e.src.ReadRuneAt(off)
+		if _, isOpening := e.BracketsQuotes.ContainsBracket(r); !isOpening {
+			stack.push(r, off)
+			continue
 		}
+
+		if stack.depth() == 0 {
+			return off
+		}
+		top, _ := stack.peek()
+		if c, _ := e.BracketsQuotes.GetCounterpart(r); c != top {
+			return -1
+		}
+		stack.pop()
 	}
 
-	return left, right
+	return -1
+}
+
+// isBracketAt checks if there is a bracket at runeOff outside of strings and
+// comments.
+func (e *TextView) isBracketAt(runeOff int) bool {
+	if runeOff < 0 {
+		return false
+	}
+	r, err := e.src.ReadRuneAt(runeOff)
+	if err != nil {
+		return false
+	}
+	if isBracket, _ := e.BracketsQuotes.ContainsBracket(r); !isBracket {
+		return false
+	}
+	return !e.inStringOrComment(runeOff)
+}
+
+// inStringOrComment checks if runeOff is covered by a string or a comment
+// token.
+func (e *TextView) inStringOrComment(runeOff int) bool {
+	return e.stringOrCommentAt(runeOff) != ""
+}
+
+// bracketMatchKey identifies the state NearestMatchingBrackets is computed
+// from.
+type bracketMatchKey struct {
+	caret           int
+	src             buffer.TextSource
+	srcVersion      int
+	tokens          *syntax.TextTokens
+	tokensVersion   int
+	brackets        *BracketsQuotes
+	bracketsVersion int
+}
+
+// bracketMatch caches the result of NearestMatchingBrackets.
+type bracketMatch struct {
+	key         bracketMatchKey
+	valid       bool
+	left, right int
 }
 
 type bracketPos struct {
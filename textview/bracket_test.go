@@ -6,6 +6,8 @@ import (
 
 	"gioui.org/layout"
 	"gioui.org/text"
+	"github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/textstyle/syntax"
 )
 
 func TestNearestMatchingBrackets(t *testing.T) {
@@ -77,3 +79,104 @@ func TestNearestMatchingBrackets(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchingBracket(t *testing.T) {
+	view := NewTextView()
+	gtx := layout.Context{}
+	shaper := text.NewShaper()
+
+	scheme := &syntax.ColorScheme{}
+	scheme.AddStyle("string", 0, color.Color{}, color.Color{})
+	scheme.AddStyle("comment", 0, color.Color{}, color.Color{})
+	view.SetColorScheme(scheme)
+
+	input := "func() {\n s := \"}\" // )\n}"
+	view.SetText(input)
+	view.Layout(gtx, shaper)
+	view.SetSyntaxTokens(
+		syntax.Token{Start: 15, End: 18, Scope: "string"},
+		syntax.Token{Start: 19, End: 23, Scope: "comment"},
+	)
+
+	cases := []struct {
+		offset int
+		want   int
+	}{
+		{offset: 4, want: 5},
+		{offset: 5, want: 4},
+		{offset: 7, want: 24},
+		{offset: 24, want: 7},
+		{offset: 1, want: -1},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			if got := view.MatchingBracket(tc.offset); got != tc.want {
+				t.Errorf("MatchingBracket(%d) = %d, want %d", tc.offset, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMoveToMatchingBracket(t *testing.T) {
+	view := NewTextView()
+	view.SetText("f(a, [b]) x")
+	view.Layout(layout.Context{}, text.NewShaper())
+
+	cases := []struct {
+		caret int
+		want  int
+		ok    bool
+	}{
+		// before a bracket, before its counterpart.
+		{caret: 1, want: 8, ok: true},
+		{caret: 8, want: 1, ok: true},
+		// after a bracket, after its counterpart.
+		{caret: 9, want: 2, ok: true},
+		{caret: 6, want: 8, ok: true},
+		// inside the brackets, the enclosing closing bracket.
+		{caret: 3, want: 8, ok: true},
+		{caret: 10, want: 10, ok: false},
+	}
+	for _, tc := range cases {
+		view.SetCaret(tc.caret, tc.caret)
+		ok := view.MoveToMatchingBracket(SelectionClear)
+		if got, _ := view.Selection(); ok != tc.ok || got != tc.want {
+			t.Errorf("caret %d: got %d, %v, want %d, %v", tc.caret, got, ok, tc.want, tc.ok)
+		}
+	}
+
+	view.SetCaret(1, 1)
+	view.MoveToMatchingBracket(SelectionExtend)
+	if start, end := view.Selection(); start != 8 || end != 1 {
+		t.Errorf("got selection %d-%d, want 8-1", start, end)
+	}
+}
+
+func TestNearestMatchingBracketsCache(t *testing.T) {
+	view := NewTextView()
+	scheme := &syntax.ColorScheme{}
+	scheme.AddStyle("string", 0, color.Color{}, color.Color{})
+	view.SetColorScheme(scheme)
+	view.SetText("(a <b>)")
+	view.Layout(layout.Context{}, text.NewShaper())
+	view.SetCaret(4, 4)
+
+	check := func(step string, wantLeft, wantRight int) {
+		t.Helper()
+		if left, right := view.NearestMatchingBrackets(); left != wantLeft || right != wantRight {
+			t.Errorf("%s: got brackets %d-%d, want %d-%d", step, left, right, wantLeft, wantRight)
+		}
+	}
+
+	check("initial", 0, 6)
+	view.BracketsQuotes.SetBrackets(map[rune]rune{'<': '>'})
+	check("after configuring the brackets", 3, 5)
+	view.BracketsQuotes.SetBrackets(nil)
+	check("after restoring the brackets", 0, 6)
+	view.SetSyntaxTokens(syntax.Token{Start: 6, End: 7, Scope: "string"})
+	check("after setting the tokens", 0, -1)
+	view.SetSyntaxTokens()
+	view.Replace(1, 1, "(")
+	check("after an edit", 1, 7)
+}
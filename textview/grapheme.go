@@ -0,0 +1,68 @@
+package textview
+
+import (
+	"github.com/go-text/typesetting/segmenter"
+)
+
+// graphemeWindow is the number of runes segmented on each side of an offset
+// to find the grapheme cluster at it. It is longer than the clusters of real
+// text, like emoji ZWJ sequences, or letters with combining marks.
+const graphemeWindow = 64
+
+// MoveGraphemes returns the rune offset resulting from moving graphemes
+// grapheme clusters from runeOff: forward if graphemes is positive, and
+// backward otherwise. Moving by zero returns the start of the cluster at
+// runeOff. Unlike the caret movements, the text is segmented from the source,
+// so the offset does not need to be laid out, e.g., for the carets of
+// multiple selections outside of the viewport.
+func (e *TextView) MoveGraphemes(runeOff, graphemes int) int {
+	total := e.src.Len()
+	runeOff = max(0, min(runeOff, total))
+	if graphemes == 0 {
+		if runeOff == total {
+			return runeOff
+		}
+		start, _ := e.graphemeAt(runeOff)
+		return start
+	}
+
+	for ; graphemes > 0 && runeOff < total; graphemes-- {
+		_, runeOff = e.graphemeAt(runeOff)
+	}
+	for ; graphemes < 0 && runeOff > 0; graphemes++ {
+		runeOff, _ = e.graphemeAt(runeOff - 1)
+	}
+	return runeOff
+}
+
+// graphemeAt returns the rune range of the grapheme cluster containing the
+// rune at runeOff.
+func (e *TextView) graphemeAt(runeOff int) (start, end int) {
+	winStart := max(runeOff-graphemeWindow, 0)
+	winEnd := min(runeOff+graphemeWindow, e.src.Len())
+	startOff := e.src.RuneOffset(winStart)
+	buf := make([]byte, e.src.RuneOffset(winEnd)-startOff)
+	n, _ := e.src.ReadAt(buf, int64(startOff))
+	runes := []rune(string(buf[:n]))
+
+	// a cluster never crosses a line break, where the segmentation can
+	// start without the text before it.
+	for i := runeOff - winStart - 1; i >= 0; i-- {
+		if runes[i] == '\n' {
+			runes, winStart = runes[i+1:], winStart+i+1
+			break
+		}
+	}
+
+	var seg segmenter.Segmenter
+	seg.Init(runes)
+	iter := seg.GraphemeIterator()
+	for iter.Next() {
+		g := iter.Grapheme()
+		start, end = winStart+g.Offset, winStart+g.Offset+len(g.Text)
+		if end > runeOff {
+			break
+		}
+	}
+	return start, max(end, runeOff+1)
+}
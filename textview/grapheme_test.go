@@ -0,0 +1,56 @@
+package textview
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMoveGraphemes(t *testing.T) {
+	// a family emoji of 5 runes, e with a combining acute accent, a flag of
+	// two regional indicators, and a CRLF line break.
+	const family = "👨‍👩‍👧"
+	doc := "a" + family + "e\u0301🇯🇵\r\nb"
+
+	testcases := []struct {
+		off, graphemes, want int
+	}{
+		{off: 0, graphemes: 1, want: 1},
+		{off: 1, graphemes: 1, want: 6},
+		{off: 6, graphemes: -1, want: 1},
+		{off: 1, graphemes: 2, want: 8},
+		{off: 8, graphemes: 1, want: 10},
+		{off: 10, graphemes: 1, want: 12},
+		{off: 12, graphemes: -1, want: 10},
+		{off: 13, graphemes: -3, want: 8},
+		// inside a cluster.
+		{off: 3, graphemes: 0, want: 1},
+		{off: 3, graphemes: 1, want: 6},
+		{off: 3, graphemes: -1, want: 1},
+		{off: 11, graphemes: 0, want: 10},
+		// at the ends of the text.
+		{off: 0, graphemes: -1, want: 0},
+		{off: 13, graphemes: 1, want: 13},
+		{off: 13, graphemes: 0, want: 13},
+	}
+
+	view := NewTextView()
+	view.SetText(doc)
+	for _, tc := range testcases {
+		if got := view.MoveGraphemes(tc.off, tc.graphemes); got != tc.want {
+			t.Errorf("MoveGraphemes(%d, %d) = %d, want %d", tc.off, tc.graphemes, got, tc.want)
+		}
+	}
+}
+
+func TestMoveGraphemesLongLine(t *testing.T) {
+	// the cluster is segmented in a window of the long line.
+	doc := strings.Repeat("x", 200) + "e\u0301\u0302" + strings.Repeat("y", 200)
+	view := NewTextView()
+	view.SetText(doc)
+	if got := view.MoveGraphemes(200, 1); got != 203 {
+		t.Errorf("MoveGraphemes(200, 1) = %d, want 203", got)
+	}
+	if got := view.MoveGraphemes(203, -1); got != 200 {
+		t.Errorf("MoveGraphemes(203, -1) = %d, want 200", got)
+	}
+}
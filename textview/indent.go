@@ -16,8 +16,8 @@ func (e *TextView) IndentLines(dedent bool) int {
 	if selectedLines, _ := e.selectedParagraphs(); !dedent && len(selectedLines) <= 1 {
 		// expand soft tab.
 		start, end := e.Selection()
-		moves := e.Replace(start, end, e.expandTab(start, end, "\t"))
-		if start != end {
+		moves, ok := e.hookedReplace(start, end, e.expandTab(start, end, "\t"))
+		if ok && start != end {
 			e.ClearSelection()
 			e.MoveCaret(moves, moves)
 		}
@@ -71,7 +71,10 @@ func (e *TextView) IndentLines(dedent bool) int {
 
 	var inserted int
 	if newLines.String() != string(e.lineBuf) {
-		inserted = e.Replace(linesStart, linesEnd, newLines.String())
+		var ok bool
+		if inserted, ok = e.hookedReplace(linesStart, linesEnd, newLines.String()); !ok {
+			return inserted
+		}
 	}
 
 	if moves != 0 {
@@ -96,6 +99,15 @@ func (e *TextView) IndentLines(dedent bool) int {
 	return inserted
 }
 
+// hookedReplace replaces the text through EditHook if it is set. It reports
+// whether the edit is made as requested.
+func (e *TextView) hookedReplace(start, end int, s string) (int, bool) {
+	if e.EditHook != nil {
+		return e.EditHook(start, end, s)
+	}
+	return e.Replace(start, end, s), true
+}
+
 func (e *TextView) dedentLine(line string) string {
 	level := 0
 	spaces := 0
@@ -174,9 +186,10 @@ func (e *TextView) IndentOnBreak(s string) int {
 	buf.WriteString(s)
 	buf.WriteString(strings.Repeat(e.Indentation(), indents))
 
-	// 2. check if we are after inside a brackets pair.
+	// 2. check if we are after inside a brackets pair. Brackets are not
+	// indented inside of strings or comments.
 	leftBracket, rightBracket := e.NearestMatchingBrackets()
-	inBrackets := leftBracket >= 0 && rightBracket > leftBracket &&
+	inBrackets := !e.CaretInStringOrComment(start) && leftBracket >= 0 && rightBracket > leftBracket &&
 		lineStart <= leftBracket && leftBracket < lineEnd && end <= rightBracket
 	if inBrackets {
 		// Inside of a pair of brackets, add one more level of indents.
@@ -189,9 +202,19 @@ func (e *TextView) IndentOnBreak(s string) int {
 			adjust += utf8.RuneCountInString(s2)
 		}
 
+	} else if e.betweenPair(lineStart, start, end, lineEnd) {
+		// Between the halves of a multi-rune pair, e.g., "/*" and "*/", put
+		// the closing half on its own line.
+		buf.WriteString(e.Indentation())
+		s2 := s + strings.Repeat(e.Indentation(), indents)
+		buf.WriteString(s2)
+		adjust += utf8.RuneCountInString(s2)
 	}
 
-	moves := e.Replace(start, end, buf.String())
+	moves, ok := e.hookedReplace(start, end, buf.String())
+	if !ok {
+		return moves
+	}
 	if start != end {
 		// if there is a seletion, clear the selection.
 		e.ClearSelection()
@@ -205,6 +228,21 @@ func (e *TextView) IndentOnBreak(s string) int {
 	return moves
 }
 
+// betweenPair checks if the text before start on the line ends with the opening
+// half of a multi-rune pair, and the text after end starts with its closing half.
+func (e *TextView) betweenPair(lineStart, start, end, lineEnd int) bool {
+	if len(e.BracketsQuotes.Pairs()) == 0 {
+		return false
+	}
+
+	before := e.readRange(lineStart, start)
+	p, ok := e.BracketsQuotes.MatchOpening(before)
+	if !ok {
+		return false
+	}
+	return strings.HasPrefix(e.readRange(end, lineEnd), p.Closing)
+}
+
 // func (e *autoIndentHandler) dedentRightBrackets(ke key.EditEvent) bool {
 // 	opening, ok := rtlBracketPairs[ke.Text]
 // 	if !ok {
@@ -2,8 +2,10 @@ package textview
 
 import (
 	"fmt"
+	"image"
 	"testing"
 
+	"gioui.org/font/gofont"
 	"gioui.org/layout"
 	"gioui.org/text"
 	"gioui.org/unit"
@@ -226,3 +228,66 @@ func TestIndentOnBreak(t *testing.T) {
 		})
 	}
 }
+
+func TestIndentOnBreakBetweenPairs(t *testing.T) {
+	vw := NewTextView()
+	vw.TabWidth = 4
+	vw.SoftTab = true
+	vw.TextSize = unit.Sp(14)
+	vw.BracketsQuotes.SetPairs(map[string]string{"/*": "*/", "<!--": "-->"})
+
+	cases := []struct {
+		input     string
+		selection int
+		want      string
+		wantCaret int
+	}{
+		{input: "/**/", selection: 2, want: "/*\n    \n*/", wantCaret: 7},
+		{input: "<!---->", selection: 4, want: "<!--\n    \n-->", wantCaret: 9},
+		{input: "/* */", selection: 2, want: "/*\n */", wantCaret: 3},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d: %s", i, tc.input), func(t *testing.T) {
+			vw.SetText(tc.input)
+			vw.Layout(layout.Context{}, text.NewShaper())
+			vw.SetCaret(tc.selection, tc.selection)
+			vw.IndentOnBreak("\n")
+
+			got := string(buffer.NewReader(vw.src).ReadAll(nil))
+			if got != tc.want {
+				t.Errorf("want content: %q, actual content: %q", tc.want, got)
+			}
+			if start, _ := vw.Selection(); start != tc.wantCaret {
+				t.Errorf("want caret at %d, actual: %d", tc.wantCaret, start)
+			}
+		})
+	}
+}
+func TestVisualTabWidth(t *testing.T) {
+	vw := NewTextView()
+	vw.TabWidth = 4
+	vw.TextSize = unit.Sp(14)
+	vw.SetText("\tx")
+
+	gtx := layout.Context{Constraints: layout.Exact(image.Pt(800, 600))}
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	vw.Layout(gtx, shaper)
+	narrow := vw.closestToRune(1).X
+
+	vw.VisualTabWidth = 8
+	vw.Layout(gtx, shaper)
+	wide := vw.closestToRune(1).X
+	if wide != narrow*2 {
+		t.Fatalf("tab advance: got %v with width 8, want twice %v", wide, narrow)
+	}
+
+	// Editing operations still follow TabWidth.
+	if got := vw.Indentation(); got != "\t" {
+		t.Fatalf("indentation: got %q", got)
+	}
+	vw.SoftTab = true
+	if got := vw.Indentation(); got != "    " {
+		t.Fatalf("soft tab indentation: got %q", got)
+	}
+}
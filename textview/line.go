@@ -3,6 +3,7 @@ package textview
 import (
 	"sort"
 	"strings"
+	"unicode"
 
 	lt "github.com/oligo/gvcode/internal/layout"
 )
@@ -16,6 +17,7 @@ func (e *TextView) Paragraphs() int {
 // find a paragraph by rune index, returning the line number(starting from zero)
 // and the paragraph itself.
 func (e *TextView) FindParagraph(runeIdx int) (int, lt.Paragraph) {
+	e.makeValid()
 	if len(e.layouter.Paragraphs) == 0 {
 		return 0, lt.Paragraph{}
 	}
@@ -111,6 +113,19 @@ func (e *TextView) SelectedLineRange() (start, end int) {
 	return paragraphs[0].RuneOff, last.RuneOff + last.Runes
 }
 
+// readRange reads the text between the rune offsets start and end.
+func (e *TextView) readRange(start, end int) string {
+	startOff := e.src.RuneOffset(start)
+	endOff := e.src.RuneOffset(end)
+	if endOff <= startOff {
+		return ""
+	}
+
+	buf := make([]byte, endOff-startOff)
+	n, _ := e.src.ReadAt(buf, int64(startOff))
+	return string(buf[:n])
+}
+
 // SelectedLine returns the text of the selected lines and the rune range. An empty selection is treated
 // as a single line selection.
 func (e *TextView) SelectedLineText(buf []byte) ([]byte, int, int) {
@@ -197,3 +212,57 @@ func (e *TextView) Indentation() string {
 	}
 	return indentation
 }
+
+// MoveBlocks moves the caret to the blank line after the next few blocks of
+// lines separated by blank lines, in the specified direction. Positive is
+// forward, negative is backward. The caret is moved to the start or the end
+// of the text if there is no blank line left.
+func (e *TextView) MoveBlocks(distance int, selAct SelectionAction) {
+	e.makeValid()
+	paragraphs := e.layouter.Paragraphs
+	if len(paragraphs) == 0 {
+		return
+	}
+
+	blocks, direction := distance, 1
+	if distance < 0 {
+		blocks, direction = -distance, -1
+	}
+	line, _ := e.FindParagraph(e.caret.start)
+	for ii := 0; ii < blocks; ii++ {
+		// skip the blank lines at the caret, then the lines of the block.
+		for line >= 0 && line < len(paragraphs) && e.isBlankParagraph(line) {
+			line += direction
+		}
+		for line >= 0 && line < len(paragraphs) && !e.isBlankParagraph(line) {
+			line += direction
+		}
+	}
+
+	switch {
+	case line < 0:
+		e.caret.start = 0
+	case line >= len(paragraphs):
+		e.caret.start = e.Len()
+	default:
+		e.caret.start = paragraphs[line].RuneOff
+	}
+	e.caret.xoff = 0
+	e.updateSelection(selAct)
+	e.clampCursorToGraphemes()
+}
+
+// isBlankParagraph reports whether the paragraph at line has only spaces.
+func (e *TextView) isBlankParagraph(line int) bool {
+	p := e.layouter.Paragraphs[line]
+	for off := p.RuneOff; off < p.RuneOff+p.Runes; off++ {
+		r, err := e.src.ReadRuneAt(off)
+		if err != nil {
+			break
+		}
+		if !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+package textview
+
+import (
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/text"
+)
+
+func TestMoveBlocks(t *testing.T) {
+	view := NewTextView()
+	view.SetText("a\nb\n\n  \nc\nd\n\ne")
+	view.Layout(layout.Context{}, text.NewShaper())
+
+	// the offsets of the stops, moving forward then backward.
+	for _, want := range []int{4, 12, 14} {
+		view.MoveBlocks(1, SelectionClear)
+		if got, _ := view.Selection(); got != want {
+			t.Fatalf("forward: got %d, want %d", got, want)
+		}
+	}
+	for _, want := range []int{12, 5, 0} {
+		view.MoveBlocks(-1, SelectionClear)
+		if got, _ := view.Selection(); got != want {
+			t.Fatalf("backward: got %d, want %d", got, want)
+		}
+	}
+
+	view.MoveBlocks(2, SelectionExtend)
+	if start, end := view.Selection(); start != 12 || end != 0 {
+		t.Fatalf("got selection %d-%d, want 12-0", start, end)
+	}
+}
@@ -0,0 +1,84 @@
+package textview
+
+import (
+	"github.com/oligo/gvcode/internal/buffer"
+)
+
+// sharedSource follows the edits made to the source by the other views
+// sharing it.
+type sharedSource struct {
+	enabled bool
+	// src is the source the listener is registered on.
+	src    buffer.TextSource
+	remove func()
+	// editing is set while the view edits the source, so that its own edits,
+	// which already move its caret, are ignored.
+	editing bool
+	// peerEdited is set by the edits of the other views, until reported by
+	// PeerEdited.
+	peerEdited bool
+}
+
+// SetShared marks the source of the view as shared with other views, e.g.,
+// the panes of a split editor. The edits made through the other views then
+// move the caret of the view and invalidate its layout. The view keeps
+// following the source set by SetViewState.
+func (e *TextView) SetShared(shared bool) {
+	e.shared.enabled = shared
+	e.bindShared()
+}
+
+// Shared reports whether the source of the view is shared with other views.
+func (e *TextView) Shared() bool {
+	return e.shared.enabled
+}
+
+// PeerEdited reports whether the other views edited the shared source since
+// the last call.
+func (e *TextView) PeerEdited() bool {
+	edited := e.shared.peerEdited
+	e.shared.peerEdited = false
+	return edited
+}
+
+// bindShared registers the listener of the edits on the current source, or
+// unregisters it if sharing is disabled.
+func (e *TextView) bindShared() {
+	s := &e.shared
+	if s.enabled && s.src == e.src {
+		return
+	}
+	if s.remove != nil {
+		s.remove()
+		s.remove, s.src = nil, nil
+	}
+	if !s.enabled || e.src == nil {
+		return
+	}
+	s.src = e.src
+	s.remove = e.src.OnEdit(e.onPeerEdit)
+}
+
+// onPeerEdit moves the caret of the view past the edits of the other views.
+func (e *TextView) onPeerEdit(delta buffer.EditDelta) {
+	if e.shared.editing {
+		return
+	}
+	e.caret.start = adjustPos(e.caret.start, delta.OldEnd, delta.NewEnd)
+	e.caret.end = adjustPos(e.caret.end, delta.OldEnd, delta.NewEnd)
+	e.shared.peerEdited = true
+	e.invalidate()
+}
+
+// adjustPos moves the rune offset pos after the replacement of the text up
+// to oldEnd by the text up to newEnd. The positions in the replaced text
+// past the inserted text are moved to its end.
+func adjustPos(pos, oldEnd, newEnd int) int {
+	switch {
+	case newEnd < pos && pos < oldEnd:
+		pos = newEnd
+	case oldEnd <= pos:
+		pos += newEnd - oldEnd
+	}
+	return pos
+}
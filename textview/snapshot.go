@@ -0,0 +1,37 @@
+package textview
+
+import (
+	lt "github.com/oligo/gvcode/internal/layout"
+)
+
+// LayoutSnapshot is an immutable copy of the lines, paragraphs and glyphs of
+// the laid out text.
+type LayoutSnapshot = lt.Snapshot
+
+// SetLayoutSnapshots enables or disables publishing a LayoutSnapshot after
+// each layout of the text. Copying the glyphs makes the layouts slower, so
+// the snapshots are only published when some reader needs them.
+func (e *TextView) SetLayoutSnapshots(enabled bool) {
+	e.snapshots = enabled
+	if !enabled {
+		e.snapshot.Store(nil)
+	} else if e.valid {
+		e.publishSnapshot()
+	}
+}
+
+// LayoutSnapshot returns the snapshot of the last layout, or nil if the
+// snapshots are disabled or the text is not laid out yet. It is safe to call
+// it from any goroutine, and the snapshot is never modified, so it can be
+// read while the next layout is running.
+func (e *TextView) LayoutSnapshot() *LayoutSnapshot {
+	return e.snapshot.Load()
+}
+
+// publishSnapshot swaps the snapshot of the last layout in, if the snapshots
+// are enabled.
+func (e *TextView) publishSnapshot() {
+	if e.snapshots {
+		e.snapshot.Store(e.layouter.Snapshot())
+	}
+}
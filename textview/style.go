@@ -1,6 +1,7 @@
 package textview
 
 import (
+	gvcolor "github.com/oligo/gvcode/color"
 	"github.com/oligo/gvcode/textstyle/decoration"
 	"github.com/oligo/gvcode/textstyle/syntax"
 )
@@ -25,6 +26,16 @@ func (e *TextView) ClearDecorations(source string) error {
 	}
 }
 
+// QueryDecorations returns the decorations overlapping the rune range
+// [start, end), with their current ranges.
+func (e *TextView) QueryDecorations(start, end int) []decoration.Decoration {
+	if e.decorations == nil {
+		return nil
+	}
+	e.decorations.Refresh()
+	return e.decorations.QueryRange(start, end)
+}
+
 func (e *TextView) SetColorScheme(scheme *syntax.ColorScheme) {
 	e.syntaxStyles = syntax.NewTextTokens(scheme)
 }
@@ -36,6 +47,15 @@ func (e *TextView) SetSyntaxTokens(tokens ...syntax.Token) {
 	e.syntaxStyles.Set(tokens...)
 }
 
+// SetSyntaxTokensInRange replaces the syntax tokens in the rune range
+// [start, end) with tokens, keeping the tokens outside of the range.
+func (e *TextView) SetSyntaxTokensInRange(start, end int, tokens ...syntax.Token) {
+	if e.syntaxStyles == nil {
+		panic("TextView is not properly initialized.")
+	}
+	e.syntaxStyles.SetRange(start, end, tokens...)
+}
+
 // UpdateSyntaxTokensOffset adjusts existing syntax token offsets after a text edit.
 // Parameters mirror Editor.replace: start and end are the old replaced range (runes),
 // newEnd is start + (number of runes inserted).
@@ -49,3 +69,108 @@ func (e *TextView) UpdateSyntaxTokensOffset(start, end, newEnd int) {
 	}
 	e.syntaxStyles.AdjustOffsets(start, end, newEnd)
 }
+
+// TokenAt returns the innermost syntax token covering the rune at runeOff.
+// Tokens are kept with their original scopes, including those having no
+// style in the color scheme. It reports false if no token covers runeOff.
+func (e *TextView) TokenAt(runeOff int) (syntax.Token, bool) {
+	if e.syntaxStyles == nil {
+		return syntax.Token{}, false
+	}
+	return e.syntaxStyles.TokenAt(runeOff)
+}
+
+// TokensAt returns all the syntax tokens covering the rune at runeOff, from
+// the outermost to the innermost one.
+func (e *TextView) TokensAt(runeOff int) []syntax.Token {
+	if e.syntaxStyles == nil {
+		return nil
+	}
+	return e.syntaxStyles.TokensAt(runeOff)
+}
+
+// ScopeAt returns the syntax scope of the innermost token covering the rune
+// at runeOff, or an empty scope if there is none.
+func (e *TextView) ScopeAt(runeOff int) syntax.StyleScope {
+	token, _ := e.TokenAt(runeOff)
+	return token.Scope
+}
+
+// stringOrCommentAt returns "string" or "comment" if any of the tokens
+// covering runeOff is a string or a comment, so that tokens nested in a
+// string, like escape sequences, are still considered part of it. It returns
+// an empty scope otherwise.
+func (e *TextView) stringOrCommentAt(runeOff int) syntax.StyleScope {
+	for _, token := range e.TokensAt(runeOff) {
+		if root := token.Scope.Root(); root == "string" || root == "comment" {
+			return root
+		}
+	}
+	return ""
+}
+
+// CaretInStringOrComment checks if a caret placed at runeOff is inside of a
+// string or a comment token. A caret at the boundary of a string is outside
+// of it, while a caret at the end of a comment reaching the line end is still
+// inside of the comment.
+func (e *TextView) CaretInStringOrComment(runeOff int) bool {
+	if runeOff <= 0 {
+		return false
+	}
+
+	before := e.stringOrCommentAt(runeOff - 1)
+	if before == "" {
+		return false
+	}
+	if e.stringOrCommentAt(runeOff) == before {
+		return true
+	}
+
+	if before == "comment" {
+		next, err := e.src.ReadRuneAt(runeOff)
+		return err != nil || next == '\n'
+	}
+	return false
+}
+
+// SyntaxColorRange is a rune range of text rendered with a syntax color.
+type SyntaxColorRange struct {
+	Start, End int
+	Color      gvcolor.Color
+}
+
+// SyntaxColors returns the foreground colors of the syntax tokens overlapping
+// the rune range [start, end). It allows rendering the text outside of the text
+// view, e.g. in a preview, with the same colors.
+func (e *TextView) SyntaxColors(start, end int) []SyntaxColorRange {
+	if e.syntaxStyles == nil {
+		return nil
+	}
+
+	var ranges []SyntaxColorRange
+	for _, token := range e.syntaxStyles.QueryRange(start, end) {
+		fg := e.syntaxStyles.GetColor(token.Style.Foreground())
+		if !fg.IsSet() {
+			continue
+		}
+		ranges = append(ranges, SyntaxColorRange{
+			Start: max(token.Start, start),
+			End:   min(token.End, end),
+			Color: fg,
+		})
+	}
+	return ranges
+}
+
+// ScopeColors returns the foreground and background colors of the scope in
+// the color scheme. ok is false if the scope is not registered.
+func (e *TextView) ScopeColors(scope syntax.StyleScope) (fg, bg gvcolor.Color, ok bool) {
+	if e.syntaxStyles == nil || e.syntaxStyles.ColorScheme() == nil {
+		return
+	}
+	style, ok := e.syntaxStyles.ColorScheme().LookupStyle(scope)
+	if !ok {
+		return
+	}
+	return e.syntaxStyles.GetColor(style.Foreground()), e.syntaxStyles.GetColor(style.Background()), true
+}
@@ -2,7 +2,9 @@ package textview
 
 import (
 	"image"
+	"maps"
 	"math"
+	"sync/atomic"
 	"unicode/utf8"
 
 	"gioui.org/f32"
@@ -13,7 +15,7 @@ import (
 	"github.com/oligo/gvcode/internal/buffer"
 	"github.com/oligo/gvcode/internal/folding"
 	lt "github.com/oligo/gvcode/internal/layout"
-	"github.com/oligo/gvcode/internal/painter"
+	"github.com/oligo/gvcode/painter"
 	"github.com/oligo/gvcode/textstyle/decoration"
 	"github.com/oligo/gvcode/textstyle/syntax"
 	"golang.org/x/exp/slices"
@@ -67,17 +69,36 @@ type TextView struct {
 	// While for hard tab, this controls the maximum width of the 'tab' glyph to expand to.
 	TabWidth int
 
+	// VisualTabWidth, if greater than zero, overrides TabWidth when rendering
+	// tab characters, e.g., to show the tabs of Go files 4 spaces wide. The
+	// editing operations, like indenting lines, still follow TabWidth.
+	VisualTabWidth int
+
 	// CornerRadius set the radius when drawing selection polygons and other corners that apply.
 	CornerRadius unit.Dp
 
 	// WrapLine configures whether the displayed text will be broken into lines or not.
 	WrapLine bool
+	// wrapIndent is the indentation of the continuation lines of wrapped
+	// paragraphs.
+	wrapIndent lt.WrapIndent
 
 	// WordSeperators configures a set of characters that will be used as word separators
 	// when doing word related operations, like navigating or deleting by word.
 	WordSeperators string
+	// WordChars are characters that are part of words, even if they are word
+	// separators, e.g., "-" in CSS or "$" in JavaScript.
+	WordChars string
+	// SubwordMotion makes moving and selecting by words stop at the
+	// boundaries of the camelCase and snake_case parts of the words.
+	SubwordMotion bool
 	// Brackets and quote pairs that can be auto-completed when the left half is entered.
-	BracketsQuotes *bracketsQuotes
+	BracketsQuotes *BracketsQuotes
+	// EditHook, if not nil, makes the edits of IndentLines and IndentOnBreak
+	// instead of Replace, e.g., to filter them. It returns the number of
+	// runes inserted, and false if the edit is not made as requested, in
+	// which case the caret is left where the hook put it.
+	EditHook func(start, end int, s string) (int, bool)
 
 	// syntaxStyles define styles originate from the syntax lexer.
 	syntaxStyles *syntax.TextTokens
@@ -95,20 +116,46 @@ type TextView struct {
 	// line height used by shaper.
 	lineHeight fixed.Int26_6
 	// scrolled offset relative to the start of dims.
-	scrollOff   image.Point
+	scrollOff image.Point
+	// scrollInset is the height at the top of the viewport covered by
+	// overlays, e.g., the sticky lines.
+	scrollInset int
 	layouter    lt.TextLayout
 	textPainter painter.TextPainter
+	// paintLines buffers the lines passed to the textPainter.
+	paintLines []painter.Line
 
 	// The layout is valid or not. Invalid layout requires a re-layout.
 	valid bool
+	// layoutTabWidth is the tab width used by the last layout.
+	layoutTabWidth int
 	// caret position in the view.
 	caret   caretPos
 	regions []Region
 	// line buffer for line related operations.
 	lineBuf []byte
+	// bracketMatch caches the brackets matched at the caret.
+	bracketMatch bracketMatch
 
 	// foldManager manages code folding regions.
 	foldManager *folding.Manager
+	// foldVersion is the version of the folds in the last layout.
+	foldVersion int
+	// foldAnimation enables the fold transition of the layouts.
+	foldAnimation bool
+	// lineGaps are the blank screen lines laid out above the lines.
+	lineGaps map[int]int
+	// pinned are the rune offsets whose paragraphs are shaped by the next
+	// virtualized layout, besides the ones of the caret.
+	pinned []int
+	// virtual enables the virtualized layout, shaping only the paragraphs
+	// around the viewport.
+	virtual bool
+	// snapshots enables publishing the snapshot of each layout.
+	snapshots bool
+	snapshot  atomic.Pointer[lt.Snapshot]
+	// shared follows the edits made by the other views of the source.
+	shared sharedSource
 }
 
 func NewTextView() *TextView {
@@ -122,7 +169,7 @@ func NewTextView() *TextView {
 func (e *TextView) setSource(source buffer.TextSource) {
 	e.src = source
 	e.layouter = lt.NewTextLayout(e.src)
-	e.BracketsQuotes = &bracketsQuotes{}
+	e.BracketsQuotes = &BracketsQuotes{}
 	e.decorations = decoration.NewDecorationTree(e.src)
 	e.invalidate()
 }
@@ -135,6 +182,17 @@ func (e *TextView) Changed() bool {
 	return e.src.Changed()
 }
 
+// SetVirtualLayout enables or disables the virtualized layout. When it is
+// enabled, only the paragraphs in and around the viewport are shaped, and the
+// heights of the other paragraphs are estimated, keeping huge documents
+// responsive. The paragraphs are shaped as they are scrolled into view.
+func (e *TextView) SetVirtualLayout(enabled bool) {
+	if e.virtual != enabled {
+		e.virtual = enabled
+		e.invalidate()
+	}
+}
+
 func (e *TextView) SetWrapLine(enabled bool) {
 	changed := e.WrapLine != enabled
 	e.WrapLine = enabled
@@ -143,6 +201,19 @@ func (e *TextView) SetWrapLine(enabled bool) {
 	}
 }
 
+// SetWrapIndent configures the indentation of the continuation lines of
+// wrapped paragraphs. If preserve is true, they are indented as much as the
+// first line of their paragraph, plus hanging spaces.
+func (e *TextView) SetWrapIndent(preserve bool, hanging int) {
+	indent := lt.WrapIndent{Preserve: preserve, Hanging: max(hanging, 0)}
+	if e.wrapIndent != indent {
+		e.wrapIndent = indent
+		if e.WrapLine {
+			e.invalidate()
+		}
+	}
+}
+
 // Dimensions returns the dimensions of the visible text.
 func (e *TextView) Dimensions() layout.Dimensions {
 	basePos := e.dims.Size.Y - e.dims.Baseline
@@ -161,6 +232,7 @@ func (e *TextView) makeValid() {
 	}
 	e.layoutText(e.shaper)
 	e.valid = true
+	e.publishSnapshot()
 }
 
 func (e *TextView) closestToRune(runeIdx int) lt.CombinedPos {
@@ -261,9 +333,21 @@ func (e *TextView) Layout(gtx layout.Context, lt *text.Shaper) {
 		e.params.LineHeightScale = e.LineHeightScale
 		e.invalidate()
 	}
+	if e.RenderTabWidth() != e.layoutTabWidth {
+		e.invalidate()
+	}
+	// the folds are collapsed and expanded by the gutter and the commands.
+	if e.foldManager != nil && e.foldManager.Version() != e.foldVersion {
+		e.foldVersion = e.foldManager.Version()
+		e.invalidate()
+	}
 
 	// calculate the final line height used by Shaper
 	e.lineHeight = e.calcLineHeight()
+	// shape the paragraphs scrolled into the viewport.
+	if e.virtual && e.valid && !e.layouter.Covers(e.scrollOff.Y, e.scrollOff.Y+e.viewSize.Y) {
+		e.invalidate()
+	}
 	e.makeValid()
 
 	if viewSize := e.calculateViewSize(gtx); viewSize != e.viewSize {
@@ -318,6 +402,13 @@ func (e *TextView) ScrollRel(dx, dy int) {
 	e.scrollAbs(e.scrollOff.X+dx, e.scrollOff.Y+dy)
 }
 
+// SetScrollInset sets the height at the top of the viewport covered by
+// overlays. ScrollToCaret and ScrollToRange keep the text they reveal below
+// it.
+func (e *TextView) SetScrollInset(inset int) {
+	e.scrollInset = max(inset, 0)
+}
+
 // ScrollOff returns the scroll offset of the text viewport.
 func (e *TextView) ScrollOff() image.Point {
 	return e.scrollOff
@@ -348,6 +439,18 @@ func (e *TextView) TextLayout() *lt.TextLayout {
 	return &e.layouter
 }
 
+// GlyphCacheStats returns the statistics of the cache of the shaped
+// paragraphs of the layout.
+func (e *TextView) GlyphCacheStats() lt.GlyphCacheStats {
+	return e.layouter.GlyphCacheStats()
+}
+
+// ResetGlyphCacheStats clears the counters of the cache of the shaped
+// paragraphs.
+func (e *TextView) ResetGlyphCacheStats() {
+	e.layouter.ResetGlyphCacheStats()
+}
+
 // SetFoldManager sets the folding manager for this text view.
 // The fold manager controls which lines are visible (not folded).
 func (e *TextView) SetFoldManager(fm *folding.Manager) {
@@ -364,6 +467,52 @@ func (e *TextView) SetColorOffsets(offsets map[int]map[int]int) {
 	e.invalidate()
 }
 
+// SetLineGaps sets the number of blank screen lines laid out above the
+// lines, keyed by the 0-based line index, e.g., to show lines which are not
+// part of the text. The gaps of the index past the last line are laid out
+// below it. The gaps are dropped when the document is switched by
+// SetViewState.
+func (e *TextView) SetLineGaps(gaps map[int]int) {
+	if maps.Equal(e.lineGaps, gaps) {
+		return
+	}
+	e.lineGaps = maps.Clone(gaps)
+	e.layouter.SetLineGaps(gaps)
+	e.invalidate()
+}
+
+// LineGap returns the number of blank screen lines laid out above the line.
+func (e *TextView) LineGap(line int) int {
+	return e.lineGaps[line]
+}
+
+// SetFoldAnimation enables sliding the lines to their new positions, and
+// fading in the expanded lines, once the folds are collapsed or expanded. The
+// progress of the animation is set by SetFoldAnimationProgress.
+func (e *TextView) SetFoldAnimation(enabled bool) {
+	e.foldAnimation = enabled
+	e.layouter.SetFoldTransition(enabled)
+}
+
+// FoldAnimating reports whether the lines are moving after folds were
+// collapsed or expanded.
+func (e *TextView) FoldAnimating() bool {
+	return e.layouter.FoldTransitionActive()
+}
+
+// FoldAnimationProgress returns the eased progress of the fold animation,
+// which is 0 in the layout starting it.
+func (e *TextView) FoldAnimationProgress() float32 {
+	return e.layouter.FoldTransitionProgress()
+}
+
+// SetFoldAnimationProgress sets the eased progress in [0, 1] of the fold
+// animation, and marks the layout invalid. The animation ends at 1.
+func (e *TextView) SetFoldAnimationProgress(progress float32) {
+	e.layouter.SetFoldTransitionProgress(progress)
+	e.invalidate()
+}
+
 // FoldManager returns the current folding manager.
 func (e *TextView) FoldManager() *folding.Manager {
 	return e.foldManager
@@ -432,6 +581,13 @@ func (e *TextView) QueryPos(pos image.Point) (line, col int, runeOff int) {
 	return line, combinedPos.Runes - p.RuneOff, combinedPos.Runes
 }
 
+// ClosestOffset returns the rune offset of the grapheme cluster boundary
+// closest to pos, which is relative to the viewport. Unlike QueryPos, the
+// positions beyond the end of the lines map to their end.
+func (e *TextView) ClosestOffset(pos image.Point) int {
+	return e.closestToXYGraphemes(fixed.I(pos.X+e.scrollOff.X), pos.Y+e.scrollOff.Y).Runes
+}
+
 // invalidate mark the layout as invalid.
 func (e *TextView) invalidate() {
 	e.valid = false
@@ -444,7 +600,9 @@ func (e *TextView) Invalidate() {
 
 // Set the text of the buffer. It returns the number of runes inserted.
 func (e *TextView) SetText(s string) int {
+	e.shared.editing = true
 	e.src.SetText([]byte(s))
+	e.shared.editing = false
 	sc := e.src.Len()
 
 	// e.SetCaret(0, 0)
@@ -465,19 +623,11 @@ func (e *TextView) Replace(start, end int, s string) int {
 	sc := utf8.RuneCountInString(s)
 	newEnd := startPos.Runes + sc
 
+	e.shared.editing = true
 	e.src.Replace(startOff, endPos.Runes, s)
-	adjust := func(pos int) int {
-		switch {
-		case newEnd < pos && pos < endPos.Runes:
-			pos = newEnd
-		case endPos.Runes <= pos:
-			diff := newEnd - endPos.Runes
-			pos = pos + diff
-		}
-		return pos
-	}
-	e.caret.start = adjust(e.caret.start)
-	e.caret.end = adjust(e.caret.end)
+	e.shared.editing = false
+	e.caret.start = adjustPos(e.caret.start, endPos.Runes, newEnd)
+	e.caret.end = adjustPos(e.caret.end, endPos.Runes, newEnd)
 	e.invalidate()
 	return sc
 }
@@ -485,9 +635,25 @@ func (e *TextView) Replace(start, end int, s string) int {
 // MovePages moves the caret position by vertical pages of text, ensuring that
 // the final position is aligned to a grapheme cluster boundary.
 func (e *TextView) MovePages(pages int, selAct SelectionAction) {
+	e.moveVertical(pages*e.viewSize.Y, selAct)
+}
+
+// MoveHalfPages is like MovePages, but moves the caret by half of the
+// viewport height.
+func (e *TextView) MoveHalfPages(halfPages int, selAct SelectionAction) {
+	e.moveVertical(halfPages*e.viewSize.Y/2, selAct)
+}
+
+// PageHeight returns the height of a vertical page in pixels.
+func (e *TextView) PageHeight() int {
+	return e.viewSize.Y
+}
+
+// moveVertical moves the caret position by dy pixels vertically.
+func (e *TextView) moveVertical(dy int, selAct SelectionAction) {
 	caret := e.closestToRune(e.caret.start)
 	x := caret.X + e.caret.xoff
-	y := caret.Y + pages*e.viewSize.Y
+	y := caret.Y + dy
 	pos := e.closestToXYGraphemes(x, y)
 	e.caret.start = pos.Runes
 	e.caret.xoff = x - pos.X
@@ -530,6 +696,42 @@ func (e *TextView) MoveCaret(startDelta, endDelta int) {
 	e.caret.end = e.moveByGraphemes(e.caret.end, endDelta)
 }
 
+// MoveVisual moves the caret by distance positions in the visual order of the
+// screen lines: to the right if distance is positive, and to the left
+// otherwise. Unlike MoveCaret, the caret always moves in the direction of the
+// arrow keys in text mixing LTR and RTL runs. The resulting position is on a
+// grapheme cluster boundary.
+func (e *TextView) MoveVisual(distance int, selAct SelectionAction) {
+	e.makeValid()
+	direction := 1
+	if distance < 0 {
+		direction, distance = -1, -distance
+	}
+	caret := e.caret.start
+	for ; distance > 0; distance-- {
+		next := caret
+		for {
+			moved := e.layouter.VisualMove(next, direction)
+			if moved == next {
+				next = caret
+				break
+			}
+			next = moved
+			// skip the positions inside grapheme clusters.
+			if e.moveByGraphemes(next, 0) == next {
+				break
+			}
+		}
+		if next == caret {
+			break
+		}
+		caret = next
+	}
+	e.caret.start = caret
+	e.caret.xoff = 0
+	e.updateSelection(selAct)
+}
+
 // MoveTextStart moves the caret to the start of the text.
 func (e *TextView) MoveTextStart(selAct SelectionAction) {
 	caret := e.closestToRune(e.caret.end)
@@ -549,28 +751,101 @@ func (e *TextView) MoveTextEnd(selAct SelectionAction) {
 	e.clampCursorToGraphemes()
 }
 
-// MoveLineStart moves the caret to the start of the current line, ensuring that the resulting
-// cursor position is on a grapheme cluster boundary.
+// MoveLineStart moves the caret to the start of the current screen line, ensuring that the resulting
+// cursor position is on a grapheme cluster boundary. If the caret is at the start of a
+// wrapped screen line already, it is moved to the start of the paragraph.
 func (e *TextView) MoveLineStart(selAct SelectionAction) {
 	caret := e.closestToRune(e.caret.start)
-	caret = e.closestToLineCol(caret.LineCol.Line, 0)
-	e.caret.start = caret.Runes
-	e.caret.xoff = -caret.X
+	start := e.closestToLineCol(caret.LineCol.Line, 0)
+	if start.Runes == e.caret.start {
+		_, p := e.FindParagraph(e.caret.start)
+		start = e.closestToRune(p.RuneOff)
+	}
+	e.caret.start = start.Runes
+	e.caret.xoff = -start.X
 	e.updateSelection(selAct)
 	e.clampCursorToGraphemes()
 }
 
-// MoveLineEnd moves the caret to the end of the current line, ensuring that the resulting
-// cursor position is on a grapheme cluster boundary.
+// MoveLineEnd moves the caret to the end of the current screen line, ensuring that the resulting
+// cursor position is on a grapheme cluster boundary. If the caret is at the end of a
+// wrapped screen line already, it is moved to the end of the paragraph.
 func (e *TextView) MoveLineEnd(selAct SelectionAction) {
 	caret := e.closestToRune(e.caret.start)
-	caret = e.closestToLineCol(caret.LineCol.Line, math.MaxInt)
-	e.caret.start = caret.Runes
-	e.caret.xoff = fixed.I(e.params.MaxWidth) - caret.X
+	end := e.closestToLineCol(caret.LineCol.Line, math.MaxInt)
+	if end.Runes == e.caret.start {
+		_, p := e.FindParagraph(e.caret.start)
+		last := e.closestToRune(max(p.RuneOff+p.Runes-1, p.RuneOff))
+		end = e.closestToLineCol(last.LineCol.Line, math.MaxInt)
+	}
+	e.caret.start = end.Runes
+	e.caret.xoff = fixed.I(e.params.MaxWidth) - end.X
 	e.updateSelection(selAct)
 	e.clampCursorToGraphemes()
 }
 
+// ScrollAlign controls where ScrollToRange places a range vertically.
+type ScrollAlign uint8
+
+const (
+	// ScrollMinimal scrolls as little as possible to make the range visible.
+	ScrollMinimal ScrollAlign = iota
+	// ScrollCenter places the range at the vertical center of the viewport.
+	ScrollCenter
+	// ScrollTop places the range at the top of the viewport.
+	ScrollTop
+)
+
+// ScrollToRange scrolls the viewport to make the rune range [start, end)
+// visible, placing it according to align. If the range is taller than the
+// viewport, its start is placed at the top. Horizontally, the viewport is
+// scrolled as little as possible to show the start of the range.
+func (e *TextView) ScrollToRange(start, end int, align ScrollAlign) {
+	if start > end {
+		start, end = end, start
+	}
+	e.shapeRunes(start, end)
+	startPos := e.closestToRune(start)
+	endPos := e.closestToRune(end)
+
+	miny := startPos.Y - startPos.Ascent.Ceil()
+	maxy := endPos.Y + endPos.Descent.Ceil()
+	inset := e.clampedInset()
+	if maxy-miny > e.viewSize.Y-inset {
+		align = ScrollTop
+	}
+
+	var ydist int
+	switch align {
+	case ScrollTop:
+		ydist = miny - inset - e.scrollOff.Y
+	case ScrollCenter:
+		ydist = (miny+maxy)/2 - (e.scrollOff.Y + (e.viewSize.Y+inset)/2)
+	default:
+		if d := miny - inset - e.scrollOff.Y; d < 0 {
+			ydist = d
+		} else if d := maxy - (e.scrollOff.Y + e.viewSize.Y); d > 0 {
+			ydist = d
+		}
+	}
+
+	var xdist int
+	minScrollGap := (e.params.PxPerEm * 1).Ceil()
+	if d := startPos.X.Floor() - minScrollGap - e.scrollOff.X; d < 0 {
+		xdist = d
+	} else if d := startPos.X.Ceil() + minScrollGap - (e.scrollOff.X + e.viewSize.X); d > 0 {
+		xdist = d
+	}
+
+	e.ScrollRel(xdist, ydist)
+}
+
+// clampedInset returns the scroll inset, leaving at least a line visible
+// below it.
+func (e *TextView) clampedInset() int {
+	return max(0, min(e.scrollInset, e.viewSize.Y-e.lineHeight.Ceil()))
+}
+
 func (e *TextView) ScrollToCaret() {
 	caret := e.closestToRune(e.caret.start)
 
@@ -591,7 +866,7 @@ func (e *TextView) ScrollToCaret() {
 	}
 
 	// calcualte y delta
-	if d := miny - e.scrollOff.Y; d < 0 {
+	if d := miny - e.clampedInset() - e.scrollOff.Y; d < 0 {
 		ydist = d
 	} else if d := maxy - (e.scrollOff.Y + e.viewSize.Y); d > 0 {
 		ydist = d
@@ -616,6 +891,7 @@ func (e *TextView) Selection() (start, end int) {
 // the two ends are clamped to the nearest grapheme cluster boundary. start
 // and end are in runes, and represent offsets into the editor text.
 func (e *TextView) SetCaret(start, end int) {
+	e.shapeRunes(start, end)
 	e.caret.start = e.closestToRune(start).Runes
 	e.caret.end = e.closestToRune(end).Runes
 	e.clampCursorToGraphemes()
@@ -657,7 +933,9 @@ func (e *TextView) ClearSelection() {
 
 // Undo revert the last operation(s) and mark the textview invalid.
 func (e *TextView) Undo() ([]buffer.CursorPos, bool) {
+	e.shared.editing = true
 	cursors, ok := e.src.Undo()
+	e.shared.editing = false
 	if ok {
 		e.invalidate()
 	}
@@ -667,7 +945,9 @@ func (e *TextView) Undo() ([]buffer.CursorPos, bool) {
 
 // Redo revert the last undo operation(s) and mark the textview invalid.
 func (e *TextView) Redo() ([]buffer.CursorPos, bool) {
+	e.shared.editing = true
 	cursors, ok := e.src.Redo()
+	e.shared.editing = false
 	if ok {
 		e.invalidate()
 	}
@@ -675,6 +955,19 @@ func (e *TextView) Redo() ([]buffer.CursorPos, bool) {
 	return cursors, ok
 }
 
+// RevertTo undoes or redoes the operations made since the checkpoint of name,
+// and marks the textview invalid.
+func (e *TextView) RevertTo(name string) ([]buffer.CursorPos, bool) {
+	e.shared.editing = true
+	cursors, ok := e.src.RevertTo(name)
+	e.shared.editing = false
+	if len(cursors) > 0 {
+		e.invalidate()
+	}
+
+	return cursors, ok
+}
+
 // Regions returns visible regions covering the rune range [start,end).
 func (e *TextView) Regions(start, end int, regions []Region) []Region {
 	viewport := image.Rectangle{
@@ -4,7 +4,9 @@ import (
 	"image"
 	"image/color"
 	"math"
+	"sort"
 
+	"gioui.org/f32"
 	"gioui.org/layout"
 	"gioui.org/op"
 	"gioui.org/op/clip"
@@ -12,7 +14,8 @@ import (
 	"gioui.org/text"
 	"gioui.org/unit"
 	lt "github.com/oligo/gvcode/internal/layout"
-	"github.com/oligo/gvcode/internal/painter"
+	"github.com/oligo/gvcode/painter"
+	"github.com/oligo/gvcode/textstyle/syntax"
 )
 
 // calculateViewSize determines the size of the current visible content,
@@ -28,7 +31,68 @@ func (e *TextView) calculateViewSize(gtx layout.Context) image.Point {
 
 func (e *TextView) layoutText(shaper *text.Shaper) {
 	// e.layoutByParagraph(shaper, &it)
-	e.dims = e.layouter.Layout(shaper, &e.params, e.TabWidth, e.WrapLine)
+	e.layoutTabWidth = e.RenderTabWidth()
+	e.layouter.SetWrapIndent(e.wrapIndent)
+	if !e.virtual {
+		e.layouter.ClearWindow()
+		e.dims = e.layouter.Layout(shaper, &e.params, e.layoutTabWidth, e.WrapLine)
+		return
+	}
+
+	// The heights of the paragraphs change as they are shaped or estimated,
+	// so keep the paragraph at the top of the viewport in place.
+	anchor, anchorOff := -1, 0
+	if paragraphs := e.layouter.Paragraphs; len(paragraphs) > 0 {
+		anchor = sort.Search(len(paragraphs), func(i int) bool {
+			return paragraphs[i].EndY+paragraphs[i].Descent.Ceil() >= e.scrollOff.Y
+		})
+		if anchor < len(paragraphs) {
+			anchorOff = e.scrollOff.Y - paragraphs[anchor].StartY
+		}
+	}
+
+	// shape a screen above and below the viewport, so that scrolling does
+	// not relayout the text in every frame.
+	margin := max(e.viewSize.Y, minVirtualMargin)
+	pinned := append([]int{e.caret.start, e.caret.end}, e.pinned...)
+	e.layouter.SetWindow(e.scrollOff.Y-margin, e.scrollOff.Y+e.viewSize.Y+margin, pinned...)
+	e.dims = e.layouter.Layout(shaper, &e.params, e.layoutTabWidth, e.WrapLine)
+
+	if paragraphs := e.layouter.Paragraphs; anchor >= 0 && anchor < len(paragraphs) {
+		e.scrollAbs(e.scrollOff.X, paragraphs[anchor].StartY+anchorOff)
+	}
+}
+
+// shapeRunes shapes the paragraphs containing the rune offsets if they are
+// estimated by the virtualized layout, so that the offsets are not snapped to
+// the ends of their paragraphs.
+func (e *TextView) shapeRunes(runes ...int) {
+	e.makeValid()
+	if !e.virtual {
+		return
+	}
+	for _, off := range runes {
+		if e.layouter.Estimated(off) {
+			e.pinned = runes
+			e.invalidate()
+			e.makeValid()
+			e.pinned = nil
+			return
+		}
+	}
+}
+
+// minVirtualMargin is the minimum height in pixels shaped above and below
+// the viewport by the virtualized layout.
+const minVirtualMargin = 1024
+
+// RenderTabWidth returns the width of tab characters in spaces when rendering,
+// which is VisualTabWidth if set, or TabWidth otherwise.
+func (e *TextView) RenderTabWidth() int {
+	if e.VisualTabWidth > 0 {
+		return e.VisualTabWidth
+	}
+	return e.TabWidth
 }
 
 // PaintText clips and paints the visible text glyph outlines using the provided
@@ -42,7 +106,72 @@ func (e *TextView) PaintText(gtx layout.Context, material op.CallOp) {
 	e.textPainter.SetViewport(viewport, e.scrollOff)
 	e.textPainter.SetLineHeight(e.lineHeight)
 	e.decorations.Refresh()
-	e.textPainter.Paint(gtx, e.shaper, e.layouter.Lines, material, e.syntaxStyles, e.decorations)
+
+	// the lines expanded by the fold animation fade in.
+	lines := e.layouter.Lines
+	fading, opacity := e.layouter.FoldTransitionFade()
+	next := 0
+	for _, r := range fading {
+		e.textPainter.Paint(gtx, e.shaper, e.painterLines(lines[next:r[0]], viewport), material, e.syntaxStyles, e.decorations)
+		stack := paint.PushOpacity(gtx.Ops, opacity)
+		e.textPainter.Paint(gtx, e.shaper, e.painterLines(lines[r[0]:r[1]], viewport), material, e.syntaxStyles, e.decorations)
+		stack.Pop()
+		next = r[1]
+	}
+	e.textPainter.Paint(gtx, e.shaper, e.painterLines(lines[next:], viewport), material, e.syntaxStyles, e.decorations)
+}
+
+// painterLines converts the lines overlapping the viewport to the lines of the
+// painter, reusing the buffer of the last call.
+func (e *TextView) painterLines(lines []lt.Line, viewport image.Rectangle) []painter.Line {
+	e.paintLines = e.paintLines[:0]
+	for i := range lines {
+		line := &lines[i]
+		if line.Descent.Ceil()+line.YOff < viewport.Min.Y {
+			continue
+		}
+		if line.YOff-line.Ascent.Floor() > viewport.Max.Y {
+			break
+		}
+		e.paintLines = append(e.paintLines, line.PainterLine())
+	}
+	return e.paintLines
+}
+
+// PaintWrapIndicators paints a return arrow after the visible screen lines
+// continued on the next screen line, marking where the paragraphs are wrapped.
+func (e *TextView) PaintWrapIndicators(gtx layout.Context, material op.CallOp) {
+	if !e.WrapLine {
+		return
+	}
+	ascent := e.lineHeight.Ceil()
+	breaks := e.layouter.SoftBreaks(e.scrollOff.Y, e.scrollOff.Y+e.viewSize.Y+ascent)
+	if len(breaks) == 0 {
+		return
+	}
+
+	defer clip.Rect(image.Rectangle{Max: e.viewSize}).Push(gtx.Ops).Pop()
+	size := float32(e.lineHeight.Round()) * 0.4
+	gap := float32(gtx.Dp(unit.Dp(2)))
+	var path clip.Path
+	path.Begin(gtx.Ops)
+	for _, line := range breaks {
+		last := line.Glyphs[len(line.Glyphs)-1]
+		x := fixedToFloat(last.X+last.Advance) + gap - float32(e.scrollOff.X)
+		x = min(x, float32(e.viewSize.X)-size-gap)
+		y := float32(line.YOff-e.scrollOff.Y) - size/3
+		// a hook going down from the top right, and left to the arrow head.
+		path.MoveTo(f32.Pt(x+size, y-size))
+		path.LineTo(f32.Pt(x+size, y))
+		path.LineTo(f32.Pt(x, y))
+		path.MoveTo(f32.Pt(x+size/3, y-size/3))
+		path.LineTo(f32.Pt(x, y))
+		path.LineTo(f32.Pt(x+size/3, y+size/3))
+	}
+	stack := clip.Stroke{Path: path.End(), Width: float32(gtx.Dp(unit.Dp(1)))}.Op().Push(gtx.Ops)
+	material.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+	stack.Pop()
 }
 
 // selectionPolygons creates clip.PathSpecs for the given selection regions,
@@ -86,13 +215,7 @@ func (e *TextView) PaintSelection(gtx layout.Context, material op.CallOp) {
 	if len(e.regions) == 0 {
 		return
 	}
-	paths := e.selectionPolygons(gtx, e.regions)
-	for _, path := range paths {
-		outline := clip.Outline{Path: path}.Op().Push(gtx.Ops)
-		material.Add(gtx.Ops)
-		paint.PaintOp{}.Add(gtx.Ops)
-		outline.Pop()
-	}
+	painter.FillPaths(gtx, e.selectionPolygons(gtx, e.regions), material)
 }
 
 func (e *TextView) PaintOverlay(gtx layout.Context, offset image.Point, overlay layout.Widget) {
@@ -149,6 +272,9 @@ func (e *TextView) PaintOverlay(gtx layout.Context, offset image.Point, overlay
 	call.Add(gtx.Ops)
 }
 
+// HighlightMatchingBrackets paints the matching bracket pair at the caret. The
+// highlight is styled with syntax.MatchingBracketScope if the color scheme
+// registers it, otherwise both brackets are filled and stroked with material.
 func (e *TextView) HighlightMatchingBrackets(gtx layout.Context, material op.CallOp) {
 	left, right := e.NearestMatchingBrackets()
 	if left < 0 || right < 0 {
@@ -164,21 +290,55 @@ func (e *TextView) HighlightMatchingBrackets(gtx layout.Context, material op.Cal
 	e.regions = append(e.regions, leftRegion...)
 	e.regions = append(e.regions, rightRegion...)
 
+	fill, stroke, underline := &material, &material, (*op.CallOp)(nil)
+	if e.syntaxStyles != nil && e.syntaxStyles.ColorScheme() != nil {
+		if style, ok := e.syntaxStyles.ColorScheme().LookupStyle(syntax.MatchingBracketScope); ok {
+			fill, stroke = nil, nil
+			if bg := e.syntaxStyles.GetColor(style.Background()); bg.IsSet() {
+				bgOp := bg.Op(gtx.Ops)
+				fill = &bgOp
+			}
+			fgOp := material
+			if fg := e.syntaxStyles.GetColor(style.Foreground()); fg.IsSet() {
+				fgOp = fg.Op(gtx.Ops)
+			}
+			if style.TextStyle().HasStyle(syntax.Border) {
+				stroke = &fgOp
+			}
+			if style.TextStyle().HasStyle(syntax.Underline) {
+				underline = &fgOp
+			}
+		}
+	}
+
 	defer clip.Rect(localViewport).Push(gtx.Ops).Pop()
+	lineWidth := gtx.Dp(unit.Dp(1))
 	for _, region := range e.regions {
-		area := clip.Rect(e.adjustPadding(region.Bounds))
-		stack := area.Push(gtx.Ops)
-		material.Add(gtx.Ops)
-		paint.PaintOp{}.Add(gtx.Ops)
-		stack.Pop()
+		bounds := e.adjustPadding(region.Bounds)
+		area := clip.Rect(bounds)
+		if fill != nil {
+			stack := area.Push(gtx.Ops)
+			fill.Add(gtx.Ops)
+			paint.PaintOp{}.Add(gtx.Ops)
+			stack.Pop()
+		}
 
-		stroke := clip.Stroke{
-			Path:  area.Path(),
-			Width: float32(gtx.Dp(unit.Dp(1))),
-		}.Op().Push(gtx.Ops)
-		material.Add(gtx.Ops)
-		paint.PaintOp{}.Add(gtx.Ops)
-		stroke.Pop()
+		if stroke != nil {
+			stack := clip.Stroke{
+				Path:  area.Path(),
+				Width: float32(lineWidth),
+			}.Op().Push(gtx.Ops)
+			stroke.Add(gtx.Ops)
+			paint.PaintOp{}.Add(gtx.Ops)
+			stack.Pop()
+		}
+
+		if underline != nil {
+			stack := clip.Rect(image.Rect(bounds.Min.X, bounds.Max.Y-lineWidth, bounds.Max.X, bounds.Max.Y)).Push(gtx.Ops)
+			underline.Add(gtx.Ops)
+			paint.PaintOp{}.Add(gtx.Ops)
+			stack.Pop()
+		}
 	}
 }
 
@@ -0,0 +1,86 @@
+package textview
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+)
+
+func TestVirtualLayoutScrolling(t *testing.T) {
+	var sb strings.Builder
+	for i := range 5000 {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+
+	view := NewTextView()
+	view.TextSize = unit.Sp(14)
+	view.SetText(sb.String())
+	view.SetVirtualLayout(true)
+
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 300))}
+	view.Layout(gtx, shaper)
+
+	viewport := view.Viewport()
+	if !view.layouter.Covers(viewport.Min.Y, viewport.Max.Y) {
+		t.Fatal("the viewport is not shaped")
+	}
+
+	// scroll to the middle of the document, beyond the shaped lines.
+	target := view.layouter.Paragraphs[2500]
+	view.ScrollRel(0, target.StartY-view.ScrollOff().Y)
+	viewport = view.Viewport()
+	if view.layouter.Covers(viewport.Min.Y, viewport.Max.Y) {
+		t.Fatal("expected the middle of the document not to be shaped yet")
+	}
+
+	view.Layout(gtx, shaper)
+	viewport = view.Viewport()
+	if !view.layouter.Covers(viewport.Min.Y, viewport.Max.Y) {
+		t.Error("the viewport is not shaped after scrolling")
+	}
+	// the paragraph at the top of the viewport is kept in place.
+	if got := view.layouter.Paragraphs[2500].StartY; got != viewport.Min.Y {
+		t.Errorf("got the anchor paragraph at %d, want it at the top of the viewport %d", got, viewport.Min.Y)
+	}
+}
+
+func TestVirtualLayoutSetCaret(t *testing.T) {
+	var sb strings.Builder
+	for i := range 5000 {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+
+	view := NewTextView()
+	view.TextSize = unit.Sp(14)
+	view.SetText(sb.String())
+	view.SetVirtualLayout(true)
+
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 300))}
+	view.Layout(gtx, shaper)
+
+	// the caret is placed inside of a paragraph far from the viewport,
+	// instead of being snapped to its start.
+	target := view.layouter.Paragraphs[2500].RuneOff + 3
+	if !view.layouter.Estimated(target) {
+		t.Fatal("expected the target paragraph to be estimated")
+	}
+	view.SetCaret(target, target+1)
+	if start, end := view.Selection(); start != target || end != target+1 {
+		t.Errorf("got selection [%d, %d), want [%d, %d)", start, end, target, target+1)
+	}
+
+	view.ScrollToRange(target, target+1, ScrollTop)
+	view.Layout(gtx, shaper)
+	if pos := view.closestToRune(target); pos.Runes != target || pos.Y-pos.Ascent.Ceil() < view.ScrollOff().Y {
+		t.Errorf("got the target at rune %d, y %d, want it shown below %d", pos.Runes, pos.Y, view.ScrollOff().Y)
+	}
+}
@@ -0,0 +1,159 @@
+package textview
+
+import (
+	"image"
+
+	"gioui.org/layout"
+	"gioui.org/text"
+	"github.com/oligo/gvcode/internal/buffer"
+	"github.com/oligo/gvcode/internal/folding"
+	lt "github.com/oligo/gvcode/internal/layout"
+	"github.com/oligo/gvcode/textstyle/decoration"
+	"github.com/oligo/gvcode/textstyle/syntax"
+)
+
+// ViewState holds a text source and the states of the view bound to it, such
+// as the caret, the scroll offset, syntax tokens and decorations. It is used
+// to switch a TextView between multiple documents.
+type ViewState struct {
+	src          buffer.TextSource
+	caret        caretPos
+	scrollOff    image.Point
+	syntaxStyles *syntax.TextTokens
+	decorations  *decoration.DecorationTree
+	// folds are the folds of the document and their collapsed states.
+	folds folding.State
+	// layout is the last valid layout of the document, reused when switching
+	// back to it.
+	layout *savedLayout
+}
+
+// savedLayout is a valid text layout together with the parameters it was
+// computed with. The hidden lines of the layout are the ones of the folds
+// saved with it.
+type savedLayout struct {
+	layouter lt.TextLayout
+	dims     layout.Dimensions
+	// version is the version of the text source laid out, as the source can
+	// be edited while the document is not shown, e.g., by a split view.
+	version  int
+	params   text.Parameters
+	tabWidth int
+	wrapLine bool
+}
+
+// Source returns the text source of the state.
+func (s *ViewState) Source() buffer.TextSource {
+	return s.src
+}
+
+// NewViewState creates a state for src, sharing the color scheme of the
+// current view.
+func (e *TextView) NewViewState(src buffer.TextSource) ViewState {
+	st := ViewState{
+		src:         src,
+		decorations: decoration.NewDecorationTree(src),
+	}
+	if e.syntaxStyles != nil {
+		st.syntaxStyles = syntax.NewTextTokens(e.syntaxStyles.ColorScheme())
+	}
+	return st
+}
+
+// ViewState returns the state of the current document.
+func (e *TextView) ViewState() ViewState {
+	st := ViewState{
+		src:          e.src,
+		caret:        e.caret,
+		scrollOff:    e.scrollOff,
+		syntaxStyles: e.syntaxStyles,
+		decorations:  e.decorations,
+	}
+	if e.foldManager != nil {
+		st.folds = e.foldManager.State()
+	}
+	// the layouts with gaps are not saved, as the gaps are dropped when
+	// switching documents, nor the ones of folds toggled since the layout.
+	if e.valid && !e.foldsChanged() && len(e.lineGaps) == 0 {
+		st.layout = &savedLayout{
+			layouter: e.layouter,
+			dims:     e.dims,
+			version:  e.src.Version(),
+			params:   e.params,
+			tabWidth: e.layoutTabWidth,
+			wrapLine: e.WrapLine,
+		}
+	}
+	return st
+}
+
+// SetViewState switches the view to the document of st, restoring the
+// states saved with it, including the folds of the fold manager.
+//
+// The layout of the new document is completed before the view is switched:
+// the layout saved in st is reused if it is still valid, otherwise the
+// document is laid out right away if a shaper is known. So the old document
+// keeps being shown until the new one is ready, and the restored scroll
+// offset is checked against the size of the new document, avoiding a blank
+// or mis-scrolled frame.
+func (e *TextView) SetViewState(st ViewState) {
+	if st.src == nil {
+		return
+	}
+
+	if e.foldManager != nil {
+		e.foldManager.SetState(st.folds)
+		e.foldVersion = e.foldManager.Version()
+	}
+
+	var valid bool
+	layouter := lt.NewTextLayout(st.src)
+	dims := e.dims
+	if saved := st.layout; saved != nil && saved.version == st.src.Version() && saved.params == e.params &&
+		saved.tabWidth == e.RenderTabWidth() && saved.wrapLine == e.WrapLine &&
+		saved.layouter.WrapIndent() == e.wrapIndent {
+		layouter = saved.layouter
+		dims = saved.dims
+		valid = true
+	}
+	layouter.SetFoldManager(e.foldManager)
+	layouter.SetFoldTransition(e.foldAnimation)
+	layouter.SetWrapIndent(e.wrapIndent)
+	if !valid && e.shaper != nil {
+		dims = layouter.Layout(e.shaper, &e.params, e.RenderTabWidth(), e.WrapLine)
+		valid = true
+	}
+
+	e.src = st.src
+	e.lineGaps = nil
+	e.bindShared()
+	e.layouter = layouter
+	e.dims = dims
+	e.valid = valid
+	if valid {
+		e.layoutTabWidth = e.RenderTabWidth()
+		e.publishSnapshot()
+	}
+	e.caret = st.caret
+	if st.syntaxStyles != nil {
+		e.syntaxStyles = st.syntaxStyles
+	} else if e.syntaxStyles != nil {
+		e.syntaxStyles = syntax.NewTextTokens(e.syntaxStyles.ColorScheme())
+	}
+	e.decorations = st.decorations
+	if e.decorations == nil {
+		e.decorations = decoration.NewDecorationTree(e.src)
+	}
+
+	if valid {
+		e.scrollAbs(st.scrollOff.X, st.scrollOff.Y)
+	} else {
+		e.scrollOff = st.scrollOff
+	}
+}
+
+// foldsChanged reports whether the folds were collapsed or expanded since the
+// last layout.
+func (e *TextView) foldsChanged() bool {
+	return e.foldManager != nil && e.foldManager.Version() != e.foldVersion
+}
@@ -16,6 +16,9 @@ const (
 // set the boundary when navigating by words, or deleting by words.
 // TODO: does it make sence to use unicode space definition here?
 func (e *TextView) IsWordSeperator(r rune) bool {
+	if e.WordChars != "" && strings.ContainsRune(e.WordChars, r) {
+		return false
+	}
 	seperators := e.WordSeperators
 
 	if e.WordSeperators == "" {
@@ -49,14 +52,21 @@ func (e *TextView) MoveWords(distance int, selAct SelectionAction) {
 		}
 		return r
 	}
+	// underscores separate the parts of snake_case words.
+	isSeperator := func(r rune) bool {
+		return e.IsWordSeperator(r) || (e.SubwordMotion && r == '_')
+	}
 	for ii := 0; ii < words; ii++ {
-		for r := next(); e.IsWordSeperator(r) && !atEnd(); r = next() {
+		for r := next(); isSeperator(r) && !atEnd(); r = next() {
 			e.MoveCaret(direction, 0)
 			caret = e.closestToRune(e.caret.start)
 		}
 		e.MoveCaret(direction, 0)
 		caret = e.closestToRune(e.caret.start)
-		for r := next(); !e.IsWordSeperator(r) && !atEnd(); r = next() {
+		for r := next(); !isSeperator(r) && !atEnd(); r = next() {
+			if e.SubwordMotion && e.isSubwordBoundary(caret.Runes) {
+				break
+			}
 			e.MoveCaret(direction, 0)
 			caret = e.closestToRune(e.caret.start)
 		}
@@ -65,6 +75,19 @@ func (e *TextView) MoveWords(distance int, selAct SelectionAction) {
 	e.clampCursorToGraphemes()
 }
 
+// isSubwordBoundary reports whether the rune offset off is between two
+// parts of a camelCase word, like in "foo|Bar" and "HTTP|Server".
+func (e *TextView) isSubwordBoundary(off int) bool {
+	prev, cur, next := e.peekRune(off-1), e.peekRune(off), e.peekRune(off+1)
+	switch {
+	case (unicode.IsLower(prev) || unicode.IsDigit(prev)) && unicode.IsUpper(cur):
+		return true
+	case unicode.IsUpper(prev) && unicode.IsUpper(cur) && unicode.IsLower(next):
+		return true
+	}
+	return false
+}
+
 // readBySeperator reads in the specified direction from caretOff until the seperator returns false.
 // It returns the read text.
 func (e *TextView) readBySeperator(direction int, caretOff int, seperator func(r rune) bool) []rune {
@@ -216,6 +239,12 @@ func (e *TextView) FindAllWordOccurrences(start, end int, bySpace bool) [][2]int
 // spanning from start to end (exclusive). This implementation scans the document once with O(n) complexity.
 // It matches exact rune sequences regardless of word boundaries.
 func (e *TextView) FindAllTextOccurrences(start, end int) [][2]int {
+	return e.FindTextOccurrencesIn(start, end, 0, e.src.Len())
+}
+
+// FindTextOccurrencesIn is like FindAllTextOccurrences, but only scans the
+// occurrences within the rune offsets from and to, e.g., of the visible lines.
+func (e *TextView) FindTextOccurrencesIn(start, end, from, to int) [][2]int {
 	if start == end {
 		return nil
 	}
@@ -237,9 +266,9 @@ func (e *TextView) FindAllTextOccurrences(start, end int) [][2]int {
 	}
 
 	var occurrences [][2]int
-	totalLen := e.src.Len()
+	totalLen := min(to, e.src.Len())
 
-	i := 0
+	i := max(from, 0)
 	for i < totalLen {
 		// Look for potential match of first rune
 		r := e.peekRune(i)
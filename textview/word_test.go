@@ -230,3 +230,48 @@ func TestFindAllWordOccurrences(t *testing.T) {
 		})
 	}
 }
+
+func TestMoveSubwords(t *testing.T) {
+	view := NewTextView()
+	view.SetText("parseHTTPServer(max_line_len)")
+	view.Layout(layout.Context{}, text.NewShaper())
+	view.SubwordMotion = true
+
+	// the offsets of the stops, moving forward then backward.
+	forward := []int{5, 9, 15, 19, 24, 28, 29}
+	for _, want := range forward {
+		view.MoveWords(1, SelectionClear)
+		if got, _ := view.Selection(); got != want {
+			t.Fatalf("forward: got %d, want %d", got, want)
+		}
+	}
+	backward := []int{25, 20, 16, 9, 5, 0}
+	for _, want := range backward {
+		view.MoveWords(-1, SelectionClear)
+		if got, _ := view.Selection(); got != want {
+			t.Fatalf("backward: got %d, want %d", got, want)
+		}
+	}
+
+	// without subword motion, only the separators stop the caret.
+	view.SubwordMotion = false
+	view.MoveWords(1, SelectionClear)
+	if got, _ := view.Selection(); got != 15 {
+		t.Fatalf("got %d, want 15", got)
+	}
+}
+
+func TestWordChars(t *testing.T) {
+	view := NewTextView()
+	view.SetText("a-b .c-d")
+	if !view.IsWordSeperator('-') {
+		t.Fatal("expected - to be a separator")
+	}
+	view.WordChars = "-"
+	if view.IsWordSeperator('-') {
+		t.Fatal("expected - to be a word char")
+	}
+	if start, end := view.WordBoundariesAt(6, false); start != 5 || end != 8 {
+		t.Fatalf("got word %d-%d, want 5-8", start, end)
+	}
+}
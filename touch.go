@@ -0,0 +1,298 @@
+package gvcode
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/gesture"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/textview"
+)
+
+const (
+	// longPressDuration is the duration of a touch press selecting the word
+	// under it.
+	longPressDuration = 500 * time.Millisecond
+	// touchSlop is the distance a touch can move without cancelling the
+	// long press.
+	touchSlop = unit.Dp(8)
+	// handleRadius is the radius of the selection handles.
+	handleRadius = unit.Dp(10)
+	// loupeWidth is the width of the magnifier shown while dragging a
+	// handle, and loupeScale its magnification.
+	loupeWidth = unit.Dp(120)
+	loupeScale = 1.5
+)
+
+// touchState implements the touch gestures: a long press selects the word
+// under it, and the selection made by touch shows draggable handles at both
+// of its ends.
+type touchState struct {
+	// pressing is set while a touch press may become a long press.
+	pressing  bool
+	pressID   pointer.ID
+	pressPos  f32.Point
+	pressTime time.Time
+	// selecting is set after a long press, while the touch extends the
+	// selection.
+	selecting bool
+	// showHandles is set when the selection is made by touch, until the
+	// selection is emptied or a mouse press.
+	showHandles bool
+	handles     [2]selectionHandle
+	// dragging is set while the handle of index dragged is dragged.
+	dragging bool
+	dragged  int
+	// loupe is the text position under the dragged handle.
+	loupe f32.Point
+}
+
+// selectionHandle is the handle of one end of the selection.
+type selectionHandle struct {
+	drag gesture.Drag
+	// anchor is the other end of the selection, kept while dragging.
+	anchor int
+	// grab is the offset of the touch to the text position of the handle.
+	grab f32.Point
+}
+
+// processTouch handles the touch pointer events of the editor dragger: a long
+// press selects the word under it, and the following drags extend the
+// selection.
+func (e *Editor) processTouch(gtx layout.Context, evt pointer.Event) {
+	t := &e.touch
+	switch evt.Kind {
+	case pointer.Press:
+		pos := evt.Position.Round()
+		if e.inEmptyArea(pos) || e.inStickyArea(pos) {
+			return
+		}
+		t.pressing = true
+		t.pressID = evt.PointerID
+		t.pressPos = evt.Position
+		t.pressTime = gtx.Now
+		gtx.Execute(op.InvalidateCmd{At: gtx.Now.Add(longPressDuration)})
+	case pointer.Drag:
+		if evt.PointerID != t.pressID {
+			return
+		}
+		if t.pressing {
+			diff := evt.Position.Sub(t.pressPos)
+			slop := float32(gtx.Dp(touchSlop))
+			if diff.X*diff.X+diff.Y*diff.Y > slop*slop {
+				t.pressing = false
+			}
+		}
+		if t.selecting {
+			e.blinkStart = gtx.Now
+			e.text.MoveCoord(evt.Position.Round())
+			e.scrollCaret = true
+		}
+	case pointer.Release, pointer.Cancel:
+		t.pressing = false
+		t.selecting = false
+	}
+}
+
+// checkLongPress selects the word under a touch press held long enough.
+func (e *Editor) checkLongPress(gtx layout.Context) {
+	t := &e.touch
+	if !t.pressing {
+		return
+	}
+	if gtx.Now.Before(t.pressTime.Add(longPressDuration)) {
+		gtx.Execute(op.InvalidateCmd{At: t.pressTime.Add(longPressDuration)})
+		return
+	}
+	t.pressing = false
+	t.selecting = true
+	t.showHandles = true
+	// the long press takes the touch from the scroller and the clicker.
+	gtx.Execute(pointer.GrabCmd{Tag: &e.dragger, ID: t.pressID})
+	gtx.Execute(key.FocusCmd{Tag: e})
+
+	e.blinkStart = gtx.Now
+	e.text.MoveCoord(t.pressPos.Round())
+	e.text.MoveWords(-1, textview.SelectionClear)
+	e.text.MoveWords(1, textview.SelectionExtend)
+	if e.hasExtraSelections() {
+		e.clearExtraSelections()
+	}
+	if e.completor != nil {
+		e.completor.Cancel()
+	}
+}
+
+// hideSelectionHandles hides the handles, e.g., when the mouse is used.
+func (e *Editor) hideSelectionHandles() {
+	e.touch.showHandles = false
+	e.touch.selecting = false
+	e.touch.dragging = false
+}
+
+// selectionHandlesVisible reports whether the handles are shown.
+func (e *Editor) selectionHandlesVisible() bool {
+	t := &e.touch
+	if t.showHandles && e.text.SelectionLen() == 0 && !t.dragging {
+		t.showHandles = false
+	}
+	return t.showHandles
+}
+
+// processSelectionHandles moves the end of the selection of the dragged
+// handle.
+func (e *Editor) processSelectionHandles(gtx layout.Context) {
+	t := &e.touch
+	for i := range t.handles {
+		h := &t.handles[i]
+		for {
+			evt, ok := h.drag.Update(gtx.Metric, gtx.Source, gesture.Both)
+			if !ok {
+				break
+			}
+			if !t.showHandles {
+				continue
+			}
+			switch evt.Kind {
+			case pointer.Press:
+				// the handle takes the touch from the editor below it.
+				gtx.Execute(pointer.GrabCmd{Tag: &h.drag, ID: evt.PointerID})
+				start, end := e.sortedSelection()
+				off, other := start, end
+				if i == 1 {
+					off, other = end, start
+				}
+				h.anchor = other
+				h.grab = evt.Position.Sub(e.handleTextPos(off))
+				t.dragging, t.dragged = true, i
+				t.loupe = evt.Position.Sub(h.grab)
+			case pointer.Drag:
+				if !t.dragging || t.dragged != i {
+					continue
+				}
+				t.loupe = evt.Position.Sub(h.grab)
+				e.blinkStart = gtx.Now
+				e.text.SetCaret(e.text.ClosestOffset(t.loupe.Round()), h.anchor)
+				e.scrollCaret = true
+			case pointer.Release, pointer.Cancel:
+				if t.dragged == i {
+					t.dragging = false
+				}
+			}
+		}
+	}
+}
+
+// sortedSelection returns the ends of the selection in the text order.
+func (e *Editor) sortedSelection() (start, end int) {
+	start, end = e.text.Selection()
+	if start > end {
+		start, end = end, start
+	}
+	return start, end
+}
+
+// handleTextPos returns the point at the middle of the line at runeOff, that
+// the handle of runeOff points to.
+func (e *Editor) handleTextPos(runeOff int) f32.Point {
+	_, ascent, descent := e.text.CaretInfo()
+	pos := e.text.RuneCoords(runeOff)
+	pos.Y += float32(descent-ascent) / 2
+	return pos
+}
+
+// handleBounds returns the bounds of the handle of runeOff, which hangs
+// below the line, on the left of the start of the selection, and on the right
+// of its end.
+func (e *Editor) handleBounds(gtx layout.Context, runeOff int, end bool) image.Rectangle {
+	_, _, descent := e.text.CaretInfo()
+	r := gtx.Dp(handleRadius)
+	pos := e.text.RuneCoords(runeOff).Round()
+	top := image.Pt(pos.X, pos.Y+descent)
+	if end {
+		return image.Rect(top.X, top.Y, top.X+2*r, top.Y+2*r)
+	}
+	return image.Rect(top.X-2*r, top.Y, top.X, top.Y+2*r)
+}
+
+// layoutSelectionHandles paints the handles of the selection, and the loupe
+// magnifying the text under the dragged handle.
+func (e *Editor) layoutSelectionHandles(gtx layout.Context, textColor, selectColor gvcolor.Color) {
+	if !e.selectionHandlesVisible() {
+		return
+	}
+	r := gtx.Dp(handleRadius)
+	material := textColor.NRGBA()
+	start, end := e.sortedSelection()
+	for i, off := range [2]int{start, end} {
+		bounds := e.handleBounds(gtx, off, i == 1)
+		// the handle is a drop pointing to the end of the selection.
+		paint.FillShape(gtx.Ops, material, clip.Ellipse(bounds).Op(gtx.Ops))
+		corner := image.Rectangle{Min: bounds.Min, Max: bounds.Min.Add(image.Pt(r, r))}
+		if i == 0 {
+			corner = corner.Add(image.Pt(r, 0))
+		}
+		paint.FillShape(gtx.Ops, material, clip.Rect(corner).Op())
+
+		// the hit area is larger than the handle, for the fingers.
+		area := clip.Rect(bounds.Inset(-r / 2)).Push(gtx.Ops)
+		e.touch.handles[i].drag.Add(gtx.Ops)
+		area.Pop()
+	}
+
+	if e.touch.dragging {
+		e.paintLoupe(gtx, textColor, selectColor)
+	}
+}
+
+// paintLoupe paints the text around the dragged handle magnified, above the
+// finger hiding it.
+func (e *Editor) paintLoupe(gtx layout.Context, textColor, selectColor gvcolor.Color) {
+	lineHeight := e.text.GetLineHeight().Ceil()
+	padding := gtx.Dp(unit.Dp(4))
+	size := image.Pt(gtx.Dp(loupeWidth), int(math.Ceil(float64(lineHeight)*loupeScale))+2*padding)
+	center := e.touch.loupe
+	origin := image.Pt(
+		int(center.X)-size.X/2,
+		int(center.Y)-lineHeight-size.Y,
+	)
+	origin.X = max(0, min(origin.X, gtx.Constraints.Max.X-size.X))
+	origin.Y = max(0, origin.Y)
+	rect := image.Rectangle{Min: origin, Max: origin.Add(size)}
+	radius := gtx.Dp(unit.Dp(6))
+
+	bgColor := color.NRGBA{R: 0xF8, G: 0xF8, B: 0xF8, A: 0xFF}
+	if e.colorPalette != nil && e.colorPalette.Background.IsSet() {
+		bgColor = e.colorPalette.Background.NRGBA()
+		bgColor.A = 0xFF
+	}
+	paint.FillShape(gtx.Ops, bgColor, clip.UniformRRect(rect, radius).Op(gtx.Ops))
+
+	stack := clip.UniformRRect(rect, radius).Push(gtx.Ops)
+	mid := layout.FPt(rect.Min.Add(rect.Max)).Mul(0.5)
+	transform := op.Affine(f32.AffineId().
+		Offset(center.Mul(-1)).
+		Scale(f32.Point{}, f32.Pt(loupeScale, loupeScale)).
+		Offset(mid)).Push(gtx.Ops)
+	e.paintSelection(gtx, selectColor)
+	e.paintText(gtx, textColor)
+	transform.Pop()
+	stack.Pop()
+
+	borderColor := textColor.NRGBA()
+	borderColor.A = 0x40
+	paint.FillShape(gtx.Ops, borderColor, clip.Stroke{
+		Path:  clip.UniformRRect(rect, radius).Path(gtx.Ops),
+		Width: float32(gtx.Dp(unit.Dp(1))),
+	}.Op())
+}
@@ -0,0 +1,78 @@
+package gvcode
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestTouchSelection(t *testing.T) {
+	e := newTestEditor(t, "hello world foo")
+
+	var router input.Router
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	now := time.Now()
+	frame := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 200)), Source: router.Source(), Now: now}
+		e.Layout(gtx, shaper)
+		router.Frame(gtx.Ops)
+	}
+	frame()
+
+	// a long press selects the word under it.
+	r := e.text.Regions(7, 8, nil)[0].Bounds
+	pos := f32.Pt(float32(r.Min.X+1), float32(r.Min.Y+r.Dy()/2))
+	router.Queue(pointer.Event{Kind: pointer.Press, Source: pointer.Touch, Position: pos})
+	frame()
+	if start, end := e.Selection(); start != end {
+		t.Fatalf("got selection [%d, %d] before the long press", start, end)
+	}
+	now = now.Add(longPressDuration)
+	frame()
+	if got := e.SelectedText(); got != "world" {
+		t.Fatalf("got selected text %q after the long press", got)
+	}
+	router.Queue(pointer.Event{Kind: pointer.Release, Source: pointer.Touch, Position: pos})
+	frame()
+	if got := e.SelectedText(); got != "world" || !e.selectionHandlesVisible() {
+		t.Fatalf("got selected text %q and handles shown %v after the release", got, e.selectionHandlesVisible())
+	}
+
+	// dragging the end handle moves the end of the selection.
+	handle := e.handleBounds(layout.Context{}, 11, true)
+	from := layout.FPt(handle.Min.Add(handle.Max)).Mul(0.5)
+	to := from.Add(e.handleTextPos(15).Sub(e.handleTextPos(11)))
+	router.Queue(pointer.Event{Kind: pointer.Press, Source: pointer.Touch, Position: from})
+	frame()
+	router.Queue(pointer.Event{Kind: pointer.Move, Source: pointer.Touch, Position: to})
+	frame()
+	if got := e.SelectedText(); got != "world foo" {
+		t.Fatalf("got selected text %q after dragging the handle", got)
+	}
+	if !e.touch.dragging {
+		t.Fatal("got no loupe while dragging the handle")
+	}
+	router.Queue(pointer.Event{Kind: pointer.Release, Source: pointer.Touch, Position: to})
+	frame()
+	if e.touch.dragging || e.SelectedText() != "world foo" {
+		t.Fatalf("got dragging %v and selected text %q after the release", e.touch.dragging, e.SelectedText())
+	}
+
+	// the handles are hidden when the mouse is used.
+	router.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: pos},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Mouse, Position: pos},
+	)
+	frame()
+	if e.selectionHandlesVisible() {
+		t.Fatal("got the handles shown after a mouse click")
+	}
+}
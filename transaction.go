@@ -0,0 +1,84 @@
+package gvcode
+
+// EditTx performs edits inside of a transaction started by Editor.Transaction.
+// All the edits of a transaction are undone or redone as a single step.
+type EditTx struct {
+	editor  *Editor
+	changed bool
+}
+
+// Replace replaces the text between the rune offsets start and end with s,
+// returning the number of runes inserted. Each call is filtered by the hooks
+// registered by Editor.OnBeforeChange as a separate edit.
+func (tx *EditTx) Replace(start, end int, s string) int {
+	if start == end && s == "" {
+		return 0
+	}
+	tx.changed = true
+	n, _ := tx.editor.replace(start, end, s)
+	return n
+}
+
+// replaceEdits makes the replacements as a single edit, see
+// Editor.replaceEdits.
+func (tx *EditTx) replaceEdits(edits []Edit) (int, editResult) {
+	n, result := tx.editor.replaceEdits(edits)
+	if result != editVetoed {
+		tx.changed = true
+	}
+	return n, result
+}
+
+// Insert inserts s at the rune offset.
+func (tx *EditTx) Insert(offset int, s string) int {
+	return tx.Replace(offset, offset, s)
+}
+
+// Delete deletes the text between the rune offsets start and end.
+func (tx *EditTx) Delete(start, end int) {
+	tx.Replace(start, end, "")
+}
+
+// SetCaret moves the caret to start, and sets the selection end to end.
+func (tx *EditTx) SetCaret(start, end int) {
+	tx.editor.SetCaret(start, end)
+}
+
+// Editor returns the editor of the transaction. Edits made through the editor
+// API directly are also grouped into the transaction, but they are not counted
+// when deciding whether to generate a ChangeEvent.
+func (tx *EditTx) Editor() *Editor {
+	return tx.editor
+}
+
+// Transaction runs fn with the edits grouped into a single undo step. The group
+// is closed even if fn panics. Nested transactions join the outermost one. If
+// any edit is made through tx, a single ChangeEvent is generated after the
+// outermost transaction commits.
+func (e *Editor) Transaction(fn func(tx *EditTx)) {
+	e.initBuffer()
+	if e.mode == ModeReadOnly {
+		return
+	}
+
+	tx := &EditTx{editor: e}
+	e.txDepth++
+	e.buffer.GroupOp()
+	defer func() {
+		e.buffer.UnGroupOp()
+		e.txDepth--
+		if tx.changed {
+			e.txChanged = true
+		}
+		if e.txDepth == 0 && e.txChanged {
+			e.txChanged = false
+			// report the edits by the event below only.
+			e.text.Changed()
+			e.text.MoveCaret(0, 0)
+			e.scrollCaret = true
+			e.pending = append(e.pending, ChangeEvent{})
+		}
+	}()
+
+	fn(tx)
+}
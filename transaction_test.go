@@ -0,0 +1,81 @@
+package gvcode
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestTransaction(t *testing.T) {
+	e := newTestEditor(t, "hello world")
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	changes := func() int {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 300))}
+		defer e.Layout(gtx, shaper)
+		n := 0
+		for {
+			evt, ok := e.Update(gtx)
+			if !ok {
+				break
+			}
+			if _, ok := evt.(ChangeEvent); ok {
+				n++
+			}
+		}
+		return n
+	}
+	changes()
+
+	e.Transaction(func(tx *EditTx) {
+		tx.Insert(0, "say ")
+		// nested transactions join the outer one.
+		e.Transaction(func(tx *EditTx) {
+			tx.Delete(9, 15)
+		})
+		tx.Insert(9, "!")
+		tx.SetCaret(10, 10)
+	})
+	if got := e.Text(); got != "say hello!" {
+		t.Fatalf("got text %q after the transaction", got)
+	}
+	if n := changes(); n != 1 {
+		t.Errorf("got %d change events, want 1", n)
+	}
+
+	e.undo()
+	if got := e.Text(); got != "hello world" {
+		t.Fatalf("got text %q after undo, want all the edits undone", got)
+	}
+	e.redo()
+	if got := e.Text(); got != "say hello!" {
+		t.Fatalf("got text %q after redo", got)
+	}
+
+	// a transaction without edits changes nothing.
+	changes()
+	e.Transaction(func(tx *EditTx) { tx.Replace(3, 3, "") })
+	if n := changes(); n != 0 {
+		t.Errorf("got %d change events without edits, want 0", n)
+	}
+
+	// the group is closed when fn panics.
+	func() {
+		defer func() { recover() }()
+		e.Transaction(func(tx *EditTx) {
+			tx.Insert(0, "x")
+			panic("abort")
+		})
+	}()
+	e.Insert("y")
+	e.undo()
+	if got := e.Text(); got != "xsay hello!" {
+		t.Errorf("got text %q after undo, want the edit after the panic undone alone", got)
+	}
+
+	e.setMode(ModeReadOnly)
+	e.Transaction(func(tx *EditTx) { t.Error("the transaction runs in a read-only editor") })
+}
@@ -0,0 +1,313 @@
+package gvcode
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"unicode"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	gvcolor "github.com/oligo/gvcode/color"
+	"github.com/oligo/gvcode/internal/buffer"
+	"github.com/oligo/gvcode/textstyle/decoration"
+)
+
+const (
+	unicodeWarningSource = "_unicode_warning"
+)
+
+// UnicodeWarningKind is the kind of a suspicious character found by the
+// Unicode warnings.
+type UnicodeWarningKind uint8
+
+const (
+	// UnicodeBidiControl is a bidirectional control character, which can
+	// reorder the displayed code, so that it reads differently from how it is
+	// compiled (CVE-2021-42574, "Trojan Source").
+	UnicodeBidiControl UnicodeWarningKind = iota
+	// UnicodeInvisible is an invisible character, like a zero width space or
+	// a control character.
+	UnicodeInvisible
+	// UnicodeConfusable is a character looking like an ASCII character, e.g.,
+	// the Cyrillic а in an identifier written in Latin letters, or a no-break
+	// space.
+	UnicodeConfusable
+)
+
+// UnicodeWarning is a suspicious character in the text.
+type UnicodeWarning struct {
+	// Offset is the rune offset of the character.
+	Offset int
+	Rune   rune
+	Kind   UnicodeWarningKind
+	// Lookalike is the ASCII character a confusable character looks like.
+	Lookalike rune
+}
+
+// UnicodeWarningsEvent is generated when the suspicious characters found in
+// the text change.
+type UnicodeWarningsEvent struct {
+	Warnings []UnicodeWarning
+}
+
+func (UnicodeWarningsEvent) isEditorEvent() {}
+
+var unicodeWarningColor = color.NRGBA{R: 0xF9, G: 0xA8, B: 0x25, A: 0xFF}
+
+// confusables maps the characters looking like ASCII characters to them. The
+// letters are only reported in words with ASCII letters, as they are the
+// usual letters of other scripts.
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'B', 'е': 'e', 'о': 'o', 'р': 'p', 'с': 'c', 'у': 'y', 'х': 'x',
+	'ѕ': 's', 'і': 'i', 'ј': 'j', 'ԁ': 'd', 'һ': 'h', 'ԛ': 'q', 'ԝ': 'w',
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O',
+	'Р': 'P', 'С': 'C', 'Т': 'T', 'Х': 'X', 'Ѕ': 'S', 'І': 'I', 'Ј': 'J',
+	// Greek
+	'ο': 'o', 'ν': 'v', 'ϲ': 'c', 'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z',
+	'Η': 'H', 'Ι': 'I', 'Κ': 'K', 'Μ': 'M', 'Ν': 'N', 'Ο': 'O', 'Ρ': 'P',
+	'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+	// punctuation and spaces
+	'\u037E': ';', '\u2010': '-', '\u2011': '-', '\u2012': '-', '\u2013': '-',
+	'\u2212': '-', '\u2018': '\'', '\u2019': '\'', '\u201C': '"', '\u201D': '"',
+	'\u2044': '/', '\u2215': '/', '\u01C3': '!', '\u00A0': ' ', '\u2000': ' ',
+	'\u2001': ' ', '\u2002': ' ', '\u2003': ' ', '\u2004': ' ', '\u2005': ' ',
+	'\u2006': ' ', '\u2007': ' ', '\u2008': ' ', '\u2009': ' ', '\u200A': ' ',
+	'\u202F': ' ', '\u205F': ' ', '\u3000': ' ',
+}
+
+// unicodeWarningState tracks the suspicious characters of the text.
+type unicodeWarningState struct {
+	enabled bool
+	// synced is set once the whole text is scanned, after which only the
+	// edited lines are scanned again.
+	synced  bool
+	version int
+	items   []unicodeWarningItem
+}
+
+type unicodeWarningItem struct {
+	r         rune
+	kind      UnicodeWarningKind
+	lookalike rune
+	// marker tracks the character as the text changes.
+	marker *buffer.Marker
+}
+
+// reset drops the warnings, to scan the text again.
+func (s *unicodeWarningState) reset(src buffer.TextSource) {
+	for _, item := range s.items {
+		src.RemoveMarker(item.marker)
+	}
+	s.items = s.items[:0]
+	s.synced = false
+}
+
+// UnicodeWarnings returns the suspicious characters found in the text, if
+// the warnings are enabled with WithUnicodeWarnings.
+func (e *Editor) UnicodeWarnings() []UnicodeWarning {
+	warnings := make([]UnicodeWarning, 0, len(e.unicodeWarnings.items))
+	for _, item := range e.unicodeWarnings.items {
+		warnings = append(warnings, UnicodeWarning{
+			Offset:    item.marker.Offset(),
+			Rune:      item.r,
+			Kind:      item.kind,
+			Lookalike: item.lookalike,
+		})
+	}
+	return warnings
+}
+
+// syncUnicodeWarnings scans the lines edited since the last scan for
+// suspicious characters, and generates a UnicodeWarningsEvent if they
+// change.
+func (e *Editor) syncUnicodeWarnings() {
+	s := &e.unicodeWarnings
+	if !s.enabled {
+		return
+	}
+	version := e.buffer.Version()
+	if s.synced && version == s.version {
+		return
+	}
+
+	start, end := 0, e.text.Len()
+	if s.synced {
+		edits, ok := e.buffer.EditsSince(s.version)
+		if ok {
+			start, end = editedRange(edits)
+		}
+		// the markers are dropped when the whole text is replaced.
+		if !ok || start == 0 && end == e.text.Len() {
+			s.reset(e.buffer)
+		}
+	}
+	// the decorations of a full scan are replaced, as the document may have
+	// been switched with its decorations.
+	full := !s.synced
+	s.synced, s.version = true, version
+
+	// scan whole lines, as the confusable letters depend on their words.
+	start, end = e.lineRange(start, end)
+	old := e.UnicodeWarnings()
+	kept := s.items[:0]
+	for _, item := range s.items {
+		if off := item.marker.Offset(); off >= start && off < end {
+			e.buffer.RemoveMarker(item.marker)
+			continue
+		}
+		kept = append(kept, item)
+	}
+	s.items = kept
+
+	runes := []rune(e.ReadRange(start, end))
+	for i, r := range runes {
+		kind, lookalike, ok := unicodeWarningAt(runes, i)
+		if !ok {
+			continue
+		}
+		marker, err := e.buffer.CreateMarker(start+i, buffer.BiasBackward)
+		if err != nil {
+			continue
+		}
+		s.items = append(s.items, unicodeWarningItem{r: r, kind: kind, lookalike: lookalike, marker: marker})
+	}
+	slices.SortFunc(s.items, func(a, b unicodeWarningItem) int { return a.marker.Offset() - b.marker.Offset() })
+
+	warnings := e.UnicodeWarnings()
+	changed := !slices.Equal(old, warnings)
+	if !changed && !full {
+		return
+	}
+	e.ClearDecorations(unicodeWarningSource)
+	c := gvcolor.MakeColor(unicodeWarningColor)
+	decos := make([]decoration.Decoration, 0, len(warnings))
+	for _, w := range warnings {
+		decos = append(decos, decoration.Decoration{
+			Source:     unicodeWarningSource,
+			Start:      w.Offset,
+			End:        w.Offset + 1,
+			Background: &decoration.Background{Color: c.MulAlpha(0x40)},
+			Underline:  &decoration.Underline{Color: c},
+			Priority:   1,
+		})
+	}
+	if len(decos) > 0 {
+		e.AddDecorations(decos...)
+	}
+	if changed {
+		e.pending = append(e.pending, UnicodeWarningsEvent{Warnings: warnings})
+	}
+}
+
+// editedRange returns the range of the current text covering the edits.
+func editedRange(edits []buffer.Edit) (start, end int) {
+	for i, edit := range edits {
+		if i == 0 {
+			start, end = edit.Start, edit.NewEnd
+			continue
+		}
+		delta := edit.NewEnd - edit.OldEnd
+		if start >= edit.OldEnd {
+			start += delta
+		} else if start > edit.Start {
+			start = edit.Start
+		}
+		if end >= edit.OldEnd {
+			end += delta
+		} else if end > edit.Start {
+			end = edit.NewEnd
+		}
+		start, end = min(start, edit.Start), max(end, edit.NewEnd)
+	}
+	return start, end
+}
+
+// lineRange extends [start, end) to the start and the end of their lines.
+func (e *Editor) lineRange(start, end int) (int, int) {
+	_, first := e.text.FindParagraph(start)
+	_, last := e.text.FindParagraph(max(end-1, start))
+	return min(first.RuneOff, start), max(last.RuneOff+last.Runes, end)
+}
+
+// unicodeWarningAt reports whether runes[i] is a suspicious character.
+func unicodeWarningAt(runes []rune, i int) (UnicodeWarningKind, rune, bool) {
+	r := runes[i]
+	if r < unicode.MaxASCII && (r >= ' ' || r == '\t' || r == '\n' || r == '\r') {
+		return 0, 0, false
+	}
+
+	switch {
+	case r == '\u061C' || r == '\u200E' || r == '\u200F' ||
+		r >= '\u202A' && r <= '\u202E' || r >= '\u2066' && r <= '\u2069':
+		return UnicodeBidiControl, 0, true
+	case r == '\u200D' && i > 0 && isEmojiPart(runes[i-1]):
+		// joins the emoji of a ZWJ sequence.
+		return 0, 0, false
+	case r == '\uFEFF' && i == 0:
+		// a byte order mark.
+		return 0, 0, false
+	}
+
+	if lookalike, ok := confusables[r]; ok {
+		if unicode.IsLetter(r) && !inLatinWord(runes, i) {
+			return 0, 0, false
+		}
+		return UnicodeConfusable, lookalike, true
+	}
+	if isInvisibleRune(r) {
+		return UnicodeInvisible, 0, true
+	}
+	return 0, 0, false
+}
+
+// isEmojiPart reports whether r can precede a zero width joiner in an emoji
+// sequence.
+func isEmojiPart(r rune) bool {
+	return unicode.Is(unicode.So, r) || unicode.Is(unicode.Sk, r) ||
+		r == '\uFE0F' || r >= 0x1F3FB && r <= 0x1F3FF
+}
+
+// inLatinWord reports whether the word around runes[i] has ASCII letters.
+func inLatinWord(runes []rune, i int) bool {
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' }
+	for j := i - 1; j >= 0 && isWordRune(runes[j]); j-- {
+		if runes[j] < unicode.MaxASCII && unicode.IsLetter(runes[j]) {
+			return true
+		}
+	}
+	for j := i + 1; j < len(runes) && isWordRune(runes[j]); j++ {
+		if runes[j] < unicode.MaxASCII && unicode.IsLetter(runes[j]) {
+			return true
+		}
+	}
+	return false
+}
+
+// paintUnicodeWarnings marks the invisible suspicious characters, which the
+// decorations can not show as they have no width.
+func (e *Editor) paintUnicodeWarnings(gtx layout.Context) {
+	if !e.unicodeWarnings.enabled || len(e.unicodeWarnings.items) == 0 {
+		return
+	}
+	width := max(gtx.Dp(unit.Dp(2)), 1)
+	viewport := e.text.Viewport()
+	start := e.text.ClosestOffset(image.Point{})
+	end := e.text.ClosestOffset(image.Pt(viewport.Dx(), viewport.Dy()))
+	for _, item := range e.unicodeWarnings.items {
+		off := item.marker.Offset()
+		if off < start || off > end {
+			continue
+		}
+		for _, r := range e.text.Regions(off, off+1, nil) {
+			if r.Bounds.Dx() > 0 {
+				continue
+			}
+			rect := image.Rect(r.Bounds.Min.X-width/2, r.Bounds.Min.Y, r.Bounds.Min.X-width/2+width, r.Bounds.Max.Y)
+			paint.FillShape(gtx.Ops, unicodeWarningColor, clip.Rect(rect).Op())
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package gvcode
+
+import (
+	"image"
+	"slices"
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+func TestUnicodeWarnings(t *testing.T) {
+	// a Trojan Source comment, a Cyrillic а in a Latin identifier, a zero
+	// width space, and Russian words which are not reported.
+	src := "x := 1 /* \u202E } \u2066 */\nvаlue := x\u200B\n// привет мир\n"
+	e := newTestEditor(t, src)
+	e.WithOptions(WithUnicodeWarnings(true))
+
+	var events []UnicodeWarningsEvent
+	update := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(800, 600))}
+		for {
+			evt, ok := e.Update(gtx)
+			if !ok {
+				break
+			}
+			if w, ok := evt.(UnicodeWarningsEvent); ok {
+				events = append(events, w)
+			}
+		}
+	}
+	update()
+
+	want := []UnicodeWarning{
+		{Offset: 10, Rune: '\u202E', Kind: UnicodeBidiControl},
+		{Offset: 14, Rune: '\u2066', Kind: UnicodeBidiControl},
+		{Offset: 20, Rune: 'а', Kind: UnicodeConfusable, Lookalike: 'a'},
+		{Offset: 29, Rune: '\u200B', Kind: UnicodeInvisible},
+	}
+	if got := e.UnicodeWarnings(); !slices.Equal(got, want) {
+		t.Fatalf("got warnings %v, want %v", got, want)
+	}
+	if len(events) != 1 || !slices.Equal(events[0].Warnings, want) {
+		t.Fatalf("got events %v, want one with the warnings", events)
+	}
+
+	// only the edited lines are scanned again, and the other warnings follow
+	// the edits.
+	e.SetCaret(0, 0)
+	e.Insert("\u00A0")
+	e.replace(21, 22, "a")
+	update()
+	want = []UnicodeWarning{
+		{Offset: 0, Rune: '\u00A0', Kind: UnicodeConfusable, Lookalike: ' '},
+		{Offset: 11, Rune: '\u202E', Kind: UnicodeBidiControl},
+		{Offset: 15, Rune: '\u2066', Kind: UnicodeBidiControl},
+		{Offset: 30, Rune: '\u200B', Kind: UnicodeInvisible},
+	}
+	if got := e.UnicodeWarnings(); !slices.Equal(got, want) {
+		t.Fatalf("got warnings %v after the edits, want %v", got, want)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+
+	// the emoji joined by zero width joiners are not reported.
+	e.SetText("👨\u200D👩\u200D👧\n")
+	update()
+	if got := e.UnicodeWarnings(); len(got) != 0 {
+		t.Errorf("got warnings %v for an emoji sequence", got)
+	}
+}
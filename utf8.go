@@ -0,0 +1,65 @@
+package gvcode
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// InvalidUTF8Policy defines how the editor handles invalid UTF-8 in the text
+// set by SetText or pasted from the clipboard.
+type InvalidUTF8Policy int
+
+const (
+	// InvalidUTF8Replace replaces each run of invalid bytes with the
+	// replacement character U+FFFD. This is the default policy.
+	InvalidUTF8Replace InvalidUTF8Policy = iota
+	// InvalidUTF8Reject leaves the text unchanged, and generates an
+	// InvalidUTF8Event.
+	InvalidUTF8Reject
+	// InvalidUTF8PassThrough keeps the raw bytes, which is useful to view
+	// binary files. Each invalid byte counts as one rune, and is read as
+	// U+FFFD. Edits joining invalid bytes into a valid sequence corrupt the
+	// rune indexing, so the editor should be read-only in this mode.
+	InvalidUTF8PassThrough
+)
+
+// InvalidUTF8Event is generated when text with invalid UTF-8 is rejected by
+// the InvalidUTF8Reject policy.
+type InvalidUTF8Event struct {
+	// Op is the rejected operation, "setText" or "paste".
+	Op string
+	// Offset is the byte offset of the first invalid byte in the text.
+	Offset int
+}
+
+func (InvalidUTF8Event) isEditorEvent() {}
+
+// checkUTF8 applies the invalid UTF-8 policy to the text of op. It returns
+// the text to use, or an event if the text is rejected.
+func (e *Editor) checkUTF8(op string, s string) (string, *InvalidUTF8Event) {
+	if utf8.ValidString(s) {
+		return s, nil
+	}
+
+	switch e.invalidUTF8 {
+	case InvalidUTF8Reject:
+		return "", &InvalidUTF8Event{Op: op, Offset: invalidUTF8Offset(s)}
+	case InvalidUTF8PassThrough:
+		return s, nil
+	default:
+		return strings.ToValidUTF8(s, string(utf8.RuneError)), nil
+	}
+}
+
+// invalidUTF8Offset returns the byte offset of the first invalid byte of s,
+// or -1 if s is valid UTF-8.
+func invalidUTF8Offset(s string) int {
+	for i, r := range s {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(s[i:]); size == 1 {
+				return i
+			}
+		}
+	}
+	return -1
+}
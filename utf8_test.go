@@ -0,0 +1,52 @@
+package gvcode
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"gioui.org/io/transfer"
+	"gioui.org/layout"
+	"gioui.org/op"
+)
+
+func TestInvalidUTF8Policy(t *testing.T) {
+	const invalid = "a\xffb\xe2\x82c"
+
+	e := newTestEditor(t, "")
+	e.SetText(invalid)
+	if got, want := e.Text(), "a�b�c"; got != want {
+		t.Fatalf("replace: got %q, want %q", got, want)
+	}
+
+	e.WithOptions(WithInvalidUTF8Policy(InvalidUTF8Reject))
+	e.SetText(invalid)
+	if got, want := e.Text(), "a�b�c"; got != want {
+		t.Fatalf("reject: text changed to %q", got)
+	}
+	gtx := layout.Context{Ops: new(op.Ops)}
+	evt, ok := e.Update(gtx)
+	if !ok || evt != (InvalidUTF8Event{Op: "setText", Offset: 1}) {
+		t.Fatalf("reject: got event %#v", evt)
+	}
+
+	paste := transfer.DataEvent{Type: "application/text", Open: func() io.ReadCloser {
+		return io.NopCloser(strings.NewReader("x\xff"))
+	}}
+	if evt := e.onPasteEvent(paste); evt != (InvalidUTF8Event{Op: "paste", Offset: 1}) {
+		t.Fatalf("reject paste: got event %#v", evt)
+	}
+
+	e.WithOptions(WithInvalidUTF8Policy(InvalidUTF8PassThrough))
+	e.SetText(invalid)
+	if got := e.Text(); got != invalid {
+		t.Fatalf("pass through: got %q, want %q", got, invalid)
+	}
+	// each invalid byte is a rune.
+	if got, want := e.Len(), 6; got != want {
+		t.Fatalf("pass through: got length %d, want %d", got, want)
+	}
+	if got, want := e.ReadRange(4, 6), "\x82c"; got != want {
+		t.Fatalf("pass through: got range %q, want %q", got, want)
+	}
+}
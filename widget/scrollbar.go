@@ -0,0 +1,95 @@
+package widget
+
+import (
+	"image"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	giowidget "gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/oligo/gvcode"
+)
+
+// Scrollbars holds the state of the scrollbars of an editor.
+type Scrollbars struct {
+	Horizontal giowidget.Scrollbar
+	Vertical   giowidget.Scrollbar
+}
+
+// ScrollbarStyle lays out an editor with a vertical scrollbar on its right,
+// and a horizontal scrollbar over the bottom of the text area when the lines
+// are not wrapped and some of them are wider than the editor. The scrollbar
+// annotations of the editor are marked over the vertical scrollbar.
+type ScrollbarStyle struct {
+	Editor     *gvcode.Editor
+	State      *Scrollbars
+	Horizontal material.ScrollbarStyle
+	Vertical   material.ScrollbarStyle
+}
+
+// EditorScrollbars returns the scrollbars of editor styled with the theme.
+func EditorScrollbars(th *material.Theme, editor *gvcode.Editor, state *Scrollbars) ScrollbarStyle {
+	s := ScrollbarStyle{
+		Editor:     editor,
+		State:      state,
+		Horizontal: material.Scrollbar(th, &state.Horizontal),
+		Vertical:   material.Scrollbar(th, &state.Vertical),
+	}
+	for _, bar := range []*material.ScrollbarStyle{&s.Horizontal, &s.Vertical} {
+		bar.Indicator.CornerRadius = unit.Dp(0)
+		bar.Track.MajorPadding = unit.Dp(0)
+	}
+	return s
+}
+
+// Layout scrolls the editor by the distances the scrollbars are dragged, and
+// lays out the editor with its scrollbars.
+func (s ScrollbarStyle) Layout(gtx layout.Context, shaper *text.Shaper) layout.Dimensions {
+	if dx, dy := s.State.Horizontal.ScrollDistance(), s.State.Vertical.ScrollDistance(); dx != 0 || dy != 0 {
+		s.Editor.Scroll(gtx, dx, dy)
+	}
+
+	return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			dims := s.Editor.Layout(gtx, shaper)
+			minX, maxX, _, _ := s.Editor.ScrollRatio()
+			if s.Editor.WrapLineEnabled() || (minX <= 0 && maxX >= 1) {
+				return dims
+			}
+
+			// the horizontal scrollbar spans the text area, right of the
+			// gutter.
+			gutter := s.Editor.GutterWidth()
+			gtx.Constraints.Min = image.Point{}
+			gtx.Constraints.Max.X = max(dims.Size.X-gutter, 0)
+			macro := op.Record(gtx.Ops)
+			bar := s.Horizontal.Layout(gtx, layout.Horizontal, minX, maxX)
+			call := macro.Stop()
+			defer op.Offset(image.Pt(gutter, dims.Size.Y-bar.Size.Y)).Push(gtx.Ops).Pop()
+			call.Add(gtx.Ops)
+			return dims
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			_, _, minY, maxY := s.Editor.ScrollRatio()
+			dims := s.Vertical.Layout(gtx, layout.Vertical, minY, maxY)
+			s.paintAnnotations(gtx, dims.Size)
+			return dims
+		}),
+	)
+}
+
+// paintAnnotations marks the annotations of the editor, e.g., the references
+// of a symbol, over the vertical scrollbar of size.
+func (s ScrollbarStyle) paintAnnotations(gtx layout.Context, size image.Point) {
+	minHeight := gtx.Dp(unit.Dp(2))
+	for _, a := range s.Editor.ScrollbarAnnotations() {
+		top := int(a.Start * float32(size.Y))
+		bottom := max(int(a.End*float32(size.Y)), top+minHeight)
+		rect := image.Rect(0, top, size.X, bottom).Intersect(image.Rectangle{Max: size})
+		paint.FillShape(gtx.Ops, a.Color.NRGBA(), clip.Rect(rect).Op())
+	}
+}
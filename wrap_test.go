@@ -0,0 +1,91 @@
+package gvcode
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestWrapIndent(t *testing.T) {
+	e := newTestEditor(t, "\t"+strings.Repeat("word ", 40)+"\nend", WrapLine(true), WithWrapIndent(true, 2), WithWrapIndicators(true))
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	var router input.Router
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(300, 400)), Source: router.Source()}
+	e.Layout(gtx, shaper)
+
+	// find the first rune of the second screen line.
+	_, first := e.ConvertPos(0, 0)
+	_, text := e.ConvertPos(0, 1)
+	_, space := e.ConvertPos(0, 5)
+	_, word := e.ConvertPos(0, 6)
+	spaceWidth := word.X - space.X
+	col := 1
+	for ; col < 200; col++ {
+		if _, pos := e.ConvertPos(0, col); pos.Y > first.Y {
+			break
+		}
+	}
+	if col == 200 {
+		t.Fatal("the line is not wrapped")
+	}
+
+	// the continuation lines are indented as the text of the first line, plus
+	// the hanging spaces.
+	_, pos := e.ConvertPos(0, col)
+	if want := text.X + 2*spaceWidth; pos.X < want-1 || pos.X > want+1 {
+		t.Errorf("continuation line starts at %v, want %v", pos.X, want)
+	}
+
+	ToggleWrapLine.Run(gtx, e)
+	if e.WrapLineEnabled() {
+		t.Fatal("the lines are still wrapped")
+	}
+	gtx.Ops.Reset()
+	e.Layout(gtx, shaper)
+	if _, pos := e.ConvertPos(0, col); pos.Y != first.Y {
+		t.Errorf("the line is wrapped after toggling the wrapping off")
+	}
+}
+
+func TestHomeEndWrappedLine(t *testing.T) {
+	line := strings.Repeat("word ", 40)
+	e := newTestEditor(t, line+"\nend", WrapLine(true))
+	var router input.Router
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(300, 400)), Source: router.Source()}
+	e.Layout(gtx, text.NewShaper(text.WithCollection(gofont.Collection())))
+
+	// the start of the second screen line.
+	_, first := e.ConvertPos(0, 0)
+	wrapAt := 1
+	for ; wrapAt < len(line); wrapAt++ {
+		if _, pos := e.ConvertPos(0, wrapAt); pos.Y > first.Y {
+			break
+		}
+	}
+
+	press := func(cmd Command) int {
+		cmd.Run(gtx, e)
+		start, _ := e.Selection()
+		return start
+	}
+	e.SetCaret(wrapAt+3, wrapAt+3)
+	if got := press(MoveLineStart); got != wrapAt {
+		t.Errorf("Home: got %d, want the start of the screen line %d", got, wrapAt)
+	}
+	if got := press(MoveLineStart); got != 0 {
+		t.Errorf("Home twice: got %d, want the start of the line", got)
+	}
+	if got := press(MoveLineEnd); got <= 0 || got >= len(line) {
+		t.Errorf("End: got %d, want the end of the first screen line", got)
+	}
+	end := e.Len() - len("\nend")
+	if got := press(MoveLineEnd); got != end {
+		t.Errorf("End twice: got %d, want the end of the line %d", got, end)
+	}
+}
@@ -0,0 +1,103 @@
+package gvcode
+
+import (
+	"gioui.org/f32"
+	"gioui.org/unit"
+)
+
+const (
+	defaultMinZoom = 0.5
+	defaultMaxZoom = 3
+)
+
+// ZoomChangedEvent is generated when the zoom level is changed by a pinch or
+// a Ctrl+wheel, for the host app to persist it and restore it with SetZoom.
+type ZoomChangedEvent struct {
+	// Level is the new zoom level, the text size being scaled by it.
+	Level float32
+}
+
+func (ZoomChangedEvent) isEditorEvent() {}
+
+// zoomState scales the text size with the zoom level.
+type zoomState struct {
+	minLevel, maxLevel float32
+	// level is the zoom level, 0 meaning no zoom.
+	level float32
+	// baseSize and baseLineHeight are the text size and the line height at
+	// the level 1.
+	baseSize       unit.Sp
+	baseLineHeight unit.Sp
+	// anchored is set when the caret is kept at the viewport position
+	// anchor in the next layout.
+	anchored bool
+	anchor   f32.Point
+}
+
+// Zoom returns the zoom level, 1 if the text is not zoomed.
+func (e *Editor) Zoom() float32 {
+	if e.zoom.level == 0 {
+		return 1
+	}
+	return e.zoom.level
+}
+
+// SetZoom scales the text size by level, clamped to the bounds set by
+// WithZoom. The caret is kept at its position in the viewport. It does not
+// generate a ZoomChangedEvent.
+func (e *Editor) SetZoom(level float32) {
+	e.initBuffer()
+	e.setZoom(level)
+}
+
+// setZoom scales the text size by level, and reports whether the level
+// changed.
+func (e *Editor) setZoom(level float32) bool {
+	z := &e.zoom
+	minLevel, maxLevel := z.minLevel, z.maxLevel
+	if minLevel <= 0 {
+		minLevel = defaultMinZoom
+	}
+	if maxLevel <= 0 {
+		maxLevel = defaultMaxZoom
+	}
+	level = max(minLevel, min(level, maxLevel))
+	if level == e.Zoom() {
+		return false
+	}
+
+	if z.level == 0 || z.level == 1 {
+		z.baseSize, z.baseLineHeight = e.text.TextSize, e.text.LineHeight
+	}
+	z.level = level
+	e.text.TextSize = z.baseSize * unit.Sp(level)
+	e.text.LineHeight = z.baseLineHeight * unit.Sp(level)
+
+	if !z.anchored {
+		z.anchored = true
+		z.anchor = e.text.CaretCoords()
+	}
+	return true
+}
+
+// zoomBy scales the zoom level by factor, for the pinches and the
+// Ctrl+wheels.
+func (e *Editor) zoomBy(factor float32) (EditorEvent, bool) {
+	if factor == 1 || !e.setZoom(e.Zoom()*factor) {
+		return nil, false
+	}
+	e.scroller.Stop()
+	return ZoomChangedEvent{Level: e.Zoom()}, true
+}
+
+// restoreZoomAnchor scrolls the caret back to its viewport position after the
+// layout of the zoomed text.
+func (e *Editor) restoreZoomAnchor() {
+	z := &e.zoom
+	if !z.anchored {
+		return
+	}
+	z.anchored = false
+	delta := e.text.CaretCoords().Sub(z.anchor).Round()
+	e.text.ScrollRel(delta.X, delta.Y)
+}
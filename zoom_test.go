@@ -0,0 +1,90 @@
+package gvcode
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/font/gofont"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestZoom(t *testing.T) {
+	e := newTestEditor(t, strings.Repeat("line\n", 100), WithTextSize(10), WithZoom(0.5, 2))
+
+	var router input.Router
+	shaper := text.NewShaper(text.WithCollection(gofont.Collection()))
+	var events []EditorEvent
+	frame := func() {
+		gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(400, 200)), Source: router.Source()}
+		for {
+			evt, ok := e.Update(gtx)
+			if !ok {
+				break
+			}
+			events = append(events, evt)
+		}
+		e.Layout(gtx, shaper)
+		router.Frame(gtx.Ops)
+	}
+	frame()
+	off := e.text.ConvertPos(50, 0)
+	e.SetCaret(off, off)
+	e.RevealRange(off, off, RevealCenter)
+	frame()
+	caret := e.text.CaretCoords()
+
+	// Ctrl+wheel zooms in, keeping the caret in place.
+	router.Queue(pointer.Event{Kind: pointer.Scroll, Source: pointer.Mouse, Modifiers: key.ModCtrl, Position: f32.Pt(10, 10), Scroll: f32.Pt(0, -40)})
+	frame()
+	if len(events) != 1 || events[0] != (ZoomChangedEvent{Level: 1.1}) {
+		t.Fatalf("got events %v after zooming in", events)
+	}
+	if got := e.text.TextSize; got != 11 {
+		t.Errorf("got text size %v, want 11", got)
+	}
+	if got := e.text.CaretCoords(); abs(int(got.Y-caret.Y)) > 1 {
+		t.Errorf("got the caret at %v, want it kept at %v", got, caret)
+	}
+
+	// a pinch zooms by the ratio of the distances of the touches.
+	events = nil
+	router.Queue(
+		pointer.Event{Kind: pointer.Press, Source: pointer.Touch, PointerID: 0, Position: f32.Pt(100, 100)},
+		pointer.Event{Kind: pointer.Press, Source: pointer.Touch, PointerID: 1, Position: f32.Pt(150, 100)},
+	)
+	frame()
+	router.Queue(pointer.Event{Kind: pointer.Move, Source: pointer.Touch, PointerID: 1, Position: f32.Pt(200, 100)})
+	frame()
+	router.Queue(
+		pointer.Event{Kind: pointer.Release, Source: pointer.Touch, PointerID: 0, Position: f32.Pt(100, 100)},
+		pointer.Event{Kind: pointer.Release, Source: pointer.Touch, PointerID: 1, Position: f32.Pt(200, 100)},
+	)
+	frame()
+	if len(events) != 1 || events[0] != (ZoomChangedEvent{Level: 2}) {
+		t.Fatalf("got events %v after the pinch, want the level clamped to 2", events)
+	}
+	if got := e.text.TextSize; got != 20 {
+		t.Errorf("got text size %v, want 20", got)
+	}
+
+	// the wheels without Ctrl scroll.
+	events = nil
+	scroll := e.text.ScrollOff()
+	router.Queue(pointer.Event{Kind: pointer.Scroll, Source: pointer.Mouse, Position: f32.Pt(10, 10), Scroll: f32.Pt(0, 40)})
+	frame()
+	if len(events) != 0 || e.text.ScrollOff().Y != scroll.Y+40 {
+		t.Errorf("got events %v and scroll offset %v after a wheel", events, e.text.ScrollOff())
+	}
+
+	e.SetZoom(0.1)
+	if got := e.Zoom(); got != 0.5 || e.text.TextSize != 5 {
+		t.Errorf("got zoom %v and text size %v, want the level clamped to 0.5", got, e.text.TextSize)
+	}
+}